@@ -0,0 +1,17 @@
+"""Python client for the Licet v2 REST API."""
+
+from .client import APIError, Client
+from .models import Alert, Feature, Incident, Server, ServerStatus, Utilization, UtilizationPoint, UtilizationStats
+
+__all__ = [
+    "APIError",
+    "Alert",
+    "Client",
+    "Feature",
+    "Incident",
+    "Server",
+    "ServerStatus",
+    "Utilization",
+    "UtilizationPoint",
+    "UtilizationStats",
+]
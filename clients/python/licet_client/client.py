@@ -0,0 +1,110 @@
+"""HTTP client for the Licet v2 API (standard library only)."""
+
+import json
+import urllib.error
+import urllib.parse
+import urllib.request
+from typing import Any, Dict, List, Optional, Tuple, Type, TypeVar
+
+from .models import (
+    Alert,
+    Feature,
+    Incident,
+    Server,
+    ServerStatus,
+    Utilization,
+    UtilizationPoint,
+    UtilizationStats,
+    from_dict,
+)
+
+T = TypeVar("T")
+
+PAGE_SIZE = 500
+
+
+class APIError(Exception):
+    """Raised when the server responds with a non-2xx status."""
+
+    def __init__(self, status: int, message: str):
+        super().__init__(f"licet: {status} {message}")
+        self.status = status
+        self.message = message
+
+
+class Client:
+    """Client for a Licet server.
+
+    >>> client = Client("http://licet.example.com:8080", api_key="...")
+    >>> client.current_utilization()
+    """
+
+    def __init__(self, base_url: str, api_key: Optional[str] = None, timeout: float = 30.0):
+        self.base_url = base_url.rstrip("/")
+        self.api_key = api_key
+        self.timeout = timeout
+
+    def _get(self, path: str, params: Optional[Dict[str, Any]] = None) -> Tuple[Any, Dict[str, Any]]:
+        query = {k: v for k, v in (params or {}).items() if v not in (None, "")}
+        url = f"{self.base_url}/api/v2{path}"
+        if query:
+            url += "?" + urllib.parse.urlencode(query)
+
+        req = urllib.request.Request(url, headers={"Accept": "application/json"})
+        if self.api_key:
+            req.add_header("X-API-Key", self.api_key)
+
+        try:
+            with urllib.request.urlopen(req, timeout=self.timeout) as resp:
+                body = json.load(resp)
+        except urllib.error.HTTPError as e:
+            message = e.reason
+            try:
+                message = json.load(e).get("error", {}).get("message", message)
+            except ValueError:
+                pass
+            raise APIError(e.code, message) from None
+
+        return body.get("data"), body.get("meta", {})
+
+    def _list(self, cls: Type[T], path: str, params: Optional[Dict[str, Any]] = None) -> List[T]:
+        params = dict(params or {}, limit=PAGE_SIZE)
+        items: List[T] = []
+        page = 1
+        while True:
+            params["page"] = page
+            data, meta = self._get(path, params)
+            items.extend(from_dict(cls, item) for item in data or [])
+            if page >= meta.get("total_pages", 0):
+                return items
+            page += 1
+
+    def health(self) -> Dict[str, Any]:
+        data, _ = self._get("/health")
+        return data
+
+    def servers(self) -> List[Server]:
+        return self._list(Server, "/servers")
+
+    def server_status(self, hostname: str, server_type: str = "") -> ServerStatus:
+        data, _ = self._get(f"/servers/{urllib.parse.quote(hostname, safe='')}/status", {"type": server_type})
+        return from_dict(ServerStatus, data)
+
+    def features(self, hostname: str) -> List[Feature]:
+        return self._list(Feature, f"/servers/{urllib.parse.quote(hostname, safe='')}/features")
+
+    def current_utilization(self, server: str = "") -> List[Utilization]:
+        return self._list(Utilization, "/utilization/current", {"server": server})
+
+    def utilization_history(self, server: str = "", feature: str = "", period: str = "") -> List[UtilizationPoint]:
+        return self._list(UtilizationPoint, "/utilization/history",
+                          {"server": server, "feature": feature, "period": period})
+
+    def utilization_stats(self, server: str = "", days: int = 30) -> List[UtilizationStats]:
+        return self._list(UtilizationStats, "/utilization/stats", {"server": server, "days": days})
+
+    def alerts(self) -> List[Alert]:
+        return self._list(Alert, "/alerts")
+
+    def incidents(self, days: int = 30) -> List[Incident]:
+        return self._list(Incident, "/incidents", {"days": days})
@@ -0,0 +1,110 @@
+"""Typed models mirroring the Licet API responses."""
+
+from dataclasses import dataclass, field, fields
+from typing import Any, Dict, List, Optional, Type, TypeVar
+
+T = TypeVar("T")
+
+
+def from_dict(cls: Type[T], data: Dict[str, Any]) -> T:
+    """Build a dataclass from a response dict, ignoring unknown keys."""
+    names = {f.name for f in fields(cls)}
+    return cls(**{k: v for k, v in data.items() if k in names})
+
+
+@dataclass
+class Server:
+    hostname: str
+    type: str
+    description: str = ""
+    id: int = 0
+    cacti_id: str = ""
+    webui: str = ""
+
+
+@dataclass
+class ServerStatus:
+    hostname: str
+    service: str
+    master: str = ""
+    version: str = ""
+    message: str = ""
+    warnings: List[str] = field(default_factory=list)
+    last_checked: Optional[str] = None
+
+
+@dataclass
+class Feature:
+    server_hostname: str
+    name: str
+    version: str = ""
+    vendor_daemon: str = ""
+    total_licenses: int = 0
+    used_licenses: int = 0
+    reserved_licenses: int = 0
+    overdraft_licenses: int = 0
+    license_model: str = "floating"
+    parse_quality: str = "ok"
+    expiration_date: Optional[str] = None
+    last_updated: Optional[str] = None
+    is_active: bool = True
+    id: int = 0
+
+
+@dataclass
+class Utilization:
+    server_hostname: str
+    feature_name: str
+    version: str = ""
+    total_licenses: int = 0
+    used_licenses: int = 0
+    reserved_licenses: int = 0
+    overdraft_licenses: int = 0
+    available_licenses: int = 0
+    utilization_pct: float = 0.0
+    vendor_daemon: str = ""
+    license_model: str = "floating"
+
+
+@dataclass
+class UtilizationPoint:
+    timestamp: str
+    users_count: int = 0
+
+
+@dataclass
+class UtilizationStats:
+    server_hostname: str
+    feature_name: str
+    avg_usage: float = 0.0
+    peak_usage: int = 0
+    min_usage: int = 0
+    total_licenses: int = 0
+
+
+@dataclass
+class Alert:
+    id: int
+    server_hostname: str
+    alert_type: str
+    message: str
+    severity: str
+    feature_name: str = ""
+    sent: bool = False
+    sent_at: Optional[str] = None
+    created_at: Optional[str] = None
+    incident_id: Optional[int] = None
+    links: Optional[Dict[str, str]] = None  # details, trend, runbook
+
+
+@dataclass
+class Incident:
+    id: int
+    server_hostname: str
+    root_cause: str
+    severity: str
+    status: str  # open or resolved
+    alert_count: int = 0
+    opened_at: Optional[str] = None
+    last_alert_at: Optional[str] = None
+    notified: bool = False
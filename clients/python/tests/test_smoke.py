@@ -0,0 +1,30 @@
+"""Smoke tests against a running Licet instance.
+
+    LICET_URL=http://localhost:8080 python -m unittest discover -s tests
+"""
+
+import os
+import unittest
+
+from licet_client import Client
+
+LICET_URL = os.environ.get("LICET_URL")
+
+
+@unittest.skipUnless(LICET_URL, "LICET_URL not set")
+class SmokeTest(unittest.TestCase):
+    def setUp(self):
+        self.client = Client(LICET_URL, api_key=os.environ.get("LICET_API_KEY"))
+
+    def test_health(self):
+        self.assertEqual(self.client.health()["status"], "ok")
+
+    def test_servers_and_utilization(self):
+        for server in self.client.servers():
+            self.client.features(server.hostname)
+        self.client.current_utilization()
+        self.client.alerts()
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -3,17 +3,23 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"runtime/debug"
 	"syscall"
 	"time"
 
 	"licet/internal/config"
 	"licet/internal/database"
+	"licet/internal/grpcapi"
 	"licet/internal/handlers"
 	appmiddleware "licet/internal/middleware"
+	"licet/internal/models"
 	"licet/internal/scheduler"
 	"licet/internal/services"
 	"licet/web"
@@ -24,10 +30,58 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// Version is set at build time via ldflags
-var Version = "dev"
+// Build metadata, set at build time via ldflags
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// buildInfo returns the build metadata, falling back to the VCS details the
+// Go toolchain embeds when the binary was built without ldflags
+func buildInfo() models.BuildInfo {
+	build := models.BuildInfo{Version: Version, Commit: Commit, BuildDate: BuildDate}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if build.Commit == "" {
+					build.Commit = setting.Value
+				}
+			case "vcs.time":
+				if build.BuildDate == "" {
+					build.BuildDate = setting.Value
+				}
+			}
+		}
+	}
+	if build.Commit == "" {
+		build.Commit = "unknown"
+	}
+	if build.BuildDate == "" {
+		build.BuildDate = "unknown"
+	}
+	return build
+}
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "recompute" {
+		err := runRecompute(os.Args[2:], os.Stdout)
+		if err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "recompute: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && (os.Args[1] == "--selftest" || os.Args[1] == "-selftest") {
+		err := runSelftest(os.Args[2:], os.Stdout)
+		if err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "selftest: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -37,7 +91,8 @@ func main() {
 	// Setup logging
 	setupLogging(cfg)
 
-	log.WithField("version", Version).Info("Starting Licet")
+	build := buildInfo()
+	log.WithFields(log.Fields{"version": build.Version, "commit": build.Commit}).Info("Starting Licet")
 
 	// Initialize database
 	db, err := database.New(cfg.Database)
@@ -51,22 +106,135 @@ func main() {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	// Field encryption for stored usernames
+	fieldCipher, err := services.NewFieldCipher(cfg.Encryption)
+	if err != nil {
+		log.Fatalf("Failed to configure encryption: %v", err)
+	}
+	if _, err := fieldCipher.EncryptExisting(context.Background(), db); err != nil {
+		log.Fatalf("Failed to encrypt existing usernames: %v", err)
+	}
+
 	// Initialize services (direct service creation - no facade)
 	dbType := cfg.Database.Type
 	storage := services.NewStorageService(db, dbType)
+	storage.SetCipher(fieldCipher)
+	holidays, err := services.NewHolidayCalendar(cfg.Holidays)
+	if err != nil {
+		log.Fatalf("Failed to load holiday calendars: %v", err)
+	}
+	storage.SetHolidays(holidays)
+	hostnames := make([]string, 0, len(cfg.Servers))
+	for _, srv := range cfg.Servers {
+		hostnames = append(hostnames, srv.Hostname)
+	}
+	if err := storage.RegisterExistingResources(context.Background(), hostnames); err != nil {
+		log.Warnf("Failed to register resource ids: %v", err)
+	}
+	if err := storage.SyncServers(context.Background(), cfg.Servers); err != nil {
+		log.Warnf("Failed to store server groups: %v", err)
+	}
 	query := services.NewQueryService(cfg, storage)
+	var chaos *services.ChaosService
+	if cfg.Chaos.Enabled {
+		chaos = services.NewChaosService(cfg.Chaos)
+		query.SetChaos(chaos)
+		log.Warnf("Chaos mode is enabled: faults can be injected into %v", cfg.Chaos.Servers)
+	}
 	analytics := services.NewAnalyticsService(db, storage, dbType)
+	if err := analytics.SetAnomalyConfig(cfg.Anomalies); err != nil {
+		log.Fatalf("Failed to configure anomaly detection: %v", err)
+	}
 	enhancedAnalytics := services.NewEnhancedAnalyticsService(db, storage, dbType)
 	alertService := services.NewAlertService(db, cfg)
 	collectorService := services.NewCollectorService(db, cfg, query, storage)
+	bus := services.NewEventBus()
+	collectorService.SetEventBus(bus)
+	alertService.SetEventBus(bus)
+	alertService.SetCipher(fieldCipher)
+	computedMetrics := services.NewComputedMetricService(db, storage)
+	dataQuality := services.NewDataQualityService(db, cfg, storage)
+	collectorService.SetComputedMetrics(computedMetrics)
+	if cfg.Tracing.Enabled {
+		tracer := services.NewTracer(cfg.Tracing)
+		collectorService.SetTracer(tracer)
+		tracer.Start()
+		defer tracer.Stop()
+	}
+	// Abandon queries in flight on shutdown
+	defer collectorService.Stop()
+	webhooks := services.NewWebhookService(db, cfg)
 	dbStats := services.NewDBStatsService(db, cfg.Database)
+	events := services.NewEventService(db, dbType)
+	events.SetCipher(fieldCipher)
+	displayNames, err := services.NewDisplayNameService(db, cfg.Display)
+	if err != nil {
+		log.Fatalf("Failed to load display names: %v", err)
+	}
+	displayNames.SetServerProfiles(cfg.Servers)
+	if err := displayNames.Reload(context.Background()); err != nil {
+		log.Warnf("Failed to load display name overrides: %v", err)
+	}
+
+	userDigest, err := services.NewUserDigestService(db, cfg, storage)
+	if err != nil {
+		log.Fatalf("Failed to configure user digest: %v", err)
+	}
+
+	reports, err := services.NewReportService(db, cfg, enhancedAnalytics)
+	if err != nil {
+		log.Fatalf("Failed to configure scheduled reports: %v", err)
+	}
+
+	entitlements, err := services.NewEntitlementService(db, cfg, storage)
+	if err != nil {
+		log.Fatalf("Failed to configure entitlement sources: %v", err)
+	}
+
+	chargeback, err := services.NewChargebackService(cfg, storage)
+	if err != nil {
+		log.Fatalf("Failed to configure chargeback: %v", err)
+	}
+
+	redactor := services.NewRedactor(cfg.Privacy)
+	anonymizer := services.NewAnonymizeService(db, cfg)
+	anonymizer.SetCipher(fieldCipher)
+	audit := services.NewAuditService(db)
+	audit.SetEventBus(bus)
+
+	flags := services.NewFlagService(db, cfg)
+	if err := flags.Load(context.Background()); err != nil {
+		log.Errorf("Failed to load feature flags, using configured values: %v", err)
+	}
 
 	// Initialize scheduler for background tasks
-	sched := scheduler.New(cfg, collectorService, alertService)
+	sched := scheduler.New(cfg, collectorService, alertService, enhancedAnalytics, flags, reports, entitlements, dbStats)
+	sched.SetChargeback(chargeback)
+	sched.SetLogTailer(services.NewLogTailer(cfg.LogTail, events))
+
+	// Run long work such as refreshes, backfills and exports on the job queue
+	jobs := services.NewJobQueue(db, cfg.Jobs)
+	jobs.Register(services.JobBackfill, 0, handlers.BackfillJob(cfg, storage))
+	sched.SetJobQueue(jobs)
+	if err := jobs.Start(); err != nil {
+		log.Fatalf("Failed to start job queue: %v", err)
+	}
+	defer jobs.Stop()
+
 	sched.Start()
 	defer sched.Stop()
 
-	// Initialize WebSocket hub
+	// Send the email queued in the database with email.queue enabled
+	outbox := services.NewEmailOutbox(db, cfg)
+	outbox.Start()
+	defer outbox.Stop()
+
+	// Watch for stalled collection independently of the scheduler
+	watchdog := services.NewWatchdog(cfg, collectorService, alertService)
+	watchdog.Start()
+	defer watchdog.Stop()
+
+	// Initialize WebSocket hub and the live feed it shares with the event stream
 	var wsHub *handlers.WebSocketHub
 	if cfg.WebSocket.Enabled {
 		wsConfig := handlers.WebSocketConfig{
@@ -76,8 +244,13 @@ func main() {
 			MaxConnections:  cfg.WebSocket.MaxConnections,
 			ReadBufferSize:  cfg.WebSocket.ReadBufferSize,
 			WriteBufferSize: cfg.WebSocket.WriteBufferSize,
+			AllowedOrigins:  cfg.AllowedOrigins(),
 		}
-		wsHub = handlers.NewWebSocketHub(wsConfig, query, storage, alertService)
+		feed := handlers.NewLiveFeed(bus, wsConfig, redactor)
+		feedCtx, stopFeed := context.WithCancel(context.Background())
+		go feed.Run(feedCtx)
+		defer stopFeed()
+		wsHub = handlers.NewWebSocketHub(wsConfig, feed)
 		go wsHub.Run()
 		defer wsHub.Stop()
 		log.WithFields(log.Fields{
@@ -86,8 +259,43 @@ func main() {
 		}).Info("WebSocket support enabled")
 	}
 
+	// Answer Slack slash commands from the latest collections
+	var slackBot *handlers.SlackBot
+	if cfg.Slack.Enabled {
+		slackBot = handlers.NewSlackBot(cfg.Slack, bus, redactor, displayNames)
+		slackCtx, stopSlack := context.WithCancel(context.Background())
+		go slackBot.Run(slackCtx)
+		defer stopSlack()
+		log.Info("Slack slash commands enabled")
+	}
+
 	// Setup HTTP router
-	r := setupRouter(cfg, query, storage, analytics, enhancedAnalytics, alertService, dbStats, wsHub, Version)
+	r := setupRouter(cfg, query, storage, analytics, enhancedAnalytics, alertService, dbStats, events, displayNames, userDigest, reports, entitlements, computedMetrics, dataQuality, redactor, anonymizer, audit, collectorService, sched, jobs, bus, webhooks, outbox, flags, chaos, wsHub, slackBot, build)
+
+	// Serve the licet.v1 gRPC service on a port of its own
+	if cfg.GRPC.Enabled {
+		var grpcAuth *appmiddleware.Authenticator
+		if cfg.Auth.Enabled {
+			grpcAuth = appmiddleware.NewAuthenticator(cfg.Auth)
+			defer grpcAuth.Stop()
+		}
+		grpcServer, err := grpcapi.NewServer(cfg, grpcAuth, grpcapi.NewService(query, storage, analytics, displayNames, bus))
+		if err != nil {
+			log.Fatalf("Failed to set up the gRPC server: %v", err)
+		}
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPC.Port))
+		if err != nil {
+			log.Fatalf("Failed to listen for gRPC: %v", err)
+		}
+		go func() {
+			log.Infof("gRPC service listening on %s", lis.Addr())
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Errorf("gRPC server stopped: %v", err)
+			}
+		}()
+		// Event streams only end when their clients cancel them
+		defer grpcServer.Stop()
+	}
 
 	// Start HTTP/HTTPS server
 	srv := &http.Server{
@@ -160,7 +368,10 @@ func setupLogging(cfg *config.Config) {
 	}
 }
 
-func setupRouter(cfg *config.Config, query *services.QueryService, storage *services.StorageService, analytics *services.AnalyticsService, enhancedAnalytics *services.EnhancedAnalyticsService, alertService *services.AlertService, dbStats *services.DBStatsService, wsHub *handlers.WebSocketHub, version string) *chi.Mux {
+func setupRouter(cfg *config.Config, query *services.QueryService, storage *services.StorageService, analytics *services.AnalyticsService, enhancedAnalytics *services.EnhancedAnalyticsService, alertService *services.AlertService, dbStats *services.DBStatsService, events *services.EventService, displayNames *services.DisplayNameService, userDigest *services.UserDigestService, reports *services.ReportService, entitlements *services.EntitlementService, computedMetrics *services.ComputedMetricService, dataQuality *services.DataQualityService, redactor *services.Redactor, anonymizer *services.AnonymizeService, audit *services.AuditService, collector *services.CollectorService, sched *scheduler.Scheduler, jobs *services.JobQueue, bus *services.EventBus, webhooks *services.WebhookService, outbox *services.EmailOutbox, flags *services.FlagService, chaos *services.ChaosService, wsHub *handlers.WebSocketHub, slackBot *handlers.SlackBot, build models.BuildInfo) *chi.Mux {
+	version := build.Version
+	startedAt := time.Now()
+
 	r := chi.NewRouter()
 
 	// Middleware
@@ -174,14 +385,35 @@ func setupRouter(cfg *config.Config, query *services.QueryService, storage *serv
 	var authenticator *appmiddleware.Authenticator
 	if cfg.Auth.Enabled {
 		authenticator = appmiddleware.NewAuthenticator(cfg.Auth)
+		if cfg.Auth.OIDC.Enabled {
+			if err := authenticator.EnableOIDC(context.Background()); err != nil {
+				log.Fatalf("Failed to set up OIDC sign-in: %v", err)
+			}
+		}
+		authenticator.SetRoutePermissions(handlers.RoutePermission)
+		if cfg.Slack.Enabled {
+			// Slack signs its requests instead of authenticating
+			authenticator.ExemptPath("/api/v1/slack/")
+		}
+		if cfg.PublicFeed.Enabled {
+			// The public feed is published to anyone by design
+			authenticator.ExemptExactPath(cfg.PublicFeed.Path)
+		}
 		r.Use(appmiddleware.AuthMiddleware(authenticator))
 		log.WithFields(log.Fields{
 			"api_keys_count": len(cfg.Auth.APIKeys),
 			"basic_auth":     cfg.Auth.BasicAuth.Enabled,
+			"oidc":           cfg.Auth.OIDC.Enabled,
 		}).Info("Authentication enabled")
 	}
 
+	// Audit mutating API requests, after authentication to record the user
+	if audit != nil && cfg.Audit.Enabled {
+		r.Use(appmiddleware.AuditMiddleware(audit, cfg.Audit.ExcludePaths))
+	}
+
 	// Rate limiting middleware
+	var rateLimiter *appmiddleware.RateLimiter
 	if cfg.RateLimit.Enabled {
 		rateLimitConfig := appmiddleware.RateLimitConfig{
 			RequestsPerMinute: cfg.RateLimit.RequestsPerMinute,
@@ -190,7 +422,7 @@ func setupRouter(cfg *config.Config, query *services.QueryService, storage *serv
 			WhitelistedIPs:    cfg.RateLimit.WhitelistedIPs,
 			WhitelistedPaths:  cfg.RateLimit.WhitelistedPaths,
 		}
-		rateLimiter := appmiddleware.NewRateLimiter(rateLimitConfig)
+		rateLimiter = appmiddleware.NewRateLimiter(rateLimitConfig)
 		r.Use(appmiddleware.RateLimitMiddleware(rateLimiter))
 		log.WithFields(log.Fields{
 			"requests_per_minute": rateLimitConfig.RequestsPerMinute,
@@ -206,20 +438,41 @@ func setupRouter(cfg *config.Config, query *services.QueryService, storage *serv
 			MaxEntries: cfg.Cache.MaxEntries,
 			Enabled:    cfg.Cache.Enabled,
 		}
-		cache = appmiddleware.NewCache(cacheConfig)
+		if cfg.Cache.Backend == "redis" {
+			backend, err := appmiddleware.NewRedisCacheBackend(appmiddleware.RedisCacheConfig{
+				Address:  cfg.Cache.Redis.Address,
+				Password: cfg.Cache.Redis.Password,
+				DB:       cfg.Cache.Redis.DB,
+				Prefix:   cfg.Cache.Redis.Prefix,
+			})
+			if err != nil {
+				log.Fatalf("Failed to set up the response cache: %v", err)
+			}
+			cache = appmiddleware.NewCacheWithBackend(cacheConfig, backend)
+		} else {
+			cache = appmiddleware.NewCache(cacheConfig)
+		}
 		log.WithFields(log.Fields{
+			"backend":     cache.Stats()["backend"],
 			"ttl_seconds": cfg.Cache.TTLSeconds,
 			"max_entries": cfg.Cache.MaxEntries,
 		}).Info("Response caching enabled")
+
+		// Responses built from collected data are stale once a collection
+		// finishes
+		if collector != nil {
+			collector.SetCache(cache,
+				"/api/v1/servers", "/api/v1/features", "/api/v1/utilization", "/api/v1/statistics",
+				"/api/v1/users", "/api/v1/checkouts", "/api/v1/denials", "/api/v1/failovers",
+				"/api/v1/alerts", "/api/v1/incidents",
+				"/api/v2/servers", "/api/v2/features", "/api/v2/utilization", "/api/v2/statistics",
+				"/api/v2/failovers", "/api/v2/alerts", "/api/v2/incidents")
+		}
 	}
 
 	// CORS - use configured origins or default to localhost
-	corsOrigins := cfg.Server.CORSOrigins
-	if len(corsOrigins) == 0 {
-		corsOrigins = []string{fmt.Sprintf("http://localhost:%d", cfg.Server.Port)}
-	}
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   corsOrigins,
+		AllowedOrigins:   cfg.AllowedOrigins(),
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
 		ExposedHeaders:   []string{"Link"},
@@ -231,15 +484,41 @@ func setupRouter(cfg *config.Config, query *services.QueryService, storage *serv
 	staticFS := web.GetStaticFS()
 	fileServer := http.FileServer(http.FS(staticFS))
 	r.Handle("/static/*", http.StripPrefix("/static/", fileServer))
+	if logo := cfg.Branding.LogoFile; logo != "" {
+		r.Get("/branding/logo", func(w http.ResponseWriter, r *http.Request) {
+			http.ServeFile(w, r, logo)
+		})
+	}
 
-	// WebSocket endpoint
+	// Prometheus metrics
+	if cfg.Metrics.Enabled {
+		r.Get(cfg.Metrics.Path, handlers.Metrics(storage, collector, cache, rateLimiter))
+		log.WithField("path", cfg.Metrics.Path).Info("Prometheus metrics enabled")
+	}
+
+	// Public availability feed, without authentication
+	if cfg.PublicFeed.Enabled {
+		r.Method(http.MethodGet, cfg.PublicFeed.Path, handlers.NewPublicFeed(cfg.PublicFeed, analytics, displayNames))
+		log.WithField("path", cfg.PublicFeed.Path).Info("Public availability feed enabled")
+	}
+
+	// WebSocket and Server-Sent Events endpoints
 	if wsHub != nil {
+		wsHub.Feed().SetAuthenticator(authenticator)
 		r.Get("/ws", handlers.WebSocketHandler(wsHub))
 		r.Get("/api/v1/ws/stats", handlers.WebSocketStatsHandler(wsHub))
+		r.Get("/api/v1/stream", handlers.Stream(wsHub.Feed()))
+	}
+
+	// OIDC sign-in
+	if authenticator != nil && cfg.Auth.OIDC.Enabled {
+		r.Get(appmiddleware.OIDCLoginPath, authenticator.OIDCLogin)
+		r.Get(appmiddleware.OIDCCallbackPath, authenticator.OIDCCallback)
+		r.Get(appmiddleware.OIDCLogoutPath, authenticator.OIDCLogout)
 	}
 
 	// Web handlers
-	webHandler := handlers.NewWebHandler(query, storage, analytics, alertService, cfg, version)
+	webHandler := handlers.NewWebHandler(query, storage, analytics, displayNames, alertService, redactor, cfg, version)
 	r.Get("/", webHandler.Index)
 	r.Get("/details/{server}", webHandler.Details)
 	r.Get("/expiration/{server}", webHandler.Expiration)
@@ -253,67 +532,255 @@ func setupRouter(cfg *config.Config, query *services.QueryService, storage *serv
 	r.Get("/database", webHandler.DatabaseStats)
 	r.Get("/settings", webHandler.Settings)
 
-	// API routes
+	// API routes. The OpenAPI document is built from them on first request.
+	openAPI := handlers.OpenAPI(r, version)
+	// Exports and archives run as jobs when requested with async=true
+	async := handlers.NewAsyncRunner(jobs)
+
 	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(handlers.ResolveResourceIDs(storage))
+		r.Use(handlers.QueryGuardrails(cfg.QueryLimits, storage))
+
 		// Read-only API endpoints -- optionally cached
 		r.Group(func(r chi.Router) {
 			if cache != nil {
 				r.Use(appmiddleware.CacheMiddleware(cache, time.Duration(cfg.Cache.TTLSeconds)*time.Second))
 			}
 
-			r.Get("/servers", handlers.ListServers(query))
+			r.Get("/servers", handlers.ListServers(query, storage))
+			r.Get("/resources/{id}", handlers.GetResource(storage))
 			r.Get("/servers/{server}/status", handlers.GetServerStatus(query))
-			r.Get("/servers/{server}/features", handlers.GetServerFeatures(storage))
-			r.Get("/servers/{server}/users", handlers.GetServerUsers(query))
-			r.Get("/features/{feature}/usage", handlers.GetFeatureUsage(storage))
+			r.Get("/servers/{server}/failovers", handlers.GetFailovers(storage))
+			r.Get("/servers/compare", handlers.CompareServers(query, storage))
+			r.Get("/failovers", handlers.GetFailovers(storage))
 			r.Get("/alerts", handlers.GetAlerts(alertService))
+			r.Get("/alerts/{id}/deliveries", handlers.GetAlertDeliveries(alertService))
+			r.Get("/incidents", handlers.GetIncidents(alertService))
+			r.Get("/incidents/{id}", handlers.GetIncident(alertService))
+
+			// Database statistics endpoints (read-only)
+			r.Get("/database/stats", handlers.GetDatabaseStats(dbStats))
+			r.Get("/database/retention", handlers.GetRetentionStats(dbStats))
+			r.Get("/database/retention/runs", handlers.GetRetentionRuns(cfg, dbStats))
+		})
+
+		// Endpoints whose usernames are redacted by the caller's role -- never
+		// cached, as the cache key does not carry the role
+		r.Group(func(r chi.Router) {
+			r.Get("/servers/{server}/users", handlers.GetServerUsers(query, redactor))
+			r.Get("/users/digest", handlers.GetUserDigest(userDigest, redactor))
+			r.Get("/users/{username}/usage", handlers.GetUserUsage(storage, redactor))
+			r.Get("/features/{feature}/top-users", handlers.GetTopUsers(storage, redactor))
+			r.Get("/checkouts/long", handlers.GetLongCheckouts(cfg, storage, redactor))
+			r.Get("/denials", handlers.GetDenials(events, redactor))
+		})
+
+		// Endpoints backed by collected data -- answer conditional requests
+		// before the response cache so unchanged data is served as 304
+		r.Group(func(r chi.Router) {
+			if cfg.Cache.ConditionalRequests {
+				r.Use(appmiddleware.ConditionalMiddleware(storage.GetDataVersions))
+			}
+			if cache != nil {
+				r.Use(appmiddleware.CacheMiddleware(cache, time.Duration(cfg.Cache.TTLSeconds)*time.Second))
+			}
+
+			r.Get("/servers/{server}/features", handlers.GetServerFeatures(storage, displayNames))
+			r.Get("/features/{feature}/usage", handlers.GetFeatureUsage(storage))
+			r.Get("/features/parse-quality", handlers.GetDegradedFeatures(storage, displayNames))
 
 			// Utilization endpoints
-			r.Get("/utilization/current", handlers.GetCurrentUtilization(analytics))
+			r.Get("/utilization/current", handlers.GetCurrentUtilization(analytics, displayNames))
 			r.Get("/utilization/history", handlers.GetUtilizationHistory(analytics))
-			r.Get("/utilization/stats", handlers.GetUtilizationStats(analytics))
+			r.Get("/utilization/stats", handlers.GetUtilizationStats(analytics, displayNames))
 			r.Get("/utilization/heatmap", handlers.GetUtilizationHeatmap(analytics))
+			r.Get("/utilization/seasonality", handlers.GetSeasonalPatterns(analytics))
+			r.Get("/utilization/token-pools", handlers.GetTokenPools(storage))
+			r.Get("/utilization/products", handlers.GetMLMProducts(cfg, storage))
 			r.Get("/utilization/predictions", handlers.GetPredictiveAnalytics(analytics))
 
 			// Enhanced statistics endpoints
 			r.Get("/statistics/enhanced", handlers.GetEnhancedStatistics(enhancedAnalytics))
 			r.Get("/statistics/trends", handlers.GetTrendAnalysis(enhancedAnalytics))
 			r.Get("/statistics/capacity", handlers.GetCapacityPlanningReport(enhancedAnalytics))
-
-			// Database statistics endpoints (read-only)
-			r.Get("/database/stats", handlers.GetDatabaseStats(dbStats))
-			r.Get("/database/retention", handlers.GetRetentionStats(dbStats))
 		})
 
 		// Non-cached endpoints (mutations and health check)
-		r.Post("/servers", handlers.AddServer(cfg))
-		r.Delete("/servers", handlers.DeleteServer(cfg))
+		r.Post("/servers", handlers.AddServer(cfg, cache))
+		r.Delete("/servers", handlers.DeleteServer(cfg, cache))
 		r.Post("/servers/test", handlers.TestServerConnection(cfg, query))
+		r.Get("/servers/{server}/wait-for-update", handlers.WaitForUpdate(cfg, bus, redactor))
+		r.Post("/servers/{server}/refresh", handlers.RefreshServer(sched))
+		r.Get("/jobs", handlers.ListJobs(jobs))
+		r.Get("/jobs/{id}", handlers.GetJob(jobs))
+		r.Delete("/jobs/{id}", handlers.CancelJob(cfg, jobs))
+		r.Get("/jobs/{id}/download", handlers.DownloadJobOutput(cfg, jobs))
+		r.Get("/collection/polls", handlers.GetCollectionPolls(collector))
 		r.Get("/utilities/check", handlers.CheckUtilities())
 		r.Post("/settings/email", handlers.UpdateEmailSettings(cfg))
 		r.Post("/settings/alerts", handlers.UpdateAlertSettings(cfg))
+
+		// Anomalies marked as expected
+		r.Get("/utilization/anomalies/expected", handlers.ListExpectedAnomalies(analytics))
+		r.Post("/utilization/anomalies/expected", handlers.MarkAnomalyExpected(analytics))
+		r.Delete("/utilization/anomalies/expected", handlers.UnmarkAnomalyExpected(analytics))
+
+		// Alertmanager silences suppressing matching alerts
+		r.Get("/alerts/silences", handlers.ListSilences(alertService))
+		r.Post("/alerts/silences", handlers.ReceiveSilences(alertService))
+		r.Post("/alerts/silences/sync", handlers.SyncSilences(alertService))
+		r.Delete("/alerts/silences/{id}", handlers.DeleteSilence(alertService))
+
+		// Per-feature utilization alert thresholds
+		r.Get("/alert-thresholds", handlers.ListAlertThresholds(cfg, alertService))
+		r.Put("/alert-thresholds", handlers.SetAlertThreshold(cfg, alertService))
+		r.Delete("/alert-thresholds", handlers.DeleteAlertThreshold(cfg, alertService))
+
+		// Seat waitlist: users are emailed once when a seat frees up
+		if cfg.Waitlist.Enabled {
+			r.Get("/waitlist", handlers.ListWaitlist(cfg, alertService))
+			r.Post("/waitlist", handlers.JoinWaitlist(cfg, alertService))
+			r.Delete("/waitlist/{id}", handlers.LeaveWaitlist(cfg, alertService))
+		}
+
+		// Outbound webhook delivery log
+		r.Get("/webhooks/deliveries", handlers.ListWebhookDeliveries(webhooks))
+
+		// Alert rules evaluated after each collection
+		r.Get("/alert-rules", handlers.ListAlertRules(alertService))
+		r.Post("/alert-rules", handlers.CreateAlertRule(cfg, alertService))
+		r.Get("/alert-rules/{id}", handlers.GetAlertRule(alertService))
+		r.Put("/alert-rules/{id}", handlers.UpdateAlertRule(cfg, alertService))
+		r.Delete("/alert-rules/{id}", handlers.DeleteAlertRule(cfg, alertService))
+
+		// Cost and contract details of features, weighing recommendations
+		r.Get("/contracts", handlers.ListContracts(storage))
+		r.Post("/contracts", handlers.CreateContract(cfg, storage))
+		r.Get("/contracts/{id}", handlers.GetContract(storage))
+		r.Put("/contracts/{id}", handlers.UpdateContract(cfg, storage))
+		r.Delete("/contracts/{id}", handlers.DeleteContract(cfg, storage))
+
+		// Feature display name overrides
+		r.Get("/display-names", handlers.ListDisplayNames(displayNames))
+		r.Put("/display-names", handlers.SetDisplayName(cfg, displayNames, cache))
+		r.Delete("/display-names", handlers.DeleteDisplayName(cfg, displayNames, cache))
+
+		// Feature tags, for reporting utilization per department
+		r.Get("/tags", handlers.ListTags(storage))
+		r.Get("/feature-tags", handlers.ListFeatureTags(storage))
+		r.Post("/feature-tags", handlers.TagFeature(cfg, storage, cache))
+		r.Delete("/feature-tags/{id}", handlers.UntagFeature(cfg, storage, cache))
 		r.Get("/health", handlers.Health(version))
+		r.Get("/system/info", handlers.GetSystemInfo(cfg, build, flags, startedAt))
+		r.Get("/system/data-quality", handlers.GetDataQuality(dataQuality))
+		r.Get("/openapi.json", openAPI)
+
+		// Scheduled capacity reports
+		r.Get("/reports", handlers.ListReports(reports))
+		if cfg.Chargeback.Enabled {
+			r.Get("/reports/chargeback", handlers.GetChargebackReport(storage))
+		}
+		r.Get("/reports/{name}", handlers.GetReport(reports))
+		r.Post("/reports/{name}/send", handlers.SendReport(cfg, reports))
+
+		// Vendor portal entitlements
+		r.Get("/entitlements", handlers.GetEntitlements(entitlements))
+		r.Post("/entitlements/sync", handlers.SyncEntitlements(cfg, entitlements))
+		r.Get("/entitlements/reconciliation", handlers.GetReconciliation(entitlements))
+
+		// Computed metrics, stored as features of the "computed" server
+		r.Get("/computed-metrics", handlers.ListComputedMetrics(computedMetrics))
+		r.Post("/computed-metrics", handlers.CreateComputedMetric(cfg, computedMetrics))
+		r.Post("/computed-metrics/preview", handlers.PreviewComputedMetric(computedMetrics))
+		r.Get("/computed-metrics/{name}", handlers.GetComputedMetric(computedMetrics))
+		r.Put("/computed-metrics/{name}", handlers.UpdateComputedMetric(cfg, computedMetrics))
+		r.Delete("/computed-metrics/{name}", handlers.DeleteComputedMetric(cfg, computedMetrics))
 
 		// Database maintenance endpoints (mutations - require settings to be enabled)
 		r.Post("/database/vacuum", handlers.VacuumDatabase(dbStats))
 		r.Post("/database/cleanup", handlers.CleanupOldData(dbStats))
+		r.Post("/database/retention/runs", handlers.RunRetention(cfg, dbStats))
 		r.Post("/database/analyze", handlers.AnalyzeDatabase(dbStats))
 		r.Post("/database/checkpoint", handlers.CheckpointWAL(dbStats))
+		r.Post("/database/dedup", handlers.DeduplicateFeatures(storage))
+
+		// Recompute historical aggregates (admin role when auth is enabled)
+		r.Post("/admin/backfill", handlers.BackfillUsage(cfg, jobs))
+
+		// Database maintenance for automation (admin role when auth is enabled)
+		r.Route("/admin/db", func(r chi.Router) {
+			r.Use(handlers.RequireAdmin(cfg))
+			r.Get("/stats", handlers.GetDatabaseStats(dbStats))
+			r.Post("/vacuum", handlers.VacuumDatabase(dbStats))
+			r.Post("/analyze", handlers.AnalyzeDatabase(dbStats))
+			r.Post("/cleanup", handlers.CleanupOldData(dbStats))
+		})
+
+		// Privacy administration (admin role when auth is enabled)
+		r.Post("/admin/anonymize", handlers.AnonymizeUser(cfg, anonymizer))
+		r.Get("/admin/audit", handlers.GetAuditLog(cfg, audit))
+
+		// Server history migration between instances
+		r.With(handlers.RequireFlag(flags, services.FlagSnapshots)).Get("/admin/snapshot", async.Handle(services.JobArchive, "snapshot", handlers.ExportSnapshot(cfg, storage)))
+		r.With(handlers.RequireFlag(flags, services.FlagSnapshots)).Post("/admin/snapshot", handlers.ImportSnapshot(cfg, storage))
+
+		// Outgoing email queued with email.queue enabled
+		r.Group(func(r chi.Router) {
+			r.Use(handlers.RequireAdmin(cfg))
+			r.Get("/admin/outbox", handlers.ListOutboxEmails(outbox))
+			r.Post("/admin/outbox/{id}/requeue", handlers.RequeueOutboxEmail(outbox))
+		})
+
+		// Feature flags of experimental subsystems
+		r.Get("/admin/flags", handlers.ListFeatureFlags(flags))
+		r.Put("/admin/flags/{name}", handlers.SetFeatureFlag(cfg, flags))
+		r.Delete("/admin/flags/{name}", handlers.ResetFeatureFlag(cfg, flags))
+
+		// Synthetic server failures for testing in staging (chaos.enabled)
+		if cfg.Chaos.Enabled {
+			r.Group(func(r chi.Router) {
+				r.Use(handlers.RequireAdmin(cfg))
+				r.Get("/admin/chaos", handlers.ListChaosFaults(chaos))
+				r.Put("/admin/chaos/{server}", handlers.InjectChaosFault(chaos))
+				r.Delete("/admin/chaos/{server}", handlers.ClearChaosFault(chaos))
+			})
+		}
 
 		// Export endpoints
 		if cfg.Export.Enabled {
-			exportHandler := handlers.NewExportHandler(query, storage, analytics)
+			exportHandler := handlers.NewExportHandler(cfg, query, storage, analytics, enhancedAnalytics, displayNames)
 			r.Route("/export", func(r chi.Router) {
-				r.Get("/servers", exportHandler.ExportServers)
-				r.Get("/features", exportHandler.ExportFeatures)
-				r.Get("/utilization", exportHandler.ExportUtilization)
-				r.Get("/utilization/history", exportHandler.ExportUtilizationHistory)
-				r.Get("/stats", exportHandler.ExportStats)
-				r.Get("/report", exportHandler.ExportReport)
+				r.Get("/servers", async.Handle(services.JobExport, "export/servers", exportHandler.ExportServers))
+				r.Get("/features", async.Handle(services.JobExport, "export/features", exportHandler.ExportFeatures))
+				r.Get("/utilization", async.Handle(services.JobExport, "export/utilization", exportHandler.ExportUtilization))
+				r.Get("/utilization/history", async.Handle(services.JobExport, "export/utilization/history", exportHandler.ExportUtilizationHistory))
+				r.Get("/stats", async.Handle(services.JobExport, "export/stats", exportHandler.ExportStats))
+				r.Get("/report", async.Handle(services.JobExport, "export/report", exportHandler.ExportReport))
+				r.Get("/expirations.ics", exportHandler.ExportExpirationsICS)
+				r.With(handlers.RequireFlag(flags, services.FlagBudgetForecast)).Get("/forecast", async.Handle(services.JobExport, "export/forecast", exportHandler.ExportForecast))
+				if cfg.Chargeback.Enabled {
+					r.Get("/chargeback", async.Handle(services.JobExport, "export/chargeback", exportHandler.ExportChargeback))
+				}
 			})
 			log.Info("Data export endpoints enabled")
 		}
 
+		// Slack slash commands, verified by their signature
+		if cfg.Slack.Enabled {
+			r.Post("/slack/commands", handlers.SlackCommand(slackBot))
+		}
+
+		// Log ingest webhook for log shippers
+		if cfg.Ingest.Enabled {
+			if cfg.Ingest.Token == "" && !cfg.Auth.Enabled {
+				log.Warn("Log ingest enabled without a token or authentication; endpoint not registered")
+			} else {
+				r.Post("/ingest/logs", handlers.IngestLogs(cfg, events))
+				r.Post("/ingest/reportlog", handlers.IngestReportLog(cfg, storage, events))
+				log.Info("Log ingest endpoint enabled")
+			}
+		}
+
 		// Cache stats endpoint (for monitoring)
 		if cache != nil {
 			r.Get("/cache/stats", func(w http.ResponseWriter, req *http.Request) {
@@ -330,5 +797,52 @@ func setupRouter(cfg *config.Config, query *services.QueryService, storage *serv
 		})
 	})
 
+	// v2 API routes -- read-only endpoints with a uniform {data, meta} envelope
+	r.Route("/api/v2", func(r chi.Router) {
+		r.Use(handlers.ResolveResourceIDs(storage))
+		r.Use(handlers.QueryGuardrails(cfg.QueryLimits, storage))
+
+		r.Group(func(r chi.Router) {
+			if cache != nil {
+				r.Use(appmiddleware.CacheMiddleware(cache, time.Duration(cfg.Cache.TTLSeconds)*time.Second))
+			}
+
+			r.Get("/servers", handlers.V2ListServers(query, storage))
+			r.Get("/servers/{server}/status", handlers.V2GetServerStatus(query))
+			r.Get("/servers/{server}/failovers", handlers.V2GetFailovers(storage))
+			r.Get("/failovers", handlers.V2GetFailovers(storage))
+			r.Get("/alerts", handlers.V2GetAlerts(alertService))
+			r.Get("/incidents", handlers.V2GetIncidents(alertService))
+			r.Get("/incidents/{id}", handlers.V2GetIncident(alertService))
+			r.Get("/database/stats", handlers.V2GetDatabaseStats(dbStats))
+			r.Get("/database/retention", handlers.V2GetRetentionStats(dbStats))
+		})
+
+		// Redacted by the caller's role -- never cached
+		r.Get("/servers/{server}/users", handlers.V2GetServerUsers(query, redactor))
+
+		r.Group(func(r chi.Router) {
+			if cfg.Cache.ConditionalRequests {
+				r.Use(appmiddleware.ConditionalMiddleware(storage.GetDataVersions))
+			}
+			if cache != nil {
+				r.Use(appmiddleware.CacheMiddleware(cache, time.Duration(cfg.Cache.TTLSeconds)*time.Second))
+			}
+
+			r.Get("/servers/{server}/features", handlers.V2GetServerFeatures(storage, displayNames))
+			r.Get("/features/{feature}/usage", handlers.V2GetFeatureUsage(storage))
+			r.Get("/utilization/current", handlers.V2GetCurrentUtilization(analytics, displayNames))
+			r.Get("/utilization/history", handlers.V2GetUtilizationHistory(analytics))
+			r.Get("/utilization/stats", handlers.V2GetUtilizationStats(analytics, displayNames))
+			r.Get("/utilization/heatmap", handlers.V2GetUtilizationHeatmap(analytics))
+			r.Get("/utilization/predictions", handlers.V2GetPredictiveAnalytics(analytics))
+			r.Get("/statistics/enhanced", handlers.V2GetEnhancedStatistics(enhancedAnalytics))
+			r.Get("/statistics/trends", handlers.V2GetTrendAnalysis(enhancedAnalytics))
+			r.Get("/statistics/capacity", handlers.V2GetCapacityPlanningReport(enhancedAnalytics))
+		})
+
+		r.Get("/health", handlers.V2Health(version))
+	})
+
 	return r
 }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/config"
+	"licet/internal/handlers"
+	"licet/internal/middleware"
+	"licet/internal/models"
+)
+
+// routerWithAllSubsystems returns the router with every optional subsystem
+// enabled, so all API routes are registered
+func routerWithAllSubsystems() *chi.Mux {
+	cfg := &config.Config{}
+	cfg.Server.SettingsEnabled = true
+	cfg.Server.UtilizationEnabled = true
+	cfg.Server.StatisticsEnabled = true
+	cfg.Export.Enabled = true
+	cfg.WebSocket.Enabled = true
+	cfg.Ingest.Enabled = true
+	cfg.Ingest.Token = "secret"
+	cfg.Metrics.Enabled = true
+	cfg.Metrics.Path = "/metrics"
+	cfg.Chaos.Enabled = true
+	cfg.Waitlist.Enabled = true
+	cfg.Slack.Enabled = true
+	cfg.Chargeback.Enabled = true
+	return setupRouter(cfg, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, models.BuildInfo{Version: "test"})
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	undocumented, stale, err := handlers.UndocumentedRoutes(routerWithAllSubsystems())
+	if err != nil {
+		t.Fatalf("Failed to walk routes: %v", err)
+	}
+	for _, route := range undocumented {
+		t.Errorf("Route %s has no entry in the OpenAPI operation registry", route)
+	}
+	for _, key := range stale {
+		t.Errorf("OpenAPI operation %s has no route", key)
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	rec := httptest.NewRecorder()
+	routerWithAllSubsystems().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Parameters  []struct {
+				Name     string `json:"name"`
+				In       string `json:"in"`
+				Required bool   `json:"required"`
+			} `json:"parameters"`
+		} `json:"paths"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+	if doc.OpenAPI != "3.0.3" || doc.Info.Version != "test" {
+		t.Errorf("Unexpected document header %+v", doc)
+	}
+
+	op, ok := doc.Paths["/api/v1/servers/{server}/status"]["get"]
+	if !ok {
+		t.Fatalf("Expected the server status operation, got paths %v", doc.Paths)
+	}
+	if op.OperationID != "get_api_v1_servers_By_server_status" || len(op.Parameters) != 2 ||
+		op.Parameters[0].Name != "server" || op.Parameters[0].In != "path" || !op.Parameters[0].Required {
+		t.Errorf("Unexpected server status operation %+v", op)
+	}
+	if _, ok := doc.Paths["/api/v2/utilization/current"]["get"]; !ok {
+		t.Error("Expected v2 routes to be documented")
+	}
+	if _, ok := doc.Paths["/settings"]; ok {
+		t.Error("Expected web pages to be left out")
+	}
+
+	ids := make(map[string]bool)
+	for path, ops := range doc.Paths {
+		for method, op := range ops {
+			if ids[op.OperationID] {
+				t.Errorf("Duplicate operationId %s (%s %s)", op.OperationID, method, path)
+			}
+			ids[op.OperationID] = true
+		}
+	}
+}
+
+func TestRoutePermission(t *testing.T) {
+	router := routerWithAllSubsystems()
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodPost, "/api/v1/alert-rules", middleware.PermissionAlertsManage},
+		{http.MethodDelete, "/api/v1/alert-rules/7", middleware.PermissionAlertsManage},
+		{http.MethodPost, "/api/v1/servers/27000@flex1/refresh", middleware.PermissionServersWrite},
+		{http.MethodGet, "/api/v1/export/features", middleware.PermissionExportsRun},
+		{http.MethodPut, "/api/v1/display-names", middleware.PermissionSettingsWrite},
+		{http.MethodGet, "/api/v1/alert-rules", ""},
+		{http.MethodPost, "/api/v1/computed-metrics", ""},
+		{http.MethodGet, "/", ""},
+	}
+	for _, tt := range tests {
+		rctx := chi.NewRouteContext()
+		rctx.Routes = router
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		if got := handlers.RoutePermission(req); got != tt.want {
+			t.Errorf("%s %s: expected permission %q, got %q", tt.method, tt.path, tt.want, got)
+		}
+	}
+}
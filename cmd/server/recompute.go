@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/database"
+	"licet/internal/services"
+)
+
+// recomputeOptions are the arguments of the recompute command
+type recomputeOptions struct {
+	from, to time.Time
+	feature  string
+}
+
+// parseRecomputeArgs parses the arguments of the recompute command
+func parseRecomputeArgs(args []string, output io.Writer) (recomputeOptions, error) {
+	var opts recomputeOptions
+	var from, to string
+	fs := flag.NewFlagSet("recompute", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.StringVar(&from, "from", "", "First day to recompute (YYYY-MM-DD, required)")
+	fs.StringVar(&to, "to", "", "Last day to recompute (YYYY-MM-DD, default today)")
+	fs.StringVar(&opts.feature, "feature", "", "Only recompute this feature")
+	fs.Usage = func() {
+		fmt.Fprintln(output, "Usage: licet recompute --from YYYY-MM-DD [--to YYYY-MM-DD] [--feature name]")
+		fmt.Fprintln(output, "Regenerates the usage rollups and trend caches derived from feature_usage.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	if fs.NArg() > 0 {
+		return opts, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	var err error
+	if opts.from, err = time.ParseInLocation("2006-01-02", from, time.Local); err != nil {
+		return opts, errors.New("--from must be YYYY-MM-DD")
+	}
+	opts.to = time.Now()
+	if to != "" {
+		if opts.to, err = time.ParseInLocation("2006-01-02", to, time.Local); err != nil {
+			return opts, errors.New("--to must be YYYY-MM-DD")
+		}
+	}
+	if opts.to.Before(opts.from) {
+		return opts, errors.New("--to must not be before --from")
+	}
+	return opts, nil
+}
+
+// runRecompute regenerates the aggregates derived from raw usage, e.g. after
+// fixing a parser or rollup bug:
+//
+//	licet recompute --from 2025-01-01 --to 2025-01-31 [--feature solver]
+func runRecompute(args []string, output io.Writer) error {
+	opts, err := parseRecomputeArgs(args, output)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	setupLogging(cfg)
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db, cfg.Database.Type); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	storage := services.NewStorageService(db, cfg.Database.Type)
+	holidays, err := services.NewHolidayCalendar(cfg.Holidays)
+	if err != nil {
+		return fmt.Errorf("failed to load holiday calendars: %w", err)
+	}
+	storage.SetHolidays(holidays)
+
+	servers := make([]string, 0, len(cfg.Servers))
+	for _, srv := range cfg.Servers {
+		servers = append(servers, srv.Hostname)
+	}
+
+	start := time.Now()
+	total := int(opts.to.Sub(opts.from).Hours()/24) + 1
+	done := 0
+	days, trends, err := storage.Recompute(context.Background(), opts.from, opts.to, opts.feature, servers,
+		func(day time.Time, featureDays int) {
+			done++
+			fmt.Fprintf(output, "[%d/%d] %s: %d feature days\n", done, total, day.Format("2006-01-02"), featureDays)
+		})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(output, "Recomputed %d feature days and %d trends in %s\n", days, trends, time.Since(start).Round(time.Millisecond))
+	return nil
+}
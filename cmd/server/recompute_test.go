@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestParseRecomputeArgs(t *testing.T) {
+	opts, err := parseRecomputeArgs([]string{"--from", "2025-01-01", "--to", "2025-01-31", "--feature", "solver"}, io.Discard)
+	if err != nil {
+		t.Fatalf("parseRecomputeArgs failed: %v", err)
+	}
+	if want := time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local); !opts.from.Equal(want) {
+		t.Errorf("Expected from %v, got %v", want, opts.from)
+	}
+	if want := time.Date(2025, 1, 31, 0, 0, 0, 0, time.Local); !opts.to.Equal(want) {
+		t.Errorf("Expected to %v, got %v", want, opts.to)
+	}
+	if opts.feature != "solver" {
+		t.Errorf("Expected feature solver, got %q", opts.feature)
+	}
+
+	opts, err = parseRecomputeArgs([]string{"--from", "2025-01-01"}, io.Discard)
+	if err != nil || opts.to.Before(time.Now().Add(-time.Minute)) {
+		t.Errorf("Expected --to to default to now, got %v (%v)", opts.to, err)
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"--from", "01/01/2025"},
+		{"--from", "2025-01-31", "--to", "2025-01-01"},
+		{"--from", "2025-01-01", "extra"},
+		{"--unknown"},
+	} {
+		if _, err := parseRecomputeArgs(args, io.Discard); err == nil {
+			t.Errorf("Expected an error for %q", args)
+		}
+	}
+
+	if _, err := parseRecomputeArgs([]string{"-h"}, io.Discard); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("Expected help, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/database"
+	"licet/internal/models"
+	"licet/internal/parsers"
+	"licet/internal/services"
+	"licet/web"
+)
+
+// Outcomes of a self-test check
+const (
+	selftestOK      = "ok"
+	selftestFailed  = "failed"
+	selftestSkipped = "skipped"
+)
+
+// errSelftestFailed is returned when any check of the self-test failed
+var errSelftestFailed = errors.New("self-test failed")
+
+// selftestCheck is the outcome of one check of the self-test
+type selftestCheck struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"` // ok, failed or skipped
+	DurationMs int64  `json:"duration_ms"`
+	Message    string `json:"message,omitempty"`
+}
+
+// selftestReport is the report the self-test prints
+type selftestReport struct {
+	OK      bool             `json:"ok"`
+	Build   models.BuildInfo `json:"build"`
+	Checks  []selftestCheck  `json:"checks"`
+	Elapsed int64            `json:"elapsed_ms"`
+}
+
+// runSelftest verifies the dependencies of a deployment before it receives
+// traffic, prints a JSON report and fails if any check failed:
+//
+//	licet --selftest [--timeout 30s]
+func runSelftest(args []string, output io.Writer) error {
+	var timeout time.Duration
+	fs := flag.NewFlagSet("selftest", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.DurationVar(&timeout, "timeout", 30*time.Second, "Time limit of each check")
+	fs.Usage = func() {
+		fmt.Fprintln(output, "Usage: licet --selftest [--timeout 30s]")
+		fmt.Fprintln(output, "Checks the database, license utilities, SMTP, parsers and templates, and prints a JSON report.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	setupLogging(cfg)
+
+	report := selftest(context.Background(), cfg, timeout)
+	enc := json.NewEncoder(output)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	if !report.OK {
+		return errSelftestFailed
+	}
+	return nil
+}
+
+// selftest runs every check, each bounded by timeout
+func selftest(ctx context.Context, cfg *config.Config, timeout time.Duration) selftestReport {
+	checks := []struct {
+		name string
+		run  func(ctx context.Context) (status, message string)
+	}{
+		{"database", func(ctx context.Context) (string, string) { return checkDatabase(ctx, cfg) }},
+		{"binaries", func(ctx context.Context) (string, string) { return checkBinaries(cfg) }},
+		{"smtp", func(ctx context.Context) (string, string) { return checkSMTP(ctx, cfg) }},
+		{"parser", func(ctx context.Context) (string, string) { return selftestResult(parsers.SelfTest()) }},
+		{"templates", func(ctx context.Context) (string, string) {
+			return selftestResult(web.CheckTemplates(cfg.Server.TemplatesDir))
+		}},
+	}
+
+	started := time.Now()
+	report := selftestReport{OK: true, Build: buildInfo()}
+	for _, c := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		status, message := c.run(checkCtx)
+		cancel()
+		report.Checks = append(report.Checks, selftestCheck{
+			Name:       c.name,
+			Status:     status,
+			DurationMs: time.Since(start).Milliseconds(),
+			Message:    message,
+		})
+		if status == selftestFailed {
+			report.OK = false
+		}
+	}
+	report.Elapsed = time.Since(started).Milliseconds()
+	return report
+}
+
+// selftestResult turns the error of a check into its outcome
+func selftestResult(err error) (string, string) {
+	if err != nil {
+		return selftestFailed, err.Error()
+	}
+	return selftestOK, ""
+}
+
+// checkDatabase connects, applies pending migrations as a startup would and
+// writes and reads back a row in a transaction that is rolled back
+func checkDatabase(ctx context.Context, cfg *config.Config) (string, string) {
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		return selftestFailed, fmt.Sprintf("failed to connect: %v", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db, cfg.Database.Type); err != nil {
+		return selftestFailed, fmt.Sprintf("failed to run migrations: %v", err)
+	}
+	return selftestResult(services.NewStorageService(db, cfg.Database.Type).CheckReadWrite(ctx))
+}
+
+// checkBinaries checks the utilities of the server types queried with one
+func checkBinaries(cfg *config.Config) (string, string) {
+	types := make(map[string]bool)
+	for _, srv := range cfg.Servers {
+		if srv.QueryMode != parsers.QueryModeNative {
+			types[srv.Type] = true
+		}
+	}
+
+	checker := services.NewUtilityChecker()
+	var checked, missing []string
+	for serverType := range types {
+		status, ok := checker.CheckServerType(serverType)
+		if !ok {
+			continue
+		}
+		if !status.Available {
+			missing = append(missing, fmt.Sprintf("%s: %s", status.Name, status.Message))
+			continue
+		}
+		checked = append(checked, status.Name)
+	}
+	sort.Strings(checked)
+	sort.Strings(missing)
+
+	switch {
+	case len(missing) > 0:
+		return selftestFailed, strings.Join(missing, "; ")
+	case len(checked) == 0:
+		return selftestSkipped, "no configured server is queried with a utility"
+	default:
+		return selftestOK, strings.Join(checked, ", ")
+	}
+}
+
+// checkSMTP connects and authenticates to the SMTP server without sending mail
+func checkSMTP(ctx context.Context, cfg *config.Config) (string, string) {
+	if !cfg.Email.Enabled {
+		return selftestSkipped, "email is disabled"
+	}
+	return selftestResult(services.TestSMTPConnection(ctx, cfg.Email))
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+)
+
+func TestSelftest(t *testing.T) {
+	cfg := &config.Config{
+		Database: config.DatabaseConfig{Type: "sqlite", Database: filepath.Join(t.TempDir(), "licet.db")},
+		Servers:  []config.LicenseServer{{Hostname: "27000@flex", Type: "flexlm", QueryMode: "native"}},
+	}
+
+	report := selftest(context.Background(), cfg, 10*time.Second)
+	if !report.OK {
+		t.Fatalf("Expected the self-test to pass, got %+v", report.Checks)
+	}
+	want := map[string]string{
+		"database":  selftestOK,
+		"binaries":  selftestSkipped,
+		"smtp":      selftestSkipped,
+		"parser":    selftestOK,
+		"templates": selftestOK,
+	}
+	if len(report.Checks) != len(want) {
+		t.Fatalf("Expected %d checks, got %+v", len(want), report.Checks)
+	}
+	for _, c := range report.Checks {
+		if c.Status != want[c.Name] {
+			t.Errorf("Expected %s to be %s, got %s (%s)", c.Name, want[c.Name], c.Status, c.Message)
+		}
+	}
+
+	cfg.Email = config.EmailConfig{Enabled: true, SMTPHost: "127.0.0.1", SMTPPort: 1, From: "licet@example.com"}
+	report = selftest(context.Background(), cfg, 10*time.Second)
+	if report.OK {
+		t.Error("Expected the self-test to fail without an SMTP server")
+	}
+}
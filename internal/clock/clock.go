@@ -0,0 +1,54 @@
+// Package clock provides the current time to time-dependent services, so
+// tests can freeze it and backfills can run as of a past time.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the clock of the host
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Fixed is a clock that stands still until it is set or advanced
+type Fixed struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFixed creates a clock fixed at t
+func NewFixed(t time.Time) *Fixed {
+	return &Fixed{now: t}
+}
+
+// Now returns the time the clock is fixed at
+func (c *Fixed) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set fixes the clock at t
+func (c *Fixed) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// Advance moves the clock forward by d
+func (c *Fixed) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
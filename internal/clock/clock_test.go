@@ -0,0 +1,34 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFixed(t *testing.T) {
+	start := time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC)
+	c := NewFixed(start)
+
+	if !c.Now().Equal(start) || !c.Now().Equal(start) {
+		t.Fatalf("Expected the clock to stand at %v, got %v", start, c.Now())
+	}
+
+	c.Advance(90 * time.Minute)
+	if want := start.Add(90 * time.Minute); !c.Now().Equal(want) {
+		t.Errorf("Expected %v after advancing, got %v", want, c.Now())
+	}
+
+	past := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.Set(past)
+	if !c.Now().Equal(past) {
+		t.Errorf("Expected %v after setting, got %v", past, c.Now())
+	}
+}
+
+func TestSystem(t *testing.T) {
+	before := time.Now()
+	now := System.Now()
+	if now.Before(before) || now.After(time.Now()) {
+		t.Errorf("Expected the system time, got %v", now)
+	}
+}
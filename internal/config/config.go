@@ -1,25 +1,62 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/spf13/viper"
+	"licet/internal/util"
 )
 
 type Config struct {
-	Server    ServerConfig
-	Database  DatabaseConfig
-	Logging   LoggingConfig
-	Servers   []LicenseServer
-	Email     EmailConfig
-	Alerts    AlertConfig
-	RRD       RRDConfig
-	Cache     CacheConfig
-	RateLimit RateLimitConfig
-	Export    ExportConfig
-	Auth      AuthConfig
-	WebSocket WebSocketConfig
+	Server       ServerConfig
+	Database     DatabaseConfig
+	Logging      LoggingConfig
+	Servers      []LicenseServer
+	Email        EmailConfig
+	Alerts       AlertConfig
+	RRD          RRDConfig
+	Collection   CollectionConfig
+	Cache        CacheConfig
+	RateLimit    RateLimitConfig
+	Export       ExportConfig
+	Auth         AuthConfig
+	WebSocket    WebSocketConfig
+	GRPC         GRPCConfig `mapstructure:"grpc"`
+	Ingest       IngestConfig
+	LogTail      LogTailConfig    `mapstructure:"log_tail"`
+	Display      DisplayConfig    `mapstructure:"display_names"`
+	UserDigest   UserDigestConfig `mapstructure:"user_digest"`
+	Privacy      PrivacyConfig
+	Encryption   EncryptionConfig
+	Watchdog     WatchdogConfig
+	Anomalies    AnomalyConfig
+	Holidays     HolidayConfig
+	Metrics      MetricsConfig
+	Tracing      TracingConfig
+	Alertmanager AlertmanagerConfig
+	Forecast     ForecastConfig
+	Webhooks     WebhookConfig
+	Checkouts    CheckoutConfig
+	Reports      ReportsConfig
+	Entitlements EntitlementConfig
+	Branding     BrandingConfig
+	Audit        AuditConfig
+	Rollup       RollupConfig
+	Retention    RetentionConfig
+	QueryLimits  QueryLimitsConfig `mapstructure:"query_limits"`
+	Jobs         JobsConfig
+	Chaos        ChaosConfig
+	Waitlist     WaitlistConfig
+	Slack        SlackConfig
+	Chargeback   ChargebackConfig
+	PublicFeed   PublicFeedConfig `mapstructure:"public_feed"`
+	FeatureFlags map[string]bool  `mapstructure:"feature_flags"` // Flag name -> enabled; runtime toggles override these
 }
 
 type ServerConfig struct {
@@ -32,6 +69,8 @@ type ServerConfig struct {
 	TLSEnabled         bool     `mapstructure:"tls_enabled"`
 	TLSCertFile        string   `mapstructure:"tls_cert_file"`
 	TLSKeyFile         string   `mapstructure:"tls_key_file"`
+	TemplatesDir       string   `mapstructure:"templates_dir"` // *.html files overriding the embedded templates
+	DevMode            bool     `mapstructure:"dev_mode"`      // Reload changed templates of templates_dir
 }
 
 type DatabaseConfig struct {
@@ -58,6 +97,38 @@ type LicenseServer struct {
 	Type        string
 	CactiID     string
 	WebUI       string
+	QueryMode   string `mapstructure:"query_mode"`                   // binary (default) or native
+	Profile     string `mapstructure:"profile"`                      // Vendor daemon adjustments: mlm (MathWorks) for flexlm servers
+	Group       string `mapstructure:"group" json:"group,omitempty"` // Site or group, e.g. emea, to filter and aggregate analytics by
+
+	// Script queried by servers of type command, run with the hostname as its
+	// last argument; it prints the server's status, features and users as JSON.
+	// Only set in the config file, never through the settings API.
+	Command     string     `mapstructure:"command" json:"-"`
+	CommandArgs []string   `mapstructure:"command_args" json:"-"`
+	REST        RESTConfig `mapstructure:"rest" json:"-"` // API queried by servers of type rest
+
+	// Polling schedule, overriding rrd.collection_interval
+	PollInterval int    `mapstructure:"poll_interval" json:"poll_interval,omitempty"` // Minutes between polls
+	Schedule     string `mapstructure:"schedule" json:"schedule,omitempty"`           // Cron expression, instead of poll_interval
+	Jitter       int    `mapstructure:"jitter" json:"jitter,omitempty"`               // Random delay of up to this many seconds before each poll
+	Timeout      int    `mapstructure:"timeout" json:"timeout,omitempty"`             // Seconds before a query is abandoned, overriding collection.query_timeout
+}
+
+// RESTConfig is the REST API of a license manager without a status tool,
+// such as Sentinel EMS or OLicense, and how its JSON maps to features and
+// checkouts
+type RESTConfig struct {
+	URL           string            `mapstructure:"url"`            // Returns the features
+	UsersURL      string            `mapstructure:"users_url"`      // Returns the checkouts, if not url
+	Token         string            `mapstructure:"token"`          // Sent with each request
+	TokenFile     string            `mapstructure:"token_file"`     // File holding the token, read at each query
+	AuthHeader    string            `mapstructure:"auth_header"`    // Header of the token, Authorization by default
+	AuthScheme    string            `mapstructure:"auth_scheme"`    // Prefix of the token in Authorization, Bearer by default
+	FeaturesPath  string            `mapstructure:"features_path"`  // Dot path to the array of features, empty for the response itself
+	UsersPath     string            `mapstructure:"users_path"`     // Dot path to the array of checkouts
+	FeatureFields map[string]string `mapstructure:"feature_fields"` // Field -> dot path within a feature, e.g. total: quantity
+	UserFields    map[string]string `mapstructure:"user_fields"`    // Field -> dot path within a checkout
 }
 
 type EmailConfig struct {
@@ -69,12 +140,48 @@ type EmailConfig struct {
 	Username string
 	Password string
 	Enabled  bool
+	Queue    EmailQueueConfig `mapstructure:"queue"`
+}
+
+// EmailQueueConfig queues outgoing email in the database and sends it in the
+// background, retrying failures with exponential backoff. Email that still
+// fails after MaxAttempts is kept as a dead letter to be requeued.
+type EmailQueueConfig struct {
+	Enabled       bool `mapstructure:"enabled"`
+	MaxAttempts   int  `mapstructure:"max_attempts"`   // Attempts of an email before it is a dead letter
+	RetryDelay    int  `mapstructure:"retry_delay"`    // Seconds before the first retry, doubling with each attempt
+	RetentionDays int  `mapstructure:"retention_days"` // Sent email is removed after this
 }
 
 type AlertConfig struct {
-	LeadTimeDays      int  `mapstructure:"lead_time_days"`
-	ResendIntervalMin int  `mapstructure:"resend_interval_min"`
-	Enabled           bool `mapstructure:"enabled"`
+	LeadTimeDays            int                `mapstructure:"lead_time_days"`
+	ResendIntervalMin       int                `mapstructure:"resend_interval_min"`
+	Enabled                 bool               `mapstructure:"enabled"`
+	Failover                bool               `mapstructure:"failover"`
+	BaseURL                 string             `mapstructure:"base_url"`                   // External Licet URL for links in notifications
+	Runbooks                map[string]string  `mapstructure:"runbooks"`                   // Alert type (or "default") -> runbook URL template
+	Variables               map[string]string  `mapstructure:"variables"`                  // Extra values for runbook templates ({{.Vars.name}})
+	IncidentWindowMin       int                `mapstructure:"incident_window_min"`        // Correlate alerts per server within this window (0 = off)
+	Utilization             bool               `mapstructure:"utilization"`                // Alert when feature utilization crosses a threshold
+	UtilizationWarn         float64            `mapstructure:"utilization_warning"`        // Percent; overridable per feature via the API
+	UtilizationCrit         float64            `mapstructure:"utilization_critical"`       // Percent; overridable per feature via the API
+	Queued                  bool               `mapstructure:"queued"`                     // Alert when checkout requests wait in a license queue
+	QueuedThreshold         int                `mapstructure:"queued_threshold"`           // Queued requests of a feature that raise the alert
+	MaxNotificationsPerHour int                `mapstructure:"max_notifications_per_hour"` // Emails per hour before alerts collapse into a summary (0 = unlimited)
+	FloodThreshold          int                `mapstructure:"flood_threshold"`            // Pending notifications that collapse into one summary (0 = off)
+	ExpiryDigest            ExpiryDigestConfig `mapstructure:"expiry_digest"`
+}
+
+// ExpiryDigestConfig sends one HTML email summarizing the features expiring
+// within Days, grouped by server and vendor daemon, besides the individual
+// expiration alerts
+type ExpiryDigestConfig struct {
+	Enabled    bool     `mapstructure:"enabled"`
+	Period     string   `mapstructure:"period"`     // daily or weekly
+	Cron       string   `mapstructure:"cron"`       // Defaults to 07:00 every day (daily) or on Mondays (weekly)
+	Days       int      `mapstructure:"days"`       // Expirations within this many days are listed
+	Template   string   `mapstructure:"template"`   // html/template file replacing the built-in email body
+	Recipients []string `mapstructure:"recipients"` // Defaults to email.to
 }
 
 type RRDConfig struct {
@@ -83,10 +190,27 @@ type RRDConfig struct {
 	CollectionInterval int
 }
 
+// CollectionConfig controls how servers are polled in parallel
+type CollectionConfig struct {
+	Workers      int `mapstructure:"workers"`       // Servers queried at once (default: 5)
+	QueryTimeout int `mapstructure:"query_timeout"` // Seconds before a query is abandoned (default: 30)
+}
+
 type CacheConfig struct {
-	Enabled    bool `mapstructure:"enabled"`
-	TTLSeconds int  `mapstructure:"ttl_seconds"`
-	MaxEntries int  `mapstructure:"max_entries"`
+	Enabled             bool             `mapstructure:"enabled"`
+	Backend             string           `mapstructure:"backend"` // memory (default) or redis
+	TTLSeconds          int              `mapstructure:"ttl_seconds"`
+	MaxEntries          int              `mapstructure:"max_entries"`
+	ConditionalRequests bool             `mapstructure:"conditional_requests"`
+	Redis               RedisCacheConfig `mapstructure:"redis"`
+}
+
+// RedisCacheConfig connects the redis cache backend, shared by replicas
+type RedisCacheConfig struct {
+	Address  string `mapstructure:"address"` // host:port
+	Password string `mapstructure:"password"`
+	DB       int    `mapstructure:"db"`
+	Prefix   string `mapstructure:"prefix"` // Prepended to every cache key
 }
 
 type RateLimitConfig struct {
@@ -98,26 +222,52 @@ type RateLimitConfig struct {
 }
 
 type ExportConfig struct {
-	Enabled        bool     `mapstructure:"enabled"`
-	AllowedFormats []string `mapstructure:"allowed_formats"`
-	MaxRecords     int      `mapstructure:"max_records"`
+	Enabled           bool     `mapstructure:"enabled"`
+	AllowedFormats    []string `mapstructure:"allowed_formats"`
+	MaxRecords        int      `mapstructure:"max_records"`
+	CalendarReminders []int    `mapstructure:"calendar_reminders"` // Days before an expiration the iCal feed reminds of it
 }
 
 type AuthConfig struct {
-	Enabled            bool            `mapstructure:"enabled"`
-	AllowAnonymousRead bool            `mapstructure:"allow_anonymous_read"`
-	APIKeys            []APIKeyConfig  `mapstructure:"api_keys"`
-	BasicAuth          BasicAuthConfig `mapstructure:"basic_auth"`
-	SessionTimeout     int             `mapstructure:"session_timeout"`
-	ExemptPaths        []string        `mapstructure:"exempt_paths"`
+	Enabled            bool                `mapstructure:"enabled"`
+	AllowAnonymousRead bool                `mapstructure:"allow_anonymous_read"`
+	APIKeys            []APIKeyConfig      `mapstructure:"api_keys"`
+	BasicAuth          BasicAuthConfig     `mapstructure:"basic_auth"`
+	SessionTimeout     int                 `mapstructure:"session_timeout"`
+	ExemptPaths        []string            `mapstructure:"exempt_paths"`
+	OIDC               OIDCConfig          `mapstructure:"oidc"`
+	Roles              map[string][]string `mapstructure:"roles"` // Custom role -> permissions, e.g. helpdesk: [read, alerts:manage]
+}
+
+// OIDCConfig signs web UI users in through an OpenID Connect provider such as
+// Keycloak or Azure AD
+type OIDCConfig struct {
+	Enabled               bool              `mapstructure:"enabled"`
+	IssuerURL             string            `mapstructure:"issuer_url"`
+	ClientID              string            `mapstructure:"client_id"`
+	ClientSecret          string            `mapstructure:"client_secret"`
+	RedirectURL           string            `mapstructure:"redirect_url"` // https://licet.example.com/auth/callback
+	Scopes                []string          `mapstructure:"scopes"`
+	UsernameClaim         string            `mapstructure:"username_claim"`
+	GroupsClaim           string            `mapstructure:"groups_claim"`
+	RoleMappings          []OIDCRoleMapping `mapstructure:"role_mappings"`
+	DefaultRole           string            `mapstructure:"default_role"`             // Role of users in no mapped group; empty denies them
+	PostLogoutRedirectURL string            `mapstructure:"post_logout_redirect_url"` // Sent to the provider's end session endpoint
+}
+
+// OIDCRoleMapping grants a role to the members of a provider group
+type OIDCRoleMapping struct {
+	Group string `mapstructure:"group"`
+	Role  string `mapstructure:"role"`
 }
 
 type APIKeyConfig struct {
-	Name        string `mapstructure:"name"`
-	Key         string `mapstructure:"key"`
-	Role        string `mapstructure:"role"`
-	Description string `mapstructure:"description"`
-	Enabled     bool   `mapstructure:"enabled"`
+	Name        string   `mapstructure:"name"`
+	Key         string   `mapstructure:"key"`
+	Role        string   `mapstructure:"role"`
+	Description string   `mapstructure:"description"`
+	Enabled     bool     `mapstructure:"enabled"`
+	Permissions []string `mapstructure:"permissions"` // Granted on top of those of the role
 }
 
 type BasicAuthConfig struct {
@@ -126,10 +276,17 @@ type BasicAuthConfig struct {
 }
 
 type BasicUserConfig struct {
-	Username string `mapstructure:"username"`
-	Password string `mapstructure:"password"`
-	Role     string `mapstructure:"role"`
-	Enabled  bool   `mapstructure:"enabled"`
+	Username    string   `mapstructure:"username"`
+	Password    string   `mapstructure:"password"`
+	Role        string   `mapstructure:"role"`
+	Enabled     bool     `mapstructure:"enabled"`
+	Permissions []string `mapstructure:"permissions"` // Granted on top of those of the role
+}
+
+// GRPCConfig serves the licet.v1 gRPC service on a port of its own
+type GRPCConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	Port    int  `mapstructure:"port"`
 }
 
 type WebSocketConfig struct {
@@ -141,6 +298,328 @@ type WebSocketConfig struct {
 	WriteBufferSize int  `mapstructure:"write_buffer_size"`
 }
 
+type IngestConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	Token        string `mapstructure:"token"`
+	MaxBodyBytes int64  `mapstructure:"max_body_bytes"`
+}
+
+// LogTailConfig lists vendor daemon logs read from local files into license
+// events, such as the report logs of RLM ISV servers
+type LogTailConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval int           `mapstructure:"interval"` // Seconds between reads of new lines
+	Files    []LogTailFile `mapstructure:"files"`
+}
+
+type LogTailFile struct {
+	Server string `mapstructure:"server"` // Hostname the events are recorded for, e.g. 5053@rlmhost
+	Type   string `mapstructure:"type"`   // flexlm, rlm or rlm_reportlog
+	Path   string `mapstructure:"path"`
+}
+
+type DisplayConfig struct {
+	Vendors map[string]string `mapstructure:"vendors"`
+	Rules   []DisplayNameRule `mapstructure:"rules"`
+}
+
+type DisplayNameRule struct {
+	Pattern     string `mapstructure:"pattern"`
+	DisplayName string `mapstructure:"display_name"`
+}
+
+type UserDigestConfig struct {
+	Enabled      bool     `mapstructure:"enabled"`
+	Email        bool     `mapstructure:"email"`
+	Features     []string `mapstructure:"features"`      // Regex patterns; empty means all features
+	InactiveDays int      `mapstructure:"inactive_days"` // Days without use before a user counts as removed
+}
+
+type PrivacyConfig struct {
+	RedactUsernames bool     `mapstructure:"redact_usernames"`
+	Mode            string   `mapstructure:"mode"`         // hash or mask
+	HashKey         string   `mapstructure:"hash_key"`     // Secret for stable username hashes
+	ExemptRoles     []string `mapstructure:"exempt_roles"` // Roles that see real usernames
+}
+
+type WatchdogConfig struct {
+	Enabled         bool   `mapstructure:"enabled"`
+	MissedIntervals int    `mapstructure:"missed_intervals"` // Alert after this many collection intervals without success
+	Email           bool   `mapstructure:"email"`            // Send directly via SMTP, bypassing the alert queue
+	WebhookURL      string `mapstructure:"webhook_url"`      // POST a JSON notification to this URL
+}
+
+type EncryptionConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Key     string `mapstructure:"key"`      // Secret for username field encryption
+	KeyFile string `mapstructure:"key_file"` // File holding the key, e.g. a KMS-provisioned secret
+}
+
+type AnomalyConfig struct {
+	Threshold  float64            `mapstructure:"threshold"`  // Standard deviations from the mean that count as an anomaly
+	Rules      []AnomalyRule      `mapstructure:"rules"`      // Per-feature thresholds; the first matching rule wins
+	Exclusions []AnomalyExclusion `mapstructure:"exclusions"` // Periods (e.g. company shutdowns) not reported as anomalies
+}
+
+type AnomalyRule struct {
+	Server    string  `mapstructure:"server"`  // Empty matches every server
+	Pattern   string  `mapstructure:"pattern"` // Feature name regex
+	Threshold float64 `mapstructure:"threshold"`
+}
+
+type AnomalyExclusion struct {
+	Name    string   `mapstructure:"name"`
+	Start   string   `mapstructure:"start"`   // First excluded day (YYYY-MM-DD)
+	End     string   `mapstructure:"end"`     // Last excluded day (YYYY-MM-DD), defaults to start
+	Servers []string `mapstructure:"servers"` // Empty applies to every server
+}
+
+type AlertmanagerConfig struct {
+	URL          string `mapstructure:"url"`           // Alertmanager base URL to sync silences from; empty disables syncing
+	SyncInterval int    `mapstructure:"sync_interval"` // Seconds between silence syncs
+}
+
+type WebhookConfig struct {
+	Endpoints   []WebhookEndpoint `mapstructure:"endpoints"`
+	MaxAttempts int               `mapstructure:"max_attempts"`    // Deliveries are retried with exponential backoff
+	BackoffSec  int               `mapstructure:"backoff_seconds"` // Delay before the first retry
+	TimeoutSec  int               `mapstructure:"timeout_seconds"`
+}
+
+type WebhookEndpoint struct {
+	Name   string   `mapstructure:"name"`
+	URL    string   `mapstructure:"url"`
+	Secret string   `mapstructure:"secret"` // Signs payloads with HMAC-SHA256 in X-Licet-Signature
+	Events []string `mapstructure:"events"` // alert, server_status; empty sends all events
+}
+
+type CheckoutConfig struct {
+	LongHeldHours float64 `mapstructure:"long_held_hours"` // Open checkouts held this long are reclamation candidates
+}
+
+// AuditConfig records mutating API requests in the audit log
+type AuditConfig struct {
+	Enabled       bool     `mapstructure:"enabled"`
+	RetentionDays int      `mapstructure:"retention_days"` // Entries older than this are removed nightly; 0 keeps them
+	ExcludePaths  []string `mapstructure:"exclude_paths"`  // Path prefixes not recorded, e.g. /api/v1/ingest/
+}
+
+// RollupConfig aggregates raw usage samples into hourly and daily rollups,
+// which queries over long periods read instead of the samples
+type RollupConfig struct {
+	Enabled   bool `mapstructure:"enabled"`
+	AfterDays int  `mapstructure:"after_days"` // Days of raw samples kept out of the rollups, at least 1
+}
+
+// JobsConfig sizes the queue running long work such as backfills, exports
+// and report generation
+type JobsConfig struct {
+	Workers       int            `mapstructure:"workers"`        // Jobs running at once
+	Concurrency   map[string]int `mapstructure:"concurrency"`    // Job type -> most jobs of the type running at once
+	MaxAttempts   int            `mapstructure:"max_attempts"`   // Attempts of a failing job before it fails for good
+	RetryDelay    int            `mapstructure:"retry_delay"`    // Seconds before the first retry, doubling with each attempt
+	RetentionDays int            `mapstructure:"retention_days"` // Finished jobs and their files are removed after this
+	OutputDir     string         `mapstructure:"output_dir"`     // Directory of the files jobs produce; defaults to a temporary directory
+}
+
+// ChaosConfig enables injecting synthetic failures into the queries of
+// license servers, to test alerting and error handling in staging. Never
+// enable it in production.
+type ChaosConfig struct {
+	Enabled bool     `mapstructure:"enabled"`
+	Servers []string `mapstructure:"servers"` // Servers faults may be injected into; no others can fail
+}
+
+// WaitlistConfig lets users subscribe to be notified once when a seat of a
+// feature frees up
+type WaitlistConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	CooldownMin int    `mapstructure:"cooldown_min"` // Minutes after a notification before a user is notified again
+	MaxPerUser  int    `mapstructure:"max_per_user"` // Pending subscriptions a user may hold
+	EmailDomain string `mapstructure:"email_domain"` // Appended to usernames that are not email addresses
+}
+
+// SlackConfig answers the slash commands of a Slack app, such as
+// "/licet status ansys", from the latest collection of each server
+type SlackConfig struct {
+	Enabled       bool              `mapstructure:"enabled"`
+	SigningSecret string            `mapstructure:"signing_secret"` // Of the Slack app, to verify that requests come from Slack
+	Teams         []string          `mapstructure:"teams"`          // Workspace IDs that may ask; empty allows any
+	Users         map[string]string `mapstructure:"users"`          // Slack user ID -> role; keys are matched case-insensitively
+	DefaultRole   string            `mapstructure:"default_role"`   // Role of the Slack users not listed
+}
+
+// PublicFeedConfig publishes the availability of chosen features as JSON
+// without authentication, e.g. for an intranet page
+type PublicFeedConfig struct {
+	Enabled      bool     `mapstructure:"enabled"`
+	Path         string   `mapstructure:"path"`          // Where the feed is served, outside /api/
+	Features     []string `mapstructure:"features"`      // Feature names or patterns such as "matlab*" that are published
+	Servers      []string `mapstructure:"servers"`       // Servers whose features are published; empty publishes all
+	Fields       []string `mapstructure:"fields"`        // Fields of each feature that are published, of PublicFeedFields
+	CacheSeconds int      `mapstructure:"cache_seconds"` // How long the feed is served from memory and cached by browsers
+}
+
+// ChargebackConfig attributes checkout hours to cost centers, for billing
+// license costs back to the departments using them
+type ChargebackConfig struct {
+	Enabled           bool                 `mapstructure:"enabled"`
+	Schedule          string               `mapstructure:"schedule"`            // Cron expression of the monthly aggregation run
+	DefaultCostCenter string               `mapstructure:"default_cost_center"` // Cost center of users in none
+	CostCenters       []CostCenter         `mapstructure:"cost_centers"`        // First match wins
+	LDAP              ChargebackLDAPConfig `mapstructure:"ldap"`
+}
+
+// CostCenter lists the users billed to a cost center, by username or by the
+// LDAP groups they are members of
+type CostCenter struct {
+	Name       string   `mapstructure:"name"`
+	Users      []string `mapstructure:"users"`       // Matched case-insensitively
+	LDAPGroups []string `mapstructure:"ldap_groups"` // cn of groups below ldap.base_dn
+}
+
+// ChargebackLDAPConfig is the directory that group memberships are read from,
+// with the ldapsearch utility
+type ChargebackLDAPConfig struct {
+	URL              string `mapstructure:"url"` // e.g. ldaps://ldap.example.com
+	BindDN           string `mapstructure:"bind_dn"`
+	BindPasswordFile string `mapstructure:"bind_password_file"` // Read by ldapsearch, so the password is not on its command line
+	BaseDN           string `mapstructure:"base_dn"`
+	MemberAttribute  string `mapstructure:"member_attribute"` // memberUid, or member holding user DNs
+}
+
+// PublicFeedFields are the fields the public feed can publish
+var PublicFeedFields = []string{
+	"server_hostname", "feature_name", "display_name", "vendor_daemon", "vendor_display_name",
+	"total_licenses", "used_licenses", "available_licenses", "utilization_pct",
+}
+
+// RetentionConfig removes data past its maximum age on a schedule
+type RetentionConfig struct {
+	Schedule string         `mapstructure:"schedule"` // Cron expression of the nightly run
+	DryRun   bool           `mapstructure:"dry_run"`  // Count what scheduled runs would remove without removing it
+	Tables   map[string]int `mapstructure:"tables"`   // Table -> maximum age in days; tables not listed are kept
+}
+
+// QueryLimitsConfig bounds the cost of analytics queries. Zero disables a limit.
+type QueryLimitsConfig struct {
+	Enabled      bool           `mapstructure:"enabled"`
+	MaxDays      int            `mapstructure:"max_days"`      // Longest period in days any endpoint may query
+	EndpointDays map[string]int `mapstructure:"endpoint_days"` // Route pattern -> longest period, overriding max_days
+	MaxFeatures  int            `mapstructure:"max_features"`  // Most features a report may span
+	MaxRows      int            `mapstructure:"max_rows"`      // Largest limit accepted without override=true
+}
+
+// RetentionTables are the tables a retention policy can apply to
+var RetentionTables = []string{
+	"feature_usage", "feature_usage_hourly", "feature_usage_daily", "license_events",
+	"alerts", "alert_events", "alert_deliveries", "webhook_deliveries", "audit_log",
+	"waitlist_subscriptions",
+}
+
+// RetentionPolicy returns the maximum age in days of each table with one.
+// audit.retention_days applies to the audit log unless retention.tables
+// sets it.
+func (c *Config) RetentionPolicy() map[string]int {
+	policy := make(map[string]int, len(c.Retention.Tables)+1)
+	for table, days := range c.Retention.Tables {
+		policy[table] = days
+	}
+	if _, ok := policy["audit_log"]; !ok && c.Audit.RetentionDays > 0 {
+		policy["audit_log"] = c.Audit.RetentionDays
+	}
+	return policy
+}
+
+// BrandingConfig rebrands the web UI, emails and exported reports
+type BrandingConfig struct {
+	ProductName     string `mapstructure:"product_name"`      // Replaces "Licet" in page titles, the navbar, emails and reports
+	LogoFile        string `mapstructure:"logo_file"`         // Image shown in the navbar, served at /branding/logo
+	FooterText      string `mapstructure:"footer_text"`       // Replaces the project link in the page footer
+	PrimaryColor    string `mapstructure:"primary_color"`     // Buttons and links, as #rgb or #rrggbb
+	NavbarColor     string `mapstructure:"navbar_color"`      // Navbar background
+	NavbarTextColor string `mapstructure:"navbar_text_color"` // Navbar brand and links
+}
+
+// Name returns the product name, Licet unless rebranded
+func (b BrandingConfig) Name() string {
+	if b.ProductName == "" {
+		return "Licet"
+	}
+	return b.ProductName
+}
+
+// ReportsConfig schedules capacity and utilization reports emailed to recipients
+type ReportsConfig struct {
+	Enabled   bool             `mapstructure:"enabled"`
+	Schedules []ReportSchedule `mapstructure:"schedules"`
+}
+
+type ReportSchedule struct {
+	Name       string   `mapstructure:"name"`
+	Period     string   `mapstructure:"period"`     // weekly or monthly
+	Cron       string   `mapstructure:"cron"`       // Defaults to Mondays (weekly) or the 1st (monthly) at 06:00
+	Format     string   `mapstructure:"format"`     // html or pdf
+	Recipients []string `mapstructure:"recipients"` // Defaults to email.to
+}
+
+// EntitlementConfig pulls purchased entitlements from vendor portals to
+// reconcile them against what the license servers report
+type EntitlementConfig struct {
+	Enabled  bool                `mapstructure:"enabled"`
+	Schedule string              `mapstructure:"schedule"` // Cron expression of the sync
+	Sources  []EntitlementSource `mapstructure:"sources"`
+}
+
+type EntitlementSource struct {
+	Name     string            `mapstructure:"name"`
+	Type     string            `mapstructure:"type"` // http (JSON API) or file (CSV or JSON)
+	URL      string            `mapstructure:"url"`
+	Token    string            `mapstructure:"token"` // Sent as a bearer token
+	Headers  map[string]string `mapstructure:"headers"`
+	Path     string            `mapstructure:"path"`  // File read by the file type
+	Items    string            `mapstructure:"items"` // Dotted path of the entitlement list in JSON, e.g. data.entitlements
+	Fields   EntitlementFields `mapstructure:"fields"`
+	Servers  []string          `mapstructure:"servers"`  // Servers serving these entitlements; empty means all
+	Features map[string]string `mapstructure:"features"` // Vendor product name -> license feature name
+}
+
+// EntitlementFields names the fields (JSON) or columns (CSV) of a source
+type EntitlementFields struct {
+	Feature  string `mapstructure:"feature"`
+	Quantity string `mapstructure:"quantity"`
+	EndDate  string `mapstructure:"end_date"`
+}
+
+type ForecastConfig struct {
+	HeadcountGrowthPct float64 `mapstructure:"headcount_growth_pct"` // Expected yearly headcount growth for budget forecasts
+	Months             int     `mapstructure:"months"`               // Months projected by budget forecasts
+}
+
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Path    string `mapstructure:"path"` // Where Prometheus scrapes the metrics
+}
+
+// TracingConfig exports a trace of each collection to an OpenTelemetry
+// collector, linked from the metrics by exemplars
+type TracingConfig struct {
+	Enabled     bool              `mapstructure:"enabled"`
+	Endpoint    string            `mapstructure:"endpoint"`     // OTLP/HTTP endpoint, e.g. http://tempo:4318
+	ServiceName string            `mapstructure:"service_name"` // service.name of the spans
+	Headers     map[string]string `mapstructure:"headers"`      // Sent with each export, e.g. an authorization header
+}
+
+type HolidayConfig struct {
+	Calendars []HolidayCalendar `mapstructure:"calendars"`
+}
+
+type HolidayCalendar struct {
+	Name    string   `mapstructure:"name"`
+	Servers []string `mapstructure:"servers"` // Servers at this site; empty makes it the default calendar
+	Dates   []string `mapstructure:"dates"`   // "YYYY-MM-DD" or yearly "MM-DD", optionally followed by a name
+}
+
 func Load() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
@@ -166,14 +645,36 @@ func Load() (*Config, error) {
 	viper.SetDefault("alerts.lead_time_days", 10)
 	viper.SetDefault("alerts.resend_interval_min", 60)
 	viper.SetDefault("alerts.enabled", false)
+	viper.SetDefault("alerts.failover", false)
+	viper.SetDefault("alerts.incident_window_min", 15)
+	viper.SetDefault("alerts.utilization", false)
+	viper.SetDefault("alerts.utilization_warning", 80.0)
+	viper.SetDefault("alerts.utilization_critical", 95.0)
+	viper.SetDefault("alerts.queued", false)
+	viper.SetDefault("alerts.queued_threshold", 1)
+	viper.SetDefault("alerts.max_notifications_per_hour", 30)
+	viper.SetDefault("alerts.flood_threshold", 10)
+	viper.SetDefault("alerts.expiry_digest.enabled", false)
+	viper.SetDefault("alerts.expiry_digest.period", "weekly")
+	viper.SetDefault("alerts.expiry_digest.days", 30)
 	viper.SetDefault("email.enabled", false)
+	viper.SetDefault("email.queue.enabled", false)
+	viper.SetDefault("email.queue.max_attempts", 5)
+	viper.SetDefault("email.queue.retry_delay", 60)
+	viper.SetDefault("email.queue.retention_days", 30)
 	viper.SetDefault("rrd.enabled", false)
 	viper.SetDefault("rrd.collectionInterval", 5)
+	viper.SetDefault("collection.workers", 5)
+	viper.SetDefault("collection.query_timeout", 30)
 
 	// Cache defaults
 	viper.SetDefault("cache.enabled", true)
+	viper.SetDefault("cache.backend", "memory")
+	viper.SetDefault("cache.redis.address", "localhost:6379")
+	viper.SetDefault("cache.redis.prefix", "licet:cache:")
 	viper.SetDefault("cache.ttl_seconds", 30)
 	viper.SetDefault("cache.max_entries", 1000)
+	viper.SetDefault("cache.conditional_requests", true)
 
 	// Rate limit defaults
 	viper.SetDefault("ratelimit.enabled", true)
@@ -184,8 +685,9 @@ func Load() (*Config, error) {
 
 	// Export defaults
 	viper.SetDefault("export.enabled", true)
-	viper.SetDefault("export.allowed_formats", []string{"json", "csv"})
+	viper.SetDefault("export.allowed_formats", []string{"json", "csv", "xlsx", "pdf"})
 	viper.SetDefault("export.max_records", 10000)
+	viper.SetDefault("export.calendar_reminders", []int{30, 7})
 
 	// Auth defaults
 	viper.SetDefault("auth.enabled", false)
@@ -193,8 +695,14 @@ func Load() (*Config, error) {
 	viper.SetDefault("auth.session_timeout", 60)
 	viper.SetDefault("auth.exempt_paths", []string{"/api/v1/health", "/static/", "/ws"})
 	viper.SetDefault("auth.basic_auth.enabled", false)
+	viper.SetDefault("auth.oidc.enabled", false)
+	viper.SetDefault("auth.oidc.scopes", []string{"openid", "profile", "email"})
+	viper.SetDefault("auth.oidc.username_claim", "preferred_username")
+	viper.SetDefault("auth.oidc.groups_claim", "groups")
 
 	// WebSocket defaults
+	viper.SetDefault("grpc.enabled", false)
+	viper.SetDefault("grpc.port", 9090)
 	viper.SetDefault("websocket.enabled", true)
 	viper.SetDefault("websocket.ping_interval", 30)
 	viper.SetDefault("websocket.update_interval", 10)
@@ -202,6 +710,99 @@ func Load() (*Config, error) {
 	viper.SetDefault("websocket.read_buffer_size", 1024)
 	viper.SetDefault("websocket.write_buffer_size", 1024)
 
+	// Log ingest defaults
+	viper.SetDefault("ingest.enabled", false)
+	viper.SetDefault("ingest.token", "")
+	viper.SetDefault("ingest.max_body_bytes", 10<<20)
+
+	// Log tail defaults
+	viper.SetDefault("log_tail.enabled", false)
+	viper.SetDefault("log_tail.interval", 60)
+
+	// User digest defaults
+	viper.SetDefault("user_digest.enabled", false)
+	viper.SetDefault("user_digest.email", false)
+	viper.SetDefault("user_digest.inactive_days", 30)
+
+	// Scheduled report defaults
+	viper.SetDefault("reports.enabled", false)
+	viper.SetDefault("branding.product_name", "Licet")
+	viper.SetDefault("audit.enabled", true)
+	viper.SetDefault("audit.retention_days", 365)
+	viper.SetDefault("rollup.enabled", true)
+	viper.SetDefault("rollup.after_days", 2)
+	viper.SetDefault("retention.schedule", "30 3 * * *")
+	viper.SetDefault("retention.dry_run", false)
+	viper.SetDefault("jobs.workers", 2)
+	viper.SetDefault("jobs.concurrency", map[string]int{"backfill": 1, "archive": 1, "capacity_reports": 1})
+	viper.SetDefault("jobs.max_attempts", 3)
+	viper.SetDefault("jobs.retry_delay", 30)
+	viper.SetDefault("jobs.retention_days", 7)
+	viper.SetDefault("waitlist.enabled", false)
+	viper.SetDefault("waitlist.cooldown_min", 60)
+	viper.SetDefault("waitlist.max_per_user", 20)
+	viper.SetDefault("slack.enabled", false)
+	viper.SetDefault("slack.default_role", "readonly")
+	viper.SetDefault("public_feed.enabled", false)
+	viper.SetDefault("public_feed.path", "/public/availability.json")
+	viper.SetDefault("public_feed.fields", []string{"feature_name", "display_name", "available_licenses", "total_licenses"})
+	viper.SetDefault("public_feed.cache_seconds", 300)
+	viper.SetDefault("chargeback.enabled", false)
+	viper.SetDefault("chargeback.schedule", "0 4 * * *")
+	viper.SetDefault("chargeback.default_cost_center", "unassigned")
+	viper.SetDefault("chargeback.ldap.member_attribute", "memberUid")
+	viper.SetDefault("query_limits.enabled", true)
+	viper.SetDefault("query_limits.max_days", 730)
+	viper.SetDefault("query_limits.max_features", 500)
+	viper.SetDefault("query_limits.max_rows", 10000)
+
+	// Entitlement defaults
+	viper.SetDefault("entitlements.enabled", false)
+	viper.SetDefault("entitlements.schedule", "0 4 * * *")
+
+	// Privacy defaults
+	viper.SetDefault("privacy.redact_usernames", false)
+	viper.SetDefault("privacy.mode", "hash")
+	viper.SetDefault("privacy.exempt_roles", []string{"admin"})
+
+	// Watchdog defaults
+	viper.SetDefault("watchdog.enabled", true)
+	viper.SetDefault("watchdog.missed_intervals", 3)
+	viper.SetDefault("watchdog.email", false)
+	viper.SetDefault("watchdog.webhook_url", "")
+
+	// Encryption defaults
+	viper.SetDefault("encryption.enabled", false)
+	viper.SetDefault("encryption.key", "")
+	viper.SetDefault("encryption.key_file", "")
+
+	// Anomaly detection defaults
+	viper.SetDefault("anomalies.threshold", 2.0)
+
+	// Alertmanager defaults
+	viper.SetDefault("alertmanager.url", "")
+	viper.SetDefault("alertmanager.sync_interval", 60)
+
+	// Metrics defaults
+	viper.SetDefault("metrics.enabled", false)
+	viper.SetDefault("metrics.path", "/metrics")
+
+	// Tracing defaults
+	viper.SetDefault("tracing.enabled", false)
+	viper.SetDefault("tracing.service_name", "licet")
+
+	// Budget forecast defaults
+	viper.SetDefault("forecast.headcount_growth_pct", 0.0)
+	viper.SetDefault("forecast.months", 12)
+
+	// Checkout defaults
+	viper.SetDefault("checkouts.long_held_hours", 24.0)
+
+	// Webhook defaults
+	viper.SetDefault("webhooks.max_attempts", 5)
+	viper.SetDefault("webhooks.backoff_seconds", 10)
+	viper.SetDefault("webhooks.timeout_seconds", 10)
+
 	// Environment variables
 	viper.SetEnvPrefix("LICET")
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
@@ -218,13 +819,290 @@ func Load() (*Config, error) {
 	if err := viper.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("unable to decode config: %w", err)
 	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
 
 	return &cfg, nil
 }
 
+// Validate checks the content of a configuration file as Load does, e.g.
+// before the settings page replaces the file
+func Validate(data []byte) error {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("error reading config: %w", err)
+	}
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return fmt.Errorf("unable to decode config: %w", err)
+	}
+	return cfg.validate()
+}
+
+// validate rejects settings the services cannot run with
+func (c *Config) validate() error {
+	for _, srv := range c.Servers {
+		if err := util.ValidatePollSchedule(srv.PollInterval, srv.Schedule, srv.Jitter); err != nil {
+			return fmt.Errorf("server %s: %w", srv.Hostname, err)
+		}
+		if srv.Timeout < 0 {
+			return fmt.Errorf("server %s: timeout must be a positive number of seconds", srv.Hostname)
+		}
+		if len(srv.Group) > 64 {
+			return fmt.Errorf("server %s: group must be at most 64 characters", srv.Hostname)
+		}
+		if srv.Profile != "" && (srv.Profile != "mlm" || srv.Type != "flexlm") {
+			return fmt.Errorf("server %s: profile must be mlm, for a flexlm server", srv.Hostname)
+		}
+		if srv.Type == "rest" && !strings.HasPrefix(srv.REST.URL, "http://") && !strings.HasPrefix(srv.REST.URL, "https://") {
+			return fmt.Errorf("server %s: type rest needs the http(s) URL of its API in rest.url", srv.Hostname)
+		}
+		if srv.Type == "command" && srv.Command == "" {
+			return fmt.Errorf("server %s: type command needs a command to run", srv.Hostname)
+		}
+	}
+	if c.Collection.Workers < 0 || c.Collection.QueryTimeout < 0 {
+		return fmt.Errorf("collection.workers and collection.query_timeout must not be negative")
+	}
+	for role, perms := range c.Auth.Roles {
+		if slices.Contains(builtinRoles, role) {
+			return fmt.Errorf("auth.roles: %s is a built-in role", role)
+		}
+		if err := validatePermissions("auth.roles."+role, perms); err != nil {
+			return err
+		}
+	}
+	for _, key := range c.Auth.APIKeys {
+		if err := validatePermissions("auth.api_keys "+key.Name, key.Permissions); err != nil {
+			return err
+		}
+	}
+	for _, user := range c.Auth.BasicAuth.Users {
+		if err := validatePermissions("auth.basic_auth.users "+user.Username, user.Permissions); err != nil {
+			return err
+		}
+	}
+	if c.Auth.OIDC.Enabled {
+		oidc := c.Auth.OIDC
+		if oidc.IssuerURL == "" || oidc.ClientID == "" || oidc.RedirectURL == "" {
+			return fmt.Errorf("auth.oidc requires issuer_url, client_id and redirect_url")
+		}
+		for _, m := range oidc.RoleMappings {
+			if !c.validRole(m.Role) {
+				return fmt.Errorf("auth.oidc.role_mappings: group %s has unknown role %q", m.Group, m.Role)
+			}
+		}
+		if oidc.DefaultRole != "" && !c.validRole(oidc.DefaultRole) {
+			return fmt.Errorf("auth.oidc.default_role: unknown role %q", oidc.DefaultRole)
+		}
+	}
+	for key, color := range map[string]string{
+		"primary_color":     c.Branding.PrimaryColor,
+		"navbar_color":      c.Branding.NavbarColor,
+		"navbar_text_color": c.Branding.NavbarTextColor,
+	} {
+		if color != "" && !cssColorPattern.MatchString(color) {
+			return fmt.Errorf("branding.%s must be a hex color like #1a2b3c, not %q", key, color)
+		}
+	}
+	if logo := c.Branding.LogoFile; logo != "" {
+		switch strings.ToLower(filepath.Ext(logo)) {
+		case ".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp":
+		default:
+			return fmt.Errorf("branding.logo_file must be a PNG, JPEG, GIF, SVG or WebP image")
+		}
+	}
+	if c.Audit.RetentionDays < 0 {
+		return fmt.Errorf("audit.retention_days must not be negative")
+	}
+	if c.Rollup.Enabled && c.Rollup.AfterDays < 1 {
+		return fmt.Errorf("rollup.after_days must be at least 1")
+	}
+	for table, days := range c.Retention.Tables {
+		if !slices.Contains(RetentionTables, table) {
+			return fmt.Errorf("retention.tables: unsupported table %q, expected one of %s", table, strings.Join(RetentionTables, ", "))
+		}
+		if days < 1 {
+			return fmt.Errorf("retention.tables.%s must be at least 1 day", table)
+		}
+	}
+	if c.Jobs.Workers < 0 || c.Jobs.MaxAttempts < 0 || c.Jobs.RetryDelay < 0 || c.Jobs.RetentionDays < 0 {
+		return fmt.Errorf("jobs settings must not be negative")
+	}
+	if c.Alerts.Queued && c.Alerts.QueuedThreshold < 1 {
+		return fmt.Errorf("alerts.queued_threshold must be at least 1")
+	}
+	if d := c.Alerts.ExpiryDigest; d.Enabled {
+		if d.Period != "daily" && d.Period != "weekly" {
+			return fmt.Errorf("alerts.expiry_digest.period must be daily or weekly")
+		}
+		if d.Days < 1 {
+			return fmt.Errorf("alerts.expiry_digest.days must be at least 1")
+		}
+	}
+	for _, days := range c.Export.CalendarReminders {
+		if days < 0 || days > 365 {
+			return fmt.Errorf("export.calendar_reminders must be days between 0 and 365")
+		}
+	}
+	if c.Email.Queue.Enabled && (c.Email.Queue.MaxAttempts < 1 || c.Email.Queue.RetryDelay < 1 || c.Email.Queue.RetentionDays < 1) {
+		return fmt.Errorf("email.queue max_attempts, retry_delay and retention_days must be at least 1")
+	}
+	for jobType, n := range c.Jobs.Concurrency {
+		if n < 1 {
+			return fmt.Errorf("jobs.concurrency.%s must be at least 1", jobType)
+		}
+	}
+	if c.GRPC.Enabled && (c.GRPC.Port < 1 || c.GRPC.Port > 65535 || c.GRPC.Port == c.Server.Port) {
+		return fmt.Errorf("grpc.port must be a port other than server.port")
+	}
+	if c.Tracing.Enabled && !strings.HasPrefix(c.Tracing.Endpoint, "http://") && !strings.HasPrefix(c.Tracing.Endpoint, "https://") {
+		return fmt.Errorf("tracing.endpoint must be the http(s) URL of an OTLP/HTTP collector")
+	}
+	if c.LogTail.Enabled {
+		if c.LogTail.Interval < 1 {
+			return fmt.Errorf("log_tail.interval must be at least 1 second")
+		}
+		for _, f := range c.LogTail.Files {
+			if f.Server == "" || f.Path == "" {
+				return fmt.Errorf("log_tail.files: server and path are required, got %q", f.Path)
+			}
+			switch f.Type {
+			case "flexlm", "rlm", "rlm_reportlog":
+			default:
+				return fmt.Errorf("log_tail.files %s: type must be flexlm, rlm or rlm_reportlog, not %q", f.Path, f.Type)
+			}
+		}
+	}
+	if c.Chaos.Enabled && len(c.Chaos.Servers) == 0 {
+		return fmt.Errorf("chaos.servers must list the servers faults may be injected into")
+	}
+	if c.Waitlist.Enabled {
+		if !c.Email.Enabled {
+			return fmt.Errorf("waitlist requires email.enabled to notify users")
+		}
+		if c.Waitlist.CooldownMin < 0 || c.Waitlist.MaxPerUser < 1 {
+			return fmt.Errorf("waitlist.cooldown_min must not be negative and waitlist.max_per_user must be at least 1")
+		}
+	}
+	if c.Slack.Enabled {
+		if c.Slack.SigningSecret == "" {
+			return fmt.Errorf("slack.signing_secret is required to verify slash commands")
+		}
+		if !c.validRole(c.Slack.DefaultRole) {
+			return fmt.Errorf("slack.default_role: unknown role %q", c.Slack.DefaultRole)
+		}
+		for user, role := range c.Slack.Users {
+			if !c.validRole(role) {
+				return fmt.Errorf("slack.users.%s: unknown role %q", user, role)
+			}
+		}
+	}
+	if c.PublicFeed.Enabled {
+		feedPath := c.PublicFeed.Path
+		if !strings.HasPrefix(feedPath, "/") || feedPath == "/" || feedPath == "/api" || strings.HasPrefix(feedPath, "/api/") {
+			return fmt.Errorf("public_feed.path must be a path outside / and /api/, such as /public/availability.json")
+		}
+		if len(c.PublicFeed.Features) == 0 {
+			return fmt.Errorf("public_feed.features must list the features to publish")
+		}
+		for _, pattern := range c.PublicFeed.Features {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("public_feed.features: invalid pattern %q", pattern)
+			}
+		}
+		if len(c.PublicFeed.Fields) == 0 {
+			return fmt.Errorf("public_feed.fields must list the fields to publish")
+		}
+		for _, field := range c.PublicFeed.Fields {
+			if !slices.Contains(PublicFeedFields, field) {
+				return fmt.Errorf("public_feed.fields: unknown field %q, expected one of %s", field, strings.Join(PublicFeedFields, ", "))
+			}
+		}
+		if c.PublicFeed.CacheSeconds < 0 {
+			return fmt.Errorf("public_feed.cache_seconds must not be negative")
+		}
+	}
+	if c.Chargeback.Enabled {
+		if c.Chargeback.DefaultCostCenter == "" {
+			return fmt.Errorf("chargeback.default_cost_center is required")
+		}
+		names := make(map[string]bool)
+		for _, cc := range c.Chargeback.CostCenters {
+			if cc.Name == "" || len(cc.Name) > 128 || names[cc.Name] {
+				return fmt.Errorf("chargeback.cost_centers: names must be unique and 1-128 characters, got %q", cc.Name)
+			}
+			names[cc.Name] = true
+			if len(cc.LDAPGroups) > 0 && (c.Chargeback.LDAP.URL == "" || c.Chargeback.LDAP.BaseDN == "") {
+				return fmt.Errorf("chargeback.cost_centers %s: ldap_groups need chargeback.ldap.url and base_dn", cc.Name)
+			}
+		}
+		if c.Chargeback.LDAP.BindDN != "" && c.Chargeback.LDAP.BindPasswordFile == "" {
+			return fmt.Errorf("chargeback.ldap.bind_password_file is required with bind_dn")
+		}
+	}
+	if c.QueryLimits.MaxDays < 0 || c.QueryLimits.MaxFeatures < 0 || c.QueryLimits.MaxRows < 0 {
+		return fmt.Errorf("query_limits must not be negative")
+	}
+	for endpoint, days := range c.QueryLimits.EndpointDays {
+		if !strings.HasPrefix(endpoint, "/api/") {
+			return fmt.Errorf("query_limits.endpoint_days: %q is not an API route such as /api/v1/utilization/history", endpoint)
+		}
+		if days < 1 {
+			return fmt.Errorf("query_limits.endpoint_days.%s must be at least 1 day", endpoint)
+		}
+	}
+	switch c.Cache.Backend {
+	case "", "memory", "redis":
+	default:
+		return fmt.Errorf("cache.backend must be memory or redis, not %q", c.Cache.Backend)
+	}
+	return nil
+}
+
+// AllowedOrigins returns the origins browsers may call the API and open
+// WebSockets from, defaulting to the server itself on localhost
+func (c *Config) AllowedOrigins() []string {
+	if len(c.Server.CORSOrigins) > 0 {
+		return c.Server.CORSOrigins
+	}
+	return []string{fmt.Sprintf("http://localhost:%d", c.Server.Port)}
+}
+
+// cssColorPattern matches the hex colors allowed in branding, which are
+// written into the pages' style sheet
+var cssColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// builtinRoles are the roles of the auth middleware
+var builtinRoles = []string{"admin", "write", "readonly"}
+
+// Permissions are the permissions that can be granted to custom roles, API
+// keys and users. Routes require read or write by their method unless they
+// are annotated with one of the others.
+var Permissions = []string{"read", "write", "servers:write", "alerts:manage", "exports:run", "settings:write"}
+
+// validRole reports whether role is a role of the auth middleware or of
+// auth.roles
+func (c *Config) validRole(role string) bool {
+	_, custom := c.Auth.Roles[role]
+	return custom || slices.Contains(builtinRoles, role)
+}
+
+// validatePermissions checks that the permissions granted by a setting exist
+func validatePermissions(setting string, perms []string) error {
+	for _, p := range perms {
+		if !slices.Contains(Permissions, p) {
+			return fmt.Errorf("%s: unknown permission %q, expected one of %s", setting, p, strings.Join(Permissions, ", "))
+		}
+	}
+	return nil
+}
+
 func (c *Config) GetDSN() string {
 	switch c.Database.Type {
-	case "postgres", "postgresql":
+	case "postgres", "postgresql", "timescaledb":
 		return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 			c.Database.Host, c.Database.Port, c.Database.Username,
 			c.Database.Password, c.Database.Database, c.Database.SSLMode)
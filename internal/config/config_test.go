@@ -259,3 +259,149 @@ func TestLoadConfig_MissingFile(t *testing.T) {
 		t.Errorf("Expected default database type 'sqlite', got '%s'", cfg.Database.Type)
 	}
 }
+
+func TestValidate_Branding(t *testing.T) {
+	tests := []struct {
+		name  string
+		yaml  string
+		valid bool
+	}{
+		{"Hex colors", "branding:\n  primary_color: \"#1a2b3c\"\n  navbar_color: \"#fff\"\n", true},
+		{"Named color", "branding:\n  navbar_color: red\n", false},
+		{"CSS injection", "branding:\n  primary_color: \"#fff; } body { display: none\"\n", false},
+		{"PNG logo", "branding:\n  logo_file: /etc/licet/logo.png\n", true},
+		{"Not an image", "branding:\n  logo_file: /etc/passwd\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate([]byte(tt.yaml))
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid, got error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestBrandingConfig_Name(t *testing.T) {
+	if name := (BrandingConfig{}).Name(); name != "Licet" {
+		t.Errorf("Expected Licet by default, got %q", name)
+	}
+	if name := (BrandingConfig{ProductName: "Acme"}).Name(); name != "Acme" {
+		t.Errorf("Expected Acme, got %q", name)
+	}
+}
+
+func TestRetentionPolicy(t *testing.T) {
+	cfg := &Config{}
+	cfg.Audit.RetentionDays = 365
+	cfg.Retention.Tables = map[string]int{"feature_usage": 730}
+	policy := cfg.RetentionPolicy()
+	if len(policy) != 2 || policy["feature_usage"] != 730 || policy["audit_log"] != 365 {
+		t.Errorf("Expected the audit retention to join the policy, got %v", policy)
+	}
+
+	cfg.Retention.Tables["audit_log"] = 90
+	if policy := cfg.RetentionPolicy(); policy["audit_log"] != 90 {
+		t.Errorf("Expected retention.tables to override audit.retention_days, got %v", policy)
+	}
+
+	tests := []struct {
+		name  string
+		yaml  string
+		valid bool
+	}{
+		{"Known tables", "retention:\n  tables:\n    feature_usage: 365\n    license_events: 730\n", true},
+		{"Unknown table", "retention:\n  tables:\n    servers: 30\n", false},
+		{"Zero days", "retention:\n  tables:\n    alerts: 0\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate([]byte(tt.yaml))
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid, got error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestValidate_QueryLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		yaml  string
+		valid bool
+	}{
+		{"Endpoint limit", "query_limits:\n  endpoint_days:\n    /api/v1/utilization/heatmap: 90\n", true},
+		{"Not a route", "query_limits:\n  endpoint_days:\n    heatmap: 90\n", false},
+		{"Zero days", "query_limits:\n  endpoint_days:\n    /api/v1/utilization/heatmap: 0\n", false},
+		{"Negative rows", "query_limits:\n  max_rows: -1\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate([]byte(tt.yaml))
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid, got error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestValidate_Roles(t *testing.T) {
+	tests := []struct {
+		name  string
+		yaml  string
+		valid bool
+	}{
+		{"Custom role", "auth:\n  roles:\n    helpdesk: [read, alerts:manage]\n", true},
+		{"Unknown permission", "auth:\n  roles:\n    helpdesk: [read, alerts:delete]\n", false},
+		{"Built-in role", "auth:\n  roles:\n    admin: [read]\n", false},
+		{"Key permissions", "auth:\n  api_keys:\n    - name: bot\n      role: readonly\n      permissions: [exports:run]\n", true},
+		{"Unknown key permission", "auth:\n  api_keys:\n    - name: bot\n      permissions: [everything]\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate([]byte(tt.yaml))
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid, got error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestValidate_PublicFeedPath(t *testing.T) {
+	feed := "public_feed:\n  enabled: true\n  features: [matlab]\n  fields: [feature_name]\n  path: "
+	tests := []struct {
+		name  string
+		path  string
+		valid bool
+	}{
+		{"Feed path", "/public/availability.json", true},
+		{"Root", "/", false},
+		{"API root", "/api", false},
+		{"Below the API", "/api/v1/feed", false},
+		{"Relative", "feed.json", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate([]byte(feed + tt.path + "\n"))
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid, got error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
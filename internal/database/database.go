@@ -1,7 +1,9 @@
 package database
 
 import (
+	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"time"
 
@@ -17,7 +19,10 @@ import (
 	"licet/internal/config"
 )
 
-//go:embed migrations/*.sql
+// Shared migrations are written for SQLite; migrations/<dialect>/ holds
+// same-named overrides for statements other databases cannot run.
+//
+//go:embed migrations/*.sql migrations/postgres/*.sql migrations/mysql/*.sql migrations/timescaledb/*.sql
 var migrationsFS embed.FS
 
 func New(cfg config.DatabaseConfig) (*sqlx.DB, error) {
@@ -25,7 +30,7 @@ func New(cfg config.DatabaseConfig) (*sqlx.DB, error) {
 	var dsn string
 
 	switch cfg.Type {
-	case "postgres", "postgresql":
+	case "postgres", "postgresql", "timescaledb":
 		driverName = "postgres"
 		dsn = fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 			cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.Database, cfg.SSLMode)
@@ -34,7 +39,7 @@ func New(cfg config.DatabaseConfig) (*sqlx.DB, error) {
 		dsn = cfg.Database
 	case "mysql":
 		driverName = "mysql"
-		dsn = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true",
+		dsn = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&multiStatements=true",
 			cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.Database)
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", cfg.Type)
@@ -70,17 +75,31 @@ func New(cfg config.DatabaseConfig) (*sqlx.DB, error) {
 	return db, nil
 }
 
+// ErrSchemaTooNew is returned when the database was migrated by a newer
+// release that is not compatible with this one
+var ErrSchemaTooNew = errors.New("database schema is newer than this release supports")
+
+// RunMigrations migrates the database to the schema of this release, holding
+// the migration lock so instances starting together migrate one at a time.
+// A schema migrated further by a newer release is left alone when it is still
+// compatible with this one (see schema_compatibility), so both releases can
+// run side by side during a rolling upgrade.
 func RunMigrations(db *sqlx.DB, dbType string) error {
+	release, err := acquireMigrationLock(context.Background(), db, dbType)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	// Get the underlying *sql.DB for golang-migrate
 	sqlDB := db.DB
 
 	// Create database-specific migration driver
 	var driver database.Driver
 	var driverName string
-	var err error
 
 	switch dbType {
-	case "postgres", "postgresql":
+	case "postgres", "postgresql", "timescaledb":
 		driver, err = postgres.WithInstance(sqlDB, &postgres.Config{})
 		driverName = "postgres"
 	case "mysql":
@@ -96,10 +115,15 @@ func RunMigrations(db *sqlx.DB, dbType string) error {
 	}
 
 	// Create migration source from embedded filesystem
-	sourceDriver, err := iofs.New(migrationsFS, "migrations")
+	migrations := newDialectFS(migrationsFS, "migrations", dbType)
+	sourceDriver, err := iofs.New(migrations, ".")
 	if err != nil {
 		return fmt.Errorf("failed to create migration source: %w", err)
 	}
+	latest, err := latestMigration(migrations)
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
 
 	// Create migrator
 	m, err := migrate.NewWithInstance("iofs", sourceDriver, driverName, driver)
@@ -113,6 +137,23 @@ func RunMigrations(db *sqlx.DB, dbType string) error {
 		return fmt.Errorf("failed to get migration version: %w", err)
 	}
 
+	// Pre-flight check: a newer release migrated the database
+	if version > latest {
+		if dirty {
+			return fmt.Errorf("%w: migration %d of a newer release failed", ErrSchemaTooNew, version)
+		}
+		minVersion, err := schemaCompatibility(sqlDB)
+		if err != nil {
+			return fmt.Errorf("failed to check schema compatibility: %w", err)
+		}
+		if minVersion > latest {
+			return fmt.Errorf("%w: schema version %d needs a release with migration %d, this one has %d",
+				ErrSchemaTooNew, version, minVersion, latest)
+		}
+		log.Warnf("Database schema version %d is newer than this release (%d) but compatible with it", version, latest)
+		return nil
+	}
+
 	if dirty {
 		log.Warnf("Database migration is in dirty state at version %d, forcing version", version)
 		if err := m.Force(int(version)); err != nil {
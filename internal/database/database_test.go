@@ -1,8 +1,12 @@
 package database
 
 import (
+	"errors"
+	"io/fs"
 	"os"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"licet/internal/config"
 )
@@ -335,3 +339,230 @@ func TestDatabaseConstraints(t *testing.T) {
 		t.Errorf("Expected at least 1 feature after REPLACE, got %d", count)
 	}
 }
+
+func TestDialectFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/000001_a.up.sql":          {Data: []byte("shared a")},
+		"migrations/000002_b.up.sql":          {Data: []byte("shared b")},
+		"migrations/postgres/000002_b.up.sql": {Data: []byte("postgres b")},
+	}
+
+	read := func(dbType, name string) string {
+		data, err := fs.ReadFile(newDialectFS(fsys, "migrations", dbType), name)
+		if err != nil {
+			t.Fatalf("ReadFile(%s, %s) failed: %v", dbType, name, err)
+		}
+		return string(data)
+	}
+
+	if got := read("postgres", "000002_b.up.sql"); got != "postgres b" {
+		t.Errorf("Expected postgres override, got %q", got)
+	}
+	if got := read("postgres", "000001_a.up.sql"); got != "shared a" {
+		t.Errorf("Expected shared migration, got %q", got)
+	}
+	if got := read("sqlite", "000002_b.up.sql"); got != "shared b" {
+		t.Errorf("Expected shared migration for sqlite, got %q", got)
+	}
+
+	// TimescaleDB layers its own migrations over the PostgreSQL overrides
+	fsys["migrations/timescaledb/000001_a.up.sql"] = &fstest.MapFile{Data: []byte("timescale a")}
+	fsys["migrations/timescaledb/000003_c.up.sql"] = &fstest.MapFile{Data: []byte("timescale c")}
+	if got := read("timescaledb", "000001_a.up.sql"); got != "timescale a" {
+		t.Errorf("Expected timescaledb override, got %q", got)
+	}
+	if got := read("timescaledb", "000002_b.up.sql"); got != "postgres b" {
+		t.Errorf("Expected postgres override for timescaledb, got %q", got)
+	}
+	timescale, err := fs.ReadDir(newDialectFS(fsys, "migrations", "timescaledb"), ".")
+	if err != nil || len(timescale) != 3 {
+		t.Errorf("Expected 3 timescaledb migrations, got %d (%v)", len(timescale), err)
+	}
+
+	entries, err := fs.ReadDir(newDialectFS(fsys, "migrations", "postgres"), ".")
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 migrations without duplicates, got %d", len(entries))
+	}
+}
+
+// TestDialectMigrations_Portable checks the migrations served to PostgreSQL
+// and MySQL for SQLite-only syntax, which needs a dialect override. The
+// integration tests run them against real servers.
+func TestDialectMigrations_Portable(t *testing.T) {
+	sqliteOnly := []string{"AUTOINCREMENT", "INSERT OR ", "PRAGMA"}
+	unsupported := map[string][]string{
+		"postgres": {"BOOLEAN NOT NULL DEFAULT 0", "BOOLEAN NOT NULL DEFAULT 1"},
+		"mysql": {"INDEX IF NOT EXISTS", "DROP INDEX IF EXISTS", "TEXT DEFAULT", "TEXT NOT NULL DEFAULT",
+			"TEXT NOT NULL UNIQUE", "TEXT PRIMARY KEY"},
+	}
+
+	for dbType, patterns := range unsupported {
+		fsys := newDialectFS(migrationsFS, "migrations", dbType)
+		entries, err := fs.ReadDir(fsys, ".")
+		if err != nil {
+			t.Fatalf("ReadDir(%s) failed: %v", dbType, err)
+		}
+		for _, entry := range entries {
+			data, err := fs.ReadFile(fsys, entry.Name())
+			if err != nil {
+				t.Fatalf("ReadFile(%s, %s) failed: %v", dbType, entry.Name(), err)
+			}
+			for _, pattern := range append(sqliteOnly, patterns...) {
+				if strings.Contains(string(data), pattern) {
+					t.Errorf("%s migration %s contains %q", dbType, entry.Name(), pattern)
+				}
+			}
+		}
+	}
+}
+
+func TestFeaturesVersionNotNull(t *testing.T) {
+	db, err := New(config.DatabaseConfig{Type: "sqlite", Database: t.TempDir() + "/test.db"})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	if err := RunMigrations(db, "sqlite"); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO features (server_hostname, name, version, total_licenses, used_licenses) VALUES ('h', 'f', NULL, 1, 0)`)
+	if err == nil {
+		t.Error("Expected NULL version to be rejected")
+	}
+
+	db.MustExec(`INSERT INTO features (server_hostname, name, total_licenses, used_licenses, expiration_date) VALUES ('h', 'f', 1, 0, '2030-01-01')`)
+	_, err = db.Exec(`INSERT INTO features (server_hostname, name, total_licenses, used_licenses, expiration_date) VALUES ('h', 'f', 1, 0, '2030-01-01')`)
+	if err == nil {
+		t.Error("Expected duplicate unversioned feature to violate the unique constraint")
+	}
+}
+
+func TestDialect_UpsertFeatureTarget(t *testing.T) {
+	db, err := New(config.DatabaseConfig{Type: "sqlite", Database: t.TempDir() + "/test.db"})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	if err := RunMigrations(db, "sqlite"); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	// The conflict target must be the unique key of the table
+	var indexes []struct {
+		Name    string `db:"name"`
+		Unique  bool   `db:"unique"`
+		Seq     int    `db:"seq"`
+		Origin  string `db:"origin"`
+		Partial bool   `db:"partial"`
+	}
+	if err := db.Select(&indexes, `PRAGMA index_list(features)`); err != nil {
+		t.Fatalf("Failed to list indexes: %v", err)
+	}
+	var key []string
+	for _, idx := range indexes {
+		if !idx.Unique || idx.Origin == "pk" {
+			continue
+		}
+		if err := db.Select(&key, `SELECT name FROM pragma_index_info(?) ORDER BY seqno`, idx.Name); err != nil {
+			t.Fatalf("Failed to read index %s: %v", idx.Name, err)
+		}
+	}
+	if got := strings.Join(key, ", "); got != FeatureKey {
+		t.Fatalf("Unique key of features is %q, FeatureKey is %q", got, FeatureKey)
+	}
+
+	for _, dbType := range []string{"sqlite", "postgres"} {
+		if upsert := NewDialect(dbType).UpsertFeature(); !strings.Contains(upsert, "ON CONFLICT ("+FeatureKey+") DO UPDATE") {
+			t.Errorf("%s upsert does not target the feature key:\n%s", dbType, upsert)
+		}
+	}
+}
+
+func TestDialect_TimeBucket(t *testing.T) {
+	if got := NewDialect("timescaledb").TimeBucket("1 hour"); got != "time_bucket(INTERVAL '1 hour', date + time)" {
+		t.Errorf("Unexpected timescaledb bucket %q", got)
+	}
+	if upsert := NewDialect("timescaledb").UpsertFeature(); upsert != NewDialect("postgres").UpsertFeature() {
+		t.Error("Expected timescaledb to write like postgres")
+	}
+	for _, dbType := range []string{"sqlite", "postgres", "mysql"} {
+		if got := NewDialect(dbType).TimeBucket("1 hour"); got != "" {
+			t.Errorf("Expected no native bucketing for %s, got %q", dbType, got)
+		}
+	}
+}
+
+func TestLatestMigration(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000001_init.up.sql":      {},
+		"000001_init.down.sql":    {},
+		"000012_more.up.sql":      {},
+		"000013_pending.down.sql": {},
+		"README.md":               {},
+	}
+	latest, err := latestMigration(fsys)
+	if err != nil || latest != 12 {
+		t.Errorf("latestMigration() = %d, %v, want 12", latest, err)
+	}
+}
+
+func TestContractMigrations_RaiseCompatibility(t *testing.T) {
+	// Contract migrations remove what older releases use, so they must stop
+	// those releases from starting against the schema
+	for _, dbType := range []string{"sqlite", "postgres", "mysql"} {
+		fsys := newDialectFS(migrationsFS, "migrations", dbType)
+		entries, err := fs.ReadDir(fsys, ".")
+		if err != nil {
+			t.Fatalf("ReadDir(%s) failed: %v", dbType, err)
+		}
+		for _, entry := range entries {
+			if !strings.Contains(entry.Name(), "_contract_") || !strings.HasSuffix(entry.Name(), ".up.sql") {
+				continue
+			}
+			data, err := fs.ReadFile(fsys, entry.Name())
+			if err != nil {
+				t.Fatalf("ReadFile(%s, %s) failed: %v", dbType, entry.Name(), err)
+			}
+			if !strings.Contains(string(data), "UPDATE schema_compatibility SET min_version") {
+				t.Errorf("%s contract migration %s does not raise schema_compatibility.min_version", dbType, entry.Name())
+			}
+		}
+	}
+}
+
+func TestRunMigrations_NewerSchema(t *testing.T) {
+	db, err := New(config.DatabaseConfig{Type: "sqlite", Database: t.TempDir() + "/test_newer.db"})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	if err := RunMigrations(db, "sqlite"); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	latest, err := latestMigration(newDialectFS(migrationsFS, "migrations", "sqlite"))
+	if err != nil {
+		t.Fatalf("latestMigration failed: %v", err)
+	}
+
+	// A newer release ran an expand migration
+	if _, err := db.Exec(`UPDATE schema_migrations SET version = ?`, latest+1); err != nil {
+		t.Fatalf("Failed to set schema version: %v", err)
+	}
+	if err := RunMigrations(db, "sqlite"); err != nil {
+		t.Errorf("Expected a compatible newer schema to be accepted, got %v", err)
+	}
+
+	// ... and then a contract migration
+	if _, err := db.Exec(`UPDATE schema_compatibility SET min_version = ?`, latest+1); err != nil {
+		t.Fatalf("Failed to set schema compatibility: %v", err)
+	}
+	if err := RunMigrations(db, "sqlite"); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Expected ErrSchemaTooNew, got %v", err)
+	}
+}
@@ -2,9 +2,14 @@ package database
 
 import "strconv"
 
+// FeatureKey lists the columns of the unique key of the features table, the
+// conflict target of feature upserts
+const FeatureKey = "server_hostname, name, version, expiration_date"
+
 // Dialect provides database-specific SQL expressions
 type Dialect interface {
-	// UpsertFeature returns the SQL for inserting or updating a feature
+	// UpsertFeature returns the SQL for inserting or updating a feature. The
+	// row is updated in place, keeping its id.
 	UpsertFeature() string
 	// InsertIgnoreUsage returns the SQL for inserting usage data (ignoring duplicates)
 	InsertIgnoreUsage() string
@@ -18,6 +23,14 @@ type Dialect interface {
 	SupportsPositionalParams() bool
 	// DeactivateFeaturesForServer returns the SQL to mark all features as inactive for a server
 	DeactivateFeaturesForServer() string
+	// InsertIgnoreEvent returns the SQL for inserting a license event (ignoring duplicates)
+	InsertIgnoreEvent() string
+	// UpsertFeatureUser returns the SQL for recording a user sighting, keeping first_seen
+	UpsertFeatureUser() string
+	// TimeBucket returns the SQL expression for the start of the bucket of
+	// width (e.g. "1 hour") holding a usage sample, or "" when the database
+	// has no native time bucketing
+	TimeBucket(width string) string
 }
 
 // NewDialect creates a dialect for the given database type
@@ -25,6 +38,8 @@ func NewDialect(dbType string) Dialect {
 	switch dbType {
 	case "postgres", "postgresql":
 		return &PostgresDialect{}
+	case "timescaledb":
+		return &TimescaleDialect{}
 	case "mysql":
 		return &MySQLDialect{}
 	default:
@@ -38,13 +53,18 @@ type PostgresDialect struct{}
 func (d *PostgresDialect) UpsertFeature() string {
 	return `
 		INSERT INTO features
-		(server_hostname, name, version, vendor_daemon, total_licenses, used_licenses, expiration_date, last_updated, is_active)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, TRUE)
-		ON CONFLICT (server_hostname, name, version) DO UPDATE SET
+		(server_hostname, name, version, vendor_daemon, total_licenses, used_licenses, reserved_licenses, borrowed_licenses, overdraft_licenses, queued_requests, license_model, parse_quality, expiration_date, last_updated, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, TRUE)
+		ON CONFLICT (` + FeatureKey + `) DO UPDATE SET
 			vendor_daemon = EXCLUDED.vendor_daemon,
 			total_licenses = EXCLUDED.total_licenses,
 			used_licenses = EXCLUDED.used_licenses,
-			expiration_date = EXCLUDED.expiration_date,
+			reserved_licenses = EXCLUDED.reserved_licenses,
+			borrowed_licenses = EXCLUDED.borrowed_licenses,
+			overdraft_licenses = EXCLUDED.overdraft_licenses,
+			queued_requests = EXCLUDED.queued_requests,
+			license_model = EXCLUDED.license_model,
+			parse_quality = EXCLUDED.parse_quality,
 			last_updated = EXCLUDED.last_updated,
 			is_active = TRUE
 	`
@@ -53,8 +73,8 @@ func (d *PostgresDialect) UpsertFeature() string {
 func (d *PostgresDialect) InsertIgnoreUsage() string {
 	return `
 		INSERT INTO feature_usage
-		(server_hostname, feature_name, date, time, users_count)
-		VALUES ($1, $2, $3, $4, $5)
+		(server_hostname, feature_name, date, time, users_count, queued_count)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		ON CONFLICT (server_hostname, feature_name, date, time) DO NOTHING
 	`
 }
@@ -79,19 +99,57 @@ func (d *PostgresDialect) DeactivateFeaturesForServer() string {
 	return `UPDATE features SET is_active = FALSE WHERE server_hostname = $1`
 }
 
+func (d *PostgresDialect) InsertIgnoreEvent() string {
+	return `
+		INSERT INTO license_events
+		(server_hostname, event_date, event_time, event_type, feature_name, username, reason, reason_code)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (server_hostname, event_date, event_time, event_type, feature_name, username) DO NOTHING
+	`
+}
+
+func (d *PostgresDialect) UpsertFeatureUser() string {
+	return `
+		INSERT INTO feature_users
+		(server_hostname, feature_name, username, first_seen, last_seen)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (server_hostname, feature_name, username) DO UPDATE SET
+			last_seen = EXCLUDED.last_seen
+	`
+}
+
+func (d *PostgresDialect) TimeBucket(width string) string {
+	return ""
+}
+
+// TimescaleDialect implements Dialect for PostgreSQL with the TimescaleDB
+// extension, where feature_usage is a hypertable
+type TimescaleDialect struct {
+	PostgresDialect
+}
+
+func (d *TimescaleDialect) TimeBucket(width string) string {
+	return "time_bucket(INTERVAL '" + width + "', date + time)"
+}
+
 // MySQLDialect implements Dialect for MySQL
 type MySQLDialect struct{}
 
 func (d *MySQLDialect) UpsertFeature() string {
 	return `
 		INSERT INTO features
-		(server_hostname, name, version, vendor_daemon, total_licenses, used_licenses, expiration_date, last_updated, is_active)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, TRUE)
+		(server_hostname, name, version, vendor_daemon, total_licenses, used_licenses, reserved_licenses, borrowed_licenses, overdraft_licenses, queued_requests, license_model, parse_quality, expiration_date, last_updated, is_active)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, TRUE)
 		ON DUPLICATE KEY UPDATE
 			vendor_daemon = VALUES(vendor_daemon),
 			total_licenses = VALUES(total_licenses),
 			used_licenses = VALUES(used_licenses),
-			expiration_date = VALUES(expiration_date),
+			reserved_licenses = VALUES(reserved_licenses),
+			borrowed_licenses = VALUES(borrowed_licenses),
+			overdraft_licenses = VALUES(overdraft_licenses),
+			queued_requests = VALUES(queued_requests),
+			license_model = VALUES(license_model),
+			parse_quality = VALUES(parse_quality),
 			last_updated = VALUES(last_updated),
 			is_active = TRUE
 	`
@@ -100,8 +158,8 @@ func (d *MySQLDialect) UpsertFeature() string {
 func (d *MySQLDialect) InsertIgnoreUsage() string {
 	return `
 		INSERT IGNORE INTO feature_usage
-		(server_hostname, feature_name, date, time, users_count)
-		VALUES (?, ?, ?, ?, ?)
+		(server_hostname, feature_name, date, time, users_count, queued_count)
+		VALUES (?, ?, ?, ?, ?, ?)
 	`
 }
 
@@ -125,22 +183,56 @@ func (d *MySQLDialect) DeactivateFeaturesForServer() string {
 	return `UPDATE features SET is_active = FALSE WHERE server_hostname = ?`
 }
 
+func (d *MySQLDialect) InsertIgnoreEvent() string {
+	return `
+		INSERT IGNORE INTO license_events
+		(server_hostname, event_date, event_time, event_type, feature_name, username, reason, reason_code)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+}
+
+func (d *MySQLDialect) UpsertFeatureUser() string {
+	return `
+		INSERT INTO feature_users
+		(server_hostname, feature_name, username, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			last_seen = VALUES(last_seen)
+	`
+}
+
+func (d *MySQLDialect) TimeBucket(width string) string {
+	return ""
+}
+
 // SQLiteDialect implements Dialect for SQLite
 type SQLiteDialect struct{}
 
 func (d *SQLiteDialect) UpsertFeature() string {
 	return `
-		INSERT OR REPLACE INTO features
-		(server_hostname, name, version, vendor_daemon, total_licenses, used_licenses, expiration_date, last_updated, is_active)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1)
+		INSERT INTO features
+		(server_hostname, name, version, vendor_daemon, total_licenses, used_licenses, reserved_licenses, borrowed_licenses, overdraft_licenses, queued_requests, license_model, parse_quality, expiration_date, last_updated, is_active)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1)
+		ON CONFLICT (` + FeatureKey + `) DO UPDATE SET
+			vendor_daemon = excluded.vendor_daemon,
+			total_licenses = excluded.total_licenses,
+			used_licenses = excluded.used_licenses,
+			reserved_licenses = excluded.reserved_licenses,
+			borrowed_licenses = excluded.borrowed_licenses,
+			overdraft_licenses = excluded.overdraft_licenses,
+			queued_requests = excluded.queued_requests,
+			license_model = excluded.license_model,
+			parse_quality = excluded.parse_quality,
+			last_updated = excluded.last_updated,
+			is_active = 1
 	`
 }
 
 func (d *SQLiteDialect) InsertIgnoreUsage() string {
 	return `
 		INSERT OR IGNORE INTO feature_usage
-		(server_hostname, feature_name, date, time, users_count)
-		VALUES (?, ?, ?, ?, ?)
+		(server_hostname, feature_name, date, time, users_count, queued_count)
+		VALUES (?, ?, ?, ?, ?, ?)
 	`
 }
 
@@ -163,3 +255,25 @@ func (d *SQLiteDialect) SupportsPositionalParams() bool {
 func (d *SQLiteDialect) DeactivateFeaturesForServer() string {
 	return `UPDATE features SET is_active = 0 WHERE server_hostname = ?`
 }
+
+func (d *SQLiteDialect) InsertIgnoreEvent() string {
+	return `
+		INSERT OR IGNORE INTO license_events
+		(server_hostname, event_date, event_time, event_type, feature_name, username, reason, reason_code)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+}
+
+func (d *SQLiteDialect) UpsertFeatureUser() string {
+	return `
+		INSERT INTO feature_users
+		(server_hostname, feature_name, username, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (server_hostname, feature_name, username) DO UPDATE SET
+			last_seen = excluded.last_seen
+	`
+}
+
+func (d *SQLiteDialect) TimeBucket(width string) string {
+	return ""
+}
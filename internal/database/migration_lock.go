@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+// migrationLockName names the advisory lock held from reading the schema
+// version until the migrations finished, so replicas starting together during
+// an upgrade migrate one after the other. golang-migrate only locks the
+// migrations themselves, not the dirty state check before them.
+const migrationLockName = "licet_migrations"
+
+// migrationLockTimeout is how long to wait for another instance to finish
+// migrating
+const migrationLockTimeout = 10 * time.Minute
+
+// errMigrationLockHeld is returned when another instance holds the lock
+var errMigrationLockHeld = errors.New("migration lock held by another instance")
+
+// acquireMigrationLock takes the migration lock on a connection of its own
+// and returns the function releasing it. SQLite databases are not shared
+// between instances, so they are not locked.
+func acquireMigrationLock(ctx context.Context, db *sqlx.DB, dbType string) (func(), error) {
+	var lock, unlock string
+	switch dbType {
+	case "postgres", "postgresql", "timescaledb":
+		lock = `SELECT pg_try_advisory_lock(hashtext($1))`
+		unlock = `SELECT pg_advisory_unlock(hashtext($1))`
+	case "mysql":
+		lock = `SELECT COALESCE(GET_LOCK(?, 0), 0) = 1`
+		unlock = `SELECT RELEASE_LOCK(?)`
+	default:
+		return func() {}, nil
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, migrationLockTimeout)
+	defer cancel()
+	waiting := false
+	for {
+		var locked bool
+		if err := conn.QueryRowContext(ctx, lock, migrationLockName).Scan(&locked); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to take migration lock: %w", err)
+		}
+		if locked {
+			break
+		}
+		if !waiting {
+			log.Info("Waiting for another instance to finish migrating the database")
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			return nil, fmt.Errorf("%w after %s", errMigrationLockHeld, migrationLockTimeout)
+		case <-time.After(time.Second):
+		}
+	}
+
+	return func() {
+		if _, err := conn.ExecContext(context.Background(), unlock, migrationLockName); err != nil {
+			log.Warnf("Failed to release migration lock: %v", err)
+		}
+		conn.Close()
+	}, nil
+}
+
+// schemaCompatibility returns the oldest latest migration a binary must have
+// to use the schema, as raised by contract migrations
+func schemaCompatibility(db *sql.DB) (uint, error) {
+	var minVersion uint
+	err := db.QueryRow(`SELECT min_version FROM schema_compatibility WHERE id = 1`).Scan(&minVersion)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return minVersion, err
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationDialectDirs returns the subdirectories holding dialect-specific
+// migration overrides for a database type, most specific first. TimescaleDB
+// is PostgreSQL with an extension, so it falls back to the PostgreSQL overrides.
+func migrationDialectDirs(dbType string) []string {
+	switch dbType {
+	case "postgres", "postgresql":
+		return []string{"postgres"}
+	case "timescaledb":
+		return []string{"timescaledb", "postgres"}
+	case "mysql":
+		return []string{"mysql"}
+	default:
+		return []string{"sqlite"}
+	}
+}
+
+// dialectFS serves the shared migrations in root, replacing any file that
+// has a same-named counterpart in root/<dialect>. This lets a migration ship
+// dialect-specific SQL where the shared SQLite-style statements do not work,
+// without forking the whole migration history.
+type dialectFS struct {
+	fsys     fs.FS
+	root     string
+	dialects []string
+}
+
+// newDialectFS creates a migration filesystem for the given database type
+func newDialectFS(fsys fs.FS, root, dbType string) *dialectFS {
+	return &dialectFS{fsys: fsys, root: root, dialects: migrationDialectDirs(dbType)}
+}
+
+// Open opens a migration file, preferring the most specific dialect version
+func (d *dialectFS) Open(name string) (fs.File, error) {
+	if name == "." {
+		return d.fsys.Open(d.root)
+	}
+	for _, dialect := range d.dialects {
+		if f, err := d.fsys.Open(path.Join(d.root, dialect, name)); err == nil {
+			return f, nil
+		}
+	}
+	return d.fsys.Open(path.Join(d.root, name))
+}
+
+// ReadDir lists the shared migrations plus any dialect-only migrations
+func (d *dialectFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name != "." {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	dirs := []string{d.root}
+	for i := len(d.dialects) - 1; i >= 0; i-- {
+		dirs = append(dirs, path.Join(d.root, d.dialects[i]))
+	}
+
+	entries := make(map[string]fs.DirEntry)
+	for _, dir := range dirs {
+		list, err := fs.ReadDir(d.fsys, dir)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		for _, entry := range list {
+			if !entry.IsDir() {
+				entries[entry.Name()] = entry
+			}
+		}
+	}
+
+	result := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+	return result, nil
+}
+
+// latestMigration returns the version of the newest migration in fsys, the
+// schema version this binary was built for
+func latestMigration(fsys fs.ReadDirFS) (uint, error) {
+	entries, err := fsys.ReadDir(".")
+	if err != nil {
+		return 0, err
+	}
+	var latest uint
+	for _, entry := range entries {
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok || !strings.HasSuffix(entry.Name(), ".up.sql") {
+			continue
+		}
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		latest = max(latest, uint(version))
+	}
+	return latest, nil
+}
@@ -0,0 +1,31 @@
+-- Remove server_hostname from license_events
+
+DROP INDEX IF EXISTS idx_events_server;
+
+-- Create backup
+DROP TABLE IF EXISTS license_events_backup;
+CREATE TABLE license_events_backup AS SELECT id, event_date, event_time, event_type,
+    feature_name, username, reason FROM license_events;
+
+-- Recreate license_events table without server_hostname
+DROP TABLE license_events;
+CREATE TABLE license_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_date DATE NOT NULL,
+    event_time TIME NOT NULL,
+    event_type TEXT NOT NULL,
+    feature_name TEXT NOT NULL,
+    username TEXT NOT NULL,
+    reason TEXT,
+    UNIQUE(event_date, event_time, feature_name, username)
+);
+
+-- Restore data (events that collide without the server are dropped)
+INSERT OR IGNORE INTO license_events (id, event_date, event_time, event_type, feature_name, username, reason)
+SELECT id, event_date, event_time, event_type, feature_name, username, reason
+FROM license_events_backup;
+
+-- Clean up backup
+DROP TABLE license_events_backup;
+
+CREATE INDEX IF NOT EXISTS idx_events_date ON license_events(event_date);
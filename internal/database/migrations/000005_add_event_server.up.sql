@@ -0,0 +1,34 @@
+-- Add server_hostname to license_events
+-- Events can now arrive from log shippers for any server, so the server is
+-- part of the event identity. SQLite cannot alter a UNIQUE constraint, so
+-- the table is rebuilt.
+
+-- Create backup
+DROP TABLE IF EXISTS license_events_backup;
+CREATE TABLE license_events_backup AS SELECT * FROM license_events;
+
+-- Rebuild license_events table with server_hostname
+DROP TABLE license_events;
+CREATE TABLE license_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    server_hostname TEXT NOT NULL DEFAULT '',
+    event_date DATE NOT NULL,
+    event_time TIME NOT NULL,
+    event_type TEXT NOT NULL,
+    feature_name TEXT NOT NULL,
+    username TEXT NOT NULL,
+    reason TEXT,
+    UNIQUE(server_hostname, event_date, event_time, event_type, feature_name, username)
+);
+
+-- Restore data
+INSERT INTO license_events (id, event_date, event_time, event_type, feature_name, username, reason)
+SELECT id, event_date, event_time, event_type, feature_name, username, reason
+FROM license_events_backup;
+
+-- Clean up backup
+DROP TABLE license_events_backup;
+
+-- Recreate indexes
+CREATE INDEX IF NOT EXISTS idx_events_date ON license_events(event_date);
+CREATE INDEX IF NOT EXISTS idx_events_server ON license_events(server_hostname);
@@ -0,0 +1,2 @@
+-- Remove feature display name overrides
+DROP TABLE IF EXISTS feature_display_names;
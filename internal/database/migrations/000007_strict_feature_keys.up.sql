@@ -0,0 +1,61 @@
+-- Make feature version and vendor_daemon NOT NULL
+-- NULL versions never collide under UNIQUE(server_hostname, name, version,
+-- expiration_date), so different parser paths could insert the same feature
+-- twice. Normalize NULLs to '' (dropping rows that would then collide) and
+-- rebuild the table with NOT NULL columns.
+
+UPDATE features SET vendor_daemon = '' WHERE vendor_daemon IS NULL;
+
+DELETE FROM features
+WHERE version IS NULL
+  AND EXISTS (
+      SELECT 1 FROM features f2
+      WHERE f2.server_hostname = features.server_hostname
+        AND f2.name = features.name
+        AND f2.version = ''
+        AND f2.expiration_date IS features.expiration_date
+  );
+
+DELETE FROM features
+WHERE version IS NULL
+  AND id NOT IN (
+      SELECT MAX(id) FROM features
+      WHERE version IS NULL
+      GROUP BY server_hostname, name, expiration_date
+  );
+
+UPDATE features SET version = '' WHERE version IS NULL;
+
+-- Create backup
+DROP TABLE IF EXISTS features_backup;
+CREATE TABLE features_backup AS SELECT * FROM features;
+
+-- Rebuild features table with NOT NULL key columns
+DROP INDEX IF EXISTS idx_features_active;
+DROP TABLE features;
+CREATE TABLE features (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    server_hostname TEXT NOT NULL,
+    name TEXT NOT NULL,
+    version TEXT NOT NULL DEFAULT '',
+    vendor_daemon TEXT NOT NULL DEFAULT '',
+    total_licenses INTEGER NOT NULL,
+    used_licenses INTEGER NOT NULL,
+    expiration_date TIMESTAMP,
+    last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    is_active BOOLEAN DEFAULT TRUE,
+    UNIQUE(server_hostname, name, version, expiration_date)
+);
+
+-- Restore data
+INSERT INTO features (id, server_hostname, name, version, vendor_daemon, total_licenses, used_licenses, expiration_date, last_updated, is_active)
+SELECT id, server_hostname, name, version, vendor_daemon, total_licenses, used_licenses, expiration_date, last_updated, is_active
+FROM features_backup;
+
+-- Clean up backup
+DROP TABLE features_backup;
+
+-- Recreate indexes
+CREATE INDEX IF NOT EXISTS idx_features_server ON features(server_hostname);
+CREATE INDEX IF NOT EXISTS idx_features_expiration ON features(expiration_date);
+CREATE INDEX IF NOT EXISTS idx_features_active ON features(is_active);
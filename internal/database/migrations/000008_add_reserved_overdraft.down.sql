@@ -0,0 +1,3 @@
+-- Requires SQLite 3.35+ for DROP COLUMN
+ALTER TABLE features DROP COLUMN overdraft_licenses;
+ALTER TABLE features DROP COLUMN reserved_licenses;
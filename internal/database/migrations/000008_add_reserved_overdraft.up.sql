@@ -0,0 +1,3 @@
+-- Track FlexLM RESERVATION and OVERDRAFT seat counts per feature
+ALTER TABLE features ADD COLUMN reserved_licenses INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE features ADD COLUMN overdraft_licenses INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- Requires SQLite 3.35+ for DROP COLUMN
+DROP INDEX IF EXISTS idx_features_license_model;
+ALTER TABLE features DROP COLUMN license_model;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_master_events_server;
+DROP TABLE IF EXISTS server_master_events;
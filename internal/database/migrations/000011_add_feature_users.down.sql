@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_feature_users_last_seen;
+DROP INDEX IF EXISTS idx_feature_users_first_seen;
+DROP TABLE IF EXISTS feature_users;
@@ -0,0 +1,14 @@
+-- Track when each user was first and last seen using a feature
+
+CREATE TABLE IF NOT EXISTS feature_users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    server_hostname TEXT NOT NULL,
+    feature_name TEXT NOT NULL,
+    username TEXT NOT NULL,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    UNIQUE(server_hostname, feature_name, username)
+);
+
+CREATE INDEX IF NOT EXISTS idx_feature_users_first_seen ON feature_users(first_seen);
+CREATE INDEX IF NOT EXISTS idx_feature_users_last_seen ON feature_users(last_seen);
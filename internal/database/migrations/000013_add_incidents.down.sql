@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_alerts_incident;
+ALTER TABLE alerts DROP COLUMN incident_id;
+DROP INDEX IF EXISTS idx_incidents_server;
+DROP TABLE IF EXISTS incidents;
@@ -0,0 +1,19 @@
+-- Correlate related alerts into incidents. Alerts for the same server raised
+-- within the correlation window share an incident and one notification thread.
+
+CREATE TABLE IF NOT EXISTS incidents (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    server_hostname TEXT NOT NULL,
+    root_cause TEXT NOT NULL,
+    severity TEXT NOT NULL,
+    alert_count INTEGER NOT NULL DEFAULT 0,
+    opened_at TIMESTAMP NOT NULL,
+    last_alert_at TIMESTAMP NOT NULL,
+    notified BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS idx_incidents_server ON incidents(server_hostname, last_alert_at);
+
+ALTER TABLE alerts ADD COLUMN incident_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_alerts_incident ON alerts(incident_id);
@@ -0,0 +1,10 @@
+-- Latest generated capacity planning report per analysis period.
+-- Reports are generated on a schedule and served from here.
+
+CREATE TABLE IF NOT EXISTS capacity_reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    period_days INTEGER NOT NULL UNIQUE,
+    generated_at TIMESTAMP NOT NULL,
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    report TEXT NOT NULL
+);
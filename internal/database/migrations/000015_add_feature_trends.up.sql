@@ -0,0 +1,20 @@
+-- Per-feature usage trend statistics, recomputed after each collection so
+-- trend and prediction endpoints can read them instead of running regressions
+
+CREATE TABLE IF NOT EXISTS feature_trends (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    server_hostname TEXT NOT NULL,
+    feature_name TEXT NOT NULL,
+    period_days INTEGER NOT NULL,
+    samples INTEGER NOT NULL DEFAULT 0,
+    slope REAL NOT NULL DEFAULT 0,
+    intercept REAL NOT NULL DEFAULT 0,
+    r_squared REAL NOT NULL DEFAULT 0,
+    last_day REAL NOT NULL DEFAULT 0,
+    current_usage INTEGER NOT NULL DEFAULT 0,
+    avg_usage REAL NOT NULL DEFAULT 0,
+    std_dev REAL NOT NULL DEFAULT 0,
+    peak_usage INTEGER NOT NULL DEFAULT 0,
+    computed_at TIMESTAMP NOT NULL,
+    UNIQUE(server_hostname, feature_name, period_days)
+);
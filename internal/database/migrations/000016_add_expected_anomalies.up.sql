@@ -0,0 +1,13 @@
+-- Anomalies marked as expected (e.g. a known training event) are no longer
+-- reported by anomaly detection
+
+CREATE TABLE IF NOT EXISTS expected_anomalies (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    server_hostname TEXT NOT NULL,
+    feature_name TEXT NOT NULL,
+    date DATE NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(server_hostname, feature_name, date)
+);
@@ -0,0 +1,3 @@
+-- Requires SQLite 3.35+ for DROP COLUMN
+DROP INDEX IF EXISTS idx_features_parse_quality;
+ALTER TABLE features DROP COLUMN parse_quality;
@@ -0,0 +1,5 @@
+-- Flag features whose license data could only be parsed by falling back to
+-- defaults, e.g. an unparseable expiration date stored as permanent
+ALTER TABLE features ADD COLUMN parse_quality TEXT NOT NULL DEFAULT 'ok';
+
+CREATE INDEX IF NOT EXISTS idx_features_parse_quality ON features(parse_quality);
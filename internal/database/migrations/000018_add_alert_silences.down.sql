@@ -0,0 +1,3 @@
+-- Requires SQLite 3.35+ for DROP COLUMN
+ALTER TABLE alerts DROP COLUMN silence_id;
+DROP TABLE IF EXISTS alert_silences;
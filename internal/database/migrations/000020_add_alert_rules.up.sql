@@ -0,0 +1,24 @@
+-- Configurable alert rules evaluated after each collection, and the time
+-- since which each rule's condition has held for a server or feature
+
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    rule_type TEXT NOT NULL,
+    server_hostname TEXT NOT NULL DEFAULT '',
+    feature_pattern TEXT NOT NULL DEFAULT '',
+    threshold REAL NOT NULL DEFAULT 0,
+    duration_min INTEGER NOT NULL DEFAULT 0,
+    severity TEXT NOT NULL DEFAULT 'warning',
+    enabled BOOLEAN NOT NULL DEFAULT 1,
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS alert_rule_state (
+    rule_id INTEGER NOT NULL,
+    subject TEXT NOT NULL,
+    since TIMESTAMP NOT NULL,
+    PRIMARY KEY (rule_id, subject)
+);
@@ -0,0 +1,3 @@
+-- Requires SQLite 3.35+ for DROP COLUMN
+DROP INDEX IF EXISTS idx_events_type_date;
+ALTER TABLE license_events DROP COLUMN reason_code;
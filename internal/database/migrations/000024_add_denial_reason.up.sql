@@ -0,0 +1,5 @@
+-- Machine-readable denial reason classified from the vendor's message.
+-- Earlier denials are classified when read.
+ALTER TABLE license_events ADD COLUMN reason_code TEXT DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_events_type_date ON license_events(event_type, event_date);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_entitlements_source;
+DROP TABLE IF EXISTS entitlements;
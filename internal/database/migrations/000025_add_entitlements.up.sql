@@ -0,0 +1,13 @@
+-- Entitlements purchased according to vendor portals, replaced on every sync
+-- of their source, for reconciliation against the license servers
+
+CREATE TABLE IF NOT EXISTS entitlements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    source TEXT NOT NULL,
+    feature_name TEXT NOT NULL,
+    quantity INTEGER NOT NULL,
+    end_date TIMESTAMP NULL,
+    fetched_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_entitlements_source ON entitlements(source);
@@ -0,0 +1,12 @@
+-- Computed metrics: expressions over features, stored after each collection
+-- as features of the virtual "computed" server
+
+CREATE TABLE IF NOT EXISTS computed_metrics (
+    name TEXT PRIMARY KEY,
+    expression TEXT NOT NULL,
+    capacity TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,7 @@
+-- Requires SQLite 3.35+ for DROP COLUMN
+ALTER TABLE audit_log DROP COLUMN duration_ms;
+ALTER TABLE audit_log DROP COLUMN status;
+ALTER TABLE audit_log DROP COLUMN path;
+ALTER TABLE audit_log DROP COLUMN method;
+ALTER TABLE audit_log DROP COLUMN remote_addr;
+ALTER TABLE audit_log DROP COLUMN role;
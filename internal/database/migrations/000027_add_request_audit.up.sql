@@ -0,0 +1,8 @@
+-- Request details of audit entries recorded for mutating API calls.
+-- Entries of administrative operations leave them empty.
+ALTER TABLE audit_log ADD COLUMN role TEXT NOT NULL DEFAULT '';
+ALTER TABLE audit_log ADD COLUMN remote_addr TEXT NOT NULL DEFAULT '';
+ALTER TABLE audit_log ADD COLUMN method TEXT NOT NULL DEFAULT '';
+ALTER TABLE audit_log ADD COLUMN path TEXT NOT NULL DEFAULT '';
+ALTER TABLE audit_log ADD COLUMN status INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE audit_log ADD COLUMN duration_ms INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- feature_usage becomes a hypertable on TimescaleDB only (see timescaledb/);
+-- other databases keep the plain table
+SELECT 1;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS feature_usage_daily;
+DROP TABLE IF EXISTS feature_usage_hourly;
@@ -0,0 +1,32 @@
+-- Usage aggregated per hour and per day. Raw feature_usage samples of days
+-- older than rollup.after_days are rolled up nightly, and queries over long
+-- periods read rolled-up days from here. Sums are kept instead of averages
+-- so rollups can be combined exactly.
+
+CREATE TABLE IF NOT EXISTS feature_usage_hourly (
+    server_hostname TEXT NOT NULL,
+    feature_name TEXT NOT NULL,
+    date DATE NOT NULL,
+    hour INTEGER NOT NULL,
+    samples INTEGER NOT NULL,
+    sum_users BIGINT NOT NULL,
+    sum_squares BIGINT NOT NULL,
+    peak_users INTEGER NOT NULL,
+    min_users INTEGER NOT NULL,
+    PRIMARY KEY (server_hostname, feature_name, date, hour)
+);
+
+CREATE TABLE IF NOT EXISTS feature_usage_daily (
+    server_hostname TEXT NOT NULL,
+    feature_name TEXT NOT NULL,
+    date DATE NOT NULL,
+    samples INTEGER NOT NULL,
+    sum_users BIGINT NOT NULL,
+    sum_squares BIGINT NOT NULL,
+    peak_users INTEGER NOT NULL,
+    min_users INTEGER NOT NULL,
+    PRIMARY KEY (server_hostname, feature_name, date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_hourly_date ON feature_usage_hourly(date);
+CREATE INDEX IF NOT EXISTS idx_usage_daily_date ON feature_usage_daily(date);
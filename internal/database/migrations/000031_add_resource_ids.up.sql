@@ -0,0 +1,12 @@
+-- Stable identifiers of servers and features, which are otherwise addressed
+-- by name. Each resource keeps its id and UUID however its rows change.
+
+CREATE TABLE IF NOT EXISTS resource_ids (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,
+    server_hostname TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    uuid TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE(kind, server_hostname, name)
+);
@@ -0,0 +1,18 @@
+-- Receipts of alert notifications per channel and target, showing for audits
+-- that an alert was delivered
+
+CREATE TABLE IF NOT EXISTS alert_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    alert_id INTEGER NOT NULL,
+    channel TEXT NOT NULL,
+    target TEXT NOT NULL,
+    status TEXT NOT NULL,
+    message_id TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_deliveries_alert ON alert_deliveries(alert_id);
+CREATE INDEX IF NOT EXISTS idx_alert_deliveries_message ON alert_deliveries(message_id);
+CREATE INDEX IF NOT EXISTS idx_alert_deliveries_created ON alert_deliveries(created_at);
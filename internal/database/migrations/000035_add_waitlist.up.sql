@@ -0,0 +1,15 @@
+-- Users waiting for a seat of a feature to free up. A subscription is
+-- notified once, then kept with notified_at set.
+
+CREATE TABLE IF NOT EXISTS waitlist_subscriptions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username TEXT NOT NULL,
+    email TEXT NOT NULL,
+    server_hostname TEXT NOT NULL DEFAULT '',
+    feature_name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    notified_at TIMESTAMP NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_waitlist_feature ON waitlist_subscriptions(feature_name, notified_at);
+CREATE INDEX IF NOT EXISTS idx_waitlist_username ON waitlist_subscriptions(username);
@@ -0,0 +1,14 @@
+-- Tags grouping features for reporting, e.g. by department. An empty server
+-- tags the feature on every server.
+
+CREATE TABLE IF NOT EXISTS feature_tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    server_hostname TEXT NOT NULL DEFAULT '',
+    feature_name TEXT NOT NULL,
+    tag TEXT NOT NULL,
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE(server_hostname, feature_name, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_feature_tags_tag ON feature_tags(tag);
@@ -0,0 +1,3 @@
+-- Requires SQLite 3.35+ for DROP COLUMN
+DROP INDEX IF EXISTS idx_servers_group;
+ALTER TABLE servers DROP COLUMN group_name;
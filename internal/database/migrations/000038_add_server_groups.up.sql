@@ -0,0 +1,5 @@
+-- Site or group of each server, e.g. emea, synced from the server config at
+-- startup for analytics to filter and aggregate by
+ALTER TABLE servers ADD COLUMN group_name TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_servers_group ON servers(group_name);
@@ -0,0 +1,11 @@
+-- Compatibility of the schema with older releases, for rolling upgrades.
+-- min_version is the newest migration a release must have to run against
+-- this schema. Expand migrations (adding tables, columns or indexes that older
+-- releases ignore) leave it alone; contract migrations (dropping or renaming
+-- what older releases still use) raise it.
+CREATE TABLE IF NOT EXISTS schema_compatibility (
+    id INTEGER PRIMARY KEY,
+    min_version INTEGER NOT NULL
+);
+
+INSERT INTO schema_compatibility (id, min_version) VALUES (1, 0);
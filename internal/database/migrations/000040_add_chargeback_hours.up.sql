@@ -0,0 +1,15 @@
+-- Checkout hours of each cost center per month, server and feature, for
+-- chargeback reports. Rows of a month are replaced when it is aggregated again.
+
+CREATE TABLE IF NOT EXISTS chargeback_hours (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    month TEXT NOT NULL,
+    cost_center TEXT NOT NULL,
+    server_hostname TEXT NOT NULL,
+    feature_name TEXT NOT NULL,
+    checkout_hours REAL NOT NULL DEFAULT 0,
+    checkouts INTEGER NOT NULL DEFAULT 0,
+    users INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE(month, cost_center, server_hostname, feature_name)
+);
@@ -0,0 +1,5 @@
+-- Requires SQLite 3.35+ for DROP COLUMN
+ALTER TABLE feature_usage_daily DROP COLUMN peak_queued;
+ALTER TABLE feature_usage_hourly DROP COLUMN peak_queued;
+ALTER TABLE feature_usage DROP COLUMN queued_count;
+ALTER TABLE features DROP COLUMN queued_requests;
@@ -0,0 +1,5 @@
+-- Track FlexLM checkout requests waiting in the queue per feature and sample
+ALTER TABLE features ADD COLUMN queued_requests INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feature_usage ADD COLUMN queued_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feature_usage_hourly ADD COLUMN peak_queued INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feature_usage_daily ADD COLUMN peak_queued INTEGER NOT NULL DEFAULT 0;
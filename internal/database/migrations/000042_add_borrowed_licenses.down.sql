@@ -0,0 +1,2 @@
+-- Requires SQLite 3.35+ for DROP COLUMN
+ALTER TABLE features DROP COLUMN borrowed_licenses;
@@ -0,0 +1,2 @@
+-- Track FlexLM borrowed (lingering) seats per feature
+ALTER TABLE features ADD COLUMN borrowed_licenses INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,12 @@
+-- When the feature metadata shown alongside collected data last changed, so
+-- that conditional requests notice edits of display names, tags and contracts
+
+CREATE TABLE IF NOT EXISTS metadata_revisions (
+    name TEXT PRIMARY KEY,
+    updated_at TIMESTAMP NOT NULL
+);
+
+INSERT INTO metadata_revisions (name, updated_at) VALUES
+    ('display_names', CURRENT_TIMESTAMP),
+    ('feature_tags', CURRENT_TIMESTAMP),
+    ('license_contracts', CURRENT_TIMESTAMP);
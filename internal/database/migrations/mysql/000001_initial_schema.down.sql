@@ -0,0 +1,8 @@
+-- Rollback initial schema (MySQL); indexes are dropped with their tables
+
+DROP TABLE IF EXISTS alert_events;
+DROP TABLE IF EXISTS alerts;
+DROP TABLE IF EXISTS license_events;
+DROP TABLE IF EXISTS feature_usage;
+DROP TABLE IF EXISTS features;
+DROP TABLE IF EXISTS servers;
@@ -0,0 +1,76 @@
+-- Initial database schema (MySQL)
+-- TEXT columns cannot be part of a key here, so keyed columns are VARCHARs
+-- short enough for the InnoDB index size limit.
+
+CREATE TABLE IF NOT EXISTS servers (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    hostname VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT,
+    type VARCHAR(32) NOT NULL,
+    cacti_id VARCHAR(255),
+    webui TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS features (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    server_hostname VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    version VARCHAR(255),
+    vendor_daemon VARCHAR(255),
+    total_licenses INTEGER NOT NULL,
+    used_licenses INTEGER NOT NULL,
+    expiration_date TIMESTAMP NULL,
+    last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(server_hostname, name, version, expiration_date)
+);
+
+CREATE TABLE IF NOT EXISTS feature_usage (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    server_hostname VARCHAR(255) NOT NULL,
+    feature_name VARCHAR(255) NOT NULL,
+    date DATE NOT NULL,
+    time TIME NOT NULL,
+    users_count INTEGER NOT NULL,
+    UNIQUE(server_hostname, feature_name, date, time)
+);
+
+-- The constraint is named so 000005 can replace it
+CREATE TABLE IF NOT EXISTS license_events (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    event_date DATE NOT NULL,
+    event_time TIME NOT NULL,
+    event_type VARCHAR(32) NOT NULL,
+    feature_name VARCHAR(255) NOT NULL,
+    username VARCHAR(255) NOT NULL,
+    reason TEXT,
+    CONSTRAINT license_events_identity UNIQUE(event_date, event_time, feature_name, username)
+);
+
+CREATE TABLE IF NOT EXISTS alerts (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    server_hostname VARCHAR(255) NOT NULL,
+    feature_name VARCHAR(255),
+    alert_type VARCHAR(64) NOT NULL,
+    message TEXT NOT NULL,
+    severity VARCHAR(16) NOT NULL,
+    sent BOOLEAN DEFAULT FALSE,
+    sent_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS alert_events (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    datetime TIMESTAMP NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    hostname VARCHAR(255) NOT NULL
+);
+
+-- Indexes for performance
+CREATE INDEX idx_features_server ON features(server_hostname);
+CREATE INDEX idx_features_expiration ON features(expiration_date);
+CREATE INDEX idx_usage_server_feature ON feature_usage(server_hostname, feature_name);
+CREATE INDEX idx_usage_date ON feature_usage(date);
+CREATE INDEX idx_alerts_sent ON alerts(sent);
+CREATE INDEX idx_events_date ON license_events(event_date);
@@ -0,0 +1,14 @@
+-- Clean up duplicate permanent license records (MySQL)
+-- MySQL cannot delete from a table it selects from in a subquery, so the
+-- ids to keep are materialized in a derived table first.
+
+DELETE FROM features
+WHERE id NOT IN (
+    SELECT id FROM (
+        SELECT MAX(id) AS id
+        FROM features
+        WHERE expiration_date IS NOT NULL
+        GROUP BY server_hostname, name, version
+    ) AS latest
+)
+AND expiration_date IS NOT NULL;
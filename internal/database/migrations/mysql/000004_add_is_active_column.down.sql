@@ -0,0 +1,4 @@
+-- Remove is_active column from features table (MySQL)
+
+DROP INDEX idx_features_active ON features;
+ALTER TABLE features DROP COLUMN is_active;
@@ -0,0 +1,7 @@
+-- Add is_active column to features table (MySQL)
+
+ALTER TABLE features ADD COLUMN is_active BOOLEAN DEFAULT TRUE;
+
+UPDATE features SET is_active = TRUE WHERE is_active IS NULL;
+
+CREATE INDEX idx_features_active ON features(is_active);
@@ -0,0 +1,17 @@
+-- Remove server_hostname from license_events (MySQL)
+
+DROP INDEX idx_events_server ON license_events;
+ALTER TABLE license_events DROP INDEX license_events_identity;
+
+-- Events that collide without the server are dropped
+DELETE a FROM license_events a
+JOIN license_events b
+  ON a.event_date = b.event_date
+ AND a.event_time = b.event_time
+ AND a.feature_name = b.feature_name
+ AND a.username = b.username
+ AND a.id > b.id;
+
+ALTER TABLE license_events
+    DROP COLUMN server_hostname,
+    ADD CONSTRAINT license_events_identity UNIQUE(event_date, event_time, feature_name, username);
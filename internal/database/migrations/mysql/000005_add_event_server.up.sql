@@ -0,0 +1,12 @@
+-- Add server_hostname to license_events (MySQL)
+-- The server is part of the event identity, so the UNIQUE constraint is
+-- replaced. username is shortened to keep the key within the InnoDB limit.
+
+ALTER TABLE license_events
+    ADD COLUMN server_hostname VARCHAR(255) NOT NULL DEFAULT '',
+    MODIFY username VARCHAR(128) NOT NULL,
+    DROP INDEX license_events_identity,
+    ADD CONSTRAINT license_events_identity
+        UNIQUE(server_hostname, event_date, event_time, event_type, feature_name, username);
+
+CREATE INDEX idx_events_server ON license_events(server_hostname);
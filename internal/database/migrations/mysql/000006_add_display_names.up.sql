@@ -0,0 +1,11 @@
+-- Add feature display name overrides (MySQL)
+-- An empty server_hostname applies the override to every server.
+
+CREATE TABLE IF NOT EXISTS feature_display_names (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    server_hostname VARCHAR(255) NOT NULL DEFAULT '',
+    feature_name VARCHAR(255) NOT NULL,
+    display_name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(server_hostname, feature_name)
+);
@@ -0,0 +1,5 @@
+-- Allow NULL feature version and vendor_daemon again (MySQL)
+
+ALTER TABLE features
+    MODIFY version VARCHAR(255) NULL,
+    MODIFY vendor_daemon VARCHAR(255) NULL;
@@ -0,0 +1,17 @@
+-- Make feature version and vendor_daemon NOT NULL (MySQL)
+
+UPDATE features SET vendor_daemon = '' WHERE vendor_daemon IS NULL;
+
+DELETE a FROM features a
+JOIN features b
+  ON a.server_hostname = b.server_hostname
+ AND a.name = b.name
+ AND a.expiration_date <=> b.expiration_date
+WHERE a.version IS NULL
+  AND (b.version = '' OR (b.version IS NULL AND a.id < b.id));
+
+UPDATE features SET version = '' WHERE version IS NULL;
+
+ALTER TABLE features
+    MODIFY version VARCHAR(255) NOT NULL DEFAULT '',
+    MODIFY vendor_daemon VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP INDEX idx_features_license_model ON features;
+ALTER TABLE features DROP COLUMN license_model;
@@ -0,0 +1,8 @@
+-- Classify features as floating, node-locked or uncounted
+ALTER TABLE features ADD COLUMN license_model VARCHAR(32) NOT NULL DEFAULT 'floating';
+
+-- Uncounted FlexLM features were stored with 9999 seats; other rows are
+-- reclassified on the next poll
+UPDATE features SET license_model = 'uncounted' WHERE total_licenses = 9999;
+
+CREATE INDEX idx_features_license_model ON features(license_model);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS server_master_events;
@@ -0,0 +1,13 @@
+-- Track which host holds the MASTER role of a (redundant) license server
+-- (MySQL). The first observation of a server is stored with an empty
+-- previous_master.
+
+CREATE TABLE IF NOT EXISTS server_master_events (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    server_hostname VARCHAR(255) NOT NULL,
+    previous_master VARCHAR(255) NOT NULL DEFAULT '',
+    new_master VARCHAR(255) NOT NULL,
+    detected_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_master_events_server ON server_master_events(server_hostname, detected_at);
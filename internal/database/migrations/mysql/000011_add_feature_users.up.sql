@@ -0,0 +1,14 @@
+-- Track when each user was first and last seen using a feature (MySQL)
+
+CREATE TABLE IF NOT EXISTS feature_users (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    server_hostname VARCHAR(255) NOT NULL,
+    feature_name VARCHAR(255) NOT NULL,
+    username VARCHAR(255) NOT NULL,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    UNIQUE(server_hostname, feature_name, username)
+);
+
+CREATE INDEX idx_feature_users_first_seen ON feature_users(first_seen);
+CREATE INDEX idx_feature_users_last_seen ON feature_users(last_seen);
@@ -0,0 +1,12 @@
+-- Audit trail of administrative operations (MySQL)
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    actor VARCHAR(255) NOT NULL,
+    action VARCHAR(64) NOT NULL,
+    target VARCHAR(255) NOT NULL DEFAULT '',
+    details TEXT NOT NULL
+);
+
+CREATE INDEX idx_audit_log_created ON audit_log(created_at);
@@ -0,0 +1,3 @@
+DROP INDEX idx_alerts_incident ON alerts;
+ALTER TABLE alerts DROP COLUMN incident_id;
+DROP TABLE IF EXISTS incidents;
@@ -0,0 +1,20 @@
+-- Correlate related alerts into incidents. Alerts for the same server raised
+-- within the correlation window share an incident and one notification
+-- thread (MySQL).
+
+CREATE TABLE IF NOT EXISTS incidents (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    server_hostname VARCHAR(255) NOT NULL,
+    root_cause VARCHAR(255) NOT NULL,
+    severity VARCHAR(16) NOT NULL,
+    alert_count INTEGER NOT NULL DEFAULT 0,
+    opened_at TIMESTAMP NOT NULL,
+    last_alert_at TIMESTAMP NOT NULL,
+    notified BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX idx_incidents_server ON incidents(server_hostname, last_alert_at);
+
+ALTER TABLE alerts ADD COLUMN incident_id INTEGER;
+
+CREATE INDEX idx_alerts_incident ON alerts(incident_id);
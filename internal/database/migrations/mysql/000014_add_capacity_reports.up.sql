@@ -0,0 +1,11 @@
+-- Latest generated capacity planning report per analysis period.
+-- Reports are generated on a schedule and served from here (MySQL).
+-- A report can exceed the 64 KB of a TEXT column.
+
+CREATE TABLE IF NOT EXISTS capacity_reports (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    period_days INTEGER NOT NULL UNIQUE,
+    generated_at TIMESTAMP NOT NULL,
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    report MEDIUMTEXT NOT NULL
+);
@@ -0,0 +1,21 @@
+-- Per-feature usage trend statistics, recomputed after each collection so
+-- trend and prediction endpoints can read them instead of running regressions
+-- (MySQL)
+
+CREATE TABLE IF NOT EXISTS feature_trends (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    server_hostname VARCHAR(255) NOT NULL,
+    feature_name VARCHAR(255) NOT NULL,
+    period_days INTEGER NOT NULL,
+    samples INTEGER NOT NULL DEFAULT 0,
+    slope DOUBLE NOT NULL DEFAULT 0,
+    intercept DOUBLE NOT NULL DEFAULT 0,
+    r_squared DOUBLE NOT NULL DEFAULT 0,
+    last_day DOUBLE NOT NULL DEFAULT 0,
+    current_usage INTEGER NOT NULL DEFAULT 0,
+    avg_usage DOUBLE NOT NULL DEFAULT 0,
+    std_dev DOUBLE NOT NULL DEFAULT 0,
+    peak_usage INTEGER NOT NULL DEFAULT 0,
+    computed_at TIMESTAMP NOT NULL,
+    UNIQUE(server_hostname, feature_name, period_days)
+);
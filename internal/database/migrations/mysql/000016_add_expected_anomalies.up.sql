@@ -0,0 +1,13 @@
+-- Anomalies marked as expected (e.g. a known training event) are no longer
+-- reported by anomaly detection (MySQL)
+
+CREATE TABLE IF NOT EXISTS expected_anomalies (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    server_hostname VARCHAR(255) NOT NULL,
+    feature_name VARCHAR(255) NOT NULL,
+    date DATE NOT NULL,
+    note VARCHAR(1024) NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(server_hostname, feature_name, date)
+);
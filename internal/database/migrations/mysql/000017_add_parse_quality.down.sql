@@ -0,0 +1,2 @@
+DROP INDEX idx_features_parse_quality ON features;
+ALTER TABLE features DROP COLUMN parse_quality;
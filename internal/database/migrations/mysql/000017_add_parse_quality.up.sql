@@ -0,0 +1,5 @@
+-- Flag features whose license data could only be parsed by falling back to
+-- defaults, e.g. an unparseable expiration date stored as permanent
+ALTER TABLE features ADD COLUMN parse_quality VARCHAR(32) NOT NULL DEFAULT 'ok';
+
+CREATE INDEX idx_features_parse_quality ON features(parse_quality);
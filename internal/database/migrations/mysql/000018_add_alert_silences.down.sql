@@ -0,0 +1,2 @@
+ALTER TABLE alerts DROP COLUMN silence_id;
+DROP TABLE IF EXISTS alert_silences;
@@ -0,0 +1,16 @@
+-- Silences mirrored from Alertmanager. Alerts matching an active silence are
+-- kept for reference but not sent.
+
+CREATE TABLE IF NOT EXISTS alert_silences (
+    id VARCHAR(64) PRIMARY KEY,
+    matchers TEXT NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    comment TEXT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_alert_silences_ends_at ON alert_silences(ends_at);
+
+ALTER TABLE alerts ADD COLUMN silence_id VARCHAR(64);
@@ -0,0 +1,14 @@
+-- Utilization alert thresholds overriding the global alerts.utilization_*
+-- settings. An empty server applies to every server, an empty feature to
+-- every feature of the server (MySQL).
+
+CREATE TABLE IF NOT EXISTS alert_thresholds (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    server_hostname VARCHAR(255) NOT NULL DEFAULT '',
+    feature_name VARCHAR(255) NOT NULL DEFAULT '',
+    warning_pct DOUBLE NOT NULL,
+    critical_pct DOUBLE NOT NULL,
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(server_hostname, feature_name)
+);
@@ -0,0 +1,24 @@
+-- Configurable alert rules evaluated after each collection, and the time
+-- since which each rule's condition has held for a server or feature
+
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(255) NOT NULL,
+    rule_type VARCHAR(32) NOT NULL,
+    server_hostname VARCHAR(255) NOT NULL DEFAULT '',
+    feature_pattern VARCHAR(255) NOT NULL DEFAULT '',
+    threshold DOUBLE NOT NULL DEFAULT 0,
+    duration_min INTEGER NOT NULL DEFAULT 0,
+    severity VARCHAR(16) NOT NULL DEFAULT 'warning',
+    enabled BOOLEAN NOT NULL DEFAULT 1,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS alert_rule_state (
+    rule_id INTEGER NOT NULL,
+    subject VARCHAR(512) NOT NULL,
+    since TIMESTAMP NOT NULL,
+    PRIMARY KEY (rule_id, subject)
+);
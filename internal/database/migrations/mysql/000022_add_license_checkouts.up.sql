@@ -0,0 +1,17 @@
+-- Individual license checkouts, from the first to the last collection that
+-- saw them, for per-user usage history
+
+CREATE TABLE IF NOT EXISTS license_checkouts (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    server_hostname VARCHAR(255) NOT NULL,
+    feature_name VARCHAR(255) NOT NULL,
+    username VARCHAR(255) NOT NULL,
+    host VARCHAR(255) NOT NULL DEFAULT '',
+    checked_out_at TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    checked_in_at TIMESTAMP NULL
+);
+
+CREATE INDEX idx_license_checkouts_open ON license_checkouts(server_hostname, checked_in_at);
+CREATE INDEX idx_license_checkouts_username ON license_checkouts(username);
+CREATE INDEX idx_license_checkouts_feature ON license_checkouts(feature_name, last_seen);
@@ -0,0 +1,2 @@
+DROP INDEX idx_events_type_date ON license_events;
+ALTER TABLE license_events DROP COLUMN reason_code;
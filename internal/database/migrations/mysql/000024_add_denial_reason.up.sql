@@ -0,0 +1,6 @@
+-- Machine-readable denial reason classified from the vendor's message.
+-- Earlier denials are classified when read. event_type was TEXT on early
+-- schemas, so the index takes a prefix of it.
+ALTER TABLE license_events ADD COLUMN reason_code VARCHAR(32) DEFAULT '';
+
+CREATE INDEX idx_events_type_date ON license_events(event_type(16), event_date);
@@ -0,0 +1,2 @@
+DROP INDEX idx_entitlements_source ON entitlements;
+DROP TABLE IF EXISTS entitlements;
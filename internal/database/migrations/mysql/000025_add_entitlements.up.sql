@@ -0,0 +1,13 @@
+-- Entitlements purchased according to vendor portals, replaced on every sync
+-- of their source, for reconciliation against the license servers
+
+CREATE TABLE IF NOT EXISTS entitlements (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    source VARCHAR(255) NOT NULL,
+    feature_name VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL,
+    end_date TIMESTAMP NULL,
+    fetched_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_entitlements_source ON entitlements(source);
@@ -0,0 +1,6 @@
+ALTER TABLE audit_log DROP COLUMN duration_ms;
+ALTER TABLE audit_log DROP COLUMN status;
+ALTER TABLE audit_log DROP COLUMN path;
+ALTER TABLE audit_log DROP COLUMN method;
+ALTER TABLE audit_log DROP COLUMN remote_addr;
+ALTER TABLE audit_log DROP COLUMN role;
@@ -0,0 +1,9 @@
+-- Request details of audit entries recorded for mutating API calls.
+-- Entries of administrative operations leave them empty. TEXT columns cannot
+-- have defaults here, so these are VARCHARs.
+ALTER TABLE audit_log ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE audit_log ADD COLUMN remote_addr VARCHAR(45) NOT NULL DEFAULT '';
+ALTER TABLE audit_log ADD COLUMN method VARCHAR(10) NOT NULL DEFAULT '';
+ALTER TABLE audit_log ADD COLUMN path VARCHAR(2048) NOT NULL DEFAULT '';
+ALTER TABLE audit_log ADD COLUMN status INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE audit_log ADD COLUMN duration_ms INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,31 @@
+-- Usage aggregated per hour and per day (MySQL). Raw feature_usage samples
+-- of days older than rollup.after_days are rolled up nightly, and queries
+-- over long periods read rolled-up days from here.
+
+CREATE TABLE IF NOT EXISTS feature_usage_hourly (
+    server_hostname VARCHAR(255) NOT NULL,
+    feature_name VARCHAR(255) NOT NULL,
+    date DATE NOT NULL,
+    hour INTEGER NOT NULL,
+    samples INTEGER NOT NULL,
+    sum_users BIGINT NOT NULL,
+    sum_squares BIGINT NOT NULL,
+    peak_users INTEGER NOT NULL,
+    min_users INTEGER NOT NULL,
+    PRIMARY KEY (server_hostname, feature_name, date, hour)
+);
+
+CREATE TABLE IF NOT EXISTS feature_usage_daily (
+    server_hostname VARCHAR(255) NOT NULL,
+    feature_name VARCHAR(255) NOT NULL,
+    date DATE NOT NULL,
+    samples INTEGER NOT NULL,
+    sum_users BIGINT NOT NULL,
+    sum_squares BIGINT NOT NULL,
+    peak_users INTEGER NOT NULL,
+    min_users INTEGER NOT NULL,
+    PRIMARY KEY (server_hostname, feature_name, date)
+);
+
+CREATE INDEX idx_usage_hourly_date ON feature_usage_hourly(date);
+CREATE INDEX idx_usage_daily_date ON feature_usage_daily(date);
@@ -0,0 +1,14 @@
+-- History of data retention runs, scheduled or triggered from the API (MySQL)
+
+CREATE TABLE IF NOT EXISTS retention_runs (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    triggered_by VARCHAR(64) NOT NULL,
+    dry_run BOOLEAN NOT NULL DEFAULT 0,
+    rows_deleted BIGINT NOT NULL DEFAULT 0,
+    results TEXT NOT NULL,
+    error VARCHAR(1024) NOT NULL DEFAULT '',
+    started_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_retention_runs_started ON retention_runs(started_at);
@@ -0,0 +1,12 @@
+-- Stable identifiers of servers and features, which are otherwise addressed
+-- by name. Each resource keeps its id and UUID however its rows change. (MySQL)
+
+CREATE TABLE IF NOT EXISTS resource_ids (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    kind VARCHAR(16) NOT NULL,
+    server_hostname VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    uuid CHAR(36) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE(kind, server_hostname, name)
+);
@@ -0,0 +1,25 @@
+-- Queue of long-running work such as backfills, exports and report
+-- generation, run by worker goroutines and kept for a while once finished.
+-- Running jobs record their instance and its last lease renewal, so that
+-- replicas requeue only the jobs of instances that stopped renewing (MySQL)
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id VARCHAR(32) PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    server VARCHAR(255) NOT NULL DEFAULT '',
+    params TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 1,
+    result TEXT NOT NULL,
+    error VARCHAR(1024) NOT NULL DEFAULT '',
+    run_after TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    started_at TIMESTAMP NULL,
+    finished_at TIMESTAMP NULL,
+    worker VARCHAR(128) NOT NULL DEFAULT '',
+    heartbeat_at TIMESTAMP NULL
+);
+
+CREATE INDEX idx_jobs_status ON jobs(status, run_after);
+CREATE INDEX idx_jobs_created ON jobs(created_at);
@@ -0,0 +1,19 @@
+-- Outgoing email waiting to be sent, retried with backoff until it is sent
+-- or given up on and kept as a dead letter to be requeued (MySQL)
+
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    subject VARCHAR(512) NOT NULL,
+    recipients TEXT NOT NULL,
+    message LONGTEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 1,
+    error VARCHAR(1024) NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    sent_at TIMESTAMP NULL
+);
+
+CREATE INDEX idx_email_outbox_status ON email_outbox(status, next_attempt_at);
+CREATE INDEX idx_email_outbox_created ON email_outbox(created_at);
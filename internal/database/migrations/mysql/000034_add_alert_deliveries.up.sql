@@ -0,0 +1,18 @@
+-- Receipts of alert notifications per channel and target, showing for audits
+-- that an alert was delivered (MySQL)
+
+CREATE TABLE IF NOT EXISTS alert_deliveries (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    alert_id INTEGER NOT NULL,
+    channel VARCHAR(32) NOT NULL,
+    target VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    message_id VARCHAR(255) NOT NULL DEFAULT '',
+    error VARCHAR(1024) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_alert_deliveries_alert ON alert_deliveries(alert_id);
+CREATE INDEX idx_alert_deliveries_message ON alert_deliveries(message_id);
+CREATE INDEX idx_alert_deliveries_created ON alert_deliveries(created_at);
@@ -0,0 +1,15 @@
+-- Users waiting for a seat of a feature to free up. A subscription is
+-- notified once, then kept with notified_at set (MySQL)
+
+CREATE TABLE IF NOT EXISTS waitlist_subscriptions (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    username VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    server_hostname VARCHAR(255) NOT NULL DEFAULT '',
+    feature_name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    notified_at TIMESTAMP NULL
+);
+
+CREATE INDEX idx_waitlist_feature ON waitlist_subscriptions(feature_name, notified_at);
+CREATE INDEX idx_waitlist_username ON waitlist_subscriptions(username);
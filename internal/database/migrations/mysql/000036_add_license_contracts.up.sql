@@ -0,0 +1,19 @@
+-- Cost and contract details of licensed features. cost_per_seat is the
+-- yearly cost of one seat. An empty server applies to the feature on every
+-- server (MySQL).
+
+CREATE TABLE IF NOT EXISTS license_contracts (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    server_hostname VARCHAR(255) NOT NULL DEFAULT '',
+    feature_name VARCHAR(255) NOT NULL,
+    cost_per_seat DOUBLE NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    renewal_date TIMESTAMP NULL,
+    po_number VARCHAR(255) NOT NULL DEFAULT '',
+    vendor_contact VARCHAR(255) NOT NULL DEFAULT '',
+    notes VARCHAR(1024) NOT NULL DEFAULT '',
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE(server_hostname, feature_name)
+);
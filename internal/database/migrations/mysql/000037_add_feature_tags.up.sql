@@ -0,0 +1,14 @@
+-- Tags grouping features for reporting, e.g. by department. An empty server
+-- tags the feature on every server (MySQL)
+
+CREATE TABLE IF NOT EXISTS feature_tags (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    server_hostname VARCHAR(255) NOT NULL DEFAULT '',
+    feature_name VARCHAR(255) NOT NULL,
+    tag VARCHAR(64) NOT NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE(server_hostname, feature_name, tag)
+);
+
+CREATE INDEX idx_feature_tags_tag ON feature_tags(tag);
@@ -0,0 +1,3 @@
+-- Remove the server groups (MySQL)
+DROP INDEX idx_servers_group ON servers;
+ALTER TABLE servers DROP COLUMN group_name;
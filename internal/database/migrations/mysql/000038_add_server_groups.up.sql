@@ -0,0 +1,6 @@
+-- Site or group of each server, e.g. emea, synced from the server config at
+-- startup for analytics to filter and aggregate by. TEXT columns cannot have
+-- defaults here (MySQL)
+ALTER TABLE servers ADD COLUMN group_name VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE INDEX idx_servers_group ON servers(group_name);
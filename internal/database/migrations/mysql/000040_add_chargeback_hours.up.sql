@@ -0,0 +1,16 @@
+-- Checkout hours of each cost center per month, server and feature, for
+-- chargeback reports. Rows of a month are replaced when it is aggregated
+-- again (MySQL).
+
+CREATE TABLE IF NOT EXISTS chargeback_hours (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    month VARCHAR(7) NOT NULL,
+    cost_center VARCHAR(128) NOT NULL,
+    server_hostname VARCHAR(255) NOT NULL,
+    feature_name VARCHAR(255) NOT NULL,
+    checkout_hours DOUBLE NOT NULL DEFAULT 0,
+    checkouts INTEGER NOT NULL DEFAULT 0,
+    users INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE(month, cost_center, server_hostname, feature_name)
+);
@@ -0,0 +1,13 @@
+-- Components of FlexLM PACKAGE features and the tokens each checkout of a
+-- component draws from the package's pool. Rows of a server are replaced on
+-- each collection (MySQL).
+
+CREATE TABLE IF NOT EXISTS feature_packages (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    server_hostname VARCHAR(255) NOT NULL,
+    package_name VARCHAR(255) NOT NULL,
+    feature_name VARCHAR(255) NOT NULL,
+    version VARCHAR(64) NOT NULL DEFAULT '',
+    tokens INTEGER NOT NULL DEFAULT 1,
+    UNIQUE(server_hostname, package_name, feature_name)
+);
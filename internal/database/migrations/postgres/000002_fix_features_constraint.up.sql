@@ -0,0 +1,5 @@
+-- Fix features table UNIQUE constraint (PostgreSQL)
+-- PostgreSQL schemas were created with the correct constraint; only SQLite
+-- databases from before 000001 was fixed need the rebuild.
+
+SELECT 'Migration 000002 is a no-op on PostgreSQL' AS message;
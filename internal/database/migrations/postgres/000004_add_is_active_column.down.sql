@@ -0,0 +1,4 @@
+-- Remove is_active column from features table (PostgreSQL)
+
+DROP INDEX IF EXISTS idx_features_active;
+ALTER TABLE features DROP COLUMN is_active;
@@ -0,0 +1,17 @@
+-- Remove server_hostname from license_events (PostgreSQL)
+
+DROP INDEX IF EXISTS idx_events_server;
+ALTER TABLE license_events DROP CONSTRAINT license_events_identity;
+
+-- Events that collide without the server are dropped
+DELETE FROM license_events a
+USING license_events b
+WHERE a.event_date = b.event_date
+  AND a.event_time = b.event_time
+  AND a.feature_name = b.feature_name
+  AND a.username = b.username
+  AND a.id > b.id;
+
+ALTER TABLE license_events DROP COLUMN server_hostname;
+ALTER TABLE license_events ADD CONSTRAINT license_events_identity
+    UNIQUE(event_date, event_time, feature_name, username);
@@ -0,0 +1,11 @@
+-- Add server_hostname to license_events (PostgreSQL)
+-- The server is part of the event identity, so the UNIQUE constraint is
+-- replaced.
+
+ALTER TABLE license_events ADD COLUMN server_hostname TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE license_events DROP CONSTRAINT license_events_identity;
+ALTER TABLE license_events ADD CONSTRAINT license_events_identity
+    UNIQUE(server_hostname, event_date, event_time, event_type, feature_name, username);
+
+CREATE INDEX IF NOT EXISTS idx_events_server ON license_events(server_hostname);
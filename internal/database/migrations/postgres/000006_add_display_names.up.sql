@@ -0,0 +1,11 @@
+-- Add feature display name overrides (PostgreSQL)
+-- An empty server_hostname applies the override to every server.
+
+CREATE TABLE IF NOT EXISTS feature_display_names (
+    id SERIAL PRIMARY KEY,
+    server_hostname TEXT NOT NULL DEFAULT '',
+    feature_name TEXT NOT NULL,
+    display_name TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(server_hostname, feature_name)
+);
@@ -0,0 +1,7 @@
+-- Allow NULL feature version and vendor_daemon again (PostgreSQL)
+
+ALTER TABLE features
+    ALTER COLUMN version DROP NOT NULL,
+    ALTER COLUMN version DROP DEFAULT,
+    ALTER COLUMN vendor_daemon DROP NOT NULL,
+    ALTER COLUMN vendor_daemon DROP DEFAULT;
@@ -0,0 +1,19 @@
+-- Make feature version and vendor_daemon NOT NULL (PostgreSQL)
+
+UPDATE features SET vendor_daemon = '' WHERE vendor_daemon IS NULL;
+
+DELETE FROM features a
+USING features b
+WHERE a.version IS NULL
+  AND a.server_hostname = b.server_hostname
+  AND a.name = b.name
+  AND a.expiration_date IS NOT DISTINCT FROM b.expiration_date
+  AND (b.version = '' OR (b.version IS NULL AND a.id < b.id));
+
+UPDATE features SET version = '' WHERE version IS NULL;
+
+ALTER TABLE features
+    ALTER COLUMN version SET DEFAULT '',
+    ALTER COLUMN version SET NOT NULL,
+    ALTER COLUMN vendor_daemon SET DEFAULT '',
+    ALTER COLUMN vendor_daemon SET NOT NULL;
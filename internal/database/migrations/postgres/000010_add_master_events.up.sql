@@ -0,0 +1,13 @@
+-- Track which host holds the MASTER role of a (redundant) license server
+-- (PostgreSQL). The first observation of a server is stored with an empty
+-- previous_master.
+
+CREATE TABLE IF NOT EXISTS server_master_events (
+    id SERIAL PRIMARY KEY,
+    server_hostname TEXT NOT NULL,
+    previous_master TEXT NOT NULL DEFAULT '',
+    new_master TEXT NOT NULL,
+    detected_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_master_events_server ON server_master_events(server_hostname, detected_at);
@@ -0,0 +1,21 @@
+-- Per-feature usage trend statistics, recomputed after each collection so
+-- trend and prediction endpoints can read them instead of running regressions
+-- (PostgreSQL)
+
+CREATE TABLE IF NOT EXISTS feature_trends (
+    id SERIAL PRIMARY KEY,
+    server_hostname TEXT NOT NULL,
+    feature_name TEXT NOT NULL,
+    period_days INTEGER NOT NULL,
+    samples INTEGER NOT NULL DEFAULT 0,
+    slope DOUBLE PRECISION NOT NULL DEFAULT 0,
+    intercept DOUBLE PRECISION NOT NULL DEFAULT 0,
+    r_squared DOUBLE PRECISION NOT NULL DEFAULT 0,
+    last_day DOUBLE PRECISION NOT NULL DEFAULT 0,
+    current_usage INTEGER NOT NULL DEFAULT 0,
+    avg_usage DOUBLE PRECISION NOT NULL DEFAULT 0,
+    std_dev DOUBLE PRECISION NOT NULL DEFAULT 0,
+    peak_usage INTEGER NOT NULL DEFAULT 0,
+    computed_at TIMESTAMP NOT NULL,
+    UNIQUE(server_hostname, feature_name, period_days)
+);
@@ -0,0 +1,14 @@
+-- Utilization alert thresholds overriding the global alerts.utilization_*
+-- settings. An empty server applies to every server, an empty feature to
+-- every feature of the server (PostgreSQL).
+
+CREATE TABLE IF NOT EXISTS alert_thresholds (
+    id SERIAL PRIMARY KEY,
+    server_hostname TEXT NOT NULL DEFAULT '',
+    feature_name TEXT NOT NULL DEFAULT '',
+    warning_pct DOUBLE PRECISION NOT NULL,
+    critical_pct DOUBLE PRECISION NOT NULL,
+    updated_by TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(server_hostname, feature_name)
+);
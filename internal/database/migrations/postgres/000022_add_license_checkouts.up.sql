@@ -0,0 +1,17 @@
+-- Individual license checkouts, from the first to the last collection that (PostgreSQL)
+-- saw them, for per-user usage history
+
+CREATE TABLE IF NOT EXISTS license_checkouts (
+    id SERIAL PRIMARY KEY,
+    server_hostname TEXT NOT NULL,
+    feature_name TEXT NOT NULL,
+    username TEXT NOT NULL,
+    host TEXT NOT NULL DEFAULT '',
+    checked_out_at TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    checked_in_at TIMESTAMP NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_license_checkouts_open ON license_checkouts(server_hostname, checked_in_at);
+CREATE INDEX IF NOT EXISTS idx_license_checkouts_username ON license_checkouts(username);
+CREATE INDEX IF NOT EXISTS idx_license_checkouts_feature ON license_checkouts(feature_name, last_seen);
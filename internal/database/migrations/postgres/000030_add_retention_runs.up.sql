@@ -0,0 +1,14 @@
+-- History of data retention runs, scheduled or triggered from the API (PostgreSQL)
+
+CREATE TABLE IF NOT EXISTS retention_runs (
+    id SERIAL PRIMARY KEY,
+    triggered_by TEXT NOT NULL,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    rows_deleted BIGINT NOT NULL DEFAULT 0,
+    results TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_retention_runs_started ON retention_runs(started_at);
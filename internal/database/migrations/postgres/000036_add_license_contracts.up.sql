@@ -0,0 +1,19 @@
+-- Cost and contract details of licensed features. cost_per_seat is the
+-- yearly cost of one seat. An empty server applies to the feature on every
+-- server (PostgreSQL).
+
+CREATE TABLE IF NOT EXISTS license_contracts (
+    id SERIAL PRIMARY KEY,
+    server_hostname TEXT NOT NULL DEFAULT '',
+    feature_name TEXT NOT NULL,
+    cost_per_seat DOUBLE PRECISION NOT NULL DEFAULT 0,
+    currency TEXT NOT NULL DEFAULT 'USD',
+    renewal_date TIMESTAMP NULL,
+    po_number TEXT NOT NULL DEFAULT '',
+    vendor_contact TEXT NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    updated_by TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE(server_hostname, feature_name)
+);
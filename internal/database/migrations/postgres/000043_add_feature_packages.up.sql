@@ -0,0 +1,13 @@
+-- Components of FlexLM PACKAGE features and the tokens each checkout of a
+-- component draws from the package's pool. Rows of a server are replaced on
+-- each collection (PostgreSQL).
+
+CREATE TABLE IF NOT EXISTS feature_packages (
+    id SERIAL PRIMARY KEY,
+    server_hostname TEXT NOT NULL,
+    package_name TEXT NOT NULL,
+    feature_name TEXT NOT NULL,
+    version TEXT NOT NULL DEFAULT '',
+    tokens INTEGER NOT NULL DEFAULT 1,
+    UNIQUE(server_hostname, package_name, feature_name)
+);
@@ -0,0 +1,15 @@
+-- A hypertable cannot be converted back in place; copy the rows into a
+-- plain table
+
+CREATE TABLE feature_usage_plain (LIKE feature_usage INCLUDING DEFAULTS);
+INSERT INTO feature_usage_plain SELECT * FROM feature_usage;
+
+-- Keep the id sequence when the hypertable is dropped
+ALTER SEQUENCE feature_usage_id_seq OWNED BY feature_usage_plain.id;
+DROP TABLE feature_usage;
+ALTER TABLE feature_usage_plain RENAME TO feature_usage;
+
+ALTER TABLE feature_usage ADD PRIMARY KEY (id);
+ALTER TABLE feature_usage ADD UNIQUE (server_hostname, feature_name, date, time);
+CREATE INDEX IF NOT EXISTS idx_usage_server_feature ON feature_usage(server_hostname, feature_name);
+CREATE INDEX IF NOT EXISTS idx_usage_date ON feature_usage(date);
@@ -0,0 +1,15 @@
+-- Partition feature_usage into weekly chunks by date (TimescaleDB)
+
+CREATE EXTENSION IF NOT EXISTS timescaledb;
+
+-- Unique indexes of a hypertable must include the partitioning column;
+-- UNIQUE(server_hostname, feature_name, date, time) already does
+ALTER TABLE feature_usage DROP CONSTRAINT IF EXISTS feature_usage_pkey;
+ALTER TABLE feature_usage ADD PRIMARY KEY (id, date);
+
+-- idx_usage_date already serves as the time index
+SELECT create_hypertable('feature_usage', 'date',
+    chunk_time_interval => INTERVAL '7 days',
+    create_default_indexes => FALSE,
+    migrate_data => TRUE,
+    if_not_exists => TRUE);
@@ -0,0 +1,270 @@
+// Package grpcapi serves the licet.v1 gRPC service defined in
+// proto/licet/v1/licet.proto, from the same services as the REST API.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/models"
+	"licet/internal/services"
+	"licet/pkg/licetv1"
+)
+
+// streamBuffer is how many events a stream may fall behind by before it
+// misses some
+const streamBuffer = 64
+
+// Service implements licet.v1.LicetService
+type Service struct {
+	licetv1.UnimplementedLicetServiceServer
+
+	query     *services.QueryService
+	storage   *services.StorageService
+	analytics *services.AnalyticsService
+	names     *services.DisplayNameService
+	bus       *services.EventBus
+}
+
+// NewService creates the gRPC service. storage and names may be nil.
+func NewService(query *services.QueryService, storage *services.StorageService, analytics *services.AnalyticsService, names *services.DisplayNameService, bus *services.EventBus) *Service {
+	return &Service{query: query, storage: storage, analytics: analytics, names: names, bus: bus}
+}
+
+// NewServer creates a gRPC server serving svc. With authentication enabled,
+// every call needs the read permission; with server.tls_enabled the server
+// uses the certificate of the web server.
+func NewServer(cfg *config.Config, auth *middleware.Authenticator, svc *Service) (*grpc.Server, error) {
+	var opts []grpc.ServerOption
+	if cfg.Server.TLSEnabled {
+		creds, err := credentials.NewServerTLSFromFile(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	if cfg.Auth.Enabled && auth != nil {
+		opts = append(opts,
+			grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				ctx, err := authenticate(ctx, auth, info.FullMethod)
+				if err != nil {
+					return nil, err
+				}
+				return handler(ctx, req)
+			}),
+			grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if _, err := authenticate(ss.Context(), auth, info.FullMethod); err != nil {
+					return err
+				}
+				return handler(srv, ss)
+			}),
+		)
+	}
+
+	server := grpc.NewServer(opts...)
+	licetv1.RegisterLicetServiceServer(server, svc)
+	return server, nil
+}
+
+// authenticate authenticates a call by the API key in its metadata, like the
+// auth middleware authenticates requests by their headers
+func authenticate(ctx context.Context, auth *middleware.Authenticator, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	r := &http.Request{Method: http.MethodGet, URL: &url.URL{Path: method}, Header: http.Header{}}
+	for _, key := range []string{"authorization", "x-api-key"} {
+		if v := md.Get(key); len(v) > 0 {
+			r.Header.Set(key, v[0])
+		}
+	}
+
+	info, ok := auth.AuthenticateRead(r)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+	if !info.Can(middleware.PermissionRead) {
+		return nil, status.Errorf(codes.PermissionDenied, "role %s may not read", info.Role)
+	}
+	return middleware.WithAuthInfo(ctx, info), nil
+}
+
+// ListServers lists the configured license servers
+func (s *Service) ListServers(ctx context.Context, _ *licetv1.ListServersRequest) (*licetv1.ListServersResponse, error) {
+	servers, err := s.query.GetAllServers()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if s.storage != nil {
+		if err := s.storage.SetServerIDs(ctx, servers); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	resp := &licetv1.ListServersResponse{Servers: make([]*licetv1.Server, 0, len(servers))}
+	for _, srv := range servers {
+		resp.Servers = append(resp.Servers, &licetv1.Server{
+			Hostname:     srv.Hostname,
+			Description:  srv.Description,
+			Type:         srv.Type,
+			ResourceId:   srv.ResourceID,
+			Uuid:         srv.UUID,
+			PollInterval: int32(srv.PollInterval),
+			Schedule:     srv.Schedule,
+		})
+	}
+	return resp, nil
+}
+
+// GetUtilization returns the current utilization of the features of all
+// servers, or of one
+func (s *Service) GetUtilization(ctx context.Context, req *licetv1.GetUtilizationRequest) (*licetv1.GetUtilizationResponse, error) {
+	utilization, err := s.analytics.GetCurrentUtilization(ctx, req.GetServer())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if s.names != nil {
+		s.names.ApplyToUtilization(utilization)
+	}
+
+	resp := &licetv1.GetUtilizationResponse{Features: make([]*licetv1.FeatureUtilization, 0, len(utilization))}
+	for _, u := range utilization {
+		resp.Features = append(resp.Features, &licetv1.FeatureUtilization{
+			Server:            u.ServerHostname,
+			Feature:           u.FeatureName,
+			Version:           u.Version,
+			TotalLicenses:     int32(u.TotalLicenses),
+			UsedLicenses:      int32(u.UsedLicenses),
+			ReservedLicenses:  int32(u.ReservedLicenses),
+			OverdraftLicenses: int32(u.OverdraftLicenses),
+			AvailableLicenses: int32(u.AvailableLicenses),
+			UtilizationPct:    u.UtilizationPct,
+			LicenseModel:      u.LicenseModel,
+			VendorDaemon:      u.VendorDaemon,
+			DisplayName:       u.DisplayName,
+		})
+	}
+	return resp, nil
+}
+
+// GetForecast projects the usage of a feature from its trend
+func (s *Service) GetForecast(ctx context.Context, req *licetv1.GetForecastRequest) (*licetv1.GetForecastResponse, error) {
+	if req.GetServer() == "" || req.GetFeature() == "" {
+		return nil, status.Error(codes.InvalidArgument, "server and feature are required")
+	}
+	days := int(req.GetDays())
+	if days <= 0 {
+		days = 30
+	}
+
+	p, err := s.analytics.GetPredictiveAnalytics(ctx, req.GetServer(), req.GetFeature(), days)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &licetv1.GetForecastResponse{
+		Server:          p.ServerHostname,
+		Feature:         p.FeatureName,
+		TotalLicenses:   int32(p.TotalLicenses),
+		CurrentUsage:    p.CurrentUsage,
+		TrendSlope:      p.TrendSlope,
+		DaysToCapacity:  int32(p.DaysToCapacity),
+		ConfidenceLevel: p.ConfidenceLevel,
+	}
+	for _, point := range p.Forecast {
+		resp.Points = append(resp.Points, &licetv1.ForecastPoint{Date: point.Date, PredictedUsage: point.PredictedUsage, Holiday: point.Holiday})
+	}
+	return resp, nil
+}
+
+// StreamEvents streams the status of servers as they are collected and
+// alerts as they are raised, until the client cancels
+func (s *Service) StreamEvents(req *licetv1.StreamEventsRequest, stream licetv1.LicetService_StreamEventsServer) error {
+	wants := func(t licetv1.EventType, server string) bool {
+		return (len(req.GetTypes()) == 0 || slices.Contains(req.GetTypes(), t)) &&
+			(len(req.GetServers()) == 0 || slices.Contains(req.GetServers(), server))
+	}
+
+	collections, cancelCollections := s.bus.SubscribeBuffered(services.CollectionsTopic, streamBuffer)
+	defer cancelCollections()
+	alerts, cancelAlerts := s.bus.SubscribeBuffered(services.AlertTopic, streamBuffer)
+	defer cancelAlerts()
+
+	for {
+		var event *licetv1.Event
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case e := <-collections:
+			c, ok := e.(services.CollectionEvent)
+			if !ok || !wants(licetv1.EventType_EVENT_TYPE_SERVER_STATUS, c.Server) {
+				continue
+			}
+			event = serverStatusEvent(c)
+		case e := <-alerts:
+			a, ok := e.(models.Alert)
+			if !ok || !wants(licetv1.EventType_EVENT_TYPE_ALERT, a.ServerHostname) {
+				continue
+			}
+			event = alertEvent(a)
+		}
+
+		if err := stream.Send(event); err != nil {
+			if !errors.Is(err, context.Canceled) && status.Code(err) != codes.Canceled {
+				log.Debugf("gRPC event stream ended: %v", err)
+			}
+			return err
+		}
+	}
+}
+
+// serverStatusEvent converts a collection to a server status event
+func serverStatusEvent(c services.CollectionEvent) *licetv1.Event {
+	st := c.Result.Status
+	message := st.Message
+	if c.Error != "" {
+		message = c.Error
+	}
+	return &licetv1.Event{
+		Type: licetv1.EventType_EVENT_TYPE_SERVER_STATUS,
+		Time: timestamppb.New(c.CollectedAt),
+		Payload: &licetv1.Event_ServerStatus{ServerStatus: &licetv1.ServerStatus{
+			Server:      c.Server,
+			Type:        c.Type,
+			Status:      st.Service,
+			Master:      st.Master,
+			Version:     st.Version,
+			Message:     strings.TrimSpace(message),
+			Features:    int32(len(c.Result.Features)),
+			Users:       int32(len(c.Result.Users)),
+			CollectedAt: timestamppb.New(c.CollectedAt),
+		}},
+	}
+}
+
+// alertEvent converts an alert to an alert event
+func alertEvent(a models.Alert) *licetv1.Event {
+	return &licetv1.Event{
+		Type: licetv1.EventType_EVENT_TYPE_ALERT,
+		Time: timestamppb.New(a.CreatedAt),
+		Payload: &licetv1.Event_Alert{Alert: &licetv1.Alert{
+			Id:        a.ID,
+			Server:    a.ServerHostname,
+			Feature:   a.FeatureName,
+			AlertType: a.AlertType,
+			Message:   a.Message,
+			Severity:  a.Severity,
+			CreatedAt: timestamppb.New(a.CreatedAt),
+		}},
+	}
+}
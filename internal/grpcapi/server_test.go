@@ -0,0 +1,169 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/models"
+	"licet/internal/services"
+	"licet/pkg/licetv1"
+)
+
+// newTestClient serves the service over an in-memory connection
+func newTestClient(t *testing.T, cfg *config.Config, bus *services.EventBus) licetv1.LicetServiceClient {
+	t.Helper()
+	var auth *middleware.Authenticator
+	if cfg.Auth.Enabled {
+		auth = middleware.NewAuthenticator(cfg.Auth)
+		t.Cleanup(auth.Stop)
+	}
+	server, err := NewServer(cfg, auth, NewService(services.NewQueryService(cfg, nil), nil, nil, nil, bus))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return licetv1.NewLicetServiceClient(conn)
+}
+
+func testConfig() *config.Config {
+	return &config.Config{Servers: []config.LicenseServer{
+		{Hostname: "27000@flex1", Description: "FlexLM", Type: "flexlm"},
+		{Hostname: "5053@rlm1", Description: "RLM", Type: "rlm"},
+	}}
+}
+
+func TestListServers(t *testing.T) {
+	client := newTestClient(t, testConfig(), services.NewEventBus())
+
+	resp, err := client.ListServers(context.Background(), &licetv1.ListServersRequest{})
+	if err != nil {
+		t.Fatalf("ListServers: %v", err)
+	}
+	if len(resp.Servers) != 2 || resp.Servers[0].Hostname != "27000@flex1" || resp.Servers[1].Type != "rlm" {
+		t.Errorf("servers = %v", resp.Servers)
+	}
+}
+
+func TestGetForecastRequiresFeature(t *testing.T) {
+	client := newTestClient(t, testConfig(), services.NewEventBus())
+
+	_, err := client.GetForecast(context.Background(), &licetv1.GetForecastRequest{Server: "27000@flex1"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("code = %v, want InvalidArgument", status.Code(err))
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	bus := services.NewEventBus()
+	client := newTestClient(t, testConfig(), bus)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.StreamEvents(ctx, &licetv1.StreamEventsRequest{Servers: []string{"27000@flex1"}})
+	if err != nil {
+		t.Fatalf("StreamEvents: %v", err)
+	}
+
+	// The stream subscribes once the call reaches the server; publish until
+	// the first event arrives
+	collected := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	received := make(chan *licetv1.Event, 2)
+	go func() {
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				close(received)
+				return
+			}
+			received <- event
+		}
+	}()
+	publish := func() {
+		bus.Publish(services.CollectionsTopic, services.CollectionEvent{Server: "5053@rlm1", Type: "rlm", CollectedAt: collected})
+		bus.Publish(services.CollectionsTopic, services.CollectionEvent{
+			Server:      "27000@flex1",
+			Type:        "flexlm",
+			Result:      models.ServerQueryResult{Status: models.ServerStatus{Service: "up", Master: "flex1"}},
+			CollectedAt: collected,
+		})
+	}
+
+	var event *licetv1.Event
+	for event == nil {
+		publish()
+		select {
+		case event = <-received:
+		case <-time.After(20 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("no event received")
+		}
+	}
+	st := event.GetServerStatus()
+	if event.Type != licetv1.EventType_EVENT_TYPE_SERVER_STATUS || st == nil {
+		t.Fatalf("event = %v, want a server status", event)
+	}
+	if st.Server != "27000@flex1" || st.Status != "up" || st.Master != "flex1" || !st.CollectedAt.AsTime().Equal(collected) {
+		t.Errorf("server status = %v", st)
+	}
+
+	bus.Publish(services.AlertTopic, models.Alert{ID: 7, ServerHostname: "27000@flex1", FeatureName: "solver", AlertType: "license_expiry", Severity: "warning"})
+	for event = range received {
+		if alert := event.GetAlert(); alert != nil {
+			if alert.Id != 7 || alert.Feature != "solver" {
+				t.Errorf("alert = %v", alert)
+			}
+			return
+		}
+	}
+	t.Fatal("no alert received")
+}
+
+func TestAuthentication(t *testing.T) {
+	cfg := testConfig()
+	cfg.Auth = config.AuthConfig{
+		Enabled: true,
+		APIKeys: []config.APIKeyConfig{{Name: "tooling", Key: "secret123", Role: middleware.RoleReadonly, Enabled: true}},
+	}
+	client := newTestClient(t, cfg, services.NewEventBus())
+
+	_, err := client.ListServers(context.Background(), &licetv1.ListServersRequest{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("without a key: code = %v, want Unauthenticated", status.Code(err))
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "wrong")
+	if _, err := client.ListServers(ctx, &licetv1.ListServersRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("with a wrong key: code = %v, want Unauthenticated", status.Code(err))
+	}
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "secret123")
+	if _, err := client.ListServers(ctx, &licetv1.ListServersRequest{}); err != nil {
+		t.Errorf("with a key: %v", err)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret123")
+	if _, err := client.ListServers(ctx, &licetv1.ListServersRequest{}); err != nil {
+		t.Errorf("with a bearer token: %v", err)
+	}
+}
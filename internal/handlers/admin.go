@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+
+	"licet/internal/config"
+	"licet/internal/middleware"
+)
+
+// RequireAdmin restricts routes to the admin role when authentication is
+// enabled
+func RequireAdmin(cfg *config.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.Auth.Enabled && middleware.GetAuthInfo(r).Role != middleware.RoleAdmin {
+				http.Error(w, "Admin role required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
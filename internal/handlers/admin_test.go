@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+func TestAdminDatabaseMaintenance(t *testing.T) {
+	db := newTestDB(t)
+	dbStats := services.NewDBStatsService(db, config.DatabaseConfig{Type: "sqlite"})
+	cfg := &config.Config{}
+
+	r := chi.NewRouter()
+	r.Route("/admin/db", func(r chi.Router) {
+		r.Use(RequireAdmin(cfg))
+		r.Get("/stats", GetDatabaseStats(dbStats))
+		r.Post("/cleanup", CleanupOldData(dbStats))
+	})
+	do := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	w := do(http.MethodPost, "/admin/db/cleanup?table=alert_events&days=30")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result models.CleanupResult
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil || !result.Success || result.TableName != "alert_events" {
+		t.Errorf("Expected a cleanup result, got %+v (%v)", result, err)
+	}
+
+	for _, target := range []string{
+		"/admin/db/cleanup",
+		"/admin/db/cleanup?table=servers",
+		"/admin/db/cleanup?table=alerts&days=0",
+		"/admin/db/cleanup?table=alerts&days=soon",
+	} {
+		if w := do(http.MethodPost, target); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", target, w.Code)
+		}
+	}
+
+	if w := do(http.MethodGet, "/admin/db/stats"); w.Code != http.StatusOK {
+		t.Errorf("Expected stats without auth, got %d", w.Code)
+	}
+	cfg.Auth.Enabled = true
+	if w := do(http.MethodGet, "/admin/db/stats"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 without the admin role, got %d", w.Code)
+	}
+}
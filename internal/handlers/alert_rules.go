@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// alertRuleRequest is the body of alert rule create and update requests
+type alertRuleRequest struct {
+	Name           string  `json:"name"`
+	RuleType       string  `json:"rule_type"`
+	ServerHostname string  `json:"server_hostname"`
+	FeaturePattern string  `json:"feature_pattern"`
+	Threshold      float64 `json:"threshold"`
+	DurationMin    int     `json:"duration_min"`
+	Severity       string  `json:"severity"`
+	Enabled        *bool   `json:"enabled"` // Defaults to true
+}
+
+func (req alertRuleRequest) rule() models.AlertRule {
+	return models.AlertRule{
+		Name:           req.Name,
+		RuleType:       strings.TrimSpace(req.RuleType),
+		ServerHostname: strings.TrimSpace(req.ServerHostname),
+		FeaturePattern: strings.TrimSpace(req.FeaturePattern),
+		Threshold:      req.Threshold,
+		DurationMin:    req.DurationMin,
+		Severity:       strings.TrimSpace(req.Severity),
+		Enabled:        req.Enabled == nil || *req.Enabled,
+	}
+}
+
+// ListAlertRules handles GET /api/v1/alert-rules - lists the alert rules
+func ListAlertRules(alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rules, err := alertService.GetAlertRules(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"rules": rules,
+			"total": len(rules),
+		})
+	}
+}
+
+// GetAlertRule handles GET /api/v1/alert-rules/{id}
+func GetAlertRule(alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid rule id", http.StatusBadRequest)
+			return
+		}
+
+		rule, err := alertService.GetAlertRule(r.Context(), id)
+		if errors.Is(err, services.ErrAlertRuleNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rule)
+	}
+}
+
+// CreateAlertRule handles POST /api/v1/alert-rules - adds an alert rule
+func CreateAlertRule(cfg *config.Config, alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Server.SettingsEnabled {
+			http.Error(w, "Settings page is disabled", http.StatusForbidden)
+			return
+		}
+
+		var req alertRuleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		rule := req.rule()
+		rule.CreatedBy = middleware.GetAuthInfo(r).Username
+		err := alertService.CreateAlertRule(r.Context(), &rule)
+		if errors.Is(err, services.ErrInvalidAlertRule) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Alert rule created",
+			"rule":    rule,
+		})
+	}
+}
+
+// UpdateAlertRule handles PUT /api/v1/alert-rules/{id} - replaces an alert rule
+func UpdateAlertRule(cfg *config.Config, alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Server.SettingsEnabled {
+			http.Error(w, "Settings page is disabled", http.StatusForbidden)
+			return
+		}
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid rule id", http.StatusBadRequest)
+			return
+		}
+
+		var req alertRuleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		rule := req.rule()
+		rule.ID = id
+		err = alertService.UpdateAlertRule(r.Context(), &rule)
+		switch {
+		case errors.Is(err, services.ErrInvalidAlertRule):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, services.ErrAlertRuleNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Alert rule updated",
+			"rule":    rule,
+		})
+	}
+}
+
+// DeleteAlertRule handles DELETE /api/v1/alert-rules/{id}
+func DeleteAlertRule(cfg *config.Config, alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Server.SettingsEnabled {
+			http.Error(w, "Settings page is disabled", http.StatusForbidden)
+			return
+		}
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid rule id", http.StatusBadRequest)
+			return
+		}
+
+		err = alertService.DeleteAlertRule(r.Context(), id)
+		if errors.Is(err, services.ErrAlertRuleNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Alert rule removed",
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"licet/internal/middleware"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// ListExpectedAnomalies handles GET /api/v1/utilization/anomalies/expected?server=&feature=
+func ListExpectedAnomalies(analytics *services.AnalyticsService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		expected, err := analytics.GetExpectedAnomalies(r.Context(),
+			r.URL.Query().Get("server"), r.URL.Query().Get("feature"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"expected_anomalies": expected,
+			"total":              len(expected),
+		})
+	}
+}
+
+// MarkAnomalyExpected handles POST /api/v1/utilization/anomalies/expected - marks
+// a day of a feature's usage as expected so it is no longer reported as an anomaly
+func MarkAnomalyExpected(analytics *services.AnalyticsService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ServerHostname string `json:"server_hostname"`
+			FeatureName    string `json:"feature_name"`
+			Date           string `json:"date"`
+			Note           string `json:"note"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		req.ServerHostname = strings.TrimSpace(req.ServerHostname)
+		req.FeatureName = strings.TrimSpace(req.FeatureName)
+		if req.ServerHostname == "" || req.FeatureName == "" {
+			http.Error(w, "server_hostname and feature_name are required", http.StatusBadRequest)
+			return
+		}
+		date, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			http.Error(w, "date must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+
+		mark := models.ExpectedAnomaly{
+			ServerHostname: req.ServerHostname,
+			FeatureName:    req.FeatureName,
+			Date:           date,
+			Note:           strings.TrimSpace(req.Note),
+			CreatedBy:      middleware.GetAuthInfo(r).Username,
+		}
+		if err := analytics.MarkAnomalyExpected(r.Context(), mark); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":          "Anomaly marked as expected",
+			"expected_anomaly": mark,
+		})
+	}
+}
+
+// UnmarkAnomalyExpected handles DELETE /api/v1/utilization/anomalies/expected?server=&feature=&date=
+func UnmarkAnomalyExpected(analytics *services.AnalyticsService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server := r.URL.Query().Get("server")
+		feature := r.URL.Query().Get("feature")
+		if server == "" || feature == "" {
+			http.Error(w, "server and feature parameters required", http.StatusBadRequest)
+			return
+		}
+		date, err := time.Parse("2006-01-02", r.URL.Query().Get("date"))
+		if err != nil {
+			http.Error(w, "date must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+
+		err = analytics.UnmarkAnomalyExpected(r.Context(), server, feature, date)
+		if errors.Is(err, services.ErrExpectedAnomalyNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Expected anomaly removed",
+		})
+	}
+}
@@ -2,24 +2,86 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"licet/internal/config"
 	"licet/internal/middleware"
+	"licet/internal/models"
+	"licet/internal/parsers"
 	"licet/internal/services"
 )
 
 // paginationConfig is the default pagination configuration for API endpoints
 var paginationConfig = middleware.DefaultPaginationConfig()
 
-func ListServers(query *services.QueryService) http.HandlerFunc {
+// licenseModels are the values accepted by the model query parameter
+var licenseModels = map[string]bool{
+	models.LicenseModelFloating:   true,
+	models.LicenseModelNodeLocked: true,
+	models.LicenseModelUncounted:  true,
+}
+
+// licenseModelParam reads the model query parameter. It returns false for unknown models.
+func licenseModelParam(r *http.Request) (string, bool) {
+	model := r.URL.Query().Get("model")
+	return model, model == "" || licenseModels[model]
+}
+
+// filterByLicenseModel keeps the items with the given license model. An empty model keeps all items.
+func filterByLicenseModel[T any](items []T, model string, modelOf func(T) string) []T {
+	if model == "" {
+		return items
+	}
+	filtered := make([]T, 0, len(items))
+	for _, item := range items {
+		if modelOf(item) == model {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// redactorFor returns the redactor when the request's role may not see
+// individual usernames, or nil when usernames can be shown
+func redactorFor(r *http.Request, redactor *services.Redactor) *services.Redactor {
+	if redactor.Applies(middleware.GetAuthInfo(r).Role) {
+		return redactor
+	}
+	return nil
+}
+
+func featureLicenseModel(f models.Feature) string { return f.LicenseModel }
+
+func utilizationLicenseModel(u models.UtilizationData) string { return u.LicenseModel }
+
+// groupServers keeps the servers of the group of ?group=, or all without one
+func groupServers(r *http.Request, servers []models.LicenseServer) []models.LicenseServer {
+	group := r.URL.Query().Get("group")
+	if group == "" {
+		return servers
+	}
+	return slices.DeleteFunc(servers, func(s models.LicenseServer) bool { return s.Group != group })
+}
+
+func ListServers(query *services.QueryService, storage *services.StorageService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		servers, err := query.GetAllServers()
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		servers = groupServers(r, servers)
+		if storage != nil {
+			if err := storage.SetServerIDs(r.Context(), servers); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
 
 		// Check if pagination is requested
 		if r.URL.Query().Get("limit") != "" || r.URL.Query().Get("page") != "" {
@@ -41,7 +103,7 @@ func ListServers(query *services.QueryService) http.HandlerFunc {
 
 func GetServerStatus(query *services.QueryService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		server := chi.URLParam(r, "server")
+		server := serverParam(r)
 		serverType := r.URL.Query().Get("type")
 
 		if serverType == "" {
@@ -59,15 +121,25 @@ func GetServerStatus(query *services.QueryService) http.HandlerFunc {
 	}
 }
 
-func GetServerFeatures(storage *services.StorageService) http.HandlerFunc {
+func GetServerFeatures(storage *services.StorageService, names *services.DisplayNameService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		server := chi.URLParam(r, "server")
+		server := serverParam(r)
+		model, ok := licenseModelParam(r)
+		if !ok {
+			http.Error(w, "invalid license model", http.StatusBadRequest)
+			return
+		}
 
 		features, err := storage.GetFeatures(r.Context(), server)
+		if err == nil {
+			err = storage.SetFeatureIDs(r.Context(), server, features)
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		features = filterByLicenseModel(features, model, featureLicenseModel)
+		names.ApplyToFeatures(features)
 
 		// Check if pagination is requested
 		if r.URL.Query().Get("limit") != "" || r.URL.Query().Get("page") != "" {
@@ -87,9 +159,72 @@ func GetServerFeatures(storage *services.StorageService) http.HandlerFunc {
 	}
 }
 
-func GetServerUsers(query *services.QueryService) http.HandlerFunc {
+// GetDegradedFeatures lists active features whose data was parsed by
+// falling back to defaults, e.g. an unparseable expiration date stored as
+// permanent. Such features never raise expiration alerts.
+func GetDegradedFeatures(storage *services.StorageService, names *services.DisplayNameService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		features, err := storage.GetDegradedFeatures(r.Context(), r.URL.Query().Get("server"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		names.ApplyToFeatures(features)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"features": features,
+			"total":    len(features),
+		})
+	}
+}
+
+// GetTokenPools returns the token usage of FlexLM packages
+func GetTokenPools(storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pools, err := storage.GetTokenPools(r.Context(), r.URL.Query().Get("server"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"pools": pools,
+			"total": len(pools),
+		})
+	}
+}
+
+// GetMLMProducts returns the usage of MathWorks products of the servers with
+// the mlm profile, grouped by product family
+func GetMLMProducts(cfg *config.Config, storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server := r.URL.Query().Get("server")
+		var servers []string
+		for _, srv := range cfg.Servers {
+			if srv.Profile == parsers.ProfileMLM && (server == "" || srv.Hostname == server) {
+				servers = append(servers, srv.Hostname)
+			}
+		}
+
+		families, err := storage.GetMLMProducts(r.Context(), servers)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"families": families,
+			"total":    len(families),
+		})
+	}
+}
+
+func GetServerUsers(query *services.QueryService, redactor *services.Redactor) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		server := chi.URLParam(r, "server")
+		server := serverParam(r)
 		serverType := r.URL.Query().Get("type")
 
 		if serverType == "" {
@@ -104,15 +239,14 @@ func GetServerUsers(query *services.QueryService) http.HandlerFunc {
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"users": result.Users,
+			"users": redactorFor(r, redactor).Users(result.Users),
 		})
 	}
 }
 
 func GetFeatureUsage(storage *services.StorageService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		feature := chi.URLParam(r, "feature")
-		server := r.URL.Query().Get("server")
+		server, feature := featureParam(r)
 		daysStr := r.URL.Query().Get("days")
 
 		days := 30
@@ -172,15 +306,28 @@ func Health(version string) http.HandlerFunc {
 }
 
 // GetCurrentUtilization returns current utilization for all features across all servers
-func GetCurrentUtilization(analytics *services.AnalyticsService) http.HandlerFunc {
+func GetCurrentUtilization(analytics *services.AnalyticsService, names *services.DisplayNameService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		serverFilter := r.URL.Query().Get("server")
+		model, ok := licenseModelParam(r)
+		if !ok {
+			http.Error(w, "invalid license model", http.StatusBadRequest)
+			return
+		}
 
 		utilization, err := analytics.GetCurrentUtilization(r.Context(), serverFilter)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		utilization = filterByLicenseModel(utilization, model, utilizationLicenseModel)
+		filtered, err := featureFilterParam(r, analytics)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		utilization = filterFeatures(utilization, filtered, utilizationKey)
+		names.ApplyToUtilization(utilization)
 
 		// Check if pagination is requested
 		if r.URL.Query().Get("limit") != "" || r.URL.Query().Get("page") != "" {
@@ -200,27 +347,26 @@ func GetCurrentUtilization(analytics *services.AnalyticsService) http.HandlerFun
 	}
 }
 
-// GetUtilizationHistory returns time-series usage data for charting
+// GetUtilizationHistory returns time-series usage data for charting, the
+// combined usage of the features carrying a tag with ?tag= or on the servers
+// of a group with ?group=
 func GetUtilizationHistory(analytics *services.AnalyticsService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		server := r.URL.Query().Get("server")
 		feature := r.URL.Query().Get("feature")
-		periodStr := r.URL.Query().Get("period")
-
-		// Default to 7 days
-		days := 7
-		switch periodStr {
-		case "7d":
-			days = 7
-		case "30d":
-			days = 30
-		case "90d":
-			days = 90
-		case "1y":
-			days = 365
+		days := periodDays(r.URL.Query().Get("period"))
+		filtered, err := featureFilterParam(r, analytics)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
-		history, err := analytics.GetUtilizationHistory(r.Context(), server, feature, days)
+		var history []models.UtilizationHistoryPoint
+		if filtered != nil {
+			history, err = analytics.GetFilteredUtilizationHistory(r.Context(), server, feature, filtered, days)
+		} else {
+			history, err = analytics.GetUtilizationHistory(r.Context(), server, feature, days)
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -234,7 +380,7 @@ func GetUtilizationHistory(analytics *services.AnalyticsService) http.HandlerFun
 }
 
 // GetUtilizationStats returns aggregated statistics
-func GetUtilizationStats(analytics *services.AnalyticsService) http.HandlerFunc {
+func GetUtilizationStats(analytics *services.AnalyticsService, names *services.DisplayNameService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		server := r.URL.Query().Get("server")
 		daysStr := r.URL.Query().Get("days")
@@ -251,6 +397,13 @@ func GetUtilizationStats(analytics *services.AnalyticsService) http.HandlerFunc
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		filtered, err := featureFilterParam(r, analytics)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		stats = filterFeatures(stats, filtered, statsKey)
+		names.ApplyToStats(stats)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -277,6 +430,12 @@ func GetUtilizationHeatmap(analytics *services.AnalyticsService) http.HandlerFun
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		filtered, err := featureFilterParam(r, analytics)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		heatmap = filterFeatures(heatmap, filtered, heatmapKey)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -285,6 +444,40 @@ func GetUtilizationHeatmap(analytics *services.AnalyticsService) http.HandlerFun
 	}
 }
 
+// GetSeasonalPatterns returns the daily and weekly cycles and peak periods of
+// feature usage
+func GetSeasonalPatterns(analytics *services.AnalyticsService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server := r.URL.Query().Get("server")
+		feature := r.URL.Query().Get("feature")
+		daysStr := r.URL.Query().Get("days")
+
+		days := 28 // Four weeks, so every weekday is seen four times
+		if daysStr != "" {
+			if d, err := strconv.Atoi(daysStr); err == nil {
+				days = d
+			}
+		}
+
+		patterns, err := analytics.GetSeasonalPatterns(r.Context(), server, feature, days)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		filtered, err := featureFilterParam(r, analytics)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		patterns = filterFeatures(patterns, filtered, seasonalKey)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"patterns": patterns,
+		})
+	}
+}
+
 // GetPredictiveAnalytics returns predictive analytics and anomaly detection
 func GetPredictiveAnalytics(analytics *services.AnalyticsService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -304,7 +497,16 @@ func GetPredictiveAnalytics(analytics *services.AnalyticsService) http.HandlerFu
 			}
 		}
 
-		predictions, err := analytics.GetPredictiveAnalytics(r.Context(), server, feature, days)
+		model := r.URL.Query().Get("model")
+		if model == "" {
+			model = services.ForecastLinear
+		}
+
+		predictions, err := analytics.GetPredictiveAnalyticsWithModel(r.Context(), server, feature, days, model)
+		if errors.Is(err, services.ErrUnknownForecastModel) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -339,12 +541,21 @@ func GetEnhancedStatistics(enhancedAnalytics *services.EnhancedAnalyticsService)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if !explainRequested(r) {
+			stats.Explanation = nil
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(stats)
 	}
 }
 
+// explainRequested reports whether ?explain=true asks for the inputs,
+// formulas and thresholds behind statistics and recommendations
+func explainRequested(r *http.Request) bool {
+	return r.URL.Query().Get("explain") == "true"
+}
+
 // GetTrendAnalysis returns detailed trend analysis for a feature
 func GetTrendAnalysis(enhancedAnalytics *services.EnhancedAnalyticsService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -369,13 +580,17 @@ func GetTrendAnalysis(enhancedAnalytics *services.EnhancedAnalyticsService) http
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if !explainRequested(r) {
+			analysis.Explanation = nil
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(analysis)
 	}
 }
 
-// GetCapacityPlanningReport returns a comprehensive capacity planning report
+// GetCapacityPlanningReport returns a comprehensive capacity planning report,
+// of the features carrying a tag with ?tag=
 func GetCapacityPlanningReport(enhancedAnalytics *services.EnhancedAnalyticsService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		daysStr := r.URL.Query().Get("days")
@@ -387,12 +602,27 @@ func GetCapacityPlanningReport(enhancedAnalytics *services.EnhancedAnalyticsServ
 			}
 		}
 
-		report, err := enhancedAnalytics.GetCapacityPlanningReport(r.Context(), days)
+		filtered, err := featureFilterParam(r, enhancedAnalytics)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
+		var report *models.CapacityPlanningReport
+		if filtered != nil {
+			report, err = enhancedAnalytics.GetFilteredCapacityReport(r.Context(), days, filtered)
+		} else {
+			refresh := r.URL.Query().Get("refresh") == "true"
+			report, err = enhancedAnalytics.CapacityReport(r.Context(), days, refresh)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if explainRequested(r) {
+			report.Explanation = services.ExplainCapacityReport(report)
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(report)
 	}
@@ -426,6 +656,67 @@ func GetRetentionStats(dbStats *services.DBStatsService) http.HandlerFunc {
 	}
 }
 
+// GetRetentionRuns handles GET /api/v1/database/retention/runs?limit=50 -
+// returns the retention policy and the latest retention runs, newest first
+func GetRetentionRuns(cfg *config.Config, dbStats *services.DBStatsService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		runs, err := dbStats.GetRetentionRuns(r.Context(), limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"policy":   cfg.RetentionPolicy(),
+			"schedule": cfg.Retention.Schedule,
+			"dry_run":  cfg.Retention.DryRun,
+			"runs":     runs,
+		})
+	}
+}
+
+// RunRetention handles POST /api/v1/database/retention/runs?dry_run=true -
+// applies the retention policy now. A dry run only counts the rows that
+// would be removed.
+func RunRetention(cfg *config.Config, dbStats *services.DBStatsService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		info := middleware.GetAuthInfo(r)
+		if cfg.Auth.Enabled && info.Role != middleware.RoleAdmin {
+			http.Error(w, "Admin role required", http.StatusForbidden)
+			return
+		}
+
+		dryRun := false
+		if v := r.URL.Query().Get("dry_run"); v != "" {
+			var err error
+			if dryRun, err = strconv.ParseBool(v); err != nil {
+				http.Error(w, "dry_run must be true or false", http.StatusBadRequest)
+				return
+			}
+		}
+		policy := cfg.RetentionPolicy()
+		if len(policy) == 0 {
+			http.Error(w, "No retention policy configured", http.StatusConflict)
+			return
+		}
+
+		actor := info.Username
+		if actor == "" {
+			actor = "anonymous"
+		}
+		run, err := dbStats.ApplyRetention(r.Context(), policy, dryRun, actor)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(run)
+	}
+}
+
 // VacuumDatabase runs VACUUM to optimize the database
 func VacuumDatabase(dbStats *services.DBStatsService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -440,25 +731,30 @@ func VacuumDatabase(dbStats *services.DBStatsService) http.HandlerFunc {
 	}
 }
 
-// CleanupOldData removes data older than the specified number of days
+// CleanupOldData removes data older than ?days= (default 90) from ?table=
 func CleanupOldData(dbStats *services.DBStatsService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		table := r.URL.Query().Get("table")
-		daysStr := r.URL.Query().Get("days")
-
 		if table == "" {
 			http.Error(w, "table parameter required", http.StatusBadRequest)
 			return
 		}
 
 		days := 90 // Default to 90 days
-		if daysStr != "" {
-			if d, err := strconv.Atoi(daysStr); err == nil {
-				days = d
+		if v := r.URL.Query().Get("days"); v != "" {
+			d, err := strconv.Atoi(v)
+			if err != nil || d < 1 {
+				http.Error(w, "days must be a positive number", http.StatusBadRequest)
+				return
 			}
+			days = d
 		}
 
 		result, err := dbStats.CleanupOldData(r.Context(), table, days)
+		if errors.Is(err, services.ErrUnsupportedTable) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -502,3 +798,147 @@ func CheckpointWAL(dbStats *services.DBStatsService) http.HandlerFunc {
 		})
 	}
 }
+
+// DeduplicateFeatures merges near-duplicate feature rows
+func DeduplicateFeatures(storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		merged, err := storage.MergeDuplicateFeatures(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"merged":  merged,
+		})
+	}
+}
+
+// GetIncidents returns correlated alert incidents from the last `days` days (default 30)
+func GetIncidents(alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		days := 30
+		if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 {
+			days = d
+		}
+
+		incidents, err := alertService.GetIncidents(r.Context(), days)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"incidents": incidents,
+			"total":     len(incidents),
+		})
+	}
+}
+
+// GetIncident returns an incident with the timeline of its alerts
+func GetIncident(alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid incident id", http.StatusBadRequest)
+			return
+		}
+
+		incident, err := alertService.GetIncident(r.Context(), id)
+		if errors.Is(err, services.ErrIncidentNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(incident)
+	}
+}
+
+// GetAlertDeliveries handles GET /api/v1/alerts/{id}/deliveries - lists the
+// delivery receipts of an alert per channel and target, for audits
+func GetAlertDeliveries(alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid alert id", http.StatusBadRequest)
+			return
+		}
+
+		deliveries, err := alertService.GetAlertDeliveries(r.Context(), id)
+		if errors.Is(err, services.ErrAlertNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"alert_id":   id,
+			"deliveries": deliveries,
+			"total":      len(deliveries),
+		})
+	}
+}
+
+// GetFailovers returns the MASTER failover history of a server, or of all
+// servers when no server is given
+func GetFailovers(storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server := serverParam(r)
+		if server == "" {
+			server = r.URL.Query().Get("server")
+		}
+
+		days := 90
+		if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 {
+			days = d
+		}
+
+		failovers, err := storage.GetMasterChanges(r.Context(), server, days)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"failovers": failovers,
+			"total":     len(failovers),
+		})
+	}
+}
+
+// GetUserDigest returns users who started or stopped using features during
+// the last `days` days (default 1)
+func GetUserDigest(digest *services.UserDigestService, redactor *services.Redactor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		days := 1
+		if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 {
+			days = d
+		}
+
+		until := time.Now()
+		result, err := digest.GetDigest(r.Context(), until.AddDate(0, 0, -days), until)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		redact := redactorFor(r, redactor)
+		result.NewUsers = redact.FeatureUsers(result.NewUsers)
+		result.RemovedUsers = redact.FeatureUsers(result.RemovedUsers)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
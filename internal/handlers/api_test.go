@@ -1,10 +1,19 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/models"
+	"licet/internal/services"
 )
 
 func TestHealth(t *testing.T) {
@@ -34,3 +43,96 @@ func TestHealth(t *testing.T) {
 		t.Errorf("Expected version '%s', got %v", testVersion, version)
 	}
 }
+
+func TestGetAlerts_LongCheckoutsNameNoUsers(t *testing.T) {
+	ctx := context.Background()
+	alerts := services.NewAlertService(newTestDB(t), &config.Config{})
+	rule := models.AlertRule{Name: "Idle seats", RuleType: models.AlertRuleLongCheckout, Threshold: 24, Enabled: true}
+	if err := alerts.CreateAlertRule(ctx, &rule); err != nil {
+		t.Fatalf("CreateAlertRule failed: %v", err)
+	}
+	checkouts := []models.Checkout{{ServerHostname: "27000@a", FeatureName: "solver", Username: "alice", Host: "ws1", HeldHours: 30}}
+	if err := alerts.EvaluateRules(ctx, nil, checkouts, nil, time.Now()); err != nil {
+		t.Fatalf("EvaluateRules failed: %v", err)
+	}
+
+	// Served from the role-blind response cache, as in the router
+	cache := middleware.NewCache(middleware.CacheConfig{DefaultTTL: time.Minute, MaxEntries: 10, Enabled: true})
+	defer cache.Stop()
+	r := chi.NewRouter()
+	r.Use(middleware.CacheMiddleware(cache, time.Minute))
+	r.Get("/api/v1/alerts", GetAlerts(alerts))
+	r.Get("/api/v2/alerts", V2GetAlerts(alerts))
+
+	for _, path := range []string{"/api/v1/alerts", "/api/v2/alerts"} {
+		for _, role := range []string{middleware.RoleAdmin, middleware.RoleReadonly} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req = req.WithContext(middleware.WithAuthInfo(req.Context(), &middleware.AuthInfo{Authenticated: true, Role: role}))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			body := w.Body.String()
+			if !strings.Contains(body, "1 checkouts of 'solver' on 27000@a") {
+				t.Fatalf("%s: expected the long checkout alert, got %s", path, body)
+			}
+			if role == middleware.RoleReadonly && (strings.Contains(body, "alice") || strings.Contains(body, "ws1")) {
+				t.Errorf("%s: expected no holder for a readonly caller, got %s", path, body)
+			}
+		}
+	}
+}
+
+func TestConditional_ContractEditChangesResponse(t *testing.T) {
+	storage := services.NewStorageService(newTestDB(t), "sqlite")
+	cfg := &config.Config{Server: config.ServerConfig{SettingsEnabled: true}}
+	cache := middleware.NewCache(middleware.CacheConfig{DefaultTTL: time.Minute, MaxEntries: 10, Enabled: true})
+	defer cache.Stop()
+
+	// The response depends only on the contracts, as the cost figures of the
+	// capacity report do, so nothing but the contract edit can change it. The
+	// cache keys responses by ETag, so the edit must not serve the cached body.
+	r := chi.NewRouter()
+	r.Post("/api/v1/contracts", CreateContract(cfg, storage))
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.ConditionalMiddleware(storage.GetDataVersions))
+		r.Use(middleware.CacheMiddleware(cache, time.Minute))
+		r.Get("/api/v1/statistics/capacity", func(w http.ResponseWriter, r *http.Request) {
+			contracts, _ := storage.GetContracts(r.Context())
+			json.NewEncoder(w).Encode(map[string]int{"contracts": len(contracts)})
+		})
+	})
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/statistics/capacity", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := get("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expected a 200 with an ETag, got %d %q", first.Code, etag)
+	}
+	if w := get(etag); w.Code != http.StatusNotModified {
+		t.Fatalf("Expected 304 before the edit, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/contracts", strings.NewReader(`{"feature_name": "solver", "cost_per_seat": 1200}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("CreateContract failed: %d %s", w.Code, w.Body.String())
+	}
+
+	after := get(etag)
+	if after.Code != http.StatusOK || after.Header().Get("ETag") == etag {
+		t.Fatalf("Expected a 200 with a new ETag after the edit, got %d %q", after.Code, after.Header().Get("ETag"))
+	}
+	if !strings.Contains(after.Body.String(), `"contracts":1`) {
+		t.Errorf("Expected the response to show the contract, got %s", after.Body.String())
+	}
+}
@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// The v2 API serves the same data as v1, but every response is wrapped in
+// the Envelope shape ({data, meta}) and every list is paginated.
+
+// intParam parses an integer query parameter, falling back to def
+func intParam(r *http.Request, key string, def int) int {
+	if s := r.URL.Query().Get(key); s != "" {
+		if v, err := strconv.Atoi(s); err == nil {
+			return v
+		}
+	}
+	return def
+}
+
+// periodDays converts a period parameter (7d, 30d, 90d, 1y) to days
+func periodDays(period string) int {
+	switch period {
+	case "30d":
+		return 30
+	case "90d":
+		return 90
+	case "1y":
+		return 365
+	default:
+		return 7
+	}
+}
+
+func V2ListServers(query *services.QueryService, storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		servers, err := query.GetAllServers()
+		if err == nil && storage != nil {
+			err = storage.SetServerIDs(r.Context(), servers)
+		}
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondList(w, r, groupServers(r, servers))
+	}
+}
+
+func V2GetServerStatus(query *services.QueryService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serverType := r.URL.Query().Get("type")
+		if serverType == "" {
+			serverType = "flexlm"
+		}
+
+		result, err := query.QueryServer(serverParam(r), serverType)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondObject(w, r, result.Status)
+	}
+}
+
+func V2GetServerFeatures(storage *services.StorageService, names *services.DisplayNameService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		model, ok := licenseModelParam(r)
+		if !ok {
+			respondError(w, r, http.StatusBadRequest, "invalid license model")
+			return
+		}
+
+		server := serverParam(r)
+		features, err := storage.GetFeatures(r.Context(), server)
+		if err == nil {
+			err = storage.SetFeatureIDs(r.Context(), server, features)
+		}
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		features = filterByLicenseModel(features, model, featureLicenseModel)
+		names.ApplyToFeatures(features)
+		respondList(w, r, features)
+	}
+}
+
+func V2GetServerUsers(query *services.QueryService, redactor *services.Redactor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serverType := r.URL.Query().Get("type")
+		if serverType == "" {
+			serverType = "flexlm"
+		}
+
+		result, err := query.QueryServer(serverParam(r), serverType)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondList(w, r, redactorFor(r, redactor).Users(result.Users))
+	}
+}
+
+func V2GetFailovers(storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server := serverParam(r)
+		if server == "" {
+			server = r.URL.Query().Get("server")
+		}
+
+		failovers, err := storage.GetMasterChanges(r.Context(), server, intParam(r, "days", 90))
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondList(w, r, failovers)
+	}
+}
+
+func V2GetFeatureUsage(storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server, feature := featureParam(r)
+		usage, err := storage.GetFeatureUsageHistory(r.Context(), server, feature, intParam(r, "days", 30))
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondList(w, r, usage)
+	}
+}
+
+func V2GetAlerts(alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		alerts, err := alertService.GetUnsentAlerts(r.Context())
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondList(w, r, alerts)
+	}
+}
+
+func V2GetIncidents(alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		incidents, err := alertService.GetIncidents(r.Context(), intParam(r, "days", 30))
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondList(w, r, incidents)
+	}
+}
+
+func V2GetIncident(alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			respondError(w, r, http.StatusBadRequest, "Invalid incident id")
+			return
+		}
+
+		incident, err := alertService.GetIncident(r.Context(), id)
+		if errors.Is(err, services.ErrIncidentNotFound) {
+			respondError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondObject(w, r, incident)
+	}
+}
+
+func V2Health(version string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		respondObject(w, r, map[string]interface{}{
+			"status":  "ok",
+			"version": version,
+		})
+	}
+}
+
+func V2GetCurrentUtilization(analytics *services.AnalyticsService, names *services.DisplayNameService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		model, ok := licenseModelParam(r)
+		if !ok {
+			respondError(w, r, http.StatusBadRequest, "invalid license model")
+			return
+		}
+
+		utilization, err := analytics.GetCurrentUtilization(r.Context(), r.URL.Query().Get("server"))
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utilization = filterByLicenseModel(utilization, model, utilizationLicenseModel)
+		filtered, err := featureFilterParam(r, analytics)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utilization = filterFeatures(utilization, filtered, utilizationKey)
+		names.ApplyToUtilization(utilization)
+		respondList(w, r, utilization)
+	}
+}
+
+func V2GetUtilizationHistory(analytics *services.AnalyticsService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server := r.URL.Query().Get("server")
+		feature := r.URL.Query().Get("feature")
+		days := periodDays(r.URL.Query().Get("period"))
+		filtered, err := featureFilterParam(r, analytics)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		var history []models.UtilizationHistoryPoint
+		if filtered != nil {
+			history, err = analytics.GetFilteredUtilizationHistory(r.Context(), server, feature, filtered, days)
+		} else {
+			history, err = analytics.GetUtilizationHistory(r.Context(), server, feature, days)
+		}
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondList(w, r, history)
+	}
+}
+
+func V2GetUtilizationStats(analytics *services.AnalyticsService, names *services.DisplayNameService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := analytics.GetUtilizationStats(r.Context(), r.URL.Query().Get("server"), intParam(r, "days", 30))
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		filtered, err := featureFilterParam(r, analytics)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		stats = filterFeatures(stats, filtered, statsKey)
+		names.ApplyToStats(stats)
+		respondList(w, r, stats)
+	}
+}
+
+func V2GetUtilizationHeatmap(analytics *services.AnalyticsService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		heatmap, err := analytics.GetHeatmapData(r.Context(), r.URL.Query().Get("server"), intParam(r, "days", 7))
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		filtered, err := featureFilterParam(r, analytics)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondList(w, r, filterFeatures(heatmap, filtered, heatmapKey))
+	}
+}
+
+func V2GetPredictiveAnalytics(analytics *services.AnalyticsService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server := r.URL.Query().Get("server")
+		feature := r.URL.Query().Get("feature")
+		if server == "" || feature == "" {
+			respondError(w, r, http.StatusBadRequest, "server and feature parameters required")
+			return
+		}
+
+		model := r.URL.Query().Get("model")
+		if model == "" {
+			model = services.ForecastLinear
+		}
+
+		predictions, err := analytics.GetPredictiveAnalyticsWithModel(r.Context(), server, feature, intParam(r, "days", 30), model)
+		if errors.Is(err, services.ErrUnknownForecastModel) {
+			respondError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondObject(w, r, predictions)
+	}
+}
+
+func V2GetEnhancedStatistics(enhancedAnalytics *services.EnhancedAnalyticsService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server := r.URL.Query().Get("server")
+		feature := r.URL.Query().Get("feature")
+		if server == "" || feature == "" {
+			respondError(w, r, http.StatusBadRequest, "server and feature parameters required")
+			return
+		}
+
+		stats, err := enhancedAnalytics.GetEnhancedStatistics(r.Context(), server, feature, intParam(r, "days", 30))
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !explainRequested(r) {
+			stats.Explanation = nil
+		}
+		respondObject(w, r, stats)
+	}
+}
+
+func V2GetTrendAnalysis(enhancedAnalytics *services.EnhancedAnalyticsService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server := r.URL.Query().Get("server")
+		feature := r.URL.Query().Get("feature")
+		if server == "" || feature == "" {
+			respondError(w, r, http.StatusBadRequest, "server and feature parameters required")
+			return
+		}
+
+		analysis, err := enhancedAnalytics.GetTrendAnalysis(r.Context(), server, feature, intParam(r, "days", 30))
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !explainRequested(r) {
+			analysis.Explanation = nil
+		}
+		respondObject(w, r, analysis)
+	}
+}
+
+func V2GetCapacityPlanningReport(enhancedAnalytics *services.EnhancedAnalyticsService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filtered, err := featureFilterParam(r, enhancedAnalytics)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		days := intParam(r, "days", 30)
+		var report *models.CapacityPlanningReport
+		if filtered != nil {
+			report, err = enhancedAnalytics.GetFilteredCapacityReport(r.Context(), days, filtered)
+		} else {
+			report, err = enhancedAnalytics.CapacityReport(r.Context(), days, r.URL.Query().Get("refresh") == "true")
+		}
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if explainRequested(r) {
+			report.Explanation = services.ExplainCapacityReport(report)
+		}
+		respondObject(w, r, report)
+	}
+}
+
+func V2GetDatabaseStats(dbStats *services.DBStatsService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := dbStats.GetDatabaseStats(r.Context())
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondObject(w, r, stats)
+	}
+}
+
+func V2GetRetentionStats(dbStats *services.DBStatsService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := dbStats.GetRetentionStats(r.Context())
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondObject(w, r, stats)
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+func TestV2ListServers_Envelope(t *testing.T) {
+	cfg := &config.Config{
+		Servers: []config.LicenseServer{
+			{Hostname: "27000@a", Type: "flexlm"},
+			{Hostname: "27000@b", Type: "flexlm"},
+			{Hostname: "5053@c", Type: "rlm"},
+		},
+	}
+	r := chi.NewRouter()
+	r.Get("/api/v2/servers", V2ListServers(services.NewQueryService(cfg, nil), nil))
+
+	req := httptest.NewRequest("GET", "/api/v2/servers?limit=2&page=2&type=flexlm", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Data []map[string]interface{} `json:"data"`
+		Meta struct {
+			Total       int               `json:"total"`
+			Page        int               `json:"page"`
+			TotalPages  int               `json:"total_pages"`
+			GeneratedAt string            `json:"generated_at"`
+			Filters     map[string]string `json:"filters"`
+		} `json:"meta"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(response.Data) != 1 {
+		t.Errorf("Expected 1 item on page 2, got %d", len(response.Data))
+	}
+	if response.Meta.Total != 3 || response.Meta.Page != 2 || response.Meta.TotalPages != 2 {
+		t.Errorf("Unexpected meta: %+v", response.Meta)
+	}
+	if response.Meta.GeneratedAt == "" {
+		t.Error("Expected generated_at to be set")
+	}
+	if response.Meta.Filters["type"] != "flexlm" {
+		t.Errorf("Expected type filter, got %v", response.Meta.Filters)
+	}
+	if _, ok := response.Meta.Filters["limit"]; ok {
+		t.Error("Pagination parameters should not be reported as filters")
+	}
+}
+
+func TestV2Health_Envelope(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/v2/health", nil)
+	w := httptest.NewRecorder()
+	V2Health("1.0.0-test")(w, req)
+
+	var response map[string]map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response["data"]["status"] != "ok" {
+		t.Errorf("Expected data.status 'ok', got %v", response["data"]["status"])
+	}
+	if _, ok := response["meta"]["total"]; ok {
+		t.Error("Object responses should not include a total")
+	}
+}
+
+func TestV2_ErrorEnvelope(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/v2/utilization/predictions", nil)
+	w := httptest.NewRecorder()
+	V2GetPredictiveAnalytics(nil)(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+
+	var response Envelope
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Error == nil || response.Error.Status != http.StatusBadRequest {
+		t.Errorf("Expected error envelope, got %+v", response)
+	}
+}
+
+func TestV2GetServerFeatures_LicenseModelFilter(t *testing.T) {
+	db := newTestDB(t)
+	storage := services.NewStorageService(db, "sqlite")
+	err := storage.StoreFeatures(context.Background(), []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", TotalLicenses: 10},
+		{ServerHostname: "27000@a", Name: "viewer", TotalLicenses: 2, LicenseModel: models.LicenseModelNodeLocked},
+	})
+	if err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	r := chi.NewRouter()
+	r.Get("/api/v2/servers/{server}/features", V2GetServerFeatures(storage, nil))
+
+	req := httptest.NewRequest("GET", "/api/v2/servers/27000@a/features?model=node-locked", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Data []models.Feature `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Data) != 1 || response.Data[0].Name != "viewer" {
+		t.Errorf("Expected only the node-locked feature, got %+v", response.Data)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v2/servers/27000@a/features?model=bogus", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for unknown model, got %d", w.Code)
+	}
+}
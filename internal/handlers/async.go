@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"licet/internal/middleware"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// jobRequest identifies who requested a job, in the parameters of the jobs
+// run on behalf of API requests
+type jobRequest struct {
+	RequestedBy string `json:"requested_by,omitempty"`
+	Role        string `json:"role,omitempty"`
+}
+
+// requestedBy returns the requester of a job from its request
+func requestedBy(r *http.Request) jobRequest {
+	info := middleware.GetAuthInfo(r)
+	return jobRequest{RequestedBy: info.Username, Role: info.Role}
+}
+
+// jobRequester returns the requester recorded in the parameters of a job
+func jobRequester(job models.Job) middleware.AuthInfo {
+	var req jobRequest
+	json.Unmarshal(job.Params, &req)
+	return middleware.AuthInfo{Authenticated: req.Role != "", Username: req.RequestedBy, Role: req.Role}
+}
+
+// asyncRequest is the request an async download job replays
+type asyncRequest struct {
+	jobRequest
+	Handler string `json:"handler"`
+	Query   string `json:"query,omitempty"`
+}
+
+// AsyncRunner runs the handlers of downloads such as exports and archives as
+// jobs when requested with async=true, so they do not have to finish within
+// the request timeout. The file a job produces is downloaded from
+// /api/v1/jobs/{id}/download.
+type AsyncRunner struct {
+	queue    *services.JobQueue
+	handlers map[string]http.HandlerFunc // By name
+}
+
+// NewAsyncRunner creates a runner queueing jobs on queue
+func NewAsyncRunner(queue *services.JobQueue) *AsyncRunner {
+	a := &AsyncRunner{queue: queue, handlers: make(map[string]http.HandlerFunc)}
+	if queue != nil {
+		queue.Register(services.JobExport, 0, a.run)
+		queue.Register(services.JobArchive, 0, a.run)
+	}
+	return a
+}
+
+// Handle returns a handler that serves h, or queues a job of jobType running
+// it when the request has async=true. name identifies h in the job, so it
+// must be stable across restarts.
+func (a *AsyncRunner) Handle(jobType, name string, h http.HandlerFunc) http.HandlerFunc {
+	a.handlers[name] = h
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("async") != "true" || a.queue == nil {
+			h(w, r)
+			return
+		}
+		query.Del("async")
+
+		params := asyncRequest{jobRequest: requestedBy(r), Handler: name, Query: query.Encode()}
+		job, err := a.queue.Enqueue(r.Context(), jobType, query.Get("server"), params)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		acceptJob(w, job)
+	}
+}
+
+// run replays the request of a job into its output file
+func (a *AsyncRunner) run(ctx context.Context, job models.Job) (interface{}, error) {
+	var params asyncRequest
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return nil, services.PermanentJobError(fmt.Errorf("invalid job parameters: %w", err))
+	}
+	h, ok := a.handlers[params.Handler]
+	if !ok {
+		return nil, services.PermanentJobError(fmt.Errorf("unknown handler %q", params.Handler))
+	}
+
+	requester := jobRequester(job)
+	r, err := http.NewRequestWithContext(middleware.WithAuthInfo(ctx, &requester), http.MethodGet, "/?"+params.Query, nil)
+	if err != nil {
+		return nil, services.PermanentJobError(err)
+	}
+	f, err := os.Create(a.queue.OutputPath(job.ID))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	w := &fileResponse{file: f, header: http.Header{}}
+	h(w, r)
+	if w.err != nil {
+		return nil, w.err
+	}
+	if w.status != 0 && w.status != http.StatusOK {
+		f.Seek(0, 0)
+		msg := make([]byte, 512)
+		n, _ := f.Read(msg)
+		err := fmt.Errorf("request failed with status %d: %s", w.status, strings.TrimSpace(string(msg[:n])))
+		if w.status < http.StatusInternalServerError {
+			return nil, services.PermanentJobError(err)
+		}
+		return nil, err
+	}
+
+	output := models.JobOutput{Filename: strings.ReplaceAll(params.Handler, "/", "-"), ContentType: w.header.Get("Content-Type"), Size: w.size}
+	if _, p, err := mime.ParseMediaType(w.header.Get("Content-Disposition")); err == nil && p["filename"] != "" {
+		output.Filename = p["filename"]
+	}
+	return output, nil
+}
+
+// fileResponse is a ResponseWriter writing the body to a file
+type fileResponse struct {
+	file   *os.File
+	header http.Header
+	status int
+	size   int64
+	err    error
+}
+
+func (w *fileResponse) Header() http.Header { return w.header }
+
+func (w *fileResponse) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *fileResponse) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// backfillParams are the parameters of a backfill job
+type backfillParams struct {
+	jobRequest
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// BackfillUsage handles POST /api/v1/admin/backfill?from=2025-01-01&to=2025-01-31 -
+// queues a job recomputing the usage rollups of past days from their raw
+// samples, then the trends of every configured server. to defaults to today;
+// days that are not rolled up yet are left to the nightly rollup. Returns
+// 202 with the job to follow at /api/v1/jobs/{id}.
+func BackfillUsage(cfg *config.Config, queue *services.JobQueue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.Auth.Enabled && middleware.GetAuthInfo(r).Role != middleware.RoleAdmin {
+			http.Error(w, "Admin role required", http.StatusForbidden)
+			return
+		}
+
+		from, err := time.Parse("2006-01-02", r.URL.Query().Get("from"))
+		if err != nil {
+			http.Error(w, "from must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		to := time.Now()
+		if v := r.URL.Query().Get("to"); v != "" {
+			if to, err = time.Parse("2006-01-02", v); err != nil {
+				http.Error(w, "to must be YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+		}
+		if to.Before(from) {
+			http.Error(w, "to must not be before from", http.StatusBadRequest)
+			return
+		}
+
+		params := backfillParams{jobRequest: requestedBy(r), From: from.Format("2006-01-02"), To: to.Format("2006-01-02")}
+		job, err := queue.Enqueue(r.Context(), services.JobBackfill, "", params)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		acceptJob(w, job)
+	}
+}
+
+// BackfillJob runs the backfill jobs queued by BackfillUsage. Their result
+// holds the number of feature days and trends recomputed.
+func BackfillJob(cfg *config.Config, storage *services.StorageService) services.JobHandler {
+	return func(ctx context.Context, job models.Job) (interface{}, error) {
+		var params backfillParams
+		if err := json.Unmarshal(job.Params, &params); err != nil {
+			return nil, services.PermanentJobError(fmt.Errorf("invalid job parameters: %w", err))
+		}
+		from, err := time.Parse("2006-01-02", params.From)
+		if err != nil {
+			return nil, services.PermanentJobError(err)
+		}
+		to, err := time.Parse("2006-01-02", params.To)
+		if err != nil {
+			return nil, services.PermanentJobError(err)
+		}
+
+		servers := make([]string, 0, len(cfg.Servers))
+		for _, srv := range cfg.Servers {
+			servers = append(servers, srv.Hostname)
+		}
+
+		days, trends, err := storage.Backfill(ctx, from, to, servers)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"feature_days": days,
+			"trends":       trends,
+		}, nil
+	}
+}
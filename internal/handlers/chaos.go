@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"licet/internal/middleware"
+	"licet/internal/parsers"
+	"licet/internal/services"
+)
+
+// Injected faults last 15 minutes unless the request says otherwise, and
+// never more than a day, so a forgotten fault does not outlive the test
+const (
+	defaultChaosDuration = 15 * time.Minute
+	maxChaosDuration     = 24 * time.Hour
+)
+
+// ListChaosFaults handles GET /api/v1/admin/chaos - lists the faults injected
+// into license servers and the servers that may fail
+func ListChaosFaults(chaos *services.ChaosService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		faults := chaos.List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"faults":  faults,
+			"total":   len(faults),
+			"servers": chaos.Servers(),
+			"kinds":   parsers.Faults,
+		})
+	}
+}
+
+// InjectChaosFault handles PUT /api/v1/admin/chaos/{server} - makes the
+// queries of a server fail ({"fault": "timeout", "duration": "15m"})
+func InjectChaosFault(chaos *services.ChaosService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Fault    string `json:"fault"`
+			Duration string `json:"duration"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Fault == "" {
+			http.Error(w, "Invalid request body, expected {\"fault\": \"timeout|server_down|vendor_down|garbage\", \"duration\": \"15m\"}", http.StatusBadRequest)
+			return
+		}
+		duration := defaultChaosDuration
+		if req.Duration != "" {
+			d, err := time.ParseDuration(req.Duration)
+			if err != nil || d <= 0 || d > maxChaosDuration {
+				http.Error(w, "duration must be a positive duration of at most 24h, e.g. 15m", http.StatusBadRequest)
+				return
+			}
+			duration = d
+		}
+
+		by := middleware.GetAuthInfo(r).Username
+		if by == "" {
+			by = "anonymous"
+		}
+		fault, err := chaos.Inject(serverParam(r), req.Fault, duration, by)
+		if errors.Is(err, services.ErrChaosNotAllowed) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(fault)
+	}
+}
+
+// ClearChaosFault handles DELETE /api/v1/admin/chaos/{server} - lets the
+// queries of a server reach it again
+func ClearChaosFault(chaos *services.ChaosService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !chaos.Clear(serverParam(r)) {
+			http.Error(w, "No fault is injected into this server", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// chargebackReport returns the chargeback hours of the months of ?from= and
+// ?to= (YYYY-MM, default the previous month), of one cost center with
+// ?cost_center=
+func chargebackReport(r *http.Request, storage *services.StorageService) (from, to string, entries []models.ChargebackEntry, err error) {
+	now := time.Now()
+	previous := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).AddDate(0, -1, 0).Format(services.ChargebackMonth)
+	from, to = r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if from == "" {
+		from = previous
+	}
+	if to == "" {
+		to = max(from, previous)
+	}
+	entries, err = storage.GetChargeback(r.Context(), from, to, r.URL.Query().Get("cost_center"))
+	return from, to, entries, err
+}
+
+// GetChargebackReport handles GET /api/v1/reports/chargeback - checkout hours
+// per cost center, month, server and feature, with the total of each cost center
+func GetChargebackReport(storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, to, entries, err := chargebackReport(r, storage)
+		if errors.Is(err, services.ErrInvalidChargebackPeriod) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"from":         from,
+			"to":           to,
+			"cost_centers": services.ChargebackTotals(entries),
+			"entries":      entries,
+			"total":        len(entries),
+		})
+	}
+}
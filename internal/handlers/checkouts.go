@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/config"
+	"licet/internal/services"
+)
+
+// GetUserUsage handles GET /api/v1/users/{username}/usage?days=30 - a user's
+// checkout count, total hours and last sighting per server and feature
+func GetUserUsage(storage *services.StorageService, redactor *services.Redactor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username := chi.URLParam(r, "username")
+		days := 30
+		if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 {
+			days = d
+		}
+
+		usage, err := storage.GetUserUsage(r.Context(), username, days)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		redact := redactorFor(r, redactor)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"username": redact.Username(username),
+			"days":     days,
+			"usage":    redact.UserUsage(usage),
+			"total":    len(usage),
+		})
+	}
+}
+
+// GetTopUsers handles GET /api/v1/features/{feature}/top-users?days=30&server=&limit=10
+// - the users with the most checkout hours of a feature
+func GetTopUsers(storage *services.StorageService, redactor *services.Redactor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server, feature := featureParam(r)
+		days := 30
+		if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 {
+			days = d
+		}
+		limit := 10
+		if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+			limit = l
+		}
+
+		users, err := storage.GetTopUsers(r.Context(), feature, server, days, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"feature": feature,
+			"server":  server,
+			"days":    days,
+			"users":   redactorFor(r, redactor).UserUsage(users),
+			"total":   len(users),
+		})
+	}
+}
+
+// GetLongCheckouts handles GET /api/v1/checkouts/long?hours=24&server= - open
+// checkouts held for at least hours, longest first, as reclamation candidates
+func GetLongCheckouts(cfg *config.Config, storage *services.StorageService, redactor *services.Redactor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server := r.URL.Query().Get("server")
+		hours := cfg.Checkouts.LongHeldHours
+		if h, err := strconv.ParseFloat(r.URL.Query().Get("hours"), 64); err == nil && h >= 0 {
+			hours = h
+		}
+
+		checkouts, err := storage.GetOpenCheckouts(r.Context(), server, hours)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"server":    server,
+			"hours":     hours,
+			"checkouts": redactorFor(r, redactor).Checkouts(checkouts),
+			"total":     len(checkouts),
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"licet/internal/services"
+)
+
+// GetCollectionPolls handles GET /api/v1/collection/polls - reports the
+// outcome and duration of the last poll of each server
+func GetCollectionPolls(collector *services.CollectorService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		polls := collector.PollResults()
+		hostnames := make([]string, 0, len(polls))
+		for hostname := range polls {
+			hostnames = append(hostnames, hostname)
+		}
+		sort.Strings(hostnames)
+
+		list := make([]map[string]interface{}, 0, len(hostnames))
+		for _, hostname := range hostnames {
+			p := polls[hostname]
+			poll := map[string]interface{}{
+				"server":      hostname,
+				"type":        p.Type,
+				"status":      "down",
+				"duration_ms": p.Duration.Milliseconds(),
+				"polled_at":   p.At,
+				"timed_out":   p.TimedOut,
+			}
+			if p.Up {
+				poll["status"] = "up"
+			}
+			if p.Error != "" {
+				poll["error"] = p.Error
+			}
+			list = append(list, poll)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"workers": collector.Workers(),
+			"polls":   list,
+			"total":   len(list),
+		})
+	}
+}
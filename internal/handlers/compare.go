@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// CompareServers handles GET /api/v1/servers/compare?a=&b= - lists features
+// present on only one of two servers and features whose license counts,
+// versions or expirations differ. Compares the last collected features, or
+// queries both servers now with live=true, e.g. for a new server that is not
+// collected yet (its type defaults to flexlm unless configured or given as
+// type_a/type_b).
+func CompareServers(query *services.QueryService, storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		a, b := r.URL.Query().Get("a"), r.URL.Query().Get("b")
+		if a == "" || b == "" {
+			http.Error(w, "a and b parameters required", http.StatusBadRequest)
+			return
+		}
+
+		var featuresA, featuresB []models.Feature
+		var err error
+		if r.URL.Query().Get("live") == "true" {
+			featuresA, err = liveFeatures(query, a, r.URL.Query().Get("type_a"))
+			if err == nil {
+				featuresB, err = liveFeatures(query, b, r.URL.Query().Get("type_b"))
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+		} else {
+			featuresA, err = storage.GetFeatures(r.Context(), a)
+			if err == nil {
+				featuresB, err = storage.GetFeatures(r.Context(), b)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(services.CompareServers(a, featuresA, b, featuresB))
+	}
+}
+
+// liveFeatures queries a server's features now, using its configured type
+// unless one is given
+func liveFeatures(query *services.QueryService, hostname, serverType string) ([]models.Feature, error) {
+	if serverType == "" {
+		serverType = "flexlm"
+		if servers, err := query.GetAllServers(); err == nil {
+			for _, s := range servers {
+				if s.Hostname == hostname {
+					serverType = s.Type
+					break
+				}
+			}
+		}
+	}
+
+	result, err := query.QueryServer(hostname, serverType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", hostname, err)
+	}
+	if result.Status.Service != "up" {
+		return nil, fmt.Errorf("license server %s is %s", hostname, result.Status.Service)
+	}
+	return result.Features, nil
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// computedMetricRequest is the body of computed metric create, update and
+// preview requests
+type computedMetricRequest struct {
+	Name        string `json:"name"`
+	Expression  string `json:"expression"`
+	Capacity    string `json:"capacity"`
+	Description string `json:"description"`
+}
+
+func (req computedMetricRequest) metric() models.ComputedMetric {
+	return models.ComputedMetric{
+		Name:        req.Name,
+		Expression:  req.Expression,
+		Capacity:    req.Capacity,
+		Description: req.Description,
+	}
+}
+
+// ListComputedMetrics handles GET /api/v1/computed-metrics - lists the
+// computed metrics
+func ListComputedMetrics(metrics *services.ComputedMetricService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, err := metrics.GetComputedMetrics(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"metrics": list,
+			"server":  models.ComputedServer,
+			"total":   len(list),
+		})
+	}
+}
+
+// GetComputedMetric handles GET /api/v1/computed-metrics/{name}
+func GetComputedMetric(metrics *services.ComputedMetricService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		metric, err := metrics.GetComputedMetric(r.Context(), chi.URLParam(r, "name"))
+		if errors.Is(err, services.ErrComputedMetricNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(metric)
+	}
+}
+
+// CreateComputedMetric handles POST /api/v1/computed-metrics - adds a
+// computed metric, recorded from the next collection on
+func CreateComputedMetric(cfg *config.Config, metrics *services.ComputedMetricService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Server.SettingsEnabled {
+			http.Error(w, "Settings page is disabled", http.StatusForbidden)
+			return
+		}
+
+		var req computedMetricRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		metric := req.metric()
+		metric.CreatedBy = middleware.GetAuthInfo(r).Username
+		err := metrics.CreateComputedMetric(r.Context(), &metric)
+		switch {
+		case errors.Is(err, services.ErrInvalidComputedMetric):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, services.ErrComputedMetricExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Computed metric created",
+			"metric":  metric,
+		})
+	}
+}
+
+// UpdateComputedMetric handles PUT /api/v1/computed-metrics/{name} - replaces
+// the expressions of a computed metric, keeping its history
+func UpdateComputedMetric(cfg *config.Config, metrics *services.ComputedMetricService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Server.SettingsEnabled {
+			http.Error(w, "Settings page is disabled", http.StatusForbidden)
+			return
+		}
+
+		var req computedMetricRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		metric := req.metric()
+		metric.Name = chi.URLParam(r, "name")
+		err := metrics.UpdateComputedMetric(r.Context(), &metric)
+		switch {
+		case errors.Is(err, services.ErrInvalidComputedMetric):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, services.ErrComputedMetricNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Computed metric updated",
+			"metric":  metric,
+		})
+	}
+}
+
+// DeleteComputedMetric handles DELETE /api/v1/computed-metrics/{name}
+func DeleteComputedMetric(cfg *config.Config, metrics *services.ComputedMetricService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Server.SettingsEnabled {
+			http.Error(w, "Settings page is disabled", http.StatusForbidden)
+			return
+		}
+
+		err := metrics.DeleteComputedMetric(r.Context(), chi.URLParam(r, "name"))
+		if errors.Is(err, services.ErrComputedMetricNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Computed metric removed",
+		})
+	}
+}
+
+// PreviewComputedMetric handles POST /api/v1/computed-metrics/preview -
+// evaluates expressions over the current features without storing them
+func PreviewComputedMetric(metrics *services.ComputedMetricService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req computedMetricRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		metric := req.metric()
+		if metric.Name == "" {
+			metric.Name = "preview"
+		}
+		used, total, err := metrics.Evaluate(r.Context(), metric)
+		if errors.Is(err, services.ErrInvalidComputedMetric) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"used":  used,
+			"total": total,
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// contractRequest is the body of license contract create and update requests
+type contractRequest struct {
+	ServerHostname string  `json:"server_hostname"`
+	FeatureName    string  `json:"feature_name"`
+	CostPerSeat    float64 `json:"cost_per_seat"`
+	Currency       string  `json:"currency"`
+	RenewalDate    string  `json:"renewal_date"` // YYYY-MM-DD
+	PONumber       string  `json:"po_number"`
+	VendorContact  string  `json:"vendor_contact"`
+	Notes          string  `json:"notes"`
+}
+
+func (req contractRequest) contract() (models.LicenseContract, error) {
+	contract := models.LicenseContract{
+		ServerHostname: req.ServerHostname,
+		FeatureName:    req.FeatureName,
+		CostPerSeat:    req.CostPerSeat,
+		Currency:       req.Currency,
+		PONumber:       req.PONumber,
+		VendorContact:  req.VendorContact,
+		Notes:          req.Notes,
+	}
+	if req.RenewalDate != "" {
+		renewal, err := time.Parse("2006-01-02", req.RenewalDate)
+		if err != nil {
+			return contract, errors.New("renewal_date must be a date such as 2027-01-31")
+		}
+		contract.RenewalDate = &renewal
+	}
+	return contract, nil
+}
+
+// ListContracts handles GET /api/v1/contracts - lists the license contracts
+func ListContracts(storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		contracts, err := storage.GetContracts(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"contracts": contracts,
+			"total":     len(contracts),
+		})
+	}
+}
+
+// GetContract handles GET /api/v1/contracts/{id}
+func GetContract(storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid contract id", http.StatusBadRequest)
+			return
+		}
+
+		contract, err := storage.GetContract(r.Context(), id)
+		if errors.Is(err, services.ErrContractNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(contract)
+	}
+}
+
+// CreateContract handles POST /api/v1/contracts - adds the cost and contract
+// details of a feature
+func CreateContract(cfg *config.Config, storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Server.SettingsEnabled {
+			http.Error(w, "Settings page is disabled", http.StatusForbidden)
+			return
+		}
+
+		var req contractRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		contract, err := req.contract()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		contract.UpdatedBy = middleware.GetAuthInfo(r).Username
+		err = storage.CreateContract(r.Context(), &contract)
+		switch {
+		case errors.Is(err, services.ErrInvalidContract):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, services.ErrContractExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":  "License contract created",
+			"contract": contract,
+		})
+	}
+}
+
+// UpdateContract handles PUT /api/v1/contracts/{id} - replaces a license
+// contract
+func UpdateContract(cfg *config.Config, storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Server.SettingsEnabled {
+			http.Error(w, "Settings page is disabled", http.StatusForbidden)
+			return
+		}
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid contract id", http.StatusBadRequest)
+			return
+		}
+
+		var req contractRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		contract, err := req.contract()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		contract.ID = id
+		contract.UpdatedBy = middleware.GetAuthInfo(r).Username
+		err = storage.UpdateContract(r.Context(), &contract)
+		switch {
+		case errors.Is(err, services.ErrInvalidContract):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, services.ErrContractNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, services.ErrContractExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":  "License contract updated",
+			"contract": contract,
+		})
+	}
+}
+
+// DeleteContract handles DELETE /api/v1/contracts/{id}
+func DeleteContract(cfg *config.Config, storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Server.SettingsEnabled {
+			http.Error(w, "Settings page is disabled", http.StatusForbidden)
+			return
+		}
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid contract id", http.StatusBadRequest)
+			return
+		}
+
+		err = storage.DeleteContract(r.Context(), id)
+		if errors.Is(err, services.ErrContractNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "License contract removed",
+		})
+	}
+}
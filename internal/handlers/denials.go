@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"licet/internal/services"
+)
+
+// GetDenials handles GET /api/v1/denials?server=&feature=&days=7 - lists
+// license denials with their count per reason (no_seats, excluded,
+// version_too_new, server_down, unsupported_feature, expired, other)
+func GetDenials(events *services.EventService, redactor *services.Redactor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		days := 7
+		if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 {
+			days = d
+		}
+
+		summary, err := events.GetDenials(r.Context(), r.URL.Query().Get("server"), r.URL.Query().Get("feature"), days)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		summary.Denials = redactorFor(r, redactor).Denials(summary.Denials)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(summary)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/services"
+)
+
+// ListDisplayNames handles GET /api/v1/display-names - lists manual display name overrides
+func ListDisplayNames(names *services.DisplayNameService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		overrides, err := names.ListOverrides(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"display_names": overrides,
+			"total":         len(overrides),
+		})
+	}
+}
+
+// featureNamePaths are the cached API responses showing feature display names
+var featureNamePaths = []string{
+	"/api/v1/servers", "/api/v1/features", "/api/v1/utilization",
+	"/api/v2/servers", "/api/v2/features", "/api/v2/utilization",
+}
+
+// SetDisplayName handles PUT /api/v1/display-names - creates or replaces a display name override
+func SetDisplayName(cfg *config.Config, names *services.DisplayNameService, cache *middleware.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Server.SettingsEnabled {
+			http.Error(w, "Settings page is disabled", http.StatusForbidden)
+			return
+		}
+
+		var req struct {
+			ServerHostname string `json:"server_hostname"`
+			FeatureName    string `json:"feature_name"`
+			DisplayName    string `json:"display_name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		req.ServerHostname = strings.TrimSpace(req.ServerHostname)
+		req.FeatureName = strings.TrimSpace(req.FeatureName)
+		req.DisplayName = strings.TrimSpace(req.DisplayName)
+		if req.FeatureName == "" || req.DisplayName == "" {
+			http.Error(w, "feature_name and display_name are required", http.StatusBadRequest)
+			return
+		}
+
+		if err := names.SetOverride(r.Context(), req.ServerHostname, req.FeatureName, req.DisplayName); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, path := range featureNamePaths {
+			cache.InvalidatePrefix(path)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":      "Display name saved",
+			"display_name": req,
+		})
+	}
+}
+
+// DeleteDisplayName handles DELETE /api/v1/display-names?feature=&server= - removes an override
+func DeleteDisplayName(cfg *config.Config, names *services.DisplayNameService, cache *middleware.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Server.SettingsEnabled {
+			http.Error(w, "Settings page is disabled", http.StatusForbidden)
+			return
+		}
+
+		feature := r.URL.Query().Get("feature")
+		if feature == "" {
+			http.Error(w, "feature parameter required", http.StatusBadRequest)
+			return
+		}
+
+		err := names.DeleteOverride(r.Context(), r.URL.Query().Get("server"), feature)
+		if errors.Is(err, services.ErrDisplayNameNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, path := range featureNamePaths {
+			cache.InvalidatePrefix(path)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Display name removed",
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/services"
+)
+
+// GetEntitlements handles GET /api/v1/entitlements - lists the entitlements
+// last synced from the vendor portals, optionally of one source
+func GetEntitlements(entitlements *services.EntitlementService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, err := entitlements.GetEntitlements(r.Context(), r.URL.Query().Get("source"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"entitlements": list,
+			"total":        len(list),
+		})
+	}
+}
+
+// SyncEntitlements handles POST /api/v1/entitlements/sync - pulls the
+// entitlements of every source now
+func SyncEntitlements(cfg *config.Config, entitlements *services.EntitlementService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.Auth.Enabled && middleware.GetAuthInfo(r).Role != middleware.RoleAdmin {
+			http.Error(w, "Admin role required", http.StatusForbidden)
+			return
+		}
+
+		results := entitlements.Sync(r.Context())
+		failed := 0
+		for _, result := range results {
+			if result.Error != "" {
+				failed++
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"sources": results,
+			"failed":  failed,
+		})
+	}
+}
+
+// GetReconciliation handles GET /api/v1/entitlements/reconciliation -
+// compares the synced entitlements with what the license servers report
+func GetReconciliation(entitlements *services.EntitlementService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := entitlements.Reconcile(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/middleware"
+)
+
+// Envelope is the uniform response shape used by all v2 API endpoints
+type Envelope struct {
+	Data  interface{}    `json:"data"`
+	Meta  EnvelopeMeta   `json:"meta"`
+	Error *EnvelopeError `json:"error,omitempty"`
+}
+
+// EnvelopeMeta carries response metadata. Total and paging fields are only
+// present on list responses.
+type EnvelopeMeta struct {
+	Total       *int              `json:"total,omitempty"`
+	Page        int               `json:"page,omitempty"`
+	Limit       int               `json:"limit,omitempty"`
+	TotalPages  int               `json:"total_pages,omitempty"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Filters     map[string]string `json:"filters"`
+}
+
+// EnvelopeError describes a failed v2 request
+type EnvelopeError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// paginationParams are query parameters consumed by pagination rather than filtering
+var paginationParams = map[string]bool{"limit": true, "page": true, "offset": true}
+
+// requestFilters collects the route and query parameters that shaped a response
+func requestFilters(r *http.Request) map[string]string {
+	filters := make(map[string]string)
+
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		for i, key := range rctx.URLParams.Keys {
+			if key != "*" && i < len(rctx.URLParams.Values) {
+				filters[key] = rctx.URLParams.Values[i]
+			}
+		}
+	}
+
+	for key, values := range r.URL.Query() {
+		if paginationParams[key] || len(values) == 0 {
+			continue
+		}
+		filters[key] = values[0]
+	}
+
+	return filters
+}
+
+func writeEnvelope(w http.ResponseWriter, status int, env Envelope) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(env)
+}
+
+// respondObject writes a single object wrapped in the v2 envelope
+func respondObject(w http.ResponseWriter, r *http.Request, data interface{}) {
+	writeEnvelope(w, http.StatusOK, Envelope{
+		Data: data,
+		Meta: EnvelopeMeta{
+			GeneratedAt: time.Now().UTC(),
+			Filters:     requestFilters(r),
+		},
+	})
+}
+
+// respondList writes a paginated list wrapped in the v2 envelope
+func respondList[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	if items == nil {
+		items = []T{}
+	}
+
+	pagination := middleware.ParsePagination(r, paginationConfig)
+	page, total := middleware.ApplyPagination(items, pagination)
+	meta := middleware.NewPaginatedResponse(page, total, pagination).Pagination
+
+	writeEnvelope(w, http.StatusOK, Envelope{
+		Data: page,
+		Meta: EnvelopeMeta{
+			Total:       &total,
+			Page:        meta.Page,
+			Limit:       meta.Limit,
+			TotalPages:  meta.TotalPages,
+			GeneratedAt: time.Now().UTC(),
+			Filters:     requestFilters(r),
+		},
+	})
+}
+
+// respondError writes an error wrapped in the v2 envelope
+func respondError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	writeEnvelope(w, status, Envelope{
+		Meta: EnvelopeMeta{
+			GeneratedAt: time.Now().UTC(),
+			Filters:     requestFilters(r),
+		},
+		Error: &EnvelopeError{Status: status, Message: message},
+	})
+}
@@ -1,30 +1,43 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
+	"licet/internal/config"
 	"licet/internal/models"
+	"licet/internal/parsers"
 	"licet/internal/services"
+	"licet/internal/util"
 )
 
 // ExportHandler handles data export operations
 type ExportHandler struct {
+	cfg       *config.Config
 	query     *services.QueryService
 	storage   *services.StorageService
 	analytics *services.AnalyticsService
+	enhanced  *services.EnhancedAnalyticsService
+	names     *services.DisplayNameService
 }
 
 // NewExportHandler creates a new export handler
-func NewExportHandler(query *services.QueryService, storage *services.StorageService, analytics *services.AnalyticsService) *ExportHandler {
+func NewExportHandler(cfg *config.Config, query *services.QueryService, storage *services.StorageService, analytics *services.AnalyticsService, enhanced *services.EnhancedAnalyticsService, names *services.DisplayNameService) *ExportHandler {
 	return &ExportHandler{
+		cfg:       cfg,
 		query:     query,
 		storage:   storage,
 		analytics: analytics,
+		enhanced:  enhanced,
+		names:     names,
 	}
 }
 
@@ -40,10 +53,13 @@ func (h *ExportHandler) ExportServers(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	servers = groupServers(r, servers)
 
 	switch format {
 	case "csv":
 		h.writeServersCSV(w, servers)
+	case "xlsx":
+		h.writeServersXLSX(w, servers)
 	default:
 		h.writeJSON(w, map[string]interface{}{
 			"servers":     servers,
@@ -71,10 +87,19 @@ func (h *ExportHandler) ExportFeatures(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	filtered, err := featureFilterParam(r, h.storage)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	features = filterFeatures(features, filtered, featureKey)
+	h.names.ApplyToFeatures(features)
 
 	switch format {
 	case "csv":
 		h.writeFeaturesCSV(w, features, server)
+	case "xlsx":
+		h.writeFeaturesXLSX(w, features, server)
 	default:
 		h.writeJSON(w, map[string]interface{}{
 			"server":      server,
@@ -99,10 +124,19 @@ func (h *ExportHandler) ExportUtilization(w http.ResponseWriter, r *http.Request
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	filtered, err := featureFilterParam(r, h.storage)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	utilization = filterFeatures(utilization, filtered, utilizationKey)
+	h.names.ApplyToUtilization(utilization)
 
 	switch format {
 	case "csv":
 		h.writeUtilizationCSV(w, utilization)
+	case "xlsx":
+		h.writeUtilizationXLSX(w, utilization)
 	default:
 		h.writeJSON(w, map[string]interface{}{
 			"utilization": utilization,
@@ -121,6 +155,8 @@ func (h *ExportHandler) ExportUtilizationHistory(w http.ResponseWriter, r *http.
 
 	server := r.URL.Query().Get("server")
 	feature := r.URL.Query().Get("feature")
+	tag := r.URL.Query().Get("tag")
+	group := r.URL.Query().Get("group")
 	daysStr := r.URL.Query().Get("days")
 
 	days := 30
@@ -130,7 +166,25 @@ func (h *ExportHandler) ExportUtilizationHistory(w http.ResponseWriter, r *http.
 		}
 	}
 
-	history, err := h.analytics.GetUtilizationHistory(r.Context(), server, feature, days)
+	filtered, err := featureFilterParam(r, h.analytics)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var history []models.UtilizationHistoryPoint
+	label := feature
+	if filtered != nil {
+		history, err = h.analytics.GetFilteredUtilizationHistory(r.Context(), server, feature, filtered, days)
+		if tag != "" {
+			label = strings.TrimPrefix(label+"_tag_"+tag, "_")
+		}
+		if group != "" {
+			label = strings.TrimPrefix(label+"_group_"+group, "_")
+		}
+	} else {
+		history, err = h.analytics.GetUtilizationHistory(r.Context(), server, feature, days)
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -138,11 +192,15 @@ func (h *ExportHandler) ExportUtilizationHistory(w http.ResponseWriter, r *http.
 
 	switch format {
 	case "csv":
-		h.writeHistoryCSV(w, history, server, feature)
+		h.writeHistoryCSV(w, history, server, label)
+	case "xlsx":
+		h.writeHistoryXLSX(w, history, server, label)
 	default:
 		h.writeJSON(w, map[string]interface{}{
 			"server":      server,
 			"feature":     feature,
+			"tag":         tag,
+			"group":       group,
 			"days":        days,
 			"history":     history,
 			"exported_at": time.Now().UTC().Format(time.RFC3339),
@@ -173,10 +231,19 @@ func (h *ExportHandler) ExportStats(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	filtered, err := featureFilterParam(r, h.storage)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	stats = filterFeatures(stats, filtered, statsKey)
+	h.names.ApplyToStats(stats)
 
 	switch format {
 	case "csv":
 		h.writeStatsCSV(w, stats)
+	case "xlsx":
+		h.writeStatsXLSX(w, stats)
 	default:
 		h.writeJSON(w, map[string]interface{}{
 			"server":      server,
@@ -205,17 +272,36 @@ func (h *ExportHandler) ExportReport(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// The PDF report is the capacity planning report, for procurement
+	if format == "pdf" {
+		h.writeReportPDF(w, r, server, days)
+		return
+	}
+
 	// Gather all data for the report
 	servers, _ := h.query.GetAllServers()
+	servers = groupServers(r, servers)
 	utilization, _ := h.analytics.GetCurrentUtilization(r.Context(), server)
 	stats, _ := h.analytics.GetUtilizationStats(r.Context(), server, days)
 	heatmap, _ := h.analytics.GetHeatmapData(r.Context(), server, days)
+	filtered, err := featureFilterParam(r, h.storage)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	utilization = filterFeatures(utilization, filtered, utilizationKey)
+	stats = filterFeatures(stats, filtered, statsKey)
+	heatmap = filterFeatures(heatmap, filtered, heatmapKey)
+	h.names.ApplyToUtilization(utilization)
+	h.names.ApplyToStats(stats)
 
 	report := map[string]interface{}{
 		"report_type":   "license_utilization",
 		"generated_at":  time.Now().UTC().Format(time.RFC3339),
 		"period_days":   days,
 		"server_filter": server,
+		"tag_filter":    r.URL.Query().Get("tag"),
+		"group_filter":  r.URL.Query().Get("group"),
 		"summary": map[string]interface{}{
 			"total_servers":  len(servers),
 			"total_features": len(utilization),
@@ -229,11 +315,199 @@ func (h *ExportHandler) ExportReport(w http.ResponseWriter, r *http.Request) {
 	switch format {
 	case "csv":
 		h.writeReportCSV(w, utilization, stats)
+	case "xlsx":
+		h.writeReportXLSX(w, server, days, servers, utilization, stats, heatmap)
 	default:
 		h.writeJSON(w, report)
 	}
 }
 
+// ExportForecast exports the projected seat requirements per feature for the
+// coming months, for budget planning. growth overrides the configured yearly
+// headcount growth in percent.
+func (h *ExportHandler) ExportForecast(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	days := 90
+	if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 {
+		days = d
+	}
+
+	growth := h.cfg.Forecast.HeadcountGrowthPct
+	if g := r.URL.Query().Get("growth"); g != "" {
+		parsed, err := strconv.ParseFloat(g, 64)
+		if err != nil || parsed <= -100 {
+			http.Error(w, "Invalid growth percentage", http.StatusBadRequest)
+			return
+		}
+		growth = parsed
+	}
+
+	months := h.cfg.Forecast.Months
+	if m, err := strconv.Atoi(r.URL.Query().Get("months")); err == nil {
+		months = m
+	}
+	if months <= 0 || months > 60 {
+		http.Error(w, "months must be between 1 and 60", http.StatusBadRequest)
+		return
+	}
+
+	forecast, err := h.enhanced.GetBudgetForecast(r.Context(), days, growth, months, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for i := range forecast.Features {
+		f := &forecast.Features[i]
+		if name := h.names.FeatureName(f.ServerHostname, f.FeatureName); name != f.FeatureName {
+			f.DisplayName = name
+		}
+	}
+
+	switch format {
+	case "csv":
+		h.writeForecastCSV(w, forecast)
+	case "xlsx":
+		h.writeForecastXLSX(w, forecast)
+	default:
+		h.writeJSON(w, forecast)
+	}
+}
+
+// ExportChargeback exports the chargeback hours of cost centers for finance
+func (h *ExportHandler) ExportChargeback(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	from, to, entries, err := chargebackReport(r, h.storage)
+	if errors.Is(err, services.ErrInvalidChargebackPeriod) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	switch format {
+	case "csv":
+		h.writeChargebackCSV(w, entries)
+	case "xlsx":
+		h.writeChargebackXLSX(w, from, to, entries)
+	default:
+		h.writeJSON(w, map[string]interface{}{
+			"from":         from,
+			"to":           to,
+			"cost_centers": services.ChargebackTotals(entries),
+			"entries":      entries,
+			"exported_at":  time.Now().UTC().Format(time.RFC3339),
+			"count":        len(entries),
+		})
+	}
+}
+
+// ExportExpirationsICS serves an iCalendar feed with an all-day event per
+// upcoming feature expiration, for calendar clients to subscribe to. Versions
+// of a feature expiring on the same day share an event. Reminders are days
+// before the expiration, from export.calendar_reminders unless overridden.
+func (h *ExportHandler) ExportExpirationsICS(w http.ResponseWriter, r *http.Request) {
+	days := 365
+	if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 {
+		days = d
+	}
+
+	reminders := h.cfg.Export.CalendarReminders
+	if param := r.URL.Query().Get("reminders"); param != "" {
+		reminders = nil
+		for _, part := range strings.Split(param, ",") {
+			d, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || d < 0 || d > 365 {
+				http.Error(w, "reminders must be days between 0 and 365, e.g. 30,7", http.StatusBadRequest)
+				return
+			}
+			reminders = append(reminders, d)
+		}
+	}
+
+	features, err := h.storage.GetExpiringFeatures(r.Context(), days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.names.ApplyToFeatures(features)
+
+	server := r.URL.Query().Get("server")
+	type expiration struct {
+		feature  models.Feature
+		versions []string
+		seats    int
+	}
+	var order []string
+	expirations := make(map[string]*expiration)
+	for _, f := range features {
+		if (server != "" && f.ServerHostname != server) || f.ExpirationDate.Equal(parsers.PermanentExpirationDate) {
+			continue
+		}
+		key := f.ServerHostname + "|" + f.Name + "|" + f.ExpirationDate.Format("2006-01-02")
+		e, ok := expirations[key]
+		if !ok {
+			e = &expiration{feature: f}
+			expirations[key] = e
+			order = append(order, key)
+		}
+		if f.Version != "" {
+			e.versions = append(e.versions, f.Version)
+		}
+		e.seats += f.TotalLicenses
+	}
+
+	cal := util.NewCalendar(h.cfg.Branding.Name() + " license expirations")
+	for _, key := range order {
+		e := expirations[key]
+		f := e.feature
+		name := f.Name
+		if f.DisplayName != "" {
+			name = f.DisplayName
+		}
+
+		var desc strings.Builder
+		fmt.Fprintf(&desc, "Feature: %s\nServer: %s\n", f.Name, f.ServerHostname)
+		if f.VendorDaemon != "" {
+			fmt.Fprintf(&desc, "Vendor: %s\n", f.VendorDaemon)
+		}
+		if len(e.versions) > 0 {
+			fmt.Fprintf(&desc, "Versions: %s\n", strings.Join(e.versions, ", "))
+		}
+		fmt.Fprintf(&desc, "Seats: %d", e.seats)
+		if base := h.cfg.Alerts.BaseURL; base != "" {
+			fmt.Fprintf(&desc, "\n%s/expiration/%s", strings.TrimRight(base, "/"), url.PathEscape(f.ServerHostname))
+		}
+
+		cal.AddEvent(util.CalendarEvent{
+			UID:         fmt.Sprintf("expiry-%s-%s-%s@licet", sanitizeFilename(f.ServerHostname), sanitizeFilename(f.Name), f.ExpirationDate.Format("20060102")),
+			Date:        f.ExpirationDate,
+			Summary:     fmt.Sprintf("%s license expires on %s", name, f.ServerHostname),
+			Description: desc.String(),
+			Categories:  []string{"License expiration"},
+			Reminders:   reminders,
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := cal.Write(&buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", "inline; filename=expirations.ics")
+	w.Write(buf.Bytes())
+}
+
 // Helper methods for writing responses
 
 func (h *ExportHandler) writeJSON(w http.ResponseWriter, data interface{}) {
@@ -275,8 +549,8 @@ func (h *ExportHandler) writeFeaturesCSV(w http.ResponseWriter, features []model
 
 	// Write header
 	writer.Write([]string{
-		"Server", "Feature", "Version", "Vendor Daemon",
-		"Total Licenses", "Used Licenses", "Available",
+		"Server", "Feature", "Display Name", "Version", "Vendor Daemon",
+		"Total Licenses", "Used Licenses", "Reserved", "Borrowed", "Overdraft", "Available",
 		"Expiration Date", "Last Updated",
 	})
 
@@ -285,10 +559,14 @@ func (h *ExportHandler) writeFeaturesCSV(w http.ResponseWriter, features []model
 		writer.Write([]string{
 			feature.ServerHostname,
 			feature.Name,
+			feature.DisplayName,
 			feature.Version,
 			feature.VendorDaemon,
 			strconv.Itoa(feature.TotalLicenses),
 			strconv.Itoa(feature.UsedLicenses),
+			strconv.Itoa(feature.ReservedLicenses),
+			strconv.Itoa(feature.BorrowedLicenses),
+			strconv.Itoa(feature.OverdraftLicenses),
 			strconv.Itoa(feature.AvailableLicenses()),
 			feature.ExpirationDate.Format("2006-01-02"),
 			feature.LastUpdated.Format(time.RFC3339),
@@ -305,8 +583,8 @@ func (h *ExportHandler) writeUtilizationCSV(w http.ResponseWriter, utilization [
 
 	// Write header
 	writer.Write([]string{
-		"Server", "Feature", "Version", "Vendor Daemon",
-		"Total Licenses", "Used Licenses", "Available",
+		"Server", "Feature", "Display Name", "Version", "Vendor Daemon",
+		"Total Licenses", "Used Licenses", "Reserved", "Borrowed", "Overdraft", "Available",
 		"Utilization %",
 	})
 
@@ -315,10 +593,14 @@ func (h *ExportHandler) writeUtilizationCSV(w http.ResponseWriter, utilization [
 		writer.Write([]string{
 			util.ServerHostname,
 			util.FeatureName,
+			util.DisplayName,
 			util.Version,
 			util.VendorDaemon,
 			strconv.Itoa(util.TotalLicenses),
 			strconv.Itoa(util.UsedLicenses),
+			strconv.Itoa(util.ReservedLicenses),
+			strconv.Itoa(util.BorrowedLicenses),
+			strconv.Itoa(util.OverdraftLicenses),
 			strconv.Itoa(util.AvailableLicenses),
 			fmt.Sprintf("%.2f", util.UtilizationPct),
 		})
@@ -333,13 +615,14 @@ func (h *ExportHandler) writeHistoryCSV(w http.ResponseWriter, history []models.
 	defer writer.Flush()
 
 	// Write header
-	writer.Write([]string{"Timestamp", "Users Count"})
+	writer.Write([]string{"Timestamp", "Users Count", "Queued Requests"})
 
 	// Write data
 	for _, point := range history {
 		writer.Write([]string{
 			point.Timestamp,
 			strconv.Itoa(point.UsersCount),
+			strconv.Itoa(point.QueuedCount),
 		})
 	}
 }
@@ -353,7 +636,7 @@ func (h *ExportHandler) writeStatsCSV(w http.ResponseWriter, stats []models.Util
 
 	// Write header
 	writer.Write([]string{
-		"Server", "Feature", "Avg Usage", "Peak Usage",
+		"Server", "Feature", "Display Name", "Avg Usage", "Peak Usage",
 		"Min Usage", "Total Licenses", "Avg Utilization %",
 	})
 
@@ -366,6 +649,7 @@ func (h *ExportHandler) writeStatsCSV(w http.ResponseWriter, stats []models.Util
 		writer.Write([]string{
 			stat.ServerHostname,
 			stat.FeatureName,
+			stat.DisplayName,
 			fmt.Sprintf("%.2f", stat.AvgUsage),
 			strconv.Itoa(stat.PeakUsage),
 			strconv.Itoa(stat.MinUsage),
@@ -390,13 +674,14 @@ func (h *ExportHandler) writeReportCSV(w http.ResponseWriter, utilization []mode
 	// Write utilization summary
 	writer.Write([]string{"Current Utilization"})
 	writer.Write([]string{
-		"Server", "Feature", "Version", "Total", "Used", "Available", "Utilization %",
+		"Server", "Feature", "Display Name", "Version", "Total", "Used", "Available", "Utilization %",
 	})
 
 	for _, util := range utilization {
 		writer.Write([]string{
 			util.ServerHostname,
 			util.FeatureName,
+			util.DisplayName,
 			util.Version,
 			strconv.Itoa(util.TotalLicenses),
 			strconv.Itoa(util.UsedLicenses),
@@ -410,13 +695,14 @@ func (h *ExportHandler) writeReportCSV(w http.ResponseWriter, utilization []mode
 	// Write statistics
 	writer.Write([]string{"Usage Statistics"})
 	writer.Write([]string{
-		"Server", "Feature", "Avg Usage", "Peak Usage", "Min Usage", "Total Licenses",
+		"Server", "Feature", "Display Name", "Avg Usage", "Peak Usage", "Min Usage", "Total Licenses",
 	})
 
 	for _, stat := range stats {
 		writer.Write([]string{
 			stat.ServerHostname,
 			stat.FeatureName,
+			stat.DisplayName,
 			fmt.Sprintf("%.2f", stat.AvgUsage),
 			strconv.Itoa(stat.PeakUsage),
 			strconv.Itoa(stat.MinUsage),
@@ -425,6 +711,378 @@ func (h *ExportHandler) writeReportCSV(w http.ResponseWriter, utilization []mode
 	}
 }
 
+func (h *ExportHandler) writeForecastCSV(w http.ResponseWriter, forecast *models.BudgetForecast) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=forecast_%s.csv", time.Now().Format("20060102_150405")))
+
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	// Write header
+	header := []string{"Server", "Feature", "Display Name", "Total Licenses", "Peak Usage", "Trend (seats/day)"}
+	header = append(header, forecast.Months...)
+	writer.Write(append(header, "Shortfall"))
+
+	// Write data
+	for _, f := range forecast.Features {
+		row := []string{
+			f.ServerHostname,
+			f.FeatureName,
+			f.DisplayName,
+			strconv.Itoa(f.TotalLicenses),
+			strconv.Itoa(f.PeakUsage),
+			fmt.Sprintf("%.3f", f.TrendSlope),
+		}
+		for _, seats := range f.RequiredSeats {
+			row = append(row, strconv.Itoa(seats))
+		}
+		writer.Write(append(row, strconv.Itoa(f.Shortfall)))
+	}
+}
+
+func (h *ExportHandler) writeChargebackCSV(w http.ResponseWriter, entries []models.ChargebackEntry) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=chargeback_%s.csv", time.Now().Format("20060102_150405")))
+
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	writer.Write([]string{"Month", "Cost Center", "Server", "Feature", "Checkout Hours", "Checkouts", "Users"})
+	for _, e := range entries {
+		writer.Write([]string{
+			e.Month,
+			e.CostCenter,
+			e.ServerHostname,
+			e.FeatureName,
+			fmt.Sprintf("%.2f", e.CheckoutHours),
+			strconv.Itoa(e.Checkouts),
+			strconv.Itoa(e.Users),
+		})
+	}
+}
+
+func (h *ExportHandler) writeServersXLSX(w http.ResponseWriter, servers []models.LicenseServer) {
+	wb := util.NewWorkbook()
+	sheet := wb.AddSheet("Servers", "ID", "Hostname", "Description", "Type", "WebUI", "Created At", "Updated At")
+	for _, server := range servers {
+		sheet.AddRow(server.ID, server.Hostname, server.Description, server.Type, server.WebUI, server.CreatedAt, server.UpdatedAt)
+	}
+	h.writeXLSX(w, wb, "servers")
+}
+
+func (h *ExportHandler) writeFeaturesXLSX(w http.ResponseWriter, features []models.Feature, server string) {
+	wb := util.NewWorkbook()
+	sheet := wb.AddSheet("Features",
+		"Server", "Feature", "Display Name", "Version", "Vendor Daemon",
+		"Total Licenses", "Used Licenses", "Reserved", "Borrowed", "Overdraft", "Available",
+		"Expiration Date", "Last Updated",
+	)
+	for _, feature := range features {
+		sheet.AddRow(
+			feature.ServerHostname, feature.Name, feature.DisplayName, feature.Version, feature.VendorDaemon,
+			feature.TotalLicenses, feature.UsedLicenses, feature.ReservedLicenses, feature.BorrowedLicenses, feature.OverdraftLicenses,
+			feature.AvailableLicenses(),
+			feature.ExpirationDate, feature.LastUpdated,
+		)
+	}
+	h.writeXLSX(w, wb, "features_"+sanitizeFilename(server))
+}
+
+func (h *ExportHandler) writeUtilizationXLSX(w http.ResponseWriter, utilization []models.UtilizationData) {
+	wb := util.NewWorkbook()
+	addUtilizationSheet(wb, utilization)
+	h.writeXLSX(w, wb, "utilization")
+}
+
+// addUtilizationSheet adds the current utilization of each feature to wb
+func addUtilizationSheet(wb *util.Workbook, utilization []models.UtilizationData) {
+	sheet := wb.AddSheet("Utilization",
+		"Server", "Feature", "Display Name", "Version", "Vendor Daemon",
+		"Total Licenses", "Used Licenses", "Reserved", "Borrowed", "Overdraft", "Available",
+		"Utilization %",
+	)
+	for _, u := range utilization {
+		sheet.AddRow(
+			u.ServerHostname, u.FeatureName, u.DisplayName, u.Version, u.VendorDaemon,
+			u.TotalLicenses, u.UsedLicenses, u.ReservedLicenses, u.BorrowedLicenses, u.OverdraftLicenses, u.AvailableLicenses,
+			u.UtilizationPct,
+		)
+	}
+}
+
+func (h *ExportHandler) writeHistoryXLSX(w http.ResponseWriter, history []models.UtilizationHistoryPoint, server, feature string) {
+	wb := util.NewWorkbook()
+	sheet := wb.AddSheet("History", "Timestamp", "Users Count", "Queued Requests")
+	for _, point := range history {
+		sheet.AddRow(historyTimestamp(point.Timestamp), point.UsersCount, point.QueuedCount)
+	}
+	h.writeXLSX(w, wb, "history_"+sanitizeFilename(server)+"_"+sanitizeFilename(feature))
+}
+
+// historyTimestamps are the layouts the databases return history timestamps in
+var historyTimestamps = []string{"2006-01-02 15:04:05", time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// historyTimestamp returns a history timestamp as a time for a date cell, or
+// as it is if it cannot be parsed
+func historyTimestamp(s string) interface{} {
+	for _, layout := range historyTimestamps {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return s
+}
+
+func (h *ExportHandler) writeStatsXLSX(w http.ResponseWriter, stats []models.UtilizationStats) {
+	wb := util.NewWorkbook()
+	addStatsSheet(wb, stats)
+	h.writeXLSX(w, wb, "stats")
+}
+
+// addStatsSheet adds the usage statistics of each feature to wb
+func addStatsSheet(wb *util.Workbook, stats []models.UtilizationStats) {
+	sheet := wb.AddSheet("Statistics",
+		"Server", "Feature", "Display Name", "Avg Usage", "Peak Usage",
+		"Min Usage", "Total Licenses", "Avg Utilization %",
+	)
+	for _, stat := range stats {
+		avgUtilization := 0.0
+		if stat.TotalLicenses > 0 {
+			avgUtilization = (stat.AvgUsage / float64(stat.TotalLicenses)) * 100
+		}
+		sheet.AddRow(
+			stat.ServerHostname, stat.FeatureName, stat.DisplayName, stat.AvgUsage, stat.PeakUsage,
+			stat.MinUsage, stat.TotalLicenses, avgUtilization,
+		)
+	}
+}
+
+func (h *ExportHandler) writeReportXLSX(w http.ResponseWriter, server string, days int, servers []models.LicenseServer, utilization []models.UtilizationData, stats []models.UtilizationStats, heatmap []models.HeatmapData) {
+	wb := util.NewWorkbook()
+
+	summary := wb.AddSheet("Summary", "Item", "Value")
+	summary.AddRow("Report", "License Utilization Report")
+	summary.AddRow("Generated At", time.Now().UTC())
+	summary.AddRow("Generated By", h.cfg.Branding.Name())
+	summary.AddRow("Period (days)", days)
+	summary.AddRow("Server Filter", server)
+	summary.AddRow("Total Servers", len(servers))
+	summary.AddRow("Total Features", len(utilization))
+
+	addUtilizationSheet(wb, utilization)
+	addStatsSheet(wb, stats)
+
+	header := []string{"Server", "Feature"}
+	for hour := 0; hour < 24; hour++ {
+		header = append(header, fmt.Sprintf("%02d:00", hour))
+	}
+	hourly := wb.AddSheet("Hourly Peak Usage", header...)
+	for _, feature := range heatmap {
+		row := make([]interface{}, 2+24)
+		row[0], row[1] = feature.ServerHostname, feature.FeatureName
+		for _, hd := range feature.HourlyData {
+			if hd.Hour >= 0 && hd.Hour < 24 {
+				row[2+hd.Hour] = hd.PeakUsage
+			}
+		}
+		hourly.AddRow(row...)
+	}
+
+	h.writeXLSX(w, wb, "report")
+}
+
+// maxReportCharts limits the usage charts of a PDF report to the features
+// most in need of seats
+const maxReportCharts = 8
+
+// writeReportPDF renders the capacity planning report of the period as a PDF
+// to attach to procurement requests: a summary page, the utilization tables
+// and the daily peak usage of the features running out of seats
+func (h *ExportHandler) writeReportPDF(w http.ResponseWriter, r *http.Request, server string, days int) {
+	filtered, err := featureFilterParam(r, h.enhanced)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var report *models.CapacityPlanningReport
+	if filtered != nil {
+		report, err = h.enhanced.GetFilteredCapacityReport(r.Context(), days, filtered)
+	} else {
+		report, err = h.enhanced.CapacityReport(r.Context(), days, false)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	onServer := func(insights []models.CapacityInsight) []models.CapacityInsight {
+		if server == "" {
+			return insights
+		}
+		var filtered []models.CapacityInsight
+		for _, in := range insights {
+			if in.ServerHostname == server {
+				filtered = append(filtered, in)
+			}
+		}
+		return filtered
+	}
+	high, low := onServer(report.HighUtilization), onServer(report.LowUtilization)
+	up, down := onServer(report.TrendingUp), onServer(report.TrendingDown)
+
+	doc := util.NewPDFDocument()
+	doc.SetHeader(h.cfg.Branding.Name())
+	doc.Heading("Capacity Planning Report")
+	doc.Text(fmt.Sprintf("Last %d days, generated %s", report.PeriodAnalyzed, report.GeneratedAt))
+	if server != "" {
+		doc.Text(fmt.Sprintf("The summary covers all servers; tables and charts are limited to %s.", server))
+	}
+	doc.Table([]string{"Servers", "Features", "At capacity", "Underutilized"}, [][]string{{
+		strconv.Itoa(report.TotalServers), strconv.Itoa(report.TotalFeatures),
+		strconv.Itoa(report.FeaturesAtCapacity), strconv.Itoa(report.FeaturesUnderutilized),
+	}})
+	if len(report.Recommendations) > 0 {
+		doc.Heading("Recommendations")
+		for _, rec := range report.Recommendations {
+			doc.Text(fmt.Sprintf("[%s] %s: %s", rec.Priority, rec.Title, rec.Description))
+		}
+	}
+
+	doc.PageBreak()
+	insightTable := func(title string, insights []models.CapacityInsight) {
+		doc.Heading(title)
+		if len(insights) == 0 {
+			doc.Text("No features.")
+			return
+		}
+		rows := make([][]string, len(insights))
+		for i, in := range insights {
+			rows[i] = []string{h.names.FeatureName(in.ServerHostname, in.FeatureName), in.ServerHostname, strconv.Itoa(in.TotalLicenses),
+				fmt.Sprintf("%.1f", in.AvgUsage), strconv.Itoa(in.PeakUsage), fmt.Sprintf("%.1f%%", in.UtilizationPct), in.Recommendation}
+		}
+		doc.Table([]string{"Feature", "Server", "Total", "Avg", "Peak", "Util", "Recommendation"}, rows)
+	}
+	insightTable("High Utilization (above 80%)", high)
+	insightTable("Low Utilization (below 20%)", low)
+	if len(up) > 0 {
+		insightTable("Trending Up", up)
+	}
+	if len(down) > 0 {
+		insightTable("Trending Down", down)
+	}
+
+	// Chart the features at capacity first, then those growing towards it
+	charted := make(map[string]bool)
+	var charts []models.CapacityInsight
+	for _, in := range append(append([]models.CapacityInsight{}, high...), up...) {
+		key := in.ServerHostname + "|" + in.FeatureName
+		if !charted[key] && len(charts) < maxReportCharts {
+			charted[key] = true
+			charts = append(charts, in)
+		}
+	}
+	if len(charts) > 0 {
+		doc.PageBreak()
+		doc.Heading("Usage Trends")
+		doc.Text("Daily peak usage of each feature; the dashed line marks its licenses.")
+		for _, in := range charts {
+			history, err := h.analytics.GetUtilizationHistory(r.Context(), in.ServerHostname, in.FeatureName, days)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			labels, peaks := dailyPeaks(history)
+			caption := fmt.Sprintf("%s on %s (%+.2f seats/day)", h.names.FeatureName(in.ServerHostname, in.FeatureName), in.ServerHostname, in.TrendSlope)
+			doc.Chart(caption, labels, peaks, float64(in.TotalLicenses))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=capacity_report_%s.pdf", time.Now().Format("20060102_150405")))
+	w.Write(buf.Bytes())
+}
+
+// dailyPeaks returns the days of a usage history with the peak usage of each
+func dailyPeaks(history []models.UtilizationHistoryPoint) ([]string, []float64) {
+	var days []string
+	var peaks []float64
+	for _, point := range history {
+		day := point.Timestamp
+		if len(day) > 10 {
+			day = day[:10]
+		}
+		if n := len(days); n > 0 && days[n-1] == day {
+			peaks[n-1] = max(peaks[n-1], float64(point.UsersCount))
+			continue
+		}
+		days = append(days, day)
+		peaks = append(peaks, float64(point.UsersCount))
+	}
+	return days, peaks
+}
+
+func (h *ExportHandler) writeForecastXLSX(w http.ResponseWriter, forecast *models.BudgetForecast) {
+	wb := util.NewWorkbook()
+
+	header := []string{"Server", "Feature", "Display Name", "Total Licenses", "Peak Usage", "Trend (seats/day)"}
+	header = append(header, forecast.Months...)
+	sheet := wb.AddSheet("Forecast", append(header, "Shortfall")...)
+	for _, f := range forecast.Features {
+		row := []interface{}{f.ServerHostname, f.FeatureName, f.DisplayName, f.TotalLicenses, f.PeakUsage, f.TrendSlope}
+		for _, seats := range f.RequiredSeats {
+			row = append(row, seats)
+		}
+		sheet.AddRow(append(row, f.Shortfall)...)
+	}
+
+	assumptions := wb.AddSheet("Assumptions", "Assumption", "Value")
+	assumptions.AddRow("Generated At", forecast.GeneratedAt)
+	assumptions.AddRow("Usage History (days)", forecast.PeriodAnalyzed)
+	assumptions.AddRow("Headcount Growth (% per year)", forecast.GrowthPct)
+	assumptions.AddRow("Generated By", h.cfg.Branding.Name())
+	assumptions.AddRow("Method", "Peak usage plus the linear usage trend, scaled by headcount growth; partial seats round up")
+
+	h.writeXLSX(w, wb, "forecast")
+}
+
+func (h *ExportHandler) writeChargebackXLSX(w http.ResponseWriter, from, to string, entries []models.ChargebackEntry) {
+	wb := util.NewWorkbook()
+
+	totals := wb.AddSheet("Cost Centers", "Cost Center", "Checkout Hours", "Share (%)")
+	for _, t := range services.ChargebackTotals(entries) {
+		totals.AddRow(t.CostCenter, t.CheckoutHours, t.SharePct)
+	}
+
+	sheet := wb.AddSheet("Chargeback", "Month", "Cost Center", "Server", "Feature", "Checkout Hours", "Checkouts", "Users")
+	for _, e := range entries {
+		sheet.AddRow(e.Month, e.CostCenter, e.ServerHostname, e.FeatureName, e.CheckoutHours, e.Checkouts, e.Users)
+	}
+
+	period := wb.AddSheet("Period", "Field", "Value")
+	period.AddRow("From", from)
+	period.AddRow("To", to)
+	period.AddRow("Generated By", h.cfg.Branding.Name())
+
+	h.writeXLSX(w, wb, "chargeback")
+}
+
+// writeXLSX writes wb as an attachment named after name and the current time
+func (h *ExportHandler) writeXLSX(w http.ResponseWriter, wb *util.Workbook, name string) {
+	var buf bytes.Buffer
+	if err := wb.Write(&buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s_%s.xlsx", name, time.Now().Format("20060102_150405")))
+	w.Write(buf.Bytes())
+}
+
 // sanitizeFilename removes or replaces characters that are not safe for filenames
 func sanitizeFilename(s string) string {
 	result := make([]byte, 0, len(s))
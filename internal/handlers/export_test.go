@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/parsers"
+	"licet/internal/services"
+)
+
+// readXLSX returns the parts of an XLSX file by name
+func readXLSX(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Export is not an XLSX file: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", f.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(content)
+	}
+	return parts
+}
+
+func newTestExportHandler(t *testing.T) *ExportHandler {
+	t.Helper()
+	db := newTestDB(t)
+	storage := services.NewStorageService(db, "sqlite")
+	cfg := &config.Config{Servers: []config.LicenseServer{{Hostname: "27000@lic1", Type: "flexlm"}}}
+	err := storage.StoreFeatures(context.Background(), []models.Feature{{
+		ServerHostname: "27000@lic1", Name: "solver", Version: "2.0", VendorDaemon: "vendor",
+		TotalLicenses: 10, UsedLicenses: 4, ExpirationDate: time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC),
+	}})
+	if err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	// Usage rising by a seat a day towards the 10 seats of solver
+	for day := 0; day < 9; day++ {
+		date := time.Now().AddDate(0, 0, day-8).Format("2006-01-02")
+		for _, hour := range []string{"09:00:00", "14:00:00"} {
+			_, err := db.Exec(`INSERT INTO feature_usage (server_hostname, feature_name, date, time, users_count) VALUES (?, ?, ?, ?, ?)`,
+				"27000@lic1", "solver", date, hour, day+1)
+			if err != nil {
+				t.Fatalf("Failed to insert usage: %v", err)
+			}
+		}
+	}
+	return NewExportHandler(cfg, services.NewQueryService(cfg, storage), storage, services.NewAnalyticsService(db, storage, "sqlite"),
+		services.NewEnhancedAnalyticsService(db, storage, "sqlite"), nil)
+}
+
+func TestExportFeatures_XLSX(t *testing.T) {
+	h := newTestExportHandler(t)
+
+	w := httptest.NewRecorder()
+	h.ExportFeatures(w, httptest.NewRequest(http.MethodGet, "/api/v1/export/features?server=27000@lic1&format=xlsx", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet" {
+		t.Errorf("Expected an XLSX content type, got %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment; filename=features_27000_lic1_") || !strings.HasSuffix(cd, ".xlsx") {
+		t.Errorf("Unexpected Content-Disposition %q", cd)
+	}
+
+	parts := readXLSX(t, w.Body.Bytes())
+	if !strings.Contains(parts["xl/workbook.xml"], `name="Features"`) {
+		t.Errorf("Expected a Features sheet, got %s", parts["xl/workbook.xml"])
+	}
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`state="frozen"`,
+		`<c r="F2"><v>10</v></c>`, // Total licenses as a number
+		`<c r="K2"><v>6</v></c>`,  // Available
+		`<c r="L2" s="1"><v>46418</v></c>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("Expected the sheet to contain %s, got %s", want, sheet)
+		}
+	}
+}
+
+func TestExportReport_XLSX(t *testing.T) {
+	h := newTestExportHandler(t)
+
+	w := httptest.NewRecorder()
+	h.ExportReport(w, httptest.NewRequest(http.MethodGet, "/api/v1/export/report?format=xlsx&days=7", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	parts := readXLSX(t, w.Body.Bytes())
+	for _, name := range []string{"Summary", "Utilization", "Statistics", "Hourly Peak Usage"} {
+		if !strings.Contains(parts["xl/workbook.xml"], `name="`+name+`"`) {
+			t.Errorf("Expected a %s sheet, got %s", name, parts["xl/workbook.xml"])
+		}
+	}
+	if !strings.Contains(parts["xl/worksheets/sheet1.xml"], `<c r="B5"><v>7</v></c>`) {
+		t.Errorf("Expected the period as a number, got %s", parts["xl/worksheets/sheet1.xml"])
+	}
+	if !strings.Contains(parts["xl/worksheets/sheet2.xml"], `<t xml:space="preserve">solver</t>`) {
+		t.Errorf("Expected the utilization of solver, got %s", parts["xl/worksheets/sheet2.xml"])
+	}
+}
+
+func TestExportReport_PDF(t *testing.T) {
+	h := newTestExportHandler(t)
+
+	w := httptest.NewRecorder()
+	h.ExportReport(w, httptest.NewRequest(http.MethodGet, "/api/v1/export/report?format=pdf&days=30", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/pdf" {
+		t.Errorf("Expected a PDF, got %q", ct)
+	}
+
+	out := w.Body.String()
+	if !strings.HasPrefix(out, "%PDF-") {
+		t.Fatal("Expected a PDF document")
+	}
+	// Summary, tables and charts each start on a page of their own
+	if pages := strings.Count(out, "/Type /Page "); pages != 3 {
+		t.Errorf("Expected 3 pages, got %d", pages)
+	}
+	for _, want := range []string{
+		"(Capacity Planning Report) Tj",
+		"(High Utilization \\(above 80%\\)) Tj",
+		"(Usage Trends) Tj",
+		"(solver on 27000@lic1 \\(+",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the report to contain %s", want)
+		}
+	}
+}
+
+func TestDailyPeaks(t *testing.T) {
+	days, peaks := dailyPeaks([]models.UtilizationHistoryPoint{
+		{Timestamp: "2026-03-01 09:00:00", UsersCount: 3},
+		{Timestamp: "2026-03-01 14:00:00", UsersCount: 5},
+		{Timestamp: "2026-03-02 09:00:00", UsersCount: 4},
+	})
+	if len(days) != 2 || days[0] != "2026-03-01" || peaks[0] != 5 || peaks[1] != 4 {
+		t.Errorf("Unexpected daily peaks %v %v", days, peaks)
+	}
+}
+
+func TestHistoryTimestamp(t *testing.T) {
+	want := time.Date(2026, 3, 2, 10, 15, 0, 0, time.UTC)
+	for _, s := range []string{"2026-03-02 10:15:00", "2026-03-02T10:15:00Z", "2026-03-02T10:15:00", "2026-03-02 10:15"} {
+		if got, ok := historyTimestamp(s).(time.Time); !ok || !got.Equal(want) {
+			t.Errorf("historyTimestamp(%q) = %v, want %v", s, got, want)
+		}
+	}
+	if got := historyTimestamp("week 9"); got != "week 9" {
+		t.Errorf("Expected an unknown timestamp to be kept as text, got %v", got)
+	}
+}
+
+func TestExportExpirationsICS(t *testing.T) {
+	h := newTestExportHandler(t)
+	expires := time.Now().AddDate(0, 0, 20).Truncate(24 * time.Hour)
+	err := h.storage.StoreFeatures(context.Background(), []models.Feature{
+		{ServerHostname: "27000@lic1", Name: "mesher", Version: "1.0", TotalLicenses: 5, ExpirationDate: expires},
+		{ServerHostname: "27000@lic1", Name: "mesher", Version: "2.0", TotalLicenses: 3, ExpirationDate: expires},
+		{ServerHostname: "27000@lic1", Name: "viewer", Version: "1.0", TotalLicenses: 50, ExpirationDate: parsers.PermanentExpirationDate},
+	})
+	if err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	h.ExportExpirationsICS(w, httptest.NewRequest(http.MethodGet, "/api/v1/export/expirations.ics?days=60&reminders=14,1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/calendar; charset=utf-8" {
+		t.Errorf("Expected an iCalendar content type, got %q", ct)
+	}
+
+	out := strings.ReplaceAll(w.Body.String(), "\r\n ", "") // Unfold long lines
+	// Both versions of mesher share an event; the permanent viewer has none
+	if n := strings.Count(out, "BEGIN:VEVENT"); n != 1 {
+		t.Fatalf("Expected 1 event, got %d:\n%s", n, out)
+	}
+	for _, want := range []string{
+		"DTSTART;VALUE=DATE:" + expires.Format("20060102"),
+		"SUMMARY:mesher license expires on 27000@lic1",
+		`Versions: 1.0\, 2.0\nSeats: 8`,
+		"TRIGGER:-P14D",
+		"TRIGGER:-P1D",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the feed to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	h.ExportExpirationsICS(w, httptest.NewRequest(http.MethodGet, "/api/v1/export/expirations.ics?reminders=soon", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid reminders, got %d", w.Code)
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/services"
+)
+
+// RequireFlag hides routes behind a feature flag, answering 404 while the
+// flag is disabled
+func RequireFlag(flags *services.FlagService, name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !flags.Enabled(name) {
+				http.Error(w, "Feature "+name+" is disabled", http.StatusNotFound)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ListFeatureFlags handles GET /api/v1/admin/flags - lists the feature flags
+// with their current and configured values
+func ListFeatureFlags(flags *services.FlagService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list := flags.List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"flags": list,
+			"total": len(list),
+		})
+	}
+}
+
+// SetFeatureFlag handles PUT /api/v1/admin/flags/{name} - toggles a feature
+// flag at runtime ({"enabled": true})
+func SetFeatureFlag(cfg *config.Config, flags *services.FlagService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor, ok := flagAdmin(w, r, cfg)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			Enabled *bool `json:"enabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+			http.Error(w, "Invalid request body, expected {\"enabled\": true|false}", http.StatusBadRequest)
+			return
+		}
+
+		flag, err := flags.Set(r.Context(), chi.URLParam(r, "name"), *req.Enabled, actor)
+		writeFlag(w, flag, err)
+	}
+}
+
+// ResetFeatureFlag handles DELETE /api/v1/admin/flags/{name} - removes the
+// runtime toggle of a feature flag, returning it to its configured value
+func ResetFeatureFlag(cfg *config.Config, flags *services.FlagService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor, ok := flagAdmin(w, r, cfg)
+		if !ok {
+			return
+		}
+
+		flag, err := flags.Reset(r.Context(), chi.URLParam(r, "name"), actor)
+		writeFlag(w, flag, err)
+	}
+}
+
+// flagAdmin checks that the request may toggle flags and returns the actor
+func flagAdmin(w http.ResponseWriter, r *http.Request, cfg *config.Config) (string, bool) {
+	if !cfg.Server.SettingsEnabled {
+		http.Error(w, "Settings page is disabled", http.StatusForbidden)
+		return "", false
+	}
+	info := middleware.GetAuthInfo(r)
+	if cfg.Auth.Enabled && info.Role != middleware.RoleAdmin {
+		http.Error(w, "Admin role required", http.StatusForbidden)
+		return "", false
+	}
+	if info.Username == "" {
+		return "anonymous", true
+	}
+	return info.Username, true
+}
+
+func writeFlag(w http.ResponseWriter, flag interface{}, err error) {
+	if errors.Is(err, services.ErrUnknownFlag) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(flag)
+}
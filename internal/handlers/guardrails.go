@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+	"licet/internal/services"
+)
+
+// reportRoutes are the routes, below the API version, that report on every
+// feature of a server, or of all servers, unless given a feature
+var reportRoutes = map[string]bool{
+	"/utilization/history":        true,
+	"/utilization/stats":          true,
+	"/utilization/heatmap":        true,
+	"/statistics/enhanced":        true,
+	"/statistics/trends":          true,
+	"/statistics/capacity":        true,
+	"/export/utilization/history": true,
+	"/export/stats":               true,
+	"/export/report":              true,
+}
+
+// routePattern returns the route pattern a request will be served by, such
+// as /api/v1/features/{feature}/usage, or its path when no route matches
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return r.URL.Path
+	}
+	tctx := chi.NewRouteContext()
+	if !rctx.Routes.Match(tctx, r.Method, r.URL.Path) {
+		return r.URL.Path
+	}
+	return tctx.RoutePattern()
+}
+
+// QueryGuardrails rejects queries of the routes below it that would cost more
+// than query_limits allows with 422 and guidance on narrowing them: periods
+// (days=) longer than the limit of the endpoint, reports spanning more
+// features than max_features, and limit= above max_rows unless override=true
+func QueryGuardrails(cfg config.QueryLimitsConfig, storage *services.StorageService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !cfg.Enabled || r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+			query := r.URL.Query()
+			pattern := routePattern(r)
+
+			maxDays := cfg.MaxDays
+			if days, ok := cfg.EndpointDays[strings.ToLower(pattern)]; ok {
+				maxDays = days
+			}
+			if days, err := strconv.Atoi(query.Get("days")); err == nil && maxDays > 0 && days > maxDays {
+				rejectQuery(w, r, "days", maxDays,
+					fmt.Sprintf("days=%d exceeds the limit of %d days for %s", days, maxDays, pattern),
+					fmt.Sprintf("Request at most %d days, or split the period into several requests", maxDays))
+				return
+			}
+
+			if limit, err := strconv.Atoi(query.Get("limit")); err == nil && cfg.MaxRows > 0 && limit > cfg.MaxRows && query.Get("override") != "true" {
+				rejectQuery(w, r, "limit", cfg.MaxRows,
+					fmt.Sprintf("limit=%d exceeds the limit of %d rows", limit, cfg.MaxRows),
+					"Page through the results with page=, or pass override=true to fetch them at once")
+				return
+			}
+
+			version := strings.TrimPrefix(pattern, "/api/")
+			if i := strings.Index(version, "/"); i >= 0 && cfg.MaxFeatures > 0 && storage != nil && query.Get("feature") == "" && reportRoutes[version[i:]] {
+				features, err := storage.CountFeatures(r.Context(), query.Get("server"))
+				if err != nil {
+					log.Errorf("Failed to count features for query guardrails: %v", err)
+				} else if features > cfg.MaxFeatures {
+					rejectQuery(w, r, "features", cfg.MaxFeatures,
+						fmt.Sprintf("The report would span %d features, more than the limit of %d", features, cfg.MaxFeatures),
+						"Narrow the report to one server with server=, or to one feature with feature=")
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rejectQuery answers a query exceeding a guardrail with 422, in the
+// envelope of the v2 API or as a plain JSON error for v1
+func rejectQuery(w http.ResponseWriter, r *http.Request, parameter string, limit int, message, guidance string) {
+	if strings.HasPrefix(r.URL.Path, "/api/v2/") {
+		respondError(w, r, http.StatusUnprocessableEntity, message+". "+guidance)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":     message,
+		"parameter": parameter,
+		"limit":     limit,
+		"guidance":  guidance,
+	})
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+func TestQueryGuardrails(t *testing.T) {
+	db := newTestDB(t)
+	storage := services.NewStorageService(db, "sqlite")
+	features := []models.Feature{
+		{ServerHostname: "27000@a", Name: "cad", TotalLicenses: 5},
+		{ServerHostname: "27000@a", Name: "cad", Version: "2.0", TotalLicenses: 5},
+		{ServerHostname: "27000@a", Name: "cam", TotalLicenses: 5},
+		{ServerHostname: "27000@b", Name: "cfd", TotalLicenses: 5},
+	}
+	if err := storage.StoreFeatures(context.Background(), features); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	cfg := config.QueryLimitsConfig{
+		Enabled:      true,
+		MaxDays:      365,
+		EndpointDays: map[string]int{"/api/v1/utilization/heatmap": 31},
+		MaxFeatures:  2,
+		MaxRows:      100,
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r := chi.NewRouter()
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(QueryGuardrails(cfg, storage))
+		r.Get("/features/{feature}/usage", ok)
+		r.Get("/utilization/heatmap", ok)
+		r.Get("/utilization/stats", ok)
+		r.Get("/checkouts", ok)
+	})
+
+	tests := []struct {
+		target    string
+		want      int
+		parameter string
+	}{
+		{"/api/v1/features/cad/usage?days=365", http.StatusOK, ""},
+		{"/api/v1/features/cad/usage?days=3650", http.StatusUnprocessableEntity, "days"},
+		{"/api/v1/utilization/heatmap?days=60&server=27000@b", http.StatusUnprocessableEntity, "days"},
+		{"/api/v1/utilization/stats?server=27000@a", http.StatusOK, ""},
+		{"/api/v1/utilization/stats", http.StatusUnprocessableEntity, "features"},
+		{"/api/v1/utilization/stats?feature=cad", http.StatusOK, ""},
+		{"/api/v1/checkouts?limit=1000", http.StatusUnprocessableEntity, "limit"},
+		{"/api/v1/checkouts?limit=1000&override=true", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", tt.target, nil))
+		if w.Code != tt.want {
+			t.Errorf("GET %s: expected %d, got %d: %s", tt.target, tt.want, w.Code, w.Body.String())
+			continue
+		}
+		if tt.parameter == "" {
+			continue
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if body["parameter"] != tt.parameter || body["guidance"] == "" {
+			t.Errorf("GET %s: expected guidance on %s, got %v", tt.target, tt.parameter, body)
+		}
+	}
+}
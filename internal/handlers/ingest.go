@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/parsers"
+	"licet/internal/services"
+	"licet/internal/util"
+)
+
+// ingestRecord is a single log record as sent by log shippers. Fluent-bit
+// puts the line in "log"; other shippers commonly use "message".
+type ingestRecord struct {
+	Server  string   `json:"server"`
+	Type    string   `json:"type"`
+	Log     string   `json:"log"`
+	Message string   `json:"message"`
+	Lines   []string `json:"lines"`
+}
+
+// ingestBatch groups log lines for one server so the stateful parsers
+// see them in order
+type ingestBatch struct {
+	server     string
+	serverType string
+	lines      []string
+}
+
+// IngestLogs handles POST /api/v1/ingest/logs - parses vendor daemon log lines
+// from log shippers and records the resulting license events.
+//
+// Accepted bodies:
+//   - text/plain with ?server=&type= query parameters, one log line per line
+//   - a JSON object {"server", "type", "lines": [...]}
+//   - a JSON array of records {"server", "type", "log"|"message"}
+//
+// Lines for servers missing from the configuration are rejected with 422.
+func IngestLogs(cfg *config.Config, events *services.EventService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !checkIngestToken(cfg.Ingest, w, r) {
+			return
+		}
+
+		batches, err := readIngestBatches(r)
+		if err != nil {
+			http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		received := 0
+		var parsed []models.LicenseEvent
+		for _, batch := range batches {
+			hostname, err := util.ValidateHostname(batch.server)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if !serverConfigured(cfg, hostname) {
+				http.Error(w, "Server not configured: "+hostname, http.StatusUnprocessableEntity)
+				return
+			}
+			serverType := strings.ToLower(strings.TrimSpace(batch.serverType))
+			parser, err := parsers.NewLogEventParser(serverType, now)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			for _, line := range batch.lines {
+				received++
+				if event, ok := parser.ParseLine(line); ok {
+					event.ServerHostname = hostname
+					parsed = append(parsed, event)
+				}
+			}
+		}
+
+		stored, err := events.RecordEvents(r.Context(), parsed)
+		if err != nil {
+			http.Error(w, "Failed to store events: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"lines":  received,
+			"events": len(parsed),
+			"stored": stored,
+		}).Debug("Ingested license log lines")
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"received": received,
+			"events":   len(parsed),
+			"stored":   stored,
+		})
+	}
+}
+
+// IngestReportLog handles POST /api/v1/ingest/reportlog?server= - imports the
+// text form of a FlexNet report log, one record per line. Every checkout,
+// check-in and denial is recorded as a license event, and checkouts with
+// their check-in and the hourly peaks replace the polled history of the
+// server over the span of the log. Logs of servers missing from the
+// configuration are rejected with 422.
+func IngestReportLog(cfg *config.Config, storage *services.StorageService, events *services.EventService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !checkIngestToken(cfg.Ingest, w, r) {
+			return
+		}
+
+		hostname, err := util.ValidateHostname(r.URL.Query().Get("server"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !serverConfigured(cfg, hostname) {
+			http.Error(w, "Server not configured: "+hostname, http.StatusUnprocessableEntity)
+			return
+		}
+
+		records, err := parsers.ParseReportLog(r.Body)
+		if err != nil {
+			http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		parsed := make([]models.LicenseEvent, 0, len(records))
+		for _, record := range records {
+			event := record.Event()
+			event.ServerHostname = hostname
+			parsed = append(parsed, event)
+		}
+		stored, err := events.RecordEvents(r.Context(), parsed)
+		if err != nil {
+			http.Error(w, "Failed to store events: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result, err := storage.ImportReportLog(r.Context(), hostname, records)
+		if err != nil {
+			http.Error(w, "Failed to import report log: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"server":   hostname,
+			"records":  result.Records,
+			"sessions": result.Sessions,
+			"samples":  result.Samples,
+		}).Info("Imported report log")
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"import": result,
+			"stored": stored,
+		})
+	}
+}
+
+// checkIngestToken checks the ingest token of a request and limits the size
+// of its body. It writes the error response and returns false when the token
+// does not match.
+func checkIngestToken(cfg config.IngestConfig, w http.ResponseWriter, r *http.Request) bool {
+	if cfg.Token != "" {
+		token := r.Header.Get("X-Ingest-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Token)) != 1 {
+			http.Error(w, "Invalid ingest token", http.StatusUnauthorized)
+			return false
+		}
+	}
+
+	if cfg.MaxBodyBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxBodyBytes)
+	}
+	return true
+}
+
+// readIngestBatches decodes the request body into per-server batches,
+// preserving line order within each server
+func readIngestBatches(r *http.Request) ([]*ingestBatch, error) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		batch := &ingestBatch{
+			server:     r.URL.Query().Get("server"),
+			serverType: r.URL.Query().Get("type"),
+		}
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			batch.lines = append(batch.lines, scanner.Text())
+		}
+		return []*ingestBatch{batch}, scanner.Err()
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	var records []ingestRecord
+	if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(raw, &records); err != nil {
+			return nil, err
+		}
+	} else {
+		var record ingestRecord
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return nil, err
+		}
+		records = []ingestRecord{record}
+	}
+
+	var batches []*ingestBatch
+	byKey := make(map[string]*ingestBatch)
+	for _, rec := range records {
+		// Query parameters supply defaults for shippers that cannot tag records
+		if rec.Server == "" {
+			rec.Server = r.URL.Query().Get("server")
+		}
+		if rec.Type == "" {
+			rec.Type = r.URL.Query().Get("type")
+		}
+
+		key := rec.Server + "|" + rec.Type
+		batch, ok := byKey[key]
+		if !ok {
+			batch = &ingestBatch{server: rec.Server, serverType: rec.Type}
+			byKey[key] = batch
+			batches = append(batches, batch)
+		}
+
+		batch.lines = append(batch.lines, rec.Lines...)
+		if rec.Log != "" {
+			batch.lines = append(batch.lines, rec.Log)
+		}
+		if rec.Message != "" {
+			batch.lines = append(batch.lines, rec.Message)
+		}
+	}
+
+	return batches, nil
+}
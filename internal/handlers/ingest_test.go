@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"licet/internal/config"
+	"licet/internal/database"
+	"licet/internal/services"
+)
+
+// newTestDB creates a migrated SQLite database in a temporary directory
+func newTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	db, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "licet_test.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.RunMigrations(db, "sqlite"); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	return db
+}
+
+// ingestConfig configures the ingest token and the servers logs are
+// accepted for
+func ingestConfig(token string, servers ...string) *config.Config {
+	cfg := &config.Config{Ingest: config.IngestConfig{Token: token}}
+	for _, hostname := range servers {
+		cfg.Servers = append(cfg.Servers, config.LicenseServer{Hostname: hostname})
+	}
+	return cfg
+}
+
+func TestIngestLogs_JSONRecords(t *testing.T) {
+	db := newTestDB(t)
+	handler := IngestLogs(ingestConfig("secret", "27000@lic1"), services.NewEventService(db, "sqlite"))
+
+	body := `[
+		{"server": "27000@lic1", "type": "flexlm", "log": "14:23:11 (adskflex) OUT: \"F1\" jdoe@ws01"},
+		{"server": "27000@lic1", "type": "flexlm", "log": "14:25:40 (adskflex) DENIED: \"F1\" asmith@ws02  (Licensed number of users already reached.)"},
+		{"server": "27000@lic1", "type": "flexlm", "log": "14:25:41 (adskflex) REStarted adskflex"}
+	]`
+
+	for i, wantStored := range []float64{2, 0} {
+		req := httptest.NewRequest("POST", "/api/v1/ingest/logs", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Ingest-Token", "secret")
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status 200, got %d: %s", i, w.Code, w.Body.String())
+		}
+
+		var response map[string]float64
+		json.NewDecoder(w.Body).Decode(&response)
+		if response["received"] != 3 || response["events"] != 2 || response["stored"] != wantStored {
+			t.Errorf("request %d: unexpected response %v", i, response)
+		}
+	}
+
+	var denials int
+	db.Get(&denials, "SELECT COUNT(*) FROM license_events WHERE event_type = 'DENIED' AND server_hostname = '27000@lic1'")
+	if denials != 1 {
+		t.Errorf("Expected 1 stored denial, got %d", denials)
+	}
+}
+
+func TestIngestLogs_PlainText(t *testing.T) {
+	db := newTestDB(t)
+	handler := IngestLogs(ingestConfig("", "5053@lic2"), services.NewEventService(db, "sqlite"))
+
+	body := "01/02 09:15 (foundry) OUT: nuke_i v2024.0 by jdoe@ws01\n"
+	req := httptest.NewRequest("POST", "/api/v1/ingest/logs?server=5053@lic2&type=rlm", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestIngestLogs_Rejects(t *testing.T) {
+	db := newTestDB(t)
+	handler := IngestLogs(ingestConfig("secret", "27000@lic1"), services.NewEventService(db, "sqlite"))
+
+	tests := []struct {
+		name   string
+		target string
+		token  string
+		want   int
+	}{
+		{"missing token", "/api/v1/ingest/logs?server=27000@lic1&type=flexlm", "", http.StatusUnauthorized},
+		{"bad token", "/api/v1/ingest/logs?server=27000@lic1&type=flexlm", "wrong", http.StatusUnauthorized},
+		{"missing server", "/api/v1/ingest/logs?type=flexlm", "secret", http.StatusBadRequest},
+		{"unsupported type", "/api/v1/ingest/logs?server=27000@lic1&type=spm", "secret", http.StatusBadRequest},
+		{"unconfigured server", "/api/v1/ingest/logs?server=27000@rogue&type=flexlm", "secret", http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", tt.target, strings.NewReader("line\n"))
+		req.Header.Set("X-Ingest-Token", tt.token)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, w.Code)
+		}
+	}
+}
+
+func TestIngestReportLog(t *testing.T) {
+	db := newTestDB(t)
+	handler := IngestReportLog(ingestConfig("secret", "27000@lic1"), services.NewStorageService(db, "sqlite"), services.NewEventService(db, "sqlite"))
+
+	body := `2024/06/01 09:10:00 OUT solver alice ws1 handle=1
+2024/06/01 09:12:00 IN solver alice ws1 handle=1
+2024/06/01 09:13:00 DENIED solver bob ws2 reason="Licensed number of users already reached."
+`
+	req := httptest.NewRequest("POST", "/api/v1/ingest/reportlog?server=27000@lic1", strings.NewReader(body))
+	req.Header.Set("X-Ingest-Token", "secret")
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Import map[string]interface{} `json:"import"`
+		Stored int                    `json:"stored"`
+	}
+	json.NewDecoder(w.Body).Decode(&response)
+	if response.Stored != 3 || response.Import["sessions"] != 1.0 || response.Import["samples"] != 1.0 {
+		t.Errorf("Unexpected response %+v", response)
+	}
+
+	for _, tt := range []struct {
+		target, token string
+		want          int
+	}{
+		{"/api/v1/ingest/reportlog?server=27000@lic1", "wrong", http.StatusUnauthorized},
+		{"/api/v1/ingest/reportlog", "secret", http.StatusBadRequest},
+		{"/api/v1/ingest/reportlog?server=27000@rogue", "secret", http.StatusUnprocessableEntity},
+	} {
+		req := httptest.NewRequest("POST", tt.target, strings.NewReader(body))
+		req.Header.Set("X-Ingest-Token", tt.token)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.target, tt.want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/models"
+	"licet/internal/scheduler"
+	"licet/internal/services"
+)
+
+// RefreshServer handles POST /api/v1/servers/{server}/refresh - polls a
+// server now and returns the job to follow at /api/v1/jobs/{id}
+func RefreshServer(sched *scheduler.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := sched.Refresh(r.Context(), serverParam(r))
+		if errors.Is(err, scheduler.ErrUnknownServer) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		acceptJob(w, job)
+	}
+}
+
+// acceptJob answers a request queued as a job with 202 and the job to follow
+func acceptJob(w http.ResponseWriter, job models.Job) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"job_id": job.ID,
+		"job":    job,
+	})
+}
+
+// ListJobs handles GET /api/v1/jobs?status=&type=&limit=50 - lists the
+// most recent jobs, newest first
+func ListJobs(queue *services.JobQueue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := 50
+		if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 500 {
+			limit = l
+		}
+		filter := services.JobFilter{Status: r.URL.Query().Get("status"), Type: r.URL.Query().Get("type")}
+
+		jobs, err := queue.List(r.Context(), filter, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jobs":  jobs,
+			"total": len(jobs),
+		})
+	}
+}
+
+// GetJob handles GET /api/v1/jobs/{id} - reports the status of a job
+func GetJob(queue *services.JobQueue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := queue.Get(r.Context(), chi.URLParam(r, "id"))
+		if errors.Is(err, services.ErrJobNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job)
+	}
+}
+
+// CancelJob handles DELETE /api/v1/jobs/{id} - cancels a queued or running
+// job. Admins may cancel any job, other users only their own.
+func CancelJob(cfg *config.Config, queue *services.JobQueue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		job, err := queue.Get(r.Context(), id)
+		if errors.Is(err, services.ErrJobNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		info := middleware.GetAuthInfo(r)
+		if cfg.Auth.Enabled && info.Role != middleware.RoleAdmin && jobRequester(job).Username != info.Username {
+			http.Error(w, "Only admins may cancel the jobs of others", http.StatusForbidden)
+			return
+		}
+
+		job, err = queue.Cancel(r.Context(), id)
+		if errors.Is(err, services.ErrJobFinished) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job)
+	}
+}
+
+// DownloadJobOutput handles GET /api/v1/jobs/{id}/download - downloads the
+// file produced by a finished export or archive job. Jobs requested by an
+// admin can only be downloaded by admins.
+func DownloadJobOutput(cfg *config.Config, queue *services.JobQueue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := queue.Get(r.Context(), chi.URLParam(r, "id"))
+		if errors.Is(err, services.ErrJobNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if cfg.Auth.Enabled && jobRequester(job).Role == middleware.RoleAdmin && middleware.GetAuthInfo(r).Role != middleware.RoleAdmin {
+			http.Error(w, "Admin role required", http.StatusForbidden)
+			return
+		}
+
+		var output models.JobOutput
+		if job.Status != models.JobSucceeded || json.Unmarshal(job.Result, &output) != nil || output.Filename == "" {
+			http.Error(w, "Job has no output to download (status "+job.Status+")", http.StatusConflict)
+			return
+		}
+		f, err := os.Open(queue.OutputPath(job.ID))
+		if err != nil {
+			http.Error(w, "Job output is no longer available", http.StatusGone)
+			return
+		}
+		defer f.Close()
+
+		w.Header().Set("Content-Type", output.ContentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", output.Filename))
+		http.ServeContent(w, r, output.Filename, *job.FinishedAt, f)
+	}
+}
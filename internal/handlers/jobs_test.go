@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+func TestAsyncExport(t *testing.T) {
+	queue := services.NewJobQueue(newTestDB(t), config.JobsConfig{OutputDir: t.TempDir()})
+	async := NewAsyncRunner(queue)
+	if err := queue.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer queue.Stop()
+
+	export := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="features.csv"`)
+		w.Write([]byte("server,feature\n" + r.URL.Query().Get("server") + ",cad\n"))
+	}
+	cfg := &config.Config{}
+	r := chi.NewRouter()
+	r.Get("/api/v1/export/features", async.Handle(services.JobExport, "export/features", export))
+	r.Get("/api/v1/jobs/{id}", GetJob(queue))
+	r.Get("/api/v1/jobs/{id}/download", DownloadJobOutput(cfg, queue))
+
+	// Without async the export is served as usual
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/export/features?server=27000@a", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "server,feature\n27000@a,cad\n" {
+		t.Fatalf("Expected the export, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/export/features?server=27000@a&async=true", nil))
+	var accepted struct {
+		JobID string `json:"job_id"`
+	}
+	json.NewDecoder(w.Body).Decode(&accepted)
+	if w.Code != http.StatusAccepted || accepted.JobID == "" || w.Header().Get("Location") != "/api/v1/jobs/"+accepted.JobID {
+		t.Fatalf("Expected 202 with a job, got %d", w.Code)
+	}
+
+	// The file is not there until the job finished
+	var job models.Job
+	deadline := time.Now().Add(5 * time.Second)
+	for job.FinishedAt == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		job, _ = queue.Get(context.Background(), accepted.JobID)
+	}
+	if job.Status != models.JobSucceeded {
+		t.Fatalf("Expected the export job to succeed, got %+v", job)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/jobs/"+accepted.JobID+"/download", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "server,feature\n27000@a,cad\n" {
+		t.Errorf("Expected the exported file, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "text/csv" || w.Header().Get("Content-Disposition") != `attachment; filename="features.csv"` {
+		t.Errorf("Expected the headers of the export, got %v", w.Header())
+	}
+}
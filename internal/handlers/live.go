@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/middleware"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// liveTopic is the event bus topic of the messages for live clients,
+// carrying liveMessages
+const liveTopic = "live"
+
+// liveBuffer is how many messages a live client may fall behind by before
+// missing any
+const liveBuffer = 256
+
+// liveMessage is a message for the live clients subscribed to its channel,
+// or for every client when the channel is empty
+type liveMessage struct {
+	channel string
+	msg     WebSocketMessage
+}
+
+// LiveFeed produces the live updates pushed to WebSocket and Server-Sent
+// Events clients from the results of the collector, so live clients add no
+// load on the license servers. Each update is published once on the event bus
+// and every transport delivers it to its own clients.
+type LiveFeed struct {
+	bus      *services.EventBus
+	redactor *services.Redactor
+	auth     *middleware.Authenticator // Nil when authentication is disabled
+	config   WebSocketConfig
+	streams  atomic.Int64
+
+	mu     sync.Mutex
+	latest map[string]services.CollectionEvent // Last collection of each server
+}
+
+// NewLiveFeed creates a live feed publishing on the event bus
+func NewLiveFeed(bus *services.EventBus, config WebSocketConfig, redactor *services.Redactor) *LiveFeed {
+	return &LiveFeed{
+		bus:      bus,
+		redactor: redactor,
+		config:   config,
+		latest:   make(map[string]services.CollectionEvent),
+	}
+}
+
+// SetAuthenticator makes live clients authenticate and limits the channels
+// they receive to those of their role
+func (f *LiveFeed) SetAuthenticator(auth *middleware.Authenticator) {
+	f.auth = auth
+}
+
+// viewer returns the role of the client making a live request, or an empty
+// role when authentication is disabled. It reports false for clients that
+// may not read.
+func (f *LiveFeed) viewer(r *http.Request) (string, bool) {
+	if f.auth == nil {
+		return "", true
+	}
+	if info := middleware.GetAuthInfo(r); info.Role != "" {
+		return info.Role, info.Can(middleware.PermissionRead)
+	}
+	// The auth middleware skips exempt paths such as /ws
+	info, ok := f.auth.AuthenticateRead(r)
+	if !ok {
+		return "", false
+	}
+	return info.Role, info.Can(middleware.PermissionRead)
+}
+
+// channelRole returns the least role receiving the messages of a channel.
+// Channels starting with admin carry administrative events.
+func channelRole(channel string) string {
+	if channel == "admin" || strings.HasPrefix(channel, "admin:") {
+		return middleware.RoleAdmin
+	}
+	return middleware.RoleReadonly
+}
+
+// mayReceive reports whether a client with role may receive the messages of
+// a channel. The empty role of disabled authentication receives everything.
+func (f *LiveFeed) mayReceive(role, channel string) bool {
+	if role == "" || f.auth == nil {
+		return true
+	}
+	if channelRole(channel) == middleware.RoleAdmin {
+		return f.auth.RoleHasPermission(role, middleware.PermissionAdmin)
+	}
+	return f.auth.RoleHasPermission(role, middleware.PermissionRead)
+}
+
+// Run publishes the status of each server as soon as it is collected, again
+// from the last collection every update interval for clients that connected
+// since, and forwards alerts until ctx is done
+func (f *LiveFeed) Run(ctx context.Context) {
+	collections, cancelCollections := f.bus.SubscribeBuffered(services.CollectionsTopic, liveBuffer)
+	defer cancelCollections()
+	alerts, cancelAlerts := f.bus.SubscribeBuffered(services.AlertTopic, liveBuffer)
+	defer cancelAlerts()
+	audit, cancelAudit := f.bus.SubscribeBuffered(services.AuditTopic, liveBuffer)
+	defer cancelAudit()
+
+	ticker := time.NewTicker(time.Duration(f.config.UpdateInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f.mu.Lock()
+			latest := make([]services.CollectionEvent, 0, len(f.latest))
+			for _, event := range f.latest {
+				latest = append(latest, event)
+			}
+			f.mu.Unlock()
+			for _, event := range latest {
+				f.publishServerStatus(event)
+			}
+		case event := <-collections:
+			f.collected(event.(services.CollectionEvent))
+		case alert := <-alerts:
+			f.PublishAlert(alert)
+		case entry := <-audit:
+			f.Publish("admin:audit", WebSocketMessage{
+				Type:      MsgTypeAudit,
+				Timestamp: time.Now(),
+				Data:      entry,
+			})
+		}
+	}
+}
+
+// collected publishes a collection of a server and the features whose use
+// changed since its previous collection
+func (f *LiveFeed) collected(event services.CollectionEvent) {
+	f.mu.Lock()
+	previous, seen := f.latest[event.Server]
+	f.latest[event.Server] = event
+	f.mu.Unlock()
+
+	f.publishServerStatus(event)
+	if !seen || event.Error != "" {
+		return
+	}
+
+	before := make(map[string]models.Feature, len(previous.Result.Features))
+	for _, feature := range previous.Result.Features {
+		before[feature.Name+"|"+feature.Version] = feature
+	}
+	for _, feature := range event.Result.Features {
+		old, ok := before[feature.Name+"|"+feature.Version]
+		if ok && old.UsedLicenses == feature.UsedLicenses && old.TotalLicenses == feature.TotalLicenses {
+			continue
+		}
+		f.Publish("server:"+event.Server, WebSocketMessage{
+			Type:      MsgTypeFeatureUpdate,
+			Timestamp: time.Now(),
+			Data: map[string]interface{}{
+				"server":        event.Server,
+				"feature":       feature.Name,
+				"version":       feature.Version,
+				"used":          feature.UsedLicenses,
+				"total":         feature.TotalLicenses,
+				"previous_used": old.UsedLicenses,
+				"collected_at":  event.CollectedAt,
+			},
+		})
+	}
+}
+
+// publishServerStatus publishes the status of a server as of a collection
+func (f *LiveFeed) publishServerStatus(event services.CollectionEvent) {
+	f.Publish("server:"+event.Server, WebSocketMessage{
+		Type:      MsgTypeServerStatus,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"server":       event.Server,
+			"type":         event.Type,
+			"status":       event.Result.Status,
+			"features":     event.Result.Features,
+			"users":        f.redactor.Users(event.Result.Users), // Subscribers share one message, so always redact
+			"collected_at": event.CollectedAt,
+		},
+	})
+}
+
+// Publish sends a message to the live clients subscribed to a channel, or to
+// every client when the channel is empty
+func (f *LiveFeed) Publish(channel string, msg WebSocketMessage) {
+	f.bus.Publish(liveTopic, liveMessage{channel: channel, msg: msg})
+}
+
+// PublishAlert sends an alert to the live clients subscribed to alerts
+func (f *LiveFeed) PublishAlert(alert interface{}) {
+	f.Publish("alerts", WebSocketMessage{
+		Type:      MsgTypeAlert,
+		Timestamp: time.Now(),
+		Data:      alert,
+	})
+}
+
+// subscribe returns the messages published for live clients from now on
+func (f *LiveFeed) subscribe() (<-chan interface{}, func()) {
+	return f.bus.SubscribeBuffered(liveTopic, liveBuffer)
+}
+
+// StreamCount returns the number of connected Server-Sent Events clients
+func (f *LiveFeed) StreamCount() int {
+	return int(f.streams.Load())
+}
+
+// subscribedTo reports whether a client subscribed to channels receives a
+// message for channel
+func subscribedTo(channels map[string]bool, channel string) bool {
+	return channel == "" || channels[channel] || channels["all"]
+}
+
+// Stream handles GET /api/v1/stream - pushes the live updates of the
+// WebSocket hub as Server-Sent Events, for clients behind proxies that block
+// WebSockets. Each event is named after the message type and carries the
+// same JSON message. ?channels= takes a comma separated list such as
+// server:27000@flex1,alerts (default all). Streams end before the request
+// timeout of the router and EventSource clients reconnect on their own.
+// Clients only receive the channels of their role; admin channels such as
+// admin:audit need the admin role.
+func Stream(feed *LiveFeed) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		role, ok := feed.viewer(r)
+		if !ok {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		if feed.streams.Add(1) > int64(feed.config.MaxConnections) {
+			feed.streams.Add(-1)
+			http.Error(w, "Too many streams", http.StatusServiceUnavailable)
+			return
+		}
+		defer feed.streams.Add(-1)
+
+		channels := make(map[string]bool)
+		for _, channel := range strings.Split(r.URL.Query().Get("channels"), ",") {
+			if channel = strings.TrimSpace(channel); channel != "" {
+				channels[channel] = true
+			}
+		}
+		if len(channels) == 0 {
+			channels["all"] = true
+		}
+		for channel := range channels {
+			if !feed.mayReceive(role, channel) {
+				http.Error(w, "Channel "+channel+" requires the "+channelRole(channel)+" role", http.StatusForbidden)
+				return
+			}
+		}
+
+		rc := http.NewResponseController(w)
+		// The server's write timeout is shorter than a stream
+		if err := rc.SetWriteDeadline(time.Now().Add(maxWaitTimeout + 5*time.Second)); err != nil {
+			log.Debugf("Failed to extend write deadline for stream: %v", err)
+		}
+
+		messages, cancel := feed.subscribe()
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+
+		send := func(msg WebSocketMessage) error {
+			data, err := json.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Type, data); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+
+		subscribed := make([]string, 0, len(channels))
+		for channel := range channels {
+			subscribed = append(subscribed, channel)
+		}
+		fmt.Fprint(w, "retry: 1000\n\n")
+		if err := send(WebSocketMessage{
+			Type:      "connected",
+			Timestamp: time.Now(),
+			Data: map[string]interface{}{
+				"message":       "Connected to Licet stream",
+				"subscriptions": subscribed,
+			},
+		}); err != nil {
+			log.WithError(err).Debug("Failed to start event stream")
+			return
+		}
+
+		ping := time.NewTicker(time.Duration(feed.config.PingInterval) * time.Second)
+		defer ping.Stop()
+		timer := time.NewTimer(maxWaitTimeout)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-timer.C:
+				return
+			case <-ping.C:
+				// Comments keep proxies from closing an idle stream
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil || rc.Flush() != nil {
+					return
+				}
+			case event := <-messages:
+				m := event.(liveMessage)
+				if !subscribedTo(channels, m.channel) || !feed.mayReceive(role, m.channel) {
+					continue
+				}
+				if err := send(m.msg); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
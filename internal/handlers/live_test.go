@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// readEvent reads the next named event of a Server-Sent Events stream
+func readEvent(t *testing.T, r *bufio.Reader) (string, string) {
+	t.Helper()
+	var event, data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && event != "":
+			return event, data
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	feed := NewLiveFeed(services.NewEventBus(), WebSocketConfig{MaxConnections: 1, PingInterval: 30}, nil)
+	server := httptest.NewServer(Stream(feed))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?channels=alerts")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %q", ct)
+	}
+	stream := bufio.NewReader(resp.Body)
+
+	if event, _ := readEvent(t, stream); event != "connected" {
+		t.Fatalf("Expected the connected event first, got %s", event)
+	}
+
+	// Only one stream fits
+	second, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	second.Body.Close()
+	if second.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 beyond max_connections, got %d", second.StatusCode)
+	}
+
+	feed.Publish("server:27000@a", WebSocketMessage{Type: MsgTypeServerStatus})
+	feed.PublishAlert(map[string]string{"alert_type": "down"})
+
+	event, data := readEvent(t, stream)
+	if event != MsgTypeAlert || !strings.Contains(data, `"alert_type":"down"`) {
+		t.Errorf("Expected only the alert on the alerts channel, got %s: %s", event, data)
+	}
+	if feed.StreamCount() != 1 {
+		t.Errorf("Expected 1 stream client, got %d", feed.StreamCount())
+	}
+}
+
+func TestLiveFeedCollections(t *testing.T) {
+	feed := NewLiveFeed(services.NewEventBus(), WebSocketConfig{UpdateInterval: 10}, nil)
+	messages, cancel := feed.subscribe()
+	defer cancel()
+
+	next := func() WebSocketMessage {
+		t.Helper()
+		select {
+		case m := <-messages:
+			return m.(liveMessage).msg
+		case <-time.After(time.Second):
+			t.Fatal("Expected a live message")
+			return WebSocketMessage{}
+		}
+	}
+
+	collection := services.CollectionEvent{
+		Server: "27000@a",
+		Type:   "flexlm",
+		Result: models.ServerQueryResult{
+			Status:   models.ServerStatus{Hostname: "27000@a", Service: "up"},
+			Features: []models.Feature{{Name: "cad", Version: "1.0", TotalLicenses: 10, UsedLicenses: 2}},
+		},
+	}
+	feed.collected(collection)
+	if msg := next(); msg.Type != MsgTypeServerStatus {
+		t.Fatalf("Expected server_status for the first collection, got %s", msg.Type)
+	}
+
+	collection.Result.Features = []models.Feature{{Name: "cad", Version: "1.0", TotalLicenses: 10, UsedLicenses: 5}}
+	feed.collected(collection)
+	if msg := next(); msg.Type != MsgTypeServerStatus {
+		t.Fatalf("Expected server_status, got %s", msg.Type)
+	}
+	msg := next()
+	data, _ := msg.Data.(map[string]interface{})
+	if msg.Type != MsgTypeFeatureUpdate || data["used"] != 5 || data["previous_used"] != 2 {
+		t.Errorf("Expected a feature_update from 2 to 5 used, got %s: %v", msg.Type, msg.Data)
+	}
+
+	feed.collected(collection)
+	next()
+	select {
+	case m := <-messages:
+		t.Errorf("Expected no feature_update for unchanged use, got %v", m.(liveMessage).msg.Type)
+	default:
+	}
+}
+
+func TestLiveAuthorization(t *testing.T) {
+	auth := middleware.NewAuthenticator(config.AuthConfig{
+		Enabled: true,
+		APIKeys: []config.APIKeyConfig{
+			{Name: "ops", Key: "admin-key", Role: middleware.RoleAdmin, Enabled: true},
+			{Name: "wall", Key: "read-key", Role: middleware.RoleReadonly, Enabled: true},
+		},
+	})
+	t.Cleanup(auth.Stop)
+
+	feed := NewLiveFeed(services.NewEventBus(), WebSocketConfig{MaxConnections: 10, PingInterval: 30}, nil)
+	feed.SetAuthenticator(auth)
+	hub := NewWebSocketHub(WebSocketConfig{MaxConnections: 10, PingInterval: 30, AllowedOrigins: []string{"https://wall.example.com"}}, feed)
+	go hub.Run()
+	t.Cleanup(hub.Stop)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", hub.HandleWebSocket)
+	mux.HandleFunc("/stream", Stream(feed))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	dial := func(query, origin string) (*websocket.Conn, int) {
+		t.Helper()
+		header := http.Header{}
+		if origin != "" {
+			header.Set("Origin", origin)
+		}
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL+query, header)
+		if err != nil {
+			if resp == nil {
+				t.Fatalf("Failed to dial: %v", err)
+			}
+			return nil, resp.StatusCode
+		}
+		return conn, http.StatusSwitchingProtocols
+	}
+
+	if _, status := dial("", ""); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without credentials, got %d", status)
+	}
+	if _, status := dial("?api_key=read-key", "https://evil.example.com"); status != http.StatusForbidden {
+		t.Errorf("Expected 403 from a foreign origin, got %d", status)
+	}
+
+	conn, status := dial("?api_key=read-key", "https://wall.example.com")
+	if conn == nil {
+		t.Fatalf("Expected a readonly client from an allowed origin to connect, got %d", status)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := conn.ReadMessage(); err != nil {
+		t.Fatalf("Expected the welcome message: %v", err)
+	}
+
+	feed.Publish("admin:audit", WebSocketMessage{Type: MsgTypeAudit})
+	feed.PublishAlert(map[string]string{"alert_type": "down"})
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Expected the alert: %v", err)
+	}
+	if strings.Contains(string(data), `"type":"audit"`) || !strings.Contains(string(data), `"type":"alert"`) {
+		t.Errorf("Expected a readonly client to get the alert but not the audit entry, got %s", data)
+	}
+
+	resp, err := http.Get(srv.URL + "/stream?channels=admin:audit&api_key=read-key")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for an admin channel with a readonly key, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"licet/internal/middleware"
+	"licet/internal/services"
+)
+
+// metricSample is one labelled value of a metric
+type metricSample struct {
+	labels   []string // Alternating label names and values
+	value    float64
+	exemplar *metricExemplar
+}
+
+// metricExemplar links a sample to the trace of the collection it came from.
+// OpenMetrics allows exemplars on counters and histogram buckets only.
+type metricExemplar struct {
+	traceID string
+	value   float64
+	at      time.Time
+}
+
+// openMetricsType is the content type of the OpenMetrics text format, which
+// Prometheus asks for when scraping and which carries exemplars
+const openMetricsType = "application/openmetrics-text"
+
+// metricLabelEscaper escapes label values for the Prometheus text format
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricWriter writes metric families in the Prometheus text exposition
+// format, or in the OpenMetrics format with the exemplars of the samples
+type metricWriter struct {
+	w           io.Writer
+	openMetrics bool
+}
+
+// write writes a metric family
+func (m metricWriter) write(name, metricType, help string, samples ...metricSample) {
+	if len(samples) == 0 {
+		return
+	}
+	w := m.w
+	family := name
+	if m.openMetrics && metricType == "counter" {
+		family = strings.TrimSuffix(name, "_total") // OpenMetrics names counters without the suffix of their samples
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", family, help, family, metricType)
+	for _, s := range samples {
+		fmt.Fprint(w, name)
+		if len(s.labels) > 0 {
+			pairs := make([]string, 0, len(s.labels)/2)
+			for i := 0; i+1 < len(s.labels); i += 2 {
+				pairs = append(pairs, fmt.Sprintf(`%s="%s"`, s.labels[i], metricLabelEscaper.Replace(s.labels[i+1])))
+			}
+			fmt.Fprintf(w, "{%s}", strings.Join(pairs, ","))
+		}
+		fmt.Fprintf(w, " %g", s.value)
+		if e := s.exemplar; m.openMetrics && e != nil && e.traceID != "" {
+			fmt.Fprintf(w, ` # {trace_id="%s"} %g %.3f`, e.traceID, e.value, float64(e.at.UnixMilli())/1000)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// statValue returns a numeric statistic from a Stats() map
+func statValue(stats map[string]interface{}, key string) float64 {
+	switch v := stats[key].(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+// Metrics serves license and collection metrics for Prometheus. The cache and
+// rate limiter are optional. Scrapes asking for OpenMetrics get exemplars
+// linking the poll counters of each server to the trace of its last
+// collection, when tracing is enabled.
+func Metrics(storage *services.StorageService, collector *services.CollectorService, cache *middleware.Cache, limiter *middleware.RateLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		features, err := storage.GetActiveFeatures(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		polls := collector.PollResults()
+
+		var total, used []metricSample
+		for _, f := range features {
+			labels := []string{"server", f.ServerHostname, "feature", f.Name, "version", f.Version, "vendor", f.VendorDaemon}
+			total = append(total, metricSample{labels: labels, value: float64(f.TotalLicenses)})
+			used = append(used, metricSample{labels: labels, value: float64(f.UsedLicenses)})
+		}
+
+		hostnames := make([]string, 0, len(polls))
+		for hostname := range polls {
+			hostnames = append(hostnames, hostname)
+		}
+		sort.Strings(hostnames)
+
+		var up, duration, last, count, seconds []metricSample
+		for _, hostname := range hostnames {
+			p := polls[hostname]
+			labels := []string{"server", hostname, "type", p.Type}
+			value := 0.0
+			if p.Up {
+				value = 1
+			}
+			up = append(up, metricSample{labels: labels, value: value})
+			duration = append(duration, metricSample{labels: labels, value: p.Duration.Seconds()})
+			last = append(last, metricSample{labels: labels, value: float64(p.At.Unix())})
+			count = append(count, metricSample{labels: labels, value: float64(p.Polls),
+				exemplar: &metricExemplar{traceID: p.TraceID, value: 1, at: p.At}})
+			seconds = append(seconds, metricSample{labels: labels, value: p.TotalDuration.Seconds(),
+				exemplar: &metricExemplar{traceID: p.TraceID, value: p.Duration.Seconds(), at: p.At}})
+		}
+
+		m := metricWriter{w: w, openMetrics: strings.Contains(r.Header.Get("Accept"), openMetricsType)}
+		if m.openMetrics {
+			w.Header().Set("Content-Type", openMetricsType+"; version=1.0.0; charset=utf-8")
+			defer fmt.Fprint(w, "# EOF\n")
+		} else {
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		}
+		m.write("licet_feature_total_licenses", "gauge", "Licenses issued for a feature.", total...)
+		m.write("licet_feature_used_licenses", "gauge", "Licenses of a feature in use.", used...)
+		m.write("licet_server_up", "gauge", "Whether the last poll of a license server found it up.", up...)
+		m.write("licet_poll_duration_seconds", "gauge", "Duration of the last poll of a license server.", duration...)
+		m.write("licet_last_poll_timestamp_seconds", "gauge", "Unix time of the last poll of a license server.", last...)
+		m.write("licet_polls_total", "counter", "Polls of a license server.", count...)
+		m.write("licet_poll_seconds_total", "counter", "Time spent polling a license server.", seconds...)
+
+		if cache != nil {
+			stats := cache.Stats()
+			labels := []string{"backend", fmt.Sprint(stats["backend"])}
+			m.write("licet_cache_entries", "gauge", "Responses held in the API cache.", metricSample{labels: labels, value: statValue(stats, "entries")})
+			m.write("licet_cache_hits_total", "counter", "API cache lookups served from the cache.", metricSample{labels: labels, value: statValue(stats, "hits")})
+			m.write("licet_cache_misses_total", "counter", "API cache lookups that missed.", metricSample{labels: labels, value: statValue(stats, "misses")})
+		}
+		if limiter != nil {
+			stats := limiter.Stats()
+			m.write("licet_rate_limit_tracked_clients", "gauge", "Client IPs tracked by the rate limiter.", metricSample{value: statValue(stats, "tracked_ips")})
+			m.write("licet_rate_limit_rejected_total", "counter", "Requests rejected by the rate limiter.", metricSample{value: statValue(stats, "rejected")})
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+func TestMetrics(t *testing.T) {
+	db := newTestDB(t)
+	storage := services.NewStorageService(db, "sqlite")
+	collector := services.NewCollectorService(db, &config.Config{}, nil, storage)
+
+	err := storage.StoreFeatures(context.Background(), []models.Feature{
+		{ServerHostname: "27000@lic1", Name: "solver", Version: "2.0", VendorDaemon: "vend\"or", TotalLicenses: 10, UsedLicenses: 4},
+	})
+	if err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	cache := middleware.NewCache(middleware.CacheConfig{DefaultTTL: time.Minute, MaxEntries: 10, Enabled: true})
+	defer cache.Stop()
+	cache.Get("missing")
+
+	rec := httptest.NewRecorder()
+	Metrics(storage, collector, cache, nil)(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Expected text exposition format, got %q", ct)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE licet_feature_total_licenses gauge",
+		`licet_feature_total_licenses{server="27000@lic1",feature="solver",version="2.0",vendor="vend\"or"} 10`,
+		`licet_feature_used_licenses{server="27000@lic1",feature="solver",version="2.0",vendor="vend\"or"} 4`,
+		"licet_cache_misses_total{backend=\"memory\"} 1",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "licet_rate_limit") {
+		t.Error("Expected no rate limit metrics without a rate limiter")
+	}
+	if strings.Contains(body, "licet_server_up") {
+		t.Error("Expected no server metrics before the first poll")
+	}
+}
+
+func TestMetrics_OpenMetricsExemplars(t *testing.T) {
+	db := newTestDB(t)
+	storage := services.NewStorageService(db, "sqlite")
+	cfg := &config.Config{}
+	collector := services.NewCollectorService(db, cfg, services.NewQueryService(cfg, storage), storage)
+	collector.SetTracer(services.NewTracer(config.TracingConfig{}))
+
+	err := storage.StoreFeatures(context.Background(), []models.Feature{
+		{ServerHostname: "27000@lic1", Name: "solver", Version: "2.0", VendorDaemon: "vendor", TotalLicenses: 10, UsedLicenses: 4},
+	})
+	if err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	// The unsupported type fails the poll at once, which is still traced
+	collector.CollectServer(models.LicenseServer{Hostname: "27000@lic1", Type: "unsupported"})
+	traceID := collector.PollResults()["27000@lic1"].TraceID
+	if len(traceID) != 32 {
+		t.Fatalf("Expected the poll to have a trace ID, got %q", traceID)
+	}
+
+	cache := middleware.NewCache(middleware.CacheConfig{DefaultTTL: time.Minute, MaxEntries: 10, Enabled: true})
+	defer cache.Stop()
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5")
+	rec := httptest.NewRecorder()
+	Metrics(storage, collector, cache, nil)(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/openmetrics-text") {
+		t.Errorf("Expected the OpenMetrics format, got %q", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE licet_polls counter\n" + `licet_polls_total{server="27000@lic1",type="unsupported"} 1 # {trace_id="` + traceID + `"} 1 `,
+		"# TYPE licet_poll_seconds counter\n" + `licet_poll_seconds_total{server="27000@lic1",type="unsupported"} `,
+		`licet_poll_duration_seconds{server="27000@lic1",type="unsupported"} `,
+		"# TYPE licet_cache_hits counter\nlicet_cache_hits_total{backend=\"memory\"} 0\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+	if strings.Count(body, `# {trace_id="`+traceID+`"}`) != 2 {
+		t.Errorf("Expected exemplars on the poll counters only, got:\n%s", body)
+	}
+	if !strings.HasSuffix(body, "# EOF\n") {
+		t.Error("Expected the OpenMetrics exposition to end with # EOF")
+	}
+
+	// The Prometheus text format has no exemplars
+	rec = httptest.NewRecorder()
+	Metrics(storage, collector, cache, nil)(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if strings.Contains(rec.Body.String(), "trace_id") || strings.Contains(rec.Body.String(), "# EOF") {
+		t.Errorf("Expected no exemplars in the text format, got:\n%s", rec.Body.String())
+	}
+}
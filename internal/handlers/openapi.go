@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/middleware"
+)
+
+// APIParam is a query parameter of an API operation
+type APIParam struct {
+	Name        string
+	Description string
+	Type        string // string, integer, number, boolean; empty means string
+	Required    bool
+}
+
+// APIOperation documents an API route for the OpenAPI document. Path
+// parameters are taken from the route pattern.
+type APIOperation struct {
+	Summary    string
+	Tag        string
+	Params     []APIParam
+	Body       string // Description of the JSON request body, if any
+	Permission string // Permission required instead of read or write by the method, e.g. alerts:manage
+}
+
+// Common query parameters
+var (
+	paramServer     = APIParam{Name: "server", Description: "License server hostname, e.g. 27000@flex1"}
+	paramFeature    = APIParam{Name: "feature", Description: "Feature name"}
+	paramDays       = APIParam{Name: "days", Description: "Days of history", Type: "integer"}
+	paramAsync      = APIParam{Name: "async", Description: "true to run as a job and download the file from /jobs/{id}/download", Type: "boolean"}
+	paramLimit      = APIParam{Name: "limit", Description: "Maximum number of results", Type: "integer"}
+	paramPage       = APIParam{Name: "page", Description: "Page number, starting at 1", Type: "integer"}
+	paramFormat     = APIParam{Name: "format", Description: "csv, json, xlsx or, for the report, pdf, as allowed by export.allowed_formats"}
+	paramServerType = APIParam{Name: "type", Description: "Server type for unconfigured servers (flexlm, rlm, ...)"}
+	paramModel      = APIParam{Name: "model", Description: "License model filter (floating, node-locked, uncounted)"}
+	paramExplain    = APIParam{Name: "explain", Description: "Include the inputs, formulas and thresholds behind the recommendations", Type: "boolean"}
+	paramTag        = APIParam{Name: "tag", Description: "Only the features carrying this tag, e.g. CAD"}
+	paramGroup      = APIParam{Name: "group", Description: "Only the servers of this group, e.g. emea"}
+
+	paramsChargeback = []APIParam{
+		{Name: "from", Description: "First month, e.g. 2026-01 (default the previous month)"},
+		{Name: "to", Description: "Last month (default the previous month, or from)"},
+		{Name: "cost_center", Description: "Only this cost center"},
+	}
+)
+
+// apiOperations documents every /api/v1 route, keyed by method and path
+// below the version prefix. /api/v2 routes share the entries of their v1
+// counterparts. A test fails when a route is registered without an entry
+// here, or an entry is left behind for a removed route.
+var apiOperations = map[string]APIOperation{
+	// Servers
+	"GET /servers":                          {Summary: "List configured servers", Tag: "Servers", Params: []APIParam{paramGroup, paramPage, paramLimit}},
+	"POST /servers":                         {Summary: "Add a server", Tag: "Settings", Body: "Server (hostname, description, type, poll_interval, schedule, jitter, timeout)", Permission: middleware.PermissionServersWrite},
+	"DELETE /servers":                       {Summary: "Remove a server", Tag: "Settings", Params: []APIParam{{Name: "hostname", Description: "Server to remove", Required: true}}, Permission: middleware.PermissionServersWrite},
+	"POST /servers/test":                    {Summary: "Test the connection to a server", Tag: "Settings", Body: "Server (hostname, type)", Permission: middleware.PermissionServersWrite},
+	"GET /servers/compare":                  {Summary: "Compare the features of two servers", Tag: "Servers", Params: []APIParam{{Name: "a", Description: "First server", Required: true}, {Name: "b", Description: "Second server", Required: true}, {Name: "live", Description: "Query both servers now", Type: "boolean"}, {Name: "type_a", Description: "Type of the first server when unconfigured"}, {Name: "type_b", Description: "Type of the second server when unconfigured"}}},
+	"GET /resources/{id}":                   {Summary: "Look up a server or feature by UUID or stable id", Tag: "Servers"},
+	"GET /servers/{server}/status":          {Summary: "Get the status of a server", Tag: "Servers", Params: []APIParam{paramServerType}},
+	"GET /servers/{server}/features":        {Summary: "List the features of a server", Tag: "Servers", Params: []APIParam{paramPage, paramLimit}},
+	"GET /servers/{server}/users":           {Summary: "List the current users of a server", Tag: "Servers", Params: []APIParam{paramServerType}},
+	"GET /servers/{server}/wait-for-update": {Summary: "Wait for the next collection of a server", Tag: "Servers", Params: []APIParam{{Name: "timeout", Description: "How long to wait, e.g. 60s (default 30s, at most 55s); 204 when no collection finished"}}},
+	"POST /servers/{server}/refresh":        {Summary: "Poll a server now; returns a job to follow at /jobs/{id}", Tag: "Servers", Permission: middleware.PermissionServersWrite},
+	"GET /jobs":                             {Summary: "Recent jobs of the job queue, newest first", Tag: "Servers", Params: []APIParam{{Name: "status", Description: "queued, running, succeeded, failed or canceled"}, {Name: "type", Description: "Job type, e.g. refresh, backfill or export"}, {Name: "limit", Description: "Jobs listed (default 50, at most 500)", Type: "integer"}}},
+	"GET /jobs/{id}":                        {Summary: "Status of a job, e.g. a server refresh, with its result", Tag: "Servers"},
+	"DELETE /jobs/{id}":                     {Summary: "Cancel a queued or running job", Tag: "Servers"},
+	"GET /jobs/{id}/download":               {Summary: "Download the file of a finished export or archive job", Tag: "Servers", Permission: middleware.PermissionExportsRun},
+	"GET /collection/polls":                 {Summary: "Outcome, duration and timeouts of the last poll of each server", Tag: "Servers"},
+	"GET /servers/{server}/failovers":       {Summary: "MASTER failover history of a server", Tag: "Servers", Params: []APIParam{paramDays}},
+	"GET /failovers":                        {Summary: "MASTER failover history of all servers", Tag: "Servers", Params: []APIParam{paramServer, paramDays}},
+	"GET /utilities/check":                  {Summary: "Check which license utilities are installed", Tag: "Settings"},
+	"POST /settings/email":                  {Summary: "Update email settings", Tag: "Settings", Body: "Email settings", Permission: middleware.PermissionSettingsWrite},
+	"POST /settings/alerts":                 {Summary: "Update alert settings", Tag: "Settings", Body: "Alert settings", Permission: middleware.PermissionAlertsManage},
+	"GET /features/parse-quality":           {Summary: "List features whose data fell back to defaults while parsing", Tag: "Features", Params: []APIParam{paramServer}},
+	"GET /features/{feature}/usage":         {Summary: "Usage history of a feature", Tag: "Features", Params: []APIParam{paramServer, paramDays}},
+	"GET /features/{feature}/top-users":     {Summary: "Users with the most checkout hours of a feature", Tag: "Users", Params: []APIParam{paramServer, paramDays, paramLimit}},
+
+	// Utilization and statistics
+	"GET /utilization/current":               {Summary: "Current utilization of all features", Tag: "Utilization", Params: []APIParam{paramServer, paramTag, paramGroup, paramModel, paramPage, paramLimit}},
+	"GET /utilization/history":               {Summary: "Time series of feature usage", Tag: "Utilization", Params: []APIParam{paramServer, paramFeature, {Name: "tag", Description: "Combined usage of the features carrying this tag"}, {Name: "group", Description: "Combined usage of the features on the servers of this group"}, {Name: "period", Description: "24h, 7d, 30d or 1y"}}},
+	"GET /utilization/stats":                 {Summary: "Aggregated utilization statistics", Tag: "Utilization", Params: []APIParam{paramServer, paramTag, paramGroup, paramDays}},
+	"GET /utilization/heatmap":               {Summary: "Usage by hour of day and weekday", Tag: "Utilization", Params: []APIParam{paramServer, paramTag, paramGroup, paramDays}},
+	"GET /utilization/seasonality":           {Summary: "Daily and weekly usage cycles with labeled peak periods", Tag: "Utilization", Params: []APIParam{paramServer, paramFeature, paramTag, paramGroup, paramDays}},
+	"GET /utilization/token-pools":           {Summary: "Token usage of FlexLM packages and their components", Tag: "Utilization", Params: []APIParam{paramServer}},
+	"GET /utilization/products":              {Summary: "Usage of the MathWorks products of MLM servers by product family", Tag: "Utilization", Params: []APIParam{paramServer}},
+	"GET /utilization/predictions":           {Summary: "Predictive analytics and anomalies", Tag: "Utilization", Params: []APIParam{paramServer, paramFeature, paramDays, {Name: "model", Description: "Forecast model: linear (default) or holtwinters"}}},
+	"GET /utilization/anomalies/expected":    {Summary: "List anomalies marked as expected", Tag: "Utilization", Params: []APIParam{paramServer, paramFeature}},
+	"POST /utilization/anomalies/expected":   {Summary: "Mark an anomaly as expected", Tag: "Utilization", Body: "Expected anomaly (server_hostname, feature_name, date, note)"},
+	"DELETE /utilization/anomalies/expected": {Summary: "Remove an expected anomaly mark", Tag: "Utilization", Params: []APIParam{paramServer, paramFeature, {Name: "date", Description: "Day of the anomaly (YYYY-MM-DD)", Required: true}}},
+	"GET /statistics/enhanced":               {Summary: "Enhanced usage statistics", Tag: "Statistics", Params: []APIParam{paramServer, paramFeature, paramDays, paramExplain}},
+	"GET /statistics/trends":                 {Summary: "Usage trend analysis", Tag: "Statistics", Params: []APIParam{paramServer, paramFeature, paramDays, paramExplain}},
+	"GET /statistics/capacity":               {Summary: "Capacity planning report", Tag: "Statistics", Params: []APIParam{paramDays, paramTag, paramGroup, {Name: "refresh", Description: "Regenerate the stored report", Type: "boolean"}, paramExplain}},
+	"GET /reports":                           {Summary: "List the scheduled reports", Tag: "Statistics"},
+	"GET /reports/chargeback":                {Summary: "Checkout hours per cost center, month, server and feature", Tag: "Statistics", Params: paramsChargeback},
+	"GET /reports/{name}":                    {Summary: "Render a scheduled report (HTML or PDF) from current data", Tag: "Statistics"},
+	"POST /reports/{name}/send":              {Summary: "Email a scheduled report now", Tag: "Statistics"},
+	"GET /entitlements":                      {Summary: "List the entitlements synced from vendor portals", Tag: "Statistics"},
+	"POST /entitlements/sync":                {Summary: "Sync entitlements from vendor portals now", Tag: "Statistics"},
+	"GET /entitlements/reconciliation":       {Summary: "Compare entitlements with what the license servers report", Tag: "Statistics"},
+	"GET /computed-metrics":                  {Summary: "List the computed metrics, recorded as features of the computed server", Tag: "Utilization"},
+	"POST /computed-metrics":                 {Summary: "Add a computed metric", Tag: "Utilization", Body: "Computed metric (name, expression, capacity, description)"},
+	"POST /computed-metrics/preview":         {Summary: "Evaluate a computed metric over the current features", Tag: "Utilization", Body: "Computed metric (expression, capacity)"},
+	"GET /computed-metrics/{name}":           {Summary: "Get a computed metric", Tag: "Utilization"},
+	"PUT /computed-metrics/{name}":           {Summary: "Replace the expressions of a computed metric", Tag: "Utilization", Body: "Computed metric (expression, capacity, description)"},
+	"DELETE /computed-metrics/{name}":        {Summary: "Remove a computed metric", Tag: "Utilization"},
+
+	// Users and checkouts
+	"GET /users/digest":           {Summary: "Users who started or stopped using features", Tag: "Users", Params: []APIParam{paramDays}},
+	"GET /users/{username}/usage": {Summary: "A user's checkouts per server and feature", Tag: "Users", Params: []APIParam{paramDays}},
+	"GET /denials":                {Summary: "License denials with counts per classified reason", Tag: "Users", Params: []APIParam{paramServer, paramFeature, {Name: "days", Description: "Days of history (default 7)", Type: "integer"}}},
+	"GET /checkouts/long":         {Summary: "Open checkouts held for at least a number of hours", Tag: "Users", Params: []APIParam{paramServer, {Name: "hours", Description: "Minimum hours held", Type: "number"}}},
+	"GET /waitlist":               {Summary: "Waitlist subscriptions of the requesting user", Tag: "Users", Params: []APIParam{{Name: "all", Description: "Subscriptions of every user (admins)", Type: "boolean"}}},
+	"POST /waitlist":              {Summary: "Be emailed once when a seat of a feature frees up", Tag: "Users", Body: "Subscription (feature_name, server_hostname, email)", Permission: middleware.PermissionRead},
+	"DELETE /waitlist/{id}":       {Summary: "Leave a waitlist", Tag: "Users", Permission: middleware.PermissionRead},
+
+	// Alerts
+	"GET /alerts":                  {Summary: "List alerts", Tag: "Alerts", Params: []APIParam{paramPage, paramLimit}},
+	"GET /alerts/{id}/deliveries":  {Summary: "Delivery receipts of an alert per channel (email, webhook) and target", Tag: "Alerts"},
+	"GET /incidents":               {Summary: "List incidents grouping related alerts", Tag: "Alerts", Params: []APIParam{paramDays}},
+	"GET /incidents/{id}":          {Summary: "Get an incident with its alerts", Tag: "Alerts"},
+	"GET /alerts/silences":         {Summary: "List Alertmanager silences", Tag: "Alerts"},
+	"POST /alerts/silences":        {Summary: "Receive silences pushed by Alertmanager", Tag: "Alerts", Body: "Silences", Permission: middleware.PermissionAlertsManage},
+	"POST /alerts/silences/sync":   {Summary: "Sync silences from Alertmanager now", Tag: "Alerts", Permission: middleware.PermissionAlertsManage},
+	"DELETE /alerts/silences/{id}": {Summary: "Remove a silence", Tag: "Alerts", Permission: middleware.PermissionAlertsManage},
+	"GET /alert-thresholds":        {Summary: "List utilization alert threshold overrides", Tag: "Alerts"},
+	"PUT /alert-thresholds":        {Summary: "Set a threshold override", Tag: "Alerts", Body: "Threshold (server_hostname, feature_name, warning_pct, critical_pct)", Permission: middleware.PermissionAlertsManage},
+	"DELETE /alert-thresholds":     {Summary: "Remove a threshold override", Tag: "Alerts", Params: []APIParam{paramServer, paramFeature}, Permission: middleware.PermissionAlertsManage},
+	"GET /alert-rules":             {Summary: "List alert rules", Tag: "Alerts"},
+	"POST /alert-rules":            {Summary: "Add an alert rule", Tag: "Alerts", Body: "Alert rule (name, rule_type, server_hostname, feature_pattern, threshold, duration_min, severity, enabled)", Permission: middleware.PermissionAlertsManage},
+	"GET /alert-rules/{id}":        {Summary: "Get an alert rule", Tag: "Alerts"},
+	"PUT /alert-rules/{id}":        {Summary: "Replace an alert rule", Tag: "Alerts", Body: "Alert rule", Permission: middleware.PermissionAlertsManage},
+	"DELETE /alert-rules/{id}":     {Summary: "Remove an alert rule", Tag: "Alerts", Permission: middleware.PermissionAlertsManage},
+	"GET /webhooks/deliveries":     {Summary: "Recent webhook delivery attempts", Tag: "Alerts", Params: []APIParam{{Name: "webhook", Description: "Webhook name"}, {Name: "failed", Description: "Only failed attempts", Type: "boolean"}, paramLimit}},
+	"GET /contracts":               {Summary: "List license contracts with cost per seat, renewal date, PO number and vendor contact", Tag: "Features"},
+	"POST /contracts":              {Summary: "Add the contract of a feature", Tag: "Features", Body: "Contract (server_hostname, feature_name, cost_per_seat, currency, renewal_date, po_number, vendor_contact, notes)", Permission: middleware.PermissionSettingsWrite},
+	"GET /contracts/{id}":          {Summary: "Get a license contract", Tag: "Features"},
+	"PUT /contracts/{id}":          {Summary: "Replace a license contract", Tag: "Features", Body: "Contract", Permission: middleware.PermissionSettingsWrite},
+	"DELETE /contracts/{id}":       {Summary: "Remove a license contract", Tag: "Features", Permission: middleware.PermissionSettingsWrite},
+	"GET /display-names":           {Summary: "List feature display name overrides", Tag: "Features"},
+	"PUT /display-names":           {Summary: "Set a display name override", Tag: "Features", Body: "Display name (server_hostname, feature_name, display_name)", Permission: middleware.PermissionSettingsWrite},
+	"DELETE /display-names":        {Summary: "Remove a display name override", Tag: "Features", Params: []APIParam{paramServer, paramFeature}, Permission: middleware.PermissionSettingsWrite},
+	"GET /tags":                    {Summary: "List feature tags with how many features carry each", Tag: "Features"},
+	"GET /feature-tags":            {Summary: "List the features of each tag", Tag: "Features", Params: []APIParam{paramTag}},
+	"POST /feature-tags":           {Summary: "Tag a feature, on one server or every server", Tag: "Features", Body: "Feature tag (server_hostname, feature_name, tag)", Permission: middleware.PermissionSettingsWrite},
+	"DELETE /feature-tags/{id}":    {Summary: "Remove a tag from a feature", Tag: "Features", Permission: middleware.PermissionSettingsWrite},
+
+	// Export
+	"GET /export/servers":             {Summary: "Export servers", Tag: "Export", Params: []APIParam{paramAsync, paramFormat, paramGroup}, Permission: middleware.PermissionExportsRun},
+	"GET /export/features":            {Summary: "Export features", Tag: "Export", Params: []APIParam{paramAsync, paramFormat, paramServer, paramTag, paramGroup}, Permission: middleware.PermissionExportsRun},
+	"GET /export/utilization":         {Summary: "Export current utilization", Tag: "Export", Params: []APIParam{paramAsync, paramFormat, paramServer, paramTag, paramGroup}, Permission: middleware.PermissionExportsRun},
+	"GET /export/utilization/history": {Summary: "Export usage history", Tag: "Export", Params: []APIParam{paramAsync, paramFormat, paramServer, paramFeature, {Name: "tag", Description: "Combined usage of the features carrying this tag"}, {Name: "group", Description: "Combined usage of the features on the servers of this group"}, paramDays}, Permission: middleware.PermissionExportsRun},
+	"GET /export/stats":               {Summary: "Export utilization statistics", Tag: "Export", Params: []APIParam{paramAsync, paramFormat, paramServer, paramTag, paramGroup, paramDays}, Permission: middleware.PermissionExportsRun},
+	"GET /export/report":              {Summary: "Export a utilization report", Tag: "Export", Params: []APIParam{paramAsync, paramFormat, paramServer, paramTag, paramGroup, paramDays}, Permission: middleware.PermissionExportsRun},
+	"GET /export/expirations.ics":     {Summary: "iCalendar feed of upcoming license expirations with reminders, to subscribe to", Tag: "Export", Params: []APIParam{paramServer, {Name: "days", Description: "Days ahead included (default 365)", Type: "integer"}, {Name: "reminders", Description: "Days before each expiration to remind, e.g. 30,7 (default export.calendar_reminders)"}}, Permission: middleware.PermissionExportsRun},
+	"GET /export/chargeback":          {Summary: "Export the chargeback report for finance", Tag: "Export", Params: append([]APIParam{paramAsync, paramFormat}, paramsChargeback...), Permission: middleware.PermissionExportsRun},
+	"GET /export/forecast":            {Summary: "Budget forecast of seat requirements", Tag: "Export", Params: []APIParam{paramAsync, paramFormat, paramDays, {Name: "growth", Description: "Yearly headcount growth in percent", Type: "number"}, {Name: "months", Description: "Months to project", Type: "integer"}}, Permission: middleware.PermissionExportsRun},
+
+	// Database maintenance
+	"GET /database/stats":           {Summary: "Database statistics", Tag: "Database"},
+	"GET /database/retention":       {Summary: "Data retention statistics", Tag: "Database"},
+	"GET /database/retention/runs":  {Summary: "Retention policy and history of retention runs", Tag: "Database", Params: []APIParam{paramLimit}},
+	"POST /database/retention/runs": {Summary: "Apply the retention policy now", Tag: "Database", Params: []APIParam{{Name: "dry_run", Description: "Only count the rows that would be removed", Type: "boolean"}}},
+	"POST /database/vacuum":         {Summary: "Vacuum the database", Tag: "Database"},
+	"POST /database/cleanup":        {Summary: "Remove old data", Tag: "Database", Params: []APIParam{{Name: "table", Description: "Table to clean up", Required: true}, paramDays}},
+	"POST /database/analyze":        {Summary: "Update query planner statistics", Tag: "Database"},
+	"POST /database/checkpoint":     {Summary: "Checkpoint the SQLite write-ahead log", Tag: "Database"},
+	"POST /database/dedup":          {Summary: "Merge duplicate feature rows", Tag: "Database"},
+
+	// Administration
+	"POST /admin/anonymize":           {Summary: "Replace a username in all stored records", Tag: "Admin", Body: "Username to anonymize"},
+	"GET /admin/db/stats":             {Summary: "Database statistics, for automated maintenance", Tag: "Admin"},
+	"POST /admin/db/vacuum":           {Summary: "Vacuum the database", Tag: "Admin"},
+	"POST /admin/db/analyze":          {Summary: "Update query planner statistics", Tag: "Admin"},
+	"POST /admin/db/cleanup":          {Summary: "Remove data older than a number of days from a table", Tag: "Admin", Params: []APIParam{{Name: "table", Description: "Table to clean up", Required: true}, {Name: "days", Description: "Age in days of the data removed, default 90", Type: "integer"}}},
+	"POST /admin/backfill":            {Summary: "Queue a job recomputing usage rollups of past days and the trends of all servers", Tag: "Admin", Params: []APIParam{{Name: "from", Description: "First day (YYYY-MM-DD)", Required: true}, {Name: "to", Description: "Last day (YYYY-MM-DD), default today"}}},
+	"GET /admin/audit":                {Summary: "Audit log of administrative operations and API changes", Tag: "Admin", Params: []APIParam{paramPage, paramLimit, {Name: "actor", Description: "User name"}, {Name: "action", Description: "Action, e.g. api_request"}, {Name: "method", Description: "HTTP method of recorded API requests"}, paramDays}},
+	"GET /admin/snapshot":             {Summary: "Download the history of a server as a snapshot archive", Tag: "Admin", Params: []APIParam{{Name: "server", Description: "Server to export", Required: true}, paramAsync}},
+	"POST /admin/snapshot":            {Summary: "Import a snapshot archive sent as the request body", Tag: "Admin", Params: []APIParam{{Name: "replace", Description: "Replace existing history of the server", Type: "boolean"}}},
+	"GET /admin/outbox":               {Summary: "Queued, sent and dead letter email of the outbox, newest first", Tag: "Admin", Params: []APIParam{{Name: "status", Description: "queued, sent or dead"}, {Name: "limit", Description: "Email listed (default 50, at most 500)", Type: "integer"}}},
+	"POST /admin/outbox/{id}/requeue": {Summary: "Queue a dead letter email again", Tag: "Admin"},
+	"GET /admin/flags":                {Summary: "List feature flags", Tag: "Admin"},
+	"PUT /admin/flags/{name}":         {Summary: "Toggle a feature flag", Tag: "Admin", Body: "Flag state (enabled)"},
+	"DELETE /admin/flags/{name}":      {Summary: "Return a feature flag to its configured value", Tag: "Admin"},
+	"GET /admin/chaos":                {Summary: "List the faults injected into license servers (chaos.enabled)", Tag: "Admin"},
+	"PUT /admin/chaos/{server}":       {Summary: "Make the queries of a server fail with a synthetic fault", Tag: "Admin", Body: "Fault (timeout, server_down, vendor_down or garbage) and duration"},
+	"DELETE /admin/chaos/{server}":    {Summary: "Clear the fault injected into a server", Tag: "Admin"},
+
+	// System
+	"GET /health":              {Summary: "Health check", Tag: "System"},
+	"GET /system/info":         {Summary: "Build, database backend and enabled subsystems", Tag: "System"},
+	"GET /system/data-quality": {Summary: "Collection gaps, stale features, parse warnings and duplicate suspects", Tag: "System", Params: []APIParam{paramDays, {Name: "stale_hours", Description: "Hours after which an active feature counts as stale", Type: "integer"}}},
+	"GET /auth/info":           {Summary: "Authentication state of the caller", Tag: "System"},
+	"GET /openapi.json":        {Summary: "This OpenAPI document", Tag: "System"},
+	"POST /slack/commands":     {Summary: "Answer a Slack slash command (status or who), verified by its Slack signature", Tag: "System", Body: "Slash command, form encoded by Slack"},
+	"POST /ingest/logs":        {Summary: "Ingest license server debug log lines", Tag: "System", Params: []APIParam{paramServer, paramServerType}, Body: "Log lines, as JSON or plain text"},
+	"POST /ingest/reportlog":   {Summary: "Import a FlexNet report log converted to text", Tag: "System", Params: []APIParam{paramServer}, Body: "Report log records, one per line"},
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}/]+)\}`)
+
+// pathParamDescriptions describe the path parameters that take more than a name
+var pathParamDescriptions = map[string]string{
+	"server":  "Server hostname or UUID",
+	"feature": "Feature name or UUID",
+}
+
+// apiRoute splits a registered route into its API version prefix and the
+// operation key, reporting whether it is an API route
+func apiRoute(method, route string) (prefix, key string, ok bool) {
+	for _, prefix := range []string{"/api/v1", "/api/v2"} {
+		if strings.HasPrefix(route, prefix+"/") {
+			return prefix, method + " " + strings.TrimPrefix(route, prefix), true
+		}
+	}
+	return "", "", false
+}
+
+// OpenAPIDocument builds an OpenAPI 3 document of the API routes of a router
+func OpenAPIDocument(routes chi.Routes, version string) (map[string]interface{}, error) {
+	paths := make(map[string]map[string]interface{})
+	tags := make(map[string]bool)
+
+	err := chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		prefix, key, ok := apiRoute(method, route)
+		if !ok {
+			return nil
+		}
+		op, documented := apiOperations[key]
+		if !documented {
+			op = APIOperation{Summary: strings.TrimPrefix(key, method+" "), Tag: "Other"}
+		}
+		if prefix == "/api/v2" {
+			op.Tag += " (v2)"
+		}
+		tags[op.Tag] = true
+
+		if paths[route] == nil {
+			paths[route] = make(map[string]interface{})
+		}
+		paths[route][strings.ToLower(method)] = openAPIOperation(method, route, prefix, op)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tagList := make([]map[string]string, 0, len(tags))
+	for tag := range tags {
+		tagList = append(tagList, map[string]string{"name": tag})
+	}
+	sort.Slice(tagList, func(i, j int) bool { return tagList[i]["name"] < tagList[j]["name"] })
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Licet API",
+			"description": "License server monitoring. /api/v2 serves the read endpoints of /api/v1 in a uniform envelope.",
+			"version":     version,
+		},
+		"tags":  tagList,
+		"paths": paths,
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"apiKey":    map[string]string{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+				"basicAuth": map[string]string{"type": "http", "scheme": "basic"},
+			},
+		},
+		"security": []map[string][]string{{"apiKey": {}}, {"basicAuth": {}}},
+	}, nil
+}
+
+// openAPIOperation describes one operation of the document
+func openAPIOperation(method, route, prefix string, op APIOperation) map[string]interface{} {
+	var params []map[string]interface{}
+	for _, m := range pathParamPattern.FindAllStringSubmatch(route, -1) {
+		param := map[string]interface{}{
+			"name": m[1], "in": "path", "required": true,
+			"schema": map[string]string{"type": "string"},
+		}
+		if description, ok := pathParamDescriptions[m[1]]; ok {
+			param["description"] = description
+		}
+		params = append(params, param)
+	}
+	for _, p := range op.Params {
+		typ := p.Type
+		if typ == "" {
+			typ = "string"
+		}
+		params = append(params, map[string]interface{}{
+			"name": p.Name, "in": "query", "required": p.Required, "description": p.Description,
+			"schema": map[string]string{"type": typ},
+		})
+	}
+
+	id := strings.ToLower(method) + pathParamPattern.ReplaceAllString(route, "By_$1")
+	id = strings.NewReplacer("/", "_", "-", "_", ".", "_").Replace(id)
+
+	success := "Success"
+	if prefix == "/api/v2" {
+		success = "Success, wrapped in the v2 envelope (data, meta, error)"
+	}
+	operation := map[string]interface{}{
+		"operationId": id,
+		"summary":     op.Summary,
+		"tags":        []string{op.Tag},
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{"description": success},
+			"400": map[string]interface{}{"description": "Invalid request"},
+			"401": map[string]interface{}{"description": "Authentication required"},
+			"403": map[string]interface{}{"description": "Forbidden"},
+			"500": map[string]interface{}{"description": "Internal error"},
+		},
+	}
+	if len(params) > 0 {
+		operation["parameters"] = params
+	}
+	if op.Permission != "" {
+		operation["x-required-permission"] = op.Permission
+	}
+	if op.Body != "" {
+		operation["requestBody"] = map[string]interface{}{
+			"description": op.Body,
+			"required":    true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": map[string]string{"type": "object"}},
+			},
+		}
+	}
+	return operation
+}
+
+// RoutePermission returns the permission annotated on the API route serving
+// a request, or an empty string when it requires that of its method
+func RoutePermission(r *http.Request) string {
+	_, key, ok := apiRoute(r.Method, routePattern(r))
+	if !ok {
+		return ""
+	}
+	return apiOperations[key].Permission
+}
+
+// UndocumentedRoutes returns the API routes of a router without an entry in
+// the operation registry, and registry entries without a route
+func UndocumentedRoutes(routes chi.Routes) (undocumented, stale []string, err error) {
+	seen := make(map[string]bool)
+	err = chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		_, key, ok := apiRoute(method, route)
+		if !ok {
+			return nil
+		}
+		seen[key] = true
+		if _, documented := apiOperations[key]; !documented {
+			undocumented = append(undocumented, method+" "+route)
+		}
+		return nil
+	})
+	for key := range apiOperations {
+		if !seen[key] {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(undocumented)
+	sort.Strings(stale)
+	return undocumented, stale, err
+}
+
+// OpenAPI handles GET /api/v1/openapi.json - the OpenAPI 3 document of the
+// API. The document is built from the router on first request, once all
+// routes are registered.
+func OpenAPI(routes chi.Routes, version string) http.HandlerFunc {
+	var (
+		once sync.Once
+		body []byte
+		err  error
+	)
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			var doc map[string]interface{}
+			if doc, err = OpenAPIDocument(routes, version); err == nil {
+				body, err = json.Marshal(doc)
+			}
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
+}
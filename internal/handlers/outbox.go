@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/services"
+)
+
+// ListOutboxEmails handles GET /api/v1/admin/outbox?status=dead&limit=50 -
+// lists the most recent email of the outbox, newest first
+func ListOutboxEmails(outbox *services.EmailOutbox) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := 50
+		if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 500 {
+			limit = l
+		}
+
+		emails, err := outbox.List(r.Context(), r.URL.Query().Get("status"), limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"emails": emails,
+			"total":  len(emails),
+		})
+	}
+}
+
+// RequeueOutboxEmail handles POST /api/v1/admin/outbox/{id}/requeue - queues
+// a dead letter again, to be sent with all its attempts
+func RequeueOutboxEmail(outbox *services.EmailOutbox) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid email ID", http.StatusBadRequest)
+			return
+		}
+
+		email, err := outbox.Requeue(r.Context(), id)
+		if errors.Is(err, services.ErrEmailNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, services.ErrEmailNotDead) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(email)
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/services"
+)
+
+// AnonymizeUser handles POST /api/v1/admin/anonymize - replaces a username in
+// all stored records with a stable pseudonym for data subject deletion requests
+func AnonymizeUser(cfg *config.Config, anonymizer *services.AnonymizeService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Server.SettingsEnabled {
+			http.Error(w, "Settings page is disabled", http.StatusForbidden)
+			return
+		}
+
+		info := middleware.GetAuthInfo(r)
+		if cfg.Auth.Enabled && info.Role != middleware.RoleAdmin {
+			http.Error(w, "Admin role required", http.StatusForbidden)
+			return
+		}
+
+		var req struct {
+			Username string `json:"username"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		req.Username = strings.TrimSpace(req.Username)
+		if req.Username == "" {
+			http.Error(w, "username is required", http.StatusBadRequest)
+			return
+		}
+
+		actor := info.Username
+		if actor == "" {
+			actor = "anonymous"
+		}
+
+		report, err := anonymizer.AnonymizeUser(r.Context(), req.Username, actor)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// GetAuditLog handles GET /api/v1/admin/audit?page=1&limit=50 - lists the
+// audit log newest first, optionally filtered by ?actor=, ?action=, ?method=
+// and ?days=
+func GetAuditLog(cfg *config.Config, audit *services.AuditService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.Auth.Enabled && middleware.GetAuthInfo(r).Role != middleware.RoleAdmin {
+			http.Error(w, "Admin role required", http.StatusForbidden)
+			return
+		}
+
+		query := r.URL.Query()
+		filter := services.AuditFilter{
+			Actor:  query.Get("actor"),
+			Action: query.Get("action"),
+			Method: query.Get("method"),
+		}
+		if d := query.Get("days"); d != "" {
+			days, err := strconv.Atoi(d)
+			if err != nil || days <= 0 {
+				http.Error(w, "days must be a positive number", http.StatusBadRequest)
+				return
+			}
+			filter.Since = time.Now().AddDate(0, 0, -days)
+		}
+
+		pagination := middleware.ParsePagination(r, middleware.DefaultPaginationConfig())
+		entries, total, err := audit.List(r.Context(), filter, pagination.Limit, pagination.Offset)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"entries":    entries,
+			"total":      total,
+			"pagination": middleware.NewPaginatedResponse(entries, total, pagination).Pagination,
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// publicFeedFields are the values of the fields the public feed can publish,
+// keyed by the names in config.PublicFeedFields
+var publicFeedFields = map[string]func(models.UtilizationData) interface{}{
+	"server_hostname":     func(u models.UtilizationData) interface{} { return u.ServerHostname },
+	"feature_name":        func(u models.UtilizationData) interface{} { return u.FeatureName },
+	"display_name":        func(u models.UtilizationData) interface{} { return u.DisplayName },
+	"vendor_daemon":       func(u models.UtilizationData) interface{} { return u.VendorDaemon },
+	"vendor_display_name": func(u models.UtilizationData) interface{} { return u.VendorName },
+	"total_licenses":      func(u models.UtilizationData) interface{} { return u.TotalLicenses },
+	"used_licenses":       func(u models.UtilizationData) interface{} { return u.UsedLicenses },
+	"available_licenses":  func(u models.UtilizationData) interface{} { return u.AvailableLicenses },
+	"utilization_pct":     func(u models.UtilizationData) interface{} { return u.UtilizationPct },
+}
+
+// PublicFeed serves the availability of the features allowed by
+// public_feed.features, with only the fields of public_feed.fields, to anyone.
+// The feed is rendered at most once per public_feed.cache_seconds.
+type PublicFeed struct {
+	cfg       config.PublicFeedConfig
+	analytics *services.AnalyticsService
+	names     *services.DisplayNameService
+	now       func() time.Time
+
+	mu      sync.Mutex
+	body    []byte
+	etag    string
+	expires time.Time
+}
+
+// NewPublicFeed creates the public feed
+func NewPublicFeed(cfg config.PublicFeedConfig, analytics *services.AnalyticsService, names *services.DisplayNameService) *PublicFeed {
+	return &PublicFeed{cfg: cfg, analytics: analytics, names: names, now: time.Now}
+}
+
+// published reports whether a feature may be published
+func (f *PublicFeed) published(u models.UtilizationData) bool {
+	if len(f.cfg.Servers) > 0 && !slices.Contains(f.cfg.Servers, u.ServerHostname) {
+		return false
+	}
+	for _, pattern := range f.cfg.Features {
+		if ok, _ := path.Match(pattern, u.FeatureName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// render returns the feed and its ETag, rendering it when the cached one expired
+func (f *PublicFeed) render(r *http.Request) ([]byte, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	if f.body != nil && now.Before(f.expires) {
+		return f.body, f.etag, nil
+	}
+
+	utilization, err := f.analytics.GetCurrentUtilization(r.Context(), "")
+	if err != nil {
+		return nil, "", err
+	}
+	f.names.ApplyToUtilization(utilization)
+
+	features := []map[string]interface{}{}
+	for _, u := range utilization {
+		if !f.published(u) {
+			continue
+		}
+		feature := make(map[string]interface{}, len(f.cfg.Fields))
+		for _, field := range f.cfg.Fields {
+			feature[field] = publicFeedFields[field](u)
+		}
+		features = append(features, feature)
+	}
+
+	var buf bytes.Buffer
+	err = json.NewEncoder(&buf).Encode(map[string]interface{}{
+		"features":     features,
+		"generated_at": now.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	f.body, f.etag = buf.Bytes(), `"`+hex.EncodeToString(sum[:8])+`"`
+	f.expires = now.Add(time.Duration(f.cfg.CacheSeconds) * time.Second)
+	return f.body, f.etag, nil
+}
+
+// ServeHTTP handles GET on public_feed.path
+func (f *PublicFeed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, etag, err := f.render(r)
+	if err != nil {
+		http.Error(w, "Feed unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", f.cfg.CacheSeconds))
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+func TestPublicFeedFields(t *testing.T) {
+	for _, field := range config.PublicFeedFields {
+		if publicFeedFields[field] == nil {
+			t.Errorf("Public feed field %q has no value", field)
+		}
+	}
+}
+
+func TestPublicFeed(t *testing.T) {
+	db := newTestDB(t)
+	storage := services.NewStorageService(db, "sqlite")
+	if err := storage.StoreFeatures(context.Background(), []models.Feature{
+		{ServerHostname: "27000@a", Name: "matlab", VendorDaemon: "MLM", TotalLicenses: 10, UsedLicenses: 4},
+		{ServerHostname: "27000@a", Name: "matlab_toolbox", TotalLicenses: 5, UsedLicenses: 5},
+		{ServerHostname: "27000@a", Name: "secret_project", TotalLicenses: 2, UsedLicenses: 1},
+		{ServerHostname: "27000@b", Name: "matlab", TotalLicenses: 3, UsedLicenses: 0},
+	}); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	feed := NewPublicFeed(config.PublicFeedConfig{
+		Features:     []string{"matlab*"},
+		Servers:      []string{"27000@a"},
+		Fields:       []string{"feature_name", "available_licenses"},
+		CacheSeconds: 60,
+	}, services.NewAnalyticsService(db, storage, "sqlite"), nil)
+	feed.now = func() time.Time { return now }
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/public/availability.json", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		feed.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("")
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "public, max-age=60" {
+		t.Fatalf("Unexpected response: %d %v", rec.Code, rec.Header())
+	}
+	var body struct {
+		Features []map[string]interface{} `json:"features"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode feed: %v", err)
+	}
+	if len(body.Features) != 2 {
+		t.Fatalf("Expected the 2 matlab features of 27000@a, got %+v", body.Features)
+	}
+	for _, f := range body.Features {
+		if len(f) != 2 || f["feature_name"] == "secret_project" {
+			t.Errorf("Expected only the whitelisted fields and features, got %+v", f)
+		}
+	}
+	etag := rec.Header().Get("ETag")
+
+	// Served from memory until the cache expires
+	if err := storage.StoreFeatures(context.Background(), []models.Feature{
+		{ServerHostname: "27000@a", Name: "matlab", TotalLicenses: 10, UsedLicenses: 9},
+	}); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	if rec := get(etag); rec.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for an unchanged feed, got %d", rec.Code)
+	}
+	now = now.Add(time.Minute)
+	if rec := get(etag); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("Expected a new feed after the cache expired, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/services"
+)
+
+// ListReports handles GET /api/v1/reports - lists the scheduled reports
+func ListReports(reports *services.ReportService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		schedules := reports.Schedules()
+		list := make([]map[string]interface{}, len(schedules))
+		for i, schedule := range schedules {
+			list[i] = map[string]interface{}{
+				"name":       schedule.Name,
+				"period":     schedule.Period,
+				"cron":       schedule.Cron,
+				"format":     schedule.Format,
+				"recipients": len(schedule.Recipients),
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"reports": list,
+			"total":   len(list),
+		})
+	}
+}
+
+// GetReport handles GET /api/v1/reports/{name} - renders a scheduled report
+// from current data as it would be emailed
+func GetReport(reports *services.ReportService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := reports.Render(r.Context(), chi.URLParam(r, "name"))
+		if errors.Is(err, services.ErrUnknownReport) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", report.ContentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", report.Filename))
+		w.Write(report.Body)
+	}
+}
+
+// SendReport handles POST /api/v1/reports/{name}/send - emails a scheduled
+// report now
+func SendReport(cfg *config.Config, reports *services.ReportService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.Auth.Enabled && middleware.GetAuthInfo(r).Role != middleware.RoleAdmin {
+			http.Error(w, "Admin role required", http.StatusForbidden)
+			return
+		}
+
+		name := chi.URLParam(r, "name")
+		err := reports.Send(r.Context(), name)
+		if errors.Is(err, services.ErrUnknownReport) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "sent",
+			"report": name,
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// uuidPattern matches the UUIDs of resources
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// resourceStoreKey is the context key of the storage that resolves resource UUIDs
+type resourceStoreKey struct{}
+
+// ResolveResourceIDs lets the {server} and {feature} URL parameters of the
+// routes below it be resource UUIDs as well as names. Names with slashes or
+// spaces are awkward in URLs; UUIDs never are.
+func ResolveResourceIDs(storage *services.StorageService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), resourceStoreKey{}, storage)))
+		})
+	}
+}
+
+// lookupResource resolves a URL parameter that is a UUID of a resource of
+// the given kind. It returns false for names.
+func lookupResource(r *http.Request, param, kind string) (models.Resource, bool) {
+	storage, _ := r.Context().Value(resourceStoreKey{}).(*services.StorageService)
+	if storage == nil || !uuidPattern.MatchString(param) {
+		return models.Resource{}, false
+	}
+	resource, err := storage.GetResource(r.Context(), param)
+	if err != nil {
+		if !errors.Is(err, services.ErrResourceNotFound) {
+			log.Errorf("Failed to resolve resource %s: %v", param, err)
+		}
+		return models.Resource{}, false
+	}
+	return resource, resource.Kind == kind
+}
+
+// serverParam returns the {server} URL parameter, or the hostname of the
+// server when it is a UUID
+func serverParam(r *http.Request) string {
+	server := chi.URLParam(r, "server")
+	if resource, ok := lookupResource(r, server, services.ResourceServer); ok {
+		return resource.ServerHostname
+	}
+	return server
+}
+
+// featureParam returns the {feature} URL parameter and the server query
+// parameter, or the name and server of the feature when it is a UUID
+func featureParam(r *http.Request) (server, feature string) {
+	feature = chi.URLParam(r, "feature")
+	if resource, ok := lookupResource(r, feature, services.ResourceFeature); ok {
+		return resource.ServerHostname, resource.Name
+	}
+	return r.URL.Query().Get("server"), feature
+}
+
+// GetResource handles GET /api/v1/resources/{id} - looks up a server or
+// feature by its UUID or stable id, with links to its endpoints
+func GetResource(storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource, err := storage.GetResource(r.Context(), chi.URLParam(r, "id"))
+		if errors.Is(err, services.ErrResourceNotFound) {
+			http.Error(w, "Resource not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		links := map[string]string{}
+		switch resource.Kind {
+		case services.ResourceServer:
+			links["status"] = "/api/v1/servers/" + resource.UUID + "/status"
+			links["features"] = "/api/v1/servers/" + resource.UUID + "/features"
+		case services.ResourceFeature:
+			links["usage"] = "/api/v1/features/" + resource.UUID + "/usage"
+			links["top_users"] = "/api/v1/features/" + resource.UUID + "/top-users"
+			links["by_name"] = "/api/v1/features/" + url.PathEscape(resource.Name) + "/usage?server=" + url.QueryEscape(resource.ServerHostname)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"resource": resource,
+			"links":    links,
+		})
+	}
+}
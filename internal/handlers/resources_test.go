@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+func TestResourceIDRoutes(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := services.NewStorageService(db, "sqlite")
+	for _, server := range []string{"27000@a", "27000@b"} {
+		features := []models.Feature{{ServerHostname: server, Name: "CAD/3D", TotalLicenses: 5, UsedLicenses: 2}}
+		if err := storage.StoreFeatures(ctx, features); err != nil {
+			t.Fatalf("StoreFeatures failed: %v", err)
+		}
+		if err := storage.RecordUsage(ctx, features); err != nil {
+			t.Fatalf("RecordUsage failed: %v", err)
+		}
+	}
+
+	r := chi.NewRouter()
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(ResolveResourceIDs(storage))
+		r.Get("/resources/{id}", GetResource(storage))
+		r.Get("/servers/{server}/features", GetServerFeatures(storage, nil))
+		r.Get("/features/{feature}/usage", GetFeatureUsage(storage))
+	})
+	get := func(target string, v interface{}) int {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		if w.Code == http.StatusOK && v != nil {
+			if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+				t.Fatalf("Failed to decode %s: %v", target, err)
+			}
+		}
+		return w.Code
+	}
+
+	var listed struct {
+		Features []models.Feature `json:"features"`
+	}
+	if code := get("/api/v1/servers/27000@a/features", &listed); code != http.StatusOK || len(listed.Features) != 1 {
+		t.Fatalf("Expected the features of 27000@a, got %d: %+v", code, listed)
+	}
+	feature := listed.Features[0]
+	if feature.UUID == "" || feature.ResourceID == 0 {
+		t.Fatalf("Expected listed features to carry their ids, got %+v", feature)
+	}
+
+	// The UUID of a feature selects its server too, and a name with a slash needs no escaping
+	var usage struct {
+		Usage []models.FeatureUsage `json:"usage"`
+	}
+	if code := get("/api/v1/features/"+feature.UUID+"/usage", &usage); code != http.StatusOK {
+		t.Fatalf("Expected 200 for the feature UUID, got %d", code)
+	}
+	if len(usage.Usage) != 1 || usage.Usage[0].ServerHostname != "27000@a" || usage.Usage[0].FeatureName != "CAD/3D" {
+		t.Errorf("Expected the usage of CAD/3D on 27000@a only, got %+v", usage.Usage)
+	}
+
+	var found struct {
+		Resource models.Resource `json:"resource"`
+	}
+	if code := get("/api/v1/resources/"+feature.UUID, &found); code != http.StatusOK || found.Resource.Name != "CAD/3D" {
+		t.Errorf("Expected the feature resource, got %d: %+v", code, found)
+	}
+	if code := get("/api/v1/resources/00000000-0000-4000-8000-000000000000", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown UUID, got %d", code)
+	}
+}
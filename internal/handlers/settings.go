@@ -1,17 +1,24 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"licet/internal/config"
+	"licet/internal/middleware"
 	"licet/internal/services"
 	"licet/internal/util"
 )
 
+// serverListPaths are the cached API responses listing the configured servers
+var serverListPaths = []string{"/api/v1/servers", "/api/v2/servers"}
+
 // AddServer handles POST /api/v1/servers - adds a new license server to config file
-func AddServer(cfg *config.Config) http.HandlerFunc {
+func AddServer(cfg *config.Config, cache *middleware.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Check if settings page is enabled
 		if !cfg.Server.SettingsEnabled {
@@ -40,12 +47,30 @@ func AddServer(cfg *config.Config) http.HandlerFunc {
 			return
 		}
 
+		server.Schedule = strings.TrimSpace(server.Schedule)
+		if err := util.ValidatePollSchedule(server.PollInterval, server.Schedule, server.Jitter); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if server.Timeout < 0 {
+			http.Error(w, "timeout must be a positive number of seconds", http.StatusBadRequest)
+			return
+		}
+		server.Group = strings.TrimSpace(server.Group)
+		if len(server.Group) > 64 {
+			http.Error(w, "group must be at most 64 characters", http.StatusBadRequest)
+			return
+		}
+
 		// Write to config file
 		configWriter := services.NewConfigWriter()
 		if err := configWriter.AddServer(server); err != nil {
 			http.Error(w, "Failed to add server: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		for _, path := range serverListPaths {
+			cache.InvalidatePrefix(path)
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
@@ -57,7 +82,7 @@ func AddServer(cfg *config.Config) http.HandlerFunc {
 }
 
 // DeleteServer handles DELETE /api/v1/servers - removes a license server from config file
-func DeleteServer(cfg *config.Config) http.HandlerFunc {
+func DeleteServer(cfg *config.Config, cache *middleware.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Check if settings page is enabled
 		if !cfg.Server.SettingsEnabled {
@@ -77,6 +102,9 @@ func DeleteServer(cfg *config.Config) http.HandlerFunc {
 			http.Error(w, "Failed to delete server: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		for _, path := range serverListPaths {
+			cache.InvalidatePrefix(path)
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -152,7 +180,12 @@ func TestServerConnection(cfg *config.Config, query *services.QueryService) http
 	}
 }
 
-// UpdateEmailSettings handles POST /api/v1/settings/email - updates email configuration
+// settingsMu serializes applying settings to the running configuration
+var settingsMu sync.Mutex
+
+// UpdateEmailSettings handles POST /api/v1/settings/email - validates the email
+// configuration, optionally tests the SMTP connection (test_connection), saves
+// it and applies it without a restart
 func UpdateEmailSettings(cfg *config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Check if settings page is enabled
@@ -162,53 +195,104 @@ func UpdateEmailSettings(cfg *config.Config) http.HandlerFunc {
 		}
 
 		var emailConfig struct {
-			Enabled  bool     `json:"enabled"`
-			From     string   `json:"from"`
-			To       []string `json:"to"`
-			SMTPHost string   `json:"smtp_host"`
-			SMTPPort int      `json:"smtp_port"`
-			Username string   `json:"username"`
-			Password string   `json:"password"`
+			Enabled        bool     `json:"enabled"`
+			From           string   `json:"from"`
+			To             []string `json:"to"`
+			SMTPHost       string   `json:"smtp_host"`
+			SMTPPort       int      `json:"smtp_port"`
+			Username       string   `json:"username"`
+			Password       string   `json:"password"`
+			TestConnection bool     `json:"test_connection"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&emailConfig); err != nil {
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
+		emailConfig.SMTPHost = strings.TrimSpace(emailConfig.SMTPHost)
 
 		// Validate email settings if enabled
 		if emailConfig.Enabled {
+			if emailConfig.From == "" {
+				http.Error(w, "A 'from' email is required", http.StatusBadRequest)
+				return
+			}
 			if err := util.ValidateEmail(emailConfig.From); err != nil {
 				http.Error(w, "Invalid 'from' email: "+err.Error(), http.StatusBadRequest)
 				return
 			}
+			if len(emailConfig.To) == 0 {
+				http.Error(w, "At least one 'to' email is required", http.StatusBadRequest)
+				return
+			}
 			for _, to := range emailConfig.To {
+				if to == "" {
+					http.Error(w, "Invalid 'to' email: empty address", http.StatusBadRequest)
+					return
+				}
 				if err := util.ValidateEmail(to); err != nil {
 					http.Error(w, "Invalid 'to' email: "+err.Error(), http.StatusBadRequest)
 					return
 				}
 			}
-			if err := util.ValidatePort(emailConfig.SMTPPort); err != nil {
+			if err := util.ValidateHost(emailConfig.SMTPHost); err != nil {
+				http.Error(w, "Invalid SMTP host: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := util.ValidateRange(emailConfig.SMTPPort, 1, 65535, "smtp_port"); err != nil {
 				http.Error(w, "Invalid SMTP port: "+err.Error(), http.StatusBadRequest)
 				return
 			}
 		}
 
+		settingsMu.Lock()
+		defer settingsMu.Unlock()
+
+		// Empty credentials keep the configured ones
+		email := cfg.Email
+		email.Enabled = emailConfig.Enabled
+		email.From = emailConfig.From
+		email.To = emailConfig.To
+		email.SMTPHost = emailConfig.SMTPHost
+		email.SMTPPort = emailConfig.SMTPPort
+		if emailConfig.Username != "" {
+			email.Username = emailConfig.Username
+		}
+		if emailConfig.Password != "" {
+			email.Password = emailConfig.Password
+		}
+
+		if emailConfig.TestConnection {
+			if err := util.ValidateHost(email.SMTPHost); err != nil {
+				http.Error(w, "Invalid SMTP host: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+			defer cancel()
+			if err := services.TestSMTPConnection(ctx, email); err != nil {
+				http.Error(w, "SMTP connection test failed: "+err.Error(), http.StatusBadGateway)
+				return
+			}
+		}
+
 		configWriter := services.NewConfigWriter()
 		if err := configWriter.UpdateEmailSettings(emailConfig.Enabled, emailConfig.From, emailConfig.To,
 			emailConfig.SMTPHost, emailConfig.SMTPPort, emailConfig.Username, emailConfig.Password); err != nil {
 			http.Error(w, "Failed to update email settings: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		cfg.Email = email
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"message": "Email settings updated successfully. Please restart the application for changes to take effect.",
+			"message":           "Email settings saved and applied.",
+			"connection_tested": emailConfig.TestConnection,
 		})
 	}
 }
 
-// UpdateAlertSettings handles POST /api/v1/settings/alerts - updates alert configuration
+// UpdateAlertSettings handles POST /api/v1/settings/alerts - validates the
+// alert configuration, saves it and applies it without a restart
 func UpdateAlertSettings(cfg *config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Check if settings page is enabled
@@ -228,25 +312,31 @@ func UpdateAlertSettings(cfg *config.Config) http.HandlerFunc {
 			return
 		}
 
-		// Validate alert settings
-		if err := util.ValidatePositiveInt(alertConfig.LeadTimeDays, "lead_time_days"); err != nil {
+		// Validate alert settings: up to a year ahead, resent at most weekly
+		if err := util.ValidateRange(alertConfig.LeadTimeDays, 1, 365, "lead_time_days"); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		if err := util.ValidatePositiveInt(alertConfig.ResendIntervalMin, "resend_interval_min"); err != nil {
+		if err := util.ValidateRange(alertConfig.ResendIntervalMin, 1, 10080, "resend_interval_min"); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
+		settingsMu.Lock()
+		defer settingsMu.Unlock()
+
 		configWriter := services.NewConfigWriter()
 		if err := configWriter.UpdateAlertSettings(alertConfig.Enabled, alertConfig.LeadTimeDays, alertConfig.ResendIntervalMin); err != nil {
 			http.Error(w, "Failed to update alert settings: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		cfg.Alerts.Enabled = alertConfig.Enabled
+		cfg.Alerts.LeadTimeDays = alertConfig.LeadTimeDays
+		cfg.Alerts.ResendIntervalMin = alertConfig.ResendIntervalMin
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"message": "Alert settings updated successfully. Please restart the application for changes to take effect.",
+			"message": "Alert settings saved and applied.",
 		})
 	}
 }
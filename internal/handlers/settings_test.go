@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"licet/internal/config"
+)
+
+// withConfigFile runs the test in a directory holding a config.yaml, which
+// the settings handlers write to
+func withConfigFile(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	data := "server:\n  port: 8080\n  settings_enabled: true\ndatabase:\n  type: sqlite\n  database: licet.db\n"
+	if err := os.WriteFile("config.yaml", []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateAlertSettings_AppliesImmediately(t *testing.T) {
+	withConfigFile(t)
+	cfg := &config.Config{Server: config.ServerConfig{SettingsEnabled: true}}
+	handler := UpdateAlertSettings(cfg)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/v1/settings/alerts",
+		strings.NewReader(`{"enabled": true, "lead_time_days": 400, "resend_interval_min": 60}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an out-of-range lead time, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/v1/settings/alerts",
+		strings.NewReader(`{"enabled": true, "lead_time_days": 14, "resend_interval_min": 60}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !cfg.Alerts.Enabled || cfg.Alerts.LeadTimeDays != 14 || cfg.Alerts.ResendIntervalMin != 60 {
+		t.Errorf("Expected the settings to be applied, got %+v", cfg.Alerts)
+	}
+	data, _ := os.ReadFile("config.yaml")
+	if !strings.Contains(string(data), "lead_time_days: 14") {
+		t.Errorf("Expected the settings to be saved, got:\n%s", data)
+	}
+}
+
+func TestUpdateEmailSettings(t *testing.T) {
+	withConfigFile(t)
+	cfg := &config.Config{
+		Server: config.ServerConfig{SettingsEnabled: true},
+		Email:  config.EmailConfig{Password: "kept", Alerts: []string{"oncall@example.com"}},
+	}
+	handler := UpdateEmailSettings(cfg)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/api/v1/settings/email", strings.NewReader(body)))
+		return rec
+	}
+
+	for name, body := range map[string]string{
+		"missing recipients": `{"enabled": true, "from": "licet@example.com", "smtp_host": "smtp.example.com", "smtp_port": 587}`,
+		"host with port":     `{"enabled": true, "from": "licet@example.com", "to": ["a@example.com"], "smtp_host": "smtp.example.com:587", "smtp_port": 587}`,
+		"port zero":          `{"enabled": true, "from": "licet@example.com", "to": ["a@example.com"], "smtp_host": "smtp.example.com", "smtp_port": 0}`,
+	} {
+		if rec := post(body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+
+	// Nothing listens on a closed port, so the connection test fails
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	rec := post(`{"enabled": true, "from": "licet@example.com", "to": ["a@example.com"], "smtp_host": "127.0.0.1", "smtp_port": ` +
+		strconv.Itoa(port) + `, "test_connection": true}`)
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("Expected 502 for a failed connection test, got %d", rec.Code)
+	}
+	if cfg.Email.Enabled {
+		t.Error("Expected settings failing the connection test not to be applied")
+	}
+
+	rec = post(`{"enabled": true, "from": "licet@example.com", "to": ["a@example.com"], "smtp_host": "smtp.example.com", "smtp_port": 587}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !cfg.Email.Enabled || cfg.Email.SMTPHost != "smtp.example.com" || cfg.Email.Password != "kept" || len(cfg.Email.Alerts) != 1 {
+		t.Errorf("Expected the settings to be applied, keeping the password and alert recipients, got %+v", cfg.Email)
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/services"
+)
+
+// ListSilences handles GET /api/v1/alerts/silences
+func ListSilences(alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		silences, err := alertService.GetSilences(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"silences": silences,
+			"total":    len(silences),
+		})
+	}
+}
+
+// ReceiveSilences handles POST /api/v1/alerts/silences - accepts one
+// Alertmanager v2 silence or a list of them, e.g. pushed by ops tooling
+// whenever a silence is created or updated
+func ReceiveSilences(alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		silences, err := services.ParseAlertmanagerSilences(http.MaxBytesReader(w, r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := alertService.StoreSilences(r.Context(), silences, false); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Silences stored",
+			"stored":  len(silences),
+		})
+	}
+}
+
+// DeleteSilence handles DELETE /api/v1/alerts/silences/{id}
+func DeleteSilence(alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := alertService.DeleteSilence(r.Context(), chi.URLParam(r, "id"))
+		if errors.Is(err, services.ErrSilenceNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Silence deleted"})
+	}
+}
+
+// SyncSilences handles POST /api/v1/alerts/silences/sync - fetches the
+// silences of the configured Alertmanager now instead of at the next sync
+func SyncSilences(alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		count, err := alertService.SyncSilences(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":  "Silences synced",
+			"silences": count,
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// slackMaxSkew is how far the timestamp of a slash command may be from now
+// before it is rejected as a replay
+const slackMaxSkew = 5 * time.Minute
+
+// slackMaxUsers is how many users the who command lists per feature
+const slackMaxUsers = 20
+
+// slackUsage is the reply to unknown commands
+const slackUsage = "Usage:\n" +
+	"• `/licet status <feature>` - free seats of the features matching a name or vendor\n" +
+	"• `/licet who <feature>` - who holds the seats of those features"
+
+// SlackBot answers Slack slash commands such as "/licet status ansys" from
+// the latest collection of each server, so Slack queries add no load on the
+// license servers
+type SlackBot struct {
+	cfg          config.SlackConfig
+	bus          *services.EventBus
+	redactor     *services.Redactor
+	displayNames *services.DisplayNameService
+	now          func() time.Time
+
+	mu     sync.RWMutex
+	latest map[string]services.CollectionEvent
+}
+
+// NewSlackBot creates a Slack bot; Run keeps its view of the servers current
+func NewSlackBot(cfg config.SlackConfig, bus *services.EventBus, redactor *services.Redactor, displayNames *services.DisplayNameService) *SlackBot {
+	return &SlackBot{
+		cfg:          cfg,
+		bus:          bus,
+		redactor:     redactor,
+		displayNames: displayNames,
+		now:          time.Now,
+		latest:       make(map[string]services.CollectionEvent),
+	}
+}
+
+// Run records the collections of every server until ctx is done
+func (b *SlackBot) Run(ctx context.Context) {
+	collections, cancel := b.bus.SubscribeBuffered(services.CollectionsTopic, liveBuffer)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-collections:
+			b.collected(event.(services.CollectionEvent))
+		}
+	}
+}
+
+// collected records a collection. A failed collection keeps the seats of the
+// previous one, which are answered with the failure.
+func (b *SlackBot) collected(event services.CollectionEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if previous, ok := b.latest[event.Server]; ok && event.Error != "" {
+		previous.Error = event.Error
+		event = previous
+	}
+	b.latest[event.Server] = event
+}
+
+// verify checks the signature Slack computes over the timestamp and body of
+// each request with the signing secret of the app
+func (b *SlackBot) verify(r *http.Request, body []byte) bool {
+	timestamp := r.Header.Get("X-Slack-Request-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if skew := b.now().Sub(time.Unix(seconds, 0)); skew > slackMaxSkew || skew < -slackMaxSkew {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(b.cfg.SigningSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Slack-Signature")))
+}
+
+// role returns the role a Slack user queries with
+func (b *SlackBot) role(userID string) string {
+	// Viper lowercases map keys, so Slack's uppercase IDs are matched lowercased
+	if role, ok := b.cfg.Users[strings.ToLower(userID)]; ok {
+		return role
+	}
+	return b.cfg.DefaultRole
+}
+
+// slackFeature is a feature of the latest collection of its server
+type slackFeature struct {
+	models.Feature
+	event services.CollectionEvent
+}
+
+// features returns the features whose name, display name or vendor contains
+// term, sorted by server and name
+func (b *SlackBot) features(term string) []slackFeature {
+	term = strings.ToLower(term)
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var matches []slackFeature
+	for _, event := range b.latest {
+		features := slices.Clone(event.Result.Features)
+		b.displayNames.ApplyToFeatures(features)
+		for _, f := range features {
+			for _, name := range []string{f.Name, f.DisplayName, f.VendorDaemon, f.VendorName} {
+				if strings.Contains(strings.ToLower(name), term) {
+					matches = append(matches, slackFeature{Feature: f, event: event})
+					break
+				}
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].ServerHostname != matches[j].ServerHostname {
+			return matches[i].ServerHostname < matches[j].ServerHostname
+		}
+		return matches[i].Name < matches[j].Name
+	})
+	return matches
+}
+
+// label names a feature and, when it differs, its display name
+func (f slackFeature) label() string {
+	if f.DisplayName != "" && f.DisplayName != f.Name {
+		return fmt.Sprintf("*%s* (%s)", f.Name, f.DisplayName)
+	}
+	return "*" + f.Name + "*"
+}
+
+// stale notes a failed last collection of the feature's server
+func (f slackFeature) stale() string {
+	if f.event.Error == "" {
+		return ""
+	}
+	return fmt.Sprintf(" _(last collection failed, seats as of %s)_", f.event.CollectedAt.Format("2006-01-02 15:04"))
+}
+
+// status answers "status <feature>" with the free seats of the matching
+// features
+func (b *SlackBot) status(term string) string {
+	features := b.features(term)
+	if len(features) == 0 {
+		return fmt.Sprintf("No feature matches %q.", term)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Seats of the features matching %q:\n", term)
+	for _, f := range features {
+		free := max(f.TotalLicenses-f.UsedLicenses, 0)
+		fmt.Fprintf(&sb, "• %s on `%s`: %d of %d free%s\n", f.label(), f.ServerHostname, free, f.TotalLicenses, f.stale())
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// who answers "who <feature>" with the users of the matching features,
+// redacted unless the role is exempt from redaction
+func (b *SlackBot) who(term, role string) string {
+	features := b.features(term)
+	if len(features) == 0 {
+		return fmt.Sprintf("No feature matches %q.", term)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Users of the features matching %q:\n", term)
+	for _, f := range features {
+		var users []models.LicenseUser
+		for _, u := range f.event.Result.Users {
+			if u.FeatureName == f.Name {
+				users = append(users, u)
+			}
+		}
+		if b.redactor.Applies(role) {
+			users = b.redactor.Users(users)
+		}
+
+		fmt.Fprintf(&sb, "• %s on `%s` (%d of %d in use)%s", f.label(), f.ServerHostname, f.UsedLicenses, f.TotalLicenses, f.stale())
+		if len(users) == 0 {
+			sb.WriteString(": nobody\n")
+			continue
+		}
+		names := make([]string, 0, min(len(users), slackMaxUsers))
+		for _, u := range users[:min(len(users), slackMaxUsers)] {
+			name := u.Username
+			if u.Host != "" {
+				name += "@" + u.Host
+			}
+			names = append(names, name)
+		}
+		sb.WriteString(": " + strings.Join(names, ", "))
+		if len(users) > slackMaxUsers {
+			fmt.Fprintf(&sb, " and %d more", len(users)-slackMaxUsers)
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// SlackCommand handles POST /api/v1/slack/commands - answers the slash
+// commands of a Slack app. Requests are authenticated by their Slack
+// signature instead of the API's own authentication.
+func SlackCommand(bot *SlackBot) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 64<<10))
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if !bot.verify(r, body) {
+			http.Error(w, "Invalid Slack signature", http.StatusUnauthorized)
+			return
+		}
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		var reply string
+		fields := strings.Fields(form.Get("text"))
+		switch {
+		case len(bot.cfg.Teams) > 0 && !slices.Contains(bot.cfg.Teams, form.Get("team_id")):
+			reply = "This Slack workspace may not query licet."
+		case len(fields) < 2:
+			reply = slackUsage
+		default:
+			term := strings.Join(fields[1:], " ")
+			switch strings.ToLower(fields[0]) {
+			case "status":
+				reply = bot.status(term)
+			case "who":
+				reply = bot.who(term, bot.role(form.Get("user_id")))
+			default:
+				reply = slackUsage
+			}
+		}
+
+		log.WithFields(log.Fields{
+			"team": form.Get("team_id"),
+			"user": form.Get("user_id"),
+			"text": form.Get("text"),
+		}).Debug("Slack command")
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"response_type": "ephemeral",
+			"text":          reply,
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// slackRequest signs a slash command the way Slack does
+func slackRequest(secret string, at time.Time, form url.Values) *http.Request {
+	body := form.Encode()
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/slack/commands", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestSlackCommand(t *testing.T) {
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	redactor := services.NewRedactor(config.PrivacyConfig{RedactUsernames: true, Mode: "hash", ExemptRoles: []string{"admin"}})
+	bot := NewSlackBot(config.SlackConfig{
+		SigningSecret: "secret",
+		Teams:         []string{"T1"},
+		Users:         map[string]string{"uadmin": "admin"},
+		DefaultRole:   "readonly",
+	}, services.NewEventBus(), redactor, nil)
+	bot.now = func() time.Time { return now }
+
+	bot.collected(services.CollectionEvent{
+		Server: "27000@a",
+		Result: models.ServerQueryResult{
+			Features: []models.Feature{
+				{ServerHostname: "27000@a", Name: "ansys_solver", VendorDaemon: "ansyslmd", TotalLicenses: 10, UsedLicenses: 7},
+				{ServerHostname: "27000@a", Name: "matlab", VendorDaemon: "MLM", TotalLicenses: 5, UsedLicenses: 2},
+			},
+			Users: []models.LicenseUser{
+				{FeatureName: "matlab", Username: "alice", Host: "ws1"},
+				{FeatureName: "matlab", Username: "bob", Host: "ws2"},
+			},
+		},
+		CollectedAt: now.Add(-time.Minute),
+	})
+	bot.collected(services.CollectionEvent{
+		Server: "27000@b",
+		Result: models.ServerQueryResult{Features: []models.Feature{
+			{ServerHostname: "27000@b", Name: "mechanical", VendorDaemon: "ansyslmd", TotalLicenses: 4, UsedLicenses: 4},
+		}},
+		CollectedAt: now.Add(-time.Minute),
+	})
+	// A failed collection keeps the seats of the previous one
+	bot.collected(services.CollectionEvent{Server: "27000@b", Error: "connection refused", CollectedAt: now})
+
+	reply := func(t *testing.T, req *http.Request) (int, string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		SlackCommand(bot)(rec, req)
+		if rec.Code != http.StatusOK {
+			return rec.Code, ""
+		}
+		var resp struct {
+			ResponseType string `json:"response_type"`
+			Text         string `json:"text"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode reply: %v", err)
+		}
+		if resp.ResponseType != "ephemeral" {
+			t.Errorf("Expected an ephemeral reply, got %q", resp.ResponseType)
+		}
+		return rec.Code, resp.Text
+	}
+	command := func(user, text string) url.Values {
+		return url.Values{"team_id": {"T1"}, "user_id": {user}, "command": {"/licet"}, "text": {text}}
+	}
+
+	_, text := reply(t, slackRequest("secret", now, command("U1", "status ansys")))
+	if !strings.Contains(text, "*ansys_solver* on `27000@a`: 3 of 10 free") ||
+		!strings.Contains(text, "*mechanical* on `27000@b`: 0 of 4 free _(last collection failed") ||
+		strings.Contains(text, "matlab") {
+		t.Errorf("Unexpected status reply:\n%s", text)
+	}
+
+	_, text = reply(t, slackRequest("secret", now, command("U1", "who matlab")))
+	if !strings.Contains(text, "(2 of 5 in use)") || strings.Contains(text, "alice") {
+		t.Errorf("Expected redacted users, got:\n%s", text)
+	}
+	_, text = reply(t, slackRequest("secret", now, command("UADMIN", "who matlab")))
+	if !strings.Contains(text, "alice@ws1, bob@ws2") {
+		t.Errorf("Expected the users for an exempt role, got:\n%s", text)
+	}
+
+	if _, text = reply(t, slackRequest("secret", now, command("U1", "status nothing"))); !strings.Contains(text, "No feature matches") {
+		t.Errorf("Unexpected reply: %s", text)
+	}
+	if _, text = reply(t, slackRequest("secret", now, command("U1", "help"))); text != slackUsage {
+		t.Errorf("Expected the usage, got %s", text)
+	}
+	other := command("U1", "status ansys")
+	other.Set("team_id", "T2")
+	if _, text = reply(t, slackRequest("secret", now, other)); strings.Contains(text, "ansys_solver") {
+		t.Errorf("Expected other workspaces to be refused, got %s", text)
+	}
+
+	if code, _ := reply(t, slackRequest("wrong", now, command("U1", "status ansys"))); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a bad signature, got %d", code)
+	}
+	if code, _ := reply(t, slackRequest("secret", now.Add(-10*time.Minute), command("U1", "status ansys"))); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a replayed request, got %d", code)
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/services"
+)
+
+// maxSnapshotSize bounds uploaded snapshot archives
+const maxSnapshotSize = 1 << 30
+
+// ExportSnapshot handles GET /api/v1/admin/snapshot?server= - downloads the
+// full history of a server as an archive for import into another instance
+func ExportSnapshot(cfg *config.Config, storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// The archive holds usernames in clear
+		if cfg.Auth.Enabled && middleware.GetAuthInfo(r).Role != middleware.RoleAdmin {
+			http.Error(w, "Admin role required", http.StatusForbidden)
+			return
+		}
+
+		server := r.URL.Query().Get("server")
+		if server == "" {
+			http.Error(w, "server parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		name := strings.NewReplacer("@", "_", ":", "_", "/", "_").Replace(server)
+		filename := fmt.Sprintf("licet-snapshot-%s-%s.zip", name, time.Now().Format("20060102-150405"))
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+		// Headers are already sent once the archive is streaming, so failures
+		// can only be logged and leave a truncated archive that will not import
+		if _, err := storage.ExportSnapshot(r.Context(), server, w); err != nil {
+			log.Errorf("Failed to export snapshot of %s: %v", server, err)
+		}
+	}
+}
+
+// ImportSnapshot handles POST /api/v1/admin/snapshot?replace=false - restores
+// a server history archive sent as the request body
+func ImportSnapshot(cfg *config.Config, storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Server.SettingsEnabled {
+			http.Error(w, "Settings page is disabled", http.StatusForbidden)
+			return
+		}
+
+		info := middleware.GetAuthInfo(r)
+		if cfg.Auth.Enabled && info.Role != middleware.RoleAdmin {
+			http.Error(w, "Admin role required", http.StatusForbidden)
+			return
+		}
+
+		// Zip archives are read from their end, so spool the upload to disk
+		tmp, err := os.CreateTemp("", "licet-snapshot-*.zip")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		size, err := io.Copy(tmp, http.MaxBytesReader(w, r.Body, maxSnapshotSize))
+		if err != nil {
+			http.Error(w, "Failed to read archive: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		actor := info.Username
+		if actor == "" {
+			actor = "anonymous"
+		}
+
+		report, err := storage.ImportSnapshot(r.Context(), tmp, size, r.URL.Query().Get("replace") == "true", actor)
+		if errors.Is(err, services.ErrInvalidSnapshot) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, services.ErrSnapshotConflict) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		log.Infof("Imported snapshot of %s (%d rows) by %s", report.Server, report.TotalRows, actor)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// GetSystemInfo handles GET /api/v1/system/info - the build, database backend,
+// enabled subsystems and feature flags of this instance
+func GetSystemInfo(cfg *config.Config, build models.BuildInfo, flags *services.FlagService, startedAt time.Time) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		database := cfg.Database.Type
+		if database == "" {
+			database = "sqlite"
+		}
+
+		enabled := make(map[string]bool)
+		for _, flag := range flags.List() {
+			enabled[flag.Name] = flag.Enabled
+		}
+
+		info := models.SystemInfo{
+			BuildInfo:     build,
+			GoVersion:     runtime.Version(),
+			OS:            runtime.GOOS,
+			Arch:          runtime.GOARCH,
+			Database:      database,
+			Servers:       len(cfg.Servers),
+			Subsystems:    subsystems(cfg),
+			FeatureFlags:  enabled,
+			StartedAt:     startedAt,
+			UptimeSeconds: int64(time.Since(startedAt).Seconds()),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(info)
+	}
+}
+
+// subsystems reports which optional subsystems are enabled
+func subsystems(cfg *config.Config) map[string]bool {
+	return map[string]bool{
+		"alerts":      cfg.Alerts.Enabled,
+		"auth":        cfg.Auth.Enabled,
+		"cache":       cfg.Cache.Enabled,
+		"encryption":  cfg.Encryption.Enabled,
+		"export":      cfg.Export.Enabled,
+		"ingest":      cfg.Ingest.Enabled,
+		"metrics":     cfg.Metrics.Enabled,
+		"rate_limit":  cfg.RateLimit.Enabled,
+		"settings":    cfg.Server.SettingsEnabled,
+		"statistics":  cfg.Server.StatisticsEnabled,
+		"user_digest": cfg.UserDigest.Enabled,
+		"utilization": cfg.Server.UtilizationEnabled,
+		"webhooks":    len(cfg.Webhooks.Endpoints) > 0,
+		"websocket":   cfg.WebSocket.Enabled,
+	}
+}
+
+// GetDataQuality handles GET /api/v1/system/data-quality - collection gaps
+// per server, stale features, parse warnings and duplicate suspects
+func GetDataQuality(dataQuality *services.DataQualityService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		days := 7
+		if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 {
+			days = min(d, 90)
+		}
+		staleHours := 24
+		if h, err := strconv.Atoi(r.URL.Query().Get("stale_hours")); err == nil && h > 0 {
+			staleHours = h
+		}
+
+		report, err := dataQuality.Report(r.Context(), days, staleHours, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}
+}
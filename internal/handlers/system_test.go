@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+func TestGetSystemInfo(t *testing.T) {
+	cfg := &config.Config{
+		Database:     config.DatabaseConfig{Type: "postgres"},
+		Export:       config.ExportConfig{Enabled: true},
+		Servers:      []config.LicenseServer{{Hostname: "27000@lic1"}, {Hostname: "27000@lic2"}},
+		FeatureFlags: map[string]bool{services.FlagSnapshots: false},
+	}
+	build := models.BuildInfo{Version: "v1.2.3", Commit: "abc123", BuildDate: "2026-01-02T03:04:05Z"}
+
+	rec := httptest.NewRecorder()
+	GetSystemInfo(cfg, build, services.NewFlagService(nil, cfg), time.Now().Add(-time.Minute))(rec, httptest.NewRequest(http.MethodGet, "/api/v1/system/info", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	var info models.SystemInfo
+	if err := json.NewDecoder(rec.Body).Decode(&info); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if info.BuildInfo != build {
+		t.Errorf("Expected build %+v, got %+v", build, info.BuildInfo)
+	}
+	if info.Database != "postgres" || info.Servers != 2 || info.UptimeSeconds < 60 {
+		t.Errorf("Unexpected system info %+v", info)
+	}
+	if !info.Subsystems["export"] || info.Subsystems["websocket"] || info.Subsystems["auth"] {
+		t.Errorf("Unexpected subsystems %v", info.Subsystems)
+	}
+	if enabled, ok := info.FeatureFlags[services.FlagSnapshots]; !ok || enabled {
+		t.Errorf("Expected the configured snapshots flag to be off, got %v", info.FeatureFlags)
+	}
+}
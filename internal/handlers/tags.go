@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// taggedPaths are the cached API responses that can be filtered by tag
+var taggedPaths = []string{"/api/v1/utilization", "/api/v1/statistics"}
+
+// featureFilterer looks up the features carrying a tag on the servers of a group
+type featureFilterer interface {
+	FeatureFilter(ctx context.Context, tag, group string) (*services.FeatureSet, error)
+}
+
+// featureFilterParam returns the features carrying the tag of ?tag= on the
+// servers of the group of ?group=, or nil without either
+func featureFilterParam(r *http.Request, filterer featureFilterer) (*services.FeatureSet, error) {
+	return filterer.FeatureFilter(r.Context(), r.URL.Query().Get("tag"), r.URL.Query().Get("group"))
+}
+
+// filterFeatures keeps the items of the features in the set. A nil set keeps all items.
+func filterFeatures[T any](items []T, set *services.FeatureSet, keyOf func(T) (string, string)) []T {
+	if set == nil {
+		return items
+	}
+	filtered := make([]T, 0, len(items))
+	for _, item := range items {
+		if set.Contains(keyOf(item)) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+func featureKey(f models.Feature) (string, string) { return f.ServerHostname, f.Name }
+func utilizationKey(u models.UtilizationData) (string, string) {
+	return u.ServerHostname, u.FeatureName
+}
+func statsKey(s models.UtilizationStats) (string, string) { return s.ServerHostname, s.FeatureName }
+func heatmapKey(h models.HeatmapData) (string, string)    { return h.ServerHostname, h.FeatureName }
+func seasonalKey(p models.SeasonalPattern) (string, string) {
+	return p.ServerHostname, p.FeatureName
+}
+
+// ListTags handles GET /api/v1/tags - lists the tags with how many features
+// carry each
+func ListTags(storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tags, err := storage.GetTagSummaries(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"tags":  tags,
+			"total": len(tags),
+		})
+	}
+}
+
+// ListFeatureTags handles GET /api/v1/feature-tags - lists the tag
+// assignments, of one tag with ?tag=
+func ListFeatureTags(storage *services.StorageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tags, err := storage.GetFeatureTags(r.Context(), r.URL.Query().Get("tag"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"feature_tags": tags,
+			"total":        len(tags),
+		})
+	}
+}
+
+// TagFeature handles POST /api/v1/feature-tags - assigns a tag to a feature,
+// on one server or every server
+func TagFeature(cfg *config.Config, storage *services.StorageService, cache *middleware.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Server.SettingsEnabled {
+			http.Error(w, "Settings page is disabled", http.StatusForbidden)
+			return
+		}
+
+		var req struct {
+			ServerHostname string `json:"server_hostname"`
+			FeatureName    string `json:"feature_name"`
+			Tag            string `json:"tag"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		tag := models.FeatureTag{
+			ServerHostname: req.ServerHostname,
+			FeatureName:    req.FeatureName,
+			Tag:            req.Tag,
+			CreatedBy:      middleware.GetAuthInfo(r).Username,
+		}
+		err := storage.TagFeature(r.Context(), &tag)
+		if errors.Is(err, services.ErrInvalidFeatureTag) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, path := range taggedPaths {
+			cache.InvalidatePrefix(path)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(tag)
+	}
+}
+
+// UntagFeature handles DELETE /api/v1/feature-tags/{id} - removes a tag
+// assignment
+func UntagFeature(cfg *config.Config, storage *services.StorageService, cache *middleware.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Server.SettingsEnabled {
+			http.Error(w, "Settings page is disabled", http.StatusForbidden)
+			return
+		}
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid feature tag id", http.StatusBadRequest)
+			return
+		}
+
+		err = storage.UntagFeature(r.Context(), id)
+		if errors.Is(err, services.ErrFeatureTagNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, path := range taggedPaths {
+			cache.InvalidatePrefix(path)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Feature tag removed",
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// ListAlertThresholds handles GET /api/v1/alert-thresholds - lists the
+// utilization threshold overrides along with the global defaults
+func ListAlertThresholds(cfg *config.Config, alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		thresholds, err := alertService.GetAlertThresholds(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"thresholds": thresholds,
+			"total":      len(thresholds),
+			"defaults": map[string]interface{}{
+				"enabled":      cfg.Alerts.Utilization,
+				"warning_pct":  cfg.Alerts.UtilizationWarn,
+				"critical_pct": cfg.Alerts.UtilizationCrit,
+			},
+		})
+	}
+}
+
+// SetAlertThreshold handles PUT /api/v1/alert-thresholds - creates or
+// replaces the utilization thresholds of a feature, a server or a feature on
+// a server
+func SetAlertThreshold(cfg *config.Config, alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Server.SettingsEnabled {
+			http.Error(w, "Settings page is disabled", http.StatusForbidden)
+			return
+		}
+
+		var req struct {
+			ServerHostname string  `json:"server_hostname"`
+			FeatureName    string  `json:"feature_name"`
+			WarningPct     float64 `json:"warning_pct"`
+			CriticalPct    float64 `json:"critical_pct"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		threshold := models.AlertThreshold{
+			ServerHostname: strings.TrimSpace(req.ServerHostname),
+			FeatureName:    strings.TrimSpace(req.FeatureName),
+			WarningPct:     req.WarningPct,
+			CriticalPct:    req.CriticalPct,
+			UpdatedBy:      middleware.GetAuthInfo(r).Username,
+		}
+		err := alertService.SetAlertThreshold(r.Context(), threshold)
+		if errors.Is(err, services.ErrInvalidAlertThreshold) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":   "Alert threshold saved",
+			"threshold": threshold,
+		})
+	}
+}
+
+// DeleteAlertThreshold handles DELETE /api/v1/alert-thresholds?server=&feature=
+// - removes an override so the next less specific thresholds apply
+func DeleteAlertThreshold(cfg *config.Config, alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Server.SettingsEnabled {
+			http.Error(w, "Settings page is disabled", http.StatusForbidden)
+			return
+		}
+
+		server, feature := r.URL.Query().Get("server"), r.URL.Query().Get("feature")
+		if server == "" && feature == "" {
+			http.Error(w, "server or feature parameter required", http.StatusBadRequest)
+			return
+		}
+
+		err := alertService.DeleteAlertThreshold(r.Context(), server, feature)
+		if errors.Is(err, services.ErrAlertThresholdNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Alert threshold removed",
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+	"licet/internal/services"
+)
+
+const (
+	defaultWaitTimeout = 30 * time.Second
+	// maxWaitTimeout stays below the request timeout of the router
+	maxWaitTimeout = 55 * time.Second
+)
+
+// WaitForUpdate handles GET /api/v1/servers/{server}/wait-for-update -
+// long-polls until the next successful collection of the server and returns
+// the collected snapshot, or answers 204 when the timeout passes first
+// (?timeout=60s, or seconds; default 30s, at most 55s)
+func WaitForUpdate(cfg *config.Config, bus *services.EventBus, redactor *services.Redactor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server := serverParam(r)
+		if !serverConfigured(cfg, server) {
+			http.Error(w, "Server not found", http.StatusNotFound)
+			return
+		}
+
+		timeout := defaultWaitTimeout
+		if s := r.URL.Query().Get("timeout"); s != "" {
+			d, err := parseWaitTimeout(s)
+			if err != nil || d <= 0 {
+				http.Error(w, "Invalid timeout, expected a duration such as 60s", http.StatusBadRequest)
+				return
+			}
+			timeout = min(d, maxWaitTimeout)
+		}
+
+		// The server's write timeout is shorter than a long poll
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + 5*time.Second)); err != nil {
+			log.Debugf("Failed to extend write deadline for long poll: %v", err)
+		}
+
+		events, cancel := bus.Subscribe(services.CollectionTopic(server))
+		defer cancel()
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case event := <-events:
+			collection := event.(services.CollectionEvent)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"server":       collection.Server,
+				"collected_at": collection.CollectedAt,
+				"status":       collection.Result.Status,
+				"features":     collection.Result.Features,
+				"users":        redactorFor(r, redactor).Users(collection.Result.Users),
+			})
+		case <-timer.C:
+			w.WriteHeader(http.StatusNoContent)
+		case <-r.Context().Done():
+		}
+	}
+}
+
+// parseWaitTimeout accepts a Go duration or a number of seconds
+func parseWaitTimeout(s string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(s); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func serverConfigured(cfg *config.Config, hostname string) bool {
+	for _, srv := range cfg.Servers {
+		if srv.Hostname == hostname {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+func TestWaitForUpdate(t *testing.T) {
+	cfg := &config.Config{Servers: []config.LicenseServer{{Hostname: "27000@lic1"}}}
+	bus := services.NewEventBus()
+	r := chi.NewRouter()
+	r.Get("/servers/{server}/wait-for-update", WaitForUpdate(cfg, bus, nil))
+
+	t.Run("collection", func(t *testing.T) {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			// Publish until the request has subscribed and returned
+			for {
+				select {
+				case <-done:
+					return
+				case <-time.After(10 * time.Millisecond):
+					bus.Publish(services.CollectionTopic("27000@lic1"), services.CollectionEvent{
+						Server:      "27000@lic1",
+						Result:      models.ServerQueryResult{Features: []models.Feature{{Name: "matlab", TotalLicenses: 10}}},
+						CollectedAt: time.Now(),
+					})
+				}
+			}
+		}()
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/servers/27000@lic1/wait-for-update?timeout=5s", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rec.Code)
+		}
+		var resp struct {
+			Server   string           `json:"server"`
+			Features []models.Feature `json:"features"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.Server != "27000@lic1" || len(resp.Features) != 1 || resp.Features[0].Name != "matlab" {
+			t.Errorf("Unexpected snapshot %+v", resp)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/servers/27000@lic1/wait-for-update?timeout=50ms", nil))
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("Expected 204, got %d", rec.Code)
+		}
+	})
+
+	for _, tc := range []struct {
+		path string
+		code int
+	}{
+		{"/servers/27000@other/wait-for-update", http.StatusNotFound},
+		{"/servers/27000@lic1/wait-for-update?timeout=soon", http.StatusBadRequest},
+		{"/servers/27000@lic1/wait-for-update?timeout=-1", http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d", tc.path, tc.code, rec.Code)
+		}
+	}
+}
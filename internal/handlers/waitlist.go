@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/config"
+	"licet/internal/middleware"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// waitlistUser returns the user a waitlist request acts for, and whether it
+// may act for everyone. Without auth every request may, naming the user in
+// the request.
+func waitlistUser(cfg *config.Config, r *http.Request, requested string) (string, bool, bool) {
+	if !cfg.Auth.Enabled {
+		return requested, true, true
+	}
+	info := middleware.GetAuthInfo(r)
+	if !info.Authenticated || info.Username == "" {
+		return "", false, false
+	}
+	return info.Username, info.Role == middleware.RoleAdmin, true
+}
+
+// ListWaitlist handles GET /api/v1/waitlist - lists the waitlist
+// subscriptions of the requesting user, or of everyone with all=true for
+// admins
+func ListWaitlist(cfg *config.Config, alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, admin, ok := waitlistUser(cfg, r, r.URL.Query().Get("username"))
+		if !ok {
+			http.Error(w, "Sign in to use the waitlist", http.StatusUnauthorized)
+			return
+		}
+		if admin && r.URL.Query().Get("all") == "true" {
+			username = ""
+		}
+
+		subs, err := alertService.GetWaitlist(r.Context(), username)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"subscriptions": subs,
+			"total":         len(subs),
+		})
+	}
+}
+
+// JoinWaitlist handles POST /api/v1/waitlist - subscribes the requesting
+// user to be emailed once when a seat of a feature frees up
+func JoinWaitlist(cfg *config.Config, alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Username       string `json:"username"` // Without auth only
+			Email          string `json:"email"`
+			ServerHostname string `json:"server_hostname"`
+			FeatureName    string `json:"feature_name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		username, _, ok := waitlistUser(cfg, r, req.Username)
+		if !ok {
+			http.Error(w, "Sign in to use the waitlist", http.StatusUnauthorized)
+			return
+		}
+
+		sub, err := alertService.Subscribe(r.Context(), models.WaitlistSubscription{
+			Username:       username,
+			Email:          req.Email,
+			ServerHostname: req.ServerHostname,
+			FeatureName:    req.FeatureName,
+		})
+		if errors.Is(err, services.ErrInvalidWaitlist) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, services.ErrWaitlistFull) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(sub)
+	}
+}
+
+// LeaveWaitlist handles DELETE /api/v1/waitlist/{id} - removes a
+// subscription of the requesting user, or of anyone for admins
+func LeaveWaitlist(cfg *config.Config, alertService *services.AlertService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid subscription ID", http.StatusBadRequest)
+			return
+		}
+		username, admin, ok := waitlistUser(cfg, r, "")
+		if !ok {
+			http.Error(w, "Sign in to use the waitlist", http.StatusUnauthorized)
+			return
+		}
+		if admin {
+			username = ""
+		}
+
+		err = alertService.Unsubscribe(r.Context(), id, username)
+		if errors.Is(err, services.ErrWaitlistNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Left the waitlist",
+		})
+	}
+}
@@ -1,8 +1,8 @@
 package handlers
 
 import (
-	"html/template"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -17,21 +17,25 @@ type WebHandler struct {
 	query        *services.QueryService
 	storage      *services.StorageService
 	analytics    *services.AnalyticsService
+	names        *services.DisplayNameService
 	alertService *services.AlertService
+	redactor     *services.Redactor
 	cfg          *config.Config
-	templates    *template.Template
+	templates    *web.Templates
 	version      string
 }
 
-func NewWebHandler(query *services.QueryService, storage *services.StorageService, analytics *services.AnalyticsService, alertService *services.AlertService, cfg *config.Config, version string) *WebHandler {
-	// Load templates from embedded filesystem via web package
-	tmpl := web.LoadTemplates()
+func NewWebHandler(query *services.QueryService, storage *services.StorageService, analytics *services.AnalyticsService, names *services.DisplayNameService, alertService *services.AlertService, redactor *services.Redactor, cfg *config.Config, version string) *WebHandler {
+	// Load templates from the embedded filesystem and the override directory
+	tmpl := web.LoadTemplates(cfg.Server.TemplatesDir, cfg.Server.DevMode)
 
 	return &WebHandler{
 		query:        query,
 		storage:      storage,
 		analytics:    analytics,
+		names:        names,
 		alertService: alertService,
+		redactor:     redactor,
 		cfg:          cfg,
 		templates:    tmpl,
 		version:      version,
@@ -46,6 +50,7 @@ func (h *WebHandler) baseData(title string) map[string]interface{} {
 		"StatisticsEnabled":  h.cfg.Server.StatisticsEnabled,
 		"SettingsEnabled":    h.cfg.Server.SettingsEnabled,
 		"Version":            h.version,
+		"Brand":              h.cfg.Branding,
 	}
 }
 
@@ -140,6 +145,7 @@ func (h *WebHandler) Details(w http.ResponseWriter, r *http.Request) {
 		data["Users"] = []interface{}{}
 		data["Error"] = err.Error()
 		data["LastUpdated"] = lastUpdated
+		data["Failovers"] = h.failovers(r, hostname)
 
 		h.render(w, "details.html", data)
 		return
@@ -148,12 +154,23 @@ func (h *WebHandler) Details(w http.ResponseWriter, r *http.Request) {
 	data := h.baseData("Server Details")
 	data["Hostname"] = hostname
 	data["Features"] = result.Features
-	data["Users"] = result.Users
+	data["Users"] = redactorFor(r, h.redactor).Users(result.Users)
 	data["LastUpdated"] = time.Now() // Data was just fetched live
+	data["Failovers"] = h.failovers(r, hostname)
 
 	h.render(w, "details.html", data)
 }
 
+// failovers returns the recent MASTER failovers of a server for display
+func (h *WebHandler) failovers(r *http.Request, hostname string) []models.MasterChange {
+	failovers, err := h.storage.GetMasterChanges(r.Context(), hostname, 90)
+	if err != nil {
+		log.Errorf("Failed to get failovers for %s: %v", hostname, err)
+		return nil
+	}
+	return failovers
+}
+
 func (h *WebHandler) Expiration(w http.ResponseWriter, r *http.Request) {
 	hostname := chi.URLParam(r, "server")
 
@@ -190,6 +207,11 @@ func (h *WebHandler) Utilization(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.URL.Query().Get("view") == "table" {
+		h.utilizationTable(w, r)
+		return
+	}
+
 	data := h.baseData("License Utilization Overview")
 	h.render(w, "utilization_overview.html", data)
 }
@@ -200,10 +222,136 @@ func (h *WebHandler) UtilizationTrends(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.URL.Query().Get("view") == "table" {
+		h.trendsTable(w, r)
+		return
+	}
+
 	data := h.baseData("Usage Trends")
 	h.render(w, "utilization_trends.html", data)
 }
 
+// utilizationTableRow is a row of the server-rendered utilization tables
+type utilizationTableRow struct {
+	Server    string
+	Feature   string
+	Total     int
+	Used      int // Peak usage in the trends table
+	Available int
+	Min       int
+	Average   float64
+	Pct       float64
+}
+
+// utilizationPeriods are the periods of the trends table, as in the charts
+var utilizationPeriods = []struct{ Value, Label string }{
+	{"7d", "last 7 days"},
+	{"30d", "last 30 days"},
+	{"90d", "last 90 days"},
+	{"1y", "last year"},
+}
+
+// tableData returns the template data shared by the utilization tables
+func (h *WebHandler) tableData(r *http.Request, title, view string) map[string]interface{} {
+	data := h.baseData(title)
+	data["View"] = view
+	data["Server"] = r.URL.Query().Get("server")
+
+	var servers []string
+	if configured, err := h.query.GetAllServers(); err == nil {
+		for _, s := range configured {
+			servers = append(servers, s.Hostname)
+		}
+	}
+	data["Servers"] = servers
+	return data
+}
+
+// utilizationTable handles GET /utilization?view=table - current utilization
+// rendered on the server, for screen readers and browsers without JavaScript
+func (h *WebHandler) utilizationTable(w http.ResponseWriter, r *http.Request) {
+	data := h.tableData(r, "License Utilization", "current")
+
+	utilization, err := h.analytics.GetCurrentUtilization(r.Context(), r.URL.Query().Get("server"))
+	if err != nil {
+		http.Error(w, "Failed to get utilization", http.StatusInternalServerError)
+		return
+	}
+	if h.names != nil {
+		h.names.ApplyToUtilization(utilization)
+	}
+	sort.SliceStable(utilization, func(i, j int) bool {
+		return utilization[i].UtilizationPct > utilization[j].UtilizationPct
+	})
+
+	rows := make([]utilizationTableRow, 0, len(utilization))
+	for _, u := range utilization {
+		feature := u.FeatureName
+		if u.DisplayName != "" {
+			feature = u.DisplayName
+		}
+		rows = append(rows, utilizationTableRow{
+			Server:    u.ServerHostname,
+			Feature:   feature,
+			Total:     u.TotalLicenses,
+			Used:      u.UsedLicenses,
+			Available: u.AvailableLicenses,
+			Pct:       u.UtilizationPct,
+		})
+	}
+	data["Rows"] = rows
+	h.render(w, "utilization_table.html", data)
+}
+
+// trendsTable handles GET /utilization/trends?view=table - usage statistics
+// of a period rendered on the server
+func (h *WebHandler) trendsTable(w http.ResponseWriter, r *http.Request) {
+	data := h.tableData(r, "Usage Trends", "trends")
+
+	period := r.URL.Query().Get("period")
+	data["Period"] = "7d"
+	data["PeriodLabel"] = utilizationPeriods[0].Label
+	for _, p := range utilizationPeriods {
+		if p.Value == period {
+			data["Period"] = p.Value
+			data["PeriodLabel"] = p.Label
+		}
+	}
+	data["Periods"] = utilizationPeriods
+
+	stats, err := h.analytics.GetUtilizationStats(r.Context(), r.URL.Query().Get("server"), periodDays(period))
+	if err != nil {
+		http.Error(w, "Failed to get utilization statistics", http.StatusInternalServerError)
+		return
+	}
+	if h.names != nil {
+		h.names.ApplyToStats(stats)
+	}
+
+	rows := make([]utilizationTableRow, 0, len(stats))
+	for _, s := range stats {
+		feature := s.FeatureName
+		if s.DisplayName != "" {
+			feature = s.DisplayName
+		}
+		row := utilizationTableRow{
+			Server:  s.ServerHostname,
+			Feature: feature,
+			Total:   s.TotalLicenses,
+			Used:    s.PeakUsage,
+			Min:     s.MinUsage,
+			Average: s.AvgUsage,
+		}
+		if s.TotalLicenses > 0 {
+			row.Pct = float64(s.PeakUsage) / float64(s.TotalLicenses) * 100
+		}
+		rows = append(rows, row)
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Pct > rows[j].Pct })
+	data["Rows"] = rows
+	h.render(w, "utilization_table.html", data)
+}
+
 func (h *WebHandler) UtilizationAnalytics(w http.ResponseWriter, r *http.Request) {
 	if !h.cfg.Server.UtilizationEnabled {
 		http.Error(w, "Utilization page is disabled", http.StatusForbidden)
@@ -237,8 +385,26 @@ func (h *WebHandler) Alerts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	incidents, err := h.alertService.GetIncidents(r.Context(), 30)
+	if err != nil {
+		log.Warnf("Failed to get incidents: %v", err)
+	}
+	index := make(map[int64]int, len(incidents))
+	for i, incident := range incidents {
+		index[incident.ID] = i
+	}
+	for _, alert := range alerts {
+		if alert.IncidentID == nil {
+			continue
+		}
+		if i, ok := index[*alert.IncidentID]; ok {
+			incidents[i].Alerts = append(incidents[i].Alerts, alert)
+		}
+	}
+
 	data := h.baseData("License Alerts")
 	data["Alerts"] = alerts
+	data["Incidents"] = incidents
 
 	h.render(w, "alerts.html", data)
 }
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/services"
+)
+
+func TestWebHandler_UtilizationTable(t *testing.T) {
+	db := newTestDB(t)
+	cfg := &config.Config{
+		Server:  config.ServerConfig{UtilizationEnabled: true},
+		Servers: []config.LicenseServer{{Hostname: "27000@a", Type: "flexlm"}},
+	}
+	storage := services.NewStorageService(db, "sqlite")
+	h := NewWebHandler(services.NewQueryService(cfg, storage), storage, services.NewAnalyticsService(db, storage, "sqlite"), nil, nil, nil, cfg, "test")
+
+	now := time.Now()
+	insert := `INSERT INTO features (server_hostname, name, version, vendor_daemon, total_licenses, used_licenses, expiration_date, last_updated)
+		VALUES (?, ?, '', 'vendord', ?, ?, ?, ?)`
+	db.MustExec(insert, "27000@a", "mesher", 10, 2, now.AddDate(1, 0, 0), now)
+	db.MustExec(insert, "27000@a", "solver", 10, 10, now.AddDate(1, 0, 0), now)
+	for _, used := range []int{4, 8} {
+		db.MustExec(`INSERT INTO feature_usage (server_hostname, feature_name, date, time, users_count) VALUES (?, ?, ?, ?, ?)`,
+			"27000@a", "solver", now.Format("2006-01-02"), now.Add(-time.Duration(used)*time.Minute).Format("15:04:05"), used)
+	}
+
+	rec := httptest.NewRecorder()
+	h.Utilization(rec, httptest.NewRequest(http.MethodGet, "/utilization?view=table", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "<caption>Current usage of 2 feature(s)</caption>") || strings.Contains(body, "<script") {
+		t.Errorf("Expected a script-free table of 2 features, got:\n%s", body)
+	}
+	if solver, mesher := strings.Index(body, ">solver<"), strings.Index(body, ">mesher<"); solver < 0 || mesher < solver {
+		t.Error("Expected the fully used feature first")
+	}
+	if !strings.Contains(body, "100.0% (high)") {
+		t.Error("Expected high utilization to be marked in text")
+	}
+
+	rec = httptest.NewRecorder()
+	h.UtilizationTrends(rec, httptest.NewRequest(http.MethodGet, "/utilization/trends?view=table&period=30d", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	body = rec.Body.String()
+	if !strings.Contains(body, "over the last 30 days</caption>") || !strings.Contains(body, `<option value="30d" selected>`) ||
+		!strings.Contains(body, ">solver<") {
+		t.Errorf("Expected the 30 day statistics, got:\n%s", body)
+	}
+}
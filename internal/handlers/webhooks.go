@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"licet/internal/services"
+)
+
+// ListWebhookDeliveries handles GET /api/v1/webhooks/deliveries - lists recent
+// webhook delivery attempts for debugging failed deliveries. Accepts
+// ?webhook=name, ?failed=true and ?limit=N (default 100, at most 1000).
+func ListWebhookDeliveries(webhooks *services.WebhookService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := 100
+		if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+			limit = l
+		}
+		if limit > 1000 {
+			limit = 1000
+		}
+
+		deliveries, err := webhooks.GetDeliveries(r.Context(), r.URL.Query().Get("webhook"),
+			r.URL.Query().Get("failed") == "true", limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"deliveries": deliveries,
+			"total":      len(deliveries),
+		})
+	}
+}
@@ -4,22 +4,24 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 	log "github.com/sirupsen/logrus"
-	"licet/internal/services"
 )
 
 // WebSocketConfig holds WebSocket configuration
 type WebSocketConfig struct {
-	Enabled         bool `mapstructure:"enabled"`
-	PingInterval    int  `mapstructure:"ping_interval"`   // Seconds
-	UpdateInterval  int  `mapstructure:"update_interval"` // Seconds for server status updates
-	MaxConnections  int  `mapstructure:"max_connections"`
-	ReadBufferSize  int  `mapstructure:"read_buffer_size"`
-	WriteBufferSize int  `mapstructure:"write_buffer_size"`
+	Enabled         bool     `mapstructure:"enabled"`
+	PingInterval    int      `mapstructure:"ping_interval"`   // Seconds
+	UpdateInterval  int      `mapstructure:"update_interval"` // Seconds for server status updates
+	MaxConnections  int      `mapstructure:"max_connections"`
+	ReadBufferSize  int      `mapstructure:"read_buffer_size"`
+	WriteBufferSize int      `mapstructure:"write_buffer_size"`
+	AllowedOrigins  []string // Origins of the pages that may connect besides the server's own; * allows any
 }
 
 // DefaultWebSocketConfig returns default WebSocket configuration
@@ -40,6 +42,7 @@ const (
 	MsgTypeFeatureUpdate = "feature_update"
 	MsgTypeUserCheckout  = "user_checkout"
 	MsgTypeAlert         = "alert"
+	MsgTypeAudit         = "audit"
 	MsgTypeSubscribe     = "subscribe"
 	MsgTypeUnsubscribe   = "unsubscribe"
 	MsgTypePing          = "ping"
@@ -65,23 +68,21 @@ type Client struct {
 	hub           *WebSocketHub
 	conn          *websocket.Conn
 	send          chan []byte
+	role          string // Empty when authentication is disabled
 	subscriptions map[string]bool
 	mu            sync.RWMutex
 }
 
 // WebSocketHub manages all WebSocket connections
 type WebSocketHub struct {
-	clients      map[*Client]bool
-	broadcast    chan []byte
-	register     chan *Client
-	unregister   chan *Client
-	mu           sync.RWMutex
-	config       WebSocketConfig
-	query        *services.QueryService
-	storage      *services.StorageService
-	alertService *services.AlertService
-	ctx          context.Context
-	cancel       context.CancelFunc
+	clients    map[*Client]bool
+	register   chan *Client
+	unregister chan *Client
+	mu         sync.RWMutex
+	config     WebSocketConfig
+	feed       *LiveFeed
+	ctx        context.Context
+	cancel     context.CancelFunc
 }
 
 // newUpgrader creates a per-call upgrader to avoid data races on global state
@@ -90,36 +91,61 @@ func newUpgrader(config WebSocketConfig) websocket.Upgrader {
 		ReadBufferSize:  config.ReadBufferSize,
 		WriteBufferSize: config.WriteBufferSize,
 		CheckOrigin: func(r *http.Request) bool {
-			// In production, you should validate the origin
-			return true
+			return allowedOrigin(config.AllowedOrigins, r)
 		},
 	}
 }
 
-// NewWebSocketHub creates a new WebSocket hub
-func NewWebSocketHub(config WebSocketConfig, query *services.QueryService, storage *services.StorageService, alertService *services.AlertService) *WebSocketHub {
+// allowedOrigin reports whether a WebSocket may be opened from the page of
+// the request's origin: the server itself or one of the allowed origins.
+// Clients other than browsers send no origin.
+func allowedOrigin(allowed []string, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, o := range allowed {
+		if o == "*" || strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewWebSocketHub creates a new WebSocket hub delivering the updates of a
+// live feed
+func NewWebSocketHub(config WebSocketConfig, feed *LiveFeed) *WebSocketHub {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	hub := &WebSocketHub{
-		clients:      make(map[*Client]bool),
-		broadcast:    make(chan []byte, 256),
-		register:     make(chan *Client),
-		unregister:   make(chan *Client),
-		config:       config,
-		query:        query,
-		storage:      storage,
-		alertService: alertService,
-		ctx:          ctx,
-		cancel:       cancel,
+		clients:    make(map[*Client]bool),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		config:     config,
+		feed:       feed,
+		ctx:        ctx,
+		cancel:     cancel,
 	}
 
 	return hub
 }
 
+// Feed returns the live feed the hub delivers
+func (h *WebSocketHub) Feed() *LiveFeed {
+	return h.feed
+}
+
 // Run starts the WebSocket hub
 func (h *WebSocketHub) Run() {
-	// Start the status update broadcaster
-	go h.statusBroadcaster()
+	messages, cancel := h.feed.subscribe()
+	defer cancel()
 
 	for {
 		select {
@@ -145,17 +171,9 @@ func (h *WebSocketHub) Run() {
 			}
 			h.mu.Unlock()
 
-		case message := <-h.broadcast:
-			h.mu.RLock()
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
-				}
-			}
-			h.mu.RUnlock()
+		case event := <-messages:
+			m := event.(liveMessage)
+			h.deliver(m.channel, m.msg)
 		}
 	}
 }
@@ -170,53 +188,14 @@ func (h *WebSocketHub) Stop() {
 	h.mu.Unlock()
 }
 
-// statusBroadcaster periodically broadcasts server status updates
-func (h *WebSocketHub) statusBroadcaster() {
-	ticker := time.NewTicker(time.Duration(h.config.UpdateInterval) * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-h.ctx.Done():
-			return
-		case <-ticker.C:
-			h.broadcastServerStatus()
-		}
-	}
-}
-
-// broadcastServerStatus sends current server status to all subscribed clients
-func (h *WebSocketHub) broadcastServerStatus() {
-	servers, err := h.query.GetAllServers()
-	if err != nil {
-		log.WithError(err).Error("Failed to get servers for WebSocket broadcast")
-		return
-	}
-
-	for _, server := range servers {
-		result, err := h.query.QueryServer(server.Hostname, server.Type)
-		if err != nil {
-			continue
-		}
-
-		msg := WebSocketMessage{
-			Type:      MsgTypeServerStatus,
-			Timestamp: time.Now(),
-			Data: map[string]interface{}{
-				"server":   server.Hostname,
-				"type":     server.Type,
-				"status":   result.Status,
-				"features": result.Features,
-				"users":    result.Users,
-			},
-		}
-
-		h.BroadcastToChannel("server:"+server.Hostname, msg)
-	}
+// BroadcastToChannel sends a message to the live clients subscribed to a
+// specific channel
+func (h *WebSocketHub) BroadcastToChannel(channel string, msg WebSocketMessage) {
+	h.feed.Publish(channel, msg)
 }
 
-// BroadcastToChannel sends a message to clients subscribed to a specific channel
-func (h *WebSocketHub) BroadcastToChannel(channel string, msg WebSocketMessage) {
+// deliver sends a message to the connected clients subscribed to its channel
+func (h *WebSocketHub) deliver(channel string, msg WebSocketMessage) {
 	data, err := json.Marshal(msg)
 	if err != nil {
 		log.WithError(err).Error("Failed to marshal WebSocket message")
@@ -228,7 +207,7 @@ func (h *WebSocketHub) BroadcastToChannel(channel string, msg WebSocketMessage)
 
 	for client := range h.clients {
 		client.mu.RLock()
-		subscribed := client.subscriptions[channel] || client.subscriptions["all"]
+		subscribed := subscribedTo(client.subscriptions, channel) && h.feed.mayReceive(client.role, channel)
 		client.mu.RUnlock()
 
 		if subscribed {
@@ -241,25 +220,14 @@ func (h *WebSocketHub) BroadcastToChannel(channel string, msg WebSocketMessage)
 	}
 }
 
-// BroadcastAlert sends an alert to all clients subscribed to alerts
+// BroadcastAlert sends an alert to all live clients subscribed to alerts
 func (h *WebSocketHub) BroadcastAlert(alert interface{}) {
-	msg := WebSocketMessage{
-		Type:      MsgTypeAlert,
-		Timestamp: time.Now(),
-		Data:      alert,
-	}
-	h.BroadcastToChannel("alerts", msg)
+	h.feed.PublishAlert(alert)
 }
 
-// BroadcastAll sends a message to all connected clients
+// BroadcastAll sends a message to all live clients
 func (h *WebSocketHub) BroadcastAll(msg WebSocketMessage) {
-	data, err := json.Marshal(msg)
-	if err != nil {
-		log.WithError(err).Error("Failed to marshal WebSocket message")
-		return
-	}
-
-	h.broadcast <- data
+	h.feed.Publish("", msg)
 }
 
 // GetClientCount returns the number of connected clients
@@ -269,8 +237,16 @@ func (h *WebSocketHub) GetClientCount() int {
 	return len(h.clients)
 }
 
-// HandleWebSocket handles WebSocket connections
+// HandleWebSocket handles WebSocket connections. With authentication enabled
+// clients authenticate like API requests, with an API key (the api_key query
+// parameter for browsers) or a sign-in session, before the upgrade.
 func (h *WebSocketHub) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	role, ok := h.feed.viewer(r)
+	if !ok {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
 	// Upgrade HTTP connection to WebSocket
 	upgrader := newUpgrader(h.config)
 	conn, err := upgrader.Upgrade(w, r, nil)
@@ -283,6 +259,7 @@ func (h *WebSocketHub) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 		hub:           h,
 		conn:          conn,
 		send:          make(chan []byte, 256),
+		role:          role,
 		subscriptions: make(map[string]bool),
 	}
 
@@ -405,10 +382,17 @@ func (c *Client) handleMessage(msg WebSocketMessage) {
 func (c *Client) handleSubscribe(msg WebSocketMessage) {
 	if data, ok := msg.Data.(map[string]interface{}); ok {
 		if channels, ok := data["channels"].([]interface{}); ok {
+			subscribed := []string{}
+			denied := []string{}
 			c.mu.Lock()
 			for _, ch := range channels {
 				if channel, ok := ch.(string); ok {
+					if !c.hub.feed.mayReceive(c.role, channel) {
+						denied = append(denied, channel)
+						continue
+					}
 					c.subscriptions[channel] = true
+					subscribed = append(subscribed, channel)
 					log.WithField("channel", channel).Debug("Client subscribed to channel")
 				}
 			}
@@ -419,9 +403,12 @@ func (c *Client) handleSubscribe(msg WebSocketMessage) {
 				Type:      "subscribed",
 				Timestamp: time.Now(),
 				Data: map[string]interface{}{
-					"channels": channels,
+					"channels": subscribed,
 				},
 			}
+			if len(denied) > 0 {
+				response.Error = "Not permitted for role " + c.role + ": " + strings.Join(denied, ", ")
+			}
 			if respData, err := json.Marshal(response); err == nil {
 				c.send <- respData
 			}
@@ -466,6 +453,7 @@ func WebSocketStatsHandler(hub *WebSocketHub) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		stats := map[string]interface{}{
 			"connected_clients": hub.GetClientCount(),
+			"stream_clients":    hub.feed.StreamCount(),
 			"max_connections":   hub.config.MaxConnections,
 			"update_interval":   hub.config.UpdateInterval,
 			"ping_interval":     hub.config.PingInterval,
@@ -0,0 +1,242 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+	"licet/internal/models"
+)
+
+const (
+	// auditBodyLimit is the part of a request body read for the payload
+	// summary; the handler still receives the whole body
+	auditBodyLimit = 64 << 10
+
+	// auditSummaryLength bounds the stored payload summary
+	auditSummaryLength = 1024
+)
+
+// auditPrivatePaths are the paths whose requests are recorded without their
+// payload, as it names the users the request is about
+var auditPrivatePaths = []string{"/api/v1/admin/anonymize", "/api/v1/waitlist"}
+
+// AuditRecorder stores audit entries
+type AuditRecorder interface {
+	Record(ctx context.Context, entry models.AuditEntry) error
+}
+
+// auditResponseWriter captures the status of the response
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *auditResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// AuditMiddleware records POST, PUT, PATCH and DELETE requests to the API
+// with the authenticated user, the client address, a summary of the payload
+// with secrets and usernames and email addresses redacted and the response
+// status. It must run after the
+// authentication middleware. Paths starting with one of excludePaths are not
+// recorded.
+func AuditMiddleware(recorder AuditRecorder, excludePaths []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !audited(r, excludePaths) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var payload []byte
+			if r.Body != nil && !privatePath(r.URL.Path) {
+				payload, _ = io.ReadAll(io.LimitReader(r.Body, auditBodyLimit+1))
+				r.Body = readCloser{io.MultiReader(bytes.NewReader(payload), r.Body), r.Body}
+			}
+
+			start := time.Now()
+			rw := &auditResponseWriter{ResponseWriter: w}
+			next.ServeHTTP(rw, r)
+
+			info := GetAuthInfo(r)
+			entry := models.AuditEntry{
+				CreatedAt:  start,
+				Actor:      info.Username,
+				Action:     models.AuditActionRequest,
+				Details:    summarizePayload(r.Header.Get("Content-Type"), payload),
+				Role:       info.Role,
+				RemoteAddr: clientAddr(r),
+				Method:     r.Method,
+				Path:       redactedURI(r.URL),
+				Status:     rw.status,
+				DurationMS: time.Since(start).Milliseconds(),
+			}
+			if entry.Actor == "" {
+				entry.Actor = "anonymous"
+			}
+			if entry.Status == 0 {
+				entry.Status = http.StatusOK
+			}
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				entry.Target = rctx.RoutePattern()
+			}
+
+			// The request's context ends with the response
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := recorder.Record(ctx, entry); err != nil {
+				log.WithError(err).WithFields(log.Fields{"method": r.Method, "path": r.URL.Path}).Error("Failed to record audit entry")
+			}
+		})
+	}
+}
+
+// readCloser reads from a reader and closes the original body
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// audited reports whether a request is a mutating API call to be recorded
+func audited(r *http.Request, excludePaths []string) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return false
+	}
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+		return false
+	}
+	for _, prefix := range excludePaths {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// privatePath reports whether the payload of requests to a path is kept out
+// of the audit log
+func privatePath(path string) bool {
+	for _, prefix := range auditPrivatePaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientAddr returns the client's IP address without the port
+func clientAddr(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// sensitiveKey reports whether a JSON field or query parameter may hold a
+// secret, whose value is then not stored
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range []string{"password", "secret", "token", "key", "authorization", "credential"} {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// personalKey reports whether a JSON field or query parameter names a user,
+// whose value is then not stored
+func personalKey(key string) bool {
+	key = strings.TrimSuffix(strings.ToLower(key), "s")
+	return key == "user" || strings.HasSuffix(key, "username") || strings.HasSuffix(key, "email")
+}
+
+// redactedURI returns the path and query of a request URL with the values of
+// sensitive and personal query parameters replaced
+func redactedURI(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Path
+	}
+	query := u.Query()
+	for key := range query {
+		if sensitiveKey(key) || personalKey(key) {
+			query[key] = []string{"[redacted]"}
+		}
+	}
+	return u.Path + "?" + query.Encode()
+}
+
+// summarizePayload describes a request body for the audit log: JSON with the
+// values of sensitive and personal fields replaced, or the size and type of other bodies
+func summarizePayload(contentType string, payload []byte) string {
+	if len(payload) == 0 {
+		return ""
+	}
+	if len(payload) > auditBodyLimit {
+		return fmt.Sprintf("more than %d bytes of %s", auditBodyLimit, contentTypeOrUnknown(contentType))
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return fmt.Sprintf("%d bytes of %s", len(payload), contentTypeOrUnknown(contentType))
+	}
+	summary, _ := json.Marshal(redactJSON(value))
+	if len(summary) > auditSummaryLength {
+		return string(summary[:auditSummaryLength]) + "..."
+	}
+	return string(summary)
+}
+
+func contentTypeOrUnknown(contentType string) string {
+	if contentType == "" {
+		return "unknown type"
+	}
+	return contentType
+}
+
+// redactJSON replaces the values of sensitive and personal fields in decoded
+// JSON
+func redactJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if sensitiveKey(key) || personalKey(key) {
+				v[key] = "[redacted]"
+			} else {
+				v[key] = redactJSON(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactJSON(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"licet/internal/models"
+)
+
+type fakeAuditRecorder struct {
+	entries []models.AuditEntry
+}
+
+func (f *fakeAuditRecorder) Record(ctx context.Context, entry models.AuditEntry) error {
+	f.entries = append(f.entries, entry)
+	return nil
+}
+
+func newAuditedRouter(recorder AuditRecorder, excludePaths []string) (*chi.Mux, *string) {
+	var received string
+	r := chi.NewRouter()
+	r.Use(AuditMiddleware(recorder, excludePaths))
+	r.Post("/api/v1/settings/email", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusCreated)
+	})
+	r.Delete("/api/v1/alerts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+	r.Get("/api/v1/servers", func(w http.ResponseWriter, r *http.Request) {})
+	r.Post("/api/v1/ingest/{server}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Post("/api/v1/admin/anonymize", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+	})
+	return r, &received
+}
+
+func TestAuditMiddleware_RecordsMutatingRequests(t *testing.T) {
+	recorder := &fakeAuditRecorder{}
+	router, received := newAuditedRouter(recorder, nil)
+
+	body := `{"smtp_host":"mail.example.com","smtp_password":"hunter2","nested":{"api_key":"abc"}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/settings/email?token=xyz&dry_run=1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "192.0.2.10:51234"
+	req = req.WithContext(context.WithValue(req.Context(), authInfoKey, &AuthInfo{Authenticated: true, Username: "jdoe", Role: RoleAdmin}))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if *received != body {
+		t.Errorf("Expected the handler to receive the whole body, got %q", *received)
+	}
+	if len(recorder.entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(recorder.entries))
+	}
+	e := recorder.entries[0]
+	if e.Actor != "jdoe" || e.Role != RoleAdmin || e.RemoteAddr != "192.0.2.10" || e.Method != http.MethodPost ||
+		e.Status != http.StatusCreated || e.Action != models.AuditActionRequest || e.Target != "/api/v1/settings/email" {
+		t.Errorf("Unexpected entry %+v", e)
+	}
+	if strings.Contains(e.Details, "hunter2") || strings.Contains(e.Details, "abc") || !strings.Contains(e.Details, "mail.example.com") {
+		t.Errorf("Expected secrets to be redacted from the payload, got %s", e.Details)
+	}
+	if strings.Contains(e.Path, "xyz") || !strings.Contains(e.Path, "dry_run=1") {
+		t.Errorf("Expected the token to be redacted from the path, got %s", e.Path)
+	}
+}
+
+func TestAuditMiddleware_SkipsReadsAndExcludedPaths(t *testing.T) {
+	recorder := &fakeAuditRecorder{}
+	router, _ := newAuditedRouter(recorder, []string{"/api/v1/ingest/"})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/servers", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/ingest/27000@a", strings.NewReader("data")))
+	if len(recorder.entries) != 0 {
+		t.Fatalf("Expected no entries, got %+v", recorder.entries)
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/api/v1/alerts/7", nil))
+	if len(recorder.entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(recorder.entries))
+	}
+	if e := recorder.entries[0]; e.Actor != "anonymous" || e.Status != http.StatusNotFound || e.Target != "/api/v1/alerts/{id}" || e.Details != "" {
+		t.Errorf("Unexpected entry %+v", e)
+	}
+}
+
+func TestAuditMiddleware_RedactsUsers(t *testing.T) {
+	recorder := &fakeAuditRecorder{}
+	router, received := newAuditedRouter(recorder, nil)
+
+	body := `{"username":"jdoe"}`
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/admin/anonymize", strings.NewReader(body)))
+	if *received != body {
+		t.Errorf("Expected the handler to receive the whole body, got %q", *received)
+	}
+	if len(recorder.entries) != 1 || recorder.entries[0].Details != "" {
+		t.Fatalf("Expected an entry without the payload, got %+v", recorder.entries)
+	}
+
+	body = `{"smtp_host":"mail.example.com","to_emails":["jdoe@example.com"],"user":"jdoe","owner_username":"asmith"}`
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/settings/email?email=jdoe@example.com", strings.NewReader(body)))
+	e := recorder.entries[1]
+	if strings.Contains(e.Details, "jdoe") || strings.Contains(e.Details, "asmith") || !strings.Contains(e.Details, "mail.example.com") {
+		t.Errorf("Expected usernames and email addresses to be redacted from the payload, got %s", e.Details)
+	}
+	if strings.Contains(e.Path, "jdoe") {
+		t.Errorf("Expected the email address to be redacted from the path, got %s", e.Path)
+	}
+}
+
+func TestSummarizePayload(t *testing.T) {
+	if got := summarizePayload("text/csv", []byte("a,b\n1,2\n")); got != "8 bytes of text/csv" {
+		t.Errorf("Unexpected summary %q", got)
+	}
+	long := `{"note":"` + strings.Repeat("x", 2*auditSummaryLength) + `"}`
+	if got := summarizePayload("application/json", []byte(long)); len(got) != auditSummaryLength+3 || !strings.HasSuffix(got, "...") {
+		t.Errorf("Expected the summary to be truncated, got %d characters", len(got))
+	}
+}
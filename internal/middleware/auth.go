@@ -7,6 +7,8 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -37,10 +39,11 @@ var authInfoKey = authContextKey{}
 
 // AuthInfo contains authentication information for a request
 type AuthInfo struct {
-	Authenticated bool   `json:"authenticated"`
-	Username      string `json:"username"`
-	Role          string `json:"role"`
-	Method        string `json:"method"` // "api_key", "basic", "none"
+	Authenticated bool     `json:"authenticated"`
+	Username      string   `json:"username"`
+	Role          string   `json:"role"`
+	Method        string   `json:"method"`                // "api_key", "basic", "oidc", "none"
+	Permissions   []string `json:"permissions,omitempty"` // Of the role and the API key or user
 }
 
 // Authenticator handles authentication for the application
@@ -51,11 +54,16 @@ type Authenticator struct {
 	sessions    map[string]*session
 	sessionMu   sync.RWMutex
 	stopCh      chan struct{}
+	oidc        *oidcProvider // Set by EnableOIDC
+
+	routePermission func(r *http.Request) string // Set by SetRoutePermissions
+	exactPaths      map[string]bool              // Set by ExemptExactPath
 }
 
 type session struct {
 	username  string
 	role      string
+	idToken   string // Sent as a hint when logging out at the OIDC provider
 	expiresAt time.Time
 }
 
@@ -127,6 +135,9 @@ func hashKey(key string) string {
 
 // isExemptPath checks if a path is exempt from authentication
 func (a *Authenticator) isExemptPath(path string) bool {
+	if a.exactPaths[path] {
+		return true
+	}
 	for _, exempt := range a.config.ExemptPaths {
 		if strings.HasPrefix(path, exempt) {
 			return true
@@ -135,6 +146,21 @@ func (a *Authenticator) isExemptPath(path string) bool {
 	return false
 }
 
+// ExemptPath exempts the paths starting with prefix from authentication, for
+// endpoints that verify the signatures of their requests themselves
+func (a *Authenticator) ExemptPath(prefix string) {
+	a.config.ExemptPaths = append(slices.Clip(a.config.ExemptPaths), prefix)
+}
+
+// ExemptExactPath exempts a single path from authentication, leaving the
+// paths below it protected
+func (a *Authenticator) ExemptExactPath(path string) {
+	if a.exactPaths == nil {
+		a.exactPaths = make(map[string]bool)
+	}
+	a.exactPaths[path] = true
+}
+
 // authenticateAPIKey attempts to authenticate using an API key
 func (a *Authenticator) authenticateAPIKey(r *http.Request) (*AuthInfo, bool) {
 	// Check Authorization header
@@ -144,12 +170,7 @@ func (a *Authenticator) authenticateAPIKey(r *http.Request) (*AuthInfo, bool) {
 		hashedToken := hashKey(token)
 
 		if apiKey, exists := a.apiKeyIndex[hashedToken]; exists {
-			return &AuthInfo{
-				Authenticated: true,
-				Username:      apiKey.Name,
-				Role:          apiKey.Role,
-				Method:        "api_key",
-			}, true
+			return a.apiKeyInfo(apiKey), true
 		}
 	}
 
@@ -158,12 +179,7 @@ func (a *Authenticator) authenticateAPIKey(r *http.Request) (*AuthInfo, bool) {
 	if apiKeyHeader != "" {
 		hashedKey := hashKey(apiKeyHeader)
 		if apiKey, exists := a.apiKeyIndex[hashedKey]; exists {
-			return &AuthInfo{
-				Authenticated: true,
-				Username:      apiKey.Name,
-				Role:          apiKey.Role,
-				Method:        "api_key",
-			}, true
+			return a.apiKeyInfo(apiKey), true
 		}
 	}
 
@@ -172,18 +188,24 @@ func (a *Authenticator) authenticateAPIKey(r *http.Request) (*AuthInfo, bool) {
 	if apiKeyParam != "" {
 		hashedKey := hashKey(apiKeyParam)
 		if apiKey, exists := a.apiKeyIndex[hashedKey]; exists {
-			return &AuthInfo{
-				Authenticated: true,
-				Username:      apiKey.Name,
-				Role:          apiKey.Role,
-				Method:        "api_key",
-			}, true
+			return a.apiKeyInfo(apiKey), true
 		}
 	}
 
 	return nil, false
 }
 
+// apiKeyInfo returns the auth info of a request authenticated with an API key
+func (a *Authenticator) apiKeyInfo(apiKey *config.APIKeyConfig) *AuthInfo {
+	return &AuthInfo{
+		Authenticated: true,
+		Username:      apiKey.Name,
+		Role:          apiKey.Role,
+		Method:        "api_key",
+		Permissions:   a.permissions(apiKey.Role, apiKey.Permissions),
+	}
+}
+
 // authenticateBasicAuth attempts to authenticate using Basic Auth
 func (a *Authenticator) authenticateBasicAuth(r *http.Request) (*AuthInfo, bool) {
 	if !a.config.BasicAuth.Enabled {
@@ -223,6 +245,7 @@ func (a *Authenticator) authenticateBasicAuth(r *http.Request) (*AuthInfo, bool)
 		Username:      username,
 		Role:          user.Role,
 		Method:        "basic",
+		Permissions:   a.permissions(user.Role, user.Permissions),
 	}, true
 }
 
@@ -238,6 +261,11 @@ func (a *Authenticator) Authenticate(r *http.Request) *AuthInfo {
 		return info
 	}
 
+	// Try an OIDC session
+	if info, ok := a.authenticateSession(r); ok {
+		return info
+	}
+
 	// No authentication
 	return &AuthInfo{
 		Authenticated: false,
@@ -245,18 +273,30 @@ func (a *Authenticator) Authenticate(r *http.Request) *AuthInfo {
 	}
 }
 
-// HasPermission checks if a role has a specific permission
+// AuthenticateRead authenticates a request for read access, as an anonymous
+// reader when anonymous read access is allowed. It reports false when the
+// request may not read at all.
+func (a *Authenticator) AuthenticateRead(r *http.Request) (*AuthInfo, bool) {
+	if info := a.Authenticate(r); info.Authenticated {
+		return info, true
+	}
+	if a.config.AllowAnonymousRead {
+		return a.anonymousInfo(), true
+	}
+	return nil, false
+}
+
+// anonymousInfo returns the auth info of anonymous readers
+func (a *Authenticator) anonymousInfo() *AuthInfo {
+	return &AuthInfo{Username: "anonymous", Role: RoleReadonly, Method: "anonymous", Permissions: a.permissions(RoleReadonly, nil)}
+}
+
+// HasPermission checks if a built-in role has a specific permission
 func HasPermission(role, permission string) bool {
-	switch role {
-	case RoleAdmin:
+	if role == RoleAdmin {
 		return true // Admin has all permissions
-	case RoleWrite:
-		return permission == PermissionRead || permission == PermissionWrite
-	case RoleReadonly:
-		return permission == PermissionRead
-	default:
-		return false
 	}
+	return contains(rolePermissions[role], permission)
 }
 
 // RequiredPermission returns the required permission for an HTTP method
@@ -281,8 +321,8 @@ func AuthMiddleware(auth *Authenticator) func(http.Handler) http.Handler {
 				return
 			}
 
-			// Skip exempt paths
-			if auth.isExemptPath(r.URL.Path) {
+			// Skip exempt paths and the OIDC sign-in endpoints
+			if auth.isExemptPath(r.URL.Path) || auth.isOIDCPath(r.URL.Path) {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -291,9 +331,10 @@ func AuthMiddleware(auth *Authenticator) func(http.Handler) http.Handler {
 			authInfo := auth.Authenticate(r)
 
 			// Allow anonymous read-only access if configured
+			requiredPerm := auth.requiredPermission(r)
 			if !authInfo.Authenticated && auth.config.AllowAnonymousRead {
-				requiredPerm := RequiredPermission(r.Method)
-				if requiredPerm == PermissionRead {
+				anonInfo := auth.anonymousInfo()
+				if anonInfo.Can(requiredPerm) {
 					// Allow anonymous read access
 					log.WithFields(log.Fields{
 						"path":   r.URL.Path,
@@ -302,12 +343,6 @@ func AuthMiddleware(auth *Authenticator) func(http.Handler) http.Handler {
 					}).Debug("Anonymous read access allowed")
 
 					// Set anonymous auth info in context
-					anonInfo := &AuthInfo{
-						Authenticated: false,
-						Username:      "anonymous",
-						Role:          RoleReadonly,
-						Method:        "anonymous",
-					}
 					ctx := context.WithValue(r.Context(), authInfoKey, anonInfo)
 					next.ServeHTTP(w, r.WithContext(ctx))
 					return
@@ -321,6 +356,12 @@ func AuthMiddleware(auth *Authenticator) func(http.Handler) http.Handler {
 					"ip":     getClientIP(r),
 				}).Warn("Authentication failed")
 
+				// Send browsers to the OIDC sign-in page
+				if auth.wantsLogin(r) {
+					http.Redirect(w, r, OIDCLoginPath+"?redirect="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+					return
+				}
+
 				// Send WWW-Authenticate header for Basic Auth
 				if auth.config.BasicAuth.Enabled {
 					w.Header().Set("WWW-Authenticate", `Basic realm="Licet"`)
@@ -335,9 +376,8 @@ func AuthMiddleware(auth *Authenticator) func(http.Handler) http.Handler {
 				return
 			}
 
-			// Check permission for the request method
-			requiredPerm := RequiredPermission(r.Method)
-			if !HasPermission(authInfo.Role, requiredPerm) {
+			// Check the permission of the route, or of the request method
+			if !authInfo.Can(requiredPerm) {
 				log.WithFields(log.Fields{
 					"path":     r.URL.Path,
 					"method":   r.Method,
@@ -372,6 +412,12 @@ func AuthMiddleware(auth *Authenticator) func(http.Handler) http.Handler {
 	}
 }
 
+// WithAuthInfo returns a context carrying auth info, for running work on
+// behalf of a request after it finished
+func WithAuthInfo(ctx context.Context, info *AuthInfo) context.Context {
+	return context.WithValue(ctx, authInfoKey, info)
+}
+
 // GetAuthInfo extracts authentication info from request context
 func GetAuthInfo(r *http.Request) *AuthInfo {
 	if info, ok := r.Context().Value(authInfoKey).(*AuthInfo); ok {
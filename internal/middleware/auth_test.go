@@ -208,6 +208,54 @@ func TestRequiredPermission(t *testing.T) {
 	}
 }
 
+func TestAuthMiddleware_RoutePermissions(t *testing.T) {
+	cfg := newTestAuthConfig()
+	cfg.Roles = map[string][]string{"helpdesk": {PermissionRead, PermissionAlertsManage}}
+	cfg.APIKeys = append(cfg.APIKeys,
+		config.APIKeyConfig{Name: "helpdesk-key", Key: "helpdesk", Role: "helpdesk", Enabled: true},
+		config.APIKeyConfig{Name: "writer-key", Key: "writer", Role: RoleWrite, Enabled: true},
+		config.APIKeyConfig{Name: "exporter-key", Key: "exporter", Role: "helpdesk", Permissions: []string{PermissionExportsRun}, Enabled: true},
+	)
+	auth := NewAuthenticator(cfg)
+	defer auth.Stop()
+	routes := map[string]string{
+		"/api/v1/alert-rules": PermissionAlertsManage,
+		"/api/v1/servers":     PermissionServersWrite,
+		"/api/v1/export/raw":  PermissionExportsRun,
+	}
+	auth.SetRoutePermissions(func(r *http.Request) string { return routes[r.URL.Path] })
+
+	handler := AuthMiddleware(auth)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	tests := []struct {
+		key    string
+		method string
+		path   string
+		want   int
+	}{
+		{"helpdesk", http.MethodPost, "/api/v1/alert-rules", http.StatusOK},
+		{"helpdesk", http.MethodPost, "/api/v1/servers", http.StatusForbidden},
+		{"helpdesk", http.MethodPost, "/api/v1/computed-metrics", http.StatusForbidden}, // Requires write by its method
+		{"helpdesk", http.MethodGet, "/api/v1/features", http.StatusOK},
+		{"helpdesk", http.MethodGet, "/api/v1/export/raw", http.StatusForbidden},
+		{"exporter", http.MethodGet, "/api/v1/export/raw", http.StatusOK},
+		{"writer", http.MethodPost, "/api/v1/servers", http.StatusOK},
+		{"writer", http.MethodPost, "/api/v1/computed-metrics", http.StatusOK},
+		{"readonly456", http.MethodPost, "/api/v1/alert-rules", http.StatusForbidden},
+		{"readonly456", http.MethodGet, "/api/v1/export/raw", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("X-API-Key", tt.key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s with key %s: expected %d, got %d", tt.method, tt.path, tt.key, tt.want, rec.Code)
+		}
+	}
+}
+
 func TestAuthMiddleware_ExemptPath(t *testing.T) {
 	auth := NewAuthenticator(newTestAuthConfig())
 	defer auth.Stop()
@@ -337,3 +385,29 @@ func TestGetAuthInfo_NoContext(t *testing.T) {
 		t.Errorf("expected method 'none', got %q", info.Method)
 	}
 }
+
+func TestAuthMiddleware_ExemptExactPath(t *testing.T) {
+	auth := NewAuthenticator(newTestAuthConfig())
+	defer auth.Stop()
+	auth.ExemptExactPath("/public/feed.json")
+
+	handler := AuthMiddleware(auth)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/public/feed.json", http.StatusOK},
+		{"/public/feed.json/extra", http.StatusUnauthorized},
+		{"/public/feed.jsonx", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rr.Code != tt.want {
+			t.Errorf("%s returned status %d, want %d", tt.path, rr.Code, tt.want)
+		}
+	}
+}
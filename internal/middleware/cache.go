@@ -5,7 +5,9 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -27,156 +29,231 @@ func DefaultCacheConfig() CacheConfig {
 	}
 }
 
-// cacheEntry represents a cached response
-type cacheEntry struct {
-	body        []byte
-	contentType string
-	statusCode  int
-	headers     http.Header
-	expiry      time.Time
+// CacheEntry is a cached response
+type CacheEntry struct {
+	Body        []byte      `json:"body"`
+	ContentType string      `json:"content_type"`
+	StatusCode  int         `json:"status_code"`
+	Headers     http.Header `json:"headers"`
+	Expiry      time.Time   `json:"expiry"`
 }
 
-// Cache is an in-memory cache for HTTP responses
+// CacheBackend stores cached responses. Backends drop entries once they
+// expire; a backend shared by several replicas (Redis) lets them all
+// serve and invalidate the same responses.
+type CacheBackend interface {
+	// Name identifies the backend in statistics, e.g. memory or redis
+	Name() string
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+	Delete(key string)
+	DeletePrefix(prefix string)
+	Clear()
+	// Len returns the number of entries held
+	Len() int
+	Close()
+}
+
+// Cache is a cache for HTTP responses, counting hits and misses of its
+// backend
 type Cache struct {
-	entries map[string]*cacheEntry
-	mu      sync.RWMutex
+	backend CacheBackend
 	config  CacheConfig
-	stopCh  chan struct{}
+
+	hits   atomic.Int64
+	misses atomic.Int64
 }
 
-// NewCache creates a new cache instance
+// NewCache creates a cache held in memory
 func NewCache(config CacheConfig) *Cache {
-	c := &Cache{
-		entries: make(map[string]*cacheEntry),
-		config:  config,
-		stopCh:  make(chan struct{}),
+	return NewCacheWithBackend(config, NewMemoryCacheBackend(config.MaxEntries))
+}
+
+// NewCacheWithBackend creates a cache storing responses in backend
+func NewCacheWithBackend(config CacheConfig, backend CacheBackend) *Cache {
+	return &Cache{backend: backend, config: config}
+}
+
+// Stop releases the backend
+func (c *Cache) Stop() {
+	c.backend.Close()
+}
+
+// Get retrieves a cached response
+func (c *Cache) Get(key string) (*CacheEntry, bool) {
+	entry, found := c.backend.Get(key)
+	if !found || time.Now().After(entry.Expiry) {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	c.hits.Add(1)
+	return entry, true
+}
+
+// Set stores a response in the cache
+func (c *Cache) Set(key string, entry *CacheEntry) {
+	c.backend.Set(key, entry)
+}
+
+// Invalidate removes a specific key from the cache
+func (c *Cache) Invalidate(key string) {
+	c.backend.Delete(key)
+}
+
+// InvalidatePrefix removes all keys with a given prefix. Keys start with the
+// request path, so InvalidatePrefix("/api/v1/servers") drops every cached
+// response under /api/v1/servers. A nil cache does nothing.
+func (c *Cache) InvalidatePrefix(prefix string) {
+	if c == nil {
+		return
+	}
+	c.backend.DeletePrefix(prefix)
+}
+
+// Clear removes all entries from the cache
+func (c *Cache) Clear() {
+	c.backend.Clear()
+}
+
+// Stats returns cache statistics
+func (c *Cache) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"backend":     c.backend.Name(),
+		"entries":     c.backend.Len(),
+		"max_entries": c.config.MaxEntries,
+		"hits":        c.hits.Load(),
+		"misses":      c.misses.Load(),
+		"default_ttl": c.config.DefaultTTL.String(),
+		"enabled":     c.config.Enabled,
+	}
+}
+
+// MemoryCacheBackend holds cached responses in memory, evicting the entries
+// closest to expiry once full
+type MemoryCacheBackend struct {
+	entries    map[string]*CacheEntry
+	mu         sync.RWMutex
+	maxEntries int
+	stopCh     chan struct{}
+}
+
+// NewMemoryCacheBackend creates a memory backend holding up to maxEntries
+// responses
+func NewMemoryCacheBackend(maxEntries int) *MemoryCacheBackend {
+	b := &MemoryCacheBackend{
+		entries:    make(map[string]*CacheEntry),
+		maxEntries: maxEntries,
+		stopCh:     make(chan struct{}),
 	}
 
 	// Start cleanup goroutine
-	go c.cleanupLoop()
+	go b.cleanupLoop()
 
-	return c
+	return b
 }
 
-// Stop shuts down the cache cleanup goroutine
-func (c *Cache) Stop() {
-	close(c.stopCh)
+func (b *MemoryCacheBackend) Name() string { return "memory" }
+
+// Close shuts down the cleanup goroutine
+func (b *MemoryCacheBackend) Close() {
+	close(b.stopCh)
 }
 
 // cleanupLoop periodically removes expired entries
-func (c *Cache) cleanupLoop() {
+func (b *MemoryCacheBackend) cleanupLoop() {
 	ticker := time.NewTicker(time.Minute)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			c.cleanup()
-		case <-c.stopCh:
+			b.cleanup()
+		case <-b.stopCh:
 			return
 		}
 	}
 }
 
 // cleanup removes expired entries
-func (c *Cache) cleanup() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (b *MemoryCacheBackend) cleanup() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
 	now := time.Now()
-	for key, entry := range c.entries {
-		if now.After(entry.expiry) {
-			delete(c.entries, key)
+	for key, entry := range b.entries {
+		if now.After(entry.Expiry) {
+			delete(b.entries, key)
 		}
 	}
 }
 
-// Get retrieves a cached response
-func (c *Cache) Get(key string) (*cacheEntry, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	entry, exists := c.entries[key]
-	if !exists {
-		return nil, false
-	}
-
-	if time.Now().After(entry.expiry) {
-		return nil, false
-	}
+func (b *MemoryCacheBackend) Get(key string) (*CacheEntry, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 
-	return entry, true
+	entry, exists := b.entries[key]
+	return entry, exists
 }
 
-// Set stores a response in the cache
-func (c *Cache) Set(key string, entry *cacheEntry) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (b *MemoryCacheBackend) Set(key string, entry *CacheEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
 	// Enforce max entries limit
-	if len(c.entries) >= c.config.MaxEntries {
+	if len(b.entries) >= b.maxEntries {
 		// Remove oldest entries
-		c.evictOldest()
+		b.evictOldest()
 	}
 
-	c.entries[key] = entry
+	b.entries[key] = entry
 }
 
 // evictOldest removes the oldest entries when cache is full
-func (c *Cache) evictOldest() {
+func (b *MemoryCacheBackend) evictOldest() {
 	// Simple eviction: remove entries closest to expiry
 	var oldestKey string
 	var oldestTime time.Time
 
-	for key, entry := range c.entries {
-		if oldestKey == "" || entry.expiry.Before(oldestTime) {
+	for key, entry := range b.entries {
+		if oldestKey == "" || entry.Expiry.Before(oldestTime) {
 			oldestKey = key
-			oldestTime = entry.expiry
+			oldestTime = entry.Expiry
 		}
 	}
 
 	if oldestKey != "" {
-		delete(c.entries, oldestKey)
+		delete(b.entries, oldestKey)
 	}
 }
 
-// Invalidate removes a specific key from the cache
-func (c *Cache) Invalidate(key string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	delete(c.entries, key)
+func (b *MemoryCacheBackend) Delete(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.entries, key)
 }
 
-// InvalidatePrefix removes all keys with a given prefix
-func (c *Cache) InvalidatePrefix(prefix string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (b *MemoryCacheBackend) DeletePrefix(prefix string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-	for key := range c.entries {
-		if len(key) >= len(prefix) && key[:len(prefix)] == prefix {
-			delete(c.entries, key)
+	for key := range b.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(b.entries, key)
 		}
 	}
 }
 
-// Clear removes all entries from the cache
-func (c *Cache) Clear() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.entries = make(map[string]*cacheEntry)
+func (b *MemoryCacheBackend) Clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries = make(map[string]*CacheEntry)
 }
 
-// Stats returns cache statistics
-func (c *Cache) Stats() map[string]interface{} {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	return map[string]interface{}{
-		"entries":     len(c.entries),
-		"max_entries": c.config.MaxEntries,
-		"default_ttl": c.config.DefaultTTL.String(),
-		"enabled":     c.config.Enabled,
-	}
+func (b *MemoryCacheBackend) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.entries)
 }
 
 // cachedResponseWriter wraps http.ResponseWriter to capture the response
@@ -204,12 +281,13 @@ func (w *cachedResponseWriter) Write(b []byte) (int, error) {
 	return w.ResponseWriter.Write(b)
 }
 
-// generateCacheKey creates a unique cache key from the request
+// generateCacheKey creates a unique cache key from the request. The key
+// starts with the path so responses can be invalidated by path prefix.
 func generateCacheKey(r *http.Request) string {
 	// Include method, path, and query string
 	data := r.Method + ":" + r.URL.Path + "?" + r.URL.RawQuery
 	hash := sha256.Sum256([]byte(data))
-	return hex.EncodeToString(hash[:])
+	return r.URL.Path + ":" + hex.EncodeToString(hash[:])
 }
 
 // CacheMiddleware creates HTTP middleware for caching GET responses
@@ -229,17 +307,22 @@ func CacheMiddleware(cache *Cache, ttl time.Duration) func(http.Handler) http.Ha
 			}
 
 			key := generateCacheKey(r)
+			// Responses tagged by ConditionalMiddleware are cached per data version
+			// so a new collection never serves a stale body under a fresh ETag
+			if etag := w.Header().Get("ETag"); etag != "" {
+				key += ":" + etag
+			}
 
 			// Try to get from cache
 			if entry, found := cache.Get(key); found {
 				log.WithField("path", r.URL.Path).Debug("Cache hit")
 				w.Header().Set("X-Cache", "HIT")
-				w.Header().Set("Content-Type", entry.contentType)
-				for k, v := range entry.headers {
+				w.Header().Set("Content-Type", entry.ContentType)
+				for k, v := range entry.Headers {
 					w.Header()[k] = v
 				}
-				w.WriteHeader(entry.statusCode)
-				w.Write(entry.body)
+				w.WriteHeader(entry.StatusCode)
+				w.Write(entry.Body)
 				return
 			}
 
@@ -252,12 +335,12 @@ func CacheMiddleware(cache *Cache, ttl time.Duration) func(http.Handler) http.Ha
 
 			// Only cache successful responses
 			if crw.statusCode >= 200 && crw.statusCode < 300 {
-				entry := &cacheEntry{
-					body:        crw.body.Bytes(),
-					contentType: crw.Header().Get("Content-Type"),
-					statusCode:  crw.statusCode,
-					headers:     cloneHeaders(crw.Header()),
-					expiry:      time.Now().Add(ttl),
+				entry := &CacheEntry{
+					Body:        crw.body.Bytes(),
+					ContentType: crw.Header().Get("Content-Type"),
+					StatusCode:  crw.statusCode,
+					Headers:     cloneHeaders(crw.Header()),
+					Expiry:      time.Now().Add(ttl),
 				}
 				cache.Set(key, entry)
 			}
@@ -270,7 +353,7 @@ func cloneHeaders(h http.Header) http.Header {
 	clone := make(http.Header)
 	for k, v := range h {
 		// Skip certain headers that shouldn't be cached
-		if k == "X-Cache" || k == "Date" {
+		if k == "X-Cache" || k == "Date" || k == "ETag" || k == "Last-Modified" {
 			continue
 		}
 		clone[k] = append([]string(nil), v...)
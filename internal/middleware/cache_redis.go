@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+)
+
+// redisTimeout bounds each Redis command so an unreachable Redis degrades to
+// cache misses instead of stalling requests
+const redisTimeout = 500 * time.Millisecond
+
+// RedisCacheConfig holds the connection settings of the Redis backend
+type RedisCacheConfig struct {
+	Address  string
+	Password string
+	DB       int
+	Prefix   string // Namespace of the cache keys, e.g. licet:cache:
+}
+
+// RedisCacheBackend stores cached responses in Redis so that replicas share
+// them. Entries expire through Redis TTLs; Redis' maxmemory policy, not
+// cache.max_entries, bounds its size. Redis errors are logged and treated as
+// misses.
+type RedisCacheBackend struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisCacheBackend connects to Redis and checks it is reachable
+func NewRedisCacheBackend(config RedisCacheConfig) (*RedisCacheBackend, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     config.Address,
+		Password: config.Password,
+		DB:       config.DB,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", config.Address, err)
+	}
+	return &RedisCacheBackend{client: client, prefix: config.Prefix}, nil
+}
+
+func (b *RedisCacheBackend) Name() string { return "redis" }
+
+func (b *RedisCacheBackend) Close() {
+	b.client.Close()
+}
+
+func (b *RedisCacheBackend) Get(key string) (*CacheEntry, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	data, err := b.client.Get(ctx, b.prefix+key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.WithError(err).Warn("Redis cache lookup failed")
+		}
+		return nil, false
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		log.WithError(err).Warn("Discarding undecodable Redis cache entry")
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (b *RedisCacheBackend) Set(key string, entry *CacheEntry) {
+	ttl := time.Until(entry.Expiry)
+	if ttl <= 0 {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.WithError(err).Warn("Failed to encode Redis cache entry")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := b.client.Set(ctx, b.prefix+key, data, ttl).Err(); err != nil {
+		log.WithError(err).Warn("Redis cache store failed")
+	}
+}
+
+func (b *RedisCacheBackend) Delete(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := b.client.Del(ctx, b.prefix+key).Err(); err != nil {
+		log.WithError(err).Warn("Redis cache invalidation failed")
+	}
+}
+
+func (b *RedisCacheBackend) DeletePrefix(prefix string) {
+	b.deleteMatching(b.prefix + prefix)
+}
+
+func (b *RedisCacheBackend) Clear() {
+	b.deleteMatching(b.prefix)
+}
+
+// deleteMatching removes every key starting with prefix
+func (b *RedisCacheBackend) deleteMatching(prefix string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*redisTimeout)
+	defer cancel()
+
+	iter := b.client.Scan(ctx, 0, redisGlobEscaper.Replace(prefix)+"*", 500).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == 500 {
+			if err := b.client.Del(ctx, keys...).Err(); err != nil {
+				log.WithError(err).Warn("Redis cache invalidation failed")
+				return
+			}
+			keys = keys[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		log.WithError(err).Warn("Redis cache invalidation failed")
+		return
+	}
+	if len(keys) > 0 {
+		if err := b.client.Del(ctx, keys...).Err(); err != nil {
+			log.WithError(err).Warn("Redis cache invalidation failed")
+		}
+	}
+}
+
+// Len counts the keys of the cache's namespace
+func (b *RedisCacheBackend) Len() int {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*redisTimeout)
+	defer cancel()
+
+	count := 0
+	iter := b.client.Scan(ctx, 0, redisGlobEscaper.Replace(b.prefix)+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		count++
+	}
+	if err := iter.Err(); err != nil {
+		log.WithError(err).Warn("Failed to count Redis cache entries")
+	}
+	return count
+}
+
+// redisGlobEscaper escapes the pattern characters of SCAN MATCH
+var redisGlobEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestRedisCache(t *testing.T) (*Cache, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	backend, err := NewRedisCacheBackend(RedisCacheConfig{Address: server.Addr(), Prefix: "licet:cache:"})
+	if err != nil {
+		t.Fatalf("NewRedisCacheBackend failed: %v", err)
+	}
+	cache := NewCacheWithBackend(CacheConfig{DefaultTTL: time.Minute, MaxEntries: 100, Enabled: true}, backend)
+	t.Cleanup(cache.Stop)
+	return cache, server
+}
+
+func TestRedisCache_SetGetAndExpiry(t *testing.T) {
+	cache, server := newTestRedisCache(t)
+
+	cache.Set("key1", &CacheEntry{
+		Body:        []byte(`{"test": true}`),
+		ContentType: "application/json",
+		StatusCode:  200,
+		Headers:     http.Header{"X-Test": {"1"}},
+		Expiry:      time.Now().Add(time.Minute),
+	})
+
+	got, found := cache.Get("key1")
+	if !found {
+		t.Fatal("expected cache hit")
+	}
+	if string(got.Body) != `{"test": true}` || got.StatusCode != 200 || got.Headers.Get("X-Test") != "1" {
+		t.Errorf("unexpected entry %+v", got)
+	}
+	if !server.Exists("licet:cache:key1") {
+		t.Error("expected the key to be namespaced by the prefix")
+	}
+
+	server.FastForward(2 * time.Minute)
+	if _, found := cache.Get("key1"); found {
+		t.Error("expected cache miss after the TTL")
+	}
+
+	stats := cache.Stats()
+	if stats["backend"] != "redis" || stats["hits"].(int64) != 1 || stats["misses"].(int64) != 1 {
+		t.Errorf("unexpected stats %v", stats)
+	}
+}
+
+func TestRedisCache_InvalidatePrefixAndClear(t *testing.T) {
+	cache, server := newTestRedisCache(t)
+	server.Set("other:key", "kept")
+
+	entry := &CacheEntry{Body: []byte("data"), Expiry: time.Now().Add(time.Minute)}
+	cache.Set("prefix:key1", entry)
+	cache.Set("prefix:key2", entry)
+	cache.Set("pre*:key3", entry)
+
+	cache.InvalidatePrefix("prefix:")
+	if _, found := cache.Get("prefix:key1"); found {
+		t.Error("expected cache miss for prefix:key1")
+	}
+	if _, found := cache.Get("pre*:key3"); !found {
+		t.Error("expected glob characters in the prefix to match literally")
+	}
+	if n := cache.Stats()["entries"].(int); n != 1 {
+		t.Errorf("expected 1 entry, got %d", n)
+	}
+
+	cache.Clear()
+	if n := cache.Stats()["entries"].(int); n != 0 {
+		t.Errorf("expected 0 entries after clear, got %d", n)
+	}
+	if !server.Exists("other:key") {
+		t.Error("clear must not remove keys outside the cache prefix")
+	}
+}
+
+func TestRedisCache_SharedBetweenReplicas(t *testing.T) {
+	cache, server := newTestRedisCache(t)
+	backend, err := NewRedisCacheBackend(RedisCacheConfig{Address: server.Addr(), Prefix: "licet:cache:"})
+	if err != nil {
+		t.Fatalf("NewRedisCacheBackend failed: %v", err)
+	}
+	replica := NewCacheWithBackend(CacheConfig{DefaultTTL: time.Minute, MaxEntries: 100, Enabled: true}, backend)
+	defer replica.Stop()
+
+	handler := func(c *Cache) http.Handler {
+		return CacheMiddleware(c, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"result": "ok"}`))
+		}))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/servers", nil)
+	handler(cache).ServeHTTP(httptest.NewRecorder(), req)
+
+	rr := httptest.NewRecorder()
+	handler(replica).ServeHTTP(rr, req)
+	if rr.Header().Get("X-Cache") != "HIT" || rr.Body.String() != `{"result": "ok"}` {
+		t.Errorf("expected the replica to serve the cached response, got %q %q", rr.Header().Get("X-Cache"), rr.Body.String())
+	}
+}
+
+func TestRedisCache_Unreachable(t *testing.T) {
+	cache, server := newTestRedisCache(t)
+	server.Close()
+
+	cache.Set("key1", &CacheEntry{Body: []byte("data"), Expiry: time.Now().Add(time.Minute)})
+	if _, found := cache.Get("key1"); found {
+		t.Error("expected a miss while redis is down")
+	}
+}
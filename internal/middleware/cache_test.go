@@ -15,12 +15,12 @@ func TestCache_SetAndGet(t *testing.T) {
 	})
 	defer cache.Stop()
 
-	entry := &cacheEntry{
-		body:        []byte(`{"test": true}`),
-		contentType: "application/json",
-		statusCode:  200,
-		headers:     http.Header{},
-		expiry:      time.Now().Add(time.Minute),
+	entry := &CacheEntry{
+		Body:        []byte(`{"test": true}`),
+		ContentType: "application/json",
+		StatusCode:  200,
+		Headers:     http.Header{},
+		Expiry:      time.Now().Add(time.Minute),
 	}
 	cache.Set("key1", entry)
 
@@ -28,8 +28,8 @@ func TestCache_SetAndGet(t *testing.T) {
 	if !found {
 		t.Fatal("expected cache hit")
 	}
-	if string(got.body) != `{"test": true}` {
-		t.Errorf("got body %q, want %q", string(got.body), `{"test": true}`)
+	if string(got.Body) != `{"test": true}` {
+		t.Errorf("got body %q, want %q", string(got.Body), `{"test": true}`)
 	}
 }
 
@@ -41,9 +41,9 @@ func TestCache_MissOnExpired(t *testing.T) {
 	})
 	defer cache.Stop()
 
-	entry := &cacheEntry{
-		body:   []byte("data"),
-		expiry: time.Now().Add(-time.Second), // Already expired
+	entry := &CacheEntry{
+		Body:   []byte("data"),
+		Expiry: time.Now().Add(-time.Second), // Already expired
 	}
 	cache.Set("key1", entry)
 
@@ -75,7 +75,7 @@ func TestCache_Invalidate(t *testing.T) {
 	})
 	defer cache.Stop()
 
-	entry := &cacheEntry{body: []byte("data"), expiry: time.Now().Add(time.Minute)}
+	entry := &CacheEntry{Body: []byte("data"), Expiry: time.Now().Add(time.Minute)}
 	cache.Set("key1", entry)
 	cache.Invalidate("key1")
 
@@ -93,7 +93,7 @@ func TestCache_InvalidatePrefix(t *testing.T) {
 	})
 	defer cache.Stop()
 
-	entry := &cacheEntry{body: []byte("data"), expiry: time.Now().Add(time.Minute)}
+	entry := &CacheEntry{Body: []byte("data"), Expiry: time.Now().Add(time.Minute)}
 	cache.Set("prefix:key1", entry)
 	cache.Set("prefix:key2", entry)
 	cache.Set("other:key3", entry)
@@ -119,7 +119,7 @@ func TestCache_Clear(t *testing.T) {
 	})
 	defer cache.Stop()
 
-	entry := &cacheEntry{body: []byte("data"), expiry: time.Now().Add(time.Minute)}
+	entry := &CacheEntry{Body: []byte("data"), Expiry: time.Now().Add(time.Minute)}
 	cache.Set("key1", entry)
 	cache.Set("key2", entry)
 	cache.Clear()
@@ -138,7 +138,7 @@ func TestCache_MaxEntries(t *testing.T) {
 	})
 	defer cache.Stop()
 
-	entry := &cacheEntry{body: []byte("data"), expiry: time.Now().Add(time.Minute)}
+	entry := &CacheEntry{Body: []byte("data"), Expiry: time.Now().Add(time.Minute)}
 	cache.Set("key1", entry)
 	cache.Set("key2", entry)
 	cache.Set("key3", entry) // Should evict oldest
@@ -238,3 +238,45 @@ func TestCacheMiddleware_SkipsNoCacheHeader(t *testing.T) {
 		t.Errorf("handler should be called twice with no-cache, was called %d times", callCount)
 	}
 }
+
+func TestCacheMiddleware_InvalidatePath(t *testing.T) {
+	cache := NewCache(CacheConfig{
+		DefaultTTL: time.Minute,
+		MaxEntries: 100,
+		Enabled:    true,
+	})
+	defer cache.Stop()
+
+	callCount := 0
+	handler := CacheMiddleware(cache, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount++
+		w.Write([]byte("ok"))
+	}))
+	get := func(path string) string {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr.Header().Get("X-Cache")
+	}
+
+	get("/api/v1/servers")
+	get("/api/v1/servers/27000@a/features?days=7")
+	get("/api/v1/alerts")
+
+	cache.InvalidatePrefix("/api/v1/servers")
+
+	if got := get("/api/v1/servers"); got != "MISS" {
+		t.Errorf("expected /api/v1/servers to be invalidated, got X-Cache=%s", got)
+	}
+	if got := get("/api/v1/servers/27000@a/features?days=7"); got != "MISS" {
+		t.Errorf("expected server features to be invalidated, got X-Cache=%s", got)
+	}
+	if got := get("/api/v1/alerts"); got != "HIT" {
+		t.Errorf("expected /api/v1/alerts to stay cached, got X-Cache=%s", got)
+	}
+	if callCount != 5 {
+		t.Errorf("handler should be called 5 times, was called %d times", callCount)
+	}
+
+	var nilCache *Cache
+	nilCache.InvalidatePrefix("/api/v1/servers") // Caching disabled
+}
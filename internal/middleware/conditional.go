@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+// DataVersionFunc returns the latest collection timestamp per server, and
+// may add the times other data shown in responses last changed under keys of
+// their own. An empty server argument means all servers.
+type DataVersionFunc func(ctx context.Context, server string) (map[string]time.Time, error)
+
+// ConditionalMiddleware adds ETag and Last-Modified headers to GET responses
+// based on the latest collection timestamps, and answers conditional requests
+// (If-None-Match / If-Modified-Since) with 304 Not Modified when nothing changed.
+func ConditionalMiddleware(version DataVersionFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			versions, err := version(r.Context(), requestServer(r))
+			if err != nil {
+				log.WithError(err).Debug("Failed to get data version, skipping conditional handling")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			etag, lastModified := computeETag(r, versions)
+			w.Header().Set("ETag", etag)
+			if !lastModified.IsZero() {
+				w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+			}
+
+			if notModified(r, etag, lastModified) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requestServer returns the server a request is scoped to, if any
+func requestServer(r *http.Request) string {
+	if server := chi.URLParam(r, "server"); server != "" {
+		return server
+	}
+	return r.URL.Query().Get("server")
+}
+
+// computeETag builds a weak ETag from the request URL and the data versions,
+// and returns the most recent timestamp
+func computeETag(r *http.Request, versions map[string]time.Time) (string, time.Time) {
+	servers := make([]string, 0, len(versions))
+	var latest time.Time
+	for server, ts := range versions {
+		servers = append(servers, server)
+		if ts.After(latest) {
+			latest = ts
+		}
+	}
+	sort.Strings(servers)
+
+	var b strings.Builder
+	b.WriteString(r.URL.Path)
+	b.WriteString("?")
+	b.WriteString(r.URL.RawQuery)
+	for _, server := range servers {
+		b.WriteString("|")
+		b.WriteString(server)
+		b.WriteString("=")
+		b.WriteString(versions[server].UTC().Format(time.RFC3339Nano))
+	}
+
+	hash := sha256.Sum256([]byte(b.String()))
+	return `W/"` + hex.EncodeToString(hash[:16]) + `"`, latest
+}
+
+// notModified reports whether the client's cached copy is still current.
+// If-None-Match takes precedence over If-Modified-Since (RFC 7232 section 6).
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, etag)
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		// HTTP dates have second precision
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
+// etagMatches performs a weak comparison of an If-None-Match header against an ETag
+func etagMatches(header, etag string) bool {
+	target := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConditionalMiddleware(t *testing.T) {
+	collected := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	version := func(ctx context.Context, server string) (map[string]time.Time, error) {
+		return map[string]time.Time{"27000@a": collected, "5053@b": collected.Add(-time.Minute)}, nil
+	}
+
+	calls := 0
+	handler := ConditionalMiddleware(version)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"ok":true}`))
+	}))
+
+	// First request returns full body with validators
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/utilization/current", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", rr.Code)
+	}
+	etag := rr.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected ETag header")
+	}
+	if got := rr.Header().Get("Last-Modified"); got != collected.Format(http.TimeFormat) {
+		t.Errorf("got Last-Modified %q, want %q", got, collected.Format(http.TimeFormat))
+	}
+
+	// If-None-Match with the same ETag returns 304
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/utilization/current", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("got status %d, want 304", rr.Code)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("expected empty body on 304, got %q", rr.Body.String())
+	}
+
+	// If-Modified-Since at the collection time returns 304
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/utilization/current", nil)
+	req.Header.Set("If-Modified-Since", collected.Format(http.TimeFormat))
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("got status %d, want 304", rr.Code)
+	}
+
+	// Older If-Modified-Since returns the full response
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/utilization/current", nil)
+	req.Header.Set("If-Modified-Since", collected.Add(-time.Hour).Format(http.TimeFormat))
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("got status %d, want 200", rr.Code)
+	}
+
+	// A different query string yields a different ETag
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/utilization/current?server=27000@a", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("got status %d, want 200 for different query", rr.Code)
+	}
+
+	if calls != 3 {
+		t.Errorf("handler called %d times, want 3", calls)
+	}
+}
+
+func TestConditionalMiddleware_ETagChangesWithCollection(t *testing.T) {
+	collected := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	version := func(ctx context.Context, server string) (map[string]time.Time, error) {
+		return map[string]time.Time{"27000@a": collected}, nil
+	}
+	handler := ConditionalMiddleware(version)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/utilization/current", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	etag := rr.Header().Get("ETag")
+
+	collected = collected.Add(5 * time.Minute)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/utilization/current", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("got status %d, want 200 after new collection", rr.Code)
+	}
+	if rr.Header().Get("ETag") == etag {
+		t.Error("expected ETag to change after new collection")
+	}
+}
+
+func TestConditionalMiddleware_VersionError(t *testing.T) {
+	version := func(ctx context.Context, server string) (map[string]time.Time, error) {
+		return nil, errors.New("db unavailable")
+	}
+	handler := ConditionalMiddleware(version)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/utilization/current", nil)
+	req.Header.Set("If-None-Match", "*")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("got status %d, want 200 when version lookup fails", rr.Code)
+	}
+	if rr.Header().Get("ETag") != "" {
+		t.Error("expected no ETag when version lookup fails")
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		etag   string
+		want   bool
+	}{
+		{`W/"abc"`, `W/"abc"`, true},
+		{`"abc"`, `W/"abc"`, true},
+		{`"xyz", W/"abc"`, `W/"abc"`, true},
+		{`*`, `W/"abc"`, true},
+		{`"xyz"`, `W/"abc"`, false},
+	}
+
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, tt.etag); got != tt.want {
+			t.Errorf("etagMatches(%q, %q) = %v, want %v", tt.header, tt.etag, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+	"licet/internal/config"
+)
+
+// OIDC endpoints, served without authentication
+const (
+	OIDCLoginPath    = "/auth/login"
+	OIDCCallbackPath = "/auth/callback"
+	OIDCLogoutPath   = "/auth/logout"
+)
+
+const (
+	sessionCookie   = "licet_session"
+	oidcStateCookie = "licet_oidc_state"
+
+	// oidcLoginTimeout bounds the time a user has to sign in at the provider
+	oidcLoginTimeout = 10 * time.Minute
+)
+
+// oidcProvider signs users in with the authorization code flow (with PKCE)
+type oidcProvider struct {
+	config        config.OIDCConfig
+	oauth         oauth2.Config
+	verifier      *oidc.IDTokenVerifier
+	endSessionURL string
+
+	mu      sync.Mutex
+	pending map[string]oidcLogin // By state
+}
+
+// oidcLogin is a sign-in started at the provider and not completed yet
+type oidcLogin struct {
+	nonce    string
+	verifier string // PKCE code verifier
+	redirect string // Page to return to
+	expires  time.Time
+}
+
+// EnableOIDC discovers the OpenID Connect provider of the auth.oidc settings
+// and signs web UI users in through it
+func (a *Authenticator) EnableOIDC(ctx context.Context) error {
+	cfg := a.config.OIDC
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return fmt.Errorf("failed to discover OIDC provider %s: %w", cfg.IssuerURL, err)
+	}
+	var discovery struct {
+		EndSessionEndpoint string `json:"end_session_endpoint"`
+	}
+	if err := provider.Claims(&discovery); err != nil {
+		return fmt.Errorf("failed to read OIDC provider metadata: %w", err)
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+	a.oidc = &oidcProvider{
+		config: cfg,
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier:      provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		endSessionURL: discovery.EndSessionEndpoint,
+		pending:       make(map[string]oidcLogin),
+	}
+	return nil
+}
+
+// isOIDCPath reports whether path is one of the OIDC endpoints
+func (a *Authenticator) isOIDCPath(path string) bool {
+	return a.oidc != nil && (path == OIDCLoginPath || path == OIDCCallbackPath || path == OIDCLogoutPath)
+}
+
+// randomToken returns a random hex string for states, nonces and sessions
+func randomToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// localRedirect returns target if it is a path on this server, "/" otherwise,
+// so a crafted login link cannot send users elsewhere
+func localRedirect(target string) string {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return "/"
+	}
+	return target
+}
+
+// secureCookies reports whether cookies should be limited to HTTPS
+func (p *oidcProvider) secureCookies() bool {
+	return strings.HasPrefix(p.config.RedirectURL, "https://")
+}
+
+// OIDCLogin handles GET /auth/login - sends the browser to the provider's
+// sign-in page, returning to ?redirect= afterwards
+func (a *Authenticator) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	p := a.oidc
+	login := oidcLogin{
+		nonce:    randomToken(),
+		verifier: oauth2.GenerateVerifier(),
+		redirect: localRedirect(r.URL.Query().Get("redirect")),
+		expires:  time.Now().Add(oidcLoginTimeout),
+	}
+	state := randomToken()
+
+	p.mu.Lock()
+	now := time.Now()
+	for s, l := range p.pending {
+		if now.After(l.expires) {
+			delete(p.pending, s)
+		}
+	}
+	p.pending[state] = login
+	p.mu.Unlock()
+
+	// Binds the state to this browser
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/auth/",
+		MaxAge:   int(oidcLoginTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   p.secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, p.oauth.AuthCodeURL(state, oidc.Nonce(login.nonce), oauth2.S256ChallengeOption(login.verifier)), http.StatusFound)
+}
+
+// OIDCCallback handles GET /auth/callback - completes a sign-in, starting a
+// session with the role mapped from the user's groups
+func (a *Authenticator) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	p := a.oidc
+	query := r.URL.Query()
+	if e := query.Get("error"); e != "" {
+		log.WithFields(log.Fields{"error": e, "description": query.Get("error_description")}).Warn("OIDC sign-in failed")
+		http.Error(w, "Sign-in failed: "+e, http.StatusUnauthorized)
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" || cookie.Value != state {
+		http.Error(w, "Invalid sign-in state, please try again", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/auth/", MaxAge: -1})
+
+	p.mu.Lock()
+	login, ok := p.pending[state]
+	delete(p.pending, state)
+	p.mu.Unlock()
+	if !ok || time.Now().After(login.expires) {
+		http.Error(w, "Sign-in expired, please try again", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	token, err := p.oauth.Exchange(ctx, query.Get("code"), oauth2.VerifierOption(login.verifier))
+	if err != nil {
+		log.WithError(err).Warn("OIDC code exchange failed")
+		http.Error(w, "Sign-in failed", http.StatusUnauthorized)
+		return
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil || idToken.Nonce != login.nonce {
+		log.WithError(err).Warn("OIDC ID token rejected")
+		http.Error(w, "Sign-in failed", http.StatusUnauthorized)
+		return
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		http.Error(w, "Sign-in failed", http.StatusUnauthorized)
+		return
+	}
+	username := p.username(claims, idToken.Subject)
+	role := p.role(claims)
+	if role == "" {
+		log.WithField("user", username).Warn("OIDC user is in no group mapped to a role")
+		http.Error(w, "Your account is not allowed to use Licet", http.StatusForbidden)
+		return
+	}
+
+	sessionToken := randomToken()
+	a.sessionMu.Lock()
+	a.sessions[sessionToken] = &session{
+		username:  username,
+		role:      role,
+		idToken:   rawIDToken,
+		expiresAt: time.Now().Add(a.sessionTimeout()),
+	}
+	a.sessionMu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    sessionToken,
+		Path:     "/",
+		MaxAge:   int(a.sessionTimeout().Seconds()),
+		HttpOnly: true,
+		Secure:   p.secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+	log.WithFields(log.Fields{"user": username, "role": role}).Info("OIDC sign-in")
+	http.Redirect(w, r, login.redirect, http.StatusFound)
+}
+
+// OIDCLogout handles GET /auth/logout - ends the session and, if the provider
+// supports it, the provider's session
+func (a *Authenticator) OIDCLogout(w http.ResponseWriter, r *http.Request) {
+	var idToken string
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		a.sessionMu.Lock()
+		if sess, ok := a.sessions[cookie.Value]; ok {
+			idToken = sess.idToken
+			delete(a.sessions, cookie.Value)
+		}
+		a.sessionMu.Unlock()
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1})
+
+	p := a.oidc
+	if p.endSessionURL == "" {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	params := url.Values{"client_id": {p.config.ClientID}}
+	if idToken != "" {
+		params.Set("id_token_hint", idToken)
+	}
+	if p.config.PostLogoutRedirectURL != "" {
+		params.Set("post_logout_redirect_uri", p.config.PostLogoutRedirectURL)
+	}
+	sep := "?"
+	if strings.Contains(p.endSessionURL, "?") {
+		sep = "&"
+	}
+	http.Redirect(w, r, p.endSessionURL+sep+params.Encode(), http.StatusFound)
+}
+
+// username reads the configured username claim, falling back to the email
+// and the subject
+func (p *oidcProvider) username(claims map[string]interface{}, subject string) string {
+	for _, claim := range []string{p.config.UsernameClaim, "email"} {
+		if name, ok := claims[claim].(string); ok && name != "" {
+			return name
+		}
+	}
+	return subject
+}
+
+// roleRank orders roles by the permissions they grant. Roles of auth.roles
+// rank between readonly and write.
+func roleRank(role string) int {
+	switch role {
+	case "":
+		return 0
+	case RoleReadonly:
+		return 1
+	case RoleWrite:
+		return 3
+	case RoleAdmin:
+		return 4
+	}
+	return 2
+}
+
+// role returns the highest role mapped from the user's groups, or the
+// default role
+func (p *oidcProvider) role(claims map[string]interface{}) string {
+	var groups []string
+	switch g := claims[p.config.GroupsClaim].(type) {
+	case string:
+		groups = []string{g}
+	case []interface{}:
+		for _, v := range g {
+			if s, ok := v.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+	}
+
+	role := p.config.DefaultRole
+	for _, m := range p.config.RoleMappings {
+		for _, g := range groups {
+			if g == m.Group && roleRank(m.Role) > roleRank(role) {
+				role = m.Role
+			}
+		}
+	}
+	return role
+}
+
+// authenticateSession authenticates a request by its OIDC session cookie
+func (a *Authenticator) authenticateSession(r *http.Request) (*AuthInfo, bool) {
+	if a.oidc == nil {
+		return nil, false
+	}
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil, false
+	}
+
+	a.sessionMu.RLock()
+	sess, ok := a.sessions[cookie.Value]
+	a.sessionMu.RUnlock()
+	if !ok || time.Now().After(sess.expiresAt) {
+		return nil, false
+	}
+	return &AuthInfo{
+		Authenticated: true,
+		Username:      sess.username,
+		Role:          sess.role,
+		Method:        "oidc",
+		Permissions:   a.permissions(sess.role, nil),
+	}, true
+}
+
+// sessionTimeout returns the lifetime of a session
+func (a *Authenticator) sessionTimeout() time.Duration {
+	if a.config.SessionTimeout > 0 {
+		return time.Duration(a.config.SessionTimeout) * time.Minute
+	}
+	return time.Hour
+}
+
+// wantsLogin reports whether an unauthenticated request is a browser loading
+// a page, to be sent to the sign-in page rather than answered with 401
+func (a *Authenticator) wantsLogin(r *http.Request) bool {
+	return a.oidc != nil && r.Method == http.MethodGet &&
+		!strings.HasPrefix(r.URL.Path, "/api/") &&
+		strings.Contains(r.Header.Get("Accept"), "text/html")
+}
@@ -0,0 +1,269 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"licet/internal/config"
+)
+
+// fakeOIDCProvider is an OpenID Connect provider issuing ID tokens for one
+// user, with the nonce of the last authorization request
+type fakeOIDCProvider struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	nonce  string
+	groups []string
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeOIDCProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                p.URL,
+			"authorization_endpoint":                p.URL + "/authorize",
+			"token_endpoint":                        p.URL + "/token",
+			"jwks_uri":                              p.URL + "/jwks",
+			"end_session_endpoint":                  p.URL + "/logout",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "good-code" || r.Form.Get("code_verifier") == "" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     p.idToken(t),
+		})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *fakeOIDCProvider) idToken(t *testing.T) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: p.key}, (&jose.SignerOptions{}).WithHeader("kid", "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":                p.URL,
+		"sub":                "u-123",
+		"aud":                "licet",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              p.nonce,
+		"preferred_username": "jdoe",
+		"groups":             p.groups,
+	})
+	signed, err := signer.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _ := signed.CompactSerialize()
+	return token
+}
+
+func newOIDCAuthenticator(t *testing.T, provider *fakeOIDCProvider) *Authenticator {
+	t.Helper()
+	auth := NewAuthenticator(config.AuthConfig{
+		Enabled:        true,
+		SessionTimeout: 60,
+		OIDC: config.OIDCConfig{
+			Enabled:       true,
+			IssuerURL:     provider.URL,
+			ClientID:      "licet",
+			ClientSecret:  "secret",
+			RedirectURL:   "http://licet.example.com/auth/callback",
+			UsernameClaim: "preferred_username",
+			GroupsClaim:   "groups",
+			RoleMappings: []config.OIDCRoleMapping{
+				{Group: "licet-users", Role: RoleReadonly},
+				{Group: "licet-admins", Role: RoleAdmin},
+			},
+		},
+	})
+	t.Cleanup(auth.Stop)
+	if err := auth.EnableOIDC(context.Background()); err != nil {
+		t.Fatalf("EnableOIDC failed: %v", err)
+	}
+	return auth
+}
+
+// signIn runs the authorization code flow and returns the callback response
+func signIn(t *testing.T, auth *Authenticator, provider *fakeOIDCProvider, redirect string) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	auth.OIDCLogin(rr, httptest.NewRequest(http.MethodGet, OIDCLoginPath+"?redirect="+url.QueryEscape(redirect), nil))
+	if rr.Code != http.StatusFound {
+		t.Fatalf("Expected a redirect to the provider, got %d", rr.Code)
+	}
+	authorize, _ := url.Parse(rr.Header().Get("Location"))
+	if !strings.HasPrefix(authorize.String(), provider.URL+"/authorize") || authorize.Query().Get("code_challenge") == "" {
+		t.Fatalf("Unexpected authorization URL %s", authorize)
+	}
+	provider.nonce = authorize.Query().Get("nonce")
+	state := authorize.Query().Get("state")
+
+	req := httptest.NewRequest(http.MethodGet, OIDCCallbackPath+"?code=good-code&state="+state, nil)
+	for _, c := range rr.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	rr = httptest.NewRecorder()
+	auth.OIDCCallback(rr, req)
+	return rr
+}
+
+func sessionCookieOf(rr *httptest.ResponseRecorder) *http.Cookie {
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == sessionCookie && c.Value != "" {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestOIDC_SignInAndLogout(t *testing.T) {
+	provider := newFakeOIDCProvider(t)
+	provider.groups = []string{"licet-users", "licet-admins"}
+	auth := newOIDCAuthenticator(t, provider)
+
+	handler := AuthMiddleware(auth)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(GetAuthInfo(r))
+	}))
+
+	// Browsers are sent to the sign-in page, API clients get 401
+	req := httptest.NewRequest(http.MethodGet, "/utilization?days=7", nil)
+	req.Header.Set("Accept", "text/html")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusFound || rr.Header().Get("Location") != "/auth/login?redirect=%2Futilization%3Fdays%3D7" {
+		t.Errorf("Expected a redirect to the sign-in page, got %d %s", rr.Code, rr.Header().Get("Location"))
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/servers", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for the API, got %d", rr.Code)
+	}
+
+	rr = signIn(t, auth, provider, "/utilization?days=7")
+	if rr.Code != http.StatusFound || rr.Header().Get("Location") != "/utilization?days=7" {
+		t.Fatalf("Expected a redirect back to the page, got %d %s: %s", rr.Code, rr.Header().Get("Location"), rr.Body.String())
+	}
+	cookie := sessionCookieOf(rr)
+	if cookie == nil || !cookie.HttpOnly {
+		t.Fatal("Expected an HttpOnly session cookie")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/servers", nil)
+	req.AddCookie(cookie)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	var info AuthInfo
+	json.NewDecoder(rr.Body).Decode(&info)
+	if !info.Authenticated || info.Username != "jdoe" || info.Role != RoleAdmin || info.Method != "oidc" {
+		t.Errorf("Unexpected auth info %+v", info)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, OIDCLogoutPath, nil)
+	req.AddCookie(cookie)
+	rr = httptest.NewRecorder()
+	auth.OIDCLogout(rr, req)
+	logout, _ := url.Parse(rr.Header().Get("Location"))
+	if !strings.HasPrefix(logout.String(), provider.URL+"/logout") || logout.Query().Get("id_token_hint") == "" {
+		t.Errorf("Expected a redirect to the provider's logout, got %s", logout)
+	}
+	if _, ok := auth.authenticateSession(req); ok {
+		t.Error("Expected the session to end")
+	}
+}
+
+func TestOIDC_Callback_Rejects(t *testing.T) {
+	provider := newFakeOIDCProvider(t)
+	auth := newOIDCAuthenticator(t, provider)
+
+	// Not in a mapped group and no default role
+	provider.groups = []string{"other"}
+	if rr := signIn(t, auth, provider, "/"); rr.Code != http.StatusForbidden || sessionCookieOf(rr) != nil {
+		t.Errorf("Expected 403 without a session, got %d", rr.Code)
+	}
+
+	// State not bound to the browser
+	rr := httptest.NewRecorder()
+	auth.OIDCCallback(rr, httptest.NewRequest(http.MethodGet, OIDCCallbackPath+"?code=good-code&state=forged", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a forged state, got %d", rr.Code)
+	}
+
+	// Error returned by the provider
+	rr = httptest.NewRecorder()
+	auth.OIDCCallback(rr, httptest.NewRequest(http.MethodGet, OIDCCallbackPath+"?error=access_denied", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a provider error, got %d", rr.Code)
+	}
+}
+
+func TestOIDCRole(t *testing.T) {
+	p := &oidcProvider{config: config.OIDCConfig{
+		GroupsClaim: "groups",
+		DefaultRole: RoleReadonly,
+		RoleMappings: []config.OIDCRoleMapping{
+			{Group: "ops", Role: RoleWrite},
+			{Group: "admins", Role: RoleAdmin},
+		},
+	}}
+	tests := []struct {
+		groups interface{}
+		want   string
+	}{
+		{nil, RoleReadonly},
+		{"ops", RoleWrite},
+		{[]interface{}{"ops", "admins"}, RoleAdmin},
+		{[]interface{}{"admins", "ops"}, RoleAdmin},
+	}
+	for _, tt := range tests {
+		if got := p.role(map[string]interface{}{"groups": tt.groups}); got != tt.want {
+			t.Errorf("role(%v) = %q, want %q", tt.groups, got, tt.want)
+		}
+	}
+}
+
+func TestLocalRedirect(t *testing.T) {
+	for target, want := range map[string]string{
+		"/utilization?days=7":  "/utilization?days=7",
+		"":                     "/",
+		"https://evil.example": "/",
+		"//evil.example":       "/",
+		"/\\evil.example":      "/",
+	} {
+		if got := localRedirect(target); got != want {
+			t.Errorf("localRedirect(%q) = %q, want %q", target, got, want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+)
+
+// Fine-grained permissions, required by the routes annotated with them
+// instead of the permission of their HTTP method
+const (
+	PermissionServersWrite  = "servers:write"  // Add, remove, test and refresh servers
+	PermissionAlertsManage  = "alerts:manage"  // Alert rules, thresholds, silences and alert settings
+	PermissionExportsRun    = "exports:run"    // Run and download exports
+	PermissionSettingsWrite = "settings:write" // Email settings and display names
+)
+
+// rolePermissions are the permissions of the built-in roles. Admins have
+// every permission.
+var rolePermissions = map[string][]string{
+	RoleWrite:    {PermissionRead, PermissionWrite, PermissionServersWrite, PermissionAlertsManage, PermissionExportsRun, PermissionSettingsWrite},
+	RoleReadonly: {PermissionRead, PermissionExportsRun},
+}
+
+// Can reports whether the request this auth info belongs to has a permission.
+// Auth info without resolved permissions has those of its role.
+func (i *AuthInfo) Can(permission string) bool {
+	if i.Role == RoleAdmin {
+		return true
+	}
+	if i.Permissions == nil {
+		return HasPermission(i.Role, permission)
+	}
+	return contains(i.Permissions, permission)
+}
+
+// RoleHasPermission checks if a built-in role or a role of auth.roles has a
+// permission
+func (a *Authenticator) RoleHasPermission(role, permission string) bool {
+	if custom, ok := a.config.Roles[role]; ok {
+		return contains(custom, permission)
+	}
+	return HasPermission(role, permission)
+}
+
+// permissions returns the permissions of a role together with the extra
+// permissions of an API key or user
+func (a *Authenticator) permissions(role string, extra []string) []string {
+	if role == RoleAdmin {
+		return []string{PermissionAdmin}
+	}
+	set := make(map[string]bool)
+	perms, ok := a.config.Roles[role]
+	if !ok {
+		perms = rolePermissions[role]
+	}
+	for _, p := range append(append([]string{}, perms...), extra...) {
+		set[p] = true
+	}
+	list := make([]string, 0, len(set))
+	for p := range set {
+		list = append(list, p)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// SetRoutePermissions sets the function returning the permission annotated
+// on the route a request is served by, or an empty string for routes that
+// require the permission of their method
+func (a *Authenticator) SetRoutePermissions(routePermission func(r *http.Request) string) {
+	a.routePermission = routePermission
+}
+
+// requiredPermission returns the permission a request requires: that of its
+// route, else that of its method
+func (a *Authenticator) requiredPermission(r *http.Request) string {
+	if a.routePermission != nil {
+		if perm := a.routePermission(r); perm != "" {
+			return perm
+		}
+	}
+	return RequiredPermission(r.Method)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
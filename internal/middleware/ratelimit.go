@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -50,6 +51,8 @@ type RateLimiter struct {
 	whitelistedIPs   map[string]bool
 	whitelistedPaths []string
 	stopCh           chan struct{}
+
+	rejected atomic.Int64
 }
 
 // NewRateLimiter creates a new rate limiter instance
@@ -149,6 +152,8 @@ func (rl *RateLimiter) Allow(ip string) (bool, int, time.Time) {
 		return true, int(entry.tokens), time.Time{}
 	}
 
+	rl.rejected.Add(1)
+
 	// Calculate when the next token will be available
 	waitTime := (1.0 - entry.tokens) / rl.tokensPerSecond
 	retryAfter := now.Add(time.Duration(waitTime * float64(time.Second)))
@@ -176,6 +181,7 @@ func (rl *RateLimiter) Stats() map[string]interface{} {
 		"requests_per_minute": rl.config.RequestsPerMinute,
 		"burst_size":          rl.config.BurstSize,
 		"enabled":             rl.config.Enabled,
+		"rejected":            rl.rejected.Load(),
 	}
 }
 
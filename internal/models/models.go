@@ -1,9 +1,32 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
+// BuildInfo identifies the build of a running binary
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+}
+
+// SystemInfo describes a running instance for support tickets and for
+// clients that hide disabled features
+type SystemInfo struct {
+	BuildInfo
+	GoVersion     string          `json:"go_version"`
+	OS            string          `json:"os"`
+	Arch          string          `json:"arch"`
+	Database      string          `json:"database"`
+	Servers       int             `json:"servers"`
+	Subsystems    map[string]bool `json:"subsystems"`
+	FeatureFlags  map[string]bool `json:"feature_flags"`
+	StartedAt     time.Time       `json:"started_at"`
+	UptimeSeconds int64           `json:"uptime_seconds"`
+}
+
 // LicenseServer represents a configured license server
 type LicenseServer struct {
 	ID          int64     `db:"id" json:"id"`
@@ -12,8 +35,17 @@ type LicenseServer struct {
 	Type        string    `db:"type" json:"type"`
 	CactiID     string    `db:"cacti_id" json:"cacti_id,omitempty"`
 	WebUI       string    `db:"webui" json:"webui,omitempty"`
+	Group       string    `db:"group_name" json:"group,omitempty"` // Site or group, e.g. emea
 	CreatedAt   time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+
+	PollInterval int    `db:"-" json:"poll_interval,omitempty"` // Minutes between polls, if not the global interval
+	Schedule     string `db:"-" json:"schedule,omitempty"`      // Cron expression of the polls
+	Jitter       int    `db:"-" json:"jitter,omitempty"`        // Seconds of random delay before each poll
+	Timeout      int    `db:"-" json:"timeout,omitempty"`       // Seconds before a query is abandoned, if not the global timeout
+
+	ResourceID int64  `db:"-" json:"resource_id,omitempty"` // Stable id, see Resource
+	UUID       string `db:"-" json:"uuid,omitempty"`
 }
 
 // ServerStatus represents the current status of a license server
@@ -23,33 +55,81 @@ type ServerStatus struct {
 	Master      string    `json:"master"`
 	Version     string    `json:"version"`
 	Message     string    `json:"message,omitempty"`
+	Warnings    []string  `json:"warnings,omitempty"` // Output that could not be parsed, e.g. unknown date formats
 	LastChecked time.Time `json:"last_checked"`
 }
 
+// License models describe how a feature's seats are counted
+const (
+	LicenseModelFloating   = "floating"
+	LicenseModelNodeLocked = "node-locked"
+	LicenseModelUncounted  = "uncounted"
+)
+
+// Parse quality describes whether a feature's data was parsed as reported
+const (
+	ParseQualityOK = "ok"
+	// ParseQualityDefaultedExpiration means the expiration date could not be
+	// parsed and was stored as permanent, so expiration alerts cannot fire
+	ParseQualityDefaultedExpiration = "defaulted_expiration"
+)
+
 // Feature represents a license feature
 type Feature struct {
+	ID                int64     `db:"id" json:"id"`
+	ServerHostname    string    `db:"server_hostname" json:"server_hostname"`
+	Name              string    `db:"name" json:"name"`
+	Version           string    `db:"version" json:"version"`
+	VendorDaemon      string    `db:"vendor_daemon" json:"vendor_daemon"`
+	TotalLicenses     int       `db:"total_licenses" json:"total_licenses"`
+	UsedLicenses      int       `db:"used_licenses" json:"used_licenses"`
+	ReservedLicenses  int       `db:"reserved_licenses" json:"reserved_licenses"`   // Seats held by RESERVATION lines
+	BorrowedLicenses  int       `db:"borrowed_licenses" json:"borrowed_licenses"`   // Seats in use that are borrowed, until they are returned
+	OverdraftLicenses int       `db:"overdraft_licenses" json:"overdraft_licenses"` // Seats allowed beyond the issued count
+	QueuedRequests    int       `db:"queued_requests" json:"queued_requests"`       // Checkout requests waiting for a free seat
+	LicenseModel      string    `db:"license_model" json:"license_model"`           // floating, node-locked, uncounted
+	ParseQuality      string    `db:"parse_quality" json:"parse_quality"`           // ok, or which value fell back to a default
+	ExpirationDate    time.Time `db:"expiration_date" json:"expiration_date"`
+	DaysToExpire      int       `json:"days_to_expire"`
+	LastUpdated       time.Time `db:"last_updated" json:"last_updated"`
+	IsActive          bool      `db:"is_active" json:"is_active"`
+	DisplayName       string    `db:"-" json:"display_name,omitempty"`
+	VendorName        string    `db:"-" json:"vendor_display_name,omitempty"`
+	ResourceID        int64     `db:"-" json:"resource_id,omitempty"` // Stable id shared by all versions, see Resource
+	UUID              string    `db:"-" json:"uuid,omitempty"`
+}
+
+// Resource is the stable identity of a server or a feature of a server.
+// Unlike row ids and names, its id and UUID never change and are safe to use
+// in URLs.
+type Resource struct {
 	ID             int64     `db:"id" json:"id"`
+	Kind           string    `db:"kind" json:"kind"` // server or feature
 	ServerHostname string    `db:"server_hostname" json:"server_hostname"`
-	Name           string    `db:"name" json:"name"`
-	Version        string    `db:"version" json:"version"`
-	VendorDaemon   string    `db:"vendor_daemon" json:"vendor_daemon"`
-	TotalLicenses  int       `db:"total_licenses" json:"total_licenses"`
-	UsedLicenses   int       `db:"used_licenses" json:"used_licenses"`
-	ExpirationDate time.Time `db:"expiration_date" json:"expiration_date"`
-	DaysToExpire   int       `json:"days_to_expire"`
-	LastUpdated    time.Time `db:"last_updated" json:"last_updated"`
-	IsActive       bool      `db:"is_active" json:"is_active"`
+	Name           string    `db:"name" json:"name,omitempty"` // Feature name
+	UUID           string    `db:"uuid" json:"uuid"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
 }
 
-// AvailableLicenses returns the number of available (unused) licenses
+// AvailableLicenses returns the number of licenses anyone can check out,
+// excluding seats that are in use or reserved
 func (f *Feature) AvailableLicenses() int {
-	available := f.TotalLicenses - f.UsedLicenses
+	available := f.TotalLicenses - f.UsedLicenses - f.ReservedLicenses
 	if available < 0 {
 		return 0
 	}
 	return available
 }
 
+// CountedLicenses returns the number of seats that count towards utilization.
+// Uncounted licenses have no seat limit, so they have no capacity to measure.
+func (f *Feature) CountedLicenses() int {
+	if f.LicenseModel == LicenseModelUncounted {
+		return 0
+	}
+	return f.TotalLicenses
+}
+
 // DaysToExpiration returns the number of days until the license expires
 // Returns negative number if already expired
 func (f *Feature) DaysToExpiration() int {
@@ -64,8 +144,9 @@ type FeatureUsage struct {
 	ServerHostname string    `db:"server_hostname" json:"server_hostname"`
 	FeatureName    string    `db:"feature_name" json:"feature_name"`
 	Date           time.Time `db:"date" json:"date"`
-	Time           time.Time `db:"time" json:"time"`
+	Time           string    `db:"time" json:"time"` // HH:MM:SS; TIME columns do not scan into time.Time
 	UsersCount     int       `db:"users_count" json:"users_count"`
+	QueuedCount    int       `db:"queued_count" json:"queued_count"` // Checkout requests waiting for a free seat
 }
 
 // LicenseUser represents a user currently using a license
@@ -78,30 +159,576 @@ type LicenseUser struct {
 	Version        string    `json:"version,omitempty"`         // Client software version (for display)
 	LicenseVersion string    `json:"license_version,omitempty"` // License pool version (for matching to features)
 	Display        string    `json:"display,omitempty"`
+	Tokens         int       `json:"tokens,omitempty"` // Seats or tokens the checkout holds, when more than one
+}
+
+// Seats returns the number of seats or tokens the checkout holds
+func (u *LicenseUser) Seats() int {
+	if u.Tokens > 1 {
+		return u.Tokens
+	}
+	return 1
 }
 
 // Alert represents a license alert
 type Alert struct {
+	ID             int64       `db:"id" json:"id"`
+	ServerHostname string      `db:"server_hostname" json:"server_hostname"`
+	FeatureName    string      `db:"feature_name" json:"feature_name"`
+	AlertType      string      `db:"alert_type" json:"alert_type"` // expiration, down, denial, failover
+	Message        string      `db:"message" json:"message"`
+	Severity       string      `db:"severity" json:"severity"` // info, warning, critical
+	Sent           bool        `db:"sent" json:"sent"`
+	SentAt         *time.Time  `db:"sent_at" json:"sent_at,omitempty"`
+	CreatedAt      time.Time   `db:"created_at" json:"created_at"`
+	IncidentID     *int64      `db:"incident_id" json:"incident_id,omitempty"`
+	SilenceID      *string     `db:"silence_id" json:"silence_id,omitempty"` // Alertmanager silence that suppressed sending
+	Links          *AlertLinks `db:"-" json:"links,omitempty"`
+}
+
+// AlertThreshold overrides the utilization alert thresholds for a feature.
+// An empty server applies to every server, an empty feature to every feature
+// of the server.
+type AlertThreshold struct {
+	ID             int64     `db:"id" json:"id"`
+	ServerHostname string    `db:"server_hostname" json:"server_hostname"`
+	FeatureName    string    `db:"feature_name" json:"feature_name"`
+	WarningPct     float64   `db:"warning_pct" json:"warning_pct"`
+	CriticalPct    float64   `db:"critical_pct" json:"critical_pct"`
+	UpdatedBy      string    `db:"updated_by" json:"updated_by"`
+	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// LicenseContract is the cost and contract details of a feature, on one
+// server or every server (empty server)
+type LicenseContract struct {
 	ID             int64      `db:"id" json:"id"`
 	ServerHostname string     `db:"server_hostname" json:"server_hostname"`
 	FeatureName    string     `db:"feature_name" json:"feature_name"`
-	AlertType      string     `db:"alert_type" json:"alert_type"` // expiration, down, denial
-	Message        string     `db:"message" json:"message"`
-	Severity       string     `db:"severity" json:"severity"` // info, warning, critical
-	Sent           bool       `db:"sent" json:"sent"`
-	SentAt         *time.Time `db:"sent_at" json:"sent_at,omitempty"`
+	CostPerSeat    float64    `db:"cost_per_seat" json:"cost_per_seat"` // Per seat and year
+	Currency       string     `db:"currency" json:"currency"`           // ISO 4217 code
+	RenewalDate    *time.Time `db:"renewal_date" json:"renewal_date,omitempty"`
+	PONumber       string     `db:"po_number" json:"po_number,omitempty"`
+	VendorContact  string     `db:"vendor_contact" json:"vendor_contact,omitempty"`
+	Notes          string     `db:"notes" json:"notes,omitempty"`
+	UpdatedBy      string     `db:"updated_by" json:"updated_by"`
 	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// FeatureTag groups a feature for reporting, e.g. by department, on one
+// server or every server (empty server)
+type FeatureTag struct {
+	ID             int64     `db:"id" json:"id"`
+	ServerHostname string    `db:"server_hostname" json:"server_hostname"`
+	FeatureName    string    `db:"feature_name" json:"feature_name"`
+	Tag            string    `db:"tag" json:"tag"`
+	CreatedBy      string    `db:"created_by" json:"created_by"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+}
+
+// TagSummary is a tag and how many features carry it
+type TagSummary struct {
+	Tag      string `db:"tag" json:"tag"`
+	Features int    `db:"features" json:"features"`
+}
+
+// Alert rule types
+const (
+	AlertRuleUtilization  = "utilization"   // Threshold is a utilization percentage
+	AlertRuleDown         = "down"          // Threshold is unused
+	AlertRuleExpiration   = "expiration"    // Threshold is a number of days
+	AlertRuleLongCheckout = "long_checkout" // Threshold is a number of hours
+)
+
+// AlertRule raises an alert when its condition holds for a server or feature
+// for at least DurationMin minutes. An empty server matches every server, an
+// empty feature pattern every feature.
+type AlertRule struct {
+	ID             int64     `db:"id" json:"id"`
+	Name           string    `db:"name" json:"name"`
+	RuleType       string    `db:"rule_type" json:"rule_type"`
+	ServerHostname string    `db:"server_hostname" json:"server_hostname"`
+	FeaturePattern string    `db:"feature_pattern" json:"feature_pattern"` // Regular expression
+	Threshold      float64   `db:"threshold" json:"threshold"`
+	DurationMin    int       `db:"duration_min" json:"duration_min"`
+	Severity       string    `db:"severity" json:"severity"`
+	Enabled        bool      `db:"enabled" json:"enabled"`
+	CreatedBy      string    `db:"created_by" json:"created_by"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// CollectionGap is a period in which scheduled polls of a server recorded
+// no usage
+type CollectionGap struct {
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	MissedPolls int       `json:"missed_polls"`
+}
+
+// ServerDataQuality is how completely a server was collected
+type ServerDataQuality struct {
+	Server        string          `json:"server"`
+	Schedule      string          `json:"schedule"`
+	Samples       int             `json:"samples"`  // Polls that recorded usage
+	Expected      int             `json:"expected"` // Polls scheduled in the period
+	Coverage      float64         `json:"coverage"` // Percent of the expected polls recorded
+	LastCollected *time.Time      `json:"last_collected,omitempty"`
+	Gaps          []CollectionGap `json:"gaps"`
+}
+
+// StaleFeature is an active feature that was not updated recently
+type StaleFeature struct {
+	Server      string    `json:"server"`
+	Feature     string    `json:"feature"`
+	Version     string    `json:"version"`
+	LastUpdated time.Time `json:"last_updated"`
+	Configured  bool      `json:"configured"` // False once the server was removed from the configuration
+}
+
+// DuplicateSuspect is a versioned feature row with unversioned rows that
+// duplicate it
+type DuplicateSuspect struct {
+	Server         string    `json:"server"`
+	Feature        string    `json:"feature"`
+	Version        string    `json:"version"`
+	ExpirationDate time.Time `json:"expiration_date"`
+	DuplicateIDs   []int64   `json:"duplicate_ids"`
+}
+
+// DataQualitySummary counts the issues of a DataQualityReport
+type DataQualitySummary struct {
+	ServersWithGaps int `json:"servers_with_gaps"`
+	Gaps            int `json:"gaps"`
+	StaleFeatures   int `json:"stale_features"`
+	ParseWarnings   int `json:"parse_warnings"`
+	Duplicates      int `json:"duplicates"`
+}
+
+// DataQualityReport summarizes what could make the collected numbers wrong
+type DataQualityReport struct {
+	GeneratedAt   time.Time           `json:"generated_at"`
+	Days          int                 `json:"days"`
+	StaleHours    int                 `json:"stale_hours"`
+	Summary       DataQualitySummary  `json:"summary"`
+	Servers       []ServerDataQuality `json:"servers"`
+	StaleFeatures []StaleFeature      `json:"stale_features"`
+	ParseWarnings []Feature           `json:"parse_warnings"`
+	Duplicates    []DuplicateSuspect  `json:"duplicates"`
+}
+
+// Job states
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+// Job is long-running work run by the job queue, e.g. refreshing a server
+// or generating an export. Failed attempts are retried until MaxAttempts.
+type Job struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	Server      string          `json:"server,omitempty"`
+	Params      json.RawMessage `json:"params,omitempty"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	RunAfter    time.Time       `json:"run_after"`
+	CreatedAt   time.Time       `json:"created_at"`
+	StartedAt   *time.Time      `json:"started_at,omitempty"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+}
+
+// JobOutput is the result of a job producing a file to download, such as an
+// export or archive
+type JobOutput struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// ComputedServer is the virtual server holding computed metrics as features
+const ComputedServer = "computed"
+
+// ComputedMetric is a derived series, e.g. used(a)+2*used(b), stored after
+// each collection as a feature of the ComputedServer
+type ComputedMetric struct {
+	Name        string    `db:"name" json:"name"`
+	Expression  string    `db:"expression" json:"expression"` // Seats in use
+	Capacity    string    `db:"capacity" json:"capacity"`     // Seats issued; empty reads total seats in the expression
+	Description string    `db:"description" json:"description"`
+	CreatedBy   string    `db:"created_by" json:"created_by"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// WebhookDelivery is one attempt to deliver an event to a webhook endpoint
+type WebhookDelivery struct {
+	ID         int64     `db:"id" json:"id"`
+	Webhook    string    `db:"webhook" json:"webhook"`
+	URL        string    `db:"url" json:"url"`
+	Event      string    `db:"event" json:"event"`
+	Payload    string    `db:"payload" json:"payload"`
+	Attempt    int       `db:"attempt" json:"attempt"`
+	StatusCode int       `db:"status_code" json:"status_code"` // 0 when no response was received
+	Success    bool      `db:"success" json:"success"`
+	Error      string    `db:"error" json:"error,omitempty"`
+	DurationMs int64     `db:"duration_ms" json:"duration_ms"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+// Outbound email states
+const (
+	EmailQueued = "queued"
+	EmailSent   = "sent"
+	EmailDead   = "dead" // Gave up after MaxAttempts; kept until requeued
+)
+
+// OutboundEmail is an email of the outbox. Failed attempts are retried with
+// backoff until MaxAttempts, after which the email is a dead letter.
+type OutboundEmail struct {
+	ID            int64      `json:"id"`
+	Subject       string     `json:"subject"`
+	Recipients    []string   `json:"recipients"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	MaxAttempts   int        `json:"max_attempts"`
+	Error         string     `json:"error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
+
+// Alert delivery channels and states
+const (
+	DeliveryChannelEmail   = "email"
+	DeliveryChannelWebhook = "webhook"
+
+	DeliveryQueued = "queued" // Waiting in the email outbox
+	DeliverySent   = "sent"   // Accepted by the SMTP server or webhook endpoint
+	DeliveryFailed = "failed"
+)
+
+// AlertDelivery is the receipt of an alert notification sent to one target
+// of a channel: an email address or a webhook endpoint
+type AlertDelivery struct {
+	ID        int64     `db:"id" json:"id"`
+	AlertID   int64     `db:"alert_id" json:"alert_id"`
+	Channel   string    `db:"channel" json:"channel"`
+	Target    string    `db:"target" json:"target"`
+	Status    string    `db:"status" json:"status"`
+	MessageID string    `db:"message_id" json:"message_id,omitempty"` // Message-ID of the email or request ID returned by the webhook
+	Error     string    `db:"error" json:"error,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// WaitlistSubscription is a user waiting for a seat of a feature to free up,
+// on one server or any (empty server). It is notified once.
+type WaitlistSubscription struct {
+	ID             int64      `db:"id" json:"id"`
+	Username       string     `db:"username" json:"username"`
+	Email          string     `db:"email" json:"email"`
+	ServerHostname string     `db:"server_hostname" json:"server_hostname,omitempty"`
+	FeatureName    string     `db:"feature_name" json:"feature_name"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	NotifiedAt     *time.Time `db:"notified_at" json:"notified_at,omitempty"` // Nil while waiting
+}
+
+// SilenceMatcher matches an alert label, following Alertmanager semantics
+type SilenceMatcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"is_regex"`
+	IsEqual bool   `json:"is_equal"`
+}
+
+// AlertSilence is an Alertmanager silence mirrored into Licet. Alerts whose
+// labels match every matcher while the silence is active are not sent.
+type AlertSilence struct {
+	ID           string           `db:"id" json:"id"`
+	Matchers     []SilenceMatcher `db:"-" json:"matchers"`
+	MatchersJSON string           `db:"matchers" json:"-"`
+	StartsAt     time.Time        `db:"starts_at" json:"starts_at"`
+	EndsAt       time.Time        `db:"ends_at" json:"ends_at"`
+	CreatedBy    string           `db:"created_by" json:"created_by"`
+	Comment      string           `db:"comment" json:"comment"`
+	UpdatedAt    time.Time        `db:"updated_at" json:"updated_at"`
+}
+
+// Incident groups related alerts for the same server. An incident is open
+// while new alerts keep arriving within the correlation window.
+type Incident struct {
+	ID             int64     `db:"id" json:"id"`
+	ServerHostname string    `db:"server_hostname" json:"server_hostname"`
+	RootCause      string    `db:"root_cause" json:"root_cause"` // type of the first alert
+	Severity       string    `db:"severity" json:"severity"`     // highest alert severity
+	AlertCount     int       `db:"alert_count" json:"alert_count"`
+	OpenedAt       time.Time `db:"opened_at" json:"opened_at"`
+	LastAlertAt    time.Time `db:"last_alert_at" json:"last_alert_at"`
+	Notified       bool      `db:"notified" json:"notified"`
+	Status         string    `db:"-" json:"status"` // open, resolved
+	Alerts         []Alert   `db:"-" json:"alerts,omitempty"`
+}
+
+// AlertLinks are the deep links attached to an alert notification
+type AlertLinks struct {
+	Details string `json:"details,omitempty"`
+	Trend   string `json:"trend,omitempty"`
+	Runbook string `json:"runbook,omitempty"`
+}
+
+// FeatureDisplayName is a manual display name override for a feature.
+// An empty ServerHostname applies the override to all servers.
+type FeatureDisplayName struct {
+	ID             int64     `db:"id" json:"id"`
+	ServerHostname string    `db:"server_hostname" json:"server_hostname"`
+	FeatureName    string    `db:"feature_name" json:"feature_name"`
+	DisplayName    string    `db:"display_name" json:"display_name"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+}
+
+// FeatureUser records when a user was first and last seen using a feature
+type FeatureUser struct {
+	ID             int64     `db:"id" json:"id"`
+	ServerHostname string    `db:"server_hostname" json:"server_hostname"`
+	FeatureName    string    `db:"feature_name" json:"feature_name"`
+	Username       string    `db:"username" json:"username"`
+	FirstSeen      time.Time `db:"first_seen" json:"first_seen"`
+	LastSeen       time.Time `db:"last_seen" json:"last_seen"`
+}
+
+// UserUsage summarizes a user's checkouts of a feature
+type UserUsage struct {
+	ServerHostname string    `json:"server_hostname,omitempty"` // Empty when summed over all servers
+	FeatureName    string    `json:"feature_name"`
+	Username       string    `json:"username"`
+	Checkouts      int       `json:"checkouts"`
+	TotalHours     float64   `json:"total_hours"`
+	LastSeen       time.Time `json:"last_seen"`
+	Active         bool      `json:"active"` // A checkout is still held
+}
+
+// Checkout is one seat of a feature held by a user from a host
+type Checkout struct {
+	ServerHostname string     `json:"server_hostname"`
+	FeatureName    string     `json:"feature_name"`
+	Username       string     `json:"username"`
+	Host           string     `json:"host"`
+	CheckedOutAt   time.Time  `json:"checked_out_at"`
+	LastSeen       time.Time  `json:"last_seen"`
+	CheckedInAt    *time.Time `json:"checked_in_at,omitempty"` // Nil while still held
+	HeldHours      float64    `json:"held_hours"`              // Until check-in, or the last sighting while held
+}
+
+// ChargebackEntry is the checkout time of the users of a cost center on a
+// feature in a month
+type ChargebackEntry struct {
+	Month          string    `db:"month" json:"month"` // YYYY-MM
+	CostCenter     string    `db:"cost_center" json:"cost_center"`
+	ServerHostname string    `db:"server_hostname" json:"server_hostname"`
+	FeatureName    string    `db:"feature_name" json:"feature_name"`
+	CheckoutHours  float64   `db:"checkout_hours" json:"checkout_hours"`
+	Checkouts      int       `db:"checkouts" json:"checkouts"`
+	Users          int       `db:"users" json:"users"` // Distinct users
+	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// CostCenterTotal sums the checkout hours of a cost center over a chargeback
+// report
+type CostCenterTotal struct {
+	CostCenter    string  `json:"cost_center"`
+	CheckoutHours float64 `json:"checkout_hours"`
+	SharePct      float64 `json:"share_pct"` // Of the checkout hours of all cost centers
+}
+
+// ReportLogImport is the outcome of importing a report log of a server
+type ReportLogImport struct {
+	Records  int       `json:"records"`
+	Sessions int       `json:"sessions"` // Checkouts with their check-in, replacing polled ones
+	Open     int       `json:"open"`     // Checkouts not checked in by the end of the log, left to polling
+	Samples  int       `json:"samples"`  // Hourly peaks recorded as usage samples
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+}
+
+// UserDigest lists users who started or stopped using features during a period
+type UserDigest struct {
+	PeriodStart  time.Time     `json:"period_start"`
+	PeriodEnd    time.Time     `json:"period_end"`
+	InactiveDays int           `json:"inactive_days"`
+	NewUsers     []FeatureUser `json:"new_users"`
+	RemovedUsers []FeatureUser `json:"removed_users"`
+}
+
+// AuditEntry records an administrative operation or a mutating API request.
+// Request entries have the action "api_request", the route as target and a
+// summary of the payload as details.
+type AuditEntry struct {
+	ID         int64     `db:"id" json:"id"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	Actor      string    `db:"actor" json:"actor"`
+	Action     string    `db:"action" json:"action"`
+	Target     string    `db:"target" json:"target"`
+	Details    string    `db:"details" json:"details"`
+	Role       string    `db:"role" json:"role,omitempty"`
+	RemoteAddr string    `db:"remote_addr" json:"remote_addr,omitempty"`
+	Method     string    `db:"method" json:"method,omitempty"`
+	Path       string    `db:"path" json:"path,omitempty"`
+	Status     int       `db:"status" json:"status,omitempty"`
+	DurationMS int64     `db:"duration_ms" json:"duration_ms,omitempty"`
+}
+
+// AuditActionRequest is the action of audit entries of API requests
+const AuditActionRequest = "api_request"
+
+// AnonymizationReport describes the records rewritten for a data subject
+// deletion request. The original username is deliberately not included.
+type AnonymizationReport struct {
+	Pseudonym    string           `json:"pseudonym"`
+	RowsAffected map[string]int64 `json:"rows_affected"` // table -> rows
+	TotalRows    int64            `json:"total_rows"`
+	PerformedAt  time.Time        `json:"performed_at"`
+}
+
+// FeatureFlag is a toggle for an experimental subsystem
+type FeatureFlag struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Enabled     bool       `json:"enabled"`
+	Default     bool       `json:"default"`    // Value from the configuration, or built in
+	Overridden  bool       `json:"overridden"` // Enabled was toggled at runtime
+	UpdatedBy   string     `json:"updated_by,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// SnapshotManifest describes a server history archive exported for import
+// into another Licet instance
+type SnapshotManifest struct {
+	Format     string           `json:"format"`
+	Version    int              `json:"version"`
+	Server     string           `json:"server"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Rows       map[string]int64 `json:"rows"` // table -> rows
+}
+
+// SnapshotImport reports the rows restored from a server history archive
+type SnapshotImport struct {
+	Server     string           `json:"server"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Replaced   bool             `json:"replaced"`
+	Rows       map[string]int64 `json:"rows"` // table -> rows
+	TotalRows  int64            `json:"total_rows"`
+	ImportedAt time.Time        `json:"imported_at"`
+}
+
+// MasterChange records a move of the MASTER role between the hosts of a
+// redundant license server
+type MasterChange struct {
+	ID             int64     `db:"id" json:"id"`
+	ServerHostname string    `db:"server_hostname" json:"server_hostname"`
+	PreviousMaster string    `db:"previous_master" json:"previous_master"`
+	NewMaster      string    `db:"new_master" json:"new_master"`
+	DetectedAt     time.Time `db:"detected_at" json:"detected_at"`
 }
 
 // LicenseEvent represents a license checkout or denial event
 type LicenseEvent struct {
-	ID          int64     `db:"id" json:"id"`
-	Date        time.Time `db:"event_date" json:"event_date"`
-	Time        time.Time `db:"event_time" json:"event_time"`
-	EventType   string    `db:"event_type" json:"event_type"` // IN, OUT, DENIED
-	FeatureName string    `db:"feature_name" json:"feature_name"`
-	Username    string    `db:"username" json:"username"`
-	Reason      string    `db:"reason" json:"reason"`
+	ID             int64     `db:"id" json:"id"`
+	ServerHostname string    `db:"server_hostname" json:"server_hostname"`
+	Date           time.Time `db:"event_date" json:"event_date"`
+	Time           time.Time `db:"event_time" json:"event_time"`
+	EventType      string    `db:"event_type" json:"event_type"` // IN, OUT, DENIED
+	FeatureName    string    `db:"feature_name" json:"feature_name"`
+	Username       string    `db:"username" json:"username"`
+	Reason         string    `db:"reason" json:"reason"`
+	ReasonCode     string    `db:"reason_code" json:"reason_code,omitempty"` // Denial reason, see DenialReasons
+}
+
+// Entitlement is a purchase according to a vendor portal
+type Entitlement struct {
+	Source      string     `db:"source" json:"source"`
+	FeatureName string     `db:"feature_name" json:"feature_name"`
+	Quantity    int        `db:"quantity" json:"quantity"`
+	EndDate     *time.Time `db:"end_date" json:"end_date,omitempty"` // Nil for perpetual entitlements
+	FetchedAt   time.Time  `db:"fetched_at" json:"fetched_at"`
+}
+
+// EntitlementSync is the outcome of syncing one entitlement source
+type EntitlementSync struct {
+	Source       string `json:"source"`
+	Entitlements int    `json:"entitlements"`
+	Error        string `json:"error,omitempty"`
+}
+
+// Entitlement mismatch kinds
+const (
+	MismatchMissing    = "missing"    // Purchased but not served
+	MismatchCount      = "count"      // Served seats differ from the purchased quantity
+	MismatchEndDate    = "end_date"   // Expiration differs from the end of the entitlement
+	MismatchUnentitled = "unentitled" // Served by a source's servers but not purchased
+)
+
+// EntitlementMismatch is a difference between a vendor portal and the servers
+type EntitlementMismatch struct {
+	Source        string     `json:"source"`
+	FeatureName   string     `json:"feature_name"`
+	Kind          string     `json:"kind"`
+	Purchased     int        `json:"purchased"`
+	Served        int        `json:"served"`
+	EndDate       *time.Time `json:"end_date,omitempty"`
+	ServerEndDate *time.Time `json:"server_end_date,omitempty"`
+	Servers       []string   `json:"servers"`
+}
+
+// ReconciliationReport compares the entitlements of every source with the
+// features of its servers
+type ReconciliationReport struct {
+	GeneratedAt time.Time             `json:"generated_at"`
+	Checked     int                   `json:"checked"`
+	Matching    int                   `json:"matching"`
+	Mismatches  []EntitlementMismatch `json:"mismatches"`
+}
+
+// Denial reasons, classified from the vendor's denial message
+const (
+	DenialNoSeats            = "no_seats"            // All licenses in use
+	DenialExcluded           = "excluded"            // User or host excluded by the options file
+	DenialVersionTooNew      = "version_too_new"     // Requested version newer than the licensed one
+	DenialServerDown         = "server_down"         // License server or vendor daemon unreachable
+	DenialUnsupportedFeature = "unsupported_feature" // Feature not served by the server
+	DenialExpired            = "expired"             // License expired
+	DenialOther              = "other"               // Unrecognized message
+)
+
+// DenialReasons lists the denial reasons in the order they are reported
+var DenialReasons = []string{
+	DenialNoSeats, DenialExcluded, DenialVersionTooNew, DenialServerDown,
+	DenialUnsupportedFeature, DenialExpired, DenialOther,
+}
+
+// Denial is a license denial with its classified reason
+type Denial struct {
+	ServerHostname string    `db:"server_hostname" json:"server_hostname"`
+	FeatureName    string    `db:"feature_name" json:"feature_name"`
+	Username       string    `db:"username" json:"username"`
+	DeniedAt       time.Time `db:"-" json:"denied_at"`
+	Reason         string    `db:"reason" json:"reason"`
+	ReasonCode     string    `db:"reason_code" json:"reason_code"`
+}
+
+// DenialSummary counts denials per reason over a period
+type DenialSummary struct {
+	Days     int            `json:"days"`
+	Total    int            `json:"total"`
+	ByReason map[string]int `json:"by_reason"`
+	Denials  []Denial       `json:"denials"`
 }
 
 // ServerQueryResult represents the result of querying a license server
@@ -109,6 +736,56 @@ type ServerQueryResult struct {
 	Status   ServerStatus
 	Features []Feature
 	Users    []LicenseUser
+	Packages []PackageComponent
+}
+
+// PackageComponent is a feature of a FlexLM PACKAGE. Each checkout of the
+// component draws Tokens from the token pool of the package.
+type PackageComponent struct {
+	ServerHostname string `db:"server_hostname" json:"server_hostname"`
+	PackageName    string `db:"package_name" json:"package_name"`
+	FeatureName    string `db:"feature_name" json:"feature_name"`
+	Version        string `db:"version" json:"version,omitempty"`
+	Tokens         int    `db:"tokens" json:"tokens"`
+}
+
+// TokenPool is the token usage of a package and the components drawing from it
+type TokenPool struct {
+	ServerHostname  string               `json:"server_hostname"`
+	PackageName     string               `json:"package_name"`
+	TotalTokens     int                  `json:"total_tokens"` // 0 when the server does not report the package as a feature
+	UsedTokens      int                  `json:"used_tokens"`
+	AvailableTokens int                  `json:"available_tokens"`
+	UtilizationPct  float64              `json:"utilization_pct"`
+	Components      []TokenPoolComponent `json:"components"`
+}
+
+// TokenPoolComponent is the usage of a component of a token pool
+type TokenPoolComponent struct {
+	FeatureName string `json:"feature_name"`
+	Tokens      int    `json:"tokens"` // Tokens per checkout
+	Checkouts   int    `json:"checkouts"`
+	UsedTokens  int    `json:"used_tokens"`
+}
+
+// ProductFamily is the usage of the MathWorks products of MLM servers that
+// require the same base product, e.g. the toolboxes of MATLAB
+type ProductFamily struct {
+	Family       string         `json:"family"`
+	UsedLicenses int            `json:"used_licenses"` // Seats in use of all products of the family
+	Products     []ProductUsage `json:"products"`
+}
+
+// ProductUsage is the usage of the feature licensing a MathWorks product
+type ProductUsage struct {
+	ServerHostname    string  `json:"server_hostname"`
+	FeatureName       string  `json:"feature_name"`
+	ProductName       string  `json:"product_name"`
+	TotalLicenses     int     `json:"total_licenses"`
+	UsedLicenses      int     `json:"used_licenses"`
+	AvailableLicenses int     `json:"available_licenses"`
+	Uncapped          bool    `json:"uncapped"` // Reported as 0 licenses issued; total and available are not counted
+	UtilizationPct    float64 `json:"utilization_pct"`
 }
 
 // UtilizationData represents current utilization for a feature
@@ -118,15 +795,22 @@ type UtilizationData struct {
 	Version           string  `json:"version" db:"version"`
 	TotalLicenses     int     `json:"total_licenses" db:"total_licenses"`
 	UsedLicenses      int     `json:"used_licenses" db:"used_licenses"`
+	ReservedLicenses  int     `json:"reserved_licenses" db:"reserved_licenses"`
+	BorrowedLicenses  int     `json:"borrowed_licenses" db:"borrowed_licenses"`
+	OverdraftLicenses int     `json:"overdraft_licenses" db:"overdraft_licenses"`
 	AvailableLicenses int     `json:"available_licenses" db:"available_licenses"`
+	LicenseModel      string  `json:"license_model" db:"license_model"`
 	UtilizationPct    float64 `json:"utilization_pct" db:"utilization_pct"`
 	VendorDaemon      string  `json:"vendor_daemon" db:"vendor_daemon"`
+	DisplayName       string  `json:"display_name,omitempty" db:"-"`
+	VendorName        string  `json:"vendor_display_name,omitempty" db:"-"`
 }
 
 // UtilizationHistoryPoint represents a single data point in utilization history
 type UtilizationHistoryPoint struct {
-	Timestamp  string `json:"timestamp" db:"timestamp"`
-	UsersCount int    `json:"users_count" db:"users_count"`
+	Timestamp   string `json:"timestamp" db:"timestamp"`
+	UsersCount  int    `json:"users_count" db:"users_count"`
+	QueuedCount int    `json:"queued_count" db:"queued_count"` // Checkout requests waiting for a free seat
 }
 
 // UtilizationStats represents aggregated statistics for a feature
@@ -137,6 +821,7 @@ type UtilizationStats struct {
 	PeakUsage      int     `json:"peak_usage" db:"peak_usage"`
 	MinUsage       int     `json:"min_usage" db:"min_usage"`
 	TotalLicenses  int     `json:"total_licenses" db:"total_licenses"`
+	DisplayName    string  `json:"display_name,omitempty" db:"-"`
 }
 
 // HeatmapData represents hour-of-day usage patterns for heatmap visualization
@@ -162,14 +847,41 @@ type PredictiveAnalytics struct {
 	TrendSlope      float64            `json:"trend_slope"`      // Licenses per day
 	DaysToCapacity  int                `json:"days_to_capacity"` // -1 if decreasing or no risk
 	ConfidenceLevel float64            `json:"confidence_level"` // 0.0 to 1.0
+	Model           string             `json:"model"`            // Forecast model, linear or holtwinters
 	Forecast        []ForecastPoint    `json:"forecast"`
 	Anomalies       []AnomalyDetection `json:"anomalies"`
+	// AnomalyThreshold is the number of standard deviations applied to the feature
+	AnomalyThreshold float64 `json:"anomaly_threshold"`
+	// SuppressedAnomalies counts anomalies hidden by exclusion windows or marked as expected
+	SuppressedAnomalies int `json:"suppressed_anomalies"`
+}
+
+// FeatureTrend holds precomputed usage trend statistics for a feature over
+// the last PeriodDays days. The regression is over days since the first sample.
+type FeatureTrend struct {
+	ID             int64     `db:"id" json:"id"`
+	ServerHostname string    `db:"server_hostname" json:"server_hostname"`
+	FeatureName    string    `db:"feature_name" json:"feature_name"`
+	PeriodDays     int       `db:"period_days" json:"period_days"`
+	Samples        int       `db:"samples" json:"samples"`
+	Slope          float64   `db:"slope" json:"slope"` // Licenses per day
+	Intercept      float64   `db:"intercept" json:"intercept"`
+	RSquared       float64   `db:"r_squared" json:"r_squared"`
+	LastDay        float64   `db:"last_day" json:"last_day"` // Days from the first to the last sample
+	CurrentUsage   int       `db:"current_usage" json:"current_usage"`
+	AvgUsage       float64   `db:"avg_usage" json:"avg_usage"`
+	StdDev         float64   `db:"std_dev" json:"std_dev"`
+	PeakUsage      int       `db:"peak_usage" json:"peak_usage"`
+	ComputedAt     time.Time `db:"computed_at" json:"computed_at"`
 }
 
 // ForecastPoint represents a single prediction point
 type ForecastPoint struct {
 	Date           string  `json:"date"`
 	PredictedUsage float64 `json:"predicted_usage"`
+	LowerBound     float64 `json:"lower_bound"` // 95% prediction interval
+	UpperBound     float64 `json:"upper_bound"`
+	Holiday        string  `json:"holiday,omitempty"` // Name of a holiday on this date at the server's site
 }
 
 // AnomalyDetection represents detected anomalies in usage patterns
@@ -181,6 +893,18 @@ type AnomalyDetection struct {
 	Severity  string  `json:"severity"`  // low, medium, high
 }
 
+// ExpectedAnomaly marks a day of a feature's usage as expected, so anomaly
+// detection no longer reports it
+type ExpectedAnomaly struct {
+	ID             int64     `db:"id" json:"id"`
+	ServerHostname string    `db:"server_hostname" json:"server_hostname"`
+	FeatureName    string    `db:"feature_name" json:"feature_name"`
+	Date           time.Time `db:"date" json:"date"`
+	Note           string    `db:"note" json:"note"`
+	CreatedBy      string    `db:"created_by" json:"created_by"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+}
+
 // EnhancedStatistics represents comprehensive statistics for license usage
 type EnhancedStatistics struct {
 	ServerHostname string `json:"server_hostname"`
@@ -211,15 +935,24 @@ type EnhancedStatistics struct {
 	// Time patterns
 	PeakHour      int     `json:"peak_hour"`        // 0-23
 	PeakDayOfWeek int     `json:"peak_day_of_week"` // 0=Sunday, 6=Saturday
-	WeekdayAvg    float64 `json:"weekday_avg"`
+	WeekdayAvg    float64 `json:"weekday_avg"`      // Excludes holidays
 	WeekendAvg    float64 `json:"weekend_avg"`
+	HolidayAvg    float64 `json:"holiday_avg"` // 0 when no holidays fall in the period
 
 	// Efficiency metrics
 	EfficiencyScore    float64 `json:"efficiency_score"`    // 0-100
 	UnderutilizedHours int     `json:"underutilized_hours"` // Hours with <10% utilization
 
+	// Cost metrics, with a license contract
+	IdleSeats        int              `json:"idle_seats"` // Seats unused even at peak
+	Contract         *LicenseContract `json:"contract,omitempty"`
+	AnnualCost       float64          `json:"annual_cost,omitempty"`
+	PotentialSavings float64          `json:"potential_savings,omitempty"` // Yearly cost of the idle seats
+
 	// Recommendations
 	Recommendations []Recommendation `json:"recommendations"`
+
+	Explanation *Explanation `json:"explanation,omitempty"` // With ?explain=true
 }
 
 // Recommendation represents a license optimization recommendation
@@ -257,6 +990,33 @@ type TrendAnalysis struct {
 	DaysToCapacity    int    `json:"days_to_capacity"` // -1 if not applicable
 	CapacityAtRisk    bool   `json:"capacity_at_risk"`
 	RecommendedAction string `json:"recommended_action"`
+
+	Explanation *Explanation `json:"explanation,omitempty"` // With ?explain=true
+}
+
+// Explanation shows how statistics and recommendations were derived, so
+// analysts can audit them
+type Explanation struct {
+	Inputs     map[string]float64 `json:"inputs"`     // Values the computation started from
+	Formulas   []ExplainedValue   `json:"formulas"`   // Derived values in order of computation
+	Thresholds []ThresholdCheck   `json:"thresholds"` // Rules compared, crossed or not
+}
+
+// ExplainedValue is a derived value with its formula and the numbers put in
+type ExplainedValue struct {
+	Name    string  `json:"name"`
+	Formula string  `json:"formula"`
+	Value   float64 `json:"value"`
+}
+
+// ThresholdCheck is a rule compared against a value; a crossed rule leads to
+// the outcome, e.g. a recommendation
+type ThresholdCheck struct {
+	Rule      string  `json:"rule"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Crossed   bool    `json:"crossed"`
+	Outcome   string  `json:"outcome"`
 }
 
 // SeasonalPattern represents detected seasonal patterns in usage
@@ -273,7 +1033,7 @@ type SeasonalPattern struct {
 // PeakPeriod represents a detected peak usage period
 type PeakPeriod struct {
 	StartHour  int     `json:"start_hour"`
-	EndHour    int     `json:"end_hour"`
+	EndHour    int     `json:"end_hour"`     // First hour after the period; at most start_hour across midnight
 	DaysOfWeek []int   `json:"days_of_week"` // 0=Sunday, 6=Saturday
 	AvgUsage   float64 `json:"avg_usage"`
 	Label      string  `json:"label"` // e.g., "Business Hours", "Morning Peak"
@@ -290,12 +1050,32 @@ type CapacityInsight struct {
 	TrendSlope     float64 `json:"trend_slope"`
 	DaysToCapacity int     `json:"days_to_capacity"`
 	Recommendation string  `json:"recommendation"`
+
+	IdleSeats        int     `json:"idle_seats"`                  // Seats unused even at peak
+	PotentialSavings float64 `json:"potential_savings,omitempty"` // Yearly cost of the idle seats, with a license contract
+	Currency         string  `json:"currency,omitempty"`
+}
+
+// GroupCapacity sums the utilization of the features on the servers of a
+// group, e.g. a site
+type GroupCapacity struct {
+	Group                 string  `json:"group"`
+	Servers               int     `json:"servers"`
+	Features              int     `json:"features"`
+	TotalLicenses         int     `json:"total_licenses"`
+	AvgUsage              float64 `json:"avg_usage"`
+	PeakUsage             int     `json:"peak_usage"` // Sum of the features' peaks
+	UtilizationPct        float64 `json:"utilization_pct"`
+	FeaturesAtCapacity    int     `json:"features_at_capacity"`
+	FeaturesUnderutilized int     `json:"features_underutilized"`
 }
 
 // CapacityPlanningReport represents capacity planning insights
 type CapacityPlanningReport struct {
 	GeneratedAt    string `json:"generated_at"`
 	PeriodAnalyzed int    `json:"period_analyzed_days"`
+	GenerationMs   int64  `json:"generation_ms"` // Time taken to generate the report
+	Cached         bool   `json:"cached"`        // Served from the stored report
 
 	// Overall summary
 	TotalServers          int `json:"total_servers"`
@@ -308,9 +1088,64 @@ type CapacityPlanningReport struct {
 	LowUtilization  []CapacityInsight `json:"low_utilization"`  // <20%
 	TrendingUp      []CapacityInsight `json:"trending_up"`
 	TrendingDown    []CapacityInsight `json:"trending_down"`
+	CostSavings     []CapacityInsight `json:"cost_savings,omitempty"` // Idle seats under contract, largest saving first
+	Groups          []GroupCapacity   `json:"groups,omitempty"`       // Per server group, of grouped servers
 
 	// Summary recommendations
 	Recommendations []Recommendation `json:"recommendations"`
+
+	Explanation *Explanation `json:"explanation,omitempty"` // With ?explain=true
+}
+
+// SeatForecast projects the seats a feature needs at the end of each month of
+// a budget forecast
+type SeatForecast struct {
+	ServerHostname string  `json:"server_hostname"`
+	FeatureName    string  `json:"feature_name"`
+	DisplayName    string  `json:"display_name,omitempty"`
+	TotalLicenses  int     `json:"total_licenses"`
+	PeakUsage      int     `json:"peak_usage"`
+	TrendSlope     float64 `json:"trend_slope"`    // Seats per day
+	RequiredSeats  []int   `json:"required_seats"` // One per forecast month
+	Shortfall      int     `json:"shortfall"`      // Peak required seats beyond the current total, negative for spare seats
+}
+
+// BudgetForecast projects seat requirements per feature for budget planning,
+// from the usage trend and an expected headcount growth
+type BudgetForecast struct {
+	GeneratedAt    string         `json:"generated_at"`
+	PeriodAnalyzed int            `json:"period_analyzed_days"`
+	GrowthPct      float64        `json:"headcount_growth_pct"` // Per year
+	Months         []string       `json:"months"`               // YYYY-MM
+	Features       []SeatForecast `json:"features"`
+}
+
+// FeatureSummary describes a feature's pools on one server for comparisons
+type FeatureSummary struct {
+	TotalLicenses int      `json:"total_licenses"`
+	Versions      []string `json:"versions"`
+	Expirations   []string `json:"expirations"` // YYYY-MM-DD, or "permanent"
+}
+
+// FeatureDifference is a feature present on both compared servers whose
+// licenses differ
+type FeatureDifference struct {
+	Name        string         `json:"name"`
+	Differences []string       `json:"differences"` // total_licenses, versions, expirations
+	A           FeatureSummary `json:"a"`
+	B           FeatureSummary `json:"b"`
+}
+
+// ServerComparison lists how the features of two license servers differ,
+// e.g. to check that a new server mirrors the old one before cutover
+type ServerComparison struct {
+	ServerA   string                    `json:"server_a"`
+	ServerB   string                    `json:"server_b"`
+	Identical bool                      `json:"identical"`
+	Matching  int                       `json:"matching"` // Features with the same licenses on both
+	OnlyOnA   map[string]FeatureSummary `json:"only_on_a"`
+	OnlyOnB   map[string]FeatureSummary `json:"only_on_b"`
+	Differing []FeatureDifference       `json:"differing"`
 }
 
 // DatabaseStats represents comprehensive database statistics
@@ -379,6 +1214,28 @@ type CleanupResult struct {
 	Duration    time.Duration `json:"duration"`
 }
 
+// RetentionRun is a run of the data retention policy, removing rows past
+// their table's maximum age or, on a dry run, counting them
+type RetentionRun struct {
+	ID          int64             `db:"id" json:"id"`
+	TriggeredBy string            `db:"triggered_by" json:"triggered_by"` // "schedule" or the user who started it
+	DryRun      bool              `db:"dry_run" json:"dry_run"`
+	RowsDeleted int64             `db:"rows_deleted" json:"rows_deleted"` // Rows that would be removed on a dry run
+	Results     []RetentionResult `db:"-" json:"results"`
+	ResultsJSON string            `db:"results" json:"-"`
+	Error       string            `db:"error" json:"error,omitempty"`
+	StartedAt   time.Time         `db:"started_at" json:"started_at"`
+	CompletedAt time.Time         `db:"completed_at" json:"completed_at"`
+}
+
+// RetentionResult is what a retention run did to one table
+type RetentionResult struct {
+	TableName   string `json:"table_name"`
+	MaxAgeDays  int    `json:"max_age_days"`
+	RowsDeleted int64  `json:"rows_deleted"`
+	Error       string `json:"error,omitempty"`
+}
+
 // RetentionStats represents data retention statistics
 type RetentionStats struct {
 	// Feature usage records
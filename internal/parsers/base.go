@@ -2,24 +2,40 @@ package parsers
 
 import (
 	"context"
+	"fmt"
 	"os/exec"
 	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"licet/internal/clock"
 	"licet/internal/models"
 )
 
 // PermanentExpirationDate is used for permanent licenses to prevent duplicate records
 var PermanentExpirationDate = time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
 
-// NewServerQueryResult creates a new ServerQueryResult with default values
-func NewServerQueryResult(hostname string) models.ServerQueryResult {
+// clocked provides the current time to a parser, which is the system time
+// unless a clock is set
+type clocked struct {
+	clock clock.Clock
+}
+
+func (c clocked) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock.Now()
+}
+
+// NewServerQueryResult creates a new ServerQueryResult with default values,
+// checked at now
+func NewServerQueryResult(hostname string, now time.Time) models.ServerQueryResult {
 	return models.ServerQueryResult{
 		Status: models.ServerStatus{
 			Hostname:    hostname,
 			Service:     "down",
-			LastChecked: time.Now(),
+			LastChecked: now,
 		},
 		Features: []models.Feature{},
 		Users:    []models.LicenseUser{},
@@ -49,49 +65,34 @@ func ExecuteCommand(ctx context.Context, serverType, binaryPath string, args ...
 }
 
 // ParseExpirationDate parses an expiration date string and returns a time.Time
-// Handles "permanent" and various date formats
-// Returns PermanentExpirationDate (2099-01-01) for permanent licenses or unparseable dates
+// Handles "permanent", localized month names and various date formats
+// Returns PermanentExpirationDate (2099-01-01) for permanent licenses or unparseable
+// dates; use ParseDate to detect unparseable dates
 func ParseExpirationDate(expirationStr string) time.Time {
-	if expirationStr == "" {
-		return PermanentExpirationDate
-	}
-
-	expirationStr = strings.TrimSpace(expirationStr)
-
-	// Handle permanent licenses
-	if strings.ToLower(expirationStr) == "permanent" {
+	expDate, err := ParseDate(expirationStr)
+	if err != nil {
+		log.Warnf("Failed to parse expiration date '%s', using permanent date", expirationStr)
 		return PermanentExpirationDate
 	}
+	return expDate
+}
 
-	// Handle special FlexLM date formats (matching PHP behavior)
-	expirationStr = strings.Replace(expirationStr, "-jan-0000", "-jan-2036", 1)
-	expirationStr = strings.Replace(expirationStr, "-jan-0", "-jan-2036", 1)
-
-	// Try common date formats
-	formats := []string{
-		"2-Jan-2006",     // 1-Jan-2025
-		"02-Jan-2006",    // 01-Jan-2025
-		"2006-01-02",     // 2025-01-01
-		"01/02/2006",     // 01/02/2025
-		"1/2/2006",       // 1/2/2025
-		"Jan 2, 2006",    // Jan 1, 2025
-		"January 2 2006", // January 1 2025
-	}
-
-	for _, format := range formats {
-		if expDate, err := time.Parse(format, expirationStr); err == nil {
-			return expDate
-		}
+// parseExpiration parses a feature's expiration date along with its parse
+// quality, recording a warning on the result when the date cannot be parsed
+func parseExpiration(result *models.ServerQueryResult, feature, expirationStr string) (time.Time, string) {
+	expDate, err := ParseDate(expirationStr)
+	if err != nil {
+		warning := fmt.Sprintf("Unparseable expiration date %q for feature %s; treated as permanent", expirationStr, feature)
+		log.Warnf("%s on %s", warning, result.Status.Hostname)
+		result.Status.Warnings = append(result.Status.Warnings, warning)
+		return PermanentExpirationDate, models.ParseQualityDefaultedExpiration
 	}
-
-	log.Debugf("Failed to parse expiration date '%s', using permanent date", expirationStr)
-	return PermanentExpirationDate
+	return expDate, models.ParseQualityOK
 }
 
-// AdjustCheckoutTimeToCurrentYear takes a parsed time without year and adjusts it to the current year
+// AdjustCheckoutTimeToCurrentYear takes a parsed time without year and adjusts it to the year of now
 // This is needed because most license servers don't include year in checkout times
-func AdjustCheckoutTimeToCurrentYear(t time.Time) time.Time {
-	now := time.Now()
+func AdjustCheckoutTimeToCurrentYear(t, now time.Time) time.Time {
 	return time.Date(now.Year(), t.Month(), t.Day(),
 		t.Hour(), t.Minute(), t.Second(),
 		t.Nanosecond(), time.Local)
@@ -0,0 +1,181 @@
+package parsers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/models"
+)
+
+// commandOutput is the normalized JSON a command prints on stdout:
+//
+//	{
+//	  "status": {"service": "up", "master": "lic01", "version": "2.1", "message": ""},
+//	  "features": [{"name": "solver", "version": "1.0", "vendor": "acme",
+//	                "total": 10, "used": 3, "expiration": "2026-12-31"}],
+//	  "users": [{"feature": "solver", "username": "jdoe", "host": "ws01",
+//	             "checked_out_at": "2025-06-01T09:30:00Z"}]
+//	}
+type commandOutput struct {
+	Status struct {
+		Service string `json:"service"` // up, down or warning; up when omitted
+		Master  string `json:"master"`
+		Version string `json:"version"`
+		Message string `json:"message"`
+	} `json:"status"`
+	Features []struct {
+		Name         string `json:"name"`
+		Version      string `json:"version"`
+		Vendor       string `json:"vendor"`
+		Total        int    `json:"total"`
+		Used         int    `json:"used"`
+		Reserved     int    `json:"reserved"`
+		Borrowed     int    `json:"borrowed"`
+		Queued       int    `json:"queued"`
+		LicenseModel string `json:"license_model"` // floating (default), node-locked or uncounted
+		Expiration   string `json:"expiration"`    // Any date format licet parses; permanent when omitted
+	} `json:"features"`
+	Users []struct {
+		Feature      string    `json:"feature"`
+		Username     string    `json:"username"`
+		Host         string    `json:"host"`
+		Version      string    `json:"version"`
+		CheckedOutAt time.Time `json:"checked_out_at"` // RFC 3339; the query time when omitted
+		Tokens       int       `json:"tokens"`
+	} `json:"users"`
+}
+
+// CommandParser queries license managers licet has no parser for by running
+// a site's script. The script is run with the server hostname as its last
+// argument and prints the server's status, features and users as JSON (see
+// commandOutput). A script that fails or prints anything else fails the
+// query; a server that is down is reported with status service "down".
+type CommandParser struct {
+	clocked
+	command string
+	args    []string
+}
+
+func NewCommandParser(command string, args []string) *CommandParser {
+	return &CommandParser{command: command, args: args}
+}
+
+// GetCommandParser returns the parser of a server of type command
+func (f *ParserFactory) GetCommandParser(command string, args []string) (Parser, error) {
+	if command == "" {
+		return nil, fmt.Errorf("server type command needs a command to run")
+	}
+	p := NewCommandParser(command, args)
+	p.clock = f.clock
+	return p, nil
+}
+
+func (p *CommandParser) Query(ctx context.Context, hostname string) (models.ServerQueryResult, error) {
+	result := NewServerQueryResult(hostname, p.now())
+
+	args := append(append([]string{}, p.args...), hostname)
+	log.Debugf("Executing command parser: %s %s", p.command, strings.Join(args, " "))
+
+	// Stderr is kept out of the JSON and only reported on failure
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.command, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return result, fmt.Errorf("command %s failed: %w: %s", p.command, err, msg)
+		}
+		return result, fmt.Errorf("command %s failed: %w", p.command, err)
+	}
+
+	if log.IsLevelEnabled(log.DebugLevel) {
+		log.Debugf("Command parser output:\n%s", stdout.String())
+	}
+
+	if err := p.parseOutput(stdout.Bytes(), &result); err != nil {
+		return result, fmt.Errorf("command %s: %w", p.command, err)
+	}
+	return result, nil
+}
+
+// parseOutput fills result from the JSON printed by a command
+func (p *CommandParser) parseOutput(data []byte, result *models.ServerQueryResult) error {
+	var out commandOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return fmt.Errorf("invalid JSON output: %w", err)
+	}
+
+	service := out.Status.Service
+	switch service {
+	case "":
+		service = "up"
+	case "up", "down", "warning":
+	default:
+		return fmt.Errorf("status service must be up, down or warning, not %q", service)
+	}
+	result.Status.Service = service
+	result.Status.Master = out.Status.Master
+	result.Status.Version = out.Status.Version
+	result.Status.Message = out.Status.Message
+
+	now := p.now()
+	for _, f := range out.Features {
+		if f.Name == "" {
+			return fmt.Errorf("feature without a name")
+		}
+		model := f.LicenseModel
+		switch model {
+		case "":
+			model = models.LicenseModelFloating
+		case models.LicenseModelFloating, models.LicenseModelNodeLocked, models.LicenseModelUncounted:
+		default:
+			return fmt.Errorf("feature %s: unknown license_model %q", f.Name, model)
+		}
+		expDate, quality := PermanentExpirationDate, models.ParseQualityOK
+		if f.Expiration != "" {
+			expDate, quality = parseExpiration(result, f.Name, f.Expiration)
+		}
+		result.Features = append(result.Features, models.Feature{
+			ServerHostname:   result.Status.Hostname,
+			Name:             f.Name,
+			Version:          f.Version,
+			VendorDaemon:     f.Vendor,
+			TotalLicenses:    f.Total,
+			UsedLicenses:     f.Used,
+			ReservedLicenses: f.Reserved,
+			BorrowedLicenses: f.Borrowed,
+			QueuedRequests:   f.Queued,
+			LicenseModel:     model,
+			ParseQuality:     quality,
+			ExpirationDate:   expDate,
+			LastUpdated:      now,
+		})
+	}
+
+	for _, u := range out.Users {
+		if u.Feature == "" || u.Username == "" {
+			return fmt.Errorf("user without a feature or username")
+		}
+		checkedOut := u.CheckedOutAt
+		if checkedOut.IsZero() {
+			checkedOut = now
+		}
+		result.Users = append(result.Users, models.LicenseUser{
+			ServerHostname: result.Status.Hostname,
+			FeatureName:    u.Feature,
+			Username:       u.Username,
+			Host:           u.Host,
+			CheckedOutAt:   checkedOut,
+			Version:        u.Version,
+			LicenseVersion: u.Version,
+			Tokens:         u.Tokens,
+		})
+	}
+	return nil
+}
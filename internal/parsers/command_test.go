@@ -0,0 +1,84 @@
+package parsers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/models"
+)
+
+func TestCommandParser_Query(t *testing.T) {
+	// The hostname is the last argument, $1 of the shell script
+	script := `echo '{"status": {"master": "'"$1"'", "version": "2.1"},
+		"features": [
+			{"name": "solver", "version": "1.0", "vendor": "acme", "total": 10, "used": 3, "queued": 1, "expiration": "2026-12-31"},
+			{"name": "viewer", "license_model": "uncounted", "used": 1}
+		],
+		"users": [
+			{"feature": "solver", "username": "jdoe", "host": "ws01", "checked_out_at": "2025-06-01T09:30:00Z", "tokens": 2},
+			{"feature": "viewer", "username": "asmith"}
+		]}'`
+	f := NewParserFactory(nil)
+	parser, err := f.GetCommandParser("sh", []string{"-c", script, "query"})
+	if err != nil {
+		t.Fatalf("GetCommandParser failed: %v", err)
+	}
+
+	result, err := parser.Query(context.Background(), "lic01")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if result.Status.Service != "up" || result.Status.Master != "lic01" || result.Status.Version != "2.1" {
+		t.Errorf("Expected server lic01 to be up, got %+v", result.Status)
+	}
+	if len(result.Features) != 2 {
+		t.Fatalf("Expected 2 features, got %+v", result.Features)
+	}
+	solver := result.Features[0]
+	if solver.ServerHostname != "lic01" || solver.TotalLicenses != 10 || solver.UsedLicenses != 3 || solver.QueuedRequests != 1 ||
+		solver.LicenseModel != models.LicenseModelFloating || solver.ExpirationDate.Format("2006-01-02") != "2026-12-31" {
+		t.Errorf("Unexpected feature %+v", solver)
+	}
+	if viewer := result.Features[1]; viewer.LicenseModel != models.LicenseModelUncounted || !viewer.ExpirationDate.Equal(PermanentExpirationDate) {
+		t.Errorf("Expected a permanent uncounted feature, got %+v", viewer)
+	}
+	if len(result.Users) != 2 || result.Users[0].Seats() != 2 ||
+		!result.Users[0].CheckedOutAt.Equal(time.Date(2025, 6, 1, 9, 30, 0, 0, time.UTC)) || result.Users[1].CheckedOutAt.IsZero() {
+		t.Errorf("Unexpected users %+v", result.Users)
+	}
+}
+
+func TestCommandParser_Errors(t *testing.T) {
+	if _, err := NewParserFactory(nil).GetCommandParser("", nil); err == nil {
+		t.Error("Expected an error without a command")
+	}
+
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"exit status", `echo "license manager unreachable" >&2; exit 2`, "license manager unreachable"},
+		{"not JSON", `echo "Users of solver: 3"`, "invalid JSON"},
+		{"unknown status", `echo '{"status": {"service": "degraded"}}'`, "up, down or warning"},
+		{"unnamed feature", `echo '{"features": [{"total": 1}]}'`, "without a name"},
+		{"unknown model", `echo '{"features": [{"name": "a", "license_model": "site"}]}'`, "unknown license_model"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCommandParser("sh", []string{"-c", tt.script, "query"}).Query(context.Background(), "lic01")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// A server that is down is reported by the script, not as an error
+	result, err := NewCommandParser("sh", []string{"-c", `echo '{"status": {"service": "down", "message": "no answer"}}'`, "query"}).
+		Query(context.Background(), "lic01")
+	if err != nil || result.Status.Service != "down" || result.Status.Message != "no answer" {
+		t.Errorf("Expected the server to be down, got %+v (%v)", result.Status, err)
+	}
+}
@@ -0,0 +1,151 @@
+package parsers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// localizedMonths maps month names and abbreviations emitted by license
+// servers on non-English systems to English abbreviations. Keys are lower
+// case without a trailing dot. English names are parsed by the time package.
+var localizedMonths = map[string]string{
+	// German
+	"januar": "Jan", "jän": "Jan", "jänner": "Jan", "februar": "Feb", "märz": "Mar", "mär": "Mar", "mrz": "Mar",
+	"mai": "May", "juni": "Jun", "juli": "Jul", "okt": "Oct", "oktober": "Oct", "dez": "Dec", "dezember": "Dec",
+	// French
+	"janv": "Jan", "janvier": "Jan", "févr": "Feb", "fév": "Feb", "fevr": "Feb", "fev": "Feb", "février": "Feb",
+	"mars": "Mar", "avr": "Apr", "avril": "Apr", "juin": "Jun", "juil": "Jul", "juillet": "Jul", "août": "Aug",
+	"aoû": "Aug", "aout": "Aug", "sept": "Sep", "septembre": "Sep", "octobre": "Oct", "novembre": "Nov",
+	"déc": "Dec", "décembre": "Dec",
+	// Spanish
+	"ene": "Jan", "enero": "Jan", "febrero": "Feb", "marzo": "Mar", "abr": "Apr", "abril": "Apr", "mayo": "May",
+	"junio": "Jun", "julio": "Jul", "ago": "Aug", "agosto": "Aug", "septiembre": "Sep", "setiembre": "Sep",
+	"octubre": "Oct", "noviembre": "Nov", "dic": "Dec", "diciembre": "Dec",
+	// Italian
+	"gen": "Jan", "gennaio": "Jan", "febbraio": "Feb", "apr": "Apr", "aprile": "Apr", "mag": "May", "maggio": "May",
+	"giu": "Jun", "giugno": "Jun", "lug": "Jul", "luglio": "Jul", "set": "Sep", "settembre": "Sep",
+	"ott": "Oct", "ottobre": "Oct", "dicembre": "Dec",
+	// Portuguese
+	"janeiro": "Jan", "fevereiro": "Feb", "março": "Mar", "maio": "May", "junho": "Jun",
+	"julho": "Jul", "setembro": "Sep", "out": "Oct", "outubro": "Oct", "dezembro": "Dec",
+	// Dutch, Swedish, Danish, Norwegian
+	"mrt": "Mar", "maart": "Mar", "mei": "May", "januari": "Jan", "februari": "Feb", "augustus": "Aug",
+	"maj": "May", "augusti": "Aug",
+	// Polish
+	"sty": "Jan", "lut": "Feb", "kwi": "Apr", "cze": "Jun", "lip": "Jul", "sie": "Aug", "wrz": "Sep",
+	"paź": "Oct", "paz": "Oct", "lis": "Nov", "gru": "Dec",
+}
+
+// localizedWeekdays are weekday names and abbreviations that may prefix
+// checkout times on non-English systems, lower case without a trailing dot
+var localizedWeekdays = map[string]bool{
+	// German
+	"mo": true, "di": true, "mi": true, "do": true, "fr": true, "sa": true, "so": true,
+	// French
+	"lun": true, "mar": true, "mer": true, "jeu": true, "ven": true, "sam": true, "dim": true,
+	// Spanish, Italian, Portuguese
+	"mié": true, "mie": true, "jue": true, "vie": true, "sáb": true, "sab": true, "dom": true,
+	"gio": true, "seg": true, "ter": true, "qua": true, "qui": true, "sex": true,
+	// Dutch, Scandinavian
+	"ma": true, "wo": true, "vr": true, "za": true, "zo": true, "tis": true, "ons": true, "tor": true,
+	"fre": true, "lör": true, "sön": true, "man": true, "tir": true, "lør": true, "søn": true,
+}
+
+// monthTokenRe matches a run of letters, optionally followed by a dot
+var monthTokenRe = regexp.MustCompile(`\p{L}+\.?`)
+
+// normalizeMonths replaces localized month names in a date with English abbreviations
+func normalizeMonths(s string) string {
+	return monthTokenRe.ReplaceAllStringFunc(s, func(token string) string {
+		lower := strings.ToLower(token)
+		if month, ok := localizedMonths[lower]; ok {
+			return month
+		}
+		if month, ok := localizedMonths[strings.TrimSuffix(lower, ".")]; ok {
+			return month
+		}
+		return token
+	})
+}
+
+// stripWeekday removes a leading weekday name (in any language) from a checkout time
+func stripWeekday(s string) string {
+	s = strings.TrimSpace(s)
+	first, rest, ok := strings.Cut(s, " ")
+	if !ok {
+		return s
+	}
+	name := strings.ToLower(strings.TrimSuffix(first, "."))
+	if localizedWeekdays[name] || isEnglishWeekday(name) {
+		return strings.TrimSpace(rest)
+	}
+	return s
+}
+
+func isEnglishWeekday(name string) bool {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if name == full || name == full[:3] {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseDate parses an expiration date in any of the formats license servers
+// emit, including localized month names. Permanent licenses return
+// PermanentExpirationDate. Unparseable dates return an error.
+func ParseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.ToLower(s) == "permanent" {
+		return PermanentExpirationDate, nil
+	}
+
+	// Handle special FlexLM date formats (matching PHP behavior)
+	normalized := strings.Replace(s, "-jan-0000", "-jan-2036", 1)
+	normalized = strings.Replace(normalized, "-jan-0", "-jan-2036", 1)
+	normalized = normalizeMonths(normalized)
+
+	formats := []string{
+		"2-Jan-2006",     // 1-Jan-2025
+		"02-Jan-2006",    // 01-Jan-2025
+		"2006-01-02",     // 2025-01-01
+		"01/02/2006",     // 01/02/2025
+		"1/2/2006",       // 1/2/2025
+		"Jan 2, 2006",    // Jan 1, 2025
+		"January 2 2006", // January 1 2025
+		"Jan 2 2006",     // Jan 1 2025
+		"2 Jan 2006",     // 1 Jan 2025
+	}
+	for _, format := range formats {
+		if d, err := time.Parse(format, normalized); err == nil {
+			return d, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date %q", s)
+}
+
+// ParseCheckoutTime parses a checkout time such as "Mon 1/2 15:04" or
+// "lun. 1/2/24 15:04". Times without a year are placed in the year of now.
+func ParseCheckoutTime(s string, now time.Time) (time.Time, error) {
+	stripped := stripWeekday(s)
+
+	formats := []string{
+		"1/2/2006 15:04", // Full year: 1/2/2024 15:04
+		"1/2/06 15:04",   // 2-digit year: 1/2/24 15:04
+		"1/2 15:04",      // No year: 1/2 15:04
+	}
+	for _, format := range formats {
+		if t, err := time.Parse(format, stripped); err == nil {
+			if t.Year() == 0 {
+				t = AdjustCheckoutTimeToCurrentYear(t, now)
+			}
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized checkout time %q", s)
+}
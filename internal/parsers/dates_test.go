@@ -0,0 +1,135 @@
+package parsers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/models"
+)
+
+func TestParseDate_Localized(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"15-déc-2026", "2026-12-15"},
+		{"15-DÉC.-2026", "2026-12-15"},
+		{"1-mär-2027", "2027-03-01"},
+		{"1-Mrz-2027", "2027-03-01"},
+		{"3-févr.-2027", "2027-02-03"},
+		{"30-dic-2026", "2026-12-30"},
+		{"7-okt-2026", "2026-10-07"},
+		{"7-out-2026", "2026-10-07"},
+		{"12-mag-2027", "2027-05-12"},
+		{"12-mei-2027", "2027-05-12"},
+		{"20-paź-2026", "2026-10-20"},
+		{"1-jan-2025", "2025-01-01"},
+		{"1-jan-0", "2036-01-01"},
+		{"2025-06-30", "2025-06-30"},
+		{"4 juillet 2026", "2026-07-04"},
+	}
+	for _, tt := range tests {
+		got, err := ParseDate(tt.input)
+		if err != nil {
+			t.Errorf("ParseDate(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if got.Format("2006-01-02") != tt.want {
+			t.Errorf("ParseDate(%q) = %s, want %s", tt.input, got.Format("2006-01-02"), tt.want)
+		}
+	}
+
+	if got, err := ParseDate("permanent"); err != nil || !got.Equal(PermanentExpirationDate) {
+		t.Errorf("Expected permanent date, got %v (%v)", got, err)
+	}
+	for _, input := range []string{"15-foo-2026", "sometime"} {
+		if _, err := ParseDate(input); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestParseCheckoutTime_Localized(t *testing.T) {
+	// Across a new year, so the year of now is not the host's
+	now := time.Date(2023, 12, 31, 23, 0, 0, 0, time.UTC)
+	year := now.Year()
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"Mon 3/2 9:15", time.Date(year, 3, 2, 9, 15, 0, 0, time.Local)},
+		{"lun. 3/2 9:15", time.Date(year, 3, 2, 9, 15, 0, 0, time.Local)},
+		{"Mi 12/24/25 17:00", time.Date(2025, 12, 24, 17, 0, 0, 0, time.UTC)},
+		{"sáb 1/4/2026 08:30", time.Date(2026, 1, 4, 8, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseCheckoutTime(tt.input, now)
+		if err != nil {
+			t.Errorf("ParseCheckoutTime(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseCheckoutTime(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestFlexLMParser_LocalizedDates(t *testing.T) {
+	output := `lmstat - Copyright (c) 1989-2024 Flexera. All Rights Reserved.
+Flexible License Manager status on jeu. 12/3/2026 10:00
+
+License server status: 27000@licsrv
+    License file(s) on licsrv: /opt/flexlm/license.dat:
+
+   licsrv: license server UP (MASTER) v11.19.0
+
+Feature usage info:
+
+Users of solver:  (Total of 10 licenses issued;  Total of 1 license in use)
+
+  "solver" v2024.1, vendor: ansyslmd, expiry: 15-déc-2026
+  floating license
+
+    alice ws01 ws01 (v2024.1) (licsrv/27000 101), start jeu. 12/3 9:15
+
+Users of mesher:  (Total of 5 licenses issued;  Total of 0 licenses in use)
+
+Feature                        Version     #licenses    Vendor        Expires
+________                       _________   _________    __________    _________
+solver                         2024.1      10           ansyslmd      15-déc-2026
+mesher                         2024.1      5            ansyslmd      31-xyz-2026
+`
+	parser := &FlexLMParser{}
+	result := NewServerQueryResult("27000@licsrv", time.Now())
+	parser.parseOutput(strings.NewReader(output), &result)
+
+	if len(result.Features) != 2 {
+		t.Fatalf("Expected 2 features, got %+v", result.Features)
+	}
+	for _, f := range result.Features {
+		switch f.Name {
+		case "solver":
+			if f.ExpirationDate.Format("2006-01-02") != "2026-12-15" {
+				t.Errorf("Expected solver to expire 2026-12-15, got %s", f.ExpirationDate.Format("2006-01-02"))
+			}
+			if f.ParseQuality != models.ParseQualityOK {
+				t.Errorf("Expected solver parse quality ok, got %q", f.ParseQuality)
+			}
+		case "mesher":
+			if !f.ExpirationDate.Equal(PermanentExpirationDate) {
+				t.Errorf("Expected unparseable mesher date to fall back to permanent, got %v", f.ExpirationDate)
+			}
+			if f.ParseQuality != models.ParseQualityDefaultedExpiration {
+				t.Errorf("Expected mesher to be flagged as defaulted, got %q", f.ParseQuality)
+			}
+		}
+	}
+
+	if len(result.Users) != 1 || result.Users[0].CheckedOutAt.Month() != time.December {
+		t.Errorf("Expected alice's localized checkout time to parse, got %+v", result.Users)
+	}
+	if len(result.Status.Warnings) != 1 || !strings.Contains(result.Status.Warnings[0], "31-xyz-2026") {
+		t.Errorf("Expected one warning for the unparseable date, got %v", result.Status.Warnings)
+	}
+}
@@ -0,0 +1,68 @@
+package parsers
+
+import (
+	"regexp"
+	"strings"
+
+	"licet/internal/models"
+)
+
+// flexErrorCodeRe matches the FlexLM error code of a denial message, e.g.
+// "Licensed number of users already reached. (-4,342)"
+var flexErrorCodeRe = regexp.MustCompile(`\((-\d+),\s*-?\d+`)
+
+// flexDenialCodes maps FlexLM error codes to denial reasons
+var flexDenialCodes = map[string]string{
+	"-4":  models.DenialNoSeats,            // Licensed number of users already reached
+	"-5":  models.DenialUnsupportedFeature, // No such feature exists
+	"-10": models.DenialExpired,            // Feature has expired
+	"-15": models.DenialServerDown,         // Cannot connect to license server
+	"-21": models.DenialVersionTooNew,      // License file does not support this version
+	"-25": models.DenialVersionTooNew,      // License server does not support this version
+	"-38": models.DenialExcluded,           // User/host on EXCLUDE list
+	"-39": models.DenialExcluded,           // User/host not on INCLUDE list
+	"-96": models.DenialServerDown,         // License server machine is down or not responding
+	"-97": models.DenialServerDown,         // Vendor daemon is down
+}
+
+// denialPhrases classify messages without an error code, as written by RLM
+// and by FlexLM daemons that omit it. The first matching phrase wins.
+var denialPhrases = []struct {
+	phrase string
+	reason string
+}{
+	{"already reached", models.DenialNoSeats},
+	{"all licenses in use", models.DenialNoSeats},
+	{"no licenses available", models.DenialNoSeats},
+	{"exclude list", models.DenialExcluded},
+	{"include list", models.DenialExcluded},
+	{"excludeall list", models.DenialExcluded},
+	{"includeall list", models.DenialExcluded},
+	{"excluded", models.DenialExcluded},
+	{"not authorized", models.DenialExcluded},
+	{"version", models.DenialVersionTooNew},
+	{"cannot connect", models.DenialServerDown},
+	{"not responding", models.DenialServerDown},
+	{"is down", models.DenialServerDown},
+	{"unsupported feature", models.DenialUnsupportedFeature},
+	{"no such feature", models.DenialUnsupportedFeature},
+	{"no license for product", models.DenialUnsupportedFeature},
+	{"expired", models.DenialExpired},
+}
+
+// ClassifyDenial returns the denial reason of a vendor denial message
+func ClassifyDenial(message string) string {
+	if m := flexErrorCodeRe.FindStringSubmatch(message); m != nil {
+		if reason, ok := flexDenialCodes[m[1]]; ok {
+			return reason
+		}
+	}
+
+	message = strings.ToLower(message)
+	for _, p := range denialPhrases {
+		if strings.Contains(message, p.phrase) {
+			return p.reason
+		}
+	}
+	return models.DenialOther
+}
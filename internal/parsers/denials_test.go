@@ -0,0 +1,35 @@
+package parsers
+
+import (
+	"testing"
+
+	"licet/internal/models"
+)
+
+func TestClassifyDenial(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"Licensed number of users already reached. (-4,342)", models.DenialNoSeats},
+		{"All licenses in use", models.DenialNoSeats},
+		{"User/host on EXCLUDE list for feature. (-38,349)", models.DenialExcluded},
+		{"User/host not on INCLUDE list for feature. (-39,147)", models.DenialExcluded},
+		{"License server does not support this version of this feature. (-25,334)", models.DenialVersionTooNew},
+		{"Request for version 2025.0 exceeds licensed version", models.DenialVersionTooNew},
+		{"Cannot connect to license server system. (-15,10:10061)", models.DenialServerDown},
+		{"Vendor daemon is down", models.DenialServerDown},
+		{"No such feature exists. (-5,414)", models.DenialUnsupportedFeature},
+		{"Unsupported feature", models.DenialUnsupportedFeature},
+		{"Feature has expired. (-10,32)", models.DenialExpired},
+		// The error code wins over the wording
+		{"Some localized text (-4,342)", models.DenialNoSeats},
+		{"Something else went wrong", models.DenialOther},
+		{"", models.DenialOther},
+	}
+	for _, tt := range tests {
+		if got := ClassifyDenial(tt.message); got != tt.want {
+			t.Errorf("ClassifyDenial(%q) = %s, want %s", tt.message, got, tt.want)
+		}
+	}
+}
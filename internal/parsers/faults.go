@@ -0,0 +1,108 @@
+package parsers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"licet/internal/models"
+)
+
+// Faults are synthetic license server failures, injected in staging to check
+// alerting and error handling without touching real servers
+const (
+	FaultTimeout    = "timeout"     // The query hangs until its timeout
+	FaultServerDown = "server_down" // The license server cannot be reached
+	FaultVendorDown = "vendor_down" // The server is up, its vendor daemon down
+	FaultGarbage    = "garbage"     // The status tool prints unparseable output
+)
+
+// Faults lists the supported faults
+var Faults = []string{FaultTimeout, FaultServerDown, FaultVendorDown, FaultGarbage}
+
+// faultOutput is what the status tools print for each fault, with {server}
+// replaced by the server, {host} by its host and {HOST} by it in upper case
+var faultOutput = map[string]map[string]string{
+	"flexlm": {
+		FaultServerDown: "lmutil - Copyright (c) 1989-2023 Flexera. All Rights Reserved.\n" +
+			"Flexible License Manager status on {HOST}\n\n" +
+			"Error getting status: Cannot connect to license server system. (-15,10:111 \"Connection refused\")\n",
+		FaultVendorDown: "lmutil - Copyright (c) 1989-2023 Flexera. All Rights Reserved.\n" +
+			"Flexible License Manager status on {HOST}\n\n" +
+			"License server status: {server}\n" +
+			"    License file(s) on {host}: /opt/licenses/license.dat:\n\n" +
+			"{host}: license server UP (MASTER) v11.19.0\n\n" +
+			"Vendor daemon status (on {host}):\n\n" +
+			"   vendor: The desired vendor daemon is down. (-97,121)\n",
+	},
+	"rlm": {
+		FaultServerDown: "rlmutil: Error connecting to \"rlm\" server: Communications error with license server (-17)\n",
+		FaultVendorDown: "Setting license file path to {server}\n" +
+			"rlm software version v15.1 (build:2)\n\n" +
+			"rlm status on {host} (port 5053), up 12d 03:14:15\n\n" +
+			"ISV servers:\n" +
+			"   Name           port Running Restarts\n" +
+			"   vendor         5054   No        3\n",
+	},
+}
+
+// garbageOutput is output of a status tool that crashed or was replaced
+const garbageOutput = "\x00\x1b[2J#%!~ lm?stat: \xff\xfe internal error 0x7f3a\n" +
+	"Users of : (Total of license issued\n" +
+	"Segmentation fault (core dumped)\n"
+
+// outputParser parses the output of a status tool
+type outputParser interface {
+	parseOutput(reader io.Reader, result *models.ServerQueryResult)
+}
+
+// faultParser answers queries as a license server with a fault would
+type faultParser struct {
+	clocked
+	fault    string
+	parser   outputParser
+	template string
+}
+
+// GetFaultParser returns a parser answering queries as a server of
+// serverType with fault would. Server and vendor down and garbage output are
+// parsed like the output of the real status tool.
+func (f *ParserFactory) GetFaultParser(serverType, fault string) (Parser, error) {
+	p := &faultParser{clocked: clocked{clock: f.clock}, fault: fault}
+	switch serverType {
+	case "flexlm":
+		p.parser = &FlexLMParser{clocked: p.clocked}
+	case "rlm":
+		p.parser = &RLMParser{clocked: p.clocked}
+	default:
+		return nil, fmt.Errorf("unsupported server type: %s", serverType)
+	}
+
+	switch fault {
+	case FaultTimeout:
+	case FaultGarbage:
+		p.template = garbageOutput
+	case FaultServerDown, FaultVendorDown:
+		p.template = faultOutput[serverType][fault]
+	default:
+		return nil, fmt.Errorf("unknown fault %q", fault)
+	}
+	return p, nil
+}
+
+func (p *faultParser) Query(ctx context.Context, hostname string) (models.ServerQueryResult, error) {
+	result := NewServerQueryResult(hostname, p.now())
+	if p.fault == FaultTimeout {
+		<-ctx.Done()
+		return result, ctx.Err()
+	}
+
+	host := hostname
+	if i := strings.LastIndex(host, "@"); i != -1 {
+		host = host[i+1:]
+	}
+	output := strings.NewReplacer("{server}", hostname, "{host}", host, "{HOST}", strings.ToUpper(host)).Replace(p.template)
+	p.parser.parseOutput(strings.NewReader(output), &result)
+	return result, nil
+}
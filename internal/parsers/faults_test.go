@@ -0,0 +1,53 @@
+package parsers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFaultParser(t *testing.T) {
+	f := NewParserFactory(nil)
+	tests := []struct {
+		serverType string
+		fault      string
+		service    string
+	}{
+		{"flexlm", FaultServerDown, "down"},
+		{"flexlm", FaultVendorDown, "warning"},
+		{"flexlm", FaultGarbage, "down"},
+		{"rlm", FaultServerDown, "down"},
+		{"rlm", FaultVendorDown, "warning"},
+		{"rlm", FaultGarbage, "down"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.serverType+" "+tt.fault, func(t *testing.T) {
+			p, err := f.GetFaultParser(tt.serverType, tt.fault)
+			if err != nil {
+				t.Fatalf("GetFaultParser failed: %v", err)
+			}
+			result, err := p.Query(context.Background(), "27000@flex1")
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if result.Status.Service != tt.service {
+				t.Errorf("Expected service %s, got %+v", tt.service, result.Status)
+			}
+			if len(result.Features) != 0 {
+				t.Errorf("Expected no features, got %d", len(result.Features))
+			}
+		})
+	}
+
+	p, _ := f.GetFaultParser("flexlm", FaultTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.Query(ctx, "27000@flex1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the query to run into its deadline, got %v", err)
+	}
+
+	if _, err := f.GetFaultParser("flexlm", "meteor"); err == nil {
+		t.Error("Expected an unknown fault to fail")
+	}
+}
@@ -6,9 +6,9 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
-	"time"
 
 	log "github.com/sirupsen/logrus"
 	"licet/internal/models"
@@ -25,15 +25,32 @@ var (
 	flexFeatureRe        = regexp.MustCompile(`(?i)users of\s+(.+?):\s+\(Total of (\d+) license[s]? issued;\s+Total of (\d+) license[s]? in use\)`)
 	flexNoFeatureRe      = regexp.MustCompile(`(?i)no such feature exists`)
 	flexUncountedRe      = regexp.MustCompile(`(?i)users of\s+(.+?):\s+\(uncounted, node-locked\)`)
-	flexExpirationOldRe  = regexp.MustCompile(`(?i)(\w+)\s+(\d+|\d+\.\d+)\s+(\d+)\s+(\d+-\w+-\d+)(?:\s+(\w+))?$`)
-	flexExpirationNewRe  = regexp.MustCompile(`(?i)(\w+)\s+(\d+|\d+\.\d+)\s+(\d+)\s+(\w+)\s+(\d+-\w+-\d+)$`)
-	flexExpirationPermRe = regexp.MustCompile(`(?i)(\w+)\s+(\d+|\d+\.\d+)\s+(\d+)\s+(\w+)\s+(permanent)`)
-	flexUserRe           = regexp.MustCompile(`\s+(.+?)\s+(.+?)\s+(.+?)\s+\(v?([^\)]+)\).*start\s+(\w+\s+\d+/\d+(?:/\d+)?\s+\d+:\d+)`)
+	flexExpirationOldRe  = regexp.MustCompile(`(?i)(\w[\w-]*)\s+(\d+|\d+\.\d+)\s+(\d+)\s+(\d+-[\p{L}.]+-\d+)(?:\s+(\w+))?$`) // Names may contain hyphens, e.g. Real-Time_Workshop
+	flexExpirationNewRe  = regexp.MustCompile(`(?i)(\w[\w-]*)\s+(\d+|\d+\.\d+)\s+(\d+)\s+(\w+)\s+(\d+-[\p{L}.]+-\d+)$`)
+	flexExpirationPermRe = regexp.MustCompile(`(?i)(\w[\w-]*)\s+(\d+|\d+\.\d+)\s+(\d+)\s+(\w+)\s+(permanent)`)
+	flexUserRe           = regexp.MustCompile(`\s+(.+?)\s+(.+?)\s+(.+?)\s+\(v?([^\)]+)\).*start\s+([\p{L}.]+\s+\d+/\d+(?:/\d+)?\s+\d+:\d+)`)
 	flexFeatureVersionRe = regexp.MustCompile(`^\s+"([^"]+)"\s+v?([0-9.]+)`)
+	flexReservationRe    = regexp.MustCompile(`(?i)^\s+(\d+)\s+RESERV(?:ATION|E)s?\s+for\s+(\w+)\s+(\S+)`)
+	flexLingerRe         = regexp.MustCompile(`(?i)\(linger:\s*\d+`)
+	flexTokensRe         = regexp.MustCompile(`(?i),\s*(\d+)\s+licenses\b`)
+	flexPackageRe        = regexp.MustCompile(`^\s*PACKAGE\s+(\S+)\s+\S+`)
+	flexComponentsRe     = regexp.MustCompile(`(?i)COMPONENTS="([^"]*)("?)`)
+	flexOverdraftRe      = regexp.MustCompile(`(?i)\boverdraft\s*[:=]\s*(\d+)`)
+	flexLicenseModelRe   = regexp.MustCompile(`(?i)^\s+(uncounted\s+)?(floating|node-?locked)\s+license`)
+	flexQueuedCountRe    = regexp.MustCompile(`(?i)^\s+(\d+)\s+users?\s+(?:waiting|queued)\b`)
+	flexQueuedUserRe     = regexp.MustCompile(`(?i)^\s+\S+\s+\S+.*\(v?[^)]+\).*\bqueued\b(?:\s+for\s+(\d+)\s+licenses?)?`)
 )
 
 type FlexLMParser struct {
+	clocked
 	lmutilPath string
+	profile    flexProfile // Vendor specific adjustments, see GetParserForProfile
+}
+
+// flexProfile adjusts the results of a vendor daemon whose lmstat output
+// differs from the usual
+type flexProfile interface {
+	apply(result *models.ServerQueryResult)
 }
 
 func NewFlexLMParser(lmutilPath string) *FlexLMParser {
@@ -44,7 +61,7 @@ func NewFlexLMParser(lmutilPath string) *FlexLMParser {
 }
 
 func (p *FlexLMParser) Query(ctx context.Context, hostname string) (models.ServerQueryResult, error) {
-	result := NewServerQueryResult(hostname)
+	result := NewServerQueryResult(hostname, p.now())
 
 	// Execute lmstat command
 	output, _ := ExecuteCommand(ctx, "FlexLM", p.lmutilPath, "lmstat", "-i", "-a", "-c", hostname)
@@ -60,6 +77,7 @@ func (p *FlexLMParser) parseOutput(reader io.Reader, result *models.ServerQueryR
 
 	// Track the last feature name to handle "no such feature exists" on next line
 	var lastFeatureName string
+	masterSeen := false
 
 	currentFeature := ""
 	currentFeatureVersion := "" // Track version from inline feature info
@@ -68,6 +86,17 @@ func (p *FlexLMParser) parseOutput(reader io.Reader, result *models.ServerQueryR
 	usageMap := make(map[string]struct{ total, used int })
 	// Track inline feature versions (the license version, not client version)
 	featureVersionMap := make(map[string]string)
+	// Track RESERVATION and OVERDRAFT seat counts by feature name
+	reservedMap := make(map[string]int)
+	overdraftMap := make(map[string]int)
+	// Track license models from the inline feature info
+	licenseModelMap := make(map[string]string)
+	// Track checkout requests waiting in the queue by feature name
+	queuedMap := make(map[string]int)
+	// Track borrowed (lingering) checkouts by feature name
+	borrowedMap := make(map[string]int)
+	// Track the PACKAGE whose COMPONENTS may continue on the next lines
+	var pkg packageLine
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -86,14 +115,31 @@ func (p *FlexLMParser) parseOutput(reader io.Reader, result *models.ServerQueryR
 			continue
 		}
 
+		// Parse PACKAGE lines, which license files may continue with a backslash
+		// Format: PACKAGE suite vendor 1.0 COMPONENTS="solver:1.0:4 mesher"
+		if matches := flexPackageRe.FindStringSubmatch(line); matches != nil {
+			pkg = packageLine{name: matches[1]}
+		}
+		if pkg.name != "" {
+			if pkg.add(line) {
+				result.Packages = append(result.Packages, parseComponents(result.Status.Hostname, pkg.name, pkg.components)...)
+				pkg = packageLine{}
+			}
+			continue
+		}
+
 		// Check server status
 		if matches := flexServerUpRe.FindStringSubmatch(line); matches != nil {
 			result.Status.Service = "up"
-			result.Status.Master = matches[1]
-			if idx := strings.Index(matches[1], "."); idx != -1 {
-				result.Status.Master = matches[1][:idx]
-			}
 			result.Status.Version = matches[2]
+			// In redundant triads every server is listed as UP; keep the MASTER
+			if !masterSeen {
+				result.Status.Master = matches[1]
+				if idx := strings.Index(matches[1], "."); idx != -1 {
+					result.Status.Master = matches[1][:idx]
+				}
+				masterSeen = strings.Contains(line, "(MASTER)")
+			}
 			continue
 		}
 
@@ -135,6 +181,57 @@ func (p *FlexLMParser) parseOutput(reader io.Reader, result *models.ServerQueryR
 			continue
 		}
 
+		// Parse queued requests (appear in the "Users of" block)
+		// Format: "    3 users waiting" or, per request,
+		// "    user4 ws04 /dev/tty (v2023.1) (server/27000 301) queued for 1 license"
+		if currentFeature != "" {
+			if matches := flexQueuedCountRe.FindStringSubmatch(line); matches != nil {
+				count, _ := strconv.Atoi(matches[1])
+				queuedMap[currentFeature] += count
+				continue
+			}
+			if matches := flexQueuedUserRe.FindStringSubmatch(line); matches != nil {
+				count := 1
+				if matches[1] != "" {
+					count, _ = strconv.Atoi(matches[1])
+				}
+				queuedMap[currentFeature] += count
+				continue
+			}
+		}
+
+		// Parse reservations (appear in the "Users of" block)
+		// Format: "    2 RESERVATIONs for HOST ws01 (server/27000)"
+		if matches := flexReservationRe.FindStringSubmatch(line); matches != nil && currentFeature != "" {
+			count, _ := strconv.Atoi(matches[1])
+			reservedMap[currentFeature] += count
+			continue
+		}
+
+		// Parse license model and overdraft seats from the inline feature info
+		// Format: "  floating license  overdraft: 5" or "  nodelocked license, locked to ..."
+		if currentFeature != "" {
+			modelMatches := flexLicenseModelRe.FindStringSubmatch(line)
+			if modelMatches != nil {
+				switch {
+				case modelMatches[1] != "":
+					licenseModelMap[currentFeature] = models.LicenseModelUncounted
+				case strings.EqualFold(modelMatches[2], "floating"):
+					licenseModelMap[currentFeature] = models.LicenseModelFloating
+				default:
+					licenseModelMap[currentFeature] = models.LicenseModelNodeLocked
+				}
+			}
+			if matches := flexOverdraftRe.FindStringSubmatch(line); matches != nil {
+				count, _ := strconv.Atoi(matches[1])
+				overdraftMap[currentFeature] = count
+				continue
+			}
+			if modelMatches != nil {
+				continue
+			}
+		}
+
 		// Parse inline feature version (appears after "Users of" line)
 		// Format: "feature_name" v2026.0630, vendor: ansyslmd, expiry: ...
 		if matches := flexFeatureVersionRe.FindStringSubmatch(line); matches != nil && currentFeature != "" {
@@ -153,8 +250,10 @@ func (p *FlexLMParser) parseOutput(reader io.Reader, result *models.ServerQueryR
 				Name:           featureName,
 				TotalLicenses:  9999, // Uncounted
 				UsedLicenses:   0,
-				LastUpdated:    time.Now(),
+				LicenseModel:   models.LicenseModelUncounted,
+				LastUpdated:    p.now(),
 			}
+			licenseModelMap[featureName] = models.LicenseModelUncounted
 			currentFeature = featureName
 			continue
 		}
@@ -201,7 +300,7 @@ func (p *FlexLMParser) parseOutput(reader io.Reader, result *models.ServerQueryR
 		}
 
 		if matched {
-			expDate := ParseExpirationDate(expirationStr)
+			expDate, quality := parseExpiration(result, featureName, expirationStr)
 
 			// Create a unique key for each license pool: name + version + expiration
 			key := fmt.Sprintf("%s|%s|%s", featureName, version, expDate.Format("2006-01-02"))
@@ -210,6 +309,9 @@ func (p *FlexLMParser) parseOutput(reader io.Reader, result *models.ServerQueryR
 			// (multiple license file entries can have the same feature/version/expiration)
 			if existing, ok := featureMap[key]; ok {
 				existing.TotalLicenses += numLicenses
+				if quality != models.ParseQualityOK {
+					existing.ParseQuality = quality
+				}
 			} else {
 				// Create feature with UsedLicenses = 0; will be updated after user parsing
 				featureMap[key] = &models.Feature{
@@ -220,7 +322,8 @@ func (p *FlexLMParser) parseOutput(reader io.Reader, result *models.ServerQueryR
 					TotalLicenses:  numLicenses,
 					UsedLicenses:   0,
 					ExpirationDate: expDate,
-					LastUpdated:    time.Now(),
+					ParseQuality:   quality,
+					LastUpdated:    p.now(),
 				}
 			}
 			continue
@@ -233,29 +336,22 @@ func (p *FlexLMParser) parseOutput(reader io.Reader, result *models.ServerQueryR
 			version := strings.TrimSpace(matches[4])
 			checkedOutStr := strings.TrimSpace(matches[5])
 
-			// Parse the checkout time - try multiple formats
-			// FlexLM can output: "Mon 1/2 15:04", "Mon 1/2/24 15:04", or "Mon 1/2/2024 15:04"
-			var checkedOut time.Time
-			var err error
-			timeFormats := []string{
-				"Mon 1/2/2006 15:04", // Full year: Mon 1/2/2024 15:04
-				"Mon 1/2/06 15:04",   // 2-digit year: Mon 1/2/24 15:04
-				"Mon 1/2 15:04",      // No year: Mon 1/2 15:04
-			}
-			for _, format := range timeFormats {
-				checkedOut, err = time.Parse(format, checkedOutStr)
-				if err == nil {
-					break
-				}
-			}
+			// Parse the checkout time
+			// FlexLM can output: "Mon 1/2 15:04", "Mon 1/2/24 15:04", or "Mon 1/2/2024 15:04",
+			// with a localized weekday on non-English systems
+			checkedOut, err := ParseCheckoutTime(checkedOutStr, p.now())
 			if err != nil {
-				log.Debugf("Failed to parse checkout time '%s': %v", checkedOutStr, err)
+				log.Warnf("Failed to parse checkout time '%s': %v", checkedOutStr, err)
+				result.Status.Warnings = append(result.Status.Warnings,
+					fmt.Sprintf("Unparseable checkout time %q for %s on %s; checkout skipped", checkedOutStr, username, currentFeature))
 				continue
 			}
 
-			// Adjust to current year if no year was in the format (year would be 0)
-			if checkedOut.Year() == 0 {
-				checkedOut = AdjustCheckoutTimeToCurrentYear(checkedOut)
+			// Token-based features check out several licenses at once:
+			// "..., start Mon 6/3 9:15, 4 licenses"
+			var tokens int
+			if matches := flexTokensRe.FindStringSubmatch(line); matches != nil {
+				tokens, _ = strconv.Atoi(matches[1])
 			}
 
 			// Store both client version (for display) and license version (for matching to features)
@@ -267,7 +363,12 @@ func (p *FlexLMParser) parseOutput(reader io.Reader, result *models.ServerQueryR
 				CheckedOutAt:   checkedOut,
 				Version:        version,               // Client software version for display
 				LicenseVersion: currentFeatureVersion, // License pool version for matching
+				Tokens:         tokens,
 			})
+			// Borrowed licenses linger: "..., start Mon 6/3 9:15 (linger: 604800)"
+			if flexLingerRe.MatchString(line) {
+				borrowedMap[currentFeature]++
+			}
 		}
 	}
 
@@ -327,19 +428,19 @@ func (p *FlexLMParser) parseOutput(reader io.Reader, result *models.ServerQueryR
 				Version:        version,
 				TotalLicenses:  usage.total,
 				UsedLicenses:   usage.used,
-				LastUpdated:    time.Now(),
+				LastUpdated:    p.now(),
 			}
 		}
 	}
 
 	// Calculate used licenses per feature+version based on actual user counts
-	// Count users per feature name + license version (not client version)
+	// Count the seats of users per feature name + license version (not client version)
 	userCountByFeatureVersion := make(map[string]int)
 	userCountByFeatureName := make(map[string]int)
 	for _, user := range result.Users {
 		key := fmt.Sprintf("%s|%s", user.FeatureName, user.LicenseVersion)
-		userCountByFeatureVersion[key]++
-		userCountByFeatureName[user.FeatureName]++
+		userCountByFeatureVersion[key] += user.Seats()
+		userCountByFeatureName[user.FeatureName] += user.Seats()
 	}
 
 	// Update UsedLicenses for each feature based on actual user counts
@@ -367,13 +468,129 @@ func (p *FlexLMParser) parseOutput(reader io.Reader, result *models.ServerQueryR
 		featureMap[key] = feature
 	}
 
+	distributeSeatCounts(featureMap, usageMap, reservedMap, borrowedMap, overdraftMap, queuedMap)
+
+	// Apply license models; features without inline info are floating
+	for _, feature := range featureMap {
+		if model, ok := licenseModelMap[feature.Name]; ok {
+			feature.LicenseModel = model
+		} else if feature.LicenseModel == "" {
+			feature.LicenseModel = models.LicenseModelFloating
+		}
+	}
+
 	// Convert feature map to slice
 	for _, feature := range featureMap {
 		result.Features = append(result.Features, *feature)
 	}
 
+	if p.profile != nil {
+		p.profile.apply(result)
+	}
+
 	if result.Status.Service == "" {
 		result.Status.Service = "down"
 		result.Status.Message = fmt.Sprintf("Unknown error from %s", result.Status.Hostname)
 	}
 }
+
+// packageLine collects the COMPONENTS of a PACKAGE line and its continuations
+type packageLine struct {
+	name       string
+	components string
+	quoted     bool // Inside the quotes of COMPONENTS
+}
+
+// add adds a line of the package and reports whether the package is complete
+func (p *packageLine) add(line string) bool {
+	text := strings.TrimSuffix(strings.TrimSpace(line), "\\")
+	if p.quoted {
+		if end := strings.Index(text, `"`); end != -1 {
+			p.components += " " + text[:end]
+			p.quoted = false
+			return true
+		}
+		p.components += " " + text
+		return false
+	}
+	if matches := flexComponentsRe.FindStringSubmatch(text); matches != nil {
+		p.components = matches[1]
+		p.quoted = matches[2] == ""
+		return !p.quoted
+	}
+	// Packages without COMPONENTS end where the line is not continued
+	return !strings.HasSuffix(strings.TrimSpace(line), "\\")
+}
+
+// parseComponents parses the COMPONENTS of a PACKAGE, each
+// "feature[:version[:tokens]]"
+func parseComponents(hostname, packageName, spec string) []models.PackageComponent {
+	var components []models.PackageComponent
+	for _, field := range strings.Fields(spec) {
+		parts := strings.SplitN(field, ":", 3)
+		component := models.PackageComponent{
+			ServerHostname: hostname,
+			PackageName:    packageName,
+			FeatureName:    parts[0],
+			Tokens:         1,
+		}
+		if len(parts) > 1 {
+			component.Version = parts[1]
+		}
+		if len(parts) > 2 {
+			if tokens, err := strconv.Atoi(parts[2]); err == nil && tokens > 0 {
+				component.Tokens = tokens
+			}
+		}
+		components = append(components, component)
+	}
+	return components
+}
+
+// distributeSeatCounts assigns reserved, borrowed and overdraft seats and
+// queued requests, which lmstat reports per feature name, to the feature's
+// license pools. Reservations fill pools in key order up to each pool's size,
+// borrowed seats up to the seats each pool has in use. Overdraft seats and
+// queued requests go to the first pool; when no explicit overdraft is
+// reported, seats in use beyond the issued count are overdraft in use.
+func distributeSeatCounts(featureMap map[string]*models.Feature, usageMap map[string]struct{ total, used int }, reservedMap, borrowedMap, overdraftMap, queuedMap map[string]int) {
+	keys := make([]string, 0, len(featureMap))
+	for key := range featureMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	overdraftAssigned := make(map[string]bool)
+	for _, key := range keys {
+		feature := featureMap[key]
+
+		if remaining := reservedMap[feature.Name]; remaining > 0 {
+			reserved := remaining
+			if reserved > feature.TotalLicenses {
+				reserved = feature.TotalLicenses
+			}
+			feature.ReservedLicenses = reserved
+			reservedMap[feature.Name] = remaining - reserved
+		}
+
+		if remaining := borrowedMap[feature.Name]; remaining > 0 {
+			borrowed := remaining
+			if borrowed > feature.UsedLicenses {
+				borrowed = feature.UsedLicenses
+			}
+			feature.BorrowedLicenses = borrowed
+			borrowedMap[feature.Name] = remaining - borrowed
+		}
+
+		if overdraftAssigned[feature.Name] {
+			continue
+		}
+		overdraftAssigned[feature.Name] = true
+		feature.QueuedRequests = queuedMap[feature.Name]
+		if overdraft, ok := overdraftMap[feature.Name]; ok {
+			feature.OverdraftLicenses = overdraft
+		} else if usage, ok := usageMap[feature.Name]; ok && usage.used > usage.total {
+			feature.OverdraftLicenses = usage.used - usage.total
+		}
+	}
+}
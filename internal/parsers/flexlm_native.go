@@ -0,0 +1,84 @@
+package parsers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/models"
+)
+
+// defaultFlexLMPort is the first port of the range lmgrd listens on when a
+// license file does not pin one
+const defaultFlexLMPort = "27000"
+
+// FlexLMNativeClient queries FlexLM servers over TCP without lmutil.
+//
+// The lmgrd and vendor daemon wire protocol is proprietary and undocumented,
+// so the native client only establishes whether lmgrd accepts connections.
+// It reports the server status but no features or checkouts; servers that
+// need those must keep using query_mode binary.
+type FlexLMNativeClient struct {
+	clocked
+	dialer net.Dialer
+}
+
+// NewFlexLMNativeClient creates a native FlexLM client with the given connect timeout
+func NewFlexLMNativeClient(timeout time.Duration) *FlexLMNativeClient {
+	return &FlexLMNativeClient{dialer: net.Dialer{Timeout: timeout}}
+}
+
+// Query checks whether any lmgrd of a server accepts connections. Hostnames
+// use the lmstat -c syntax: port@host, or port@host1,port@host2,port@host3
+// for a redundant triad.
+func (c *FlexLMNativeClient) Query(ctx context.Context, hostname string) (models.ServerQueryResult, error) {
+	result := NewServerQueryResult(hostname, c.now())
+
+	addrs, err := flexLMAddresses(hostname)
+	if err != nil {
+		result.Status.Message = err.Error()
+		return result, nil
+	}
+
+	for _, addr := range addrs {
+		conn, err := c.dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			log.Debugf("FlexLM native: cannot connect to %s: %v", addr, err)
+			continue
+		}
+		conn.Close()
+
+		result.Status.Service = "up"
+		result.Status.Message = fmt.Sprintf("lmgrd accepts connections on %s; features and checkouts need query_mode binary", addr)
+		return result, nil
+	}
+
+	result.Status.Message = fmt.Sprintf("Cannot connect to %s", hostname)
+	return result, nil
+}
+
+// flexLMAddresses converts a port@host list into TCP addresses
+func flexLMAddresses(hostname string) ([]string, error) {
+	var addrs []string
+	for _, entry := range strings.Split(hostname, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		port, host, ok := strings.Cut(entry, "@")
+		if !ok {
+			port, host = defaultFlexLMPort, entry
+		}
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("invalid FlexLM server %q: use port@host", entry)
+		}
+		addrs = append(addrs, net.JoinHostPort(host, port))
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("invalid FlexLM server %q: use port@host", hostname)
+	}
+	return addrs, nil
+}
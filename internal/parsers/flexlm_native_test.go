@@ -0,0 +1,110 @@
+package parsers
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestFlexLMAddresses(t *testing.T) {
+	tests := []struct {
+		hostname string
+		want     []string
+	}{
+		{"27000@lic1", []string{"lic1:27000"}},
+		{"lic1", []string{"lic1:27000"}},
+		{"27000@lic1,27000@lic2, 27001@lic3", []string{"lic1:27000", "lic2:27000", "lic3:27001"}},
+	}
+	for _, tt := range tests {
+		got, err := flexLMAddresses(tt.hostname)
+		if err != nil {
+			t.Errorf("flexLMAddresses(%q) failed: %v", tt.hostname, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("flexLMAddresses(%q) = %v, want %v", tt.hostname, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("flexLMAddresses(%q) = %v, want %v", tt.hostname, got, tt.want)
+			}
+		}
+	}
+
+	for _, bad := range []string{"", "27000@", "@lic1"} {
+		if _, err := flexLMAddresses(bad); err == nil {
+			t.Errorf("Expected flexLMAddresses(%q) to fail", bad)
+		}
+	}
+}
+
+func TestFlexLMNativeClient_Query(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	// A port nothing listens on
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	_, closedPort, _ := net.SplitHostPort(closed.Addr().String())
+	closed.Close()
+
+	client := NewFlexLMNativeClient(time.Second)
+	ctx := context.Background()
+
+	result, _ := client.Query(ctx, port+"@127.0.0.1")
+	if result.Status.Service != "up" {
+		t.Errorf("Expected listening server to be up, got %+v", result.Status)
+	}
+	if len(result.Features) != 0 {
+		t.Errorf("Expected no features from native status, got %d", len(result.Features))
+	}
+
+	// A triad is up while any of its servers accepts connections
+	result, _ = client.Query(ctx, closedPort+"@127.0.0.1,"+port+"@127.0.0.1")
+	if result.Status.Service != "up" {
+		t.Errorf("Expected triad with one listening server to be up, got %+v", result.Status)
+	}
+
+	result, _ = client.Query(ctx, closedPort+"@127.0.0.1")
+	if result.Status.Service != "down" || result.Status.Message == "" {
+		t.Errorf("Expected unreachable server to be down with a message, got %+v", result.Status)
+	}
+}
+
+func TestParserFactory_GetParserForMode(t *testing.T) {
+	factory := NewParserFactory(map[string]string{"lmutil": "/usr/bin/lmutil"})
+
+	if p, err := factory.GetParserForMode("flexlm", ""); err != nil {
+		t.Errorf("Expected default mode to work: %v", err)
+	} else if _, ok := p.(*FlexLMParser); !ok {
+		t.Errorf("Expected binary parser by default, got %T", p)
+	}
+	if p, err := factory.GetParserForMode("flexlm", QueryModeNative); err != nil {
+		t.Errorf("Expected native flexlm to work: %v", err)
+	} else if _, ok := p.(*FlexLMNativeClient); !ok {
+		t.Errorf("Expected native client, got %T", p)
+	}
+	if _, err := factory.GetParserForMode("rlm", QueryModeNative); err == nil {
+		t.Error("Expected native rlm to be unsupported")
+	}
+	if _, err := factory.GetParserForMode("flexlm", "telnet"); err == nil {
+		t.Error("Expected unknown mode to fail")
+	}
+}
@@ -1,6 +1,7 @@
 package parsers
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -296,6 +297,10 @@ Users of nodelocked:  (uncounted, node-locked)
 	if feature.TotalLicenses != 9999 {
 		t.Errorf("Expected uncounted to have 9999 licenses, got %d", feature.TotalLicenses)
 	}
+
+	if feature.LicenseModel != models.LicenseModelUncounted {
+		t.Errorf("Expected license model 'uncounted', got '%s'", feature.LicenseModel)
+	}
 }
 
 func TestFlexLMParser_MultipleUsersOfFeatures(t *testing.T) {
@@ -1435,3 +1440,324 @@ ans_act 2026.0630 25 ansyslmd permanent
 		}
 	}
 }
+
+func TestFlexLMParser_ReservationsAndOverdraft(t *testing.T) {
+	parser := &FlexLMParser{lmutilPath: "/usr/local/bin/lmutil"}
+
+	output := `lmstat - Copyright (c) 1989-2023 Flexera.
+License server status: 27000@server.example.com
+    server.example.com: license server UP v11.18.1
+
+Feature usage info:
+
+Users of solver:  (Total of 10 licenses issued;  Total of 3 licenses in use)
+
+  "solver" v1.0, vendor: vendor1, expiry: permanent
+  floating license  overdraft: 2
+
+    jdoe ws01 /dev/tty (v1.0) (server/27000 101), start Mon 6/3 9:15
+    2 RESERVATIONs for HOST ws02 (server/27000)
+    1 RESERVATION for USER asmith (server/27000)
+
+Users of mesher:  (Total of 4 licenses issued;  Total of 6 licenses in use)
+
+License files:
+solver 1.0 10 vendor1 permanent
+mesher 2.0 4 vendor1 permanent
+`
+
+	result := models.ServerQueryResult{
+		Status: models.ServerStatus{
+			Hostname: "27000@server.example.com",
+			Service:  "down",
+		},
+	}
+
+	parser.parseOutput(strings.NewReader(output), &result)
+
+	features := make(map[string]models.Feature)
+	for _, f := range result.Features {
+		features[f.Name] = f
+	}
+
+	solver := features["solver"]
+	if solver.ReservedLicenses != 3 {
+		t.Errorf("Expected 3 reserved solver licenses, got %d", solver.ReservedLicenses)
+	}
+	if solver.OverdraftLicenses != 2 {
+		t.Errorf("Expected 2 overdraft solver licenses, got %d", solver.OverdraftLicenses)
+	}
+	if solver.UsedLicenses != 1 {
+		t.Errorf("Expected 1 used solver license, got %d", solver.UsedLicenses)
+	}
+	if solver.AvailableLicenses() != 6 {
+		t.Errorf("Expected 6 available solver licenses, got %d", solver.AvailableLicenses())
+	}
+
+	// Seats in use beyond the issued count are overdraft
+	if mesher := features["mesher"]; mesher.OverdraftLicenses != 2 {
+		t.Errorf("Expected 2 overdraft mesher licenses, got %d", mesher.OverdraftLicenses)
+	}
+
+	if len(result.Users) != 1 {
+		t.Errorf("Expected reservations not to be parsed as users, got %d users", len(result.Users))
+	}
+}
+
+func TestFlexLMParser_BorrowedAndGroupReservations(t *testing.T) {
+	parser := &FlexLMParser{lmutilPath: "/usr/local/bin/lmutil"}
+
+	output := `lmstat - Copyright (c) 1989-2023 Flexera.
+License server status: 27000@server.example.com
+    server.example.com: license server UP v11.18.1
+
+Feature usage info:
+
+Users of solver:  (Total of 10 licenses issued;  Total of 3 licenses in use)
+
+  "solver" v1.0, vendor: vendor1, expiry: permanent
+  floating license
+
+    jdoe ws01 /dev/tty (v1.0) (server/27000 101), start Mon 6/3 9:15 (linger: 604800)
+    asmith laptop02 /dev/tty (v1.0) (server/27000 102), start Mon 6/3 9:20 (linger: 604800 / 12345)
+    bwong ws03 /dev/tty (v1.0) (server/27000 103), start Mon 6/3 9:25
+    4 RESERVATIONs for GROUP engineering (server/27000)
+
+License files:
+solver 1.0 10 vendor1 permanent
+`
+
+	result := models.ServerQueryResult{
+		Status: models.ServerStatus{
+			Hostname: "27000@server.example.com",
+			Service:  "down",
+		},
+	}
+
+	parser.parseOutput(strings.NewReader(output), &result)
+
+	if len(result.Features) != 1 {
+		t.Fatalf("Expected 1 feature, got %d", len(result.Features))
+	}
+	solver := result.Features[0]
+	if solver.BorrowedLicenses != 2 {
+		t.Errorf("Expected 2 borrowed solver licenses, got %d", solver.BorrowedLicenses)
+	}
+	if solver.ReservedLicenses != 4 {
+		t.Errorf("Expected 4 reserved solver licenses, got %d", solver.ReservedLicenses)
+	}
+	// Borrowed seats are in use until returned
+	if solver.UsedLicenses != 3 {
+		t.Errorf("Expected 3 used solver licenses, got %d", solver.UsedLicenses)
+	}
+	if solver.AvailableLicenses() != 3 {
+		t.Errorf("Expected 3 available solver licenses, got %d", solver.AvailableLicenses())
+	}
+	if len(result.Users) != 3 {
+		t.Errorf("Expected borrowed licenses to be parsed as users, got %d users", len(result.Users))
+	}
+}
+
+func TestFlexLMParser_TokensAndPackages(t *testing.T) {
+	parser := &FlexLMParser{lmutilPath: "/usr/local/bin/lmutil"}
+
+	output := `lmstat - Copyright (c) 1989-2023 Flexera.
+License server status: 1055@server.example.com
+    server.example.com: license server UP v11.18.1
+
+Feature usage info:
+
+Users of anshpc_pack:  (Total of 64 licenses issued;  Total of 12 licenses in use)
+
+  "anshpc_pack" v2023.0, vendor: ansyslmd, expiry: permanent
+  floating license
+
+    jdoe ws01 ws01 (v2023.0) (server/1055 101), start Mon 6/3 9:15, 8 licenses
+    asmith ws02 ws02 (v2023.0) (server/1055 102), start Mon 6/3 9:20, 4 licenses
+
+Users of solver:  (Total of 10 licenses issued;  Total of 1 license in use)
+
+    bwong ws03 ws03 (v2023.0) (server/1055 103), start Mon 6/3 9:25
+
+PACKAGE anshpc_pack ansyslmd 2023.0 COMPONENTS="anshpc:2023.0:4 \
+	solver:2023.0:2 mesher"
+PACKAGE suite ansyslmd 1.0 COMPONENTS="viewer"
+
+License files:
+anshpc_pack 2023.0 64 ansyslmd permanent
+solver 2023.0 10 ansyslmd permanent
+`
+
+	result := models.ServerQueryResult{
+		Status: models.ServerStatus{
+			Hostname: "1055@server.example.com",
+			Service:  "down",
+		},
+	}
+
+	parser.parseOutput(strings.NewReader(output), &result)
+
+	features := make(map[string]models.Feature)
+	for _, f := range result.Features {
+		features[f.Name] = f
+	}
+	if pack := features["anshpc_pack"]; pack.UsedLicenses != 12 {
+		t.Errorf("Expected 12 tokens in use, got %d", pack.UsedLicenses)
+	}
+	if solver := features["solver"]; solver.UsedLicenses != 1 {
+		t.Errorf("Expected 1 solver license in use, got %d", solver.UsedLicenses)
+	}
+	if len(result.Users) != 3 || result.Users[0].Tokens != 8 || result.Users[2].Seats() != 1 {
+		t.Errorf("Expected the tokens of each checkout, got %+v", result.Users)
+	}
+
+	want := []models.PackageComponent{
+		{ServerHostname: "1055@server.example.com", PackageName: "anshpc_pack", FeatureName: "anshpc", Version: "2023.0", Tokens: 4},
+		{ServerHostname: "1055@server.example.com", PackageName: "anshpc_pack", FeatureName: "solver", Version: "2023.0", Tokens: 2},
+		{ServerHostname: "1055@server.example.com", PackageName: "anshpc_pack", FeatureName: "mesher", Tokens: 1},
+		{ServerHostname: "1055@server.example.com", PackageName: "suite", FeatureName: "viewer", Tokens: 1},
+	}
+	if !reflect.DeepEqual(result.Packages, want) {
+		t.Errorf("Expected packages %+v, got %+v", want, result.Packages)
+	}
+	if _, ok := features["suite"]; ok {
+		t.Error("Expected PACKAGE lines not to be parsed as features")
+	}
+}
+
+func TestFlexLMParser_QueuedRequests(t *testing.T) {
+	parser := &FlexLMParser{lmutilPath: "/usr/local/bin/lmutil"}
+
+	output := `lmstat - Copyright (c) 1989-2023 Flexera.
+License server status: 27000@server.example.com
+    server.example.com: license server UP v11.18.1
+
+Feature usage info:
+
+Users of solver:  (Total of 2 licenses issued;  Total of 2 licenses in use)
+
+  "solver" v1.0, vendor: vendor1, expiry: permanent
+  floating license
+
+    jdoe ws01 /dev/tty (v1.0) (server/27000 101), start Mon 6/3 9:15
+    asmith ws02 /dev/tty (v1.0) (server/27000 102), start Mon 6/3 9:20
+    bwong ws03 /dev/tty (v1.0) (server/27000 103) queued for 2 licenses
+    cdiaz ws04 /dev/tty (v1.0) (server/27000 104) (queued)
+
+Users of mesher:  (Total of 4 licenses issued;  Total of 4 licenses in use)
+
+    3 users waiting
+
+Users of viewer:  (Total of 4 licenses issued;  Total of 0 licenses in use)
+
+License files:
+solver 1.0 2 vendor1 permanent
+mesher 2.0 4 vendor1 permanent
+viewer 1.0 4 vendor1 permanent
+`
+
+	result := models.ServerQueryResult{
+		Status: models.ServerStatus{
+			Hostname: "27000@server.example.com",
+			Service:  "down",
+		},
+	}
+
+	parser.parseOutput(strings.NewReader(output), &result)
+
+	features := make(map[string]models.Feature)
+	for _, f := range result.Features {
+		features[f.Name] = f
+	}
+
+	if solver := features["solver"]; solver.QueuedRequests != 3 {
+		t.Errorf("Expected 3 queued solver requests, got %d", solver.QueuedRequests)
+	}
+	if mesher := features["mesher"]; mesher.QueuedRequests != 3 {
+		t.Errorf("Expected 3 queued mesher requests, got %d", mesher.QueuedRequests)
+	}
+	if viewer := features["viewer"]; viewer.QueuedRequests != 0 {
+		t.Errorf("Expected no queued viewer requests, got %d", viewer.QueuedRequests)
+	}
+
+	if len(result.Users) != 2 {
+		t.Errorf("Expected queued requests not to be parsed as users, got %d users", len(result.Users))
+	}
+}
+
+func TestFlexLMParser_LicenseModels(t *testing.T) {
+	parser := &FlexLMParser{lmutilPath: "/usr/local/bin/lmutil"}
+
+	output := `lmstat - Copyright (c) 1989-2023 Flexera.
+License server status: 27000@server.example.com
+    server.example.com: license server UP v11.18.1
+
+Feature usage info:
+
+Users of solver:  (Total of 10 licenses issued;  Total of 0 licenses in use)
+
+  "solver" v1.0, vendor: vendor1, expiry: permanent
+  floating license
+
+Users of viewer:  (Total of 2 licenses issued;  Total of 0 licenses in use)
+
+  "viewer" v1.0, vendor: vendor1, expiry: permanent
+  nodelocked license, locked to "ID_STRING=1234"
+
+Users of plugin:  (Total of 5 licenses issued;  Total of 0 licenses in use)
+
+License files:
+solver 1.0 10 vendor1 permanent
+viewer 1.0 2 vendor1 permanent
+plugin 1.0 5 vendor1 permanent
+`
+
+	result := models.ServerQueryResult{
+		Status: models.ServerStatus{
+			Hostname: "27000@server.example.com",
+			Service:  "down",
+		},
+	}
+
+	parser.parseOutput(strings.NewReader(output), &result)
+
+	expected := map[string]string{
+		"solver": models.LicenseModelFloating,
+		"viewer": models.LicenseModelNodeLocked,
+		"plugin": models.LicenseModelFloating, // No inline info defaults to floating
+	}
+	if len(result.Features) != len(expected) {
+		t.Fatalf("Expected %d features, got %d", len(expected), len(result.Features))
+	}
+	for _, f := range result.Features {
+		if f.LicenseModel != expected[f.Name] {
+			t.Errorf("Expected %s to be %s, got '%s'", f.Name, expected[f.Name], f.LicenseModel)
+		}
+	}
+}
+
+func TestFlexLMParser_TriadMaster(t *testing.T) {
+	parser := &FlexLMParser{lmutilPath: "/usr/local/bin/lmutil"}
+
+	output := `lmstat - Copyright (c) 1989-2023 Flexera.
+License server status: 27000@lic1,27000@lic2,27000@lic3
+License file(s) on lic2: /opt/license.dat:
+
+      lic1.example.com: license server UP v11.18.1
+      lic2.example.com: license server UP (MASTER) v11.18.1
+      lic3.example.com: license server UP v11.18.1
+`
+
+	result := models.ServerQueryResult{
+		Status: models.ServerStatus{
+			Hostname: "27000@lic1,27000@lic2,27000@lic3",
+			Service:  "down",
+		},
+	}
+
+	parser.parseOutput(strings.NewReader(output), &result)
+
+	if result.Status.Master != "lic2" {
+		t.Errorf("Expected master 'lic2', got '%s'", result.Status.Master)
+	}
+}
@@ -0,0 +1,158 @@
+package parsers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"licet/internal/models"
+)
+
+// License event types recorded from vendor daemon logs
+const (
+	EventOut    = "OUT"
+	EventIn     = "IN"
+	EventDenied = "DENIED"
+)
+
+// Vendor daemon debug log patterns compiled once at package level
+var (
+	// 14:23:11 (adskflex) OUT: "85536ACD_2023_0F" jdoe@ws01
+	// 14:23:11 (adskflex) DENIED: "85536ACD_2023_0F" jdoe@ws01  (Licensed number of users already reached. (-4,342))
+	flexLogEventRe     = regexp.MustCompile(`^\s*(?:(\d{1,2}/\d{1,2}/\d{4})\s+)?(\d{1,2}:\d{2}:\d{2})\s+\(([^)]+)\)\s+(OUT|IN|DENIED|UNSUPPORTED):\s+"?([^"\s]+)"?\s+(\S+)(?:\s+\((.*)\))?`)
+	flexLogTimestampRe = regexp.MustCompile(`\([^)]+\)\s+TIMESTAMP\s+(\d{1,2}/\d{1,2}/\d{4})`)
+
+	// 06/01 14:23 (isv) OUT: feature v1.0 by jdoe@ws01 (handle: 41)
+	// 06/01 14:23 (isv) DENIED: (1) feature v1.0 to jdoe@ws01 - All licenses in use
+	rlmLogEventRe = regexp.MustCompile(`^\s*(\d{1,2}/\d{1,2})\s+(\d{1,2}:\d{2}(?::\d{2})?)\s+\(([^)]+)\)\s+(OUT|IN|DENIED):\s+(?:\(\d+\)\s+)?(\S+)\s+v\S+\s+(?:by|to)\s+(\S+)(?:\s+-\s+(.*))?`)
+)
+
+// LogEventParser extracts license events from vendor daemon debug log lines
+type LogEventParser interface {
+	// ParseLine parses a single log line. It returns false for lines that carry no event.
+	ParseLine(line string) (models.LicenseEvent, bool)
+}
+
+// NewLogEventParser returns a log event parser for the given server type, or
+// for rlm_reportlog, of RLM ISV report logs. Events without a date in the log
+// are dated relative to now.
+func NewLogEventParser(serverType string, now time.Time) (LogEventParser, error) {
+	switch serverType {
+	case "flexlm":
+		return &flexLogParser{date: now}, nil
+	case "rlm":
+		return &rlmLogParser{now: now}, nil
+	case "rlm_reportlog":
+		return &rlmReportLogParser{now: now}, nil
+	default:
+		return nil, fmt.Errorf("log parsing not supported for server type: %s", serverType)
+	}
+}
+
+// splitUserHost splits a user@host token into the user name
+func splitUserHost(token string) string {
+	if i := strings.Index(token, "@"); i > 0 {
+		return token[:i]
+	}
+	return token
+}
+
+// flexLogParser parses FlexLM lmgrd/vendor daemon debug logs. FlexLM only
+// writes the date in periodic TIMESTAMP lines, so the parser is stateful.
+type flexLogParser struct {
+	date time.Time
+}
+
+func (p *flexLogParser) ParseLine(line string) (models.LicenseEvent, bool) {
+	if m := flexLogTimestampRe.FindStringSubmatch(line); m != nil {
+		if d, err := time.Parse("1/2/2006", m[1]); err == nil {
+			p.date = d
+		}
+		return models.LicenseEvent{}, false
+	}
+
+	m := flexLogEventRe.FindStringSubmatch(line)
+	if m == nil {
+		return models.LicenseEvent{}, false
+	}
+
+	date := p.date
+	if m[1] != "" {
+		if d, err := time.Parse("1/2/2006", m[1]); err == nil {
+			date = d
+		}
+	}
+	clock, err := time.Parse("15:04:05", m[2])
+	if err != nil {
+		return models.LicenseEvent{}, false
+	}
+
+	eventType := m[4]
+	reason := strings.TrimSpace(m[7])
+	if eventType == "UNSUPPORTED" {
+		// Requests for features the daemon does not serve are denials too
+		eventType = EventDenied
+		if reason == "" {
+			reason = "Unsupported feature"
+		}
+	}
+
+	return withReasonCode(models.LicenseEvent{
+		Date:        time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local),
+		Time:        clock,
+		EventType:   eventType,
+		FeatureName: m[5],
+		Username:    splitUserHost(m[6]),
+		Reason:      reason,
+	}), true
+}
+
+// rlmLogParser parses RLM ISV server debug logs, which omit the year
+type rlmLogParser struct {
+	now time.Time
+}
+
+func (p *rlmLogParser) ParseLine(line string) (models.LicenseEvent, bool) {
+	m := rlmLogEventRe.FindStringSubmatch(line)
+	if m == nil {
+		return models.LicenseEvent{}, false
+	}
+
+	day, err := time.Parse("1/2", m[1])
+	if err != nil {
+		return models.LicenseEvent{}, false
+	}
+	layout := "15:04"
+	if strings.Count(m[2], ":") == 2 {
+		layout = "15:04:05"
+	}
+	clock, err := time.Parse(layout, m[2])
+	if err != nil {
+		return models.LicenseEvent{}, false
+	}
+
+	// Dates later than now belong to the previous year (log spans New Year)
+	year := p.now.Year()
+	date := time.Date(year, day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+	if date.After(p.now) {
+		date = date.AddDate(-1, 0, 0)
+	}
+
+	return withReasonCode(models.LicenseEvent{
+		Date:        date,
+		Time:        clock,
+		EventType:   m[4],
+		FeatureName: m[5],
+		Username:    splitUserHost(m[6]),
+		Reason:      strings.TrimSpace(m[7]),
+	}), true
+}
+
+// withReasonCode classifies the reason of denials
+func withReasonCode(event models.LicenseEvent) models.LicenseEvent {
+	if event.EventType == EventDenied {
+		event.ReasonCode = ClassifyDenial(event.Reason)
+	}
+	return event
+}
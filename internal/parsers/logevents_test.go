@@ -0,0 +1,140 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+
+	"licet/internal/models"
+)
+
+func TestFlexLogParser(t *testing.T) {
+	now := time.Date(2024, 6, 15, 10, 0, 0, 0, time.Local)
+	parser, err := NewLogEventParser("flexlm", now)
+	if err != nil {
+		t.Fatalf("NewLogEventParser failed: %v", err)
+	}
+
+	lines := []string{
+		` 9:00:01 (lmgrd) TIMESTAMP 6/14/2024`,
+		`14:23:11 (adskflex) OUT: "85536ACD_2023_0F" jdoe@ws01`,
+		`14:25:40 (adskflex) DENIED: "85536ACD_2023_0F" asmith@ws02  (Licensed number of users already reached. (-4,342))`,
+		`14:26:00 (adskflex) UNSUPPORTED: "NOSUCH" bob@ws03  (No such feature exists. (-5,414))`,
+		`14:30:00 (adskflex) IN: "85536ACD_2023_0F" jdoe@ws01`,
+		`14:30:01 (adskflex) Lost connection to client`,
+	}
+
+	var types []string
+	for i, line := range lines {
+		event, ok := parser.ParseLine(line)
+		if !ok {
+			continue
+		}
+		types = append(types, event.EventType)
+
+		if event.Date.Format("2006-01-02") != "2024-06-14" {
+			t.Errorf("line %d: expected date from TIMESTAMP line, got %s", i, event.Date.Format("2006-01-02"))
+		}
+		if i == 1 {
+			if event.FeatureName != "85536ACD_2023_0F" || event.Username != "jdoe" || event.Time.Format("15:04:05") != "14:23:11" {
+				t.Errorf("unexpected OUT event: %+v", event)
+			}
+		}
+		if i == 2 && (event.Reason != "Licensed number of users already reached. (-4,342)" || event.ReasonCode != models.DenialNoSeats) {
+			t.Errorf("unexpected denial reason: %q (%s)", event.Reason, event.ReasonCode)
+		}
+		if i == 3 && event.ReasonCode != models.DenialUnsupportedFeature {
+			t.Errorf("unexpected reason code for unsupported feature: %s", event.ReasonCode)
+		}
+		if event.EventType != EventDenied && event.ReasonCode != "" {
+			t.Errorf("line %d: expected no reason code for %s events", i, event.EventType)
+		}
+	}
+
+	want := []string{EventOut, EventDenied, EventDenied, EventIn}
+	if len(types) != len(want) {
+		t.Fatalf("expected %d events, got %d (%v)", len(want), len(types), types)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Errorf("event %d: got type %s, want %s", i, types[i], want[i])
+		}
+	}
+}
+
+func TestRLMLogParser(t *testing.T) {
+	now := time.Date(2024, 1, 2, 10, 0, 0, 0, time.Local)
+	parser, err := NewLogEventParser("rlm", now)
+	if err != nil {
+		t.Fatalf("NewLogEventParser failed: %v", err)
+	}
+
+	event, ok := parser.ParseLine(`01/02 09:15 (foundry) OUT: nuke_i v2024.0 by jdoe@ws01 (handle: 41)`)
+	if !ok {
+		t.Fatal("expected OUT event")
+	}
+	if event.FeatureName != "nuke_i" || event.Username != "jdoe" || event.Date.Year() != 2024 {
+		t.Errorf("unexpected event: %+v", event)
+	}
+
+	// A date after "now" belongs to the previous year
+	event, ok = parser.ParseLine(`12/31 23:59 (foundry) DENIED: (1) nuke_i v2024.0 to asmith@ws02 - All licenses in use`)
+	if !ok {
+		t.Fatal("expected DENIED event")
+	}
+	if event.EventType != EventDenied || event.Date.Year() != 2023 || event.Reason != "All licenses in use" || event.ReasonCode != models.DenialNoSeats {
+		t.Errorf("unexpected event: %+v", event)
+	}
+}
+
+func TestRLMReportLogParser(t *testing.T) {
+	now := time.Date(2024, 1, 2, 10, 0, 0, 0, time.Local)
+	parser, err := NewLogEventParser("rlm_reportlog", now)
+	if err != nil {
+		t.Fatalf("NewLogEventParser failed: %v", err)
+	}
+
+	lines := []string{
+		`RLM Report Log Format 2, version 15.2 BL 1`,
+		`START licsrv 12/31/2023 08:00`,
+		`OUT nuke_i 2024.0 1 jdoe ws01 "" 1 1 0 41 41 1234 "my project" "" "" 12/31 09:15:04.20`,
+		`OUT nuke_r 2024.0 asmith ws02 "" 1 42 42 01/01 10:00:00.00`,
+		`IN 1 nuke_i 2024.0 jdoe ws01 "" 1 0 0 41 12/31 11:30:00.00`,
+		`DENY nuke_i 2024.0 bwong ws03 "" 1 -22 0 01/01 10:05`,
+		`DENY nuke_x 1.0 bwong ws03 "" 1 -99 0 01/01 10:06`,
+		`AUTH nuke_i 2024.0 ...`,
+	}
+	var events []models.LicenseEvent
+	for _, line := range lines {
+		if event, ok := parser.ParseLine(line); ok {
+			events = append(events, event)
+		}
+	}
+	if len(events) != 5 {
+		t.Fatalf("Expected 5 events, got %+v", events)
+	}
+
+	if e := events[0]; e.EventType != EventOut || e.FeatureName != "nuke_i" || e.Username != "jdoe" ||
+		e.Date.Format("2006-01-02") != "2023-12-31" || e.Time.Format("15:04:05") != "09:15:04" {
+		t.Errorf("unexpected std OUT event: %+v", e)
+	}
+	// Days after New Year belong to the year after START
+	if e := events[1]; e.EventType != EventOut || e.Username != "asmith" || e.Date.Format("2006-01-02") != "2024-01-01" {
+		t.Errorf("unexpected small OUT event: %+v", e)
+	}
+	if e := events[2]; e.EventType != EventIn || e.FeatureName != "nuke_i" || e.Username != "jdoe" {
+		t.Errorf("unexpected IN event: %+v", e)
+	}
+	if e := events[3]; e.EventType != EventDenied || e.Username != "bwong" || e.ReasonCode != models.DenialNoSeats ||
+		e.Time.Format("15:04") != "10:05" {
+		t.Errorf("unexpected DENY event: %+v", e)
+	}
+	if e := events[4]; e.Reason != "RLM status -99" || e.ReasonCode != models.DenialOther {
+		t.Errorf("unexpected DENY event with an unknown status: %+v", e)
+	}
+}
+
+func TestNewLogEventParser_Unsupported(t *testing.T) {
+	if _, err := NewLogEventParser("spm", time.Now()); err == nil {
+		t.Error("expected error for unsupported server type")
+	}
+}
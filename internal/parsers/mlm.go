@@ -0,0 +1,119 @@
+package parsers
+
+import (
+	"regexp"
+	"strings"
+
+	"licet/internal/models"
+)
+
+// ProfileMLM is the profile of FlexLM servers running MathWorks' MLM vendor
+// daemon
+const ProfileMLM = "mlm"
+
+// MathWorks product families, named after the base product their products
+// require
+const (
+	MLMFamilyMATLAB         = "MATLAB"
+	MLMFamilySimulink       = "Simulink"
+	MLMFamilyParallelServer = "MATLAB Parallel Server"
+)
+
+// MLMProduct is the MathWorks product an MLM feature licenses
+type MLMProduct struct {
+	Name   string
+	Family string
+}
+
+// mlmProducts maps MLM feature names, lowercased, to their products. Many
+// features keep the name of a product that was renamed since.
+var mlmProducts = map[string]MLMProduct{
+	"matlab":                     {"MATLAB", MLMFamilyMATLAB},
+	"aerospace_toolbox":          {"Aerospace Toolbox", MLMFamilyMATLAB},
+	"antenna_toolbox":            {"Antenna Toolbox", MLMFamilyMATLAB},
+	"bioinformatics_toolbox":     {"Bioinformatics Toolbox", MLMFamilyMATLAB},
+	"communication_toolbox":      {"Communications Toolbox", MLMFamilyMATLAB},
+	"compiler":                   {"MATLAB Compiler", MLMFamilyMATLAB},
+	"control_toolbox":            {"Control System Toolbox", MLMFamilyMATLAB},
+	"curve_fitting_toolbox":      {"Curve Fitting Toolbox", MLMFamilyMATLAB},
+	"data_acq_toolbox":           {"Data Acquisition Toolbox", MLMFamilyMATLAB},
+	"database_toolbox":           {"Database Toolbox", MLMFamilyMATLAB},
+	"distrib_computing_toolbox":  {"Parallel Computing Toolbox", MLMFamilyMATLAB},
+	"econometrics_toolbox":       {"Econometrics Toolbox", MLMFamilyMATLAB},
+	"excel_link":                 {"Spreadsheet Link", MLMFamilyMATLAB},
+	"financial_toolbox":          {"Financial Toolbox", MLMFamilyMATLAB},
+	"fixed_point_toolbox":        {"Fixed-Point Designer", MLMFamilyMATLAB},
+	"fuzzy_toolbox":              {"Fuzzy Logic Toolbox", MLMFamilyMATLAB},
+	"gpu_coder":                  {"GPU Coder", MLMFamilyMATLAB},
+	"identification_toolbox":     {"System Identification Toolbox", MLMFamilyMATLAB},
+	"image_acquisition_toolbox":  {"Image Acquisition Toolbox", MLMFamilyMATLAB},
+	"image_toolbox":              {"Image Processing Toolbox", MLMFamilyMATLAB},
+	"instr_control_toolbox":      {"Instrument Control Toolbox", MLMFamilyMATLAB},
+	"map_toolbox":                {"Mapping Toolbox", MLMFamilyMATLAB},
+	"matlab_coder":               {"MATLAB Coder", MLMFamilyMATLAB},
+	"matlab_report_gen":          {"MATLAB Report Generator", MLMFamilyMATLAB},
+	"mpc_toolbox":                {"Model Predictive Control Toolbox", MLMFamilyMATLAB},
+	"neural_network_toolbox":     {"Deep Learning Toolbox", MLMFamilyMATLAB},
+	"optimization_toolbox":       {"Optimization Toolbox", MLMFamilyMATLAB},
+	"pde_toolbox":                {"Partial Differential Equation Toolbox", MLMFamilyMATLAB},
+	"robust_toolbox":             {"Robust Control Toolbox", MLMFamilyMATLAB},
+	"signal_blocks":              {"DSP System Toolbox", MLMFamilyMATLAB},
+	"signal_toolbox":             {"Signal Processing Toolbox", MLMFamilyMATLAB},
+	"statistics_toolbox":         {"Statistics and Machine Learning Toolbox", MLMFamilyMATLAB},
+	"symbolic_toolbox":           {"Symbolic Math Toolbox", MLMFamilyMATLAB},
+	"text_analytics_toolbox":     {"Text Analytics Toolbox", MLMFamilyMATLAB},
+	"video_and_image_blockset":   {"Computer Vision Toolbox", MLMFamilyMATLAB},
+	"wavelet_toolbox":            {"Wavelet Toolbox", MLMFamilyMATLAB},
+	"simulink":                   {"Simulink", MLMFamilySimulink},
+	"aerospace_blockset":         {"Aerospace Blockset", MLMFamilySimulink},
+	"power_system_blocks":        {"Simscape Electrical", MLMFamilySimulink},
+	"real-time_win_target":       {"Simulink Desktop Real-Time", MLMFamilySimulink},
+	"real-time_workshop":         {"Simulink Coder", MLMFamilySimulink},
+	"rtw_embedded_coder":         {"Embedded Coder", MLMFamilySimulink},
+	"simmechanics":               {"Simscape Multibody", MLMFamilySimulink},
+	"simscape":                   {"Simscape", MLMFamilySimulink},
+	"simulink_control_design":    {"Simulink Control Design", MLMFamilySimulink},
+	"simulink_design_optim":      {"Simulink Design Optimization", MLMFamilySimulink},
+	"simulink_report_gen":        {"Simulink Report Generator", MLMFamilySimulink},
+	"simulink_test":              {"Simulink Test", MLMFamilySimulink},
+	"sl_verification_validation": {"Simulink Check", MLMFamilySimulink},
+	"stateflow":                  {"Stateflow", MLMFamilySimulink},
+	"matlab_distrib_comp_engine": {"MATLAB Parallel Server", MLMFamilyParallelServer},
+}
+
+// mlmSimulinkRe matches the feature names of Simulink products missing from
+// mlmProducts
+var mlmSimulinkRe = regexp.MustCompile(`(?i)simulink|blockset|simscape|^sl_`)
+
+// LookupMLMProduct returns the product an MLM feature licenses. Features
+// missing from the table are named after the feature, with spaces for its
+// underscores, in the MATLAB family unless their name is Simulink's.
+func LookupMLMProduct(feature string) MLMProduct {
+	if product, ok := mlmProducts[strings.ToLower(feature)]; ok {
+		return product
+	}
+	family := MLMFamilyMATLAB
+	if mlmSimulinkRe.MatchString(feature) {
+		family = MLMFamilySimulink
+	}
+	return MLMProduct{Name: strings.ReplaceAll(feature, "_", " "), Family: family}
+}
+
+// mlmProfile adjusts the results of MLM, which reports uncapped features,
+// such as those of campus-wide licenses, as "Total of 0 licenses issued"
+// while seats of them are in use
+type mlmProfile struct{}
+
+// apply counts features without issued seats as uncounted rather than as
+// seats in use beyond the issued count
+func (mlmProfile) apply(result *models.ServerQueryResult) {
+	for i := range result.Features {
+		feature := &result.Features[i]
+		if feature.TotalLicenses > 0 {
+			continue
+		}
+		feature.TotalLicenses = 9999 // Uncounted
+		feature.OverdraftLicenses = 0
+		feature.LicenseModel = models.LicenseModelUncounted
+	}
+}
@@ -0,0 +1,96 @@
+package parsers
+
+import (
+	"strings"
+	"testing"
+
+	"licet/internal/models"
+)
+
+const mlmOutput = `lmstat - Copyright (c) 1989-2023 Flexera.
+License server status: 27000@mlm.example.com
+    mlm.example.com: license server UP v11.18.1
+
+Feature usage info:
+
+Users of MATLAB:  (Total of 50 licenses issued;  Total of 2 licenses in use)
+
+    jdoe ws01 ws01 (v45) (mlm/27000 101), start Mon 6/3 9:15
+    asmith ws02 ws02 (v45) (mlm/27000 102), start Mon 6/3 9:20
+
+Users of Real-Time_Workshop:  (Total of 5 licenses issued;  Total of 1 license in use)
+
+    jdoe ws01 ws01 (v45) (mlm/27000 103), start Mon 6/3 9:30
+
+Users of MATLAB_Distrib_Comp_Engine:  (Total of 0 licenses issued;  Total of 3 licenses in use)
+
+    worker1 node01 node01 (v45) (mlm/27000 201), start Mon 6/3 8:00
+    worker2 node02 node02 (v45) (mlm/27000 202), start Mon 6/3 8:00
+    worker3 node03 node03 (v45) (mlm/27000 203), start Mon 6/3 8:00
+
+License files:
+MATLAB 45 50 01-jan-0000 MLM
+Real-Time_Workshop 45 5 01-jan-0000 MLM
+`
+
+func TestFlexLMParser_MLMProfile(t *testing.T) {
+	parse := func(parser *FlexLMParser) map[string]models.Feature {
+		result := models.ServerQueryResult{Status: models.ServerStatus{Hostname: "27000@mlm.example.com"}}
+		parser.parseOutput(strings.NewReader(mlmOutput), &result)
+		features := make(map[string]models.Feature)
+		for _, f := range result.Features {
+			features[f.Name] = f
+		}
+		return features
+	}
+
+	// Without the profile, the uncapped engine looks like overdraft
+	if engine := parse(&FlexLMParser{})["MATLAB_Distrib_Comp_Engine"]; engine.TotalLicenses != 0 || engine.OverdraftLicenses != 3 {
+		t.Errorf("Expected 3 seats beyond the issued count without the profile, got %+v", engine)
+	}
+
+	parser, err := NewParserFactory(nil).GetParserForProfile("flexlm", "", ProfileMLM)
+	if err != nil {
+		t.Fatalf("GetParserForProfile failed: %v", err)
+	}
+	features := parse(parser.(*FlexLMParser))
+	if len(features) != 3 {
+		t.Fatalf("Expected 3 features, got %+v", features)
+	}
+	if engine := features["MATLAB_Distrib_Comp_Engine"]; engine.LicenseModel != models.LicenseModelUncounted || engine.UsedLicenses != 3 || engine.OverdraftLicenses != 0 {
+		t.Errorf("Expected the engine to be uncounted with 3 seats in use, got %+v", engine)
+	}
+	if matlab := features["MATLAB"]; matlab.TotalLicenses != 50 || matlab.UsedLicenses != 2 || matlab.LicenseModel != models.LicenseModelFloating {
+		t.Errorf("Expected counted features to be unchanged, got %+v", matlab)
+	}
+	// Hyphenated names keep their license file entry
+	if rtw := features["Real-Time_Workshop"]; rtw.TotalLicenses != 5 || rtw.Version != "45" || rtw.VendorDaemon != "MLM" {
+		t.Errorf("Expected Real-Time_Workshop from the license file, got %+v", rtw)
+	}
+
+	if _, err := NewParserFactory(nil).GetParserForProfile("rlm", "", ProfileMLM); err == nil {
+		t.Error("Expected an error for the mlm profile on an rlm server")
+	}
+	if _, err := NewParserFactory(nil).GetParserForProfile("flexlm", "", "sentinel"); err == nil {
+		t.Error("Expected an error for an unknown profile")
+	}
+}
+
+func TestLookupMLMProduct(t *testing.T) {
+	tests := []struct {
+		feature string
+		want    MLMProduct
+	}{
+		{"Signal_Toolbox", MLMProduct{"Signal Processing Toolbox", MLMFamilyMATLAB}},
+		{"distrib_computing_toolbox", MLMProduct{"Parallel Computing Toolbox", MLMFamilyMATLAB}},
+		{"Real-Time_Workshop", MLMProduct{"Simulink Coder", MLMFamilySimulink}},
+		{"MATLAB_Distrib_Comp_Engine", MLMProduct{"MATLAB Parallel Server", MLMFamilyParallelServer}},
+		{"Vehicle_Network_Toolbox", MLMProduct{"Vehicle Network Toolbox", MLMFamilyMATLAB}},
+		{"Powertrain_Blockset", MLMProduct{"Powertrain Blockset", MLMFamilySimulink}},
+	}
+	for _, tt := range tests {
+		if got := LookupMLMProduct(tt.feature); got != tt.want {
+			t.Errorf("LookupMLMProduct(%q) = %+v, want %+v", tt.feature, got, tt.want)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package parsers
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"licet/internal/clock"
 	"licet/internal/models"
 )
 
@@ -12,6 +14,15 @@ type Parser interface {
 	Query(ctx context.Context, hostname string) (models.ServerQueryResult, error)
 }
 
+// Query modes select how a license server is queried
+const (
+	QueryModeBinary = "binary" // Run the vendor's status tool, e.g. lmutil
+	QueryModeNative = "native" // Speak to the server directly, without vendor binaries
+)
+
+// nativeConnectTimeout bounds connecting to a license server in native mode
+const nativeConnectTimeout = 10 * time.Second
+
 // ParserFactory creates appropriate parser for license server type
 type ParserFactory struct {
 	lmutilPath     string
@@ -21,6 +32,7 @@ type ParserFactory struct {
 	rvlstatusPath  string
 	tlmServerPath  string
 	pixarQueryPath string
+	clock          clock.Clock
 }
 
 func NewParserFactory(binPaths map[string]string) *ParserFactory {
@@ -35,12 +47,22 @@ func NewParserFactory(binPaths map[string]string) *ParserFactory {
 	}
 }
 
+// SetClock sets the clock that parsers time query results and year-less
+// checkout times with
+func (f *ParserFactory) SetClock(c clock.Clock) {
+	f.clock = c
+}
+
 func (f *ParserFactory) GetParser(serverType string) (Parser, error) {
 	switch serverType {
 	case "flexlm":
-		return NewFlexLMParser(f.lmutilPath), nil
+		p := NewFlexLMParser(f.lmutilPath)
+		p.clock = f.clock
+		return p, nil
 	case "rlm":
-		return NewRLMParser(f.rlmstatPath), nil
+		p := NewRLMParser(f.rlmstatPath)
+		p.clock = f.clock
+		return p, nil
 	// TODO: Implement other parsers
 	// case "spm":
 	//     return NewSPMParser(f.spmstatPath), nil
@@ -56,3 +78,42 @@ func (f *ParserFactory) GetParser(serverType string) (Parser, error) {
 		return nil, fmt.Errorf("unsupported server type: %s", serverType)
 	}
 }
+
+// GetParserForMode returns the parser for a server type and query mode. An
+// empty mode is binary.
+func (f *ParserFactory) GetParserForMode(serverType, mode string) (Parser, error) {
+	switch mode {
+	case "", QueryModeBinary:
+		return f.GetParser(serverType)
+	case QueryModeNative:
+		if serverType == "flexlm" {
+			c := NewFlexLMNativeClient(nativeConnectTimeout)
+			c.clock = f.clock
+			return c, nil
+		}
+		return nil, fmt.Errorf("query mode native is not supported for server type %s", serverType)
+	default:
+		return nil, fmt.Errorf("unknown query mode %q", mode)
+	}
+}
+
+// GetParserForProfile returns the parser for a server type and query mode,
+// adjusted to the vendor daemon of profile. An empty profile is none.
+func (f *ParserFactory) GetParserForProfile(serverType, mode, profile string) (Parser, error) {
+	parser, err := f.GetParserForMode(serverType, mode)
+	if err != nil || profile == "" {
+		return parser, err
+	}
+	switch profile {
+	case ProfileMLM:
+		// Native mode only reports whether the server is up
+		if p, ok := parser.(*FlexLMParser); ok {
+			p.profile = mlmProfile{}
+		} else if serverType != "flexlm" {
+			return nil, fmt.Errorf("profile mlm is not supported for server type %s", serverType)
+		}
+		return parser, nil
+	default:
+		return nil, fmt.Errorf("unknown profile %q", profile)
+	}
+}
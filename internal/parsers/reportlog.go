@@ -0,0 +1,132 @@
+package parsers
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"licet/internal/models"
+)
+
+// FlexNet report log patterns compiled once at package level
+var (
+	// 2024/06/01 14:23:11 OUT    MATLAB  jdoe    ws01  handle=41 count=1
+	// 2024/06/01 15:02:40 IN     MATLAB  jdoe    ws01  handle=41
+	// 2024/06/01 15:03:00 DENIED MATLAB  asmith  ws02  reason="Licensed number of users already reached. (-4,342)"
+	reportLogRecordRe = regexp.MustCompile(`^\s*(\d{1,4}[/-]\d{1,2}[/-]\d{1,4})\s+(\d{1,2}:\d{2}:\d{2})\s+(OUT|IN|DENIED|UNSUPPORTED)\s+"?([^"\s]+)"?\s+(\S+)\s+(\S+)(.*)$`)
+	reportLogAttrRe   = regexp.MustCompile(`(\w+)=(?:"([^"]*)"|(\S+))`)
+)
+
+// reportLogDateLayouts are the date formats written by report log converters
+var reportLogDateLayouts = []string{"2006/01/02", "2006-01-02", "1/2/2006"}
+
+// ReportLogRecord is a checkout, check-in or denial from a FlexNet report
+// log. Unlike polled usage, report logs hold every checkout with the second
+// it happened, however short.
+type ReportLogRecord struct {
+	At      time.Time
+	Type    string
+	Feature string
+	User    string
+	Host    string
+	// Handle identifies a checkout until it is checked in; empty when the
+	// converter does not write it
+	Handle string
+	// Count is the number of licenses checked out or in
+	Count  int
+	Reason string
+}
+
+// ParseReportLog parses the text form of a FlexNet report log, one record per
+// line, in the local time zone. The binary REPORTLOG written by vendor daemons
+// is encrypted and must first be converted to text with FlexNet's report
+// tools (repgen). Lines that are not records, such as report headers, are
+// skipped. Records are returned in time order.
+func ParseReportLog(r io.Reader) ([]ReportLogRecord, error) {
+	var records []ReportLogRecord
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if record, ok := parseReportLogLine(scanner.Text()); ok {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].At.Before(records[j].At)
+	})
+	return records, nil
+}
+
+// parseReportLogLine parses a single report log line. It returns false for
+// lines that carry no record.
+func parseReportLogLine(line string) (ReportLogRecord, bool) {
+	m := reportLogRecordRe.FindStringSubmatch(line)
+	if m == nil {
+		return ReportLogRecord{}, false
+	}
+
+	var date time.Time
+	var err error
+	for _, layout := range reportLogDateLayouts {
+		if date, err = time.ParseInLocation(layout, m[1], time.Local); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return ReportLogRecord{}, false
+	}
+	clock, err := time.Parse("15:04:05", m[2])
+	if err != nil {
+		return ReportLogRecord{}, false
+	}
+
+	record := ReportLogRecord{
+		At:      time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, time.Local),
+		Type:    m[3],
+		Feature: m[4],
+		User:    m[5],
+		Host:    m[6],
+		Count:   1,
+	}
+	for _, attr := range reportLogAttrRe.FindAllStringSubmatch(m[7], -1) {
+		value := attr[2] + attr[3]
+		switch strings.ToLower(attr[1]) {
+		case "handle":
+			record.Handle = value
+		case "count":
+			if n, err := strconv.Atoi(value); err == nil && n > 0 {
+				record.Count = n
+			}
+		case "reason":
+			record.Reason = strings.TrimSpace(value)
+		}
+	}
+
+	if record.Type == "UNSUPPORTED" {
+		// Requests for features the daemon does not serve are denials too
+		record.Type = EventDenied
+		if record.Reason == "" {
+			record.Reason = "Unsupported feature"
+		}
+	}
+	return record, true
+}
+
+// Event returns the license event of the record
+func (r ReportLogRecord) Event() models.LicenseEvent {
+	return withReasonCode(models.LicenseEvent{
+		Date:        time.Date(r.At.Year(), r.At.Month(), r.At.Day(), 0, 0, 0, 0, time.Local),
+		Time:        time.Date(0, 1, 1, r.At.Hour(), r.At.Minute(), r.At.Second(), 0, time.UTC),
+		EventType:   r.Type,
+		FeatureName: r.Feature,
+		Username:    r.User,
+		Reason:      r.Reason,
+	})
+}
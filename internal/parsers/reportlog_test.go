@@ -0,0 +1,45 @@
+package parsers
+
+import (
+	"strings"
+	"testing"
+
+	"licet/internal/models"
+)
+
+func TestParseReportLog(t *testing.T) {
+	log := `FlexNet report log - adskflex
+Date       Time     Event  Feature  User    Host
+2024/06/01 15:02:40 IN     MATLAB   jdoe    ws01  handle=41
+2024/06/01 14:23:11 OUT    MATLAB   jdoe    ws01  handle=41 count=2
+2024-06-01 15:03:00 DENIED MATLAB   asmith  ws02  reason="Licensed number of users already reached. (-4,342)"
+6/1/2024   15:04:00 UNSUPPORTED "NOSUCH" bob ws03
+2024/13/01 15:05:00 OUT    MATLAB   jdoe    ws01
+`
+	records, err := ParseReportLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("ParseReportLog failed: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("Expected 4 records, got %d: %+v", len(records), records)
+	}
+
+	out := records[0]
+	if out.Type != EventOut || out.Feature != "MATLAB" || out.User != "jdoe" || out.Host != "ws01" ||
+		out.Handle != "41" || out.Count != 2 || out.At.Format("2006-01-02 15:04:05") != "2024-06-01 14:23:11" {
+		t.Errorf("Unexpected first record, expected the OUT sorted first: %+v", out)
+	}
+	if records[1].Type != EventIn || records[1].Count != 1 {
+		t.Errorf("Unexpected IN record: %+v", records[1])
+	}
+
+	denial := records[2].Event()
+	if denial.EventType != EventDenied || denial.Reason != "Licensed number of users already reached. (-4,342)" ||
+		denial.ReasonCode != models.DenialNoSeats || denial.Time.Format("15:04:05") != "15:03:00" ||
+		denial.Date.Format("2006-01-02") != "2024-06-01" {
+		t.Errorf("Unexpected denial event: %+v", denial)
+	}
+	if unsupported := records[3]; unsupported.Type != EventDenied || unsupported.Feature != "NOSUCH" || unsupported.Reason != "Unsupported feature" {
+		t.Errorf("Expected UNSUPPORTED as a denial: %+v", unsupported)
+	}
+}
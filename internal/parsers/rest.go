@@ -0,0 +1,418 @@
+package parsers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/models"
+)
+
+// restMaxBody bounds the responses read from a REST API
+const restMaxBody = 32 << 20
+
+// RESTSource is the REST API a server of type rest is queried through, such
+// as that of Sentinel EMS or OLicense
+type RESTSource struct {
+	URL           string            // Returns the features
+	UsersURL      string            // Returns the checkouts, if not URL
+	Token         string            // Sent with each request when set
+	AuthHeader    string            // Header of the token, Authorization by default
+	AuthScheme    string            // Prefix of the token in Authorization, Bearer by default
+	FeaturesPath  string            // Dot path to the array of features; the response itself when empty
+	UsersPath     string            // Dot path to the array of checkouts
+	FeatureFields map[string]string // Field -> dot path within a feature, over RESTFeatureFields
+	UserFields    map[string]string // Field -> dot path within a checkout, over RESTUserFields
+}
+
+// RESTFeatureFields are the fields a feature of a REST response is mapped
+// to, with their default paths. users is the path of the feature's checkouts,
+// if they are nested in it.
+var RESTFeatureFields = map[string]string{
+	"name":       "name",
+	"version":    "version",
+	"vendor":     "vendor",
+	"total":      "total",
+	"used":       "used",
+	"reserved":   "reserved",
+	"expiration": "expiration",
+	"users":      "",
+}
+
+// RESTUserFields are the fields a checkout of a REST response is mapped to,
+// with their default paths. feature defaults to the feature a nested checkout
+// is in.
+var RESTUserFields = map[string]string{
+	"feature":        "feature",
+	"username":       "username",
+	"host":           "host",
+	"version":        "version",
+	"checked_out_at": "checked_out_at",
+	"tokens":         "tokens",
+}
+
+// RESTParser queries license managers that expose a REST API instead of a
+// status tool. Features and checkouts are read from JSON with a configurable
+// mapping of fields; a feature whose used count is not mapped counts its
+// checkouts. A server that cannot be reached or answers with a server error
+// is down; rejected credentials and unexpected responses fail the query.
+type RESTParser struct {
+	clocked
+	source        RESTSource
+	client        *http.Client
+	featureFields map[string]string
+	userFields    map[string]string
+}
+
+// GetRESTParser returns the parser of a server of type rest
+func (f *ParserFactory) GetRESTParser(source RESTSource) (Parser, error) {
+	p, err := NewRESTParser(source)
+	if err != nil {
+		return nil, err
+	}
+	p.clock = f.clock
+	return p, nil
+}
+
+func NewRESTParser(source RESTSource) (*RESTParser, error) {
+	if u, err := url.Parse(source.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("server type rest needs the http(s) URL of its API, not %q", source.URL)
+	}
+	featureFields, err := restFields(RESTFeatureFields, source.FeatureFields, "feature_fields")
+	if err != nil {
+		return nil, err
+	}
+	userFields, err := restFields(RESTUserFields, source.UserFields, "user_fields")
+	if err != nil {
+		return nil, err
+	}
+	return &RESTParser{
+		source:        source,
+		client:        &http.Client{},
+		featureFields: featureFields,
+		userFields:    userFields,
+	}, nil
+}
+
+// restFields returns the default paths of fields with those of overrides
+func restFields(defaults, overrides map[string]string, name string) (map[string]string, error) {
+	fields := make(map[string]string, len(defaults))
+	for field, path := range defaults {
+		fields[field] = path
+	}
+	for field, path := range overrides {
+		if _, ok := defaults[field]; !ok {
+			return nil, fmt.Errorf("%s: unknown field %q", name, field)
+		}
+		fields[field] = path
+	}
+	return fields, nil
+}
+
+func (p *RESTParser) Query(ctx context.Context, hostname string) (models.ServerQueryResult, error) {
+	result := NewServerQueryResult(hostname, p.now())
+
+	doc, down, err := p.get(ctx, p.source.URL)
+	if err != nil {
+		return result, err
+	}
+	if down != "" {
+		result.Status.Message = down
+		return result, nil
+	}
+
+	usersDoc := doc
+	if p.source.UsersURL != "" {
+		usersDoc, down, err = p.get(ctx, p.source.UsersURL)
+		if err != nil {
+			return result, err
+		}
+		if down != "" {
+			result.Status.Message = down
+			return result, nil
+		}
+	}
+
+	result.Status.Service = "up"
+	if u, err := url.Parse(p.source.URL); err == nil {
+		result.Status.Master = u.Hostname()
+	}
+	if err := p.parseResponse(doc, usersDoc, &result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// get requests a document of the API. A server that cannot be reached or
+// fails is reported as down rather than as an error.
+func (p *RESTParser) get(ctx context.Context, target string) (interface{}, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.source.Token != "" {
+		header := p.source.AuthHeader
+		if header == "" || strings.EqualFold(header, "Authorization") {
+			scheme := p.source.AuthScheme
+			if scheme == "" {
+				scheme = "Bearer"
+			}
+			req.Header.Set("Authorization", scheme+" "+p.source.Token)
+		} else {
+			req.Header.Set(header, p.source.Token)
+		}
+	}
+
+	log.Debugf("Requesting REST API: %s", target)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, "", ctx.Err()
+		}
+		return nil, fmt.Sprintf("Cannot connect to %s: %v", req.URL.Host, err), nil
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, restMaxBody))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", target, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, "", fmt.Errorf("%s rejected the credentials: %s", target, resp.Status)
+	case resp.StatusCode >= 500:
+		return nil, fmt.Sprintf("%s answered %s", req.URL.Host, resp.Status), nil
+	case resp.StatusCode >= 300:
+		return nil, "", fmt.Errorf("%s answered %s", target, resp.Status)
+	}
+
+	if log.IsLevelEnabled(log.DebugLevel) {
+		log.Debugf("REST API response:\n%s", string(body))
+	}
+
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, "", fmt.Errorf("invalid JSON from %s: %w", target, err)
+	}
+	return doc, "", nil
+}
+
+// parseResponse fills result from the documents of the features and of the
+// checkouts
+func (p *RESTParser) parseResponse(doc, usersDoc interface{}, result *models.ServerQueryResult) error {
+	items, err := restArray(doc, p.source.FeaturesPath)
+	if err != nil {
+		return fmt.Errorf("features: %w", err)
+	}
+
+	now := p.now()
+	featureMap := make(map[string]*models.Feature)
+	var keys []string
+	usedMapped := make(map[string]bool)
+	for _, item := range items {
+		name := restString(item, p.featureFields["name"])
+		if name == "" {
+			result.Status.Warnings = append(result.Status.Warnings, "Feature without a name skipped")
+			continue
+		}
+		version := restString(item, p.featureFields["version"])
+
+		expDate, quality := PermanentExpirationDate, models.ParseQualityOK
+		switch v := restValue(item, p.featureFields["expiration"]).(type) {
+		case json.Number:
+			if n, err := v.Int64(); err == nil && n > 0 {
+				expDate = restUnix(n)
+			}
+		case string:
+			if t, err := time.Parse(time.RFC3339, v); err == nil {
+				expDate = t
+			} else if v != "" {
+				expDate, quality = parseExpiration(result, name, v)
+			}
+		}
+
+		// Entitlements of the same pool are added up
+		key := fmt.Sprintf("%s|%s|%s", name, version, expDate.Format("2006-01-02"))
+		feature, ok := featureMap[key]
+		if !ok {
+			feature = &models.Feature{
+				ServerHostname: result.Status.Hostname,
+				Name:           name,
+				Version:        version,
+				VendorDaemon:   restString(item, p.featureFields["vendor"]),
+				LicenseModel:   models.LicenseModelFloating,
+				ExpirationDate: expDate,
+				ParseQuality:   quality,
+				LastUpdated:    now,
+			}
+			featureMap[key] = feature
+			keys = append(keys, key)
+		}
+		feature.TotalLicenses += restInt(item, p.featureFields["total"])
+		feature.ReservedLicenses += restInt(item, p.featureFields["reserved"])
+		if path := p.featureFields["used"]; path != "" && restValue(item, path) != nil {
+			feature.UsedLicenses += restInt(item, path)
+			usedMapped[key] = true
+		}
+
+		if path := p.featureFields["users"]; path != "" {
+			nested, err := restArray(item, path)
+			if err != nil {
+				return fmt.Errorf("users of %s: %w", name, err)
+			}
+			for _, u := range nested {
+				p.addUser(u, name, version, now, result)
+			}
+		}
+	}
+
+	if p.source.UsersURL != "" || p.source.UsersPath != "" {
+		users, err := restArray(usersDoc, p.source.UsersPath)
+		if err != nil {
+			return fmt.Errorf("users: %w", err)
+		}
+		for _, u := range users {
+			p.addUser(u, "", "", now, result)
+		}
+	}
+
+	// Features without a used count count the seats of their checkouts
+	seats := make(map[string]int)
+	for _, u := range result.Users {
+		seats[u.FeatureName] += u.Seats()
+	}
+	for _, key := range keys {
+		feature := featureMap[key]
+		if !usedMapped[key] {
+			feature.UsedLicenses = seats[feature.Name]
+			seats[feature.Name] = 0 // Counted once, for the first pool
+		}
+		result.Features = append(result.Features, *feature)
+	}
+	return nil
+}
+
+// addUser adds a checkout to result; nested checkouts default to the feature
+// they are in
+func (p *RESTParser) addUser(item interface{}, feature, version string, now time.Time, result *models.ServerQueryResult) {
+	if name := restString(item, p.userFields["feature"]); name != "" {
+		feature = name
+	}
+	username := restString(item, p.userFields["username"])
+	if feature == "" || username == "" {
+		result.Status.Warnings = append(result.Status.Warnings, "Checkout without a feature or username skipped")
+		return
+	}
+
+	checkedOut := now
+	switch v := restValue(item, p.userFields["checked_out_at"]).(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil && n > 0 {
+			checkedOut = restUnix(n)
+		}
+	case string:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			checkedOut = t
+		} else if t, err := ParseCheckoutTime(v, now); err == nil {
+			checkedOut = t
+		}
+	}
+
+	if v := restString(item, p.userFields["version"]); v != "" {
+		version = v
+	}
+	result.Users = append(result.Users, models.LicenseUser{
+		ServerHostname: result.Status.Hostname,
+		FeatureName:    feature,
+		Username:       username,
+		Host:           restString(item, p.userFields["host"]),
+		CheckedOutAt:   checkedOut,
+		Version:        version,
+		LicenseVersion: version,
+		Tokens:         restInt(item, p.userFields["tokens"]),
+	})
+}
+
+// restValue returns the value at a dot path, e.g. "data.items" or
+// "pools.0.size"; an empty path is the value itself
+func restValue(v interface{}, path string) interface{} {
+	if path == "" {
+		return v
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			v = node[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil
+			}
+			v = node[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+// restArray returns the array at a dot path
+func restArray(v interface{}, path string) ([]interface{}, error) {
+	switch node := restValue(v, path).(type) {
+	case []interface{}:
+		return node, nil
+	case nil:
+		return nil, fmt.Errorf("no array at %q", path)
+	default:
+		return nil, fmt.Errorf("%q is not an array", path)
+	}
+}
+
+// restString returns the value at a path as a string, empty if it is missing
+// or not a scalar
+func restString(v interface{}, path string) string {
+	if path == "" {
+		return ""
+	}
+	switch s := restValue(v, path).(type) {
+	case string:
+		return strings.TrimSpace(s)
+	case json.Number:
+		return s.String()
+	case bool:
+		return strconv.FormatBool(s)
+	default:
+		return ""
+	}
+}
+
+// restInt returns the number at a path, which APIs may also send as a string
+func restInt(v interface{}, path string) int {
+	s := restString(v, path)
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return int(f)
+	}
+	return 0
+}
+
+// restUnix returns the time of a Unix timestamp in seconds or milliseconds
+func restUnix(n int64) time.Time {
+	if n > 1e12 {
+		return time.UnixMilli(n)
+	}
+	return time.Unix(n, 0)
+}
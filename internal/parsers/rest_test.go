@@ -0,0 +1,155 @@
+package parsers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/models"
+)
+
+func TestRESTParser_SeparateUsers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/features":
+			w.Write([]byte(`{"data": {"features": [
+				{"featureName": "solver", "ver": "2.0", "quantity": "10", "end": "2026-12-31T00:00:00Z"},
+				{"featureName": "solver", "ver": "2.0", "quantity": 5, "end": "2026-12-31T00:00:00Z"},
+				{"featureName": "mesher", "quantity": 4, "end": "31-dec-2027"},
+				{"quantity": 1}
+			]}}`))
+		case "/sessions":
+			w.Write([]byte(`[
+				{"feature": "solver", "user": {"name": "jdoe"}, "machine": "ws01", "since": 1717406100000},
+				{"feature": "solver", "user": {"name": "asmith"}, "machine": "ws02", "since": 1717406400, "count": 2}
+			]`))
+		}
+	}))
+	defer srv.Close()
+
+	parser, err := NewRESTParser(RESTSource{
+		URL:          srv.URL + "/features",
+		UsersURL:     srv.URL + "/sessions",
+		Token:        "secret",
+		AuthHeader:   "X-API-Key",
+		FeaturesPath: "data.features",
+		FeatureFields: map[string]string{
+			"name": "featureName", "version": "ver", "total": "quantity", "used": "", "expiration": "end",
+		},
+		UserFields: map[string]string{"username": "user.name", "host": "machine", "checked_out_at": "since", "tokens": "count"},
+	})
+	if err != nil {
+		t.Fatalf("NewRESTParser failed: %v", err)
+	}
+
+	result, err := parser.Query(context.Background(), "ems.example.com")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if result.Status.Service != "up" || result.Status.Master != "127.0.0.1" || len(result.Status.Warnings) != 1 {
+		t.Errorf("Expected the server up with a skipped feature, got %+v", result.Status)
+	}
+	if len(result.Features) != 2 {
+		t.Fatalf("Expected 2 features, got %+v", result.Features)
+	}
+
+	// Entitlements of a pool are added up, and used seats counted from checkouts
+	solver := result.Features[0]
+	if solver.Name != "solver" || solver.Version != "2.0" || solver.TotalLicenses != 15 || solver.UsedLicenses != 3 ||
+		solver.ExpirationDate.Format("2006-01-02") != "2026-12-31" {
+		t.Errorf("Unexpected solver %+v", solver)
+	}
+	if mesher := result.Features[1]; mesher.UsedLicenses != 0 || mesher.ExpirationDate.Format("2006-01-02") != "2027-12-31" {
+		t.Errorf("Unexpected mesher %+v", mesher)
+	}
+	if len(result.Users) != 2 || result.Users[0].Username != "jdoe" || result.Users[0].Host != "ws01" ||
+		!result.Users[0].CheckedOutAt.Equal(time.UnixMilli(1717406100000)) || !result.Users[1].CheckedOutAt.Equal(time.Unix(1717406400, 0)) {
+		t.Errorf("Unexpected users %+v", result.Users)
+	}
+}
+
+func TestRESTParser_NestedUsers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token abc" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`[
+			{"name": "designer", "version": "5", "total": 3, "used": 1, "vendor": "acme",
+			 "licenses": [{"username": "jdoe", "host": "ws01", "checked_out_at": "2025-06-01T09:30:00Z"}]}
+		]`))
+	}))
+	defer srv.Close()
+
+	parser, err := NewRESTParser(RESTSource{
+		URL:           srv.URL,
+		Token:         "abc",
+		AuthScheme:    "Token",
+		FeatureFields: map[string]string{"users": "licenses"},
+	})
+	if err != nil {
+		t.Fatalf("NewRESTParser failed: %v", err)
+	}
+	result, err := parser.Query(context.Background(), "olicense.example.com")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Features) != 1 || result.Features[0].UsedLicenses != 1 || result.Features[0].VendorDaemon != "acme" ||
+		!result.Features[0].ExpirationDate.Equal(PermanentExpirationDate) || result.Features[0].LicenseModel != models.LicenseModelFloating {
+		t.Errorf("Unexpected features %+v", result.Features)
+	}
+	if len(result.Users) != 1 || result.Users[0].FeatureName != "designer" || result.Users[0].LicenseVersion != "5" {
+		t.Errorf("Expected the nested checkout of designer, got %+v", result.Users)
+	}
+}
+
+func TestRESTParser_Errors(t *testing.T) {
+	if _, err := NewRESTParser(RESTSource{URL: "ems.example.com"}); err == nil {
+		t.Error("Expected an error without an http(s) URL")
+	}
+	if _, err := NewRESTParser(RESTSource{URL: "http://ems", FeatureFields: map[string]string{"seats": "qty"}}); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+
+	status := http.StatusOK
+	body := `{"items": []}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	query := func(path string) (models.ServerQueryResult, error) {
+		parser, err := NewRESTParser(RESTSource{URL: srv.URL, FeaturesPath: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parser.Query(context.Background(), "ems")
+	}
+
+	if result, err := query("items"); err != nil || result.Status.Service != "up" {
+		t.Errorf("Expected an empty server up, got %+v (%v)", result.Status, err)
+	}
+	if _, err := query("data"); err == nil || !strings.Contains(err.Error(), "no array") {
+		t.Errorf("Expected a missing array error, got %v", err)
+	}
+	status = http.StatusForbidden
+	if _, err := query("items"); err == nil || !strings.Contains(err.Error(), "credentials") {
+		t.Errorf("Expected rejected credentials, got %v", err)
+	}
+	status = http.StatusServiceUnavailable
+	if result, err := query("items"); err != nil || result.Status.Service != "down" {
+		t.Errorf("Expected the server down on 503, got %+v (%v)", result.Status, err)
+	}
+
+	// A server that cannot be reached is down
+	srv.Close()
+	if result, err := query("items"); err != nil || result.Status.Service != "down" || !strings.Contains(result.Status.Message, "Cannot connect") {
+		t.Errorf("Expected the server down, got %+v (%v)", result.Status, err)
+	}
+}
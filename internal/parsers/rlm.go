@@ -21,12 +21,13 @@ var (
 	rlmVersionRe          = regexp.MustCompile(`rlm software version v([\d\.]+)`)
 	rlmISVStatusRe        = regexp.MustCompile(`^(\w+)\s+\d+\s+(\w+)\s+\d+`)
 	rlmFeatureHeaderRe    = regexp.MustCompile(`(?i)^([\w\+]+)\s+(\w[\d\.]+).*pool.*$`)
-	rlmFeatureLicenseRe   = regexp.MustCompile(`^count:\s+(\d+)[,\s]+.*inuse:\s+(\d+)[,\s]+.*exp:\s+(\d+-\w+-\d{4}|\w+)`)
-	rlmUncountedLicenseRe = regexp.MustCompile(`^UNCOUNTED[,\s]+.*inuse:\s+(\d+)(?:[,\s]+.*exp:\s+(\d+-\w+-\d{4}|\w+))?`)
+	rlmFeatureLicenseRe   = regexp.MustCompile(`^count:\s+(\d+)[,\s]+.*inuse:\s+(\d+)[,\s]+.*exp:\s+(\d+-[\p{L}.]+-\d{4}|\w+)`)
+	rlmUncountedLicenseRe = regexp.MustCompile(`^UNCOUNTED[,\s]+.*inuse:\s+(\d+)(?:[,\s]+.*exp:\s+(\d+-[\p{L}.]+-\d{4}|\w+))?`)
 	rlmUserRe             = regexp.MustCompile(`^([\w\+]+)\s+(v[\d\.]+):\s+([\w\.\-]+@[\w\-]+)\s+\d+\/\d+\s+at\s+(\d+\/\d+\s+\d+:\d+)`)
 )
 
 type RLMParser struct {
+	clocked
 	rlmstatPath string
 }
 
@@ -38,7 +39,7 @@ func NewRLMParser(rlmstatPath string) *RLMParser {
 }
 
 func (p *RLMParser) Query(ctx context.Context, hostname string) (models.ServerQueryResult, error) {
-	result := NewServerQueryResult(hostname)
+	result := NewServerQueryResult(hostname, p.now())
 
 	// Execute rlmstat command
 	output, _ := ExecuteCommand(ctx, "RLM", p.rlmstatPath, "rlmstat", "-a", "-c", hostname)
@@ -106,7 +107,7 @@ func (p *RLMParser) parseOutput(reader io.Reader, result *models.ServerQueryResu
 		if matches := rlmFeatureLicenseRe.FindStringSubmatch(line); matches != nil && currentFeature != "" {
 			total, _ := strconv.Atoi(matches[1])
 			used, _ := strconv.Atoi(matches[2])
-			expDate := ParseExpirationDate(matches[3])
+			expDate, quality := parseExpiration(result, currentFeature, matches[3])
 
 			featureMap[currentFeature] = &models.Feature{
 				ServerHostname: result.Status.Hostname,
@@ -115,15 +116,17 @@ func (p *RLMParser) parseOutput(reader io.Reader, result *models.ServerQueryResu
 				VendorDaemon:   currentVendor,
 				TotalLicenses:  total,
 				UsedLicenses:   used,
+				LicenseModel:   models.LicenseModelFloating,
 				ExpirationDate: expDate,
-				LastUpdated:    time.Now(),
+				ParseQuality:   quality,
+				LastUpdated:    p.now(),
 			}
 			continue
 		}
 
 		if matches := rlmUncountedLicenseRe.FindStringSubmatch(line); matches != nil && currentFeature != "" {
 			used, _ := strconv.Atoi(matches[1])
-			expDate := ParseExpirationDate(matches[2])
+			expDate, quality := parseExpiration(result, currentFeature, matches[2])
 
 			featureMap[currentFeature] = &models.Feature{
 				ServerHostname: result.Status.Hostname,
@@ -132,8 +135,10 @@ func (p *RLMParser) parseOutput(reader io.Reader, result *models.ServerQueryResu
 				VendorDaemon:   currentVendor,
 				TotalLicenses:  999, // UNCOUNTED licenses
 				UsedLicenses:   used,
+				LicenseModel:   models.LicenseModelUncounted,
 				ExpirationDate: expDate,
-				LastUpdated:    time.Now(),
+				ParseQuality:   quality,
+				LastUpdated:    p.now(),
 			}
 			continue
 		}
@@ -156,8 +161,8 @@ func (p *RLMParser) parseOutput(reader io.Reader, result *models.ServerQueryResu
 				continue
 			}
 
-			// Adjust to current year since RLM doesn't include year
-			checkedOut = AdjustCheckoutTimeToCurrentYear(checkedOut)
+			// Adjust to the current year since RLM doesn't include year
+			checkedOut = AdjustCheckoutTimeToCurrentYear(checkedOut, p.now())
 
 			result.Users = append(result.Users, models.LicenseUser{
 				ServerHostname: result.Status.Hostname,
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"licet/internal/clock"
 	"licet/internal/models"
 )
 
@@ -180,6 +181,7 @@ test v1.0: joe@library 1/0 at 07/06 13:27 (handle: 41)
 `
 
 	parser := NewRLMParser("")
+	parser.clock = clock.NewFixed(time.Date(2019, 7, 6, 14, 0, 0, 0, time.UTC))
 	result := models.ServerQueryResult{
 		Status: models.ServerStatus{
 			Hostname: "server.example.com",
@@ -206,15 +208,15 @@ test v1.0: joe@library 1/0 at 07/06 13:27 (handle: 41)
 		t.Errorf("Expected feature 'test', got '%s'", user.FeatureName)
 	}
 
-	// Check time parsing - should be July 6, 13:27 of current year
-	now := time.Now()
+	// Check time parsing - should be July 6, 13:27 of the parser's year
+	expectedYear := 2019
 	expectedMonth := time.July
 	expectedDay := 6
 	expectedHour := 13
 	expectedMinute := 27
 
-	if user.CheckedOutAt.Year() != now.Year() {
-		t.Errorf("Expected year %d, got %d", now.Year(), user.CheckedOutAt.Year())
+	if user.CheckedOutAt.Year() != expectedYear {
+		t.Errorf("Expected year %d, got %d", expectedYear, user.CheckedOutAt.Year())
 	}
 	if user.CheckedOutAt.Month() != expectedMonth {
 		t.Errorf("Expected month %s, got %s", expectedMonth, user.CheckedOutAt.Month())
@@ -0,0 +1,39 @@
+package parsers
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"time"
+)
+
+// selftestOutput is lmstat output of a server with two features in use
+//
+//go:embed selftest/lmstat.txt
+var selftestOutput []byte
+
+// SelfTest parses the embedded lmstat output and checks what was found, so a
+// deployment can verify the parsers without a license server
+func SelfTest() error {
+	p := &FlexLMParser{}
+	result := NewServerQueryResult("27000@license-server.example.com", time.Now())
+	p.parseOutput(bytes.NewReader(selftestOutput), &result)
+
+	if result.Status.Service != "up" {
+		return fmt.Errorf("expected the server to be up, got %q", result.Status.Service)
+	}
+	if len(result.Features) != 2 {
+		return fmt.Errorf("expected 2 features, got %d", len(result.Features))
+	}
+	if len(result.Users) != 3 {
+		return fmt.Errorf("expected 3 checkouts, got %d", len(result.Users))
+	}
+	used := 0
+	for _, f := range result.Features {
+		used += f.UsedLicenses
+	}
+	if used != 3 {
+		return fmt.Errorf("expected 3 licenses in use, got %d", used)
+	}
+	return nil
+}
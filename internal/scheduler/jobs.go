@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// JobRefresh is the type of jobs polling a server on request
+const JobRefresh = "refresh"
+
+// ErrUnknownServer is returned when refreshing a server that is not configured
+var ErrUnknownServer = errors.New("server not configured")
+
+// SetJobQueue runs server refreshes and capacity report regeneration as jobs
+// of a queue instead of in goroutines of their own
+func (s *Scheduler) SetJobQueue(queue *services.JobQueue) {
+	s.jobs = queue
+	// A failed poll is retried by the next collection anyway
+	queue.Register(JobRefresh, 1, s.runRefresh)
+	queue.Register(services.JobCapacityReports, 1, func(ctx context.Context, job models.Job) (interface{}, error) {
+		log.Debug("Refreshing capacity reports")
+		return nil, s.enhancedAnalytics.RefreshCapacityReports(ctx)
+	})
+}
+
+// Refresh polls a server now instead of waiting for its next collection,
+// returning the job to follow its progress. A refresh requested while one
+// for the same server is queued or running returns that job.
+func (s *Scheduler) Refresh(ctx context.Context, hostname string) (models.Job, error) {
+	if _, err := s.server(hostname); err != nil {
+		return models.Job{}, err
+	}
+	return s.jobs.Enqueue(ctx, JobRefresh, hostname, nil)
+}
+
+// server returns a configured server
+func (s *Scheduler) server(hostname string) (models.LicenseServer, error) {
+	servers, err := s.collectorService.Servers()
+	if err != nil {
+		return models.LicenseServer{}, err
+	}
+	for _, server := range servers {
+		if server.Hostname == hostname {
+			return server, nil
+		}
+	}
+	return models.LicenseServer{}, ErrUnknownServer
+}
+
+// runRefresh collects a server for a refresh job and evaluates the alert
+// rules on the result
+func (s *Scheduler) runRefresh(ctx context.Context, job models.Job) (interface{}, error) {
+	server, err := s.server(job.Server)
+	if errors.Is(err, ErrUnknownServer) {
+		return nil, services.PermanentJobError(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Refreshing %s on request", server.Hostname)
+	if err := s.collectorService.CollectNow(server); err != nil {
+		return nil, err
+	}
+	if err := s.collectorService.EvaluateAlertRules(); err != nil {
+		log.Errorf("Alert rule evaluation failed: %v", err)
+	}
+	return nil, nil
+}
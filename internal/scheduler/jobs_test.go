@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"licet/internal/config"
+	"licet/internal/database"
+	"licet/internal/models"
+	"licet/internal/services"
+)
+
+// newTestQueue creates a started job queue on a migrated SQLite database
+func newTestQueue(t *testing.T) *services.JobQueue {
+	t.Helper()
+
+	db, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "licet_test.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.RunMigrations(db, "sqlite"); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	return services.NewJobQueue(db, config.JobsConfig{Workers: 1, MaxAttempts: 3, OutputDir: t.TempDir()})
+}
+
+func TestRefresh(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{Servers: []config.LicenseServer{{Hostname: "27000@a", Type: "unsupported"}}}
+	collector := services.NewCollectorService(nil, cfg, services.NewQueryService(cfg, nil), nil)
+	defer collector.Stop()
+	s := New(cfg, collector, nil, nil, nil, nil, nil, nil)
+	queue := newTestQueue(t)
+	s.SetJobQueue(queue)
+
+	if _, err := s.Refresh(ctx, "27000@unknown"); !errors.Is(err, ErrUnknownServer) {
+		t.Errorf("Expected an unknown server error, got %v", err)
+	}
+
+	job, err := s.Refresh(ctx, "27000@a")
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if job.ID == "" || job.Type != JobRefresh || job.Server != "27000@a" || job.Status != models.JobQueued {
+		t.Errorf("Unexpected job %+v", job)
+	}
+
+	// A refresh requested while one is queued returns that job
+	again, err := s.Refresh(ctx, "27000@a")
+	if err != nil || again.ID != job.ID {
+		t.Errorf("Expected the queued job, got %+v (%v)", again, err)
+	}
+
+	if err := queue.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer queue.Stop()
+	deadline := time.Now().Add(5 * time.Second)
+	for job.FinishedAt == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		if job, err = queue.Get(ctx, job.ID); err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+	}
+	// The server type has no parser, so the poll fails, and a refresh is
+	// not retried
+	if job.Status != models.JobFailed || job.Error == "" || job.StartedAt == nil || job.Attempts != 1 {
+		t.Errorf("Expected a failed job after one attempt, got %+v", job)
+	}
+
+	// Finished jobs no longer block a new refresh
+	next, err := s.Refresh(ctx, "27000@a")
+	if err != nil || next.ID == job.ID {
+		t.Errorf("Expected a new job, got %+v (%v)", next, err)
+	}
+}
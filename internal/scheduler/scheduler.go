@@ -1,6 +1,7 @@
 package scheduler
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/robfig/cron/v3"
@@ -10,18 +11,31 @@ import (
 )
 
 type Scheduler struct {
-	cron             *cron.Cron
-	collectorService *services.CollectorService
-	alertService     *services.AlertService
-	cfg              *config.Config
+	cron              *cron.Cron
+	collectorService  *services.CollectorService
+	alertService      *services.AlertService
+	enhancedAnalytics *services.EnhancedAnalyticsService
+	flags             *services.FlagService
+	reports           *services.ReportService
+	entitlements      *services.EntitlementService
+	dbStats           *services.DBStatsService
+	cfg               *config.Config
+	jobs              *services.JobQueue          // Set by SetJobQueue
+	chargeback        *services.ChargebackService // Set by SetChargeback
+	logTailer         *services.LogTailer         // Set by SetLogTailer
 }
 
-func New(cfg *config.Config, collector *services.CollectorService, alert *services.AlertService) *Scheduler {
+func New(cfg *config.Config, collector *services.CollectorService, alert *services.AlertService, enhanced *services.EnhancedAnalyticsService, flags *services.FlagService, reports *services.ReportService, entitlements *services.EntitlementService, dbStats *services.DBStatsService) *Scheduler {
 	return &Scheduler{
-		cron:             cron.New(),
-		collectorService: collector,
-		alertService:     alert,
-		cfg:              cfg,
+		cron:              cron.New(),
+		collectorService:  collector,
+		alertService:      alert,
+		enhancedAnalytics: enhanced,
+		flags:             flags,
+		reports:           reports,
+		entitlements:      entitlements,
+		dbStats:           dbStats,
+		cfg:               cfg,
 	}
 }
 
@@ -35,6 +49,23 @@ func (s *Scheduler) Start() {
 		if err := s.collectorService.CollectAll(); err != nil {
 			log.Errorf("Collection job failed: %v", err)
 		}
+		if err := s.collectorService.EvaluateAlertRules(); err != nil {
+			log.Errorf("Alert rule evaluation failed: %v", err)
+		}
+		s.refreshReports()
+	})
+
+	// Poll servers with their own interval or cron schedule separately
+	s.scheduleServers()
+
+	// Regenerate stored reports hourly, in case collection is not producing data
+	s.cron.AddFunc("30 * * * *", s.refreshReports)
+
+	// Pick up feature flags toggled by other instances sharing the database
+	s.cron.AddFunc("* * * * *", func() {
+		if err := s.flags.Load(context.Background()); err != nil {
+			log.Errorf("Feature flag refresh failed: %v", err)
+		}
 	})
 
 	// Check for expiring licenses daily at 2 AM
@@ -45,21 +76,215 @@ func (s *Scheduler) Start() {
 		}
 	})
 
-	// Send alerts every 5 minutes
-	if s.cfg.Alerts.Enabled {
-		s.cron.AddFunc("*/5 * * * *", func() {
-			log.Debug("Running alert sending job")
-			if err := s.alertService.SendAlerts(); err != nil {
-				log.Errorf("Alert sending failed: %v", err)
+	// Summarize upcoming expirations in one email daily or weekly
+	if digest := s.cfg.Alerts.ExpiryDigest; digest.Enabled {
+		if _, err := s.cron.AddFunc(services.ExpiryDigestSchedule(digest), func() {
+			log.Debug("Running expiry digest")
+			if err := s.alertService.SendExpiryDigest(context.Background()); err != nil {
+				log.Errorf("Expiry digest failed: %v", err)
+			}
+		}); err != nil {
+			log.Errorf("Failed to schedule the expiry digest: %v", err)
+		}
+	}
+
+	// Merge duplicate feature rows daily at 3 AM
+	s.cron.AddFunc("0 3 * * *", func() {
+		log.Debug("Running feature deduplication")
+		if err := s.collectorService.DeduplicateFeatures(); err != nil {
+			log.Errorf("Feature deduplication failed: %v", err)
+		}
+	})
+
+	// Roll up raw usage of past days daily at 1:15 AM
+	if s.cfg.Rollup.Enabled {
+		s.cron.AddFunc("15 1 * * *", func() {
+			log.Debug("Running usage rollup")
+			if err := s.collectorService.RollupUsage(); err != nil {
+				log.Errorf("Usage rollup failed: %v", err)
 			}
 		})
 	}
 
+	// Report new and removed feature users daily at 7 AM
+	if s.cfg.UserDigest.Enabled {
+		s.cron.AddFunc("0 7 * * *", func() {
+			log.Debug("Running user digest")
+			if err := s.collectorService.SendUserDigest(); err != nil {
+				log.Errorf("User digest failed: %v", err)
+			}
+		})
+	}
+
+	// Email scheduled capacity reports
+	if s.cfg.Reports.Enabled {
+		for _, schedule := range s.reports.Schedules() {
+			name := schedule.Name
+			if _, err := s.cron.AddFunc(schedule.Cron, func() {
+				log.Debugf("Running scheduled report %s", name)
+				if err := s.reports.Send(context.Background(), name); err != nil {
+					log.Errorf("Report %s failed: %v", name, err)
+				}
+			}); err != nil {
+				log.Errorf("Failed to schedule report %s: %v", name, err)
+			}
+		}
+	}
+
+	// Pull entitlements from vendor portals
+	if s.cfg.Entitlements.Enabled {
+		if _, err := s.cron.AddFunc(s.cfg.Entitlements.Schedule, func() {
+			log.Debug("Running entitlement sync")
+			s.entitlements.Sync(context.Background())
+		}); err != nil {
+			log.Errorf("Failed to schedule entitlement sync: %v", err)
+		}
+	}
+
+	// Attribute checkout hours to cost centers, and once at startup so the
+	// report of the current month is available right away
+	if s.chargeback != nil && s.cfg.Chargeback.Enabled {
+		if _, err := s.cron.AddFunc(s.chargeback.Schedule(), s.aggregateChargeback); err != nil {
+			log.Errorf("Failed to schedule chargeback aggregation: %v", err)
+		}
+		go s.aggregateChargeback()
+	}
+
+	// Remove data past its retention nightly
+	if policy := s.cfg.RetentionPolicy(); s.dbStats != nil && len(policy) > 0 {
+		if _, err := s.cron.AddFunc(s.cfg.Retention.Schedule, func() {
+			s.applyRetention(policy)
+		}); err != nil {
+			log.Errorf("Failed to schedule data retention: %v", err)
+		}
+	}
+
+	// Send alerts every 5 minutes. Always scheduled so alerts enabled from
+	// the settings page are sent without a restart
+	s.cron.AddFunc("*/5 * * * *", func() {
+		if !s.cfg.Alerts.Enabled {
+			return
+		}
+		log.Debug("Running alert sending job")
+		if err := s.alertService.SendAlerts(); err != nil {
+			log.Errorf("Alert sending failed: %v", err)
+		}
+	})
+
+	// Mirror Alertmanager silences so matching alerts are not sent
+	if s.cfg.Alertmanager.URL != "" {
+		interval := s.cfg.Alertmanager.SyncInterval
+		if interval <= 0 {
+			interval = 60
+		}
+		s.cron.AddFunc(fmt.Sprintf("@every %ds", interval), s.syncSilences)
+		go s.syncSilences()
+	}
+
+	// Read new lines of local vendor daemon logs into license events
+	if s.logTailer != nil && s.cfg.LogTail.Enabled {
+		s.cron.AddFunc(fmt.Sprintf("@every %ds", s.cfg.LogTail.Interval), s.tailLogs)
+		go s.tailLogs()
+	}
+
 	s.cron.Start()
 	log.Info("Scheduler started")
 }
 
+// scheduleServers adds a collection job for each server polled on its own
+// schedule instead of the global collection interval
+func (s *Scheduler) scheduleServers() {
+	servers, err := s.collectorService.Servers()
+	if err != nil {
+		log.Errorf("Failed to get servers: %v", err)
+		return
+	}
+	for _, server := range servers {
+		spec := services.PollSchedule(server)
+		if spec == "" {
+			continue
+		}
+		_, err := s.cron.AddFunc(spec, func() {
+			log.Debugf("Running scheduled collection of %s", server.Hostname)
+			if err := s.collectorService.CollectOnSchedule(server); err != nil {
+				log.Errorf("Collection of %s failed: %v", server.Hostname, err)
+				return
+			}
+			if err := s.collectorService.EvaluateAlertRules(); err != nil {
+				log.Errorf("Alert rule evaluation failed: %v", err)
+			}
+		})
+		if err != nil {
+			log.Errorf("Failed to schedule collection of %s: %v", server.Hostname, err)
+			continue
+		}
+		log.Infof("Polling %s on its own schedule (%s)", server.Hostname, spec)
+	}
+}
+
+// refreshReports queues the regeneration of the stored capacity reports
+// unless one is already queued or running
+func (s *Scheduler) refreshReports() {
+	if _, err := s.jobs.Enqueue(context.Background(), services.JobCapacityReports, "", nil); err != nil {
+		log.Errorf("Failed to queue capacity report refresh: %v", err)
+	}
+}
+
+// applyRetention runs the retention policy on schedule
+func (s *Scheduler) applyRetention(policy map[string]int) {
+	run, err := s.dbStats.ApplyRetention(context.Background(), policy, s.cfg.Retention.DryRun, "schedule")
+	if err != nil {
+		log.Errorf("Data retention failed: %v", err)
+		return
+	}
+	if run.DryRun {
+		log.Infof("Data retention dry run: %d rows past their retention", run.RowsDeleted)
+	} else {
+		log.Infof("Data retention removed %d rows", run.RowsDeleted)
+	}
+}
+
+// syncSilences fetches the current Alertmanager silences
+func (s *Scheduler) syncSilences() {
+	count, err := s.alertService.SyncSilences(context.Background())
+	if err != nil {
+		log.Errorf("Alertmanager silence sync failed: %v", err)
+		return
+	}
+	log.Debugf("Synced %d Alertmanager silences", count)
+}
+
 func (s *Scheduler) Stop() {
 	log.Info("Stopping scheduler")
 	s.cron.Stop()
 }
+
+// SetChargeback aggregates chargeback hours on chargeback.schedule
+func (s *Scheduler) SetChargeback(chargeback *services.ChargebackService) {
+	s.chargeback = chargeback
+}
+
+// SetLogTailer reads the log_tail files every log_tail.interval seconds
+func (s *Scheduler) SetLogTailer(tailer *services.LogTailer) {
+	s.logTailer = tailer
+}
+
+// tailLogs records the events of the lines added to the tailed logs
+func (s *Scheduler) tailLogs() {
+	count, err := s.logTailer.Poll(context.Background())
+	if err != nil {
+		log.Errorf("Log tailing failed: %v", err)
+	}
+	if count > 0 {
+		log.Debugf("Recorded %d license events from tailed logs", count)
+	}
+}
+
+// aggregateChargeback attributes the checkout hours of the recent months to
+// cost centers
+func (s *Scheduler) aggregateChargeback() {
+	log.Debug("Running chargeback aggregation")
+	if err := s.chargeback.Aggregate(context.Background()); err != nil {
+		log.Errorf("Chargeback aggregation failed: %v", err)
+	}
+}
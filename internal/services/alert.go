@@ -1,6 +1,7 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"time"
@@ -9,59 +10,132 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
+	"licet/internal/clock"
 	"licet/internal/config"
 	"licet/internal/models"
 )
 
 type AlertService struct {
-	db  *sqlx.DB
-	cfg *config.Config
+	db     *sqlx.DB
+	cfg    *config.Config
+	clock  clock.Clock
+	bus    *EventBus
+	cipher *FieldCipher
 }
 
 func NewAlertService(db *sqlx.DB, cfg *config.Config) *AlertService {
 	return &AlertService{
-		db:  db,
-		cfg: cfg,
+		db:    db,
+		cfg:   cfg,
+		clock: clock.System,
 	}
 }
 
+// SetClock sets the clock that resend intervals and notification budgets
+// are measured with
+func (s *AlertService) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// SetCipher stores the usernames and email addresses of waitlist
+// subscriptions encrypted
+func (s *AlertService) SetCipher(c *FieldCipher) {
+	s.cipher = c
+}
+
+// SetEventBus publishes the alerts that are not silenced on the bus under
+// AlertTopic once stored
+func (s *AlertService) SetEventBus(bus *EventBus) {
+	s.bus = bus
+}
+
+// CreateAlert stores an alert and correlates it into an incident for its
+// server. Alerts matching an active silence are stored but never sent.
 func (s *AlertService) CreateAlert(ctx context.Context, alert *models.Alert) error {
+	now := s.clock.Now()
+
+	if id, silenced, err := s.activeSilence(ctx, alert, now); err != nil {
+		log.Errorf("Failed to check silences: %v", err)
+	} else if silenced {
+		log.Infof("Alert %s for %s suppressed by silence %s", alert.AlertType, alert.ServerHostname, id)
+		alert.SilenceID = &id
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	incidentID, err := s.correlate(ctx, tx, alert, now)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO alerts (server_hostname, feature_name, alert_type, message, severity, created_at)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO alerts (server_hostname, feature_name, alert_type, message, severity, created_at, incident_id, silence_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := s.db.ExecContext(ctx, query,
+	_, err = tx.ExecContext(ctx, tx.Rebind(query),
 		alert.ServerHostname,
 		alert.FeatureName,
 		alert.AlertType,
 		alert.Message,
 		alert.Severity,
-		time.Now(),
+		now,
+		incidentID,
+		alert.SilenceID,
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
+	err = tx.GetContext(ctx, &alert.ID, tx.Rebind(`
+		SELECT id FROM alerts WHERE server_hostname = ? AND alert_type = ? AND created_at = ?
+		ORDER BY id DESC LIMIT 1
+	`), alert.ServerHostname, alert.AlertType, now)
+	if err != nil {
+		return err
+	}
+
+	alert.IncidentID = incidentID
+	alert.CreatedAt = now
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if alert.SilenceID != nil {
+		return nil
+	}
+	event := s.withLinks([]models.Alert{*alert})[0]
+	s.bus.Publish(AlertTopic, event)
+	if webhooks := NewWebhookService(s.db, s.cfg); webhooks.Enabled() {
+		go webhooks.Notify(context.Background(), WebhookEventAlert, event)
+	}
+	return nil
 }
 
+// GetUnsentAlerts returns alerts waiting to be sent, leaving out silenced alerts
 func (s *AlertService) GetUnsentAlerts(ctx context.Context) ([]models.Alert, error) {
 	var alerts []models.Alert
-	query := `SELECT * FROM alerts WHERE sent = 0 ORDER BY created_at ASC`
+	query := `SELECT * FROM alerts WHERE sent = FALSE AND silence_id IS NULL ORDER BY created_at ASC`
 	err := s.db.SelectContext(ctx, &alerts, query)
-	return alerts, err
+	return s.withLinks(alerts), err
 }
 
 // GetActiveAlerts returns all alerts from the last 30 days, both sent and unsent
 func (s *AlertService) GetActiveAlerts(ctx context.Context) ([]models.Alert, error) {
 	var alerts []models.Alert
-	thirtyDaysAgo := time.Now().AddDate(0, 0, -30)
+	thirtyDaysAgo := s.clock.Now().AddDate(0, 0, -30)
 	query := `SELECT * FROM alerts WHERE created_at > ? ORDER BY created_at DESC`
-	err := s.db.SelectContext(ctx, &alerts, query, thirtyDaysAgo)
-	return alerts, err
+	err := s.db.SelectContext(ctx, &alerts, s.db.Rebind(query), thirtyDaysAgo)
+	return s.withLinks(alerts), err
 }
 
 func (s *AlertService) MarkAlertSent(ctx context.Context, alertID int64) error {
-	query := `UPDATE alerts SET sent = 1, sent_at = ? WHERE id = ?`
-	_, err := s.db.ExecContext(ctx, query, time.Now(), alertID)
+	query := `UPDATE alerts SET sent = TRUE, sent_at = ? WHERE id = ?`
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(query), s.clock.Now(), alertID)
 	return err
 }
 
@@ -82,46 +156,66 @@ func (s *AlertService) SendAlerts() error {
 		return nil
 	}
 
-	// Group alerts by type for better email formatting
+	// Alerts belonging to an incident are sent as one notification per incident
+	var notifications []notification
+	incidents := make(map[int64]int)
+	var grouped []notification
 	for _, alert := range alerts {
-		if err := s.sendAlert(&alert); err != nil {
-			log.Errorf("Failed to send alert %d: %v", alert.ID, err)
+		if alert.IncidentID == nil {
+			notifications = append(notifications, notification{alerts: []models.Alert{alert}})
+			continue
+		}
+		id := *alert.IncidentID
+		if i, ok := incidents[id]; ok {
+			grouped[i].alerts = append(grouped[i].alerts, alert)
 			continue
 		}
+		incidents[id] = len(grouped)
+		grouped = append(grouped, notification{incidentID: id, alerts: []models.Alert{alert}})
+	}
+	notifications = append(notifications, grouped...)
+
+	budget := s.notificationBudget(ctx)
+	if s.isFlood(len(notifications), budget) {
+		if budget == 0 {
+			log.Warnf("Notification rate limit reached, deferring %d notifications", len(notifications))
+			return nil
+		}
+		return s.sendFloodSummary(ctx, notifications)
+	}
 
-		if err := s.MarkAlertSent(ctx, alert.ID); err != nil {
-			log.Errorf("Failed to mark alert %d as sent: %v", alert.ID, err)
+	for _, n := range notifications {
+		var err error
+		if n.incidentID != 0 {
+			err = s.sendIncident(ctx, n.incidentID, n.alerts)
+		} else {
+			err = s.sendAlert(ctx, &n.alerts[0])
+		}
+		if err != nil {
+			log.Errorf("Failed to send %s: %v", n, err)
+			continue
 		}
+		s.recordNotification()
+		s.markSent(ctx, n.alerts)
 	}
 
 	return nil
 }
 
-func (s *AlertService) sendAlert(alert *models.Alert) error {
-	// Create new message
-	m := mail.NewMsg()
-
-	// Set From header
-	if err := m.From(s.cfg.Email.From); err != nil {
-		return fmt.Errorf("failed to set From header: %w", err)
-	}
-
+func (s *AlertService) sendAlert(ctx context.Context, alert *models.Alert) error {
 	// Determine recipients based on alert severity
 	recipients := s.cfg.Email.To
 	if alert.Severity == "critical" {
 		recipients = append(recipients, s.cfg.Email.Alerts...)
 	}
 
-	// Set To header
-	if err := m.To(recipients...); err != nil {
-		return fmt.Errorf("failed to set To header: %w", err)
-	}
-
-	// Set subject
 	subject := fmt.Sprintf("[%s] License Alert: %s", alert.Severity, alert.AlertType)
-	m.Subject(subject)
 
-	// Set body
+	links, err := s.AlertLinks(alert)
+	if err != nil {
+		log.Warnf("Alert %d: %v", alert.ID, err)
+	}
+
 	body := fmt.Sprintf(`
 License Alert
 
@@ -133,9 +227,9 @@ Time: %s
 
 Message:
 %s
-
+%s
 --
-Licet
+%s
 `,
 		alert.ServerHostname,
 		alert.FeatureName,
@@ -143,32 +237,141 @@ Licet
 		alert.Severity,
 		alert.CreatedAt.Format(time.RFC3339),
 		alert.Message,
+		formatAlertLinks(links),
+		s.cfg.Branding.Name(),
 	)
 
-	m.SetBodyString(mail.TypeTextPlain, body)
+	if err := s.sendAlertEmail(ctx, []models.Alert{*alert}, subject, body, recipients, nil); err != nil {
+		return err
+	}
+
+	log.Infof("Alert sent: %s - %s", alert.AlertType, alert.ServerHostname)
+	return nil
+}
+
+// SendEmail sends a plain text email using the configured SMTP server
+func (s *AlertService) SendEmail(subject, body string, recipients []string) error {
+	return s.sendEmail(subject, body, recipients, nil)
+}
+
+// sendEmail sends a plain text email with additional headers, e.g. for threading
+func (s *AlertService) sendEmail(subject, body string, recipients []string, headers map[mail.Header]string) error {
+	return s.send(emailMessage{Subject: subject, Recipients: recipients, Text: body, Headers: headers})
+}
+
+// SendHTMLEmail sends an HTML email with a plain text alternative
+func (s *AlertService) SendHTMLEmail(subject, html, text string, recipients []string) error {
+	return s.send(emailMessage{Subject: subject, Recipients: recipients, Text: text, HTML: html})
+}
+
+// SendEmailAttachment sends a plain text email with a file attached
+func (s *AlertService) SendEmailAttachment(subject, body string, recipients []string, filename string, content []byte) error {
+	return s.send(emailMessage{Subject: subject, Recipients: recipients, Text: body, Filename: filename, Attachment: content})
+}
+
+// send sends an email now, or queues it in the outbox with email.queue
+// enabled. Queued email is still checked to build before it is queued.
+func (s *AlertService) send(msg emailMessage) error {
+	m, err := buildMessage(s.cfg.Email, msg)
+	if err != nil {
+		return err
+	}
+	if s.cfg.Email.Queue.Enabled {
+		return enqueueEmail(context.Background(), s.db, s.clock.Now(), s.cfg.Email.Queue.MaxAttempts, msg)
+	}
+	return deliver(s.cfg.Email, m)
+}
+
+// sendEmailDirect sends a plain text email now even with email.queue
+// enabled, for notifications that must not wait behind a stuck outbox
+func (s *AlertService) sendEmailDirect(subject, body string, recipients []string) error {
+	m, err := buildMessage(s.cfg.Email, emailMessage{Subject: subject, Recipients: recipients, Text: body})
+	if err != nil {
+		return err
+	}
+	return deliver(s.cfg.Email, m)
+}
+
+// emailMessage is the content of an email, as stored in the outbox
+type emailMessage struct {
+	Subject    string                 `json:"subject"`
+	Recipients []string               `json:"recipients"`
+	Text       string                 `json:"text"`
+	HTML       string                 `json:"html,omitempty"`
+	Headers    map[mail.Header]string `json:"headers,omitempty"`
+	Filename   string                 `json:"filename,omitempty"`
+	Attachment []byte                 `json:"attachment,omitempty"`
+}
+
+// buildMessage creates an email from the configured sender
+func buildMessage(email config.EmailConfig, msg emailMessage) (*mail.Msg, error) {
+	m := mail.NewMsg()
+
+	if err := m.From(email.From); err != nil {
+		return nil, fmt.Errorf("failed to set From header: %w", err)
+	}
+	if err := m.To(msg.Recipients...); err != nil {
+		return nil, fmt.Errorf("failed to set To header: %w", err)
+	}
+	m.Subject(msg.Subject)
+	for header, value := range msg.Headers {
+		m.SetGenHeader(header, value)
+	}
+	m.SetBodyString(mail.TypeTextPlain, msg.Text)
+	if msg.HTML != "" {
+		m.AddAlternativeString(mail.TypeTextHTML, msg.HTML)
+	}
+	if msg.Filename != "" {
+		if err := m.AttachReader(msg.Filename, bytes.NewReader(msg.Attachment)); err != nil {
+			return nil, fmt.Errorf("failed to attach %s: %w", msg.Filename, err)
+		}
+	}
+	return m, nil
+}
 
-	// Create client
-	client, err := mail.NewClient(s.cfg.Email.SMTPHost,
-		mail.WithPort(s.cfg.Email.SMTPPort),
+// newMailClient creates a client for the SMTP server of the email settings
+func newMailClient(email config.EmailConfig) (*mail.Client, error) {
+	client, err := mail.NewClient(email.SMTPHost,
+		mail.WithPort(email.SMTPPort),
 		mail.WithSMTPAuth(mail.SMTPAuthPlain),
-		mail.WithUsername(s.cfg.Email.Username),
-		mail.WithPassword(s.cfg.Email.Password),
+		mail.WithUsername(email.Username),
+		mail.WithPassword(email.Password),
+		mail.WithTimeout(15*time.Second),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create mail client: %w", err)
+		return nil, fmt.Errorf("failed to create mail client: %w", err)
+	}
+	return client, nil
+}
+
+// TestSMTPConnection connects and authenticates to the SMTP server of the
+// email settings without sending mail
+func TestSMTPConnection(ctx context.Context, email config.EmailConfig) error {
+	client, err := newMailClient(email)
+	if err != nil {
+		return err
+	}
+	if err := client.DialWithContext(ctx); err != nil {
+		return err
+	}
+	return client.Close()
+}
+
+// deliver sends an email using the SMTP server of the email settings
+func deliver(email config.EmailConfig, m *mail.Msg) error {
+	client, err := newMailClient(email)
+	if err != nil {
+		return err
 	}
 
-	// Send the mail
 	if err := client.DialAndSend(m); err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
 	}
-
-	log.Infof("Alert sent: %s - %s", alert.AlertType, alert.ServerHostname)
 	return nil
 }
 
 func (s *AlertService) CheckThrottle(hostname, alertType string) bool {
-	cutoff := time.Now().Add(-time.Duration(s.cfg.Alerts.ResendIntervalMin) * time.Minute)
+	cutoff := s.clock.Now().Add(-time.Duration(s.cfg.Alerts.ResendIntervalMin) * time.Minute)
 
 	var count int
 	query := `
@@ -189,7 +392,7 @@ func (s *AlertService) CheckThrottle(hostname, alertType string) bool {
 
 	// Record this alert check
 	insertQuery := `INSERT INTO alert_events (datetime, type, hostname) VALUES (?, ?, ?)`
-	_, err = s.db.Exec(insertQuery, time.Now(), alertType, hostname)
+	_, err = s.db.Exec(insertQuery, s.clock.Now(), alertType, hostname)
 	if err != nil {
 		log.Errorf("Failed to record alert event: %v", err)
 	}
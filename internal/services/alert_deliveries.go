@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	mail "github.com/wneessen/go-mail"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"licet/internal/models"
+)
+
+// ErrAlertNotFound is returned when an alert does not exist
+var ErrAlertNotFound = errors.New("alert not found")
+
+// sendAlertEmail sends the email notifying of alerts and records a receipt
+// per alert and recipient, identified by the Message-ID of the email. Email
+// queued in the outbox is recorded as queued until the outbox sends it.
+func (s *AlertService) sendAlertEmail(ctx context.Context, alerts []models.Alert, subject, body string, recipients []string, headers map[mail.Header]string) error {
+	if headers == nil {
+		headers = make(map[mail.Header]string)
+	}
+	if headers[mail.HeaderMessageID] == "" {
+		headers[mail.HeaderMessageID] = s.newMessageID("alert")
+	}
+
+	err := s.sendEmail(subject, body, recipients, headers)
+	status, errMsg := models.DeliverySent, ""
+	switch {
+	case err != nil:
+		status, errMsg = models.DeliveryFailed, err.Error()
+	case s.cfg.Email.Queue.Enabled:
+		status = models.DeliveryQueued
+	}
+
+	ids := make([]int64, len(alerts))
+	for i, alert := range alerts {
+		ids[i] = alert.ID
+	}
+	recordDeliveries(ctx, s.db, s.clock.Now(), ids, models.DeliveryChannelEmail, recipients, status, headers[mail.HeaderMessageID], errMsg)
+	return err
+}
+
+// messageDomain returns the domain of the sender, for the Message-IDs of
+// notifications
+func (s *AlertService) messageDomain() string {
+	if at := strings.LastIndex(s.cfg.Email.From, "@"); at >= 0 {
+		return strings.Trim(s.cfg.Email.From[at+1:], "> ")
+	}
+	return "licet"
+}
+
+// newMessageID returns a unique Message-ID for a notification of a kind
+func (s *AlertService) newMessageID(kind string) string {
+	return fmt.Sprintf("<licet-%s-%d-%s@%s>", kind, s.clock.Now().UnixNano(), randomHex(4), s.messageDomain())
+}
+
+// recordDeliveries records a receipt for each alert and distinct target.
+// Failing to record is logged rather than failing the notification.
+func recordDeliveries(ctx context.Context, db *sqlx.DB, now time.Time, alertIDs []int64, channel string, targets []string, status, messageID, errMsg string) {
+	query := db.Rebind(`
+		INSERT INTO alert_deliveries (alert_id, channel, target, status, message_id, error, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		if seen[target] {
+			continue
+		}
+		seen[target] = true
+		for _, id := range alertIDs {
+			if _, err := db.ExecContext(ctx, query, id, channel, target, status, messageID, errMsg, now, now); err != nil {
+				log.Errorf("Failed to record the %s delivery of alert %d: %v", channel, id, err)
+			}
+		}
+	}
+}
+
+// updateEmailDeliveries records the outcome of sending a queued email on the
+// receipts of the alerts it notified of
+func updateEmailDeliveries(ctx context.Context, db *sqlx.DB, now time.Time, messageID, status, errMsg string) error {
+	if messageID == "" {
+		return nil
+	}
+	query := `UPDATE alert_deliveries SET status = ?, error = ?, updated_at = ? WHERE channel = ? AND message_id = ?`
+	_, err := db.ExecContext(ctx, db.Rebind(query), status, errMsg, now, models.DeliveryChannelEmail, messageID)
+	return err
+}
+
+// GetAlertDeliveries returns the delivery receipts of an alert, oldest first
+func (s *AlertService) GetAlertDeliveries(ctx context.Context, alertID int64) ([]models.AlertDelivery, error) {
+	var exists int64
+	err := s.db.GetContext(ctx, &exists, s.db.Rebind(`SELECT id FROM alerts WHERE id = ?`), alertID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAlertNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []models.AlertDelivery{}
+	query := `SELECT * FROM alert_deliveries WHERE alert_id = ? ORDER BY id`
+	if err := s.db.SelectContext(ctx, &deliveries, s.db.Rebind(query), alertID); err != nil {
+		return nil, fmt.Errorf("failed to get alert deliveries: %w", err)
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestAlertDeliveries_Email(t *testing.T) {
+	alerts, outbox, _ := newTestOutbox(t)
+	ctx := context.Background()
+	alerts.cfg.Email.Enabled = true
+	alerts.cfg.Email.To = []string{"ops@example.com"}
+	alerts.cfg.Email.Alerts = []string{"oncall@example.com", "ops@example.com"}
+	alerts.cfg.Alerts.Enabled = true
+
+	alert := &models.Alert{ServerHostname: "27000@a", FeatureName: "solver", AlertType: "expiry", Message: "solver expires in 7 days", Severity: "critical"}
+	if err := alerts.CreateAlert(ctx, alert); err != nil {
+		t.Fatalf("CreateAlert failed: %v", err)
+	}
+	if alert.ID == 0 {
+		t.Fatal("Expected the alert to get its ID")
+	}
+	if err := alerts.SendAlerts(); err != nil {
+		t.Fatalf("SendAlerts failed: %v", err)
+	}
+
+	deliveries, err := alerts.GetAlertDeliveries(ctx, alert.ID)
+	if err != nil {
+		t.Fatalf("GetAlertDeliveries failed: %v", err)
+	}
+	// One receipt per distinct recipient, queued in the outbox
+	if len(deliveries) != 2 || deliveries[0].Target != "ops@example.com" || deliveries[1].Target != "oncall@example.com" {
+		t.Fatalf("Unexpected deliveries: %+v", deliveries)
+	}
+	for _, d := range deliveries {
+		if d.Channel != models.DeliveryChannelEmail || d.Status != models.DeliveryQueued || !strings.HasPrefix(d.MessageID, "<licet-alert-") || !strings.HasSuffix(d.MessageID, "@example.com>") {
+			t.Errorf("Unexpected delivery: %+v", d)
+		}
+	}
+
+	outbox.send = func(emailMessage) error { return nil }
+	if n, err := outbox.Process(ctx); err != nil || n != 1 {
+		t.Fatalf("Process = %d, %v", n, err)
+	}
+	deliveries, _ = alerts.GetAlertDeliveries(ctx, alert.ID)
+	for _, d := range deliveries {
+		if d.Status != models.DeliverySent {
+			t.Errorf("Expected the delivery to be sent once the outbox sent it, got %+v", d)
+		}
+	}
+
+	if _, err := alerts.GetAlertDeliveries(ctx, 999); !errors.Is(err, ErrAlertNotFound) {
+		t.Errorf("Expected ErrAlertNotFound, got %v", err)
+	}
+}
+
+func TestAlertDeliveries_Webhook(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/down") {
+			http.Error(w, "gone", http.StatusGone)
+			return
+		}
+		w.Header().Set("X-Request-Id", "req-42")
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{Webhooks: config.WebhookConfig{
+		MaxAttempts: 1,
+		Endpoints: []config.WebhookEndpoint{
+			{Name: "chatops", URL: server.URL + "/hook"},
+			{Name: "ticketing", URL: server.URL + "/down"},
+		},
+	}}
+	alerts := NewAlertService(db, cfg)
+	alert := &models.Alert{ServerHostname: "27000@a", AlertType: "down", Message: "Server down", Severity: "critical"}
+	if err := alerts.CreateAlert(ctx, alert); err != nil {
+		t.Fatalf("CreateAlert failed: %v", err)
+	}
+
+	// Alerts are posted to webhooks in the background
+	var deliveries []models.AlertDelivery
+	for deadline := time.Now().Add(5 * time.Second); len(deliveries) < 2 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		deliveries, _ = alerts.GetAlertDeliveries(ctx, alert.ID)
+	}
+	if len(deliveries) != 2 {
+		t.Fatalf("Expected 2 webhook deliveries, got %+v", deliveries)
+	}
+	byTarget := map[string]models.AlertDelivery{}
+	for _, d := range deliveries {
+		byTarget[d.Target] = d
+	}
+	if d := byTarget["chatops"]; d.Channel != models.DeliveryChannelWebhook || d.Status != models.DeliverySent || d.MessageID != "req-42" {
+		t.Errorf("Unexpected chatops delivery: %+v", d)
+	}
+	if d := byTarget["ticketing"]; d.Status != models.DeliveryFailed || !strings.Contains(d.Error, "410") {
+		t.Errorf("Unexpected ticketing delivery: %+v", d)
+	}
+}
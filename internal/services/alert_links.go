@@ -0,0 +1,101 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"licet/internal/models"
+)
+
+// alertTemplateData is the data available to runbook URL templates
+type alertTemplateData struct {
+	Server     string
+	Feature    string
+	Type       string
+	Severity   string
+	DetailsURL string
+	TrendURL   string
+	Vars       map[string]string
+}
+
+// AlertLinks builds the details, trend and runbook links for an alert.
+// Details and trend links require alerts.base_url to be set.
+func (s *AlertService) AlertLinks(alert *models.Alert) (models.AlertLinks, error) {
+	var links models.AlertLinks
+
+	base := strings.TrimRight(s.cfg.Alerts.BaseURL, "/")
+	if base != "" && alert.ServerHostname != "" {
+		links.Details = base + "/details/" + url.PathEscape(alert.ServerHostname)
+
+		params := url.Values{"server": {alert.ServerHostname}}
+		if alert.FeatureName != "" {
+			params.Set("feature", alert.FeatureName)
+		}
+		links.Trend = base + "/utilization/trends?" + params.Encode()
+	}
+
+	runbook, ok := s.cfg.Alerts.Runbooks[alert.AlertType]
+	if !ok {
+		runbook = s.cfg.Alerts.Runbooks["default"]
+	}
+	if runbook == "" {
+		return links, nil
+	}
+
+	tmpl, err := template.New(alert.AlertType).Option("missingkey=zero").Parse(runbook)
+	if err != nil {
+		return links, fmt.Errorf("invalid runbook template for %s alerts: %w", alert.AlertType, err)
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, alertTemplateData{
+		Server:     alert.ServerHostname,
+		Feature:    alert.FeatureName,
+		Type:       alert.AlertType,
+		Severity:   alert.Severity,
+		DetailsURL: links.Details,
+		TrendURL:   links.Trend,
+		Vars:       s.cfg.Alerts.Variables,
+	})
+	if err != nil {
+		return links, fmt.Errorf("failed to render runbook for %s alerts: %w", alert.AlertType, err)
+	}
+	links.Runbook = buf.String()
+
+	return links, nil
+}
+
+// withLinks attaches notification links to alerts for API responses
+func (s *AlertService) withLinks(alerts []models.Alert) []models.Alert {
+	for i := range alerts {
+		links, err := s.AlertLinks(&alerts[i])
+		if err != nil {
+			continue
+		}
+		if links != (models.AlertLinks{}) {
+			alerts[i].Links = &links
+		}
+	}
+	return alerts
+}
+
+// formatAlertLinks renders the links section of a notification body
+func formatAlertLinks(links models.AlertLinks) string {
+	var b strings.Builder
+	for _, l := range []struct{ label, href string }{
+		{"Details", links.Details},
+		{"Trend", links.Trend},
+		{"Runbook", links.Runbook},
+	} {
+		if l.href != "" {
+			fmt.Fprintf(&b, "%-8s %s\n", l.label+":", l.href)
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "\nLinks:\n" + b.String()
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestAlertLinks(t *testing.T) {
+	cfg := &config.Config{Alerts: config.AlertConfig{
+		BaseURL: "https://licet.example.com/",
+		Runbooks: map[string]string{
+			"default": "{{.Vars.wiki}}/Licet/Alerts",
+			"down":    "{{.Vars.wiki}}/Licet/ServerDown?server={{urlquery .Server}}",
+		},
+		Variables: map[string]string{"wiki": "https://wiki.example.com"},
+	}}
+	s := NewAlertService(nil, cfg)
+
+	links, err := s.AlertLinks(&models.Alert{ServerHostname: "27000@lic1", FeatureName: "solver pro", AlertType: "down"})
+	if err != nil {
+		t.Fatalf("AlertLinks failed: %v", err)
+	}
+	if links.Details != "https://licet.example.com/details/27000@lic1" {
+		t.Errorf("Unexpected details link %q", links.Details)
+	}
+	if links.Trend != "https://licet.example.com/utilization/trends?feature=solver+pro&server=27000%40lic1" {
+		t.Errorf("Unexpected trend link %q", links.Trend)
+	}
+	if links.Runbook != "https://wiki.example.com/Licet/ServerDown?server=27000%40lic1" {
+		t.Errorf("Unexpected runbook link %q", links.Runbook)
+	}
+
+	links, _ = s.AlertLinks(&models.Alert{ServerHostname: "27000@lic1", AlertType: "expiration"})
+	if links.Runbook != "https://wiki.example.com/Licet/Alerts" {
+		t.Errorf("Expected default runbook, got %q", links.Runbook)
+	}
+
+	body := formatAlertLinks(links)
+	if !strings.Contains(body, "Details: https://licet.example.com/details/27000@lic1") || !strings.Contains(body, "Runbook: ") {
+		t.Errorf("Unexpected links section:\n%s", body)
+	}
+}
+
+func TestAlertLinks_NotConfigured(t *testing.T) {
+	s := NewAlertService(nil, &config.Config{})
+	links, err := s.AlertLinks(&models.Alert{ServerHostname: "27000@lic1", AlertType: "down"})
+	if err != nil || links != (models.AlertLinks{}) {
+		t.Errorf("Expected no links, got %+v (%v)", links, err)
+	}
+	if formatAlertLinks(links) != "" {
+		t.Error("Expected no links section")
+	}
+
+	s = NewAlertService(nil, &config.Config{Alerts: config.AlertConfig{
+		Runbooks: map[string]string{"down": "{{.Server"},
+	}})
+	if _, err := s.AlertLinks(&models.Alert{AlertType: "down"}); err == nil {
+		t.Error("Expected an error for an invalid template")
+	}
+}
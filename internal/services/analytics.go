@@ -2,21 +2,25 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
+	"licet/internal/clock"
 	"licet/internal/database"
 	"licet/internal/models"
 )
 
 // AnalyticsService handles utilization analytics and predictive operations
 type AnalyticsService struct {
-	db      *sqlx.DB
-	storage *StorageService
-	dialect database.Dialect
+	db        *sqlx.DB
+	storage   *StorageService
+	dialect   database.Dialect
+	anomalies *anomalyPolicy
+	clock     clock.Clock
 }
 
 // NewAnalyticsService creates a new analytics service
@@ -25,9 +29,15 @@ func NewAnalyticsService(db *sqlx.DB, storage *StorageService, dbType string) *A
 		db:      db,
 		storage: storage,
 		dialect: database.NewDialect(dbType),
+		clock:   clock.System,
 	}
 }
 
+// SetClock sets the clock that analysis periods end at
+func (s *AnalyticsService) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
 // GetCurrentUtilization returns current utilization for all features across all servers
 func (s *AnalyticsService) GetCurrentUtilization(ctx context.Context, serverFilter string) ([]models.UtilizationData, error) {
 	var utilization []models.UtilizationData
@@ -39,12 +49,20 @@ func (s *AnalyticsService) GetCurrentUtilization(ctx context.Context, serverFilt
 			f.version,
 			f.total_licenses,
 			f.used_licenses,
-			(f.total_licenses - f.used_licenses) as available_licenses,
+			f.reserved_licenses,
+			f.borrowed_licenses,
+			f.overdraft_licenses,
+			CASE
+				WHEN f.total_licenses - f.used_licenses - f.reserved_licenses > 0
+				THEN f.total_licenses - f.used_licenses - f.reserved_licenses
+				ELSE 0
+			END as available_licenses,
 			CASE
 				WHEN f.total_licenses > 0 THEN (f.used_licenses * 100.0 / f.total_licenses)
 				ELSE 0
 			END as utilization_pct,
-			f.vendor_daemon
+			f.vendor_daemon,
+			f.license_model
 		FROM features f
 		INNER JOIN (
 			SELECT server_hostname, name, MAX(last_updated) as latest
@@ -55,6 +73,7 @@ func (s *AnalyticsService) GetCurrentUtilization(ctx context.Context, serverFilt
 		           AND f.name = latest.name
 		           AND f.last_updated = latest.latest
 		WHERE f.total_licenses > 0
+		  AND f.license_model <> 'uncounted'
 	`
 
 	args := []interface{}{}
@@ -65,22 +84,54 @@ func (s *AnalyticsService) GetCurrentUtilization(ctx context.Context, serverFilt
 
 	query += " ORDER BY utilization_pct DESC, feature_name ASC"
 
-	err := s.db.SelectContext(ctx, &utilization, query, args...)
+	err := s.db.SelectContext(ctx, &utilization, s.db.Rebind(query), args...)
 	return utilization, err
 }
 
-// GetUtilizationHistory returns time-series usage data for charting
+// historyBucket returns the bucket width of usage history over a period, or
+// "" to return every sample
+func historyBucket(days int) string {
+	switch {
+	case days > 90:
+		return "1 day"
+	case days > 7:
+		return "1 hour"
+	}
+	return ""
+}
+
+// GetUtilizationHistory returns time-series usage and queued requests for
+// charting. Longer periods return the peaks per hour or day instead of every
+// sample, bucketed natively where the database supports it and from the usage
+// rollups otherwise.
 func (s *AnalyticsService) GetUtilizationHistory(ctx context.Context, server, feature string, days int) ([]models.UtilizationHistoryPoint, error) {
 	var history []models.UtilizationHistoryPoint
-	cutoff := time.Now().AddDate(0, 0, -days)
+	cutoff := s.clock.Now().AddDate(0, 0, -days)
+	width := historyBucket(days)
+	if width != "" && s.dialect.TimeBucket(width) == "" {
+		return s.bucketedHistory(ctx, server, feature, cutoff, width == "1 hour")
+	}
 
 	query := fmt.Sprintf(`
 		SELECT
 			%s as timestamp,
-			users_count
+			users_count,
+			queued_count
 		FROM feature_usage
 		WHERE 1=1
 	`, s.dialect.TimestampConcat())
+	order := " ORDER BY date ASC, time ASC"
+	if width != "" {
+		query = fmt.Sprintf(`
+			SELECT
+				%s as timestamp,
+				MAX(users_count) as users_count,
+				MAX(queued_count) as queued_count
+			FROM feature_usage
+			WHERE 1=1
+		`, s.dialect.TimeBucket(width))
+		order = " GROUP BY 1 ORDER BY 1"
+	}
 
 	args := []interface{}{}
 	if server != "" {
@@ -92,92 +143,131 @@ func (s *AnalyticsService) GetUtilizationHistory(ctx context.Context, server, fe
 		args = append(args, feature)
 	}
 
-	query += " AND date >= ? ORDER BY date ASC, time ASC"
+	query += " AND date >= ?" + order
 	args = append(args, cutoff.Format("2006-01-02"))
 
-	err := s.db.SelectContext(ctx, &history, query, args...)
+	err := s.db.SelectContext(ctx, &history, s.db.Rebind(query), args...)
 	return history, err
 }
 
+// bucketedHistory returns the peak usage and queued requests per hour or day
+// since cutoff
+func (s *AnalyticsService) bucketedHistory(ctx context.Context, server, feature string, cutoff time.Time, hourly bool) ([]models.UtilizationHistoryPoint, error) {
+	watermark, err := s.storage.rollupWatermark(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usage, args := s.storage.usageAggregates(watermark, hourly, cutoff, server, feature)
+
+	query := `
+		SELECT date, 0 AS hour, MAX(peak_users) AS users_count, MAX(peak_queued) AS queued_count
+		FROM (` + usage + `) u
+		GROUP BY date
+		ORDER BY date
+	`
+	if hourly {
+		query = `
+			SELECT date, hour, MAX(peak_users) AS users_count, MAX(peak_queued) AS queued_count
+			FROM (` + usage + `) u
+			GROUP BY date, hour
+			ORDER BY date, hour
+		`
+	}
+
+	var rows []struct {
+		Date        interface{} `db:"date"`
+		Hour        int         `db:"hour"`
+		UsersCount  int         `db:"users_count"`
+		QueuedCount int         `db:"queued_count"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	history := make([]models.UtilizationHistoryPoint, 0, len(rows))
+	for _, row := range rows {
+		at := scannedDate(row.Date).Add(time.Duration(row.Hour) * time.Hour)
+		history = append(history, models.UtilizationHistoryPoint{
+			Timestamp:   at.Format("2006-01-02 15:04:05"),
+			UsersCount:  row.UsersCount,
+			QueuedCount: row.QueuedCount,
+		})
+	}
+	return history, nil
+}
+
 // GetUtilizationStats returns aggregated statistics
 func (s *AnalyticsService) GetUtilizationStats(ctx context.Context, server string, days int) ([]models.UtilizationStats, error) {
 	var stats []models.UtilizationStats
-	cutoff := time.Now().AddDate(0, 0, -days)
+	watermark, err := s.storage.rollupWatermark(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usage, args := s.storage.usageAggregates(watermark, false, s.clock.Now().AddDate(0, 0, -days), server, "")
 
 	query := `
 		SELECT
-			fu.server_hostname,
-			fu.feature_name,
-			AVG(fu.users_count) as avg_usage,
-			MAX(fu.users_count) as peak_usage,
-			MIN(fu.users_count) as min_usage,
+			u.server_hostname,
+			u.feature_name,
+			SUM(u.sum_users) * 1.0 / SUM(u.samples) as avg_usage,
+			MAX(u.peak_users) as peak_usage,
+			MIN(u.min_users) as min_usage,
 			(SELECT total_licenses FROM features
-			 WHERE server_hostname = fu.server_hostname
-			   AND name = fu.feature_name
+			 WHERE server_hostname = u.server_hostname
+			   AND name = u.feature_name
 			 LIMIT 1) as total_licenses
-		FROM feature_usage fu
-		WHERE fu.date >= ?
+		FROM (` + usage + `) u
+		WHERE NOT EXISTS (
+			SELECT 1 FROM features
+			WHERE server_hostname = u.server_hostname
+			  AND name = u.feature_name
+			  AND license_model = 'uncounted')
+		GROUP BY u.server_hostname, u.feature_name
+		ORDER BY avg_usage DESC
 	`
 
-	args := []interface{}{cutoff.Format("2006-01-02")}
-	if server != "" {
-		query += " AND fu.server_hostname = ?"
-		args = append(args, server)
-	}
-
-	query += " GROUP BY fu.server_hostname, fu.feature_name ORDER BY avg_usage DESC"
-
-	err := s.db.SelectContext(ctx, &stats, query, args...)
+	err = s.db.SelectContext(ctx, &stats, s.db.Rebind(query), args...)
 	return stats, err
 }
 
 // GetHeatmapData returns hour-of-day usage patterns for heatmap visualization
 func (s *AnalyticsService) GetHeatmapData(ctx context.Context, server string, days int) ([]models.HeatmapData, error) {
-	cutoff := time.Now().AddDate(0, 0, -days)
+	cutoff := s.clock.Now().AddDate(0, 0, -days)
+	watermark, err := s.storage.rollupWatermark(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	// Get all unique features
-	featuresQuery := `
-		SELECT DISTINCT server_hostname, feature_name
-		FROM feature_usage
-		WHERE date >= ?
-	`
-	args := []interface{}{cutoff.Format("2006-01-02")}
-	if server != "" {
-		featuresQuery += " AND server_hostname = ?"
-		args = append(args, server)
-	}
+	usage, args := s.storage.usageAggregates(watermark, false, cutoff, server, "")
+	featuresQuery := `SELECT DISTINCT server_hostname, feature_name FROM (` + usage + `) u`
 
 	type featureKey struct {
 		ServerHostname string `db:"server_hostname"`
 		FeatureName    string `db:"feature_name"`
 	}
 	var features []featureKey
-	if err := s.db.SelectContext(ctx, &features, featuresQuery, args...); err != nil {
+	if err := s.db.SelectContext(ctx, &features, s.db.Rebind(featuresQuery), args...); err != nil {
 		return nil, err
 	}
 
 	// For each feature, get hourly usage patterns
 	var heatmapData []models.HeatmapData
 
-	hourlyQuery := fmt.Sprintf(`
-		SELECT
-			%s as hour,
-			AVG(users_count) as avg_usage,
-			MAX(users_count) as peak_usage
-		FROM feature_usage
-		WHERE server_hostname = ?
-		  AND feature_name = ?
-		  AND date >= ?
-		GROUP BY hour
-		ORDER BY hour
-	`, s.dialect.HourExtract())
-
 	for _, feature := range features {
+		usage, args := s.storage.usageAggregates(watermark, true, cutoff, feature.ServerHostname, feature.FeatureName)
+		hourlyQuery := `
+			SELECT
+				hour,
+				SUM(sum_users) * 1.0 / SUM(samples) as avg_usage,
+				MAX(peak_users) as peak_usage
+			FROM (` + usage + `) u
+			GROUP BY hour
+			ORDER BY hour
+		`
+
 		var hourlyData []models.HeatmapHourly
-		err := s.db.SelectContext(ctx, &hourlyData, hourlyQuery,
-			feature.ServerHostname,
-			feature.FeatureName,
-			cutoff.Format("2006-01-02"))
+		err := s.db.SelectContext(ctx, &hourlyData, s.db.Rebind(hourlyQuery), args...)
 
 		if err != nil {
 			log.Errorf("Failed to get heatmap data for %s:%s: %v",
@@ -214,113 +304,194 @@ func (s *AnalyticsService) GetHeatmapData(ctx context.Context, server string, da
 	return heatmapData, nil
 }
 
-// GetPredictiveAnalytics performs trend analysis and anomaly detection for a feature
+// Forecast models of predictive analytics
+const (
+	ForecastLinear      = "linear"      // Linear regression over the daily usage
+	ForecastHoltWinters = "holtwinters" // Holt-Winters triple exponential smoothing with a weekly season
+)
+
+// ErrUnknownForecastModel is returned for forecast models other than
+// ForecastLinear and ForecastHoltWinters
+var ErrUnknownForecastModel = errors.New("unknown forecast model")
+
+// GetPredictiveAnalytics performs trend analysis and anomaly detection for a
+// feature, forecasting with linear regression. The trend is precomputed
+// during collection when available.
 func (s *AnalyticsService) GetPredictiveAnalytics(ctx context.Context, server, feature string, days int) (*models.PredictiveAnalytics, error) {
-	// Get historical usage data
-	usageHistory, err := s.storage.GetFeatureUsageHistory(ctx, server, feature, days)
+	return s.GetPredictiveAnalyticsWithModel(ctx, server, feature, days, ForecastLinear)
+}
+
+// GetPredictiveAnalyticsWithModel performs trend analysis and anomaly
+// detection for a feature, forecasting with the model. Holt-Winters follows
+// the weekly cycle of usage that a straight line underfits and needs two weeks
+// of usage.
+func (s *AnalyticsService) GetPredictiveAnalyticsWithModel(ctx context.Context, server, feature string, days int, model string) (*models.PredictiveAnalytics, error) {
+	if model != ForecastLinear && model != ForecastHoltWinters {
+		return nil, fmt.Errorf("%w %q: use %s or %s", ErrUnknownForecastModel, model, ForecastLinear, ForecastHoltWinters)
+	}
+
+	trend, err := s.storage.featureTrend(ctx, server, feature, days)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(usageHistory) < 7 {
+	if trend.Samples < minTrendSamples {
 		return nil, fmt.Errorf("insufficient data for predictions (need at least 7 data points)")
 	}
 
 	// Get current feature info
 	var currentFeature models.Feature
 	query := `SELECT * FROM features WHERE server_hostname = ? AND name = ? LIMIT 1`
-	err = s.db.GetContext(ctx, &currentFeature, query, server, feature)
+	err = s.db.GetContext(ctx, &currentFeature, s.db.Rebind(query), server, feature)
 	if err != nil {
 		return nil, err
 	}
 
-	// Prepare data for analysis
-	var xValues []float64 // Days from start
-	var yValues []float64 // Usage values
+	slope, intercept := trend.Slope, trend.Intercept
+	mean, stdDev := trend.AvgUsage, trend.StdDev
 
-	startTime := usageHistory[len(usageHistory)-1].Date
-	for i := len(usageHistory) - 1; i >= 0; i-- {
-		usage := usageHistory[i]
-		daysDiff := usage.Date.Sub(startTime).Hours() / 24
-		xValues = append(xValues, daysDiff)
-		yValues = append(yValues, float64(usage.UsersCount))
+	// Detect anomalies (values beyond the threshold in standard deviations
+	// from the mean), skipping holidays, exclusion windows and expected anomalies
+	threshold := s.anomalies.threshold(server, feature)
+	outliers, err := s.storage.getUsageOutliers(ctx, server, feature, days, mean-threshold*stdDev, mean+threshold*stdDev)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := s.expectedAnomalyDays(ctx, server, feature)
+	if err != nil {
+		return nil, err
 	}
 
-	// Calculate linear regression
-	slope, intercept := linearRegression(xValues, yValues)
-
-	// Calculate statistics for anomaly detection
-	mean, stdDev := calculateStats(yValues)
-
-	// Detect anomalies (values > 2 standard deviations from mean)
 	var anomalies []models.AnomalyDetection
-	for i := len(usageHistory) - 1; i >= 0; i-- {
-		usage := usageHistory[i]
-		deviation := (float64(usage.UsersCount) - mean) / stdDev
-
-		if deviation > 2.0 || deviation < -2.0 {
-			severity := "low"
-			if deviation > 3.0 || deviation < -3.0 {
-				severity = "high"
-			} else if deviation > 2.5 || deviation < -2.5 {
-				severity = "medium"
-			}
-
-			anomalies = append(anomalies, models.AnomalyDetection{
-				Date:      usage.Date.Format("2006-01-02"),
-				Usage:     usage.UsersCount,
-				Expected:  mean,
-				Deviation: deviation,
-				Severity:  severity,
-			})
+	suppressed := 0
+	for _, usage := range outliers {
+		if s.storage.holidays.IsHoliday(server, usage.Date) || s.anomalies.excluded(server, usage.Date) ||
+			expected[usage.Date.Format("2006-01-02")] {
+			suppressed++
+			continue
 		}
+
+		deviation := (float64(usage.UsersCount) - mean) / stdDev
+		anomalies = append(anomalies, models.AnomalyDetection{
+			Date:      usage.Date.Format("2006-01-02"),
+			Usage:     usage.UsersCount,
+			Expected:  mean,
+			Deviation: deviation,
+			Severity:  anomalySeverity(deviation, threshold),
+		})
 	}
 
-	// Generate forecast for next 30 days
 	var forecast []models.ForecastPoint
-	lastDay := xValues[len(xValues)-1]
-	for i := 1; i <= 30; i++ {
-		futureDay := lastDay + float64(i)
-		predictedUsage := slope*futureDay + intercept
-
-		// Ensure predictions don't go negative
-		if predictedUsage < 0 {
-			predictedUsage = 0
+	daysToCapacity := -1
+	confidence := trend.RSquared
+	if model == ForecastHoltWinters {
+		forecast, slope, daysToCapacity, confidence, err = s.holtWintersForecast(ctx, server, feature, days, currentFeature.CountedLicenses())
+		if err != nil {
+			return nil, err
 		}
+	} else {
+		// Prediction interval from the spread of usage around the trend line
+		halfWidth := predictionZ * stdDev * math.Sqrt(math.Max(1-trend.RSquared, 0))
+
+		// Generate forecast for next 30 days
+		lastDay := trend.LastDay
+		for i := 1; i <= 30; i++ {
+			futureDay := lastDay + float64(i)
+			predictedUsage := slope*futureDay + intercept
+
+			// Ensure predictions don't go negative
+			if predictedUsage < 0 {
+				predictedUsage = 0
+			}
 
-		futureDate := time.Now().AddDate(0, 0, i).Format("2006-01-02")
-		forecast = append(forecast, models.ForecastPoint{
-			Date:           futureDate,
-			PredictedUsage: predictedUsage,
-		})
-	}
+			futureDate := s.clock.Now().AddDate(0, 0, i)
+			holiday, _ := s.storage.holidays.Holiday(server, futureDate)
+			forecast = append(forecast, models.ForecastPoint{
+				Date:           futureDate.Format("2006-01-02"),
+				PredictedUsage: predictedUsage,
+				LowerBound:     math.Max(predictedUsage-halfWidth, 0),
+				UpperBound:     predictedUsage + halfWidth,
+				Holiday:        holiday,
+			})
+		}
 
-	// Calculate days to capacity (if trend is increasing)
-	daysToCapacity := -1
-	if slope > 0 && currentFeature.TotalLicenses > 0 {
-		currentUsage := slope*lastDay + intercept
-		remainingCapacity := float64(currentFeature.TotalLicenses) - currentUsage
-		if remainingCapacity > 0 {
-			daysToCapacity = int(remainingCapacity / slope)
+		// Calculate days to capacity (if trend is increasing)
+		if slope > 0 && currentFeature.CountedLicenses() > 0 {
+			currentUsage := slope*lastDay + intercept
+			remainingCapacity := float64(currentFeature.CountedLicenses()) - currentUsage
+			if remainingCapacity > 0 {
+				daysToCapacity = int(remainingCapacity / slope)
+			}
 		}
 	}
 
-	// Calculate confidence level (R-squared)
-	confidenceLevel := calculateRSquared(xValues, yValues, slope, intercept)
-
 	return &models.PredictiveAnalytics{
 		ServerHostname:  server,
 		FeatureName:     feature,
-		TotalLicenses:   currentFeature.TotalLicenses,
+		TotalLicenses:   currentFeature.CountedLicenses(),
 		CurrentUsage:    mean,
 		TrendSlope:      slope,
 		DaysToCapacity:  daysToCapacity,
-		ConfidenceLevel: confidenceLevel,
+		ConfidenceLevel: confidence,
+		Model:           model,
 		Forecast:        forecast,
 		Anomalies:       anomalies,
+
+		AnomalyThreshold:    threshold,
+		SuppressedAnomalies: suppressed,
 	}, nil
 }
 
+// holtWintersForecast forecasts the next 30 days of a feature's daily usage
+// with Holt-Winters. It returns the forecast, the trend per day, the days
+// until the forecast reaches capacity (-1 for never within the trend) and the
+// share of the usage variance the model explains.
+func (s *AnalyticsService) holtWintersForecast(ctx context.Context, server, feature string, days, capacity int) ([]models.ForecastPoint, float64, int, float64, error) {
+	series, last, err := s.storage.dailyUsageSeries(ctx, server, feature, s.clock.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return nil, 0, -1, 0, err
+	}
+	hw, err := fitHoltWinters(series, holtWintersSeason)
+	if err != nil {
+		return nil, 0, -1, 0, err
+	}
+
+	today := calendarDay(s.clock.Now())
+	ahead := int(today.Sub(last).Hours() / 24)
+	var forecast []models.ForecastPoint
+	daysToCapacity := -1
+	for i := 1; i <= 30; i++ {
+		predictedUsage, halfWidth := hw.forecast(ahead + i)
+		predictedUsage = math.Max(predictedUsage, 0)
+		if daysToCapacity < 0 && capacity > 0 && predictedUsage >= float64(capacity) {
+			daysToCapacity = i
+		}
+
+		futureDate := s.clock.Now().AddDate(0, 0, i)
+		holiday, _ := s.storage.holidays.Holiday(server, futureDate)
+		forecast = append(forecast, models.ForecastPoint{
+			Date:           futureDate.Format("2006-01-02"),
+			PredictedUsage: predictedUsage,
+			LowerBound:     math.Max(predictedUsage-halfWidth, 0),
+			UpperBound:     predictedUsage + halfWidth,
+			Holiday:        holiday,
+		})
+	}
+
+	// Beyond the forecast, extend the trend from the busiest day of the season
+	if daysToCapacity < 0 && hw.trend > 0 && capacity > 0 {
+		var peak float64
+		for _, offset := range hw.season {
+			peak = math.Max(peak, offset)
+		}
+		remaining := float64(capacity) - (hw.level + peak)
+		if remaining > 0 {
+			daysToCapacity = max(int(remaining/hw.trend)-ahead, 31)
+		}
+	}
+	return forecast, hw.trend, daysToCapacity, hw.fit, nil
+}
+
 // linearRegression calculates the slope and intercept for linear regression
 func linearRegression(x, y []float64) (slope, intercept float64) {
 	n := float64(len(x))
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+// defaultAnomalyThreshold is the anomaly threshold in standard deviations
+// when none is configured
+const defaultAnomalyThreshold = 2.0
+
+// ErrExpectedAnomalyNotFound is returned when unmarking an anomaly that was not marked
+var ErrExpectedAnomalyNotFound = errors.New("expected anomaly not found")
+
+// anomalyRule is a compiled per-feature anomaly threshold
+type anomalyRule struct {
+	server    string
+	pattern   *regexp.Regexp
+	threshold float64
+}
+
+// anomalyExclusion is a parsed exclusion window, inclusive of both days
+type anomalyExclusion struct {
+	name       string
+	start, end time.Time
+	servers    map[string]bool
+}
+
+// anomalyPolicy decides which usage samples are reported as anomalies. A nil
+// policy applies the default threshold without exclusions.
+type anomalyPolicy struct {
+	defaultThreshold float64
+	rules            []anomalyRule
+	exclusions       []anomalyExclusion
+}
+
+// newAnomalyPolicy compiles the anomaly detection config
+func newAnomalyPolicy(cfg config.AnomalyConfig) (*anomalyPolicy, error) {
+	p := &anomalyPolicy{defaultThreshold: cfg.Threshold}
+	if p.defaultThreshold <= 0 {
+		p.defaultThreshold = defaultAnomalyThreshold
+	}
+
+	for _, rule := range cfg.Rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid anomaly rule pattern %q: %w", rule.Pattern, err)
+		}
+		if rule.Threshold <= 0 {
+			return nil, fmt.Errorf("anomaly rule %q needs a positive threshold", rule.Pattern)
+		}
+		p.rules = append(p.rules, anomalyRule{server: rule.Server, pattern: re, threshold: rule.Threshold})
+	}
+
+	for _, ex := range cfg.Exclusions {
+		start, err := time.Parse("2006-01-02", ex.Start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start of anomaly exclusion %q: %w", ex.Name, err)
+		}
+		end := start
+		if ex.End != "" {
+			if end, err = time.Parse("2006-01-02", ex.End); err != nil {
+				return nil, fmt.Errorf("invalid end of anomaly exclusion %q: %w", ex.Name, err)
+			}
+		}
+		if end.Before(start) {
+			return nil, fmt.Errorf("anomaly exclusion %q ends before it starts", ex.Name)
+		}
+
+		exclusion := anomalyExclusion{name: ex.Name, start: start, end: end}
+		if len(ex.Servers) > 0 {
+			exclusion.servers = make(map[string]bool, len(ex.Servers))
+			for _, server := range ex.Servers {
+				exclusion.servers[server] = true
+			}
+		}
+		p.exclusions = append(p.exclusions, exclusion)
+	}
+
+	return p, nil
+}
+
+// threshold returns the anomaly threshold in standard deviations for a feature
+func (p *anomalyPolicy) threshold(server, feature string) float64 {
+	if p == nil {
+		return defaultAnomalyThreshold
+	}
+	for _, rule := range p.rules {
+		if (rule.server == "" || rule.server == server) && rule.pattern.MatchString(feature) {
+			return rule.threshold
+		}
+	}
+	return p.defaultThreshold
+}
+
+// excluded reports whether a day on a server falls inside an exclusion window
+func (p *anomalyPolicy) excluded(server string, date time.Time) bool {
+	if p == nil {
+		return false
+	}
+	day, _ := time.Parse("2006-01-02", date.Format("2006-01-02"))
+	for _, ex := range p.exclusions {
+		if ex.servers != nil && !ex.servers[server] {
+			continue
+		}
+		if !day.Before(ex.start) && !day.After(ex.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// anomalySeverity grades a deviation relative to the threshold
+func anomalySeverity(deviation, threshold float64) string {
+	if deviation < 0 {
+		deviation = -deviation
+	}
+	switch {
+	case deviation > threshold+1:
+		return "high"
+	case deviation > threshold+0.5:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// SetAnomalyConfig applies the anomaly detection config
+func (s *AnalyticsService) SetAnomalyConfig(cfg config.AnomalyConfig) error {
+	policy, err := newAnomalyPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	s.anomalies = policy
+	return nil
+}
+
+// GetExpectedAnomalies returns the anomalies marked as expected, optionally
+// limited to a server and feature
+func (s *AnalyticsService) GetExpectedAnomalies(ctx context.Context, server, feature string) ([]models.ExpectedAnomaly, error) {
+	query := `SELECT * FROM expected_anomalies WHERE 1 = 1`
+	var args []interface{}
+	if server != "" {
+		query += ` AND server_hostname = ?`
+		args = append(args, server)
+	}
+	if feature != "" {
+		query += ` AND feature_name = ?`
+		args = append(args, feature)
+	}
+	query += ` ORDER BY date DESC, server_hostname, feature_name`
+
+	var expected []models.ExpectedAnomaly
+	err := s.db.SelectContext(ctx, &expected, s.db.Rebind(query), args...)
+	return expected, err
+}
+
+// MarkAnomalyExpected marks a day of a feature's usage as expected, replacing
+// any existing mark for that day
+func (s *AnalyticsService) MarkAnomalyExpected(ctx context.Context, mark models.ExpectedAnomaly) error {
+	day := mark.Date.Format("2006-01-02")
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM expected_anomalies WHERE server_hostname = ? AND feature_name = ? AND date = ?`
+	if _, err := tx.ExecContext(ctx, tx.Rebind(query), mark.ServerHostname, mark.FeatureName, day); err != nil {
+		return fmt.Errorf("failed to replace expected anomaly: %w", err)
+	}
+
+	query = `INSERT INTO expected_anomalies (server_hostname, feature_name, date, note, created_by) VALUES (?, ?, ?, ?, ?)`
+	_, err = tx.ExecContext(ctx, tx.Rebind(query), mark.ServerHostname, mark.FeatureName, day, mark.Note, mark.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to store expected anomaly: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// UnmarkAnomalyExpected removes an expected anomaly mark
+func (s *AnalyticsService) UnmarkAnomalyExpected(ctx context.Context, server, feature string, date time.Time) error {
+	query := `DELETE FROM expected_anomalies WHERE server_hostname = ? AND feature_name = ? AND date = ?`
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(query), server, feature, date.Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrExpectedAnomalyNotFound
+	}
+	return nil
+}
+
+// expectedAnomalyDays returns the days of a feature marked as expected
+func (s *AnalyticsService) expectedAnomalyDays(ctx context.Context, server, feature string) (map[string]bool, error) {
+	expected, err := s.GetExpectedAnomalies(ctx, server, feature)
+	if err != nil {
+		return nil, err
+	}
+	days := make(map[string]bool, len(expected))
+	for _, e := range expected {
+		days[e.Date.Format("2006-01-02")] = true
+	}
+	return days, nil
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestAnomalyPolicy(t *testing.T) {
+	policy, err := newAnomalyPolicy(config.AnomalyConfig{
+		Threshold: 2.5,
+		Rules: []config.AnomalyRule{
+			{Server: "27000@a", Pattern: "^solver", Threshold: 4},
+			{Pattern: "^solver", Threshold: 3},
+		},
+		Exclusions: []config.AnomalyExclusion{
+			{Name: "Shutdown", Start: "2026-12-24", End: "2027-01-01"},
+			{Name: "Site move", Start: "2026-06-01", Servers: []string{"27000@b"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("newAnomalyPolicy failed: %v", err)
+	}
+
+	for _, tt := range []struct {
+		server, feature string
+		want            float64
+	}{
+		{"27000@a", "solver_pro", 4},
+		{"27000@b", "solver_pro", 3},
+		{"27000@b", "viewer", 2.5},
+	} {
+		if got := policy.threshold(tt.server, tt.feature); got != tt.want {
+			t.Errorf("threshold(%s, %s) = %v, want %v", tt.server, tt.feature, got, tt.want)
+		}
+	}
+
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	for _, tt := range []struct {
+		server string
+		date   string
+		want   bool
+	}{
+		{"27000@a", "2026-12-23", false},
+		{"27000@a", "2026-12-24", true},
+		{"27000@a", "2027-01-01", true},
+		{"27000@a", "2027-01-02", false},
+		{"27000@a", "2026-06-01", false},
+		{"27000@b", "2026-06-01", true},
+	} {
+		if got := policy.excluded(tt.server, day(tt.date)); got != tt.want {
+			t.Errorf("excluded(%s, %s) = %v, want %v", tt.server, tt.date, got, tt.want)
+		}
+	}
+
+	var none *anomalyPolicy
+	if none.threshold("a", "b") != defaultAnomalyThreshold || none.excluded("a", time.Now()) {
+		t.Error("Expected a nil policy to use the defaults")
+	}
+
+	for _, cfg := range []config.AnomalyConfig{
+		{Rules: []config.AnomalyRule{{Pattern: "(", Threshold: 3}}},
+		{Rules: []config.AnomalyRule{{Pattern: "x"}}},
+		{Exclusions: []config.AnomalyExclusion{{Name: "bad", Start: "24.12.2026"}}},
+		{Exclusions: []config.AnomalyExclusion{{Name: "reversed", Start: "2026-12-24", End: "2026-12-01"}}},
+	} {
+		if _, err := newAnomalyPolicy(cfg); err == nil {
+			t.Errorf("Expected an error for %+v", cfg)
+		}
+	}
+}
+
+func TestPredictiveAnalyticsAnomalySuppression(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	if err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", TotalLicenses: 100},
+	}); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	// Flat usage with a spike five days ago and a dip ten days ago
+	spike := time.Now().AddDate(0, 0, -5)
+	dip := time.Now().AddDate(0, 0, -10)
+	for day := 0; day < 20; day++ {
+		users := 40 + day%2
+		switch day {
+		case 5:
+			users = 90
+		case 10:
+			users = 0
+		}
+		date := time.Now().AddDate(0, 0, -day).Format("2006-01-02")
+		_, err := db.Exec(`INSERT INTO feature_usage (server_hostname, feature_name, date, time, users_count) VALUES (?, ?, ?, ?, ?)`,
+			"27000@a", "solver", date, "12:00:00", users)
+		if err != nil {
+			t.Fatalf("Failed to insert usage: %v", err)
+		}
+	}
+
+	analytics := NewAnalyticsService(db, storage, "sqlite")
+	result, err := analytics.GetPredictiveAnalytics(ctx, "27000@a", "solver", 30)
+	if err != nil {
+		t.Fatalf("GetPredictiveAnalytics failed: %v", err)
+	}
+	if len(result.Anomalies) != 2 || result.AnomalyThreshold != defaultAnomalyThreshold {
+		t.Fatalf("Expected 2 anomalies at the default threshold, got %+v", result)
+	}
+
+	// The dip falls inside a shutdown window
+	err = analytics.SetAnomalyConfig(config.AnomalyConfig{
+		Exclusions: []config.AnomalyExclusion{{Name: "Shutdown", Start: dip.Format("2006-01-02")}},
+	})
+	if err != nil {
+		t.Fatalf("SetAnomalyConfig failed: %v", err)
+	}
+
+	// The spike was a planned training session
+	err = analytics.MarkAnomalyExpected(ctx, models.ExpectedAnomaly{
+		ServerHostname: "27000@a", FeatureName: "solver", Date: spike, Note: "Training", CreatedBy: "alice",
+	})
+	if err != nil {
+		t.Fatalf("MarkAnomalyExpected failed: %v", err)
+	}
+
+	result, err = analytics.GetPredictiveAnalytics(ctx, "27000@a", "solver", 30)
+	if err != nil {
+		t.Fatalf("GetPredictiveAnalytics failed: %v", err)
+	}
+	if len(result.Anomalies) != 0 || result.SuppressedAnomalies != 2 {
+		t.Errorf("Expected both anomalies suppressed, got %+v (%d suppressed)", result.Anomalies, result.SuppressedAnomalies)
+	}
+
+	expected, err := analytics.GetExpectedAnomalies(ctx, "27000@a", "")
+	if err != nil || len(expected) != 1 || expected[0].Note != "Training" || expected[0].CreatedBy != "alice" {
+		t.Fatalf("Expected the training mark, got %+v (%v)", expected, err)
+	}
+
+	if err := analytics.UnmarkAnomalyExpected(ctx, "27000@a", "solver", spike); err != nil {
+		t.Fatalf("UnmarkAnomalyExpected failed: %v", err)
+	}
+	if err := analytics.UnmarkAnomalyExpected(ctx, "27000@a", "solver", spike); !errors.Is(err, ErrExpectedAnomalyNotFound) {
+		t.Errorf("Expected ErrExpectedAnomalyNotFound, got %v", err)
+	}
+
+	// A higher threshold for the feature hides the remaining spike
+	err = analytics.SetAnomalyConfig(config.AnomalyConfig{
+		Rules: []config.AnomalyRule{{Pattern: "^solver$", Threshold: 10}},
+	})
+	if err != nil {
+		t.Fatalf("SetAnomalyConfig failed: %v", err)
+	}
+	result, err = analytics.GetPredictiveAnalytics(ctx, "27000@a", "solver", 30)
+	if err != nil {
+		t.Fatalf("GetPredictiveAnalytics failed: %v", err)
+	}
+	if len(result.Anomalies) != 0 || result.AnomalyThreshold != 10 {
+		t.Errorf("Expected no anomalies at threshold 10, got %+v", result.Anomalies)
+	}
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+// ErrEmptyUsername is returned when anonymizing without a username
+var ErrEmptyUsername = errors.New("username is required")
+
+// userColumns lists every stored column holding a license username. Tables
+// that record users must be added here so anonymization covers them, except
+// waitlist_subscriptions, whose rows also hold the user's email address and
+// are deleted instead, with the queued email to that address.
+var userColumns = []struct{ table, column string }{
+	{"license_events", "username"},
+	{"feature_users", "username"},
+	{"license_checkouts", "username"},
+}
+
+// AnonymizeService handles data subject deletion requests
+type AnonymizeService struct {
+	db     *sqlx.DB
+	cfg    *config.Config
+	cipher *FieldCipher
+}
+
+// NewAnonymizeService creates a new anonymization service
+func NewAnonymizeService(db *sqlx.DB, cfg *config.Config) *AnonymizeService {
+	return &AnonymizeService{db: db, cfg: cfg}
+}
+
+// SetCipher matches encrypted usernames and stores the pseudonym encrypted
+func (s *AnonymizeService) SetCipher(c *FieldCipher) {
+	s.cipher = c
+}
+
+// AnonymizeUser replaces a username in all stored records with a stable
+// pseudonym and records an audit entry. The pseudonym matches the hashed
+// form used for username redaction.
+func (s *AnonymizeService) AnonymizeUser(ctx context.Context, username, actor string) (*models.AnonymizationReport, error) {
+	if username == "" {
+		return nil, ErrEmptyUsername
+	}
+
+	report := &models.AnonymizationReport{
+		Pseudonym:    Pseudonym([]byte(s.cfg.Privacy.HashKey), username),
+		RowsAffected: make(map[string]int64, len(userColumns)),
+		PerformedAt:  time.Now(),
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, c := range userColumns {
+		query := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s = ?`, c.table, c.column, c.column)
+		result, err := tx.ExecContext(ctx, tx.Rebind(query), s.cipher.Encrypt(report.Pseudonym), s.cipher.Encrypt(username))
+		if err != nil {
+			return nil, fmt.Errorf("failed to anonymize %s: %w", c.table, err)
+		}
+		n, _ := result.RowsAffected()
+		report.RowsAffected[c.table] = n
+		report.TotalRows += n
+	}
+
+	// Waitlist notifications are addressed to the user alone, so the outbox
+	// rows sent to their addresses go with the subscriptions
+	var stored []string
+	query := `SELECT DISTINCT email FROM waitlist_subscriptions WHERE username = ?`
+	if err := tx.SelectContext(ctx, &stored, tx.Rebind(query), s.cipher.Encrypt(username)); err != nil {
+		return nil, fmt.Errorf("failed to get waitlist subscriptions: %w", err)
+	}
+	emails := []string{waitlistEmail(s.cfg, username)}
+	for _, email := range stored {
+		if email, err := s.cipher.Decrypt(email); err == nil && !slices.Contains(emails, email) {
+			emails = append(emails, email)
+		}
+	}
+	for _, email := range emails {
+		query := `DELETE FROM email_outbox WHERE recipients = ?`
+		result, err := tx.ExecContext(ctx, tx.Rebind(query), email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete queued email: %w", err)
+		}
+		n, _ := result.RowsAffected()
+		report.RowsAffected["email_outbox"] += n
+		report.TotalRows += n
+	}
+
+	query = `DELETE FROM waitlist_subscriptions WHERE username = ?`
+	result, err := tx.ExecContext(ctx, tx.Rebind(query), s.cipher.Encrypt(username))
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete waitlist subscriptions: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	report.RowsAffected["waitlist_subscriptions"] = n
+	report.TotalRows += n
+
+	details, _ := json.Marshal(report.RowsAffected)
+	_, err = tx.ExecContext(ctx, tx.Rebind(`
+		INSERT INTO audit_log (created_at, actor, action, target, details)
+		VALUES (?, ?, ?, ?, ?)
+	`), report.PerformedAt, actor, "anonymize_user", report.Pseudonym, string(details))
+	if err != nil {
+		return nil, fmt.Errorf("failed to write audit entry: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Infof("Anonymized %d records as %s (requested by %s)", report.TotalRows, report.Pseudonym, actor)
+	return report, nil
+}
+
+// GetAuditLog returns the most recent audit entries
+func (s *AnonymizeService) GetAuditLog(ctx context.Context, limit int) ([]models.AuditEntry, error) {
+	entries := []models.AuditEntry{}
+	query := `SELECT * FROM audit_log ORDER BY created_at DESC, id DESC LIMIT ?`
+	err := s.db.SelectContext(ctx, &entries, s.db.Rebind(query), limit)
+	return entries, err
+}
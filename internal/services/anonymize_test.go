@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestAnonymizeUser(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	events := NewEventService(db, "sqlite")
+	_, err := events.RecordEvents(ctx, []models.LicenseEvent{
+		{ServerHostname: "27000@a", Date: now, Time: now, EventType: "DENIED", FeatureName: "solver", Username: "jdoe"},
+		{ServerHostname: "27000@a", Date: now, Time: now.Add(time.Second), EventType: "OUT", FeatureName: "solver", Username: "jdoe"},
+		{ServerHostname: "27000@a", Date: now, Time: now, EventType: "OUT", FeatureName: "solver", Username: "asmith"},
+	})
+	if err != nil {
+		t.Fatalf("RecordEvents failed: %v", err)
+	}
+
+	storage := NewStorageService(db, "sqlite")
+	err = storage.RecordUsers(ctx, []models.LicenseUser{
+		{ServerHostname: "27000@a", FeatureName: "solver", Username: "jdoe"},
+		{ServerHostname: "27000@a", FeatureName: "solver", Username: "asmith"},
+	}, now)
+	if err != nil {
+		t.Fatalf("RecordUsers failed: %v", err)
+	}
+
+	cfg := &config.Config{Privacy: config.PrivacyConfig{HashKey: "secret"}}
+	cfg.Waitlist.MaxPerUser = 5
+	alerts := NewAlertService(db, cfg)
+	if _, err := alerts.Subscribe(ctx, models.WaitlistSubscription{Username: "jdoe", Email: "jdoe@example.com", FeatureName: "solver"}); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	// A waitlist notification to jdoe and an alert to the operators
+	for _, to := range []string{"jdoe@example.com", "ops@example.com"} {
+		if err := enqueueEmail(ctx, db, now, 1, emailMessage{Subject: "Seat available", Recipients: []string{to}, Text: "solver"}); err != nil {
+			t.Fatalf("enqueueEmail failed: %v", err)
+		}
+	}
+	svc := NewAnonymizeService(db, cfg)
+
+	report, err := svc.AnonymizeUser(ctx, "jdoe", "admin")
+	if err != nil {
+		t.Fatalf("AnonymizeUser failed: %v", err)
+	}
+
+	if report.Pseudonym != Pseudonym([]byte("secret"), "jdoe") {
+		t.Errorf("Expected the redaction pseudonym, got %q", report.Pseudonym)
+	}
+	if report.RowsAffected["license_events"] != 2 || report.RowsAffected["feature_users"] != 1 ||
+		report.RowsAffected["waitlist_subscriptions"] != 1 || report.RowsAffected["email_outbox"] != 1 || report.TotalRows != 5 {
+		t.Errorf("Unexpected row counts: %+v", report)
+	}
+
+	var remaining int
+	db.Get(&remaining, `SELECT COUNT(*) FROM license_events WHERE username = 'jdoe'`)
+	if remaining != 0 {
+		t.Errorf("Expected no events left for jdoe, got %d", remaining)
+	}
+	db.Get(&remaining, `SELECT COUNT(*) FROM waitlist_subscriptions`)
+	if remaining != 0 {
+		t.Errorf("Expected the waitlist subscriptions of jdoe to be deleted, got %d", remaining)
+	}
+	db.Get(&remaining, `SELECT COUNT(*) FROM email_outbox WHERE recipients = 'ops@example.com'`)
+	if remaining != 1 {
+		t.Errorf("Expected email to others to be kept, got %d", remaining)
+	}
+	db.Get(&remaining, `SELECT COUNT(*) FROM license_events WHERE username = 'asmith'`)
+	if remaining != 1 {
+		t.Errorf("Expected other users to be untouched, got %d", remaining)
+	}
+
+	entries, err := svc.GetAuditLog(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetAuditLog failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 audit entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Actor != "admin" || entry.Action != "anonymize_user" || entry.Target != report.Pseudonym {
+		t.Errorf("Unexpected audit entry: %+v", entry)
+	}
+
+	if _, err := svc.AnonymizeUser(ctx, "", "admin"); !errors.Is(err, ErrEmptyUsername) {
+		t.Errorf("Expected ErrEmptyUsername, got %v", err)
+	}
+}
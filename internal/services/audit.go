@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"licet/internal/models"
+)
+
+// AuditService stores and queries the audit log: administrative operations
+// written by the services performing them and mutating API requests recorded
+// by the audit middleware
+type AuditService struct {
+	db  *sqlx.DB
+	bus *EventBus
+}
+
+// AuditFilter narrows an audit log query; empty fields match everything
+type AuditFilter struct {
+	Actor  string
+	Action string
+	Method string
+	Since  time.Time
+}
+
+func NewAuditService(db *sqlx.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// SetEventBus publishes the audit entries on the bus under AuditTopic once
+// stored
+func (s *AuditService) SetEventBus(bus *EventBus) {
+	s.bus = bus
+}
+
+// Record stores an audit entry
+func (s *AuditService) Record(ctx context.Context, entry models.AuditEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`
+		INSERT INTO audit_log (created_at, actor, action, target, details, role, remote_addr, method, path, status, duration_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`), entry.CreatedAt, entry.Actor, entry.Action, entry.Target, entry.Details,
+		entry.Role, entry.RemoteAddr, entry.Method, entry.Path, entry.Status, entry.DurationMS)
+	if err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	s.bus.Publish(AuditTopic, entry)
+	return nil
+}
+
+// List returns a page of the audit entries matching filter, newest first, and
+// the number of matching entries
+func (s *AuditService) List(ctx context.Context, filter AuditFilter, limit, offset int) ([]models.AuditEntry, int, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.Method != "" {
+		conditions = append(conditions, "method = ?")
+		args = append(args, strings.ToUpper(filter.Method))
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.Since)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := s.db.GetContext(ctx, &total, s.db.Rebind("SELECT COUNT(*) FROM audit_log"+where), args...); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	entries := []models.AuditEntry{}
+	query := "SELECT * FROM audit_log" + where + " ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+	if err := s.db.SelectContext(ctx, &entries, s.db.Rebind(query), append(args, limit, offset)...); err != nil {
+		return nil, 0, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	return entries, total, nil
+}
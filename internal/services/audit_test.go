@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestAuditService_RecordAndList(t *testing.T) {
+	db := newTestDB(t)
+	audit := NewAuditService(db)
+	ctx := context.Background()
+
+	now := time.Now()
+	entries := []models.AuditEntry{
+		{CreatedAt: now.AddDate(0, 0, -10), Actor: "admin", Action: "anonymize_user", Target: "jdoe"},
+		{CreatedAt: now.Add(-2 * time.Hour), Actor: "jdoe", Action: models.AuditActionRequest, Method: "POST", Path: "/api/v1/alerts", Status: 201, Role: "write", RemoteAddr: "192.0.2.10"},
+		{CreatedAt: now.Add(-time.Hour), Actor: "jdoe", Action: models.AuditActionRequest, Method: "DELETE", Path: "/api/v1/alerts/1", Status: 204},
+		{CreatedAt: now, Actor: "admin", Action: models.AuditActionRequest, Method: "PUT", Path: "/api/v1/settings/alerts", Status: 200},
+	}
+	for _, e := range entries {
+		if err := audit.Record(ctx, e); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	page, total, err := audit.List(ctx, AuditFilter{}, 2, 0)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if total != 4 || len(page) != 2 || page[0].Method != "PUT" || page[1].Method != "DELETE" {
+		t.Errorf("Expected the newest 2 of 4 entries, got %d %+v", total, page)
+	}
+	page, _, _ = audit.List(ctx, AuditFilter{}, 2, 2)
+	if len(page) != 2 || page[0].Path != "/api/v1/alerts" || page[0].RemoteAddr != "192.0.2.10" || page[0].Status != 201 || page[1].Target != "jdoe" {
+		t.Errorf("Unexpected second page %+v", page)
+	}
+
+	page, total, _ = audit.List(ctx, AuditFilter{Actor: "jdoe", Method: "post"}, 10, 0)
+	if total != 1 || len(page) != 1 || page[0].Role != "write" {
+		t.Errorf("Expected jdoe's POST, got %d %+v", total, page)
+	}
+	_, total, _ = audit.List(ctx, AuditFilter{Since: now.AddDate(0, 0, -1)}, 10, 0)
+	if total != 3 {
+		t.Errorf("Expected 3 entries of the last day, got %d", total)
+	}
+
+	result, err := NewDBStatsService(db, config.DatabaseConfig{Type: "sqlite"}).CleanupOldData(ctx, "audit_log", 7)
+	if err != nil {
+		t.Fatalf("CleanupOldData failed: %v", err)
+	}
+	if result.RowsDeleted != 1 {
+		t.Errorf("Expected 1 old entry removed, got %d", result.RowsDeleted)
+	}
+}
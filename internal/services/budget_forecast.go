@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"licet/internal/models"
+)
+
+// GetBudgetForecast projects the seats each feature needs at the end of each
+// of the coming months. The projection starts from the peak usage of the last
+// days, follows the usage trend and scales with the expected yearly headcount
+// growth, so it answers "how many seats do we buy next year".
+func (s *EnhancedAnalyticsService) GetBudgetForecast(ctx context.Context, days int, growthPct float64, months int, now time.Time) (*models.BudgetForecast, error) {
+	utilization, err := s.GetCurrentUtilizationWithTrend(ctx, "", days)
+	if err != nil {
+		return nil, err
+	}
+
+	forecast := &models.BudgetForecast{
+		GeneratedAt:    now.UTC().Format(time.RFC3339),
+		PeriodAnalyzed: days,
+		GrowthPct:      growthPct,
+		Features:       make([]models.SeatForecast, 0, len(utilization)),
+	}
+
+	monthEnds := make([]time.Time, months)
+	for m := range monthEnds {
+		// Day 0 of the following month is the last day of month m+1 from now
+		monthEnds[m] = time.Date(now.Year(), now.Month()+time.Month(m+2), 0, 0, 0, 0, 0, now.Location())
+		forecast.Months = append(forecast.Months, monthEnds[m].Format("2006-01"))
+	}
+
+	for _, u := range utilization {
+		seats := models.SeatForecast{
+			ServerHostname: u.ServerHostname,
+			FeatureName:    u.FeatureName,
+			TotalLicenses:  u.TotalLicenses,
+			PeakUsage:      u.PeakUsage,
+			TrendSlope:     u.TrendSlope,
+			RequiredSeats:  make([]int, months),
+		}
+		peak := 0
+		for m, end := range monthEnds {
+			daysAhead := end.Sub(now).Hours() / 24
+			seats.RequiredSeats[m] = projectSeats(u.PeakUsage, u.TrendSlope, growthPct, daysAhead)
+			if seats.RequiredSeats[m] > peak {
+				peak = seats.RequiredSeats[m]
+			}
+		}
+		seats.Shortfall = peak - u.TotalLicenses
+		forecast.Features = append(forecast.Features, seats)
+	}
+
+	return forecast, nil
+}
+
+// projectSeats returns the seats needed daysAhead days from now for a feature
+// with the given peak usage and daily trend, grown by the yearly headcount
+// growth. Partial seats round up, as they cannot be bought.
+func projectSeats(peakUsage int, slope, growthPct, daysAhead float64) int {
+	projected := float64(peakUsage) + slope*daysAhead
+	if projected <= 0 {
+		return 0
+	}
+	projected *= math.Pow(1+growthPct/100, daysAhead/365)
+	// Avoid buying a seat for floating point noise, e.g. 10.0000000001
+	return int(math.Ceil(projected - 1e-9))
+}
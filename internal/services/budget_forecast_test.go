@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"licet/internal/models"
+)
+
+func TestProjectSeats(t *testing.T) {
+	tests := []struct {
+		name      string
+		peak      int
+		slope     float64
+		growth    float64
+		daysAhead float64
+		want      int
+	}{
+		{"flat", 10, 0, 0, 365, 10},
+		{"trend", 10, 0.1, 0, 100, 20},
+		{"headcount growth", 100, 0, 10, 365, 110},
+		{"partial seats round up", 10, 0.01, 0, 30, 11},
+		{"shrinking to zero", 5, -1, 10, 30, 0},
+	}
+	for _, tt := range tests {
+		if got := projectSeats(tt.peak, tt.slope, tt.growth, tt.daysAhead); got != tt.want {
+			t.Errorf("%s: projectSeats = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGetBudgetForecast(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", TotalLicenses: 50},
+	})
+	if err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	for day := 0; day < 10; day++ {
+		date := time.Now().AddDate(0, 0, -day).Format("2006-01-02")
+		_, err := db.Exec(`INSERT INTO feature_usage (server_hostname, feature_name, date, time, users_count) VALUES (?, ?, ?, ?, ?)`,
+			"27000@a", "solver", date, "12:00:00", 40)
+		if err != nil {
+			t.Fatalf("Failed to insert usage: %v", err)
+		}
+	}
+
+	s := NewEnhancedAnalyticsService(db, storage, "sqlite")
+	now := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+	forecast, err := s.GetBudgetForecast(ctx, 30, 20, 12, now)
+	if err != nil {
+		t.Fatalf("GetBudgetForecast failed: %v", err)
+	}
+
+	if len(forecast.Months) != 12 || forecast.Months[0] != "2027-01" || forecast.Months[11] != "2027-12" {
+		t.Fatalf("Unexpected forecast months %v", forecast.Months)
+	}
+	if len(forecast.Features) != 1 {
+		t.Fatalf("Expected 1 feature, got %d", len(forecast.Features))
+	}
+	f := forecast.Features[0]
+	if f.RequiredSeats[0] < 40 || f.RequiredSeats[11] != 48 {
+		t.Errorf("Expected flat usage of 40 seats to grow to 48 in a year, got %v", f.RequiredSeats)
+	}
+	if f.Shortfall != -2 {
+		t.Errorf("Expected 2 spare seats, got shortfall %d", f.Shortfall)
+	}
+}
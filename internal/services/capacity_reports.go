@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/models"
+)
+
+// DefaultCapacityReportDays is the analysis period kept fresh by the scheduler
+const DefaultCapacityReportDays = 30
+
+// CapacityReport returns the stored capacity planning report for the period,
+// generating and storing it when none exists or refresh is requested
+func (s *EnhancedAnalyticsService) CapacityReport(ctx context.Context, days int, refresh bool) (*models.CapacityPlanningReport, error) {
+	if !refresh {
+		report, err := s.storedCapacityReport(ctx, days)
+		if err != nil {
+			return nil, err
+		}
+		if report != nil {
+			return report, nil
+		}
+	}
+	return s.GenerateCapacityReport(ctx, days)
+}
+
+// GenerateCapacityReport generates the capacity planning report for the
+// period and stores it as the latest version. Concurrent generations are
+// serialized so a burst of refresh requests runs the analysis once at a time.
+func (s *EnhancedAnalyticsService) GenerateCapacityReport(ctx context.Context, days int) (*models.CapacityPlanningReport, error) {
+	s.reportMu.Lock()
+	defer s.reportMu.Unlock()
+
+	start := time.Now()
+	report, err := s.GetCapacityPlanningReport(ctx, days)
+	if err != nil {
+		return nil, err
+	}
+	report.GenerationMs = time.Since(start).Milliseconds()
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM capacity_reports WHERE period_days = ?`), days); err != nil {
+		return nil, fmt.Errorf("failed to replace capacity report: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, tx.Rebind(`
+		INSERT INTO capacity_reports (period_days, generated_at, duration_ms, report)
+		VALUES (?, ?, ?, ?)
+	`), days, start, report.GenerationMs, string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to store capacity report: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Generated %d-day capacity report in %dms", days, report.GenerationMs)
+	return report, nil
+}
+
+// storedCapacityReport returns the stored report for the period, or nil if none exists
+func (s *EnhancedAnalyticsService) storedCapacityReport(ctx context.Context, days int) (*models.CapacityPlanningReport, error) {
+	var data string
+	query := `SELECT report FROM capacity_reports WHERE period_days = ?`
+	err := s.db.GetContext(ctx, &data, s.db.Rebind(query), days)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var report models.CapacityPlanningReport
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		return nil, fmt.Errorf("failed to decode stored capacity report: %w", err)
+	}
+	report.Cached = true
+	return &report, nil
+}
+
+// RefreshCapacityReports regenerates the default report and every other
+// period that has a stored report
+func (s *EnhancedAnalyticsService) RefreshCapacityReports(ctx context.Context) error {
+	var periods []int
+	if err := s.db.SelectContext(ctx, &periods, `SELECT period_days FROM capacity_reports`); err != nil {
+		return err
+	}
+
+	seen := map[int]bool{DefaultCapacityReportDays: true}
+	todo := []int{DefaultCapacityReportDays}
+	for _, days := range periods {
+		if !seen[days] {
+			seen[days] = true
+			todo = append(todo, days)
+		}
+	}
+
+	for _, days := range todo {
+		if _, err := s.GenerateCapacityReport(ctx, days); err != nil {
+			return fmt.Errorf("failed to generate %d-day capacity report: %w", days, err)
+		}
+	}
+	return nil
+}
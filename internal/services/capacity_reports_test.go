@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCapacityReport_Stored(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	s := NewEnhancedAnalyticsService(db, NewStorageService(db, "sqlite"), "sqlite")
+
+	report, err := s.CapacityReport(ctx, 30, false)
+	if err != nil {
+		t.Fatalf("CapacityReport failed: %v", err)
+	}
+	if report.Cached {
+		t.Error("Expected the first report to be generated")
+	}
+
+	stored, err := s.CapacityReport(ctx, 30, false)
+	if err != nil {
+		t.Fatalf("CapacityReport failed: %v", err)
+	}
+	if !stored.Cached || stored.GeneratedAt != report.GeneratedAt {
+		t.Errorf("Expected the stored report, got %+v", stored)
+	}
+
+	refreshed, err := s.CapacityReport(ctx, 30, true)
+	if err != nil {
+		t.Fatalf("CapacityReport failed: %v", err)
+	}
+	if refreshed.Cached {
+		t.Error("Expected refresh to regenerate the report")
+	}
+
+	if _, err := s.CapacityReport(ctx, 7, false); err != nil {
+		t.Fatalf("CapacityReport failed: %v", err)
+	}
+	if err := s.RefreshCapacityReports(ctx); err != nil {
+		t.Fatalf("RefreshCapacityReports failed: %v", err)
+	}
+	var periods []int
+	db.Select(&periods, `SELECT period_days FROM capacity_reports ORDER BY period_days`)
+	if len(periods) != 2 || periods[0] != 7 || periods[1] != 30 {
+		t.Errorf("Expected stored 7 and 30 day reports, got %v", periods)
+	}
+}
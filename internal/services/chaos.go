@@ -0,0 +1,132 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/clock"
+	"licet/internal/config"
+	"licet/internal/parsers"
+)
+
+// ErrChaosNotAllowed is returned when injecting a fault into a server not
+// listed in chaos.servers
+var ErrChaosNotAllowed = errors.New("faults cannot be injected into this server")
+
+// ChaosFault is a synthetic failure of a license server, answering its
+// queries until it expires or is cleared
+type ChaosFault struct {
+	Server     string    `json:"server"`
+	Fault      string    `json:"fault"`
+	InjectedBy string    `json:"injected_by"`
+	InjectedAt time.Time `json:"injected_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// ChaosService holds the faults injected into the queries of the servers
+// listed in chaos.servers. Faults are kept in memory, so a restart clears
+// them.
+type ChaosService struct {
+	allowed map[string]bool
+	clock   clock.Clock
+
+	mu     sync.Mutex
+	faults map[string]ChaosFault // By server
+}
+
+// NewChaosService creates a chaos service allowing faults in the servers of cfg
+func NewChaosService(cfg config.ChaosConfig) *ChaosService {
+	c := &ChaosService{allowed: make(map[string]bool), clock: clock.System, faults: make(map[string]ChaosFault)}
+	for _, server := range cfg.Servers {
+		c.allowed[server] = true
+	}
+	return c
+}
+
+// SetClock sets the clock faults expire by
+func (c *ChaosService) SetClock(clk clock.Clock) {
+	c.clock = clk
+}
+
+// Inject makes the queries of server fail with fault for duration
+func (c *ChaosService) Inject(server, fault string, duration time.Duration, by string) (ChaosFault, error) {
+	if !c.allowed[server] {
+		return ChaosFault{}, fmt.Errorf("%w: %s is not listed in chaos.servers", ErrChaosNotAllowed, server)
+	}
+	known := false
+	for _, f := range parsers.Faults {
+		known = known || f == fault
+	}
+	if !known {
+		return ChaosFault{}, fmt.Errorf("unknown fault %q", fault)
+	}
+
+	now := c.clock.Now()
+	f := ChaosFault{Server: server, Fault: fault, InjectedBy: by, InjectedAt: now, ExpiresAt: now.Add(duration)}
+	c.mu.Lock()
+	c.faults[server] = f
+	c.mu.Unlock()
+	log.Warnf("Chaos: injected fault %s into %s until %s (by %s)", fault, server, f.ExpiresAt.Format(time.RFC3339), by)
+	return f, nil
+}
+
+// Clear removes the fault of server, returning whether it had one
+func (c *ChaosService) Clear(server string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.faults[server]
+	delete(c.faults, server)
+	if ok {
+		log.Warnf("Chaos: cleared the fault of %s", server)
+	}
+	return ok
+}
+
+// Fault returns the fault currently injected into server, if any
+func (c *ChaosService) Fault(server string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, ok := c.faults[server]
+	if !ok {
+		return "", false
+	}
+	if !c.clock.Now().Before(f.ExpiresAt) {
+		delete(c.faults, server)
+		return "", false
+	}
+	return f.Fault, true
+}
+
+// List returns the faults that have not expired, by server
+func (c *ChaosService) List() []ChaosFault {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock.Now()
+	faults := []ChaosFault{}
+	for server, f := range c.faults {
+		if !now.Before(f.ExpiresAt) {
+			delete(c.faults, server)
+			continue
+		}
+		faults = append(faults, f)
+	}
+	sort.Slice(faults, func(i, j int) bool { return faults[i].Server < faults[j].Server })
+	return faults
+}
+
+// Servers returns the servers faults may be injected into
+func (c *ChaosService) Servers() []string {
+	servers := make([]string, 0, len(c.allowed))
+	for server := range c.allowed {
+		servers = append(servers, server)
+	}
+	sort.Strings(servers)
+	return servers
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"licet/internal/clock"
+	"licet/internal/config"
+	"licet/internal/parsers"
+)
+
+func TestChaosService(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFixed(now)
+	chaos := NewChaosService(config.ChaosConfig{Enabled: true, Servers: []string{"27000@staging"}})
+	chaos.SetClock(clk)
+
+	if _, err := chaos.Inject("27000@prod", parsers.FaultTimeout, time.Minute, "admin"); !errors.Is(err, ErrChaosNotAllowed) {
+		t.Errorf("Expected a server not listed to be refused, got %v", err)
+	}
+	if _, err := chaos.Inject("27000@staging", "meteor", time.Minute, "admin"); err == nil {
+		t.Error("Expected an unknown fault to be refused")
+	}
+
+	fault, err := chaos.Inject("27000@staging", parsers.FaultVendorDown, 10*time.Minute, "admin")
+	if err != nil {
+		t.Fatalf("Inject failed: %v", err)
+	}
+	if !fault.ExpiresAt.Equal(now.Add(10 * time.Minute)) {
+		t.Errorf("Expected the fault to expire in 10 minutes, got %s", fault.ExpiresAt)
+	}
+
+	cfg := &config.Config{Servers: []config.LicenseServer{{Hostname: "27000@staging", Type: "flexlm"}}}
+	query := NewQueryService(cfg, nil)
+	query.SetChaos(chaos)
+	result, err := query.QueryServer("27000@staging", "flexlm")
+	if err != nil || result.Status.Service != "warning" {
+		t.Errorf("Expected the query to report the vendor daemon down, got %+v (%v)", result.Status, err)
+	}
+
+	clk.Advance(10 * time.Minute)
+	if _, ok := chaos.Fault("27000@staging"); ok {
+		t.Error("Expected the fault to expire")
+	}
+	if faults := chaos.List(); len(faults) != 0 {
+		t.Errorf("Expected no faults, got %v", faults)
+	}
+
+	chaos.Inject("27000@staging", parsers.FaultGarbage, time.Minute, "admin")
+	if !chaos.Clear("27000@staging") || chaos.Clear("27000@staging") {
+		t.Error("Expected the fault to be cleared once")
+	}
+}
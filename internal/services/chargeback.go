@@ -0,0 +1,307 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+// ChargebackMonth is the layout of the months of chargeback reports
+const ChargebackMonth = "2006-01"
+
+// ldapTimeout bounds a group lookup with ldapsearch
+const ldapTimeout = 30 * time.Second
+
+// ChargebackService attributes the checkout hours of each month to the cost
+// centers of the users, from chargeback.cost_centers
+type ChargebackService struct {
+	cfg     config.ChargebackConfig
+	storage *StorageService
+	members func(ctx context.Context, group string) ([]string, error) // Usernames in an LDAP group
+}
+
+// NewChargebackService validates the aggregation schedule and creates the service
+func NewChargebackService(cfg *config.Config, storage *StorageService) (*ChargebackService, error) {
+	if cfg.Chargeback.Enabled {
+		if _, err := cron.ParseStandard(cfg.Chargeback.Schedule); err != nil {
+			return nil, fmt.Errorf("invalid chargeback schedule %q: %w", cfg.Chargeback.Schedule, err)
+		}
+	}
+	s := &ChargebackService{cfg: cfg.Chargeback, storage: storage}
+	s.members = func(ctx context.Context, group string) ([]string, error) {
+		return ldapGroupMembers(ctx, s.cfg.LDAP, group)
+	}
+	return s, nil
+}
+
+// Schedule returns the cron expression of the aggregation runs
+func (s *ChargebackService) Schedule() string {
+	return s.cfg.Schedule
+}
+
+// Aggregate attributes the checkout hours of the previous and the current
+// month to cost centers. The previous month is aggregated again because its
+// last checkouts may have been returned since.
+func (s *ChargebackService) Aggregate(ctx context.Context) error {
+	costCenter, err := s.costCenters(ctx)
+	if err != nil {
+		return err
+	}
+	now := s.storage.clock.Now().Local()
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	for _, month := range []time.Time{current.AddDate(0, -1, 0), current} {
+		if err := s.storage.AggregateChargeback(ctx, month, costCenter); err != nil {
+			return fmt.Errorf("failed to aggregate chargeback of %s: %w", month.Format(ChargebackMonth), err)
+		}
+	}
+	return nil
+}
+
+// costCenters returns the cost center of a username. The first cost center
+// listing the user, or one of their LDAP groups, wins.
+func (s *ChargebackService) costCenters(ctx context.Context) (func(string) string, error) {
+	byUser := make(map[string]string)
+	assign := func(username, costCenter string) {
+		username = strings.ToLower(username)
+		if _, ok := byUser[username]; !ok {
+			byUser[username] = costCenter
+		}
+	}
+	for _, cc := range s.cfg.CostCenters {
+		for _, user := range cc.Users {
+			assign(user, cc.Name)
+		}
+		for _, group := range cc.LDAPGroups {
+			members, err := s.members(ctx, group)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read LDAP group %s: %w", group, err)
+			}
+			for _, user := range members {
+				assign(user, cc.Name)
+			}
+		}
+	}
+	return func(username string) string {
+		if cc, ok := byUser[strings.ToLower(username)]; ok {
+			return cc
+		}
+		return s.cfg.DefaultCostCenter
+	}, nil
+}
+
+// ldapGroupMembers returns the usernames of the members of a group below
+// ldap.base_dn, found by its cn with ldapsearch
+func ldapGroupMembers(ctx context.Context, cfg config.ChargebackLDAPConfig, group string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, ldapTimeout)
+	defer cancel()
+
+	args := []string{"-x", "-LLL", "-o", "ldif-wrap=no", "-H", cfg.URL, "-b", cfg.BaseDN}
+	if cfg.BindDN != "" {
+		args = append(args, "-D", cfg.BindDN, "-y", cfg.BindPasswordFile)
+	}
+	args = append(args, "(cn="+ldapEscape(group)+")", cfg.MemberAttribute)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ldapsearch", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ldapsearch: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parseLDAPMembers(out, cfg.MemberAttribute), nil
+}
+
+// ldapEscape escapes the characters with a meaning in LDAP filters (RFC 4515)
+func ldapEscape(s string) string {
+	return strings.NewReplacer(`\`, `\5c`, `*`, `\2a`, `(`, `\28`, `)`, `\29`, "\x00", `\00`).Replace(s)
+}
+
+// parseLDAPMembers returns the values of attribute in LDIF output. Member DNs,
+// such as uid=alice,ou=people,dc=example,dc=com, give the value of their
+// first RDN.
+func parseLDAPMembers(ldif []byte, attribute string) []string {
+	// Unfold continuation lines, which start with a space
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(ldif))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, " ") && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	var members []string
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || !strings.EqualFold(name, attribute) {
+			continue
+		}
+		if encoded, ok := strings.CutPrefix(value, ":"); ok {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+			if err != nil {
+				continue
+			}
+			value = string(decoded)
+		}
+		value = strings.TrimSpace(value)
+		if rdn, _, isDN := strings.Cut(value, ","); isDN {
+			if _, v, ok := strings.Cut(rdn, "="); ok {
+				value = v
+			}
+		}
+		if value != "" {
+			members = append(members, value)
+		}
+	}
+	return members
+}
+
+// AggregateChargeback replaces the chargeback hours of the month starting at
+// month with the checkout time within it, attributed to the cost center of
+// each user
+func (s *StorageService) AggregateChargeback(ctx context.Context, month time.Time, costCenter func(username string) string) error {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.Local)
+	end := start.AddDate(0, 1, 0)
+
+	var rows []checkoutRow
+	query := `
+		SELECT server_hostname, feature_name, username, checked_out_at, last_seen, checked_in_at
+		FROM license_checkouts
+		WHERE checked_out_at < ? AND COALESCE(checked_in_at, last_seen) >= ?
+	`
+	if err := s.db.SelectContext(ctx, &rows, s.db.Rebind(query), end, start); err != nil {
+		return err
+	}
+
+	type key struct{ costCenter, server, feature string }
+	type usage struct {
+		hours     float64
+		checkouts int
+		users     map[string]bool
+	}
+	byKey := make(map[key]*usage)
+	for _, r := range rows {
+		username := r.Username
+		if name, err := s.cipher.Decrypt(username); err == nil {
+			username = name
+		}
+		k := key{costCenter(username), r.ServerHostname, r.FeatureName}
+		u := byKey[k]
+		if u == nil {
+			u = &usage{users: make(map[string]bool)}
+			byKey[k] = u
+		}
+
+		from, to := r.CheckedOutAt, r.LastSeen
+		if r.CheckedInAt != nil {
+			to = *r.CheckedInAt
+		}
+		if from.Before(start) {
+			from = start
+		}
+		if to.After(end) {
+			to = end
+		}
+		if to.After(from) {
+			u.hours += to.Sub(from).Hours()
+		}
+		u.checkouts++
+		u.users[strings.ToLower(username)] = true
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	label := start.Format(ChargebackMonth)
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM chargeback_hours WHERE month = ?`), label); err != nil {
+		return err
+	}
+	now := s.clock.Now()
+	for k, u := range byKey {
+		query := `
+			INSERT INTO chargeback_hours (month, cost_center, server_hostname, feature_name, checkout_hours, checkouts, users, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`
+		hours := float64(int(u.hours*100+0.5)) / 100
+		_, err := tx.ExecContext(ctx, tx.Rebind(query), label, k.costCenter, k.server, k.feature, hours, u.checkouts, len(u.users), now)
+		if err != nil {
+			return fmt.Errorf("failed to store chargeback hours: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ErrInvalidChargebackPeriod is returned for report periods that are not
+// YYYY-MM months in order
+var ErrInvalidChargebackPeriod = errors.New("invalid chargeback period")
+
+// GetChargeback returns the chargeback hours of the months from to to
+// (YYYY-MM, inclusive), optionally of one cost center, by month and cost
+// center with the most used features first
+func (s *StorageService) GetChargeback(ctx context.Context, from, to, costCenter string) ([]models.ChargebackEntry, error) {
+	first, err := time.Parse(ChargebackMonth, from)
+	if err != nil {
+		return nil, fmt.Errorf("%w: from must be a month such as 2026-01", ErrInvalidChargebackPeriod)
+	}
+	last, err := time.Parse(ChargebackMonth, to)
+	if err != nil {
+		return nil, fmt.Errorf("%w: to must be a month such as 2026-01", ErrInvalidChargebackPeriod)
+	}
+	if last.Before(first) {
+		return nil, fmt.Errorf("%w: to is before from", ErrInvalidChargebackPeriod)
+	}
+
+	entries := []models.ChargebackEntry{}
+	query := `SELECT month, cost_center, server_hostname, feature_name, checkout_hours, checkouts, users, updated_at FROM chargeback_hours WHERE month >= ? AND month <= ?`
+	args := []interface{}{from, to}
+	if costCenter != "" {
+		query += ` AND cost_center = ?`
+		args = append(args, costCenter)
+	}
+	query += ` ORDER BY month, cost_center, checkout_hours DESC, server_hostname, feature_name`
+	err = s.db.SelectContext(ctx, &entries, s.db.Rebind(query), args...)
+	return entries, err
+}
+
+// ChargebackTotals sums chargeback hours per cost center, the largest first
+func ChargebackTotals(entries []models.ChargebackEntry) []models.CostCenterTotal {
+	hours := make(map[string]float64)
+	var all float64
+	for _, e := range entries {
+		hours[e.CostCenter] += e.CheckoutHours
+		all += e.CheckoutHours
+	}
+
+	totals := make([]models.CostCenterTotal, 0, len(hours))
+	for cc, h := range hours {
+		total := models.CostCenterTotal{CostCenter: cc, CheckoutHours: float64(int(h*100+0.5)) / 100}
+		if all > 0 {
+			total.SharePct = float64(int(h/all*10000+0.5)) / 100
+		}
+		totals = append(totals, total)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].CheckoutHours != totals[j].CheckoutHours {
+			return totals[i].CheckoutHours > totals[j].CheckoutHours
+		}
+		return totals[i].CostCenter < totals[j].CostCenter
+	})
+	return totals
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"licet/internal/clock"
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestParseLDAPMembers(t *testing.T) {
+	ldif := []byte("dn: cn=eng,ou=groups,dc=example,dc=com\n" +
+		"memberUid: alice\n" +
+		"memberuid: Bob\n" +
+		"memberUid:: Y2Fyb2w=\n" +
+		"\n" +
+		"dn: cn=ops,ou=groups,dc=example,dc=com\n" +
+		"member: uid=dave,ou=people,\n" +
+		" dc=example,dc=com\n")
+
+	if got := parseLDAPMembers(ldif, "memberUid"); !slices.Equal(got, []string{"alice", "Bob", "carol"}) {
+		t.Errorf("Unexpected memberUid values: %v", got)
+	}
+	if got := parseLDAPMembers(ldif, "member"); !slices.Equal(got, []string{"dave"}) {
+		t.Errorf("Unexpected member values: %v", got)
+	}
+	if got := ldapEscape(`eng*(a)\`); got != `eng\2a\28a\29\5c` {
+		t.Errorf("ldapEscape() = %s", got)
+	}
+}
+
+func TestChargeback(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+	storage.SetClock(clock.NewFixed(now))
+
+	for _, c := range []struct {
+		feature, user string
+		out, in       time.Time
+	}{
+		// Spans the month boundary: 4 hours in February, 2 in March
+		{"catia", "alice", time.Date(2026, 2, 28, 20, 0, 0, 0, time.Local), time.Date(2026, 3, 1, 2, 0, 0, 0, time.Local)},
+		{"catia", "Bob", time.Date(2026, 3, 2, 8, 0, 0, 0, time.Local), time.Date(2026, 3, 2, 11, 0, 0, 0, time.Local)},
+		{"catia", "carol", time.Date(2026, 3, 3, 8, 0, 0, 0, time.Local), time.Date(2026, 3, 3, 9, 0, 0, 0, time.Local)},
+		{"matlab", "erin", time.Date(2026, 3, 4, 8, 0, 0, 0, time.Local), time.Date(2026, 3, 4, 13, 0, 0, 0, time.Local)},
+	} {
+		_, err := db.Exec(`INSERT INTO license_checkouts (server_hostname, feature_name, username, host, checked_out_at, last_seen, checked_in_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			"27000@a", c.feature, c.user, "ws1", c.out, c.in, c.in)
+		if err != nil {
+			t.Fatalf("Failed to insert checkout: %v", err)
+		}
+	}
+
+	cfg := &config.Config{Chargeback: config.ChargebackConfig{
+		Enabled:           true,
+		Schedule:          "0 4 * * *",
+		DefaultCostCenter: "unassigned",
+		CostCenters: []config.CostCenter{
+			{Name: "Engineering", Users: []string{"ALICE"}, LDAPGroups: []string{"eng"}},
+			{Name: "Research", LDAPGroups: []string{"research"}},
+		},
+	}}
+	chargeback, err := NewChargebackService(cfg, storage)
+	if err != nil {
+		t.Fatalf("NewChargebackService failed: %v", err)
+	}
+	chargeback.members = func(ctx context.Context, group string) ([]string, error) {
+		return map[string][]string{"eng": {"bob"}, "research": {"bob", "carol"}}[group], nil
+	}
+	if err := chargeback.Aggregate(ctx); err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+
+	february, err := storage.GetChargeback(ctx, "2026-02", "2026-02", "")
+	if err != nil || len(february) != 1 || february[0].CheckoutHours != 4 || february[0].CostCenter != "Engineering" {
+		t.Fatalf("Expected alice's 4 hours of February, got %+v, %v", february, err)
+	}
+	march, err := storage.GetChargeback(ctx, "2026-03", "2026-03", "")
+	if err != nil {
+		t.Fatalf("GetChargeback failed: %v", err)
+	}
+	want := []struct {
+		costCenter, feature string
+		hours               float64
+		users               int
+	}{
+		{"Engineering", "catia", 5, 2}, // Alice's 2 hours and Bob's 3, Bob's first cost center
+		{"Research", "catia", 1, 1},
+		{"unassigned", "matlab", 5, 1},
+	}
+	if len(march) != len(want) {
+		t.Fatalf("Expected %d entries for March, got %+v", len(want), march)
+	}
+	for i, w := range want {
+		e := march[i]
+		if e.CostCenter != w.costCenter || e.FeatureName != w.feature || e.CheckoutHours != w.hours || e.Users != w.users {
+			t.Errorf("Entry %d = %+v, want %+v", i, e, w)
+		}
+	}
+
+	// Aggregating again replaces the month
+	if err := chargeback.Aggregate(ctx); err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+	if again, _ := storage.GetChargeback(ctx, "2026-02", "2026-03", "Engineering"); len(again) != 2 {
+		t.Errorf("Expected the Engineering entries of both months once, got %+v", again)
+	}
+
+	totals := ChargebackTotals(march)
+	if totals[0] != (models.CostCenterTotal{CostCenter: "Engineering", CheckoutHours: 5, SharePct: 45.45}) {
+		t.Errorf("Unexpected totals: %+v", totals)
+	}
+
+	for _, period := range [][2]string{{"2026-3", "2026-03"}, {"2026-03", "march"}, {"2026-03", "2026-02"}} {
+		if _, err := storage.GetChargeback(ctx, period[0], period[1], ""); !errors.Is(err, ErrInvalidChargebackPeriod) {
+			t.Errorf("Expected ErrInvalidChargebackPeriod for %v, got %v", period, err)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"licet/internal/models"
+)
+
+// openCheckout is a stored checkout that has not been returned yet
+type openCheckout struct {
+	ID           int64     `db:"id"`
+	FeatureName  string    `db:"feature_name"`
+	Username     string    `db:"username"`
+	Host         string    `db:"host"`
+	CheckedOutAt time.Time `db:"checked_out_at"`
+	matched      bool
+}
+
+// RecordCheckouts tracks the individual checkouts of a server from the users
+// seen by a collection at the given time. Checkouts still held are extended,
+// new ones are added, and open checkouts that are gone are closed at the last
+// collection that saw them.
+func (s *StorageService) RecordCheckouts(ctx context.Context, hostname string, users []models.LicenseUser, at time.Time) error {
+	at = at.Local()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var open []*openCheckout
+	query := `
+		SELECT id, feature_name, username, host, checked_out_at FROM license_checkouts
+		WHERE server_hostname = ? AND checked_in_at IS NULL
+	`
+	if err := tx.SelectContext(ctx, &open, tx.Rebind(query), hostname); err != nil {
+		return fmt.Errorf("failed to get open checkouts: %w", err)
+	}
+
+	// A user may hold several seats of a feature on one host; those are told
+	// apart by their checkout time when the parser reports one
+	byHolder := make(map[string][]*openCheckout)
+	for _, c := range open {
+		key := c.FeatureName + "|" + c.Username + "|" + c.Host
+		byHolder[key] = append(byHolder[key], c)
+	}
+
+	for _, user := range users {
+		if user.Username == "" {
+			continue
+		}
+		username := s.cipher.Encrypt(user.Username)
+		host := s.cipher.Encrypt(user.Host)
+		key := user.FeatureName + "|" + username + "|" + host
+
+		var match *openCheckout
+		for _, c := range byHolder[key] {
+			if !c.matched && (user.CheckedOutAt.IsZero() || c.CheckedOutAt.Equal(user.CheckedOutAt)) {
+				match = c
+				break
+			}
+		}
+
+		if match != nil {
+			match.matched = true
+			query := `UPDATE license_checkouts SET last_seen = ? WHERE id = ?`
+			if _, err := tx.ExecContext(ctx, tx.Rebind(query), at, match.ID); err != nil {
+				return fmt.Errorf("failed to update checkout: %w", err)
+			}
+			continue
+		}
+
+		checkedOut := user.CheckedOutAt
+		if checkedOut.IsZero() || checkedOut.After(at) {
+			checkedOut = at
+		}
+		query := `
+			INSERT INTO license_checkouts (server_hostname, feature_name, username, host, checked_out_at, last_seen)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`
+		_, err := tx.ExecContext(ctx, tx.Rebind(query), hostname, user.FeatureName, username, host, checkedOut.Local(), at)
+		if err != nil {
+			return fmt.Errorf("failed to record checkout: %w", err)
+		}
+	}
+
+	for _, c := range open {
+		if c.matched {
+			continue
+		}
+		query := `UPDATE license_checkouts SET checked_in_at = last_seen WHERE id = ?`
+		if _, err := tx.ExecContext(ctx, tx.Rebind(query), c.ID); err != nil {
+			return fmt.Errorf("failed to close checkout: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// checkoutRow is a stored checkout as listed or aggregated into usage summaries
+type checkoutRow struct {
+	ServerHostname string     `db:"server_hostname"`
+	FeatureName    string     `db:"feature_name"`
+	Username       string     `db:"username"`
+	Host           string     `db:"host"`
+	CheckedOutAt   time.Time  `db:"checked_out_at"`
+	LastSeen       time.Time  `db:"last_seen"`
+	CheckedInAt    *time.Time `db:"checked_in_at"`
+}
+
+// GetUserUsage summarizes a user's checkouts per server and feature over the
+// last days, most used first
+func (s *StorageService) GetUserUsage(ctx context.Context, username string, days int) ([]models.UserUsage, error) {
+	var rows []checkoutRow
+	query := `
+		SELECT server_hostname, feature_name, username, checked_out_at, last_seen, checked_in_at
+		FROM license_checkouts
+		WHERE username = ? AND last_seen >= ?
+	`
+	since := time.Now().AddDate(0, 0, -days).Local()
+	if err := s.db.SelectContext(ctx, &rows, s.db.Rebind(query), s.cipher.Encrypt(username), since); err != nil {
+		return nil, err
+	}
+
+	usage := summarizeCheckouts(rows, since, func(r checkoutRow) string { return r.ServerHostname + "|" + r.FeatureName })
+	for i := range usage {
+		usage[i].Username = username
+	}
+	return usage, nil
+}
+
+// GetTopUsers summarizes the checkouts of a feature per user over the last
+// days, optionally on one server only, and returns the users with the most
+// checkout hours
+func (s *StorageService) GetTopUsers(ctx context.Context, feature, hostname string, days, limit int) ([]models.UserUsage, error) {
+	var rows []checkoutRow
+	query := `
+		SELECT server_hostname, feature_name, username, checked_out_at, last_seen, checked_in_at
+		FROM license_checkouts
+		WHERE feature_name = ? AND last_seen >= ?
+	`
+	since := time.Now().AddDate(0, 0, -days).Local()
+	args := []interface{}{feature, since}
+	if hostname != "" {
+		query += ` AND server_hostname = ?`
+		args = append(args, hostname)
+	}
+	if err := s.db.SelectContext(ctx, &rows, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	usage := summarizeCheckouts(rows, since, func(r checkoutRow) string { return r.Username })
+	for i := range usage {
+		usage[i].ServerHostname = hostname
+		usage[i].FeatureName = feature
+		if name, err := s.cipher.Decrypt(usage[i].Username); err == nil {
+			usage[i].Username = name
+		}
+	}
+	if limit > 0 && len(usage) > limit {
+		usage = usage[:limit]
+	}
+	return usage, nil
+}
+
+// summarizeCheckouts adds up checkouts grouped by key, counting only the time
+// after since. Checkouts still open last until their last sighting.
+func summarizeCheckouts(rows []checkoutRow, since time.Time, key func(checkoutRow) string) []models.UserUsage {
+	groups := make(map[string]*models.UserUsage)
+	var order []string
+	for _, r := range rows {
+		k := key(r)
+		u := groups[k]
+		if u == nil {
+			u = &models.UserUsage{ServerHostname: r.ServerHostname, FeatureName: r.FeatureName, Username: r.Username}
+			groups[k] = u
+			order = append(order, k)
+		}
+
+		start, end := r.CheckedOutAt, r.LastSeen
+		if r.CheckedInAt != nil {
+			end = *r.CheckedInAt
+		} else {
+			u.Active = true
+		}
+		if start.Before(since) {
+			start = since
+		}
+		if end.After(start) {
+			u.TotalHours += end.Sub(start).Hours()
+		}
+		u.Checkouts++
+		if r.LastSeen.After(u.LastSeen) {
+			u.LastSeen = r.LastSeen
+		}
+	}
+
+	usage := make([]models.UserUsage, 0, len(order))
+	for _, k := range order {
+		u := groups[k]
+		u.TotalHours = float64(int(u.TotalHours*100+0.5)) / 100
+		usage = append(usage, *u)
+	}
+	sort.SliceStable(usage, func(i, j int) bool {
+		if usage[i].TotalHours != usage[j].TotalHours {
+			return usage[i].TotalHours > usage[j].TotalHours
+		}
+		return usage[i].Checkouts > usage[j].Checkouts
+	})
+	return usage
+}
+
+// GetOpenCheckouts returns the checkouts still held, optionally on one server
+// only, that had been held for at least minHours when last seen, longest
+// first. Long held seats are candidates for reclamation.
+func (s *StorageService) GetOpenCheckouts(ctx context.Context, hostname string, minHours float64) ([]models.Checkout, error) {
+	var rows []checkoutRow
+	query := `
+		SELECT server_hostname, feature_name, username, host, checked_out_at, last_seen, checked_in_at
+		FROM license_checkouts
+		WHERE checked_in_at IS NULL
+	`
+	var args []interface{}
+	if hostname != "" {
+		query += ` AND server_hostname = ?`
+		args = append(args, hostname)
+	}
+	if err := s.db.SelectContext(ctx, &rows, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	checkouts := make([]models.Checkout, 0)
+	for _, r := range rows {
+		c := r.checkout()
+		if c.HeldHours < minHours {
+			continue
+		}
+		if name, err := s.cipher.Decrypt(c.Username); err == nil {
+			c.Username = name
+		}
+		if host, err := s.cipher.Decrypt(c.Host); err == nil {
+			c.Host = host
+		}
+		checkouts = append(checkouts, c)
+	}
+	sort.SliceStable(checkouts, func(i, j int) bool { return checkouts[i].HeldHours > checkouts[j].HeldHours })
+	return checkouts, nil
+}
+
+// checkout returns the session of a stored checkout. Sessions are only known
+// to the resolution of the collection interval, so they end at the check-in
+// or, while still held, at the last collection that saw them.
+func (r checkoutRow) checkout() models.Checkout {
+	end := r.LastSeen
+	if r.CheckedInAt != nil {
+		end = *r.CheckedInAt
+	}
+	return models.Checkout{
+		ServerHostname: r.ServerHostname,
+		FeatureName:    r.FeatureName,
+		Username:       r.Username,
+		Host:           r.Host,
+		CheckedOutAt:   r.CheckedOutAt,
+		LastSeen:       r.LastSeen,
+		CheckedInAt:    r.CheckedInAt,
+		HeldHours:      float64(int(end.Sub(r.CheckedOutAt).Hours()*100+0.5)) / 100,
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"licet/internal/models"
+)
+
+func TestRecordCheckouts(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	start := time.Now().Add(-3 * time.Hour).Truncate(time.Minute)
+	alice := models.LicenseUser{ServerHostname: "27000@a", FeatureName: "solver", Username: "alice", Host: "ws1", CheckedOutAt: start}
+	alice2 := models.LicenseUser{ServerHostname: "27000@a", FeatureName: "solver", Username: "alice", Host: "ws1", CheckedOutAt: start.Add(30 * time.Minute)}
+	bob := models.LicenseUser{ServerHostname: "27000@a", FeatureName: "solver", Username: "bob", Host: "ws2"}
+
+	polls := []struct {
+		at    time.Time
+		users []models.LicenseUser
+	}{
+		{start.Add(5 * time.Minute), []models.LicenseUser{alice, bob}},
+		{start.Add(35 * time.Minute), []models.LicenseUser{alice, alice2, bob}},
+		{start.Add(65 * time.Minute), []models.LicenseUser{alice2}},       // alice returns her first seat, bob leaves
+		{start.Add(125 * time.Minute), []models.LicenseUser{alice2, bob}}, // bob checks out again
+	}
+	for _, p := range polls {
+		if err := storage.RecordCheckouts(ctx, "27000@a", p.users, p.at); err != nil {
+			t.Fatalf("RecordCheckouts failed: %v", err)
+		}
+	}
+
+	var count int
+	db.Get(&count, `SELECT COUNT(*) FROM license_checkouts`)
+	if count != 4 {
+		t.Fatalf("Expected 4 checkouts (2 for alice, 2 for bob), got %d", count)
+	}
+
+	usage, err := storage.GetUserUsage(ctx, "alice", 30)
+	if err != nil {
+		t.Fatalf("GetUserUsage failed: %v", err)
+	}
+	if len(usage) != 1 || usage[0].Checkouts != 2 || !usage[0].Active {
+		t.Fatalf("Unexpected usage for alice: %+v", usage)
+	}
+	// First seat: checked out at start, last seen at +35m; second: +30m to +125m
+	if want := (35.0 + 95.0) / 60; abs(usage[0].TotalHours-want) > 0.01 {
+		t.Errorf("Expected %.2f hours, got %.2f", want, usage[0].TotalHours)
+	}
+
+	top, err := storage.GetTopUsers(ctx, "solver", "", 30, 10)
+	if err != nil {
+		t.Fatalf("GetTopUsers failed: %v", err)
+	}
+	if len(top) != 2 || top[0].Username != "alice" || top[1].Username != "bob" || top[1].Checkouts != 2 {
+		t.Fatalf("Unexpected top users %+v", top)
+	}
+	// bob has no checkout time: +5m to +35m, then open since +125m
+	if want := 0.5; abs(top[1].TotalHours-want) > 0.01 {
+		t.Errorf("Expected %.2f hours for bob, got %.2f", want, top[1].TotalHours)
+	}
+
+	if limited, _ := storage.GetTopUsers(ctx, "solver", "", 30, 1); len(limited) != 1 {
+		t.Errorf("Expected the limit to apply, got %d users", len(limited))
+	}
+}
+
+func TestGetOpenCheckouts(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	start := time.Now().Add(-48 * time.Hour).Truncate(time.Minute)
+	alice := models.LicenseUser{ServerHostname: "27000@a", FeatureName: "solver", Username: "alice", Host: "ws1", CheckedOutAt: start}
+	bob := models.LicenseUser{ServerHostname: "27000@a", FeatureName: "solver", Username: "bob", Host: "ws2", CheckedOutAt: start.Add(40 * time.Hour)}
+	carol := models.LicenseUser{ServerHostname: "27000@a", FeatureName: "mesher", Username: "carol", Host: "ws3", CheckedOutAt: start}
+
+	if err := storage.RecordCheckouts(ctx, "27000@a", []models.LicenseUser{alice, carol}, start.Add(time.Hour)); err != nil {
+		t.Fatalf("RecordCheckouts failed: %v", err)
+	}
+	// carol returns her seat; alice has held hers for 46 hours when last seen
+	if err := storage.RecordCheckouts(ctx, "27000@a", []models.LicenseUser{alice, bob}, start.Add(46*time.Hour)); err != nil {
+		t.Fatalf("RecordCheckouts failed: %v", err)
+	}
+
+	long, err := storage.GetOpenCheckouts(ctx, "", 24)
+	if err != nil {
+		t.Fatalf("GetOpenCheckouts failed: %v", err)
+	}
+	if len(long) != 1 || long[0].Username != "alice" || long[0].Host != "ws1" || long[0].HeldHours != 46 {
+		t.Fatalf("Expected alice's 46 hour checkout, got %+v", long)
+	}
+
+	open, err := storage.GetOpenCheckouts(ctx, "27000@a", 0)
+	if err != nil {
+		t.Fatalf("GetOpenCheckouts failed: %v", err)
+	}
+	if len(open) != 2 || open[1].Username != "bob" || open[1].HeldHours != 6 {
+		t.Fatalf("Expected alice's and bob's open checkouts, longest first, got %+v", open)
+	}
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
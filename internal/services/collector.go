@@ -2,8 +2,13 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -12,36 +17,135 @@ import (
 	"licet/internal/models"
 )
 
+// PollResult is the outcome of the last collection from a license server
+type PollResult struct {
+	Type     string
+	Up       bool
+	Duration time.Duration
+	At       time.Time
+	TimedOut bool   // The server did not answer within its query timeout
+	Error    string // Why the query failed, if it did
+	TraceID  string // Trace of the collection, when tracing is enabled
+
+	// Polls of the server since startup and the time they took in all
+	Polls         int64
+	TotalDuration time.Duration
+}
+
+// defaultCollectionWorkers is the number of servers queried at once when
+// collection.workers is unset
+const defaultCollectionWorkers = 5
+
 type CollectorService struct {
 	db      *sqlx.DB
 	cfg     *config.Config
 	query   *QueryService
 	storage *StorageService
+	bus     *EventBus
+	metrics *ComputedMetricService
+	tracer  *Tracer
+
+	// Cancelled by Stop, abandoning queries in flight
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lastSuccess atomic.Int64 // Unix nanoseconds of the last successful collection
+
+	// Set by the router once the response cache exists, possibly while a
+	// collection is running
+	cache atomic.Pointer[cacheInvalidation]
+
+	pollMu sync.RWMutex
+	polls  map[string]PollResult // By server hostname
 }
 
 func NewCollectorService(db *sqlx.DB, cfg *config.Config, query *QueryService, storage *StorageService) *CollectorService {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &CollectorService{
 		db:      db,
 		cfg:     cfg,
 		query:   query,
 		storage: storage,
+		ctx:     ctx,
+		cancel:  cancel,
+		polls:   make(map[string]PollResult),
 	}
 }
 
+// SetComputedMetrics recomputes the computed metrics after each collection
+func (s *CollectorService) SetComputedMetrics(metrics *ComputedMetricService) {
+	s.metrics = metrics
+}
+
+// SetTracer traces each collection cycle, with a span for each server
+func (s *CollectorService) SetTracer(tracer *Tracer) {
+	s.tracer = tracer
+}
+
+// Stop cancels the queries in flight and any collection started afterwards
+func (s *CollectorService) Stop() {
+	s.cancel()
+}
+
+// Workers returns the number of servers queried at once
+func (s *CollectorService) Workers() int {
+	if s.cfg.Collection.Workers > 0 {
+		return s.cfg.Collection.Workers
+	}
+	return defaultCollectionWorkers
+}
+
+// CacheInvalidator drops cached API responses, e.g. a *middleware.Cache
+type CacheInvalidator interface {
+	InvalidatePrefix(prefix string)
+}
+
+// cacheInvalidation is a cache and the API paths a collection makes stale
+type cacheInvalidation struct {
+	cache CacheInvalidator
+	paths []string
+}
+
+// SetCache drops the cached responses under paths after each collection so
+// fresh data is served immediately
+func (s *CollectorService) SetCache(cache CacheInvalidator, paths ...string) {
+	s.cache.Store(&cacheInvalidation{cache: cache, paths: paths})
+}
+
+// SetEventBus publishes a CollectionEvent on the bus after each successful
+// collection of a server
+func (s *CollectorService) SetEventBus(bus *EventBus) {
+	s.bus = bus
+}
+
+// alerts returns an alert service publishing on the event bus of the collector
+func (s *CollectorService) alerts() *AlertService {
+	alerts := NewAlertService(s.db, s.cfg)
+	alerts.SetEventBus(s.bus)
+	return alerts
+}
+
+// CollectAll collects every server polled at the global collection interval.
+// Servers with their own poll interval or schedule are left to CollectOnSchedule.
 func (s *CollectorService) CollectAll() error {
 	log.Info("Starting license data collection")
 
-	servers, err := s.query.GetAllServers()
+	configured, err := s.query.GetAllServers()
 	if err != nil {
 		return fmt.Errorf("failed to get servers: %w", err)
 	}
+	cycle := s.tracer.StartSpan(nil, "collection")
+	defer func() { cycle.Finish(s.ctx.Err()) }()
+	var servers []models.LicenseServer
+	for _, server := range configured {
+		if PollSchedule(server) == "" {
+			servers = append(servers, server)
+		}
+	}
 
 	// Use parallel collection with worker pool
 	// Limit concurrent queries to avoid overwhelming license servers
-	maxWorkers := 5
-	if len(servers) < maxWorkers {
-		maxWorkers = len(servers)
-	}
+	maxWorkers := min(s.Workers(), len(servers))
 
 	var wg sync.WaitGroup
 	serverChan := make(chan models.LicenseServer, len(servers))
@@ -53,7 +157,10 @@ func (s *CollectorService) CollectAll() error {
 		go func() {
 			defer wg.Done()
 			for server := range serverChan {
-				if err := s.CollectServer(server); err != nil {
+				if s.ctx.Err() != nil {
+					continue // Stopped; drain the servers left
+				}
+				if err := s.collectServer(server, cycle); err != nil {
 					log.Errorf("Failed to collect data for %s: %v", server.Hostname, err)
 					errorChan <- err
 				}
@@ -61,9 +168,23 @@ func (s *CollectorService) CollectAll() error {
 		}()
 	}
 
-	// Send servers to workers
-	for _, server := range servers {
-		serverChan <- server
+	// Send servers to workers, each once its jitter has passed, so delayed
+	// servers do not hold up a worker
+	type delayed struct {
+		server models.LicenseServer
+		delay  time.Duration
+	}
+	queue := make([]delayed, len(servers))
+	for i, server := range servers {
+		queue[i] = delayed{server, jitterDelay(server)}
+	}
+	sort.SliceStable(queue, func(i, j int) bool { return queue[i].delay < queue[j].delay })
+	start := time.Now()
+	for _, d := range queue {
+		if !s.sleep(time.Until(start.Add(d.delay))) {
+			break
+		}
+		serverChan <- d.server
 	}
 	close(serverChan)
 
@@ -71,31 +192,319 @@ func (s *CollectorService) CollectAll() error {
 	wg.Wait()
 	close(errorChan)
 
+	if err := s.ctx.Err(); err != nil {
+		log.Info("License data collection cancelled")
+		return err
+	}
+
 	// Check if any errors occurred
 	errorCount := len(errorChan)
 	if errorCount > 0 {
 		log.Warnf("Collection completed with %d errors", errorCount)
 	}
+	cycle.SetAttribute("servers", fmt.Sprint(len(servers)))
+	cycle.SetAttribute("errors", fmt.Sprint(errorCount))
+
+	// A run counts as successful if at least one server could be collected
+	if len(servers) == 0 || errorCount < len(servers) {
+		s.lastSuccess.Store(time.Now().UnixNano())
+		s.collected()
+	}
 
 	log.Info("License data collection completed")
 	return nil
 }
 
+// Servers returns the configured servers with their polling schedules
+func (s *CollectorService) Servers() ([]models.LicenseServer, error) {
+	return s.query.GetAllServers()
+}
+
+// CollectOnSchedule collects a server polled on its own schedule, after its
+// jitter
+func (s *CollectorService) CollectOnSchedule(server models.LicenseServer) error {
+	if !s.sleep(jitterDelay(server)) {
+		return s.ctx.Err()
+	}
+	return s.CollectNow(server)
+}
+
+// CollectNow collects one server outside of the collection cycle and
+// recomputes the computed metrics
+func (s *CollectorService) CollectNow(server models.LicenseServer) error {
+	if err := s.CollectServer(server); err != nil {
+		return err
+	}
+	s.lastSuccess.Store(time.Now().UnixNano())
+	s.collected()
+	return nil
+}
+
+// PollSchedule returns the cron spec of a server polled on its own schedule,
+// or "" for servers polled at the global collection interval
+func PollSchedule(server models.LicenseServer) string {
+	if server.Schedule != "" {
+		return server.Schedule
+	}
+	if server.PollInterval > 0 {
+		return fmt.Sprintf("@every %dm", server.PollInterval)
+	}
+	return ""
+}
+
+// jitterDelay returns a random delay up to the jitter of a server, spreading
+// the polls of servers that share a schedule
+func jitterDelay(server models.LicenseServer) time.Duration {
+	if server.Jitter <= 0 {
+		return 0
+	}
+	return rand.N(time.Duration(server.Jitter) * time.Second)
+}
+
+// collected records the computed metrics from the features just collected
+// and drops the cached responses they make stale
+func (s *CollectorService) collected() {
+	if err := s.metrics.Compute(s.ctx); err != nil {
+		log.Errorf("Failed to compute metrics: %v", err)
+	}
+	if c := s.cache.Load(); c != nil {
+		for _, path := range c.paths {
+			c.cache.InvalidatePrefix(path)
+		}
+	}
+}
+
+// sleep waits for d, returning false if the collector is stopped first
+func (s *CollectorService) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return s.ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+// LastSuccess returns when a collection run last succeeded, or the zero
+// time if none has succeeded yet
+func (s *CollectorService) LastSuccess() time.Time {
+	if ns := s.lastSuccess.Load(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
+
+// CollectServer collects one server, in a trace of its own
 func (s *CollectorService) CollectServer(server models.LicenseServer) error {
+	return s.collectServer(server, nil)
+}
+
+// collectServer collects one server in a span of the trace of cycle
+func (s *CollectorService) collectServer(server models.LicenseServer, cycle *Span) (err error) {
 	log.Debugf("Collecting data for %s (%s)", server.Hostname, server.Type)
+	span := s.tracer.StartSpan(cycle, "collect "+server.Hostname, "server", server.Hostname, "type", server.Type)
+	defer func() { span.Finish(err) }()
 
-	result, err := s.query.QueryServer(server.Hostname, server.Type)
+	start := time.Now()
+	result, err := s.query.QueryServerContext(s.ctx, server.Hostname, server.Type)
+	s.recordPoll(server, err == nil && result.Status.Service == "up", start, err, span.TraceID())
 	if err != nil {
 		log.Errorf("Query failed for %s: %v", server.Hostname, err)
+		down := models.ServerQueryResult{
+			Status: models.ServerStatus{Hostname: server.Hostname, Service: "down", Message: err.Error(), LastChecked: start},
+		}
+		s.bus.Publish(CollectionsTopic, CollectionEvent{
+			Server: server.Hostname, Type: server.Type, Result: down, CollectedAt: start, Error: err.Error(),
+		})
 		return fmt.Errorf("query failed for %s: %w", server.Hostname, err)
 	}
 
 	log.Infof("Collected %d features and %d users from %s",
 		len(result.Features), len(result.Users), server.Hostname)
+	span.SetAttribute("features", fmt.Sprint(len(result.Features)))
+	span.SetAttribute("users", fmt.Sprint(len(result.Users)))
+
+	if result.Status.Service == "up" && result.Status.Master != "" {
+		s.recordMaster(server.Hostname, result.Status.Master)
+	}
+
+	s.checkParseQuality(server.Hostname, result.Features)
 
+	if s.cfg.Alerts.Queued {
+		s.checkQueued(server.Hostname, result.Features)
+	}
+
+	if s.cfg.Alerts.Utilization {
+		if err := s.alerts().CheckUtilization(context.Background(), server.Hostname, result.Features); err != nil {
+			log.Errorf("Failed to check utilization of %s: %v", server.Hostname, err)
+		}
+	}
+
+	if s.cfg.Waitlist.Enabled {
+		if err := s.alerts().CheckWaitlist(context.Background(), server.Hostname, result.Features); err != nil {
+			log.Errorf("Failed to check the waitlist of %s: %v", server.Hostname, err)
+		}
+	}
+
+	if _, err := s.storage.UpdateFeatureTrends(context.Background(), server.Hostname, DefaultTrendDays, s.storage.clock.Now()); err != nil {
+		log.Errorf("Failed to update feature trends for %s: %v", server.Hostname, err)
+	}
+
+	event := CollectionEvent{Server: server.Hostname, Type: server.Type, Result: result, CollectedAt: start}
+	s.bus.Publish(CollectionTopic(server.Hostname), event)
+	s.bus.Publish(CollectionsTopic, event)
 	return nil
 }
 
+// recordPoll remembers the outcome and duration of a collection
+// and notifies webhooks when the server went up or down
+func (s *CollectorService) recordPoll(server models.LicenseServer, up bool, start time.Time, err error, traceID string) {
+	poll := PollResult{Type: server.Type, Up: up, Duration: time.Since(start), At: start, TraceID: traceID}
+	if err != nil {
+		poll.TimedOut = errors.Is(err, ErrQueryTimeout)
+		poll.Error = err.Error()
+	}
+
+	s.pollMu.Lock()
+	previous, polled := s.polls[server.Hostname]
+	poll.Polls = previous.Polls + 1
+	poll.TotalDuration = previous.TotalDuration + poll.Duration
+	s.polls[server.Hostname] = poll
+	s.pollMu.Unlock()
+
+	if !polled || previous.Up == up {
+		return
+	}
+	if webhooks := NewWebhookService(s.db, s.cfg); webhooks.Enabled() {
+		change := ServerStatusChange{
+			Server:         server.Hostname,
+			Type:           server.Type,
+			Status:         pollStatus(up),
+			PreviousStatus: pollStatus(previous.Up),
+		}
+		go webhooks.Notify(context.Background(), WebhookEventServerStatus, change)
+	}
+}
+
+func pollStatus(up bool) string {
+	if up {
+		return "up"
+	}
+	return "down"
+}
+
+// PollResults returns the last collection outcome of each server polled so far
+func (s *CollectorService) PollResults() map[string]PollResult {
+	s.pollMu.RLock()
+	defer s.pollMu.RUnlock()
+	polls := make(map[string]PollResult, len(s.polls))
+	for hostname, p := range s.polls {
+		polls[hostname] = p
+	}
+	return polls
+}
+
+// recordMaster tracks the MASTER host of a server and raises an optional
+// alert when it moves to another host of a redundant triad
+func (s *CollectorService) recordMaster(hostname, master string) {
+	ctx := context.Background()
+	change, err := s.storage.RecordMaster(ctx, hostname, master, s.storage.clock.Now())
+	if err != nil {
+		log.Errorf("Failed to record master for %s: %v", hostname, err)
+		return
+	}
+	if change == nil {
+		return
+	}
+
+	log.Warnf("License server %s failed over from %s to %s", hostname, change.PreviousMaster, change.NewMaster)
+
+	if !s.cfg.Alerts.Failover {
+		return
+	}
+	alert := &models.Alert{
+		ServerHostname: hostname,
+		AlertType:      "failover",
+		Message: fmt.Sprintf("License server %s failed over: MASTER moved from %s to %s",
+			hostname, change.PreviousMaster, change.NewMaster),
+		Severity: "warning",
+	}
+	if err := s.alerts().CreateAlert(ctx, alert); err != nil {
+		log.Errorf("Failed to create failover alert: %v", err)
+	}
+}
+
+// checkParseQuality raises a warning alert when a server reports features
+// whose expiration date could not be parsed. Those features are stored as
+// permanent, so expiration alerts would never fire for them.
+func (s *CollectorService) checkParseQuality(hostname string, features []models.Feature) {
+	var degraded []string
+	for _, f := range features {
+		if f.ParseQuality == models.ParseQualityDefaultedExpiration {
+			degraded = append(degraded, f.Name)
+		}
+	}
+	if len(degraded) == 0 {
+		return
+	}
+
+	alertService := s.alerts()
+	if alertService.CheckThrottle(hostname, "parse_quality") {
+		return
+	}
+	alert := &models.Alert{
+		ServerHostname: hostname,
+		AlertType:      "parse_quality",
+		Message: fmt.Sprintf("Could not parse the expiration date of %d feature(s) on %s (%s); expiration alerts cannot fire for them",
+			len(degraded), hostname, strings.Join(degraded, ", ")),
+		Severity: "warning",
+	}
+	if err := alertService.CreateAlert(context.Background(), alert); err != nil {
+		log.Errorf("Failed to create parse quality alert: %v", err)
+	}
+}
+
+// checkQueued raises a warning alert when checkout requests of a feature wait
+// in the license queue, meaning users are blocked until a seat frees up
+func (s *CollectorService) checkQueued(hostname string, features []models.Feature) {
+	queued := make(map[string]int)
+	var names []string
+	for _, f := range features {
+		if _, seen := queued[f.Name]; !seen {
+			names = append(names, f.Name)
+		}
+		queued[f.Name] += f.QueuedRequests
+	}
+	var waiting []string
+	for _, name := range names {
+		if queued[name] > 0 && queued[name] >= s.cfg.Alerts.QueuedThreshold {
+			waiting = append(waiting, fmt.Sprintf("%s: %d", name, queued[name]))
+		}
+	}
+	if len(waiting) == 0 {
+		return
+	}
+
+	alertService := s.alerts()
+	if alertService.CheckThrottle(hostname, "queued") {
+		return
+	}
+	alert := &models.Alert{
+		ServerHostname: hostname,
+		AlertType:      "queued",
+		Message: fmt.Sprintf("Checkout requests are queued for %d feature(s) on %s (%s)",
+			len(waiting), hostname, strings.Join(waiting, ", ")),
+		Severity: "warning",
+	}
+	if err := alertService.CreateAlert(context.Background(), alert); err != nil {
+		log.Errorf("Failed to create queued requests alert: %v", err)
+	}
+}
+
 func (s *CollectorService) CheckExpirations() error {
 	log.Info("Checking for expiring licenses")
 
@@ -113,7 +522,7 @@ func (s *CollectorService) CheckExpirations() error {
 	log.Infof("Found %d expiring features", len(features))
 
 	// Create alerts for expiring licenses
-	alertService := NewAlertService(s.db, s.cfg)
+	alertService := s.alerts()
 
 	for _, feature := range features {
 		daysToExpire := int(time.Until(feature.ExpirationDate).Hours() / 24)
@@ -148,3 +557,54 @@ func (s *CollectorService) CheckExpirations() error {
 
 	return nil
 }
+
+// EvaluateAlertRules checks the configured alert rules against the current
+// features and checkouts and the last collection outcome of each server
+func (s *CollectorService) EvaluateAlertRules() error {
+	ctx := context.Background()
+	features, err := s.storage.GetActiveFeatures(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get active features: %w", err)
+	}
+	checkouts, err := s.storage.GetOpenCheckouts(ctx, "", 0)
+	if err != nil {
+		return fmt.Errorf("failed to get open checkouts: %w", err)
+	}
+	return s.alerts().EvaluateRules(ctx, features, checkouts, s.PollResults(), time.Now())
+}
+
+// DeduplicateFeatures merges near-duplicate feature rows left behind by
+// different parser paths
+func (s *CollectorService) DeduplicateFeatures() error {
+	merged, err := s.storage.MergeDuplicateFeatures(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to merge duplicate features: %w", err)
+	}
+	if merged > 0 {
+		log.Infof("Merged %d duplicate feature rows", merged)
+	}
+	return nil
+}
+
+// RollupUsage aggregates the raw usage of days older than rollup.after_days
+// into the hourly and daily rollups
+func (s *CollectorService) RollupUsage() error {
+	rolled, err := s.storage.RollupUsage(context.Background(), s.cfg.Rollup.AfterDays, s.storage.clock.Now())
+	if err != nil {
+		return fmt.Errorf("failed to roll up usage: %w", err)
+	}
+	if rolled > 0 {
+		log.Infof("Rolled up %d days of feature usage", rolled)
+	}
+	return nil
+}
+
+// SendUserDigest reports users who started or stopped using features during
+// the last day
+func (s *CollectorService) SendUserDigest() error {
+	digest, err := NewUserDigestService(s.db, s.cfg, s.storage)
+	if err != nil {
+		return err
+	}
+	return digest.SendDailyDigest(context.Background())
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/parsers"
+)
+
+func TestPollSchedule(t *testing.T) {
+	tests := []struct {
+		server models.LicenseServer
+		want   string
+	}{
+		{models.LicenseServer{Hostname: "27000@default"}, ""},
+		{models.LicenseServer{Hostname: "27000@heavy", PollInterval: 30}, "@every 30m"},
+		{models.LicenseServer{Hostname: "27000@critical", Schedule: "* * * * *"}, "* * * * *"},
+	}
+	for _, tt := range tests {
+		if got := PollSchedule(tt.server); got != tt.want {
+			t.Errorf("PollSchedule(%s) = %q, want %q", tt.server.Hostname, got, tt.want)
+		}
+	}
+}
+
+func TestJitterDelay(t *testing.T) {
+	if d := jitterDelay(models.LicenseServer{}); d != 0 {
+		t.Errorf("Expected no delay without jitter, got %s", d)
+	}
+	for i := 0; i < 100; i++ {
+		if d := jitterDelay(models.LicenseServer{Jitter: 2}); d < 0 || d >= 2*time.Second {
+			t.Fatalf("Expected a delay below 2s, got %s", d)
+		}
+	}
+}
+
+// newHangingCollector returns a collector whose lmutil never answers
+func newHangingCollector(t *testing.T, cfg *config.Config) *CollectorService {
+	t.Helper()
+	lmutil := filepath.Join(t.TempDir(), "lmutil")
+	if err := os.WriteFile(lmutil, []byte("#!/bin/sh\nexec sleep 10\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	query := &QueryService{cfg: cfg, parserFactory: parsers.NewParserFactory(map[string]string{"lmutil": lmutil})}
+	collector := NewCollectorService(nil, cfg, query, nil)
+	t.Cleanup(collector.Stop)
+	return collector
+}
+
+func TestCollectServer_Timeout(t *testing.T) {
+	cfg := &config.Config{Servers: []config.LicenseServer{{Hostname: "27000@slow", Type: "flexlm", Timeout: 1}}}
+	collector := newHangingCollector(t, cfg)
+
+	start := time.Now()
+	err := collector.CollectServer(models.LicenseServer{Hostname: "27000@slow", Type: "flexlm"})
+	if !errors.Is(err, ErrQueryTimeout) {
+		t.Fatalf("Expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the query to be abandoned after 1s, took %s", elapsed)
+	}
+
+	poll := collector.PollResults()["27000@slow"]
+	if !poll.TimedOut || poll.Up || poll.Error == "" || poll.Duration < time.Second {
+		t.Errorf("Unexpected poll result %+v", poll)
+	}
+}
+
+func TestCollectAll_Stop(t *testing.T) {
+	cfg := &config.Config{
+		Servers:    []config.LicenseServer{{Hostname: "27000@a", Type: "flexlm"}, {Hostname: "27000@b", Type: "flexlm"}},
+		Collection: config.CollectionConfig{Workers: 1},
+	}
+	collector := newHangingCollector(t, cfg)
+	if collector.Workers() != 1 {
+		t.Errorf("Expected 1 worker, got %d", collector.Workers())
+	}
+
+	time.AfterFunc(200*time.Millisecond, collector.Stop)
+	start := time.Now()
+	if err := collector.CollectAll(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the collection to be cancelled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the collection to stop promptly, took %s", elapsed)
+	}
+	if !collector.LastSuccess().IsZero() {
+		t.Error("Expected a cancelled collection not to count as successful")
+	}
+}
+
+// recordingInvalidator records the invalidated cache prefixes
+type recordingInvalidator struct{ prefixes []string }
+
+func (r *recordingInvalidator) InvalidatePrefix(prefix string) {
+	r.prefixes = append(r.prefixes, prefix)
+}
+
+func TestCollected_InvalidatesCache(t *testing.T) {
+	collector := NewCollectorService(nil, &config.Config{}, nil, nil)
+	defer collector.Stop()
+	collector.collected() // No cache set
+
+	cache := &recordingInvalidator{}
+	collector.SetCache(cache, "/api/v1/servers", "/api/v1/utilization")
+	collector.collected()
+	if len(cache.prefixes) != 2 || cache.prefixes[0] != "/api/v1/servers" || cache.prefixes[1] != "/api/v1/utilization" {
+		t.Errorf("Expected both paths to be invalidated, got %v", cache.prefixes)
+	}
+}
+
+func TestCheckQueued(t *testing.T) {
+	db := newTestDB(t)
+	cfg := &config.Config{Alerts: config.AlertConfig{Queued: true, QueuedThreshold: 2, ResendIntervalMin: 60}}
+	collector := NewCollectorService(db, cfg, nil, NewStorageService(db, "sqlite"))
+
+	collector.checkQueued("27000@a", []models.Feature{
+		{Name: "solver", QueuedRequests: 1},
+		{Name: "mesher", QueuedRequests: 1},
+	})
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM alerts WHERE alert_type = 'queued'"); err != nil {
+		t.Fatalf("Failed to count alerts: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("Expected no alert below the threshold, got %d", count)
+	}
+
+	// Pools of a feature queue together
+	collector.checkQueued("27000@a", []models.Feature{
+		{Name: "solver", QueuedRequests: 1},
+		{Name: "solver", QueuedRequests: 2},
+		{Name: "mesher", QueuedRequests: 1},
+	})
+	var messages []string
+	if err := db.Select(&messages, "SELECT message FROM alerts WHERE alert_type = 'queued'"); err != nil {
+		t.Fatalf("Failed to read alerts: %v", err)
+	}
+	if len(messages) != 1 || !strings.Contains(messages[0], "solver: 3") || strings.Contains(messages[0], "mesher") {
+		t.Errorf("Expected an alert for the queued solver requests, got %v", messages)
+	}
+}
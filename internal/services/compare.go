@@ -0,0 +1,92 @@
+package services
+
+import (
+	"reflect"
+	"sort"
+
+	"licet/internal/models"
+	"licet/internal/parsers"
+)
+
+// CompareServers compares the features of two servers by name. Pools of a
+// feature are combined: their seats are added up and their versions and
+// expiration dates collected.
+func CompareServers(serverA string, a []models.Feature, serverB string, b []models.Feature) models.ServerComparison {
+	summariesA, summariesB := summarizeFeatures(a), summarizeFeatures(b)
+	comparison := models.ServerComparison{
+		ServerA:   serverA,
+		ServerB:   serverB,
+		OnlyOnA:   make(map[string]models.FeatureSummary),
+		OnlyOnB:   make(map[string]models.FeatureSummary),
+		Differing: []models.FeatureDifference{},
+	}
+
+	for name, sa := range summariesA {
+		sb, ok := summariesB[name]
+		if !ok {
+			comparison.OnlyOnA[name] = sa
+			continue
+		}
+
+		var differences []string
+		if sa.TotalLicenses != sb.TotalLicenses {
+			differences = append(differences, "total_licenses")
+		}
+		if !reflect.DeepEqual(sa.Versions, sb.Versions) {
+			differences = append(differences, "versions")
+		}
+		if !reflect.DeepEqual(sa.Expirations, sb.Expirations) {
+			differences = append(differences, "expirations")
+		}
+		if len(differences) == 0 {
+			comparison.Matching++
+			continue
+		}
+		comparison.Differing = append(comparison.Differing, models.FeatureDifference{
+			Name: name, Differences: differences, A: sa, B: sb,
+		})
+	}
+	for name, sb := range summariesB {
+		if _, ok := summariesA[name]; !ok {
+			comparison.OnlyOnB[name] = sb
+		}
+	}
+
+	sort.Slice(comparison.Differing, func(i, j int) bool {
+		return comparison.Differing[i].Name < comparison.Differing[j].Name
+	})
+	comparison.Identical = len(comparison.OnlyOnA) == 0 && len(comparison.OnlyOnB) == 0 && len(comparison.Differing) == 0
+	return comparison
+}
+
+// summarizeFeatures combines the pools of each feature
+func summarizeFeatures(features []models.Feature) map[string]models.FeatureSummary {
+	summaries := make(map[string]models.FeatureSummary)
+	for _, f := range features {
+		s := summaries[f.Name]
+		s.TotalLicenses += f.TotalLicenses
+		s.Versions = appendUnique(s.Versions, f.Version)
+
+		expiration := "permanent"
+		if !f.ExpirationDate.IsZero() && !f.ExpirationDate.Equal(parsers.PermanentExpirationDate) {
+			expiration = f.ExpirationDate.Format("2006-01-02")
+		}
+		s.Expirations = appendUnique(s.Expirations, expiration)
+		summaries[f.Name] = s
+	}
+	for name, s := range summaries {
+		sort.Strings(s.Versions)
+		sort.Strings(s.Expirations)
+		summaries[name] = s
+	}
+	return summaries
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"licet/internal/models"
+	"licet/internal/parsers"
+)
+
+func TestCompareServers(t *testing.T) {
+	expires := time.Date(2027, 3, 31, 0, 0, 0, 0, time.UTC)
+	old := []models.Feature{
+		{Name: "solver", Version: "2024.1", TotalLicenses: 10, ExpirationDate: expires},
+		{Name: "solver", Version: "2024.1", TotalLicenses: 5, ExpirationDate: expires.AddDate(0, 6, 0)},
+		{Name: "mesher", Version: "1.0", TotalLicenses: 4, ExpirationDate: parsers.PermanentExpirationDate},
+		{Name: "viewer", Version: "3", TotalLicenses: 20, ExpirationDate: expires},
+		{Name: "legacy", Version: "1", TotalLicenses: 1, ExpirationDate: expires},
+	}
+	replacement := []models.Feature{
+		{Name: "solver", Version: "2024.1", TotalLicenses: 15, ExpirationDate: expires},
+		{Name: "mesher", Version: "1.0", TotalLicenses: 4, ExpirationDate: parsers.PermanentExpirationDate},
+		{Name: "viewer", Version: "4", TotalLicenses: 25, ExpirationDate: expires},
+		{Name: "postproc", Version: "1", TotalLicenses: 2, ExpirationDate: expires},
+	}
+
+	c := CompareServers("27000@old", old, "27000@new", replacement)
+	if c.Identical {
+		t.Fatal("Expected the servers to differ")
+	}
+	if c.Matching != 1 {
+		t.Errorf("Expected mesher to match, got %d matching", c.Matching)
+	}
+	if _, ok := c.OnlyOnA["legacy"]; !ok || len(c.OnlyOnA) != 1 {
+		t.Errorf("Expected legacy only on the old server, got %v", c.OnlyOnA)
+	}
+	if _, ok := c.OnlyOnB["postproc"]; !ok || len(c.OnlyOnB) != 1 {
+		t.Errorf("Expected postproc only on the new server, got %v", c.OnlyOnB)
+	}
+	if len(c.Differing) != 2 {
+		t.Fatalf("Expected 2 differing features, got %+v", c.Differing)
+	}
+
+	// Pools are combined: 15 seats on both, but the old server has a second expiration
+	solver := c.Differing[0]
+	if solver.Name != "solver" || !reflect.DeepEqual(solver.Differences, []string{"expirations"}) {
+		t.Errorf("Unexpected solver difference %+v", solver)
+	}
+	if !reflect.DeepEqual(solver.A.Expirations, []string{"2027-03-31", "2027-10-01"}) {
+		t.Errorf("Unexpected solver expirations %v", solver.A.Expirations)
+	}
+	viewer := c.Differing[1]
+	if !reflect.DeepEqual(viewer.Differences, []string{"total_licenses", "versions"}) {
+		t.Errorf("Unexpected viewer difference %+v", viewer)
+	}
+
+	if same := CompareServers("a", replacement, "b", replacement); !same.Identical || same.Matching != 4 {
+		t.Errorf("Expected identical servers, got %+v", same)
+	}
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"licet/internal/models"
+	"licet/internal/parsers"
+	"licet/internal/util"
+)
+
+var (
+	// ErrComputedMetricNotFound is returned for computed metrics that do not exist
+	ErrComputedMetricNotFound = errors.New("computed metric not found")
+	// ErrInvalidComputedMetric is returned for computed metrics that cannot be evaluated
+	ErrInvalidComputedMetric = errors.New("invalid computed metric")
+	// ErrComputedMetricExists is returned when creating a metric whose name is taken
+	ErrComputedMetricExists = errors.New("computed metric already exists")
+)
+
+// computedMetricName restricts metric names to what license features are named
+var computedMetricName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// ComputedMetricService manages computed metrics and stores their values as
+// features of the virtual models.ComputedServer, so history, statistics and
+// alert rules treat them like any other feature
+type ComputedMetricService struct {
+	db      *sqlx.DB
+	storage *StorageService
+}
+
+func NewComputedMetricService(db *sqlx.DB, storage *StorageService) *ComputedMetricService {
+	return &ComputedMetricService{db: db, storage: storage}
+}
+
+// GetComputedMetrics returns all computed metrics
+func (s *ComputedMetricService) GetComputedMetrics(ctx context.Context) ([]models.ComputedMetric, error) {
+	metrics := []models.ComputedMetric{}
+	err := s.db.SelectContext(ctx, &metrics, `SELECT * FROM computed_metrics ORDER BY name`)
+	return metrics, err
+}
+
+// GetComputedMetric returns one computed metric
+func (s *ComputedMetricService) GetComputedMetric(ctx context.Context, name string) (*models.ComputedMetric, error) {
+	var metric models.ComputedMetric
+	err := s.db.GetContext(ctx, &metric, s.db.Rebind(`SELECT * FROM computed_metrics WHERE name = ?`), name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrComputedMetricNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &metric, nil
+}
+
+// validateComputedMetric checks a metric's name and expressions before it is
+// stored
+func validateComputedMetric(metric *models.ComputedMetric) error {
+	metric.Name = strings.TrimSpace(metric.Name)
+	metric.Expression = strings.TrimSpace(metric.Expression)
+	metric.Capacity = strings.TrimSpace(metric.Capacity)
+	if !computedMetricName.MatchString(metric.Name) {
+		return fmt.Errorf("%w: name must consist of letters, digits, '_', '.' and '-'", ErrInvalidComputedMetric)
+	}
+	if metric.Expression == "" {
+		return fmt.Errorf("%w: expression is required", ErrInvalidComputedMetric)
+	}
+	if _, err := util.ParseExpression(metric.Expression); err != nil {
+		return fmt.Errorf("%w: expression: %v", ErrInvalidComputedMetric, err)
+	}
+	if metric.Capacity != "" {
+		if _, err := util.ParseExpression(metric.Capacity); err != nil {
+			return fmt.Errorf("%w: capacity: %v", ErrInvalidComputedMetric, err)
+		}
+	}
+	return nil
+}
+
+// CreateComputedMetric validates and stores a new computed metric
+func (s *ComputedMetricService) CreateComputedMetric(ctx context.Context, metric *models.ComputedMetric) error {
+	if err := validateComputedMetric(metric); err != nil {
+		return err
+	}
+	if _, err := s.GetComputedMetric(ctx, metric.Name); err == nil {
+		return ErrComputedMetricExists
+	} else if !errors.Is(err, ErrComputedMetricNotFound) {
+		return err
+	}
+
+	now := time.Now()
+	query := `
+		INSERT INTO computed_metrics (name, expression, capacity, description, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(query), metric.Name, metric.Expression, metric.Capacity,
+		metric.Description, metric.CreatedBy, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to store computed metric: %w", err)
+	}
+	metric.CreatedAt, metric.UpdatedAt = now, now
+	return nil
+}
+
+// UpdateComputedMetric validates and replaces the expressions and description
+// of an existing computed metric. Its history is kept.
+func (s *ComputedMetricService) UpdateComputedMetric(ctx context.Context, metric *models.ComputedMetric) error {
+	if err := validateComputedMetric(metric); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	query := `UPDATE computed_metrics SET expression = ?, capacity = ?, description = ?, updated_at = ? WHERE name = ?`
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(query), metric.Expression, metric.Capacity,
+		metric.Description, now, metric.Name)
+	if err != nil {
+		return fmt.Errorf("failed to update computed metric: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrComputedMetricNotFound
+	}
+	metric.UpdatedAt = now
+	return nil
+}
+
+// DeleteComputedMetric removes a computed metric and deactivates its feature.
+// Its recorded usage is kept.
+func (s *ComputedMetricService) DeleteComputedMetric(ctx context.Context, name string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM computed_metrics WHERE name = ?`), name)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrComputedMetricNotFound
+	}
+	query := `UPDATE features SET is_active = FALSE WHERE server_hostname = ? AND name = ?`
+	if _, err := tx.ExecContext(ctx, tx.Rebind(query), models.ComputedServer, name); err != nil {
+		return fmt.Errorf("failed to deactivate computed feature: %w", err)
+	}
+	return tx.Commit()
+}
+
+// featureValues sums the seats of the features collected from license
+// servers, by feature and by server and feature
+type featureValues struct {
+	used, total map[string]float64
+}
+
+func newFeatureValues(features []models.Feature) featureValues {
+	v := featureValues{used: make(map[string]float64), total: make(map[string]float64)}
+	for _, f := range features {
+		if f.ServerHostname == models.ComputedServer {
+			continue
+		}
+		for _, key := range []string{f.Name, f.ServerHostname + "|" + f.Name} {
+			v.used[key] += float64(f.UsedLicenses)
+			v.total[key] += float64(f.CountedLicenses())
+		}
+	}
+	return v
+}
+
+// lookup reads features as an expression's functions do. With onlyTotal,
+// every function reads the total seats, deriving a capacity from a usage
+// expression.
+func (v featureValues) lookup(onlyTotal bool) util.FeatureLookup {
+	return func(function, feature, server string) float64 {
+		key := feature
+		if server != "" {
+			key = server + "|" + feature
+		}
+		if onlyTotal {
+			return v.total[key]
+		}
+		switch function {
+		case "used":
+			return v.used[key]
+		case "total":
+			return v.total[key]
+		default:
+			return v.total[key] - v.used[key]
+		}
+	}
+}
+
+// evaluate computes a metric's seats in use and issued
+func (v featureValues) evaluate(metric models.ComputedMetric) (used, total float64, err error) {
+	expr, err := util.ParseExpression(metric.Expression)
+	if err != nil {
+		return 0, 0, err
+	}
+	if used, err = expr.Eval(v.lookup(false)); err != nil {
+		return 0, 0, err
+	}
+
+	capacity, onlyTotal := expr, true
+	if metric.Capacity != "" {
+		if capacity, err = util.ParseExpression(metric.Capacity); err != nil {
+			return 0, 0, err
+		}
+		onlyTotal = false
+	}
+	if total, err = capacity.Eval(v.lookup(onlyTotal)); err != nil {
+		return 0, 0, err
+	}
+	return used, total, nil
+}
+
+// Evaluate computes a metric from the current features without storing it,
+// e.g. to preview an expression
+func (s *ComputedMetricService) Evaluate(ctx context.Context, metric models.ComputedMetric) (used, total float64, err error) {
+	if err := validateComputedMetric(&metric); err != nil {
+		return 0, 0, err
+	}
+	features, err := s.storage.GetActiveFeatures(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get features: %w", err)
+	}
+	used, total, err = newFeatureValues(features).evaluate(metric)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %v", ErrInvalidComputedMetric, err)
+	}
+	return used, total, nil
+}
+
+// Compute evaluates every computed metric over the current features and
+// records the values as features of the computed server. Values are rounded
+// to whole seats. Metrics that cannot be evaluated, e.g. dividing by zero,
+// are skipped.
+func (s *ComputedMetricService) Compute(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	metrics, err := s.GetComputedMetrics(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get computed metrics: %w", err)
+	}
+	if len(metrics) == 0 {
+		return nil
+	}
+	features, err := s.storage.GetActiveFeatures(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get features: %w", err)
+	}
+
+	values := newFeatureValues(features)
+	var computed []models.Feature
+	for _, metric := range metrics {
+		used, total, err := values.evaluate(metric)
+		if err != nil {
+			log.Warnf("Skipping computed metric %s: %v", metric.Name, err)
+			continue
+		}
+		computed = append(computed, models.Feature{
+			ServerHostname: models.ComputedServer,
+			Name:           metric.Name,
+			VendorDaemon:   models.ComputedServer,
+			TotalLicenses:  int(math.Round(total)),
+			UsedLicenses:   int(math.Round(used)),
+			ExpirationDate: parsers.PermanentExpirationDate,
+		})
+	}
+
+	if err := s.storage.StoreFeatures(ctx, computed); err != nil {
+		return fmt.Errorf("failed to store computed metrics: %w", err)
+	}
+	return s.storage.RecordUsage(ctx, computed)
+}
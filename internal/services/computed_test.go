@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"licet/internal/models"
+)
+
+func TestComputedMetricService_CRUD(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	s := NewComputedMetricService(db, NewStorageService(db, "sqlite"))
+
+	for _, invalid := range []models.ComputedMetric{
+		{Name: "bad name", Expression: "used(a)"},
+		{Name: "weighted"},
+		{Name: "weighted", Expression: "used(a"},
+		{Name: "weighted", Expression: "used(a)", Capacity: "total("},
+	} {
+		if err := s.CreateComputedMetric(ctx, &invalid); !errors.Is(err, ErrInvalidComputedMetric) {
+			t.Errorf("Expected %+v to be invalid, got %v", invalid, err)
+		}
+	}
+
+	metric := models.ComputedMetric{Name: "weighted", Expression: "used(a) + 2*used(b)", CreatedBy: "admin"}
+	if err := s.CreateComputedMetric(ctx, &metric); err != nil {
+		t.Fatalf("CreateComputedMetric failed: %v", err)
+	}
+	if err := s.CreateComputedMetric(ctx, &metric); !errors.Is(err, ErrComputedMetricExists) {
+		t.Errorf("Expected a duplicate to be rejected, got %v", err)
+	}
+
+	metric.Expression = "used(a)"
+	if err := s.UpdateComputedMetric(ctx, &metric); err != nil {
+		t.Fatalf("UpdateComputedMetric failed: %v", err)
+	}
+	stored, err := s.GetComputedMetric(ctx, "weighted")
+	if err != nil || stored.Expression != "used(a)" || stored.CreatedBy != "admin" {
+		t.Errorf("Unexpected stored metric %+v (%v)", stored, err)
+	}
+
+	if err := s.UpdateComputedMetric(ctx, &models.ComputedMetric{Name: "missing", Expression: "1"}); !errors.Is(err, ErrComputedMetricNotFound) {
+		t.Errorf("Expected not found, got %v", err)
+	}
+	if err := s.DeleteComputedMetric(ctx, "weighted"); err != nil {
+		t.Fatalf("DeleteComputedMetric failed: %v", err)
+	}
+	if _, err := s.GetComputedMetric(ctx, "weighted"); !errors.Is(err, ErrComputedMetricNotFound) {
+		t.Errorf("Expected the metric to be removed, got %v", err)
+	}
+}
+
+func TestComputedMetricService_Compute(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+	s := NewComputedMetricService(db, storage)
+
+	expires := time.Now().AddDate(1, 0, 0)
+	for _, features := range [][]models.Feature{
+		{
+			{ServerHostname: "27000@a", Name: "solver", TotalLicenses: 10, UsedLicenses: 3, ExpirationDate: expires},
+			{ServerHostname: "27000@a", Name: "mesher", TotalLicenses: 5, UsedLicenses: 2, ExpirationDate: expires},
+		},
+		{
+			{ServerHostname: "27000@b", Name: "solver", TotalLicenses: 4, UsedLicenses: 4, ExpirationDate: expires},
+		},
+	} {
+		if err := storage.StoreFeatures(ctx, features); err != nil {
+			t.Fatalf("StoreFeatures failed: %v", err)
+		}
+	}
+
+	for _, metric := range []models.ComputedMetric{
+		{Name: "weighted", Expression: "used(solver) + 2*used(mesher)"},
+		{Name: "solver_b", Expression: `used(solver, "27000@b")`, Capacity: "8"},
+		{Name: "broken", Expression: "used(solver) / used(missing)"},
+	} {
+		if err := s.CreateComputedMetric(ctx, &metric); err != nil {
+			t.Fatalf("CreateComputedMetric failed: %v", err)
+		}
+	}
+
+	used, total, err := s.Evaluate(ctx, models.ComputedMetric{Name: "preview", Expression: "free(solver)"})
+	if err != nil || used != 7 || total != 14 {
+		t.Errorf("Evaluate = %v, %v (%v), want 7, 14", used, total, err)
+	}
+
+	if err := s.Compute(ctx); err != nil {
+		t.Fatalf("Compute failed: %v", err)
+	}
+	features, err := storage.GetAllFeatures(ctx, models.ComputedServer)
+	if err != nil {
+		t.Fatalf("GetAllFeatures failed: %v", err)
+	}
+	got := make(map[string]models.Feature)
+	for _, f := range features {
+		got[f.Name] = f
+	}
+	if f := got["weighted"]; f.UsedLicenses != 11 || f.TotalLicenses != 24 {
+		t.Errorf("Expected weighted to use 11 of 24, got %d of %d", f.UsedLicenses, f.TotalLicenses)
+	}
+	if f := got["solver_b"]; f.UsedLicenses != 4 || f.TotalLicenses != 8 {
+		t.Errorf("Expected solver_b to use 4 of 8, got %d of %d", f.UsedLicenses, f.TotalLicenses)
+	}
+	if _, ok := got["broken"]; ok {
+		t.Error("Expected a metric dividing by zero to be skipped")
+	}
+
+	var usage int
+	if err := db.Get(&usage, `SELECT COUNT(*) FROM feature_usage WHERE server_hostname = ?`, models.ComputedServer); err != nil || usage != 2 {
+		t.Errorf("Expected 2 usage records for computed metrics, got %d (%v)", usage, err)
+	}
+
+	// Computing again ignores the computed features themselves
+	if err := s.Compute(ctx); err != nil {
+		t.Fatalf("Compute failed: %v", err)
+	}
+	if err := s.DeleteComputedMetric(ctx, "weighted"); err != nil {
+		t.Fatal(err)
+	}
+	active, _ := storage.GetActiveFeatures(ctx)
+	for _, f := range active {
+		if f.ServerHostname == models.ComputedServer && f.Name == "weighted" {
+			t.Error("Expected the feature of a removed metric to be deactivated")
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package services
+
+// lockConfigFile does nothing where advisory locks are unavailable; writers
+// within the process are still serialized
+func lockConfigFile(path string) (unlock func(), err error) {
+	return func() {}, nil
+}
@@ -0,0 +1,34 @@
+//go:build unix
+
+package services
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockConfigFile takes an exclusive advisory lock on the config file, waiting
+// for other processes holding it. Writers replace the file, so a lock taken
+// on a file that was replaced meanwhile is dropped and taken again.
+func lockConfigFile(path string) (unlock func(), err error) {
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			return nil, err
+		}
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		locked, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if current, err := os.Stat(path); err == nil && os.SameFile(locked, current) {
+			return func() { f.Close() }, nil
+		}
+		f.Close()
+	}
+}
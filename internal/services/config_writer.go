@@ -1,9 +1,12 @@
 package services
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 
 	"gopkg.in/yaml.v3"
 	"licet/internal/config"
@@ -36,28 +39,76 @@ func NewConfigWriter() *ConfigWriter {
 	}
 }
 
-// readConfig reads and parses the config file
-func (cw *ConfigWriter) readConfig() (map[string]interface{}, error) {
+// configWriteMu serializes config writers within this process; lockConfigFile
+// excludes other processes
+var configWriteMu sync.Mutex
+
+// update locks the config file, applies edit to its top-level mapping and
+// replaces the file with the result if it still is a valid configuration.
+// Editing the YAML tree keeps comments and the order of keys.
+func (cw *ConfigWriter) update(edit func(root *yaml.Node) error) error {
+	configWriteMu.Lock()
+	defer configWriteMu.Unlock()
+
+	unlock, err := lockConfigFile(cw.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to lock config file: %w", err)
+	}
+	defer unlock()
+
+	doc, err := cw.readConfig()
+	if err != nil {
+		return err
+	}
+	if err := edit(doc.Content[0]); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := config.Validate(buf.Bytes()); err != nil {
+		return fmt.Errorf("refusing to write invalid config: %w", err)
+	}
+
+	return cw.writeConfigAtomic(buf.Bytes())
+}
+
+// readConfig reads and parses the config file into a document whose content
+// is the top-level mapping
+func (cw *ConfigWriter) readConfig() (*yaml.Node, error) {
 	data, err := os.ReadFile(cw.configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var configData map[string]interface{}
-	if err := yaml.Unmarshal(data, &configData); err != nil {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	if doc.Kind == 0 {
+		// Empty file
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind == yaml.ScalarNode && root.Tag == "!!null" {
+		*root = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file is not a YAML mapping")
+	}
 
-	return configData, nil
+	return &doc, nil
 }
 
 // writeConfigAtomic writes config data atomically (write to temp, then rename)
-func (cw *ConfigWriter) writeConfigAtomic(configData map[string]interface{}) error {
-	output, err := yaml.Marshal(configData)
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
-	}
-
+func (cw *ConfigWriter) writeConfigAtomic(output []byte) error {
 	// Write to temp file in same directory (for atomic rename)
 	dir := filepath.Dir(cw.configPath)
 	tmpFile, err := os.CreateTemp(dir, "config-*.yaml.tmp")
@@ -96,103 +147,144 @@ func (cw *ConfigWriter) writeConfigAtomic(configData map[string]interface{}) err
 	return nil
 }
 
-// UpdateSection updates a section of the config file with the given data
-func (cw *ConfigWriter) UpdateSection(section string, data map[string]interface{}) error {
-	configData, err := cw.readConfig()
-	if err != nil {
-		return err
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
 	}
-
-	configData[section] = data
-
-	return cw.writeConfigAtomic(configData)
+	return nil
 }
 
-// AddServer adds a new server to the config file
-func (cw *ConfigWriter) AddServer(server config.LicenseServer) error {
-	configData, err := cw.readConfig()
-	if err != nil {
-		return err
+// setMappingValue sets key in a mapping node to v. An existing key keeps its
+// position and, for plain values, its comments; a new key is appended.
+func setMappingValue(mapping *yaml.Node, key string, v interface{}) error {
+	var value yaml.Node
+	if err := value.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", key, err)
 	}
-
-	// Get or create servers array
-	serversInterface, ok := configData["servers"]
-	if !ok {
-		configData["servers"] = []interface{}{}
-		serversInterface = configData["servers"]
+	if old := mappingValue(mapping, key); old != nil {
+		if old.Kind == yaml.ScalarNode && value.Kind == yaml.ScalarNode {
+			value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+		}
+		*old = value
+		return nil
 	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, &value)
+	return nil
+}
 
-	servers, ok := serversInterface.([]interface{})
-	if !ok {
-		return fmt.Errorf("servers is not an array in config file")
-	}
+// UpdateSection updates a section of the config file with the given data.
+// Keys not present in data (e.g. runbook templates) are kept.
+func (cw *ConfigWriter) UpdateSection(section string, data map[string]interface{}) error {
+	return cw.update(func(root *yaml.Node) error {
+		sectionNode := mappingValue(root, section)
+		if sectionNode == nil || sectionNode.Kind != yaml.MappingNode {
+			if err := setMappingValue(root, section, map[string]interface{}{}); err != nil {
+				return err
+			}
+			sectionNode = mappingValue(root, section)
+		}
 
-	// Check if server already exists
-	for _, s := range servers {
-		if srv, ok := s.(map[string]interface{}); ok {
-			if hostname, exists := srv["hostname"]; exists && hostname == server.Hostname {
-				return fmt.Errorf("server %s already exists", server.Hostname)
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := setMappingValue(sectionNode, k, data[k]); err != nil {
+				return err
 			}
 		}
-	}
+		return nil
+	})
+}
 
-	// Add new server
-	newServer := map[string]interface{}{
-		"hostname":    server.Hostname,
-		"description": server.Description,
-		"type":        server.Type,
-	}
-	if server.CactiID != "" {
-		newServer["cacti_id"] = server.CactiID
+// serversNode returns the servers sequence of the config, creating it if
+// create is set
+func serversNode(root *yaml.Node, create bool) (*yaml.Node, error) {
+	servers := mappingValue(root, "servers")
+	if servers == nil || (servers.Kind == yaml.ScalarNode && servers.Tag == "!!null") {
+		if !create {
+			return nil, fmt.Errorf("no servers found in config file")
+		}
+		if err := setMappingValue(root, "servers", []interface{}{}); err != nil {
+			return nil, err
+		}
+		servers = mappingValue(root, "servers")
 	}
-	if server.WebUI != "" {
-		newServer["webui"] = server.WebUI
+	if servers.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("servers is not an array in config file")
 	}
+	// Block style, even for a config starting with "servers: []"
+	servers.Style = 0
+	return servers, nil
+}
+
+// AddServer adds a new server to the config file
+func (cw *ConfigWriter) AddServer(server config.LicenseServer) error {
+	return cw.update(func(root *yaml.Node) error {
+		servers, err := serversNode(root, true)
+		if err != nil {
+			return err
+		}
+
+		// Check if server already exists
+		for _, srv := range servers.Content {
+			if h := mappingValue(srv, "hostname"); h != nil && h.Value == server.Hostname {
+				return fmt.Errorf("server %s already exists", server.Hostname)
+			}
+		}
 
-	servers = append(servers, newServer)
-	configData["servers"] = servers
+		// Add new server, keeping the usual key order
+		newServer := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		fields := []struct {
+			key   string
+			value interface{}
+			set   bool
+		}{
+			{"hostname", server.Hostname, true},
+			{"description", server.Description, true},
+			{"type", server.Type, true},
+			{"cacti_id", server.CactiID, server.CactiID != ""},
+			{"webui", server.WebUI, server.WebUI != ""},
+			{"group", server.Group, server.Group != ""},
+			{"poll_interval", server.PollInterval, server.PollInterval > 0},
+			{"schedule", server.Schedule, server.Schedule != ""},
+			{"jitter", server.Jitter, server.Jitter > 0},
+			{"timeout", server.Timeout, server.Timeout > 0},
+		}
+		for _, f := range fields {
+			if f.set {
+				if err := setMappingValue(newServer, f.key, f.value); err != nil {
+					return err
+				}
+			}
+		}
 
-	return cw.writeConfigAtomic(configData)
+		servers.Content = append(servers.Content, newServer)
+		return nil
+	})
 }
 
 // DeleteServer removes a server from the config file
 func (cw *ConfigWriter) DeleteServer(hostname string) error {
-	configData, err := cw.readConfig()
-	if err != nil {
-		return err
-	}
-
-	// Get servers array
-	serversInterface, ok := configData["servers"]
-	if !ok {
-		return fmt.Errorf("no servers found in config file")
-	}
-
-	servers, ok := serversInterface.([]interface{})
-	if !ok {
-		return fmt.Errorf("servers is not an array in config file")
-	}
+	return cw.update(func(root *yaml.Node) error {
+		servers, err := serversNode(root, false)
+		if err != nil {
+			return err
+		}
 
-	// Find and remove server
-	found := false
-	newServers := []interface{}{}
-	for _, s := range servers {
-		if srv, ok := s.(map[string]interface{}); ok {
-			if h, exists := srv["hostname"]; exists && h == hostname {
-				found = true
-				continue // skip this server
+		// Find and remove server
+		for i, srv := range servers.Content {
+			if h := mappingValue(srv, "hostname"); h != nil && h.Value == hostname {
+				servers.Content = append(servers.Content[:i], servers.Content[i+1:]...)
+				return nil
 			}
 		}
-		newServers = append(newServers, s)
-	}
-
-	if !found {
 		return fmt.Errorf("server %s not found", hostname)
-	}
-
-	configData["servers"] = newServers
-
-	return cw.writeConfigAtomic(configData)
+	})
 }
 
 // UpdateEmailSettings updates email configuration in the config file
@@ -1,8 +1,11 @@
 package services
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"gopkg.in/yaml.v3"
@@ -206,6 +209,38 @@ func TestConfigWriter_UpdateAlertSettings(t *testing.T) {
 	}
 }
 
+func TestConfigWriter_UpdateAlertSettings_KeepsOtherKeys(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	initialConfig := map[string]interface{}{
+		"alerts": map[string]interface{}{
+			"enabled":  false,
+			"failover": true,
+			"runbooks": map[string]interface{}{"down": "https://wiki.example.com/licet/down"},
+		},
+	}
+	data, _ := yaml.Marshal(initialConfig)
+	os.WriteFile(configPath, data, 0600)
+
+	cw := &ConfigWriter{configPath: configPath}
+	if err := cw.UpdateAlertSettings(true, 14, 120); err != nil {
+		t.Fatalf("UpdateAlertSettings failed: %v", err)
+	}
+
+	data, _ = os.ReadFile(configPath)
+	var result map[string]interface{}
+	yaml.Unmarshal(data, &result)
+
+	alerts := result["alerts"].(map[string]interface{})
+	if alerts["enabled"] != true {
+		t.Error("Expected enabled to be updated")
+	}
+	if alerts["failover"] != true || alerts["runbooks"] == nil {
+		t.Errorf("Expected settings not managed by the form to be kept, got %v", alerts)
+	}
+}
+
 func TestConfigWriter_AtomicWrite(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "config.yaml")
@@ -247,3 +282,118 @@ func TestConfigWriter_AtomicWrite(t *testing.T) {
 		t.Errorf("Expected 5 servers, got %d", len(servers))
 	}
 }
+
+func TestConfigWriter_AddServer_Schedule(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(configPath, []byte("servers: []\n"), 0600)
+	cw := &ConfigWriter{configPath: configPath}
+
+	err := cw.AddServer(config.LicenseServer{Hostname: "27000@heavy", Type: "flexlm", PollInterval: 30, Jitter: 60})
+	if err != nil {
+		t.Fatalf("AddServer failed: %v", err)
+	}
+	err = cw.AddServer(config.LicenseServer{Hostname: "27000@light", Type: "flexlm"})
+	if err != nil {
+		t.Fatalf("AddServer failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(configPath)
+	var result map[string]interface{}
+	yaml.Unmarshal(data, &result)
+	servers := result["servers"].([]interface{})
+
+	heavy := servers[0].(map[string]interface{})
+	if heavy["poll_interval"] != 30 || heavy["jitter"] != 60 {
+		t.Errorf("Expected the poll interval and jitter to be written, got %v", heavy)
+	}
+	if _, ok := heavy["schedule"]; ok {
+		t.Error("Expected no schedule to be written")
+	}
+	if _, ok := servers[1].(map[string]interface{})["poll_interval"]; ok {
+		t.Error("Expected servers on the global interval to have no poll_interval")
+	}
+}
+
+func TestConfigWriter_KeepsCommentsAndOrder(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	initial := `# Licet configuration
+server:
+  port: 8080  # HTTP port
+
+alerts:
+  enabled: false  # Send expiration alerts
+  lead_time_days: 7
+
+servers:
+  # Production FlexLM
+  - hostname: 27000@prod
+    type: flexlm
+`
+	os.WriteFile(configPath, []byte(initial), 0600)
+	cw := &ConfigWriter{configPath: configPath}
+
+	if err := cw.UpdateAlertSettings(true, 14, 60); err != nil {
+		t.Fatalf("UpdateAlertSettings failed: %v", err)
+	}
+	if err := cw.AddServer(config.LicenseServer{Hostname: "27000@new", Type: "flexlm"}); err != nil {
+		t.Fatalf("AddServer failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(configPath)
+	out := string(data)
+	for _, want := range []string{"# Licet configuration", "port: 8080 # HTTP port", "enabled: true # Send expiration alerts", "# Production FlexLM", "hostname: 27000@new"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the written config:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "server:") > strings.Index(out, "alerts:") || strings.Index(out, "alerts:") > strings.Index(out, "servers:") {
+		t.Errorf("Expected the sections to keep their order:\n%s", out)
+	}
+}
+
+func TestConfigWriter_RejectsInvalidConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	initial := []byte("collection:\n  workers: 5\n")
+	os.WriteFile(configPath, initial, 0600)
+	cw := &ConfigWriter{configPath: configPath}
+
+	if err := cw.UpdateSection("collection", map[string]interface{}{"workers": -1}); err == nil {
+		t.Fatal("Expected a negative worker count to be rejected")
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != string(initial) {
+		t.Errorf("Expected the config file to be unchanged, got:\n%s", data)
+	}
+}
+
+func TestConfigWriter_ConcurrentWriters(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(configPath, []byte("servers: []\n"), 0600)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// A writer per request, as the settings handlers use
+			cw := &ConfigWriter{configPath: configPath}
+			errs <- cw.AddServer(config.LicenseServer{Hostname: fmt.Sprintf("27000@server%d", i), Type: "flexlm"})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("AddServer failed: %v", err)
+		}
+	}
+
+	data, _ := os.ReadFile(configPath)
+	var result map[string]interface{}
+	if err := yaml.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Config file corrupted: %v", err)
+	}
+	if servers := result["servers"].([]interface{}); len(servers) != 20 {
+		t.Errorf("Expected 20 servers, got %d", len(servers))
+	}
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"licet/internal/models"
+)
+
+var (
+	// ErrContractNotFound is returned for license contracts that do not exist
+	ErrContractNotFound = errors.New("license contract not found")
+	// ErrInvalidContract is returned for license contracts that cannot be stored
+	ErrInvalidContract = errors.New("invalid license contract")
+	// ErrContractExists is returned when the feature already has a contract on the server
+	ErrContractExists = errors.New("license contract already exists")
+)
+
+// GetContracts returns all license contracts
+func (s *StorageService) GetContracts(ctx context.Context) ([]models.LicenseContract, error) {
+	contracts := []models.LicenseContract{}
+	query := `SELECT * FROM license_contracts ORDER BY feature_name, server_hostname`
+	err := s.db.SelectContext(ctx, &contracts, query)
+	return contracts, err
+}
+
+// GetContract returns one license contract
+func (s *StorageService) GetContract(ctx context.Context, id int64) (*models.LicenseContract, error) {
+	var contract models.LicenseContract
+	err := s.db.GetContext(ctx, &contract, s.db.Rebind(`SELECT * FROM license_contracts WHERE id = ?`), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrContractNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &contract, nil
+}
+
+// validateContract checks a contract before it is stored and fills in the
+// default currency
+func validateContract(c *models.LicenseContract) error {
+	c.ServerHostname = strings.TrimSpace(c.ServerHostname)
+	c.FeatureName = strings.TrimSpace(c.FeatureName)
+	c.Currency = strings.ToUpper(strings.TrimSpace(c.Currency))
+	if c.FeatureName == "" {
+		return fmt.Errorf("%w: feature_name is required", ErrInvalidContract)
+	}
+	if c.CostPerSeat < 0 {
+		return fmt.Errorf("%w: cost_per_seat must not be negative", ErrInvalidContract)
+	}
+	if c.Currency == "" {
+		c.Currency = "USD"
+	}
+	if len(c.Currency) != 3 {
+		return fmt.Errorf("%w: currency must be a three letter code such as USD", ErrInvalidContract)
+	}
+	return nil
+}
+
+// CreateContract validates and stores a new license contract, setting its ID
+func (s *StorageService) CreateContract(ctx context.Context, c *models.LicenseContract) error {
+	if err := validateContract(c); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var existing int
+	query := `SELECT COUNT(*) FROM license_contracts WHERE server_hostname = ? AND feature_name = ?`
+	if err := tx.GetContext(ctx, &existing, tx.Rebind(query), c.ServerHostname, c.FeatureName); err != nil {
+		return err
+	}
+	if existing > 0 {
+		return ErrContractExists
+	}
+
+	now := s.clock.Now()
+	query = `
+		INSERT INTO license_contracts (server_hostname, feature_name, cost_per_seat, currency, renewal_date,
+			po_number, vendor_contact, notes, updated_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = tx.ExecContext(ctx, tx.Rebind(query), c.ServerHostname, c.FeatureName, c.CostPerSeat, c.Currency,
+		c.RenewalDate, c.PONumber, c.VendorContact, c.Notes, c.UpdatedBy, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to store license contract: %w", err)
+	}
+
+	query = `SELECT id FROM license_contracts WHERE server_hostname = ? AND feature_name = ?`
+	if err := tx.GetContext(ctx, &c.ID, tx.Rebind(query), c.ServerHostname, c.FeatureName); err != nil {
+		return fmt.Errorf("failed to store license contract: %w", err)
+	}
+	c.CreatedAt, c.UpdatedAt = now, now
+	if err := touchMetadata(ctx, tx, metadataContracts, now); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateContract validates and replaces an existing license contract
+func (s *StorageService) UpdateContract(ctx context.Context, c *models.LicenseContract) error {
+	if err := validateContract(c); err != nil {
+		return err
+	}
+
+	var other int
+	query := `SELECT COUNT(*) FROM license_contracts WHERE server_hostname = ? AND feature_name = ? AND id <> ?`
+	if err := s.db.GetContext(ctx, &other, s.db.Rebind(query), c.ServerHostname, c.FeatureName, c.ID); err != nil {
+		return err
+	}
+	if other > 0 {
+		return ErrContractExists
+	}
+
+	now := s.clock.Now()
+	query = `
+		UPDATE license_contracts SET server_hostname = ?, feature_name = ?, cost_per_seat = ?, currency = ?,
+			renewal_date = ?, po_number = ?, vendor_contact = ?, notes = ?, updated_by = ?, updated_at = ?
+		WHERE id = ?
+	`
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(query), c.ServerHostname, c.FeatureName, c.CostPerSeat, c.Currency,
+		c.RenewalDate, c.PONumber, c.VendorContact, c.Notes, c.UpdatedBy, now, c.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update license contract: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrContractNotFound
+	}
+	if err := touchMetadata(ctx, s.db, metadataContracts, now); err != nil {
+		return err
+	}
+
+	stored, err := s.GetContract(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+	*c = *stored
+	return nil
+}
+
+// DeleteContract removes a license contract
+func (s *StorageService) DeleteContract(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(`DELETE FROM license_contracts WHERE id = ?`), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrContractNotFound
+	}
+	return touchMetadata(ctx, s.db, metadataContracts, s.clock.Now())
+}
+
+// contractFor returns the contract of a feature on a server: the one of the
+// server, else the one for every server. Nil when there is none.
+func contractFor(contracts []models.LicenseContract, server, feature string) *models.LicenseContract {
+	var shared *models.LicenseContract
+	for i := range contracts {
+		c := &contracts[i]
+		if c.FeatureName != feature {
+			continue
+		}
+		if c.ServerHostname == server {
+			return c
+		}
+		if c.ServerHostname == "" {
+			shared = c
+		}
+	}
+	return shared
+}
+
+// currencySymbols are the symbols of common currencies; others are shown by code
+var currencySymbols = map[string]string{"USD": "$", "EUR": "€", "GBP": "£"}
+
+// formatMoney formats a whole amount in a currency, e.g. "$12,500" or
+// "CHF 12,500"
+func formatMoney(amount float64, currency string) string {
+	digits := fmt.Sprintf("%.0f", math.Abs(amount))
+	var b strings.Builder
+	if amount <= -0.5 {
+		b.WriteByte('-')
+	}
+	if symbol, ok := currencySymbols[currency]; ok {
+		b.WriteString(symbol)
+	} else {
+		b.WriteString(currency + " ")
+	}
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return b.String()
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/models"
+)
+
+func TestLicenseContracts(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	renewal := time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC)
+	shared := models.LicenseContract{FeatureName: "solver", CostPerSeat: 1200, RenewalDate: &renewal, PONumber: "PO-1", UpdatedBy: "admin"}
+	if err := storage.CreateContract(ctx, &shared); err != nil {
+		t.Fatalf("CreateContract failed: %v", err)
+	}
+	if shared.ID == 0 || shared.Currency != "USD" {
+		t.Fatalf("Expected the ID and default currency to be set, got %+v", shared)
+	}
+	local := models.LicenseContract{ServerHostname: "27000@b", FeatureName: "solver", CostPerSeat: 900, Currency: "eur"}
+	if err := storage.CreateContract(ctx, &local); err != nil {
+		t.Fatalf("CreateContract failed: %v", err)
+	}
+	duplicate := models.LicenseContract{FeatureName: "solver", CostPerSeat: 1}
+	if err := storage.CreateContract(ctx, &duplicate); !errors.Is(err, ErrContractExists) {
+		t.Errorf("Expected ErrContractExists, got %v", err)
+	}
+	for _, invalid := range []models.LicenseContract{
+		{CostPerSeat: 1},
+		{FeatureName: "mesher", CostPerSeat: -1},
+		{FeatureName: "mesher", Currency: "Dollars"},
+	} {
+		if err := storage.CreateContract(ctx, &invalid); !errors.Is(err, ErrInvalidContract) {
+			t.Errorf("Expected ErrInvalidContract for %+v, got %v", invalid, err)
+		}
+	}
+
+	local.CostPerSeat = 1000
+	if err := storage.UpdateContract(ctx, &local); err != nil {
+		t.Fatalf("UpdateContract failed: %v", err)
+	}
+	got, err := storage.GetContract(ctx, local.ID)
+	if err != nil || got.CostPerSeat != 1000 || got.Currency != "EUR" {
+		t.Fatalf("Unexpected contract after update: %+v, %v", got, err)
+	}
+	missing := models.LicenseContract{ID: 999, FeatureName: "viewer"}
+	if err := storage.UpdateContract(ctx, &missing); !errors.Is(err, ErrContractNotFound) {
+		t.Errorf("Expected ErrContractNotFound, got %v", err)
+	}
+
+	contracts, err := storage.GetContracts(ctx)
+	if err != nil || len(contracts) != 2 {
+		t.Fatalf("Expected 2 contracts, got %+v, %v", contracts, err)
+	}
+	if c := contractFor(contracts, "27000@b", "solver"); c == nil || c.ID != local.ID {
+		t.Errorf("Expected the server's own contract, got %+v", c)
+	}
+	if c := contractFor(contracts, "27000@a", "solver"); c == nil || c.ID != shared.ID {
+		t.Errorf("Expected the contract for every server, got %+v", c)
+	}
+	if c := contractFor(contracts, "27000@a", "viewer"); c != nil {
+		t.Errorf("Expected no contract, got %+v", c)
+	}
+
+	if err := storage.DeleteContract(ctx, local.ID); err != nil {
+		t.Fatalf("DeleteContract failed: %v", err)
+	}
+	if err := storage.DeleteContract(ctx, local.ID); !errors.Is(err, ErrContractNotFound) {
+		t.Errorf("Expected ErrContractNotFound, got %v", err)
+	}
+}
+
+func TestCostWeightedRecommendations(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	if err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", TotalLicenses: 20},
+		{ServerHostname: "27000@a", Name: "viewer", TotalLicenses: 10},
+	}); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	for day := 0; day < 10; day++ {
+		date := time.Now().AddDate(0, 0, -day).Format("2006-01-02")
+		for feature, users := range map[string]int{"solver": 8 + day%3, "viewer": 9} {
+			_, err := db.Exec(`INSERT INTO feature_usage (server_hostname, feature_name, date, time, users_count) VALUES (?, ?, ?, ?, ?)`,
+				"27000@a", feature, date, "12:00:00", users)
+			if err != nil {
+				t.Fatalf("Failed to insert usage: %v", err)
+			}
+		}
+	}
+	renewal := time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC)
+	for _, c := range []models.LicenseContract{
+		{FeatureName: "solver", CostPerSeat: 1500, RenewalDate: &renewal},
+		{FeatureName: "viewer", CostPerSeat: 200},
+	} {
+		if err := storage.CreateContract(ctx, &c); err != nil {
+			t.Fatalf("CreateContract failed: %v", err)
+		}
+	}
+
+	s := NewEnhancedAnalyticsService(db, storage, "sqlite")
+	stats, err := s.GetEnhancedStatistics(ctx, "27000@a", "solver", 30)
+	if err != nil {
+		t.Fatalf("GetEnhancedStatistics failed: %v", err)
+	}
+	// Peak usage of 10 leaves 10 of 20 seats idle
+	if stats.IdleSeats != 10 || stats.AnnualCost != 30000 || stats.PotentialSavings != 15000 || stats.Contract == nil {
+		t.Fatalf("Unexpected cost metrics: idle %d, cost %v, savings %v", stats.IdleSeats, stats.AnnualCost, stats.PotentialSavings)
+	}
+	var found bool
+	for _, rec := range stats.Recommendations {
+		if rec.Title == "Idle Seat Cost Savings" {
+			found = true
+			if rec.Impact != "Reducing 10 idle seats saves $15,000/year from the renewal on 2027-01-31" {
+				t.Errorf("Unexpected impact: %s", rec.Impact)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected a cost recommendation, got %+v", stats.Recommendations)
+	}
+
+	report, err := s.GetCapacityPlanningReport(ctx, 30)
+	if err != nil {
+		t.Fatalf("GetCapacityPlanningReport failed: %v", err)
+	}
+	if len(report.CostSavings) != 2 || report.CostSavings[0].FeatureName != "solver" || report.CostSavings[1].PotentialSavings != 200 {
+		t.Fatalf("Unexpected cost savings: %+v", report.CostSavings)
+	}
+	found = false
+	for _, rec := range report.Recommendations {
+		if rec.Title == "Idle Seat Cost Savings" {
+			found = true
+			if !strings.HasPrefix(rec.Impact, "Reducing 11 idle seats saves $15,200/year") {
+				t.Errorf("Unexpected impact: %s", rec.Impact)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected a cost recommendation, got %+v", report.Recommendations)
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	for _, tt := range []struct {
+		amount   float64
+		currency string
+		want     string
+	}{
+		{15000, "USD", "$15,000"},
+		{999.6, "EUR", "€1,000"},
+		{1234567, "CHF", "CHF 1,234,567"},
+		{-2500, "GBP", "-£2,500"},
+	} {
+		if got := formatMoney(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("formatMoney(%v, %s) = %q, want %q", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/robfig/cron/v3"
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+// maxReportedGaps bounds the gaps listed per server, keeping the latest
+const maxReportedGaps = 100
+
+// DataQualityService checks the collected data for gaps, stale and
+// duplicate features and parse problems
+type DataQualityService struct {
+	db      *sqlx.DB
+	cfg     *config.Config
+	storage *StorageService
+}
+
+func NewDataQualityService(db *sqlx.DB, cfg *config.Config, storage *StorageService) *DataQualityService {
+	return &DataQualityService{db: db, cfg: cfg, storage: storage}
+}
+
+// Report checks the last days of collection of every configured server and
+// the current features. Active features not updated within staleHours are
+// reported as stale.
+func (s *DataQualityService) Report(ctx context.Context, days, staleHours int, now time.Time) (*models.DataQualityReport, error) {
+	report := &models.DataQualityReport{
+		GeneratedAt:   now,
+		Days:          days,
+		StaleHours:    staleHours,
+		Servers:       []models.ServerDataQuality{},
+		StaleFeatures: []models.StaleFeature{},
+		Duplicates:    []models.DuplicateSuspect{},
+	}
+
+	configured := map[string]bool{models.ComputedServer: true}
+	start := now.AddDate(0, 0, -days)
+	for _, srv := range s.cfg.Servers {
+		configured[srv.Hostname] = true
+		quality, err := s.serverQuality(ctx, srv, start, now)
+		if err != nil {
+			return nil, err
+		}
+		if len(quality.Gaps) > 0 {
+			report.Summary.ServersWithGaps++
+			report.Summary.Gaps += len(quality.Gaps)
+		}
+		report.Servers = append(report.Servers, *quality)
+	}
+
+	features, err := s.storage.GetActiveFeatures(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get features: %w", err)
+	}
+	cutoff := now.Add(-time.Duration(staleHours) * time.Hour)
+	for _, f := range features {
+		if f.LastUpdated.Before(cutoff) {
+			report.StaleFeatures = append(report.StaleFeatures, models.StaleFeature{
+				Server: f.ServerHostname, Feature: f.Name, Version: f.Version,
+				LastUpdated: f.LastUpdated, Configured: configured[f.ServerHostname],
+			})
+		}
+	}
+
+	if report.ParseWarnings, err = s.storage.GetDegradedFeatures(ctx, ""); err != nil {
+		return nil, fmt.Errorf("failed to get degraded features: %w", err)
+	}
+	if report.ParseWarnings == nil {
+		report.ParseWarnings = []models.Feature{}
+	}
+
+	var all []models.Feature
+	if err := s.db.SelectContext(ctx, &all, `SELECT * FROM features ORDER BY id`); err != nil {
+		return nil, fmt.Errorf("failed to get features: %w", err)
+	}
+	for _, group := range duplicateFeatures(all) {
+		suspect := models.DuplicateSuspect{
+			Server: group.keeper.ServerHostname, Feature: group.keeper.Name, Version: group.keeper.Version,
+			ExpirationDate: group.keeper.ExpirationDate,
+		}
+		for _, dup := range group.duplicates {
+			suspect.DuplicateIDs = append(suspect.DuplicateIDs, dup.ID)
+		}
+		report.Duplicates = append(report.Duplicates, suspect)
+	}
+
+	report.Summary.StaleFeatures = len(report.StaleFeatures)
+	report.Summary.ParseWarnings = len(report.ParseWarnings)
+	report.Summary.Duplicates = len(report.Duplicates)
+	return report, nil
+}
+
+// pollSpec returns the cron spec a server is polled on
+func (s *DataQualityService) pollSpec(srv config.LicenseServer) string {
+	spec := PollSchedule(models.LicenseServer{PollInterval: srv.PollInterval, Schedule: srv.Schedule})
+	if spec == "" {
+		interval := s.cfg.RRD.CollectionInterval
+		if interval <= 0 {
+			interval = 5
+		}
+		spec = fmt.Sprintf("@every %dm", interval)
+	}
+	return spec
+}
+
+// serverQuality compares the usage samples recorded for a server since start
+// with its poll schedule. Two or more consecutive scheduled polls without a
+// sample make a gap; a single missed poll is tolerated.
+func (s *DataQualityService) serverQuality(ctx context.Context, srv config.LicenseServer, start, now time.Time) (*models.ServerDataQuality, error) {
+	quality := &models.ServerDataQuality{Server: srv.Hostname, Schedule: s.pollSpec(srv), Gaps: []models.CollectionGap{}}
+	schedule, err := cron.ParseStandard(quality.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("server %s: invalid schedule: %w", srv.Hostname, err)
+	}
+
+	var rows []struct {
+		Date interface{} `db:"date"`
+		Time interface{} `db:"time"`
+	}
+	query := `SELECT DISTINCT date, time FROM feature_usage WHERE server_hostname = ? AND date >= ?`
+	if err := s.db.SelectContext(ctx, &rows, s.db.Rebind(query), srv.Hostname, start.Format("2006-01-02")); err != nil {
+		return nil, fmt.Errorf("failed to get usage samples of %s: %w", srv.Hostname, err)
+	}
+	samples := make([]time.Time, 0, len(rows))
+	for _, row := range rows {
+		if t := eventTimestamp(row.Date, row.Time); !t.Before(start) && !t.After(now) {
+			samples = append(samples, t)
+		}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Before(samples[j]) })
+
+	quality.Samples = len(samples)
+	if len(samples) > 0 {
+		last := samples[len(samples)-1]
+		quality.LastCollected = &last
+	}
+	for t := schedule.Next(start); !t.After(now); t = schedule.Next(t) {
+		quality.Expected++
+	}
+	if quality.Expected > 0 {
+		quality.Coverage = min(100, float64(quality.Samples)/float64(quality.Expected)*100)
+	}
+
+	// Samples are recorded to the minute and polls may be delayed by jitter
+	grace := time.Minute + time.Duration(srv.Jitter)*time.Second
+	bounds := append(append([]time.Time{start}, samples...), now)
+	for i := 1; i < len(bounds); i++ {
+		from, to := bounds[i-1], bounds[i]
+		missed := 0
+		for t := schedule.Next(from); t.Add(grace).Before(to); t = schedule.Next(t) {
+			missed++
+		}
+		if missed >= 2 {
+			quality.Gaps = append(quality.Gaps, models.CollectionGap{From: from, To: to, MissedPolls: missed})
+		}
+	}
+	if len(quality.Gaps) > maxReportedGaps {
+		quality.Gaps = quality.Gaps[len(quality.Gaps)-maxReportedGaps:]
+	}
+	return quality, nil
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+)
+
+func TestDataQualityService_Report(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	cfg := &config.Config{Servers: []config.LicenseServer{{Hostname: "27000@a", PollInterval: 60}}}
+	s := NewDataQualityService(db, cfg, NewStorageService(db, "sqlite"))
+
+	now := time.Date(2026, 7, 10, 12, 0, 0, 0, time.Local)
+	start := now.AddDate(0, 0, -1)
+	// Hourly samples, except from 06:00 to 09:00 on the last day
+	for at := start.Add(time.Hour); !at.After(now.Add(-time.Hour)); at = at.Add(time.Hour) {
+		if at.Day() == 10 && at.Hour() >= 6 && at.Hour() <= 9 {
+			continue
+		}
+		db.MustExec(`INSERT INTO feature_usage (server_hostname, feature_name, date, time, users_count) VALUES (?, ?, ?, ?, ?)`,
+			"27000@a", "solver", at.Format("2006-01-02"), at.Format("15:04:00"), 1)
+	}
+
+	exp := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	insert := `INSERT INTO features (server_hostname, name, version, vendor_daemon, total_licenses, used_licenses, expiration_date, last_updated, is_active)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1)`
+	db.MustExec(insert, "27000@a", "solver", "", "vendord", 10, 1, exp, now)
+	db.MustExec(insert, "27000@a", "solver", "1.0", "vendord", 10, 1, exp, now.Add(-time.Hour))
+	db.MustExec(insert, "27000@old", "mesher", "", "vendord", 5, 0, exp, now.Add(-48*time.Hour))
+
+	report, err := s.Report(ctx, 1, 24, now)
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+
+	if len(report.Servers) != 1 {
+		t.Fatalf("Expected 1 server, got %d", len(report.Servers))
+	}
+	server := report.Servers[0]
+	if server.Schedule != "@every 60m" || server.Expected != 24 || server.Samples != 19 {
+		t.Errorf("Unexpected server quality %+v", server)
+	}
+	if len(server.Gaps) != 1 || server.Gaps[0].MissedPolls != 4 || server.Gaps[0].From.Hour() != 5 || server.Gaps[0].To.Hour() != 10 {
+		t.Errorf("Expected one gap of 4 polls from 05:00 to 10:00, got %+v", server.Gaps)
+	}
+
+	if len(report.StaleFeatures) != 1 || report.StaleFeatures[0].Server != "27000@old" || report.StaleFeatures[0].Configured {
+		t.Errorf("Expected mesher of an unconfigured server to be stale, got %+v", report.StaleFeatures)
+	}
+	if len(report.Duplicates) != 1 || len(report.Duplicates[0].DuplicateIDs) != 1 {
+		t.Errorf("Expected one duplicate suspect, got %+v", report.Duplicates)
+	}
+	summary := report.Summary
+	if summary.ServersWithGaps != 1 || summary.Gaps != 1 || summary.StaleFeatures != 1 || summary.Duplicates != 1 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+}
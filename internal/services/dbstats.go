@@ -2,21 +2,30 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"time"
+	"sync"
 
 	"github.com/jmoiron/sqlx"
+	"licet/internal/clock"
 	"licet/internal/config"
 	"licet/internal/models"
 )
 
+// ErrUnsupportedTable is returned when cleaning up a table without a
+// retention date
+var ErrUnsupportedTable = errors.New("cleanup not supported for table")
+
 // DBStatsService provides database statistics and maintenance operations
 type DBStatsService struct {
 	db     *sqlx.DB
 	dbType string
 	dbPath string // For SQLite
+	clock  clock.Clock
+
+	retentionMu sync.Mutex // serializes retention runs
 }
 
 // NewDBStatsService creates a new database statistics service
@@ -29,14 +38,20 @@ func NewDBStatsService(db *sqlx.DB, cfg config.DatabaseConfig) *DBStatsService {
 		db:     db,
 		dbType: cfg.Type,
 		dbPath: dbPath,
+		clock:  clock.System,
 	}
 }
 
+// SetClock sets the clock that retention cutoffs are based on
+func (s *DBStatsService) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
 // GetDatabaseStats returns comprehensive database statistics
 func (s *DBStatsService) GetDatabaseStats(ctx context.Context) (*models.DatabaseStats, error) {
 	stats := &models.DatabaseStats{
 		Type:        s.dbType,
-		GeneratedAt: time.Now(),
+		GeneratedAt: s.clock.Now(),
 		Tables:      make([]models.TableStats, 0),
 	}
 
@@ -76,7 +91,7 @@ func (s *DBStatsService) GetDatabaseStats(ctx context.Context) (*models.Database
 	switch s.dbType {
 	case "sqlite":
 		s.getSQLiteStats(ctx, stats)
-	case "postgres", "postgresql":
+	case "postgres", "postgresql", "timescaledb":
 		s.getPostgresStats(ctx, stats)
 	case "mysql":
 		s.getMySQLStats(ctx, stats)
@@ -91,7 +106,7 @@ func (s *DBStatsService) GetDatabaseStats(ctx context.Context) (*models.Database
 // getTableStats returns statistics for each table
 func (s *DBStatsService) getTableStats(ctx context.Context) ([]models.TableStats, error) {
 	tables := []models.TableStats{}
-	tableNames := []string{"servers", "features", "feature_usage", "license_events", "alerts", "alert_events"}
+	tableNames := []string{"servers", "features", "feature_usage", "feature_usage_hourly", "feature_usage_daily", "license_events", "alerts", "alert_events"}
 
 	for _, tableName := range tableNames {
 		ts := models.TableStats{Name: tableName}
@@ -112,9 +127,13 @@ func (s *DBStatsService) getTableStats(ctx context.Context) ([]models.TableStats
 			var pageCount int64
 			// This is an approximation
 			s.db.GetContext(ctx, &pageCount, "SELECT COUNT(*) * 4096 FROM "+tableName+" LIMIT 1")
-		case "postgres", "postgresql":
+		case "postgres", "postgresql", "timescaledb":
 			var size int64
 			query := fmt.Sprintf("SELECT pg_total_relation_size('%s')", tableName)
+			if s.dbType == "timescaledb" && tableName == "feature_usage" {
+				// The size of a hypertable is spread over its chunks
+				query = "SELECT hypertable_size('feature_usage')"
+			}
 			s.db.GetContext(ctx, &size, query)
 			ts.SizeBytes = size
 			ts.SizeHuman = formatBytes(size)
@@ -248,7 +267,7 @@ func (s *DBStatsService) analyzeSpaceOptimization(ctx context.Context, stats *mo
 		// Check for inactive features
 		if table.Name == "features" {
 			var inactiveCount int64
-			s.db.GetContext(ctx, &inactiveCount, "SELECT COUNT(*) FROM features WHERE is_active = 0")
+			s.db.GetContext(ctx, &inactiveCount, "SELECT COUNT(*) FROM features WHERE is_active = FALSE")
 			if inactiveCount > 100 {
 				recommendations = append(recommendations, models.SpaceRecommendation{
 					Type:        "cleanup",
@@ -280,7 +299,7 @@ func (s *DBStatsService) analyzeSpaceOptimization(ctx context.Context, stats *mo
 // VacuumDatabase runs VACUUM to optimize the database
 func (s *DBStatsService) VacuumDatabase(ctx context.Context) (*models.VacuumResult, error) {
 	result := &models.VacuumResult{
-		StartedAt: time.Now(),
+		StartedAt: s.clock.Now(),
 	}
 
 	// Get size before
@@ -298,7 +317,7 @@ func (s *DBStatsService) VacuumDatabase(ctx context.Context) (*models.VacuumResu
 		if _, err := s.db.ExecContext(ctx, "VACUUM"); err != nil {
 			return nil, fmt.Errorf("vacuum failed: %w", err)
 		}
-	case "postgres", "postgresql":
+	case "postgres", "postgresql", "timescaledb":
 		if _, err := s.db.ExecContext(ctx, "VACUUM ANALYZE"); err != nil {
 			return nil, fmt.Errorf("vacuum failed: %w", err)
 		}
@@ -310,7 +329,7 @@ func (s *DBStatsService) VacuumDatabase(ctx context.Context) (*models.VacuumResu
 		}
 	}
 
-	result.CompletedAt = time.Now()
+	result.CompletedAt = s.clock.Now()
 	result.Duration = result.CompletedAt.Sub(result.StartedAt)
 
 	// Get size after
@@ -332,54 +351,65 @@ func (s *DBStatsService) VacuumDatabase(ctx context.Context) (*models.VacuumResu
 func (s *DBStatsService) CleanupOldData(ctx context.Context, tableName string, days int) (*models.CleanupResult, error) {
 	result := &models.CleanupResult{
 		TableName: tableName,
-		StartedAt: time.Now(),
+		StartedAt: s.clock.Now(),
 	}
 
-	cutoffDate := time.Now().AddDate(0, 0, -days)
-
-	var query string
-	var dateColumn string
-
-	switch tableName {
-	case "feature_usage":
-		dateColumn = "date"
-		query = "DELETE FROM feature_usage WHERE date < ?"
-	case "license_events":
-		dateColumn = "event_date"
-		query = "DELETE FROM license_events WHERE event_date < ?"
-	case "alerts":
-		dateColumn = "created_at"
-		query = "DELETE FROM alerts WHERE created_at < ? AND sent = 1"
-	case "alert_events":
-		dateColumn = "datetime"
-		query = "DELETE FROM alert_events WHERE datetime < ?"
-	default:
-		return nil, fmt.Errorf("cleanup not supported for table: %s", tableName)
-	}
+	cutoffDate := s.clock.Now().AddDate(0, 0, -days)
 
-	// Get count before deletion
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s < ?", tableName, dateColumn)
-	var rowsToDelete int64
-	if err := s.db.GetContext(ctx, &rowsToDelete, countQuery, cutoffDate); err != nil {
-		return nil, fmt.Errorf("failed to count rows: %w", err)
+	query, err := cleanupQuery(tableName)
+	if err != nil {
+		return nil, err
 	}
-	result.RowsDeleted = rowsToDelete
 
 	// Execute deletion
-	res, err := s.db.ExecContext(ctx, query, cutoffDate)
+	res, err := s.db.ExecContext(ctx, s.db.Rebind("DELETE"+query), cutoffDate)
 	if err != nil {
 		return nil, fmt.Errorf("cleanup failed: %w", err)
 	}
 
 	affected, _ := res.RowsAffected()
 	result.RowsDeleted = affected
-	result.CompletedAt = time.Now()
+	result.CompletedAt = s.clock.Now()
 	result.Duration = result.CompletedAt.Sub(result.StartedAt)
 	result.Success = true
 
 	return result, nil
 }
 
+// cleanupQuery returns the FROM and WHERE clauses selecting the rows of a
+// table older than a cutoff, to follow DELETE or SELECT COUNT(*)
+func cleanupQuery(tableName string) (string, error) {
+	switch tableName {
+	case "feature_usage", "feature_usage_hourly", "feature_usage_daily":
+		return " FROM " + tableName + " WHERE date < ?", nil
+	case "license_events":
+		return " FROM license_events WHERE event_date < ?", nil
+	case "alerts":
+		return " FROM alerts WHERE created_at < ? AND (sent = TRUE OR silence_id IS NOT NULL)", nil
+	case "alert_events":
+		return " FROM alert_events WHERE datetime < ?", nil
+	case "alert_deliveries", "webhook_deliveries", "audit_log":
+		return " FROM " + tableName + " WHERE created_at < ?", nil
+	case "waitlist_subscriptions":
+		return " FROM waitlist_subscriptions WHERE notified_at < ?", nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedTable, tableName)
+	}
+}
+
+// countOldData returns the number of rows CleanupOldData would remove
+func (s *DBStatsService) countOldData(ctx context.Context, tableName string, days int) (int64, error) {
+	query, err := cleanupQuery(tableName)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	if err := s.db.GetContext(ctx, &count, s.db.Rebind("SELECT COUNT(*)"+query), s.clock.Now().AddDate(0, 0, -days)); err != nil {
+		return 0, fmt.Errorf("failed to count rows: %w", err)
+	}
+	return count, nil
+}
+
 // GetRetentionStats returns statistics about data retention
 func (s *DBStatsService) GetRetentionStats(ctx context.Context) (*models.RetentionStats, error) {
 	stats := &models.RetentionStats{}
@@ -396,7 +426,7 @@ func (s *DBStatsService) GetRetentionStats(ctx context.Context) (*models.Retenti
 
 	// Alerts stats
 	s.db.GetContext(ctx, &stats.AlertsTotal, "SELECT COUNT(*) FROM alerts")
-	s.db.GetContext(ctx, &stats.AlertsSent, "SELECT COUNT(*) FROM alerts WHERE sent = 1")
+	s.db.GetContext(ctx, &stats.AlertsSent, "SELECT COUNT(*) FROM alerts WHERE sent = TRUE")
 
 	// Calculate potential savings
 	olderThan90 := stats.UsageRecordsTotal - stats.UsageRecords90Days
@@ -416,7 +446,7 @@ func (s *DBStatsService) AnalyzeDatabase(ctx context.Context) error {
 	case "sqlite":
 		_, err := s.db.ExecContext(ctx, "ANALYZE")
 		return err
-	case "postgres", "postgresql":
+	case "postgres", "postgresql", "timescaledb":
 		_, err := s.db.ExecContext(ctx, "ANALYZE")
 		return err
 	case "mysql":
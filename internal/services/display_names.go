@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/parsers"
+)
+
+// ErrDisplayNameNotFound is returned when deleting an override that does not exist
+var ErrDisplayNameNotFound = errors.New("display name override not found")
+
+// displayRule is a compiled display name rule
+type displayRule struct {
+	pattern     *regexp.Regexp
+	displayName string
+}
+
+// DisplayNameService maps raw feature and vendor daemon names to readable
+// display names. Manual overrides stored in the database take precedence over
+// regex rules from the config, and those over the product names of servers
+// with the mlm profile. All methods are safe on a nil receiver, in
+// which case names are left unchanged.
+type DisplayNameService struct {
+	db      *sqlx.DB
+	vendors map[string]string
+	rules   []displayRule
+	mlm     map[string]bool // Servers with the mlm profile, set by SetServerProfiles
+
+	mu        sync.RWMutex
+	overrides map[string]string // "server|feature" -> display name
+}
+
+// NewDisplayNameService creates a display name service from config rules
+func NewDisplayNameService(db *sqlx.DB, cfg config.DisplayConfig) (*DisplayNameService, error) {
+	s := &DisplayNameService{
+		db:        db,
+		vendors:   make(map[string]string, len(cfg.Vendors)),
+		overrides: make(map[string]string),
+	}
+
+	// Viper lowercases map keys, so vendor lookups are case-insensitive
+	for vendor, name := range cfg.Vendors {
+		s.vendors[strings.ToLower(vendor)] = name
+	}
+
+	for _, rule := range cfg.Rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid display name pattern %q: %w", rule.Pattern, err)
+		}
+		s.rules = append(s.rules, displayRule{pattern: re, displayName: rule.DisplayName})
+	}
+
+	return s, nil
+}
+
+// SetServerProfiles names the features of servers with the mlm profile after
+// the MathWorks products they license, e.g. Signal_Toolbox after the Signal
+// Processing Toolbox
+func (s *DisplayNameService) SetServerProfiles(servers []config.LicenseServer) {
+	s.mlm = make(map[string]bool)
+	for _, srv := range servers {
+		if srv.Profile == parsers.ProfileMLM {
+			s.mlm[srv.Hostname] = true
+		}
+	}
+}
+
+// Reload refreshes the cached overrides from the database
+func (s *DisplayNameService) Reload(ctx context.Context) error {
+	overrides, err := s.ListOverrides(ctx)
+	if err != nil {
+		return err
+	}
+
+	cache := make(map[string]string, len(overrides))
+	for _, o := range overrides {
+		cache[o.ServerHostname+"|"+o.FeatureName] = o.DisplayName
+	}
+
+	s.mu.Lock()
+	s.overrides = cache
+	s.mu.Unlock()
+
+	log.Debugf("Loaded %d feature display name overrides", len(cache))
+	return nil
+}
+
+// FeatureName returns the display name for a feature on a server
+func (s *DisplayNameService) FeatureName(server, feature string) string {
+	if s == nil {
+		return feature
+	}
+
+	s.mu.RLock()
+	name, ok := s.overrides[server+"|"+feature]
+	if !ok {
+		name, ok = s.overrides["|"+feature]
+	}
+	s.mu.RUnlock()
+	if ok {
+		return name
+	}
+
+	for _, rule := range s.rules {
+		if m := rule.pattern.FindStringSubmatchIndex(feature); m != nil {
+			return string(rule.pattern.ExpandString(nil, rule.displayName, feature, m))
+		}
+	}
+
+	if s.mlm[server] {
+		return parsers.LookupMLMProduct(feature).Name
+	}
+
+	return feature
+}
+
+// VendorName returns the display name for a vendor daemon
+func (s *DisplayNameService) VendorName(vendor string) string {
+	if s == nil {
+		return vendor
+	}
+	if name, ok := s.vendors[strings.ToLower(vendor)]; ok {
+		return name
+	}
+	return vendor
+}
+
+// ApplyToFeatures sets display names on features
+func (s *DisplayNameService) ApplyToFeatures(features []models.Feature) {
+	for i := range features {
+		features[i].DisplayName = s.FeatureName(features[i].ServerHostname, features[i].Name)
+		features[i].VendorName = s.VendorName(features[i].VendorDaemon)
+	}
+}
+
+// ApplyToUtilization sets display names on utilization data
+func (s *DisplayNameService) ApplyToUtilization(utilization []models.UtilizationData) {
+	for i := range utilization {
+		utilization[i].DisplayName = s.FeatureName(utilization[i].ServerHostname, utilization[i].FeatureName)
+		utilization[i].VendorName = s.VendorName(utilization[i].VendorDaemon)
+	}
+}
+
+// ApplyToStats sets display names on utilization statistics
+func (s *DisplayNameService) ApplyToStats(stats []models.UtilizationStats) {
+	for i := range stats {
+		stats[i].DisplayName = s.FeatureName(stats[i].ServerHostname, stats[i].FeatureName)
+	}
+}
+
+// ListOverrides returns all manual display name overrides
+func (s *DisplayNameService) ListOverrides(ctx context.Context) ([]models.FeatureDisplayName, error) {
+	var overrides []models.FeatureDisplayName
+	query := `SELECT * FROM feature_display_names ORDER BY feature_name, server_hostname`
+	err := s.db.SelectContext(ctx, &overrides, query)
+	return overrides, err
+}
+
+// SetOverride creates or replaces a manual display name override
+func (s *DisplayNameService) SetOverride(ctx context.Context, server, feature, displayName string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM feature_display_names WHERE server_hostname = ? AND feature_name = ?`,
+		server, feature)
+	if err != nil {
+		return fmt.Errorf("failed to replace display name for %s: %w", feature, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO feature_display_names (server_hostname, feature_name, display_name) VALUES (?, ?, ?)`,
+		server, feature, displayName)
+	if err != nil {
+		return fmt.Errorf("failed to store display name for %s: %w", feature, err)
+	}
+	if err := touchMetadata(ctx, tx, metadataDisplayNames, time.Now()); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return s.Reload(ctx)
+}
+
+// DeleteOverride removes a manual display name override
+func (s *DisplayNameService) DeleteOverride(ctx context.Context, server, feature string) error {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM feature_display_names WHERE server_hostname = ? AND feature_name = ?`,
+		server, feature)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrDisplayNameNotFound
+	}
+	if err := touchMetadata(ctx, s.db, metadataDisplayNames, time.Now()); err != nil {
+		return err
+	}
+	return s.Reload(ctx)
+}
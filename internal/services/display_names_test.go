@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestDisplayNameService_Resolution(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	names, err := NewDisplayNameService(db, config.DisplayConfig{
+		Vendors: map[string]string{"adskflex": "Autodesk"},
+		Rules: []config.DisplayNameRule{
+			{Pattern: `^85693ACD_(\d{4})_0F$`, DisplayName: "AutoCAD $1"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewDisplayNameService failed: %v", err)
+	}
+
+	if got := names.FeatureName("27000@a", "85693ACD_2023_0F"); got != "AutoCAD 2023" {
+		t.Errorf("rule: got %q, want %q", got, "AutoCAD 2023")
+	}
+	if got := names.FeatureName("27000@a", "MAYA"); got != "MAYA" {
+		t.Errorf("unmatched: got %q, want raw name", got)
+	}
+	if got := names.VendorName("ADSKFLEX"); got != "Autodesk" {
+		t.Errorf("vendor: got %q, want %q", got, "Autodesk")
+	}
+
+	// Global override beats rules, server override beats global
+	if err := names.SetOverride(ctx, "", "85693ACD_2023_0F", "AutoCAD LT 2023"); err != nil {
+		t.Fatalf("SetOverride failed: %v", err)
+	}
+	if err := names.SetOverride(ctx, "27000@b", "85693ACD_2023_0F", "AutoCAD (B)"); err != nil {
+		t.Fatalf("SetOverride failed: %v", err)
+	}
+	if got := names.FeatureName("27000@a", "85693ACD_2023_0F"); got != "AutoCAD LT 2023" {
+		t.Errorf("global override: got %q", got)
+	}
+	if got := names.FeatureName("27000@b", "85693ACD_2023_0F"); got != "AutoCAD (B)" {
+		t.Errorf("server override: got %q", got)
+	}
+
+	// Replacing an override keeps a single row
+	if err := names.SetOverride(ctx, "", "85693ACD_2023_0F", "AutoCAD 2023 (LT)"); err != nil {
+		t.Fatalf("SetOverride failed: %v", err)
+	}
+	overrides, err := names.ListOverrides(ctx)
+	if err != nil || len(overrides) != 2 {
+		t.Fatalf("expected 2 overrides, got %d (%v)", len(overrides), err)
+	}
+
+	features := []models.Feature{{ServerHostname: "27000@a", Name: "85693ACD_2023_0F", VendorDaemon: "adskflex"}}
+	names.ApplyToFeatures(features)
+	if features[0].Name != "85693ACD_2023_0F" || features[0].DisplayName != "AutoCAD 2023 (LT)" || features[0].VendorName != "Autodesk" {
+		t.Errorf("unexpected feature after apply: %+v", features[0])
+	}
+
+	if err := names.DeleteOverride(ctx, "", "85693ACD_2023_0F"); err != nil {
+		t.Fatalf("DeleteOverride failed: %v", err)
+	}
+	if got := names.FeatureName("27000@a", "85693ACD_2023_0F"); got != "AutoCAD 2023" {
+		t.Errorf("after delete: got %q, want rule result", got)
+	}
+	if err := names.DeleteOverride(ctx, "", "85693ACD_2023_0F"); !errors.Is(err, ErrDisplayNameNotFound) {
+		t.Errorf("expected ErrDisplayNameNotFound, got %v", err)
+	}
+}
+
+func TestDisplayNameService_InvalidPattern(t *testing.T) {
+	_, err := NewDisplayNameService(nil, config.DisplayConfig{
+		Rules: []config.DisplayNameRule{{Pattern: "(", DisplayName: "x"}},
+	})
+	if err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestDisplayNameService_NilSafe(t *testing.T) {
+	var names *DisplayNameService
+	features := []models.Feature{{Name: "F1", VendorDaemon: "v"}}
+	names.ApplyToFeatures(features)
+	if features[0].DisplayName != "F1" || features[0].VendorName != "v" {
+		t.Errorf("nil service should fall back to raw names, got %+v", features[0])
+	}
+}
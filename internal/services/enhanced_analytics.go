@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"runtime"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"licet/internal/clock"
 	"licet/internal/models"
 )
 
@@ -17,6 +20,9 @@ type EnhancedAnalyticsService struct {
 	db        *sqlx.DB
 	storage   *StorageService
 	analytics *AnalyticsService
+	clock     clock.Clock
+
+	reportMu sync.Mutex // serializes capacity report generation
 }
 
 // NewEnhancedAnalyticsService creates a new enhanced analytics service
@@ -25,9 +31,16 @@ func NewEnhancedAnalyticsService(db *sqlx.DB, storage *StorageService, dbType st
 		db:        db,
 		storage:   storage,
 		analytics: NewAnalyticsService(db, storage, dbType),
+		clock:     clock.System,
 	}
 }
 
+// SetClock sets the clock that analysis periods end at
+func (s *EnhancedAnalyticsService) SetClock(c clock.Clock) {
+	s.clock = c
+	s.analytics.SetClock(c)
+}
+
 // GetEnhancedStatistics returns comprehensive statistics for a feature
 func (s *EnhancedAnalyticsService) GetEnhancedStatistics(ctx context.Context, server, feature string, days int) (*models.EnhancedStatistics, error) {
 	// Get usage history
@@ -69,29 +82,23 @@ func (s *EnhancedAnalyticsService) GetEnhancedStatistics(ctx context.Context, se
 	median := calculateMedian(values)
 	stdDev := calculateStdDev(values, avgUsage)
 
-	// Calculate trend using linear regression
-	xValues := make([]float64, len(usage))
-	yValues := make([]float64, len(usage))
-	startTime := usage[len(usage)-1].Date
-	for i := len(usage) - 1; i >= 0; i-- {
-		xValues[len(usage)-1-i] = usage[i].Date.Sub(startTime).Hours() / 24
-		yValues[len(usage)-1-i] = float64(usage[i].UsersCount)
-	}
-	slope, _ := linearRegression(xValues, yValues)
+	// Calculate trend using linear regression, leaving out holidays
+	trend := trendFromUsage(usage, s.storage.holidays)
+	slope := trend.Slope
 
 	// Determine trend direction and strength
 	trendDirection := "stable"
 	trendStrength := "weak"
-	if slope > 0.1 {
+	if slope > trendDirectionSlope {
 		trendDirection = "increasing"
-	} else if slope < -0.1 {
+	} else if slope < -trendDirectionSlope {
 		trendDirection = "decreasing"
 	}
 
 	absSlope := math.Abs(slope)
-	if absSlope > 1.0 {
+	if absSlope > trendStrongSlope {
 		trendStrength = "strong"
-	} else if absSlope > 0.3 {
+	} else if absSlope > trendModerateSlope {
 		trendStrength = "moderate"
 	}
 
@@ -102,16 +109,17 @@ func (s *EnhancedAnalyticsService) GetEnhancedStatistics(ctx context.Context, se
 	// Calculate utilization metrics
 	avgUtilization := 0.0
 	peakUtilization := 0.0
-	if currentFeature.TotalLicenses > 0 {
-		avgUtilization = (avgUsage / float64(currentFeature.TotalLicenses)) * 100
-		peakUtilization = (float64(maxVal) / float64(currentFeature.TotalLicenses)) * 100
+	if currentFeature.CountedLicenses() > 0 {
+		avgUtilization = (avgUsage / float64(currentFeature.CountedLicenses())) * 100
+		peakUtilization = (float64(maxVal) / float64(currentFeature.CountedLicenses())) * 100
 	}
 
 	// Calculate time patterns from actual heatmap data
 	peakHour := 0
-	peakDayOfWeek := 0
-	weekdayAvg := avgUsage
-	weekendAvg := avgUsage
+	peakDayOfWeek, weekdayAvg, weekendAvg, holidayAvg := weeklyPattern(usage, s.storage.holidays)
+	if weekdayAvg == 0 && weekendAvg == 0 {
+		weekdayAvg, weekendAvg = avgUsage, avgUsage
+	}
 
 	heatmapData, heatErr := s.analytics.GetHeatmapData(ctx, server, days)
 	if heatErr == nil {
@@ -130,16 +138,29 @@ func (s *EnhancedAnalyticsService) GetEnhancedStatistics(ctx context.Context, se
 	}
 
 	// Calculate efficiency score
-	efficiencyScore := calculateEfficiencyScore(avgUtilization, peakUtilization, stdDev, float64(currentFeature.TotalLicenses))
+	efficiencyScore := calculateEfficiencyScore(avgUtilization, peakUtilization, stdDev, float64(currentFeature.CountedLicenses()))
 
 	// Generate recommendations
-	recommendations := generateRecommendations(avgUtilization, peakUtilization, trendDirection, slope, currentFeature.TotalLicenses)
+	recommendations, checks := generateRecommendations(avgUtilization, peakUtilization, trendDirection, slope, currentFeature.CountedLicenses())
+
+	// Weigh the seats unused even at peak by the cost of the license contract
+	contracts, err := s.storage.GetContracts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	contract := contractFor(contracts, server, feature)
+	idleSeats := max(currentFeature.CountedLicenses()-maxVal, 0)
+	rec, check := costRecommendation(contract, idleSeats, maxVal, currentFeature.CountedLicenses())
+	checks = append(checks, check)
+	if rec != nil {
+		recommendations = append(recommendations, *rec)
+	}
 
-	return &models.EnhancedStatistics{
+	stats := &models.EnhancedStatistics{
 		ServerHostname:     server,
 		FeatureName:        feature,
 		Period:             fmt.Sprintf("%d days", days),
-		TotalLicenses:      currentFeature.TotalLicenses,
+		TotalLicenses:      currentFeature.CountedLicenses(),
 		AvgUsage:           avgUsage,
 		MedianUsage:        median,
 		PeakUsage:          maxVal,
@@ -156,21 +177,30 @@ func (s *EnhancedAnalyticsService) GetEnhancedStatistics(ctx context.Context, se
 		PeakDayOfWeek:      peakDayOfWeek,
 		WeekdayAvg:         weekdayAvg,
 		WeekendAvg:         weekendAvg,
+		HolidayAvg:         holidayAvg,
 		EfficiencyScore:    efficiencyScore,
 		UnderutilizedHours: int((100 - avgUtilization) / 10),
+		IdleSeats:          idleSeats,
+		Contract:           contract,
 		Recommendations:    recommendations,
-	}, nil
+	}
+	if contract != nil {
+		stats.AnnualCost = contract.CostPerSeat * float64(currentFeature.CountedLicenses())
+		stats.PotentialSavings = contract.CostPerSeat * float64(idleSeats)
+	}
+	stats.Explanation = explainStatistics(stats, trend, len(usage), checks)
+	return stats, nil
 }
 
-// GetTrendAnalysis returns detailed trend analysis for a feature
+// GetTrendAnalysis returns detailed trend analysis for a feature. The trend
+// is precomputed during collection when available.
 func (s *EnhancedAnalyticsService) GetTrendAnalysis(ctx context.Context, server, feature string, days int) (*models.TrendAnalysis, error) {
-	// Get usage history
-	usage, err := s.storage.GetFeatureUsageHistory(ctx, server, feature, days)
+	trend, err := s.storage.featureTrend(ctx, server, feature, days)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(usage) < 7 {
+	if trend.Samples < minTrendSamples {
 		return nil, fmt.Errorf("insufficient data for trend analysis (need at least 7 data points)")
 	}
 
@@ -182,29 +212,18 @@ func (s *EnhancedAnalyticsService) GetTrendAnalysis(ctx context.Context, server,
 		return nil, err
 	}
 
-	// Prepare data for regression
-	xValues := make([]float64, len(usage))
-	yValues := make([]float64, len(usage))
-	startTime := usage[len(usage)-1].Date
-	for i := len(usage) - 1; i >= 0; i-- {
-		xValues[len(usage)-1-i] = usage[i].Date.Sub(startTime).Hours() / 24
-		yValues[len(usage)-1-i] = float64(usage[i].UsersCount)
-	}
-
-	// Calculate linear regression
-	slope, intercept := linearRegression(xValues, yValues)
-	rSquared := calculateRSquared(xValues, yValues, slope, intercept)
+	slope, intercept, rSquared := trend.Slope, trend.Intercept, trend.RSquared
 
 	// Determine trend direction
 	direction := "stable"
-	if slope > 0.1 {
+	if slope > trendDirectionSlope {
 		direction = "increasing"
-	} else if slope < -0.1 {
+	} else if slope < -trendDirectionSlope {
 		direction = "decreasing"
 	}
 
 	// Calculate projections
-	lastDay := xValues[len(xValues)-1]
+	lastDay := trend.LastDay
 	projected7Days := slope*(lastDay+7) + intercept
 	projected30Days := slope*(lastDay+30) + intercept
 	projected90Days := slope*(lastDay+90) + intercept
@@ -225,26 +244,26 @@ func (s *EnhancedAnalyticsService) GetTrendAnalysis(ctx context.Context, server,
 	capacityAtRisk := false
 	recommendedAction := "No action required"
 
-	if slope > 0 && currentFeature.TotalLicenses > 0 {
+	if slope > 0 && currentFeature.CountedLicenses() > 0 {
 		currentUsage := slope*lastDay + intercept
-		remainingCapacity := float64(currentFeature.TotalLicenses) - currentUsage
+		remainingCapacity := float64(currentFeature.CountedLicenses()) - currentUsage
 		if remainingCapacity > 0 {
 			daysToCapacity = int(remainingCapacity / slope)
-			if daysToCapacity < 30 {
+			if daysToCapacity < capacityRiskDays {
 				capacityAtRisk = true
 				recommendedAction = "Consider increasing license count within 30 days"
-			} else if daysToCapacity < 90 {
+			} else if daysToCapacity < capacityWatchDays {
 				recommendedAction = "Monitor usage and plan for potential license increase"
 			}
 		} else {
 			capacityAtRisk = true
 			recommendedAction = "Immediately increase license count - at capacity"
 		}
-	} else if slope < -0.5 && currentFeature.TotalLicenses > 0 {
+	} else if slope < -trendReduceSlope && currentFeature.CountedLicenses() > 0 {
 		recommendedAction = "Consider reducing license count to optimize costs"
 	}
 
-	return &models.TrendAnalysis{
+	analysis := &models.TrendAnalysis{
 		ServerHostname:       server,
 		FeatureName:          feature,
 		Period:               days,
@@ -261,19 +280,39 @@ func (s *EnhancedAnalyticsService) GetTrendAnalysis(ctx context.Context, server,
 		DaysToCapacity:       daysToCapacity,
 		CapacityAtRisk:       capacityAtRisk,
 		RecommendedAction:    recommendedAction,
-	}, nil
+	}
+	analysis.Explanation = explainTrend(analysis, trend, currentFeature.CountedLicenses())
+	return analysis, nil
 }
 
 // GetCapacityPlanningReport generates a comprehensive capacity planning report
 func (s *EnhancedAnalyticsService) GetCapacityPlanningReport(ctx context.Context, days int) (*models.CapacityPlanningReport, error) {
+	return s.capacityPlanningReport(ctx, days, nil)
+}
+
+// GetFilteredCapacityReport generates the capacity planning report of the
+// features in the set. It is not stored.
+func (s *EnhancedAnalyticsService) GetFilteredCapacityReport(ctx context.Context, days int, set *FeatureSet) (*models.CapacityPlanningReport, error) {
+	return s.capacityPlanningReport(ctx, days, set)
+}
+
+// capacityPlanningReport generates the capacity planning report of the
+// features in the set
+func (s *EnhancedAnalyticsService) capacityPlanningReport(ctx context.Context, days int, set *FeatureSet) (*models.CapacityPlanningReport, error) {
 	// Get all utilization data
-	utilization, err := s.GetCurrentUtilizationWithTrend(ctx, "", days)
+	all, err := s.GetCurrentUtilizationWithTrend(ctx, "", days)
 	if err != nil {
 		return nil, err
 	}
+	utilization := make([]UtilizationWithTrend, 0, len(all))
+	for _, u := range all {
+		if set.Contains(u.ServerHostname, u.FeatureName) {
+			utilization = append(utilization, u)
+		}
+	}
 
 	report := &models.CapacityPlanningReport{
-		GeneratedAt:    time.Now().UTC().Format(time.RFC3339),
+		GeneratedAt:    s.clock.Now().UTC().Format(time.RFC3339),
 		PeriodAnalyzed: days,
 		TotalFeatures:  len(utilization),
 	}
@@ -285,6 +324,11 @@ func (s *EnhancedAnalyticsService) GetCapacityPlanningReport(ctx context.Context
 	}
 	report.TotalServers = len(servers)
 
+	contracts, err := s.storage.GetContracts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Categorize features
 	for _, u := range utilization {
 		insight := models.CapacityInsight{
@@ -296,35 +340,93 @@ func (s *EnhancedAnalyticsService) GetCapacityPlanningReport(ctx context.Context
 			UtilizationPct: u.UtilizationPct,
 			TrendSlope:     u.TrendSlope,
 			DaysToCapacity: u.DaysToCapacity,
+			IdleSeats:      max(u.TotalLicenses-u.PeakUsage, 0),
+		}
+		if c := contractFor(contracts, u.ServerHostname, u.FeatureName); c != nil && c.CostPerSeat > 0 && insight.IdleSeats > 0 {
+			insight.PotentialSavings = c.CostPerSeat * float64(insight.IdleSeats)
+			insight.Currency = c.Currency
 		}
 
 		// Categorize by utilization
-		if u.UtilizationPct >= 80 {
+		if u.UtilizationPct >= capacityHighUtilizationPct {
 			report.FeaturesAtCapacity++
 			insight.Recommendation = "High utilization - consider increasing licenses"
 			report.HighUtilization = append(report.HighUtilization, insight)
-		} else if u.UtilizationPct <= 20 {
+		} else if u.UtilizationPct <= capacityLowUtilizationPct {
 			report.FeaturesUnderutilized++
 			insight.Recommendation = "Low utilization - consider reducing licenses"
 			report.LowUtilization = append(report.LowUtilization, insight)
 		}
 
 		// Categorize by trend
-		if u.TrendSlope > 0.5 {
+		if u.TrendSlope > capacityTrendSlope {
 			insight.Recommendation = "Usage increasing - monitor capacity"
 			report.TrendingUp = append(report.TrendingUp, insight)
-		} else if u.TrendSlope < -0.5 {
+		} else if u.TrendSlope < -capacityTrendSlope {
 			insight.Recommendation = "Usage decreasing - opportunity to optimize"
 			report.TrendingDown = append(report.TrendingDown, insight)
 		}
+
+		if insight.PotentialSavings > 0 {
+			report.CostSavings = append(report.CostSavings, insight)
+		}
 	}
+	sort.SliceStable(report.CostSavings, func(i, j int) bool {
+		return report.CostSavings[i].PotentialSavings > report.CostSavings[j].PotentialSavings
+	})
+
+	groups, err := s.storage.GetServerGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.Groups = groupCapacity(utilization, groups)
 
 	// Generate summary recommendations
-	report.Recommendations = generateCapacityRecommendations(report)
+	report.Recommendations, _ = generateCapacityRecommendations(report)
 
 	return report, nil
 }
 
+// groupCapacity sums the utilization of the features by the group of their
+// server, in order of the groups. Features of ungrouped servers are left out.
+func groupCapacity(utilization []UtilizationWithTrend, groups map[string]string) []models.GroupCapacity {
+	byGroup := make(map[string]*models.GroupCapacity)
+	servers := make(map[string]map[string]bool)
+	for _, u := range utilization {
+		name := groups[u.ServerHostname]
+		if name == "" {
+			continue
+		}
+		g := byGroup[name]
+		if g == nil {
+			g = &models.GroupCapacity{Group: name}
+			byGroup[name] = g
+			servers[name] = make(map[string]bool)
+		}
+		servers[name][u.ServerHostname] = true
+		g.Features++
+		g.TotalLicenses += u.TotalLicenses
+		g.AvgUsage += u.AvgUsage
+		g.PeakUsage += u.PeakUsage
+		if u.UtilizationPct >= capacityHighUtilizationPct {
+			g.FeaturesAtCapacity++
+		} else if u.UtilizationPct <= capacityLowUtilizationPct {
+			g.FeaturesUnderutilized++
+		}
+	}
+
+	result := make([]models.GroupCapacity, 0, len(byGroup))
+	for name, g := range byGroup {
+		g.Servers = len(servers[name])
+		if g.TotalLicenses > 0 {
+			g.UtilizationPct = g.AvgUsage / float64(g.TotalLicenses) * 100
+		}
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Group < result[j].Group })
+	return result
+}
+
 // UtilizationWithTrend combines utilization data with trend information
 type UtilizationWithTrend struct {
 	ServerHostname string
@@ -337,7 +439,10 @@ type UtilizationWithTrend struct {
 	DaysToCapacity int
 }
 
-// GetCurrentUtilizationWithTrend returns utilization data enriched with trend information
+// GetCurrentUtilizationWithTrend returns utilization data enriched with trend information.
+// Precomputed trends are used where available. The usage history of the
+// remaining features is loaded in one query and their trends are computed by
+// a bounded pool of workers; the result keeps the order of the utilization stats.
 func (s *EnhancedAnalyticsService) GetCurrentUtilizationWithTrend(ctx context.Context, serverFilter string, days int) ([]UtilizationWithTrend, error) {
 	// Delegate to the composed AnalyticsService for base stats
 	stats, err := s.analytics.GetUtilizationStats(ctx, serverFilter, days)
@@ -345,55 +450,145 @@ func (s *EnhancedAnalyticsService) GetCurrentUtilizationWithTrend(ctx context.Co
 		return nil, err
 	}
 
-	var result []UtilizationWithTrend
+	trends, err := s.storage.GetFeatureTrends(ctx, serverFilter, days)
+	if err != nil {
+		return nil, err
+	}
+
+	var history map[UsageKey][]models.FeatureUsage
 	for _, stat := range stats {
-		utilPct := 0.0
-		if stat.TotalLicenses > 0 {
-			utilPct = (stat.AvgUsage / float64(stat.TotalLicenses)) * 100
+		if _, ok := trends[UsageKey{stat.ServerHostname, stat.FeatureName}]; !ok {
+			history, err = s.storage.GetUsageHistoryBatch(ctx, serverFilter, days)
+			if err != nil {
+				return nil, err
+			}
+			break
 		}
+	}
 
-		// Get trend for this feature
-		slope := 0.0
-		daysToCapacity := -1
-
-		usage, err := s.storage.GetFeatureUsageHistory(ctx, stat.ServerHostname, stat.FeatureName, days)
-		if err == nil && len(usage) >= 7 {
-			xValues := make([]float64, len(usage))
-			yValues := make([]float64, len(usage))
-			startTime := usage[len(usage)-1].Date
-			for i := len(usage) - 1; i >= 0; i-- {
-				xValues[len(usage)-1-i] = usage[i].Date.Sub(startTime).Hours() / 24
-				yValues[len(usage)-1-i] = float64(usage[i].UsersCount)
-			}
-			slope, _ = linearRegression(xValues, yValues)
-
-			if slope > 0 && stat.TotalLicenses > 0 {
-				lastDay := xValues[len(xValues)-1]
-				currentUsage := slope*lastDay + yValues[len(yValues)-1]
-				remainingCapacity := float64(stat.TotalLicenses) - currentUsage
-				if remainingCapacity > 0 {
-					daysToCapacity = int(remainingCapacity / slope)
+	result := make([]UtilizationWithTrend, len(stats))
+
+	workers := runtime.NumCPU()
+	if len(stats) < workers {
+		workers = len(stats)
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				stat := stats[i]
+				utilPct := 0.0
+				if stat.TotalLicenses > 0 {
+					utilPct = (stat.AvgUsage / float64(stat.TotalLicenses)) * 100
+				}
+
+				key := UsageKey{stat.ServerHostname, stat.FeatureName}
+				trend, ok := trends[key]
+				if !ok {
+					trend = trendFromUsage(history[key], s.storage.holidays)
+				}
+				slope, daysToCapacity := trendCapacity(trend, stat.TotalLicenses)
+
+				result[i] = UtilizationWithTrend{
+					ServerHostname: stat.ServerHostname,
+					FeatureName:    stat.FeatureName,
+					TotalLicenses:  stat.TotalLicenses,
+					AvgUsage:       stat.AvgUsage,
+					PeakUsage:      stat.PeakUsage,
+					UtilizationPct: utilPct,
+					TrendSlope:     slope,
+					DaysToCapacity: daysToCapacity,
 				}
 			}
-		}
+		}()
+	}
 
-		result = append(result, UtilizationWithTrend{
-			ServerHostname: stat.ServerHostname,
-			FeatureName:    stat.FeatureName,
-			TotalLicenses:  stat.TotalLicenses,
-			AvgUsage:       stat.AvgUsage,
-			PeakUsage:      stat.PeakUsage,
-			UtilizationPct: utilPct,
-			TrendSlope:     slope,
-			DaysToCapacity: daysToCapacity,
-		})
+feed:
+	for i := range stats {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
 	}
+	close(indexes)
+	wg.Wait()
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return result, nil
 }
 
+// trendCapacity returns the daily usage slope of a feature trend and the
+// estimated days until it reaches capacity, or -1
+func trendCapacity(trend models.FeatureTrend, totalLicenses int) (float64, int) {
+	if trend.Samples < minTrendSamples {
+		return 0, -1
+	}
+
+	daysToCapacity := -1
+	if trend.Slope > 0 && totalLicenses > 0 {
+		currentUsage := trend.Slope*trend.LastDay + float64(trend.CurrentUsage)
+		remainingCapacity := float64(totalLicenses) - currentUsage
+		if remainingCapacity > 0 {
+			daysToCapacity = int(remainingCapacity / trend.Slope)
+		}
+	}
+	return trend.Slope, daysToCapacity
+}
+
 // Helper functions
 
+// weeklyPattern returns the day of week with the highest average usage and
+// the average usage on weekdays, weekends and holidays. Holidays are only
+// counted in the holiday average so they do not pull down weekday averages.
+func weeklyPattern(usage []models.FeatureUsage, holidays *HolidayCalendar) (peakDay int, weekdayAvg, weekendAvg, holidayAvg float64) {
+	var daySum, dayCount [7]float64
+	var weekday, weekend, holiday struct{ sum, count float64 }
+
+	for _, u := range usage {
+		y := float64(u.UsersCount)
+		if holidays.IsHoliday(u.ServerHostname, u.Date) {
+			holiday.sum += y
+			holiday.count++
+			continue
+		}
+
+		d := u.Date.Weekday()
+		daySum[d] += y
+		dayCount[d]++
+		if d == time.Saturday || d == time.Sunday {
+			weekend.sum += y
+			weekend.count++
+		} else {
+			weekday.sum += y
+			weekday.count++
+		}
+	}
+
+	avg := func(sum, count float64) float64 {
+		if count == 0 {
+			return 0
+		}
+		return sum / count
+	}
+
+	peakAvg := -1.0
+	for d := range daySum {
+		if dayCount[d] > 0 && daySum[d]/dayCount[d] > peakAvg {
+			peakAvg = daySum[d] / dayCount[d]
+			peakDay = d
+		}
+	}
+
+	return peakDay, avg(weekday.sum, weekday.count), avg(weekend.sum, weekend.count), avg(holiday.sum, holiday.count)
+}
+
 func calculateMedian(values []float64) float64 {
 	if len(values) == 0 {
 		return 0
@@ -485,98 +680,151 @@ func calculateEfficiencyScore(avgUtil, peakUtil, stdDev, totalLicenses float64)
 	return score
 }
 
-func generateRecommendations(avgUtil, peakUtil float64, trendDir string, slope float64, totalLicenses int) []models.Recommendation {
+// generateRecommendations returns the recommendations for a feature and the
+// threshold checks they were chosen by
+func generateRecommendations(avgUtil, peakUtil float64, trendDir string, slope float64, totalLicenses int) ([]models.Recommendation, []models.ThresholdCheck) {
 	var recommendations []models.Recommendation
+	var checks []models.ThresholdCheck
+	apply := func(rule string, value, threshold float64, crossed bool, rec models.Recommendation) {
+		checks = append(checks, thresholdCheck(rule, value, threshold, crossed, rec.Title))
+		if crossed {
+			recommendations = append(recommendations, rec)
+		}
+	}
 
 	// High utilization warning
-	if avgUtil > 80 {
-		recommendations = append(recommendations, models.Recommendation{
-			Type:        "increase",
-			Priority:    "high",
-			Title:       "High Utilization Alert",
-			Description: fmt.Sprintf("Average utilization is %.1f%%, which is above the recommended 80%% threshold.", avgUtil),
-			Impact:      "Users may experience license denials during peak times",
-		})
-	}
+	apply("avg_utilization_pct > 80", avgUtil, 80, avgUtil > 80, models.Recommendation{
+		Type:        "increase",
+		Priority:    "high",
+		Title:       "High Utilization Alert",
+		Description: fmt.Sprintf("Average utilization is %.1f%%, which is above the recommended 80%% threshold.", avgUtil),
+		Impact:      "Users may experience license denials during peak times",
+	})
 
 	// Peak utilization warning
-	if peakUtil > 95 {
-		recommendations = append(recommendations, models.Recommendation{
-			Type:        "alert",
-			Priority:    "high",
-			Title:       "Peak Capacity Reached",
-			Description: fmt.Sprintf("Peak utilization reached %.1f%%, indicating license shortage during peak times.", peakUtil),
-			Impact:      "Immediate risk of license denials",
-		})
-	}
-
-	// Underutilization opportunity
-	if avgUtil < 20 && totalLicenses > 5 {
-		recommendations = append(recommendations, models.Recommendation{
-			Type:        "reduce",
-			Priority:    "medium",
-			Title:       "License Optimization Opportunity",
-			Description: fmt.Sprintf("Average utilization is only %.1f%%. Consider reducing license count to optimize costs.", avgUtil),
-			Impact:      fmt.Sprintf("Potential to reduce licenses by %d without impacting users", int(float64(totalLicenses)*0.5)),
-		})
-	}
+	apply("peak_utilization_pct > 95", peakUtil, 95, peakUtil > 95, models.Recommendation{
+		Type:        "alert",
+		Priority:    "high",
+		Title:       "Peak Capacity Reached",
+		Description: fmt.Sprintf("Peak utilization reached %.1f%%, indicating license shortage during peak times.", peakUtil),
+		Impact:      "Immediate risk of license denials",
+	})
+
+	// Underutilization opportunity, suggesting to cut half the licenses
+	apply("avg_utilization_pct < 20 and total_licenses > 5", avgUtil, 20, avgUtil < 20 && totalLicenses > 5, models.Recommendation{
+		Type:        "reduce",
+		Priority:    "medium",
+		Title:       "License Optimization Opportunity",
+		Description: fmt.Sprintf("Average utilization is only %.1f%%. Consider reducing license count to optimize costs.", avgUtil),
+		Impact:      fmt.Sprintf("Potential to reduce licenses by %d without impacting users", int(float64(totalLicenses)*0.5)),
+	})
 
 	// Trend-based recommendations
-	if trendDir == "increasing" && slope > 0.5 {
-		recommendations = append(recommendations, models.Recommendation{
-			Type:        "increase",
-			Priority:    "medium",
-			Title:       "Growing Demand Detected",
-			Description: fmt.Sprintf("Usage is increasing by approximately %.1f licenses per day.", slope),
-			Impact:      "Plan for additional licenses within the next quarter",
-		})
-	}
-
-	if trendDir == "decreasing" && slope < -0.5 {
-		recommendations = append(recommendations, models.Recommendation{
-			Type:        "reduce",
-			Priority:    "low",
-			Title:       "Declining Usage Trend",
-			Description: fmt.Sprintf("Usage is decreasing by approximately %.1f licenses per day.", -slope),
-			Impact:      "Opportunity to reduce licenses during next renewal",
-		})
-	}
+	apply("trend_slope > 0.5", slope, 0.5, trendDir == "increasing" && slope > 0.5, models.Recommendation{
+		Type:        "increase",
+		Priority:    "medium",
+		Title:       "Growing Demand Detected",
+		Description: fmt.Sprintf("Usage is increasing by approximately %.1f licenses per day.", slope),
+		Impact:      "Plan for additional licenses within the next quarter",
+	})
+
+	apply("trend_slope < -0.5", slope, -0.5, trendDir == "decreasing" && slope < -0.5, models.Recommendation{
+		Type:        "reduce",
+		Priority:    "low",
+		Title:       "Declining Usage Trend",
+		Description: fmt.Sprintf("Usage is decreasing by approximately %.1f licenses per day.", -slope),
+		Impact:      "Opportunity to reduce licenses during next renewal",
+	})
+
+	return recommendations, checks
+}
 
-	return recommendations
+// costRecommendation recommends reducing the seats of a feature unused even
+// at peak when its license contract puts a cost on them
+func costRecommendation(contract *models.LicenseContract, idleSeats, peakUsage, totalLicenses int) (*models.Recommendation, models.ThresholdCheck) {
+	const title = "Idle Seat Cost Savings"
+	crossed := contract != nil && contract.CostPerSeat > 0 && idleSeats > 0
+	check := thresholdCheck("idle_seats > 0 and cost_per_seat > 0", float64(idleSeats), 0, crossed, title)
+	if !crossed {
+		return nil, check
+	}
+
+	impact := fmt.Sprintf("Reducing %d idle seats saves %s/year", idleSeats, formatMoney(contract.CostPerSeat*float64(idleSeats), contract.Currency))
+	if contract.RenewalDate != nil {
+		impact += " from the renewal on " + contract.RenewalDate.Format("2006-01-02")
+	}
+	return &models.Recommendation{
+		Type:     "reduce",
+		Priority: "medium",
+		Title:    title,
+		Description: fmt.Sprintf("Peak usage was %d of %d seats, leaving %d seats unused at %s per seat and year.",
+			peakUsage, totalLicenses, idleSeats, formatMoney(contract.CostPerSeat, contract.Currency)),
+		Impact: impact,
+	}, check
 }
 
-func generateCapacityRecommendations(report *models.CapacityPlanningReport) []models.Recommendation {
+// generateCapacityRecommendations returns the summary recommendations of a
+// capacity report and the threshold checks they were chosen by
+func generateCapacityRecommendations(report *models.CapacityPlanningReport) ([]models.Recommendation, []models.ThresholdCheck) {
 	var recommendations []models.Recommendation
-
-	if report.FeaturesAtCapacity > 0 {
-		recommendations = append(recommendations, models.Recommendation{
-			Type:        "increase",
-			Priority:    "high",
-			Title:       "Features at High Utilization",
-			Description: fmt.Sprintf("%d features have utilization above 80%% and may need additional licenses.", report.FeaturesAtCapacity),
-			Impact:      "Prevent license denials and ensure user productivity",
-		})
+	var checks []models.ThresholdCheck
+	apply := func(rule string, value, threshold float64, crossed bool, rec models.Recommendation) {
+		checks = append(checks, thresholdCheck(rule, value, threshold, crossed, rec.Title))
+		if crossed {
+			recommendations = append(recommendations, rec)
+		}
 	}
 
-	if report.FeaturesUnderutilized > 0 {
-		recommendations = append(recommendations, models.Recommendation{
+	apply("features_at_capacity > 0", float64(report.FeaturesAtCapacity), 0, report.FeaturesAtCapacity > 0, models.Recommendation{
+		Type:        "increase",
+		Priority:    "high",
+		Title:       "Features at High Utilization",
+		Description: fmt.Sprintf("%d features have utilization above 80%% and may need additional licenses.", report.FeaturesAtCapacity),
+		Impact:      "Prevent license denials and ensure user productivity",
+	})
+
+	apply("features_underutilized > 0", float64(report.FeaturesUnderutilized), 0, report.FeaturesUnderutilized > 0, models.Recommendation{
+		Type:        "reduce",
+		Priority:    "medium",
+		Title:       "Underutilized Features Detected",
+		Description: fmt.Sprintf("%d features have utilization below 20%% and may have excess licenses.", report.FeaturesUnderutilized),
+		Impact:      "Potential cost savings by reducing unused licenses",
+	})
+
+	apply("trending_up > 0", float64(len(report.TrendingUp)), 0, len(report.TrendingUp) > 0, models.Recommendation{
+		Type:        "alert",
+		Priority:    "medium",
+		Title:       "Growing Usage Trends",
+		Description: fmt.Sprintf("%d features show increasing usage trends.", len(report.TrendingUp)),
+		Impact:      "Plan for capacity increases in the next quarter",
+	})
+
+	// Idle seats under license contracts, summed per currency
+	savings := make(map[string]float64)
+	seats := make(map[string]int)
+	features := make(map[string]int)
+	for _, insight := range report.CostSavings {
+		savings[insight.Currency] += insight.PotentialSavings
+		seats[insight.Currency] += insight.IdleSeats
+		features[insight.Currency]++
+	}
+	currencies := make([]string, 0, len(savings))
+	for currency := range savings {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	if len(currencies) == 0 {
+		currencies = append(currencies, "")
+	}
+	for _, currency := range currencies {
+		apply("potential_savings > 0", savings[currency], 0, savings[currency] > 0, models.Recommendation{
 			Type:        "reduce",
 			Priority:    "medium",
-			Title:       "Underutilized Features Detected",
-			Description: fmt.Sprintf("%d features have utilization below 20%% and may have excess licenses.", report.FeaturesUnderutilized),
-			Impact:      "Potential cost savings by reducing unused licenses",
-		})
-	}
-
-	if len(report.TrendingUp) > 0 {
-		recommendations = append(recommendations, models.Recommendation{
-			Type:        "alert",
-			Priority:    "medium",
-			Title:       "Growing Usage Trends",
-			Description: fmt.Sprintf("%d features show increasing usage trends.", len(report.TrendingUp)),
-			Impact:      "Plan for capacity increases in the next quarter",
+			Title:       "Idle Seat Cost Savings",
+			Description: fmt.Sprintf("%d features under contract have seats that were unused even at peak.", features[currency]),
+			Impact:      fmt.Sprintf("Reducing %d idle seats saves %s/year", seats[currency], formatMoney(savings[currency], currency)),
 		})
 	}
 
-	return recommendations
+	return recommendations, checks
 }
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"licet/internal/models"
+)
+
+func TestGetCurrentUtilizationWithTrend(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", TotalLicenses: 50},
+		{ServerHostname: "27000@a", Name: "viewer", TotalLicenses: 10},
+		{ServerHostname: "27000@b", Name: "mesher", TotalLicenses: 20},
+	})
+	if err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	// Ten days of history: solver grows, viewer shrinks, mesher is flat
+	for day := 0; day < 10; day++ {
+		date := time.Now().AddDate(0, 0, -day).Format("2006-01-02")
+		for _, u := range []struct {
+			server, feature string
+			users           int
+		}{
+			{"27000@a", "solver", 30 - day*2},
+			{"27000@a", "viewer", day},
+			{"27000@b", "mesher", 5},
+		} {
+			_, err := db.Exec(`INSERT INTO feature_usage (server_hostname, feature_name, date, time, users_count) VALUES (?, ?, ?, ?, ?)`,
+				u.server, u.feature, date, "12:00:00", u.users)
+			if err != nil {
+				t.Fatalf("Failed to insert usage: %v", err)
+			}
+		}
+	}
+
+	s := NewEnhancedAnalyticsService(db, storage, "sqlite")
+	result, err := s.GetCurrentUtilizationWithTrend(ctx, "", 30)
+	if err != nil {
+		t.Fatalf("GetCurrentUtilizationWithTrend failed: %v", err)
+	}
+	if len(result) != 3 {
+		t.Fatalf("Expected 3 features, got %d", len(result))
+	}
+
+	// Batched, parallel results must match the per-feature computation
+	for _, r := range result {
+		usage, err := storage.GetFeatureUsageHistory(ctx, r.ServerHostname, r.FeatureName, 30)
+		if err != nil {
+			t.Fatalf("GetFeatureUsageHistory failed: %v", err)
+		}
+		x, y := usageSeries(usage)
+		slope, _ := linearRegression(x, y)
+		if math.Abs(r.TrendSlope-slope) > 1e-9 {
+			t.Errorf("%s: expected slope %v, got %v", r.FeatureName, slope, r.TrendSlope)
+		}
+		switch r.FeatureName {
+		case "solver":
+			if r.TrendSlope <= 0 {
+				t.Errorf("Expected solver usage to be increasing, got slope %v", r.TrendSlope)
+			}
+		case "viewer":
+			if r.TrendSlope >= 0 {
+				t.Errorf("Expected viewer usage to be decreasing, got slope %v", r.TrendSlope)
+			}
+		}
+	}
+
+	filtered, err := s.GetCurrentUtilizationWithTrend(ctx, "27000@b", 30)
+	if err != nil || len(filtered) != 1 || filtered[0].FeatureName != "mesher" {
+		t.Errorf("Expected only the mesher feature for 27000@b, got %+v (%v)", filtered, err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := s.GetCurrentUtilizationWithTrend(cancelled, "", 30); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,512 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/robfig/cron/v3"
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/parsers"
+)
+
+// maxEntitlementResponse bounds the responses of entitlement portals
+const maxEntitlementResponse = 50 << 20
+
+// EntitlementConnector fetches the entitlements of a vendor portal
+type EntitlementConnector interface {
+	Fetch(ctx context.Context) ([]models.Entitlement, error)
+}
+
+// EntitlementService syncs entitlements from vendor portals and reconciles
+// them against the features the license servers report
+type EntitlementService struct {
+	db         *sqlx.DB
+	cfg        *config.Config
+	storage    *StorageService
+	sources    []config.EntitlementSource
+	connectors map[string]EntitlementConnector // By source name
+}
+
+// NewEntitlementService validates the entitlement sources and creates their
+// connectors
+func NewEntitlementService(db *sqlx.DB, cfg *config.Config, storage *StorageService) (*EntitlementService, error) {
+	s := &EntitlementService{
+		db:         db,
+		cfg:        cfg,
+		storage:    storage,
+		connectors: make(map[string]EntitlementConnector),
+	}
+	if cfg.Entitlements.Enabled {
+		if _, err := cron.ParseStandard(cfg.Entitlements.Schedule); err != nil {
+			return nil, fmt.Errorf("invalid entitlement schedule %q: %w", cfg.Entitlements.Schedule, err)
+		}
+	}
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	for _, src := range cfg.Entitlements.Sources {
+		if src.Name == "" {
+			return nil, fmt.Errorf("entitlement source without a name")
+		}
+		if _, ok := s.connectors[src.Name]; ok {
+			return nil, fmt.Errorf("duplicate entitlement source %q", src.Name)
+		}
+		connector, err := newEntitlementConnector(src, client)
+		if err != nil {
+			return nil, fmt.Errorf("entitlement source %s: %w", src.Name, err)
+		}
+		s.sources = append(s.sources, src)
+		s.connectors[src.Name] = connector
+	}
+	return s, nil
+}
+
+// SetConnector replaces the connector of a source, e.g. with a vendor
+// specific client
+func (s *EntitlementService) SetConnector(source string, connector EntitlementConnector) {
+	s.connectors[source] = connector
+}
+
+// Sync fetches every source and replaces its stored entitlements. A source
+// that fails keeps its previous entitlements.
+func (s *EntitlementService) Sync(ctx context.Context) []models.EntitlementSync {
+	results := make([]models.EntitlementSync, 0, len(s.sources))
+	for _, src := range s.sources {
+		result := models.EntitlementSync{Source: src.Name}
+		entitlements, err := s.connectors[src.Name].Fetch(ctx)
+		if err == nil {
+			err = s.store(ctx, src.Name, entitlements)
+		}
+		if err != nil {
+			log.Errorf("Entitlement sync of %s failed: %v", src.Name, err)
+			result.Error = err.Error()
+		} else {
+			log.Infof("Synced %d entitlements from %s", len(entitlements), src.Name)
+			result.Entitlements = len(entitlements)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// store replaces the entitlements of a source
+func (s *EntitlementService) store(ctx context.Context, source string, entitlements []models.Entitlement) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM entitlements WHERE source = ?`), source); err != nil {
+		return fmt.Errorf("failed to replace entitlements: %w", err)
+	}
+	now := time.Now()
+	for _, e := range entitlements {
+		var endDate interface{}
+		if e.EndDate != nil {
+			endDate = e.EndDate.Local()
+		}
+		_, err := tx.ExecContext(ctx, tx.Rebind(`
+			INSERT INTO entitlements (source, feature_name, quantity, end_date, fetched_at)
+			VALUES (?, ?, ?, ?, ?)
+		`), source, e.FeatureName, e.Quantity, endDate, now)
+		if err != nil {
+			return fmt.Errorf("failed to store entitlement %s: %w", e.FeatureName, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetEntitlements returns the stored entitlements, of one source if given
+func (s *EntitlementService) GetEntitlements(ctx context.Context, source string) ([]models.Entitlement, error) {
+	query := `SELECT source, feature_name, quantity, end_date, fetched_at FROM entitlements`
+	var args []interface{}
+	if source != "" {
+		query += ` WHERE source = ?`
+		args = append(args, source)
+	}
+	query += ` ORDER BY source, feature_name`
+
+	entitlements := []models.Entitlement{}
+	if err := s.db.SelectContext(ctx, &entitlements, s.db.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to get entitlements: %w", err)
+	}
+	return entitlements, nil
+}
+
+// entitledFeature combines the entitlements of a feature in one source
+type entitledFeature struct {
+	quantity int
+	endDate  *time.Time // Latest end, nil if any entitlement is perpetual
+}
+
+// servedFeature combines the pools of a feature on the servers of a source
+type servedFeature struct {
+	seats     int
+	uncounted bool
+	endDate   *time.Time // Latest expiration, nil if any pool is permanent
+	servers   []string
+}
+
+// Reconcile compares the stored entitlements of every source with the active
+// features of the source's servers. Features served by a source's servers
+// but absent from its entitlements are reported only for sources that list
+// their servers, as other sources cover just part of what a server serves.
+func (s *EntitlementService) Reconcile(ctx context.Context) (*models.ReconciliationReport, error) {
+	stored, err := s.GetEntitlements(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	features, err := s.storage.GetActiveFeatures(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get features: %w", err)
+	}
+
+	report := &models.ReconciliationReport{GeneratedAt: time.Now(), Mismatches: []models.EntitlementMismatch{}}
+	for _, src := range s.sources {
+		entitled := make(map[string]*entitledFeature)
+		for _, e := range stored {
+			if e.Source != src.Name {
+				continue
+			}
+			ef, ok := entitled[e.FeatureName]
+			if !ok {
+				ef = &entitledFeature{endDate: e.EndDate}
+				entitled[e.FeatureName] = ef
+			} else {
+				ef.endDate = laterEnd(ef.endDate, e.EndDate)
+			}
+			ef.quantity += e.Quantity
+		}
+
+		served := servedFeatures(features, src.Servers)
+		for name, ef := range entitled {
+			report.Checked++
+			mismatch := models.EntitlementMismatch{Source: src.Name, FeatureName: name, Purchased: ef.quantity, EndDate: ef.endDate, Servers: []string{}}
+			sf, ok := served[name]
+			if !ok {
+				mismatch.Kind = models.MismatchMissing
+				report.Mismatches = append(report.Mismatches, mismatch)
+				continue
+			}
+			mismatch.Served = sf.seats
+			mismatch.ServerEndDate = sf.endDate
+			mismatch.Servers = sf.servers
+
+			matching := true
+			if !sf.uncounted && sf.seats != ef.quantity {
+				mismatch.Kind = models.MismatchCount
+				report.Mismatches = append(report.Mismatches, mismatch)
+				matching = false
+			}
+			if !sameDay(sf.endDate, ef.endDate) {
+				mismatch.Kind = models.MismatchEndDate
+				report.Mismatches = append(report.Mismatches, mismatch)
+				matching = false
+			}
+			if matching {
+				report.Matching++
+			}
+		}
+
+		if len(src.Servers) == 0 {
+			continue
+		}
+		for name, sf := range served {
+			if _, ok := entitled[name]; !ok {
+				report.Mismatches = append(report.Mismatches, models.EntitlementMismatch{
+					Source: src.Name, FeatureName: name, Kind: models.MismatchUnentitled,
+					Served: sf.seats, ServerEndDate: sf.endDate, Servers: sf.servers,
+				})
+			}
+		}
+	}
+
+	sort.Slice(report.Mismatches, func(i, j int) bool {
+		a, b := report.Mismatches[i], report.Mismatches[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.FeatureName != b.FeatureName {
+			return a.FeatureName < b.FeatureName
+		}
+		return a.Kind < b.Kind
+	})
+	return report, nil
+}
+
+// servedFeatures combines the pools of each feature on the given servers, or
+// on all servers if none are given
+func servedFeatures(features []models.Feature, servers []string) map[string]*servedFeature {
+	include := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		include[server] = true
+	}
+
+	served := make(map[string]*servedFeature)
+	for _, f := range features {
+		if len(include) > 0 && !include[f.ServerHostname] {
+			continue
+		}
+		var expiration *time.Time
+		if !f.ExpirationDate.IsZero() && !f.ExpirationDate.Equal(parsers.PermanentExpirationDate) {
+			e := f.ExpirationDate
+			expiration = &e
+		}
+
+		sf, ok := served[f.Name]
+		if !ok {
+			sf = &servedFeature{endDate: expiration}
+			served[f.Name] = sf
+		} else {
+			sf.endDate = laterEnd(sf.endDate, expiration)
+		}
+		sf.seats += f.TotalLicenses
+		sf.uncounted = sf.uncounted || f.LicenseModel == "uncounted"
+		sf.servers = appendUnique(sf.servers, f.ServerHostname)
+	}
+	for _, sf := range served {
+		sort.Strings(sf.servers)
+	}
+	return served
+}
+
+// laterEnd returns the later of two ends, where nil never ends
+func laterEnd(a, b *time.Time) *time.Time {
+	if a == nil || b == nil {
+		return nil
+	}
+	if b.After(*a) {
+		return b
+	}
+	return a
+}
+
+// sameDay reports whether two ends fall on the same day, or both never end
+func sameDay(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
+
+// newEntitlementConnector creates the connector of a source
+func newEntitlementConnector(src config.EntitlementSource, client *http.Client) (EntitlementConnector, error) {
+	switch src.Type {
+	case "http":
+		if src.URL == "" {
+			return nil, fmt.Errorf("url is required")
+		}
+		return &httpEntitlementConnector{src: src, client: client}, nil
+	case "file":
+		if src.Path == "" {
+			return nil, fmt.Errorf("path is required")
+		}
+		return &fileEntitlementConnector{src: src}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %q, expected http or file", src.Type)
+	}
+}
+
+// httpEntitlementConnector reads entitlements from a JSON API
+type httpEntitlementConnector struct {
+	src    config.EntitlementSource
+	client *http.Client
+}
+
+func (c *httpEntitlementConnector) Fetch(ctx context.Context) ([]models.Entitlement, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.src.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.src.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.src.Token)
+	}
+	for name, value := range c.src.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("portal returned %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxEntitlementResponse))
+	if err != nil {
+		return nil, err
+	}
+	return parseEntitlementJSON(c.src, body)
+}
+
+// fileEntitlementConnector reads entitlements exported from a portal as CSV
+// (by extension) or JSON
+type fileEntitlementConnector struct {
+	src config.EntitlementSource
+}
+
+func (c *fileEntitlementConnector) Fetch(ctx context.Context) ([]models.Entitlement, error) {
+	data, err := os.ReadFile(c.src.Path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(c.src.Path), ".csv") {
+		return parseEntitlementCSV(c.src, data)
+	}
+	return parseEntitlementJSON(c.src, data)
+}
+
+// entitlementFields returns the field names of a source with their defaults
+func entitlementFields(src config.EntitlementSource) config.EntitlementFields {
+	fields := src.Fields
+	if fields.Feature == "" {
+		fields.Feature = "feature"
+	}
+	if fields.Quantity == "" {
+		fields.Quantity = "quantity"
+	}
+	if fields.EndDate == "" {
+		fields.EndDate = "end_date"
+	}
+	return fields
+}
+
+// parseEntitlementJSON reads the list of entitlement records found at the
+// source's items path
+func parseEntitlementJSON(src config.EntitlementSource, data []byte) ([]models.Entitlement, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if src.Items != "" {
+		for _, key := range strings.Split(src.Items, ".") {
+			obj, ok := doc.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("items path %q not found", src.Items)
+			}
+			doc = obj[key]
+		}
+	}
+	items, ok := doc.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list of entitlements at %q", src.Items)
+	}
+
+	fields := entitlementFields(src)
+	entitlements := make([]models.Entitlement, 0, len(items))
+	for i, item := range items {
+		record, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("entitlement %d is not an object", i+1)
+		}
+		e, err := newEntitlement(src, jsonText(record[fields.Feature]), jsonText(record[fields.Quantity]), jsonText(record[fields.EndDate]))
+		if err != nil {
+			return nil, fmt.Errorf("entitlement %d: %w", i+1, err)
+		}
+		entitlements = append(entitlements, e)
+	}
+	return entitlements, nil
+}
+
+// parseEntitlementCSV reads entitlement rows by the column names in the header
+func parseEntitlementCSV(src config.EntitlementSource, data []byte) ([]models.Entitlement, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return []models.Entitlement{}, nil
+	}
+
+	fields := entitlementFields(src)
+	columns := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	cell := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+	for _, name := range []string{fields.Feature, fields.Quantity} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	entitlements := make([]models.Entitlement, 0, len(records)-1)
+	for i, row := range records[1:] {
+		e, err := newEntitlement(src, cell(row, fields.Feature), cell(row, fields.Quantity), cell(row, fields.EndDate))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+2, err)
+		}
+		entitlements = append(entitlements, e)
+	}
+	return entitlements, nil
+}
+
+// newEntitlement builds an entitlement from its text fields, mapping the
+// vendor's product name to a license feature name
+func newEntitlement(src config.EntitlementSource, product, quantity, endDate string) (models.Entitlement, error) {
+	product = strings.TrimSpace(product)
+	if product == "" {
+		return models.Entitlement{}, fmt.Errorf("missing feature")
+	}
+	// Configuration keys are lower case
+	feature := product
+	if mapped, ok := src.Features[strings.ToLower(product)]; ok {
+		feature = mapped
+	}
+
+	count, err := strconv.ParseFloat(strings.TrimSpace(quantity), 64)
+	if err != nil {
+		return models.Entitlement{}, fmt.Errorf("%s: invalid quantity %q", product, quantity)
+	}
+
+	e := models.Entitlement{Source: src.Name, FeatureName: feature, Quantity: int(count)}
+	if end, err := parsers.ParseDate(endDate); err != nil {
+		if t, rfcErr := time.Parse(time.RFC3339, strings.TrimSpace(endDate)); rfcErr == nil {
+			e.EndDate = &t
+		} else {
+			return models.Entitlement{}, fmt.Errorf("%s: %w", product, err)
+		}
+	} else if !end.Equal(parsers.PermanentExpirationDate) {
+		e.EndDate = &end
+	}
+	return e, nil
+}
+
+// jsonText returns a JSON value decoded with UseNumber as text
+func jsonText(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	default:
+		return fmt.Sprint(value)
+	}
+}
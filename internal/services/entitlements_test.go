@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestNewEntitlementService_Validation(t *testing.T) {
+	tests := map[string][]config.EntitlementSource{
+		"name":      {{Type: "file", Path: "a.csv"}},
+		"duplicate": {{Name: "a", Type: "file", Path: "a.csv"}, {Name: "a", Type: "file", Path: "b.csv"}},
+		"type":      {{Name: "a", Type: "ftp", URL: "ftp://portal"}},
+		"url":       {{Name: "a", Type: "http"}},
+		"path":      {{Name: "a", Type: "file"}},
+	}
+	for name, sources := range tests {
+		cfg := &config.Config{Entitlements: config.EntitlementConfig{Sources: sources}}
+		if _, err := NewEntitlementService(nil, cfg, nil); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+
+	cfg := &config.Config{Entitlements: config.EntitlementConfig{Enabled: true, Schedule: "daily"}}
+	if _, err := NewEntitlementService(nil, cfg, nil); err == nil {
+		t.Error("Expected an invalid schedule to be rejected")
+	}
+}
+
+func TestParseEntitlementJSON(t *testing.T) {
+	src := config.EntitlementSource{
+		Name:     "portal",
+		Items:    "data.contracts",
+		Fields:   config.EntitlementFields{Feature: "product", Quantity: "seats", EndDate: "expires"},
+		Features: map[string]string{"acme solver": "solver"},
+	}
+	body := `{"data": {"contracts": [
+		{"product": "ACME Solver", "seats": 10, "expires": "2030-06-30"},
+		{"product": "viewer", "seats": "25", "expires": null},
+		{"product": "mesher", "seats": 2.0, "expires": "2031-01-01T00:00:00Z"}
+	]}}`
+
+	entitlements, err := parseEntitlementJSON(src, []byte(body))
+	if err != nil {
+		t.Fatalf("parseEntitlementJSON failed: %v", err)
+	}
+	if len(entitlements) != 3 {
+		t.Fatalf("Expected 3 entitlements, got %d", len(entitlements))
+	}
+	solver := entitlements[0]
+	if solver.FeatureName != "solver" || solver.Quantity != 10 || solver.EndDate == nil || solver.EndDate.Format("2006-01-02") != "2030-06-30" {
+		t.Errorf("Unexpected mapped entitlement %+v", solver)
+	}
+	if entitlements[1].Quantity != 25 || entitlements[1].EndDate != nil {
+		t.Errorf("Expected a perpetual entitlement of 25, got %+v", entitlements[1])
+	}
+	if entitlements[2].Quantity != 2 || entitlements[2].EndDate == nil {
+		t.Errorf("Expected an RFC 3339 end date, got %+v", entitlements[2])
+	}
+
+	if _, err := parseEntitlementJSON(config.EntitlementSource{Items: "missing"}, []byte(body)); err == nil {
+		t.Error("Expected an error for a missing items path")
+	}
+	if _, err := parseEntitlementJSON(config.EntitlementSource{}, []byte(`[{"feature": "a", "quantity": "many"}]`)); err == nil {
+		t.Error("Expected an error for an invalid quantity")
+	}
+}
+
+func TestParseEntitlementCSV(t *testing.T) {
+	data := "feature,quantity,end_date\nsolver,10,30-jun-2030\nviewer,5,permanent\n"
+	entitlements, err := parseEntitlementCSV(config.EntitlementSource{Name: "export"}, []byte(data))
+	if err != nil {
+		t.Fatalf("parseEntitlementCSV failed: %v", err)
+	}
+	if len(entitlements) != 2 || entitlements[0].EndDate == nil || entitlements[1].EndDate != nil {
+		t.Errorf("Unexpected entitlements %+v", entitlements)
+	}
+
+	if _, err := parseEntitlementCSV(config.EntitlementSource{}, []byte("product,seats\nsolver,1\n")); err == nil {
+		t.Error("Expected an error for missing columns")
+	}
+}
+
+func TestEntitlementService_SyncAndReconcile(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	end := time.Date(2030, 6, 30, 0, 0, 0, 0, time.UTC)
+	err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", TotalLicenses: 6, ExpirationDate: end},
+		{ServerHostname: "27000@b", Name: "solver", TotalLicenses: 4, ExpirationDate: end},
+		{ServerHostname: "27000@a", Name: "viewer", TotalLicenses: 20, ExpirationDate: end},
+		{ServerHostname: "27000@a", Name: "extra", TotalLicenses: 1, ExpirationDate: end},
+		{ServerHostname: "27000@c", Name: "other", TotalLicenses: 1, ExpirationDate: end},
+	})
+	if err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	var token string
+	portal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("Authorization")
+		w.Write([]byte(`{"items": [
+			{"feature": "solver", "quantity": 10, "end_date": "2030-06-30"},
+			{"feature": "viewer", "quantity": 25, "end_date": "2030-06-30"},
+			{"feature": "mesher", "quantity": 2, "end_date": "2030-06-30"}
+		]}`))
+	}))
+	defer portal.Close()
+
+	export := filepath.Join(t.TempDir(), "export.csv")
+	if err := os.WriteFile(export, []byte("feature,quantity,end_date\nother,1,2029-01-01\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{Entitlements: config.EntitlementConfig{Sources: []config.EntitlementSource{
+		{Name: "portal", Type: "http", URL: portal.URL, Token: "secret", Items: "items", Servers: []string{"27000@a", "27000@b"}},
+		{Name: "export", Type: "file", Path: export},
+	}}}
+	s, err := NewEntitlementService(db, cfg, storage)
+	if err != nil {
+		t.Fatalf("NewEntitlementService failed: %v", err)
+	}
+
+	results := s.Sync(ctx)
+	if len(results) != 2 || results[0].Entitlements != 3 || results[1].Entitlements != 1 || results[0].Error != "" {
+		t.Fatalf("Unexpected sync results %+v", results)
+	}
+	if token != "Bearer secret" {
+		t.Errorf("Expected the bearer token to be sent, got %q", token)
+	}
+	stored, err := s.GetEntitlements(ctx, "portal")
+	if err != nil || len(stored) != 3 {
+		t.Fatalf("Expected 3 stored entitlements, got %d (%v)", len(stored), err)
+	}
+
+	report, err := s.Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if report.Checked != 4 || report.Matching != 1 {
+		t.Errorf("Expected 4 checked and 1 matching, got %d and %d", report.Checked, report.Matching)
+	}
+	kinds := make(map[string]string)
+	for _, m := range report.Mismatches {
+		kinds[m.Source+"/"+m.FeatureName] = m.Kind
+	}
+	expected := map[string]string{
+		"export/other":  models.MismatchEndDate,
+		"portal/extra":  models.MismatchUnentitled,
+		"portal/mesher": models.MismatchMissing,
+		"portal/viewer": models.MismatchCount,
+	}
+	if len(kinds) != len(expected) {
+		t.Errorf("Expected mismatches %v, got %v", expected, kinds)
+	}
+	for key, kind := range expected {
+		if kinds[key] != kind {
+			t.Errorf("%s: expected %s, got %s", key, kind, kinds[key])
+		}
+	}
+
+	// A failing source keeps its previous entitlements
+	portal.Close()
+	results = s.Sync(ctx)
+	if results[0].Error == "" {
+		t.Error("Expected the closed portal to fail")
+	}
+	if stored, _ := s.GetEntitlements(ctx, "portal"); len(stored) != 3 {
+		t.Errorf("Expected the previous entitlements to be kept, got %d", len(stored))
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"licet/internal/models"
+)
+
+// EventBus delivers in-process notifications to the subscribers of a topic.
+// Publishing never blocks: a subscriber that has not received its previous
+// event misses the next one.
+type EventBus struct {
+	mu   sync.Mutex
+	subs map[string]map[chan interface{}]struct{}
+}
+
+// NewEventBus creates an event bus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[string]map[chan interface{}]struct{})}
+}
+
+// Subscribe returns a channel receiving the events published on a topic
+// from now on, and a function that ends the subscription
+func (b *EventBus) Subscribe(topic string) (<-chan interface{}, func()) {
+	return b.SubscribeBuffered(topic, 1)
+}
+
+// SubscribeBuffered is Subscribe for subscribers that may fall behind by up
+// to size events before missing any
+func (b *EventBus) SubscribeBuffered(topic string, size int) (<-chan interface{}, func()) {
+	ch := make(chan interface{}, size)
+
+	b.mu.Lock()
+	if b.subs[topic] == nil {
+		b.subs[topic] = make(map[chan interface{}]struct{})
+	}
+	b.subs[topic][ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subs[topic], ch)
+		if len(b.subs[topic]) == 0 {
+			delete(b.subs, topic)
+		}
+		b.mu.Unlock()
+	}
+}
+
+// Publish sends an event to the current subscribers of a topic
+func (b *EventBus) Publish(topic string, event interface{}) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[topic] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// CollectionTopic is the topic of the successful collections of a server,
+// carrying CollectionEvents
+func CollectionTopic(hostname string) string {
+	return "collection:" + hostname
+}
+
+// AuditTopic is the topic of the audit entries as they are recorded,
+// carrying models.AuditEntry
+const AuditTopic = "audit"
+
+// CollectionsTopic is the topic of every collection of every server,
+// successful or not, carrying CollectionEvents
+const CollectionsTopic = "collections"
+
+// CollectionEvent is published after a server was collected successfully,
+// and on CollectionsTopic also after a collection failed
+type CollectionEvent struct {
+	Server      string
+	Type        string
+	Result      models.ServerQueryResult
+	CollectedAt time.Time
+	Error       string // Why the collection failed; the status is then down
+}
+
+// AlertTopic is the topic of the alerts that were stored and not silenced,
+// carrying models.Alerts
+const AlertTopic = "alerts"
@@ -0,0 +1,34 @@
+package services
+
+import "testing"
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+	events, cancel := bus.Subscribe("collection:a")
+	other, cancelOther := bus.Subscribe("collection:b")
+	defer cancelOther()
+
+	bus.Publish("collection:a", 1)
+	// A subscriber that has not received the previous event misses this one
+	bus.Publish("collection:a", 2)
+
+	if got := <-events; got != 1 {
+		t.Errorf("Expected the first event, got %v", got)
+	}
+	select {
+	case got := <-events:
+		t.Errorf("Expected the second event to be dropped, got %v", got)
+	case got := <-other:
+		t.Errorf("Expected no event on another topic, got %v", got)
+	default:
+	}
+
+	cancel()
+	bus.Publish("collection:a", 3)
+	if len(bus.subs["collection:a"]) != 0 {
+		t.Error("Expected the cancelled subscription to be removed")
+	}
+
+	var nilBus *EventBus
+	nilBus.Publish("collection:a", 4)
+}
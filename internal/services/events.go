@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"licet/internal/database"
+	"licet/internal/models"
+	"licet/internal/parsers"
+)
+
+// EventService stores license checkout and denial events
+type EventService struct {
+	db      *sqlx.DB
+	dialect database.Dialect
+	cipher  *FieldCipher
+}
+
+// NewEventService creates a new event service
+func NewEventService(db *sqlx.DB, dbType string) *EventService {
+	return &EventService{
+		db:      db,
+		dialect: database.NewDialect(dbType),
+	}
+}
+
+// SetCipher enables encryption of stored usernames
+func (s *EventService) SetCipher(c *FieldCipher) {
+	s.cipher = c
+}
+
+// RecordEvents stores license events, skipping duplicates, and returns the
+// number of newly stored events
+func (s *EventService) RecordEvents(ctx context.Context, events []models.LicenseEvent) (int, error) {
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PreparexContext(ctx, s.dialect.InsertIgnoreEvent())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	stored := 0
+	for _, event := range events {
+		if event.EventType == parsers.EventDenied && event.ReasonCode == "" {
+			event.ReasonCode = parsers.ClassifyDenial(event.Reason)
+		}
+		result, err := stmt.ExecContext(ctx,
+			event.ServerHostname,
+			event.Date.Format("2006-01-02"),
+			event.Time.Format("15:04:05"),
+			event.EventType,
+			event.FeatureName,
+			s.cipher.Encrypt(event.Username),
+			event.Reason,
+			event.ReasonCode,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record event for %s: %w", event.FeatureName, err)
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			stored += int(n)
+		}
+	}
+
+	return stored, tx.Commit()
+}
+
+// denialRow is a stored denial. Dates and times scan as time.Time or text
+// depending on the driver.
+type denialRow struct {
+	ServerHostname string      `db:"server_hostname"`
+	EventDate      interface{} `db:"event_date"`
+	EventTime      interface{} `db:"event_time"`
+	FeatureName    string      `db:"feature_name"`
+	Username       string      `db:"username"`
+	Reason         *string     `db:"reason"`
+	ReasonCode     *string     `db:"reason_code"`
+}
+
+// GetDenials returns the denials of the last days, newest first, with their
+// count per reason. Denials recorded before reasons were classified are
+// classified from their message. Empty server or feature match all.
+func (s *EventService) GetDenials(ctx context.Context, server, feature string, days int) (*models.DenialSummary, error) {
+	cutoff := time.Now().AddDate(0, 0, -days).Format("2006-01-02")
+	query := `
+		SELECT server_hostname, event_date, event_time, feature_name, username, reason, reason_code
+		FROM license_events
+		WHERE event_type = ? AND event_date >= ?`
+	args := []interface{}{parsers.EventDenied, cutoff}
+	if server != "" {
+		query += ` AND server_hostname = ?`
+		args = append(args, server)
+	}
+	if feature != "" {
+		query += ` AND feature_name = ?`
+		args = append(args, feature)
+	}
+	query += ` ORDER BY event_date DESC, event_time DESC`
+
+	var rows []denialRow
+	if err := s.db.SelectContext(ctx, &rows, s.db.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to get denials: %w", err)
+	}
+
+	summary := &models.DenialSummary{
+		Days:     days,
+		Total:    len(rows),
+		ByReason: make(map[string]int, len(models.DenialReasons)),
+		Denials:  make([]models.Denial, 0, len(rows)),
+	}
+	for _, reason := range models.DenialReasons {
+		summary.ByReason[reason] = 0
+	}
+	for _, row := range rows {
+		denial := models.Denial{
+			ServerHostname: row.ServerHostname,
+			FeatureName:    row.FeatureName,
+			Username:       row.Username,
+			DeniedAt:       eventTimestamp(row.EventDate, row.EventTime),
+		}
+		if row.Reason != nil {
+			denial.Reason = *row.Reason
+		}
+		if row.ReasonCode != nil && *row.ReasonCode != "" {
+			denial.ReasonCode = *row.ReasonCode
+		} else {
+			denial.ReasonCode = parsers.ClassifyDenial(denial.Reason)
+		}
+		if name, err := s.cipher.Decrypt(denial.Username); err == nil {
+			denial.Username = name
+		}
+		summary.ByReason[denial.ReasonCode]++
+		summary.Denials = append(summary.Denials, denial)
+	}
+	return summary, nil
+}
+
+// eventTimestamp combines the date and time columns of an event in local time
+func eventTimestamp(date, clock interface{}) time.Time {
+	text := func(v interface{}, layout string) string {
+		switch value := v.(type) {
+		case time.Time:
+			return value.Format(layout)
+		case []byte:
+			return string(value)
+		case string:
+			return value
+		}
+		return ""
+	}
+	d := text(date, "2006-01-02")
+	if len(d) > 10 {
+		d = d[:10]
+	}
+	c := text(clock, "15:04:05")
+	if i := strings.LastIndex(c, " "); i >= 0 {
+		c = c[i+1:] // Times scanned with a date part
+	}
+	if len(c) > 8 {
+		c = c[:8]
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", d+" "+c, time.Local)
+	if err != nil {
+		t, _ = time.ParseInLocation("2006-01-02", d, time.Local)
+	}
+	return t
+}
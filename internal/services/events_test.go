@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"licet/internal/models"
+)
+
+func TestEventService_GetDenials(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	s := NewEventService(db, "sqlite")
+
+	today := time.Now()
+	events := []models.LicenseEvent{
+		{ServerHostname: "27000@a", Date: today, Time: time.Date(0, 1, 1, 9, 0, 0, 0, time.Local), EventType: "DENIED",
+			FeatureName: "solver", Username: "jdoe", Reason: "Licensed number of users already reached. (-4,342)"},
+		{ServerHostname: "27000@a", Date: today, Time: time.Date(0, 1, 1, 10, 30, 0, 0, time.Local), EventType: "DENIED",
+			FeatureName: "solver", Username: "asmith", Reason: "User/host on EXCLUDE list for feature. (-38,349)"},
+		{ServerHostname: "27000@b", Date: today, Time: time.Date(0, 1, 1, 11, 0, 0, 0, time.Local), EventType: "DENIED",
+			FeatureName: "mesher", Username: "jdoe", Reason: "All licenses in use"},
+		{ServerHostname: "27000@a", Date: today, Time: time.Date(0, 1, 1, 9, 0, 0, 0, time.Local), EventType: "OUT",
+			FeatureName: "solver", Username: "bob"},
+		{ServerHostname: "27000@a", Date: today.AddDate(0, 0, -30), Time: time.Date(0, 1, 1, 9, 0, 0, 0, time.Local), EventType: "DENIED",
+			FeatureName: "solver", Username: "old", Reason: "All licenses in use"},
+	}
+	if _, err := s.RecordEvents(ctx, events); err != nil {
+		t.Fatalf("RecordEvents failed: %v", err)
+	}
+
+	var codes []string
+	db.Select(&codes, `SELECT reason_code FROM license_events WHERE event_type = 'DENIED' ORDER BY id`)
+	if len(codes) != 4 || codes[0] != models.DenialNoSeats || codes[1] != models.DenialExcluded {
+		t.Errorf("Expected reason codes to be stored, got %v", codes)
+	}
+
+	// Denials recorded before classification are classified when read
+	_, err := db.Exec(`INSERT INTO license_events (server_hostname, event_date, event_time, event_type, feature_name, username, reason)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, "27000@b", today.Format("2006-01-02"), "12:00:00", "DENIED", "mesher", "legacy", "Feature has expired. (-10,32)")
+	if err != nil {
+		t.Fatalf("Failed to insert legacy denial: %v", err)
+	}
+
+	summary, err := s.GetDenials(ctx, "", "", 7)
+	if err != nil {
+		t.Fatalf("GetDenials failed: %v", err)
+	}
+	if summary.Total != 4 || len(summary.Denials) != 4 {
+		t.Fatalf("Expected 4 recent denials, got %+v", summary)
+	}
+	want := map[string]int{models.DenialNoSeats: 2, models.DenialExcluded: 1, models.DenialExpired: 1, models.DenialServerDown: 0}
+	for reason, count := range want {
+		if summary.ByReason[reason] != count {
+			t.Errorf("Expected %d %s denials, got %d", count, reason, summary.ByReason[reason])
+		}
+	}
+	first := summary.Denials[0]
+	if first.Username != "legacy" || first.ReasonCode != models.DenialExpired || first.DeniedAt.Hour() != 12 {
+		t.Errorf("Expected the newest denial first, got %+v", first)
+	}
+
+	filtered, err := s.GetDenials(ctx, "27000@a", "solver", 7)
+	if err != nil {
+		t.Fatalf("GetDenials failed: %v", err)
+	}
+	if filtered.Total != 2 || filtered.ByReason[models.DenialExcluded] != 1 {
+		t.Errorf("Expected the denials of solver on 27000@a, got %+v", filtered)
+	}
+}
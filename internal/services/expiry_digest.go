@@ -0,0 +1,252 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+// expiryDigestPeriods are the default cron schedules of the digest periods
+var expiryDigestPeriods = map[string]string{
+	"daily":  "0 7 * * *",
+	"weekly": "0 7 * * 1",
+}
+
+// expiryDigest is what the expiry digest template renders. Custom templates
+// (alerts.expiry_digest.template) receive the same data.
+type expiryDigest struct {
+	Title       string
+	Period      string
+	Days        int
+	GeneratedAt time.Time
+	Total       int // Features expiring within Days
+	Servers     []expiryDigestServer
+	Brand       config.BrandingConfig
+}
+
+// expiryDigestServer lists the expiring features of a server by vendor daemon
+type expiryDigestServer struct {
+	Hostname    string
+	Description string
+	Vendors     []expiryDigestVendor
+}
+
+// expiryDigestVendor lists the expiring features of a vendor daemon, soonest
+// first, with DaysToExpire set
+type expiryDigestVendor struct {
+	Name     string
+	Features []models.Feature
+}
+
+// ExpiryDigestSchedule returns the cron schedule of the expiry digest
+func ExpiryDigestSchedule(cfg config.ExpiryDigestConfig) string {
+	if cfg.Cron != "" {
+		return cfg.Cron
+	}
+	return expiryDigestPeriods[cfg.Period]
+}
+
+// SendExpiryDigest emails one summary of the features expiring within the
+// digest window. Nothing is sent when no feature expires.
+func (s *AlertService) SendExpiryDigest(ctx context.Context) error {
+	if !s.cfg.Email.Enabled {
+		return fmt.Errorf("expiry digest: email is not enabled")
+	}
+	recipients := s.cfg.Alerts.ExpiryDigest.Recipients
+	if len(recipients) == 0 {
+		recipients = s.cfg.Email.To
+	}
+	if len(recipients) == 0 {
+		return fmt.Errorf("expiry digest: no recipients")
+	}
+
+	digest, err := s.buildExpiryDigest(ctx)
+	if err != nil {
+		return err
+	}
+	if digest.Total == 0 {
+		log.Debug("Expiry digest: no features expiring")
+		return nil
+	}
+	html, err := s.renderExpiryDigest(digest)
+	if err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("%s: %d features expiring within %d days", digest.Title, digest.Total, digest.Days)
+	if err := s.SendHTMLEmail(subject, html, formatExpiryDigestText(digest), recipients); err != nil {
+		return fmt.Errorf("failed to send expiry digest: %w", err)
+	}
+	log.Infof("Expiry digest sent: %d features on %d servers", digest.Total, len(digest.Servers))
+	return nil
+}
+
+// buildExpiryDigest collects the active features expiring within the digest
+// window, grouped by server and vendor daemon
+func (s *AlertService) buildExpiryDigest(ctx context.Context) (*expiryDigest, error) {
+	cfg := s.cfg.Alerts.ExpiryDigest
+	now := s.clock.Now()
+	var features []models.Feature
+	query := `
+		SELECT * FROM features
+		WHERE expiration_date <= ? AND expiration_date > ? AND is_active = TRUE
+		ORDER BY server_hostname, vendor_daemon, expiration_date, name
+	`
+	if err := s.db.SelectContext(ctx, &features, s.db.Rebind(query), now.AddDate(0, 0, cfg.Days), now); err != nil {
+		return nil, fmt.Errorf("failed to get expiring features: %w", err)
+	}
+
+	descriptions := make(map[string]string)
+	for _, server := range s.cfg.Servers {
+		descriptions[server.Hostname] = server.Description
+	}
+
+	digest := &expiryDigest{
+		Title:       fmt.Sprintf("%s License Expiry Digest", s.cfg.Branding.Name()),
+		Period:      cfg.Period,
+		Days:        cfg.Days,
+		GeneratedAt: now,
+		Total:       len(features),
+		Brand:       s.cfg.Branding,
+	}
+	for _, f := range features {
+		f.DaysToExpire = int(f.ExpirationDate.Sub(now).Hours() / 24)
+		if n := len(digest.Servers); n == 0 || digest.Servers[n-1].Hostname != f.ServerHostname {
+			digest.Servers = append(digest.Servers, expiryDigestServer{Hostname: f.ServerHostname, Description: descriptions[f.ServerHostname]})
+		}
+		server := &digest.Servers[len(digest.Servers)-1]
+		if n := len(server.Vendors); n == 0 || server.Vendors[n-1].Name != f.VendorDaemon {
+			server.Vendors = append(server.Vendors, expiryDigestVendor{Name: f.VendorDaemon})
+		}
+		vendor := &server.Vendors[len(server.Vendors)-1]
+		vendor.Features = append(vendor.Features, f)
+	}
+
+	// Servers with the soonest expiration first
+	sort.SliceStable(digest.Servers, func(i, j int) bool {
+		return soonestExpiration(digest.Servers[i]).Before(soonestExpiration(digest.Servers[j]))
+	})
+	return digest, nil
+}
+
+// soonestExpiration returns the earliest expiration of a server of a digest
+func soonestExpiration(server expiryDigestServer) time.Time {
+	var soonest time.Time
+	for _, vendor := range server.Vendors {
+		if first := vendor.Features[0].ExpirationDate; soonest.IsZero() || first.Before(soonest) {
+			soonest = first
+		}
+	}
+	return soonest
+}
+
+// renderExpiryDigest renders the HTML body of a digest with the configured
+// template, or the built-in one
+func (s *AlertService) renderExpiryDigest(digest *expiryDigest) (string, error) {
+	tmpl := expiryDigestTemplate
+	if path := s.cfg.Alerts.ExpiryDigest.Template; path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read expiry digest template: %w", err)
+		}
+		tmpl, err = template.New("expiry_digest").Funcs(expiryDigestFuncs).Parse(string(content))
+		if err != nil {
+			return "", fmt.Errorf("failed to parse expiry digest template %s: %w", path, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, digest); err != nil {
+		return "", fmt.Errorf("failed to render expiry digest: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// formatExpiryDigestText renders the plain text alternative of a digest
+func formatExpiryDigestText(digest *expiryDigest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n%d features expire within %d days.\n", digest.Title, digest.Total, digest.Days)
+	for _, server := range digest.Servers {
+		fmt.Fprintf(&b, "\n%s", server.Hostname)
+		if server.Description != "" {
+			fmt.Fprintf(&b, " (%s)", server.Description)
+		}
+		b.WriteString("\n")
+		for _, vendor := range server.Vendors {
+			fmt.Fprintf(&b, "  %s\n", vendorLabel(vendor.Name))
+			for _, f := range vendor.Features {
+				fmt.Fprintf(&b, "    %s %s: %d seats, expires %s (%s)\n",
+					f.Name, f.Version, f.TotalLicenses, f.ExpirationDate.Format("2006-01-02"), daysLeft(f.DaysToExpire))
+			}
+		}
+	}
+	fmt.Fprintf(&b, "\n--\n%s\n", digest.Brand.Name())
+	return b.String()
+}
+
+// vendorLabel names a vendor daemon group, also when the daemon is unknown
+func vendorLabel(name string) string {
+	if name == "" {
+		return "Unknown vendor"
+	}
+	return name
+}
+
+// daysLeft describes the days until an expiration
+func daysLeft(days int) string {
+	switch days {
+	case 0:
+		return "today"
+	case 1:
+		return "in 1 day"
+	default:
+		return fmt.Sprintf("in %d days", days)
+	}
+}
+
+var expiryDigestFuncs = template.FuncMap{
+	"vendor":   vendorLabel,
+	"daysLeft": daysLeft,
+}
+
+var expiryDigestTemplate = template.Must(template.New("expiry_digest").Funcs(expiryDigestFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; font-size: 14px; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #f0f0f0; }
+td.num { text-align: right; }
+tr.soon td { color: #b00020; font-weight: bold; }
+.brand { font-weight: bold; color: {{or .Brand.PrimaryColor "#555"}}; }
+</style>
+</head>
+<body>
+<p class="brand">{{.Brand.Name}}</p>
+<h1>{{.Title}}</h1>
+<p>{{.Total}} features expire within {{.Days}} days. Generated {{.GeneratedAt.Format "2006-01-02 15:04"}}.</p>
+{{range .Servers}}
+<h2>{{.Hostname}}{{if .Description}} ({{.Description}}){{end}}</h2>
+{{range .Vendors}}
+<h3>{{vendor .Name}}</h3>
+<table>
+<tr><th>Feature</th><th>Version</th><th>Seats</th><th>Expires</th><th></th></tr>
+{{range .Features}}<tr{{if le .DaysToExpire 7}} class="soon"{{end}}><td>{{.Name}}</td><td>{{.Version}}</td><td class="num">{{.TotalLicenses}}</td><td>{{.ExpirationDate.Format "2006-01-02"}}</td><td>{{daysLeft .DaysToExpire}}</td></tr>
+{{end}}</table>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestExpiryDigest(t *testing.T) {
+	alerts, outbox, clk := newTestOutbox(t)
+	ctx := context.Background()
+	alerts.cfg.Email.Enabled = true
+	alerts.cfg.Email.To = []string{"ops@example.com"}
+	alerts.cfg.Alerts.ExpiryDigest = config.ExpiryDigestConfig{Enabled: true, Period: "weekly", Days: 30}
+	alerts.cfg.Servers = []config.LicenseServer{{Hostname: "27000@b", Description: "CAD"}}
+
+	now := clk.Now()
+	storage := NewStorageService(alerts.db, "sqlite")
+	if err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", VendorDaemon: "ansyslmd", TotalLicenses: 10, ExpirationDate: now.AddDate(0, 0, 20)},
+		{ServerHostname: "27000@a", Name: "mesher", VendorDaemon: "ansyslmd", TotalLicenses: 5, ExpirationDate: now.AddDate(0, 0, 200)},
+		{ServerHostname: "27000@b", Name: "modeler", VendorDaemon: "ugslmd", TotalLicenses: 4, ExpirationDate: now.AddDate(0, 0, 25)},
+		{ServerHostname: "27000@b", Name: "drafting", VendorDaemon: "ugslmd", TotalLicenses: 2, ExpirationDate: now.AddDate(0, 0, 3)},
+		{ServerHostname: "27000@b", Name: "viewer", TotalLicenses: 8, ExpirationDate: now.AddDate(0, 0, 10)},
+	}); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	digest, err := alerts.buildExpiryDigest(ctx)
+	if err != nil {
+		t.Fatalf("buildExpiryDigest failed: %v", err)
+	}
+	if digest.Total != 4 || len(digest.Servers) != 2 {
+		t.Fatalf("Expected 4 features on 2 servers, got %+v", digest)
+	}
+	// The server with the soonest expiration comes first
+	b := digest.Servers[0]
+	if b.Hostname != "27000@b" || b.Description != "CAD" || len(b.Vendors) != 2 {
+		t.Fatalf("Unexpected first server: %+v", b)
+	}
+	if b.Vendors[0].Name != "" || b.Vendors[1].Name != "ugslmd" || len(b.Vendors[1].Features) != 2 {
+		t.Fatalf("Unexpected vendors: %+v", b.Vendors)
+	}
+	if f := b.Vendors[1].Features[0]; f.Name != "drafting" || f.DaysToExpire != 3 {
+		t.Errorf("Expected drafting to expire first, got %+v", f)
+	}
+	if a := digest.Servers[1]; a.Hostname != "27000@a" || len(a.Vendors) != 1 || len(a.Vendors[0].Features) != 1 {
+		t.Errorf("Unexpected second server: %+v", a)
+	}
+
+	if err := alerts.SendExpiryDigest(ctx); err != nil {
+		t.Fatalf("SendExpiryDigest failed: %v", err)
+	}
+	var sent []emailMessage
+	outbox.send = func(msg emailMessage) error {
+		sent = append(sent, msg)
+		return nil
+	}
+	if n, err := outbox.Process(ctx); err != nil || n != 1 {
+		t.Fatalf("Process = %d, %v", n, err)
+	}
+	msg := sent[0]
+	if msg.Subject != "Licet License Expiry Digest: 4 features expiring within 30 days" {
+		t.Errorf("Unexpected subject: %s", msg.Subject)
+	}
+	for _, want := range []string{"27000@b (CAD)", "Unknown vendor", "ugslmd", `<tr class="soon"><td>drafting</td>`, "in 20 days"} {
+		if !strings.Contains(msg.HTML, want) {
+			t.Errorf("Expected the digest to contain %q", want)
+		}
+	}
+	if strings.Contains(msg.HTML, "mesher") || !strings.Contains(msg.Text, "drafting") {
+		t.Errorf("Unexpected digest:\n%s\n%s", msg.HTML, msg.Text)
+	}
+
+	// A custom template receives the same data
+	path := filepath.Join(t.TempDir(), "digest.html")
+	custom := `{{range .Servers}}[{{.Hostname}}{{range .Vendors}} {{vendor .Name}}:{{len .Features}}{{end}}]{{end}}`
+	if err := os.WriteFile(path, []byte(custom), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	alerts.cfg.Alerts.ExpiryDigest.Template = path
+	html, err := alerts.renderExpiryDigest(digest)
+	if err != nil {
+		t.Fatalf("renderExpiryDigest failed: %v", err)
+	}
+	if html != "[27000@b Unknown vendor:1 ugslmd:2][27000@a ansyslmd:1]" {
+		t.Errorf("Unexpected custom digest: %s", html)
+	}
+
+	// Nothing expiring, nothing sent
+	alerts.cfg.Alerts.ExpiryDigest.Days = 1
+	if err := alerts.SendExpiryDigest(ctx); err != nil {
+		t.Fatalf("SendExpiryDigest failed: %v", err)
+	}
+	if n, _ := outbox.Process(ctx); n != 0 {
+		t.Errorf("Expected no digest, processed %d", n)
+	}
+}
+
+func TestExpiryDigestSchedule(t *testing.T) {
+	if s := ExpiryDigestSchedule(config.ExpiryDigestConfig{Period: "daily"}); s != "0 7 * * *" {
+		t.Errorf("Unexpected daily schedule %q", s)
+	}
+	if s := ExpiryDigestSchedule(config.ExpiryDigestConfig{Period: "weekly", Cron: "0 8 * * 5"}); s != "0 8 * * 5" {
+		t.Errorf("Unexpected custom schedule %q", s)
+	}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"licet/internal/models"
+)
+
+// Trend classification thresholds, in licenses per day
+const (
+	trendDirectionSlope = 0.1
+	trendModerateSlope  = 0.3
+	trendStrongSlope    = 1.0
+	trendReduceSlope    = 0.5 // Decline worth reducing licenses for
+)
+
+// Days to capacity below which a trend analysis recommends action
+const (
+	capacityRiskDays  = 30
+	capacityWatchDays = 90
+)
+
+// Capacity report classification thresholds
+const (
+	capacityHighUtilizationPct = 80
+	capacityLowUtilizationPct  = 20
+	capacityTrendSlope         = 0.5
+)
+
+// thresholdCheck records the comparison of a value with a rule's threshold
+func thresholdCheck(rule string, value, threshold float64, crossed bool, outcome string) models.ThresholdCheck {
+	return models.ThresholdCheck{Rule: rule, Value: value, Threshold: threshold, Crossed: crossed, Outcome: outcome}
+}
+
+// trendChecks compares a slope with the direction and strength thresholds
+func trendChecks(slope float64) []models.ThresholdCheck {
+	abs := math.Abs(slope)
+	return []models.ThresholdCheck{
+		thresholdCheck(fmt.Sprintf("slope > %g", trendDirectionSlope), slope, trendDirectionSlope, slope > trendDirectionSlope, "direction = increasing"),
+		thresholdCheck(fmt.Sprintf("slope < %g", -trendDirectionSlope), slope, -trendDirectionSlope, slope < -trendDirectionSlope, "direction = decreasing"),
+		thresholdCheck(fmt.Sprintf("|slope| > %g", trendStrongSlope), abs, trendStrongSlope, abs > trendStrongSlope, "strength = strong"),
+		thresholdCheck(fmt.Sprintf("|slope| > %g", trendModerateSlope), abs, trendModerateSlope, abs > trendModerateSlope, "strength = moderate unless strong"),
+	}
+}
+
+// utilizationFormula explains a utilization percentage of the counted licenses
+func utilizationFormula(name, usageName string, usage float64, totalLicenses int, value float64) models.ExplainedValue {
+	if totalLicenses == 0 {
+		return models.ExplainedValue{Name: name, Formula: "0, the feature has no counted licenses", Value: value}
+	}
+	return models.ExplainedValue{
+		Name:    name,
+		Formula: fmt.Sprintf("%s / total_licenses * 100 = %.2f / %d * 100", usageName, usage, totalLicenses),
+		Value:   value,
+	}
+}
+
+// explainStatistics lists the inputs and formulas behind enhanced statistics
+// and the thresholds its trend and recommendations were chosen by
+func explainStatistics(stats *models.EnhancedStatistics, trend models.FeatureTrend, samples int, checks []models.ThresholdCheck) *models.Explanation {
+	return &models.Explanation{
+		Inputs: map[string]float64{
+			"samples":              float64(samples),
+			"total_licenses":       float64(stats.TotalLicenses),
+			"peak_usage":           float64(stats.PeakUsage),
+			"min_usage":            float64(stats.MinUsage),
+			"regression_days":      float64(trend.Samples),
+			"regression_slope":     trend.Slope,
+			"regression_intercept": trend.Intercept,
+			"regression_r_squared": trend.RSquared,
+		},
+		Formulas: []models.ExplainedValue{
+			{Name: "avg_usage", Formula: fmt.Sprintf("sum(users_count) / samples = %.2f / %d", stats.AvgUsage*float64(samples), samples), Value: stats.AvgUsage},
+			{Name: "std_dev", Formula: "sqrt(sum((users_count - avg_usage)^2) / samples)", Value: stats.StdDev},
+			utilizationFormula("avg_utilization_pct", "avg_usage", stats.AvgUsage, stats.TotalLicenses, stats.AvgUtilizationPct),
+			utilizationFormula("peak_utilization_pct", "peak_usage", float64(stats.PeakUsage), stats.TotalLicenses, stats.PeakUtilizationPct),
+			{Name: "trend_slope", Formula: fmt.Sprintf("least-squares slope of the daily average usage over %d days, holidays left out", trend.Samples), Value: stats.TrendSlope},
+			{Name: "efficiency_score", Formula: "utilization score (100 between 30% and 80% average utilization) - peak-to-average penalty - variability penalty, within 0-100", Value: stats.EfficiencyScore},
+		},
+		Thresholds: append(trendChecks(stats.TrendSlope), checks...),
+	}
+}
+
+// explainTrend lists the regression and formulas behind a trend analysis and
+// the thresholds its recommended action was chosen by
+func explainTrend(analysis *models.TrendAnalysis, trend models.FeatureTrend, totalLicenses int) *models.Explanation {
+	slope, intercept, lastDay := trend.Slope, trend.Intercept, trend.LastDay
+	e := &models.Explanation{
+		Inputs: map[string]float64{
+			"regression_days": float64(trend.Samples),
+			"slope":           slope,
+			"intercept":       intercept,
+			"r_squared":       trend.RSquared,
+			"last_day":        lastDay,
+			"total_licenses":  float64(totalLicenses),
+		},
+		Formulas: []models.ExplainedValue{
+			{Name: "change_per_week", Formula: fmt.Sprintf("slope * 7 = %.4f * 7", slope), Value: analysis.ChangePerWeek},
+			{Name: "change_per_month", Formula: fmt.Sprintf("slope * 30 = %.4f * 30", slope), Value: analysis.ChangePerMonth},
+		},
+		Thresholds: trendChecks(slope),
+	}
+	for _, p := range []struct {
+		days  int
+		value float64
+	}{{7, analysis.ProjectedUsage7Days}, {30, analysis.ProjectedUsage30Days}, {90, analysis.ProjectedUsage90Days}} {
+		e.Formulas = append(e.Formulas, models.ExplainedValue{
+			Name:    fmt.Sprintf("projected_usage_%d_days", p.days),
+			Formula: fmt.Sprintf("max(0, slope * (last_day + %d) + intercept) = max(0, %.4f * (%.0f + %d) + %.4f)", p.days, slope, lastDay, p.days, intercept),
+			Value:   p.value,
+		})
+	}
+
+	if slope <= 0 || totalLicenses == 0 {
+		e.Thresholds = append(e.Thresholds, thresholdCheck(fmt.Sprintf("slope < %g", -trendReduceSlope), slope, -trendReduceSlope,
+			slope < -trendReduceSlope && totalLicenses > 0, "Consider reducing license count to optimize costs"))
+		return e
+	}
+
+	currentUsage := slope*lastDay + intercept
+	remaining := float64(totalLicenses) - currentUsage
+	e.Formulas = append(e.Formulas,
+		models.ExplainedValue{Name: "current_usage", Formula: fmt.Sprintf("slope * last_day + intercept = %.4f * %.0f + %.4f", slope, lastDay, intercept), Value: currentUsage},
+		models.ExplainedValue{Name: "remaining_capacity", Formula: fmt.Sprintf("total_licenses - current_usage = %d - %.2f", totalLicenses, currentUsage), Value: remaining},
+	)
+	e.Thresholds = append(e.Thresholds, thresholdCheck("remaining_capacity <= 0", remaining, 0, remaining <= 0, "Immediately increase license count - at capacity"))
+	if remaining > 0 {
+		days := float64(analysis.DaysToCapacity)
+		e.Formulas = append(e.Formulas, models.ExplainedValue{
+			Name:    "days_to_capacity",
+			Formula: fmt.Sprintf("floor(remaining_capacity / slope) = floor(%.2f / %.4f)", remaining, slope),
+			Value:   days,
+		})
+		e.Thresholds = append(e.Thresholds,
+			thresholdCheck(fmt.Sprintf("days_to_capacity < %d", capacityRiskDays), days, capacityRiskDays, days < capacityRiskDays, "Consider increasing license count within 30 days"),
+			thresholdCheck(fmt.Sprintf("days_to_capacity < %d", capacityWatchDays), days, capacityWatchDays, days < capacityWatchDays, "Monitor usage and plan for potential license increase, unless within 30 days"),
+		)
+	}
+	return e
+}
+
+// ExplainCapacityReport lists the classification rules and thresholds behind
+// a capacity planning report, including stored reports
+func ExplainCapacityReport(report *models.CapacityPlanningReport) *models.Explanation {
+	_, checks := generateCapacityRecommendations(report)
+	return &models.Explanation{
+		Inputs: map[string]float64{
+			"period_days":    float64(report.PeriodAnalyzed),
+			"total_servers":  float64(report.TotalServers),
+			"total_features": float64(report.TotalFeatures),
+		},
+		Formulas: []models.ExplainedValue{
+			{Name: "utilization_pct", Formula: fmt.Sprintf("avg_usage / total_licenses * 100 per feature over %d days", report.PeriodAnalyzed)},
+			{Name: "features_at_capacity", Formula: fmt.Sprintf("count of features with utilization_pct >= %d", capacityHighUtilizationPct), Value: float64(report.FeaturesAtCapacity)},
+			{Name: "features_underutilized", Formula: fmt.Sprintf("count of features with utilization_pct <= %d", capacityLowUtilizationPct), Value: float64(report.FeaturesUnderutilized)},
+			{Name: "trending_up", Formula: fmt.Sprintf("count of features with trend_slope > %g licenses per day", capacityTrendSlope), Value: float64(len(report.TrendingUp))},
+			{Name: "trending_down", Formula: fmt.Sprintf("count of features with trend_slope < %g licenses per day", -capacityTrendSlope), Value: float64(len(report.TrendingDown))},
+		},
+		Thresholds: checks,
+	}
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"licet/internal/models"
+)
+
+func TestGenerateRecommendations_Checks(t *testing.T) {
+	recs, checks := generateRecommendations(10, 25, "stable", 0, 40)
+	if len(recs) != 1 || recs[0].Impact != "Potential to reduce licenses by 20 without impacting users" {
+		t.Fatalf("Expected one reduce recommendation, got %+v", recs)
+	}
+	if len(checks) != 5 {
+		t.Fatalf("Expected every rule to be listed, got %d", len(checks))
+	}
+	for _, c := range checks {
+		if c.Crossed != (c.Outcome == recs[0].Title) {
+			t.Errorf("Check %+v does not match the recommendations", c)
+		}
+	}
+}
+
+func TestGetEnhancedStatistics_Explanation(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	if err := storage.StoreFeatures(ctx, []models.Feature{{ServerHostname: "27000@a", Name: "solver", TotalLicenses: 40, UsedLicenses: 4}}); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	for day := 0; day < 14; day++ {
+		date := time.Now().AddDate(0, 0, -day).Format("2006-01-02")
+		_, err := db.Exec(`INSERT INTO feature_usage (server_hostname, feature_name, date, time, users_count) VALUES (?, ?, ?, ?, ?)`,
+			"27000@a", "solver", date, "12:00:00", 4)
+		if err != nil {
+			t.Fatalf("Failed to insert usage: %v", err)
+		}
+	}
+	enhanced := NewEnhancedAnalyticsService(db, storage, "sqlite")
+
+	stats, err := enhanced.GetEnhancedStatistics(ctx, "27000@a", "solver", 30)
+	if err != nil {
+		t.Fatalf("GetEnhancedStatistics failed: %v", err)
+	}
+	e := stats.Explanation
+	if e == nil || e.Inputs["samples"] != 14 || e.Inputs["total_licenses"] != 40 {
+		t.Fatalf("Unexpected explanation %+v", e)
+	}
+	if f := e.Formulas[2]; f.Name != "avg_utilization_pct" || f.Formula != "avg_usage / total_licenses * 100 = 4.00 / 40 * 100" || f.Value != 10 {
+		t.Errorf("Unexpected utilization formula %+v", f)
+	}
+	crossed := 0
+	for _, c := range e.Thresholds {
+		if c.Crossed {
+			crossed++
+			if c.Rule != "avg_utilization_pct < 20 and total_licenses > 5" {
+				t.Errorf("Unexpected crossed rule %+v", c)
+			}
+		}
+	}
+	if crossed != 1 || len(stats.Recommendations) != 1 {
+		t.Errorf("Expected one crossed rule and recommendation, got %d and %+v", crossed, stats.Recommendations)
+	}
+
+	analysis, err := enhanced.GetTrendAnalysis(ctx, "27000@a", "solver", 30)
+	if err != nil {
+		t.Fatalf("GetTrendAnalysis failed: %v", err)
+	}
+	if e := analysis.Explanation; e == nil || e.Inputs["regression_days"] != 14 || len(e.Formulas) != 5 {
+		t.Errorf("Unexpected trend explanation %+v", e)
+	}
+}
+
+func TestExplainCapacityReport(t *testing.T) {
+	report := &models.CapacityPlanningReport{
+		PeriodAnalyzed:        30,
+		TotalFeatures:         3,
+		FeaturesUnderutilized: 2,
+		TrendingUp:            []models.CapacityInsight{{FeatureName: "solver"}},
+	}
+	report.Recommendations, _ = generateCapacityRecommendations(report)
+
+	e := ExplainCapacityReport(report)
+	var crossed []string
+	for _, c := range e.Thresholds {
+		if c.Crossed {
+			crossed = append(crossed, c.Outcome)
+		}
+	}
+	if len(crossed) != len(report.Recommendations) || crossed[0] != report.Recommendations[0].Title {
+		t.Errorf("Crossed rules %v do not match recommendations %+v", crossed, report.Recommendations)
+	}
+	if e.Formulas[2].Name != "features_underutilized" || e.Formulas[2].Value != 2 {
+		t.Errorf("Unexpected formula %+v", e.Formulas[2])
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+)
+
+// encryptedPrefix marks encrypted field values; values without it are plaintext
+const encryptedPrefix = "enc1:"
+
+// ErrNoEncryptionKey is returned when encryption is enabled without a key
+var ErrNoEncryptionKey = errors.New("encryption is enabled but no key is configured")
+
+// FieldCipher encrypts sensitive fields such as usernames and the client
+// hosts of checkouts before they are stored. Encryption is deterministic
+// (the nonce is derived from the value) so equality lookups, grouping and
+// unique constraints still work. A nil FieldCipher stores values in
+// plaintext.
+type FieldCipher struct {
+	aead     cipher.AEAD
+	nonceKey []byte
+}
+
+// NewFieldCipher creates a field cipher, or returns nil when encryption is disabled
+func NewFieldCipher(cfg config.EncryptionConfig) (*FieldCipher, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	secret := cfg.Key
+	if secret == "" && cfg.KeyFile != "" {
+		data, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption key file: %w", err)
+		}
+		secret = strings.TrimSpace(string(data))
+	}
+	if secret == "" {
+		return nil, ErrNoEncryptionKey
+	}
+
+	block, err := aes.NewCipher(deriveKey(secret, "licet field encryption"))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FieldCipher{
+		aead:     aead,
+		nonceKey: deriveKey(secret, "licet field nonce"),
+	}, nil
+}
+
+// deriveKey derives a 256-bit purpose-specific key from the configured secret
+func deriveKey(secret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// Encrypt returns the stored form of a value
+func (c *FieldCipher) Encrypt(value string) string {
+	if c == nil || value == "" || strings.HasPrefix(value, encryptedPrefix) {
+		return value
+	}
+
+	mac := hmac.New(sha256.New, c.nonceKey)
+	mac.Write([]byte(value))
+	nonce := mac.Sum(nil)[:c.aead.NonceSize()]
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedPrefix + base64.RawStdEncoding.EncodeToString(sealed)
+}
+
+// Decrypt returns the plaintext of a stored value. Plaintext values are
+// returned unchanged.
+func (c *FieldCipher) Decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil
+	}
+	if c == nil {
+		return "", errors.New("encrypted value found but encryption is not configured")
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+
+	plain, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt value: wrong key or corrupted data")
+	}
+	return string(plain), nil
+}
+
+// encryptedColumns lists every stored column the cipher encrypts: the
+// username columns, the client hosts of checkouts, which tell whose machine
+// used a license as well, and the identities of waitlist subscribers. Events
+// and feature users store no hosts.
+var encryptedColumns = append(slices.Clip(userColumns), []struct{ table, column string }{
+	{"license_checkouts", "host"},
+	{"waitlist_subscriptions", "username"},
+	{"waitlist_subscriptions", "email"},
+}...)
+
+// EncryptExisting encrypts plaintext values left over from before
+// encryption was enabled and returns the number of rows updated
+func (c *FieldCipher) EncryptExisting(ctx context.Context, db *sqlx.DB) (int64, error) {
+	if c == nil {
+		return 0, nil
+	}
+
+	var total int64
+	for _, col := range encryptedColumns {
+		var values []string
+		query := fmt.Sprintf(`SELECT DISTINCT %s FROM %s WHERE %s NOT LIKE ?`, col.column, col.table, col.column)
+		if err := db.SelectContext(ctx, &values, db.Rebind(query), encryptedPrefix+"%"); err != nil {
+			return total, fmt.Errorf("failed to read %s: %w", col.table, err)
+		}
+
+		update := db.Rebind(fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s = ?`, col.table, col.column, col.column))
+		for _, value := range values {
+			if value == "" {
+				continue
+			}
+			result, err := db.ExecContext(ctx, update, c.Encrypt(value), value)
+			if err != nil {
+				return total, fmt.Errorf("failed to encrypt %s: %w", col.table, err)
+			}
+			n, _ := result.RowsAffected()
+			total += n
+		}
+	}
+
+	if total > 0 {
+		log.Infof("Encrypted %d stored values", total)
+	}
+	return total, nil
+}
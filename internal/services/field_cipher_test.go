@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestFieldCipher_RoundTrip(t *testing.T) {
+	c, err := NewFieldCipher(config.EncryptionConfig{Enabled: true, Key: "secret"})
+	if err != nil {
+		t.Fatalf("NewFieldCipher failed: %v", err)
+	}
+
+	enc := c.Encrypt("jdoe")
+	if !strings.HasPrefix(enc, encryptedPrefix) || strings.Contains(enc, "jdoe") {
+		t.Errorf("Expected encrypted value, got %q", enc)
+	}
+	if c.Encrypt("jdoe") != enc {
+		t.Error("Expected deterministic encryption")
+	}
+	if c.Encrypt(enc) != enc {
+		t.Error("Expected encrypted values not to be encrypted twice")
+	}
+	if plain, err := c.Decrypt(enc); err != nil || plain != "jdoe" {
+		t.Errorf("Expected 'jdoe', got %q (%v)", plain, err)
+	}
+	if plain, _ := c.Decrypt("legacy"); plain != "legacy" {
+		t.Errorf("Expected plaintext to pass through, got %q", plain)
+	}
+
+	other, _ := NewFieldCipher(config.EncryptionConfig{Enabled: true, Key: "other"})
+	if _, err := other.Decrypt(enc); err == nil {
+		t.Error("Expected decryption with the wrong key to fail")
+	}
+
+	var disabled *FieldCipher
+	if disabled.Encrypt("jdoe") != "jdoe" {
+		t.Error("Expected nil cipher to store plaintext")
+	}
+	if _, err := disabled.Decrypt(enc); err == nil {
+		t.Error("Expected nil cipher to reject encrypted values")
+	}
+}
+
+func TestNewFieldCipher_Key(t *testing.T) {
+	if c, err := NewFieldCipher(config.EncryptionConfig{}); c != nil || err != nil {
+		t.Errorf("Expected nil cipher when disabled, got %v, %v", c, err)
+	}
+	if _, err := NewFieldCipher(config.EncryptionConfig{Enabled: true}); !errors.Is(err, ErrNoEncryptionKey) {
+		t.Errorf("Expected ErrNoEncryptionKey, got %v", err)
+	}
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+	os.WriteFile(keyFile, []byte("secret\n"), 0600)
+	fromFile, err := NewFieldCipher(config.EncryptionConfig{Enabled: true, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("NewFieldCipher with key file failed: %v", err)
+	}
+	fromKey, _ := NewFieldCipher(config.EncryptionConfig{Enabled: true, Key: "secret"})
+	if fromFile.Encrypt("jdoe") != fromKey.Encrypt("jdoe") {
+		t.Error("Expected key file to be equivalent to the inline key")
+	}
+}
+
+func TestFieldCipher_StoredUsernames(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	// Rows written before encryption was enabled
+	storage := NewStorageService(db, "sqlite")
+	users := []models.LicenseUser{{ServerHostname: "27000@a", FeatureName: "solver", Username: "jdoe"}}
+	if err := storage.RecordUsers(ctx, users, now); err != nil {
+		t.Fatalf("RecordUsers failed: %v", err)
+	}
+
+	c, _ := NewFieldCipher(config.EncryptionConfig{Enabled: true, Key: "secret"})
+	n, err := c.EncryptExisting(ctx, db)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 row encrypted, got %d (%v)", n, err)
+	}
+
+	storage.SetCipher(c)
+	if err := storage.RecordUsers(ctx, users, now.Add(time.Minute)); err != nil {
+		t.Fatalf("RecordUsers failed: %v", err)
+	}
+
+	var stored []string
+	db.Select(&stored, `SELECT username FROM feature_users`)
+	if len(stored) != 1 || stored[0] == "jdoe" {
+		t.Errorf("Expected a single encrypted row, got %v", stored)
+	}
+
+	seen, err := storage.GetFirstSeenUsers(ctx, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetFirstSeenUsers failed: %v", err)
+	}
+	if len(seen) != 1 || seen[0].Username != "jdoe" {
+		t.Errorf("Expected decrypted username, got %+v", seen)
+	}
+
+	// Waitlist subscribers are stored encrypted and read back in plaintext
+	cfg := &config.Config{}
+	cfg.Waitlist.MaxPerUser = 1
+	alerts := NewAlertService(db, cfg)
+	alerts.SetCipher(c)
+	if _, err := alerts.Subscribe(ctx, models.WaitlistSubscription{Username: "jdoe", Email: "jdoe@example.com", FeatureName: "solver"}); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	var subscriber []string
+	db.Select(&subscriber, `SELECT username || email FROM waitlist_subscriptions`)
+	if len(subscriber) != 1 || strings.Contains(subscriber[0], "jdoe") {
+		t.Errorf("Expected an encrypted subscriber, got %v", subscriber)
+	}
+	subs, err := alerts.GetWaitlist(ctx, "jdoe")
+	if err != nil || len(subs) != 1 || subs[0].Username != "jdoe" || subs[0].Email != "jdoe@example.com" {
+		t.Errorf("Expected the decrypted subscription, got %+v (%v)", subs, err)
+	}
+
+	svc := NewAnonymizeService(db, cfg)
+	svc.SetCipher(c)
+	report, err := svc.AnonymizeUser(ctx, "jdoe", "admin")
+	if err != nil || report.TotalRows != 2 || report.RowsAffected["waitlist_subscriptions"] != 1 {
+		t.Fatalf("Expected encrypted user to be anonymized, got %+v (%v)", report, err)
+	}
+}
+
+func TestFieldCipher_CheckoutHosts(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	c, _ := NewFieldCipher(config.EncryptionConfig{Enabled: true, Key: "secret"})
+	storage := NewStorageService(db, "sqlite")
+	storage.SetCipher(c)
+
+	users := []models.LicenseUser{{ServerHostname: "27000@a", FeatureName: "solver", Username: "jdoe", Host: "ws01"}}
+	if err := storage.RecordCheckouts(ctx, "27000@a", users, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("RecordCheckouts failed: %v", err)
+	}
+	// The encrypted host still matches the open checkout
+	if err := storage.RecordCheckouts(ctx, "27000@a", users, now); err != nil {
+		t.Fatalf("RecordCheckouts failed: %v", err)
+	}
+
+	var hosts []string
+	db.Select(&hosts, `SELECT host FROM license_checkouts`)
+	if len(hosts) != 1 || hosts[0] == "ws01" {
+		t.Errorf("Expected a single checkout with an encrypted host, got %v", hosts)
+	}
+
+	checkouts, err := storage.GetOpenCheckouts(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("GetOpenCheckouts failed: %v", err)
+	}
+	if len(checkouts) != 1 || checkouts[0].Host != "ws01" || checkouts[0].Username != "jdoe" {
+		t.Errorf("Expected the decrypted checkout, got %+v", checkouts)
+	}
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+// ErrUnknownFlag is returned for feature flags that are not registered
+var ErrUnknownFlag = errors.New("unknown feature flag")
+
+// Feature flags of experimental subsystems
+const (
+	FlagBudgetForecast = "budget_forecast"
+	FlagSnapshots      = "snapshots"
+)
+
+// flagDefinition is a registered feature flag and its built-in default.
+// Risky functionality registers a flag that defaults to off, so it ships dark
+// until a deployment enables it.
+type flagDefinition struct {
+	description string
+	enabled     bool
+}
+
+var featureFlags = map[string]flagDefinition{
+	FlagBudgetForecast: {"Budget forecast export of projected seat requirements", true},
+	FlagSnapshots:      {"Server history snapshot export and import", true},
+}
+
+// flagOverride is a runtime toggle stored in the database
+type flagOverride struct {
+	Name      string    `db:"name"`
+	Enabled   bool      `db:"enabled"`
+	UpdatedBy string    `db:"updated_by"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// FlagService answers whether feature flags are enabled. A flag's value comes
+// from a runtime override stored in the database, else from feature_flags in
+// the configuration, else from its built-in default.
+type FlagService struct {
+	db  *sqlx.DB
+	cfg *config.Config
+
+	mu        sync.RWMutex
+	overrides map[string]flagOverride
+}
+
+// NewFlagService creates a feature flag service. Call Load to read the
+// runtime overrides.
+func NewFlagService(db *sqlx.DB, cfg *config.Config) *FlagService {
+	for name := range cfg.FeatureFlags {
+		if _, ok := featureFlags[name]; !ok {
+			log.Warnf("Ignoring unknown feature flag %q in configuration", name)
+		}
+	}
+	return &FlagService{db: db, cfg: cfg, overrides: make(map[string]flagOverride)}
+}
+
+// Load reads the runtime overrides, picking up changes made by other
+// instances sharing the database
+func (s *FlagService) Load(ctx context.Context) error {
+	var rows []flagOverride
+	if err := s.db.SelectContext(ctx, &rows, `SELECT name, enabled, updated_by, updated_at FROM feature_flags`); err != nil {
+		return fmt.Errorf("failed to load feature flags: %w", err)
+	}
+
+	overrides := make(map[string]flagOverride, len(rows))
+	for _, row := range rows {
+		overrides[row.Name] = row
+	}
+
+	s.mu.Lock()
+	s.overrides = overrides
+	s.mu.Unlock()
+	return nil
+}
+
+// Enabled reports whether a feature flag is enabled. Unknown flags are off.
+func (s *FlagService) Enabled(name string) bool {
+	def, ok := featureFlags[name]
+	if !ok {
+		return false
+	}
+	if s == nil {
+		return def.enabled
+	}
+
+	s.mu.RLock()
+	override, overridden := s.overrides[name]
+	s.mu.RUnlock()
+	if overridden {
+		return override.Enabled
+	}
+	return s.configured(name, def)
+}
+
+// configured returns the value of a flag without its runtime override
+func (s *FlagService) configured(name string, def flagDefinition) bool {
+	if enabled, ok := s.cfg.FeatureFlags[name]; ok {
+		return enabled
+	}
+	return def.enabled
+}
+
+// List returns every registered flag by name
+func (s *FlagService) List() []models.FeatureFlag {
+	flags := make([]models.FeatureFlag, 0, len(featureFlags))
+	for name := range featureFlags {
+		flags = append(flags, s.flag(name))
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags
+}
+
+// flag describes one registered flag
+func (s *FlagService) flag(name string) models.FeatureFlag {
+	def := featureFlags[name]
+	flag := models.FeatureFlag{
+		Name:        name,
+		Description: def.description,
+		Default:     s.configured(name, def),
+	}
+	flag.Enabled = flag.Default
+
+	s.mu.RLock()
+	override, ok := s.overrides[name]
+	s.mu.RUnlock()
+	if ok {
+		flag.Enabled = override.Enabled
+		flag.Overridden = true
+		flag.UpdatedBy = override.UpdatedBy
+		updatedAt := override.UpdatedAt
+		flag.UpdatedAt = &updatedAt
+	}
+	return flag
+}
+
+// Set stores a runtime override of a flag and records an audit entry
+func (s *FlagService) Set(ctx context.Context, name string, enabled bool, actor string) (*models.FeatureFlag, error) {
+	if _, ok := featureFlags[name]; !ok {
+		return nil, ErrUnknownFlag
+	}
+
+	override := flagOverride{Name: name, Enabled: enabled, UpdatedBy: actor, UpdatedAt: time.Now()}
+	err := s.write(ctx, name, actor, "set_feature_flag", map[string]bool{"enabled": enabled}, func(tx *sqlx.Tx) error {
+		query := `INSERT INTO feature_flags (name, enabled, updated_by, updated_at) VALUES (?, ?, ?, ?)`
+		_, err := tx.ExecContext(ctx, tx.Rebind(query), name, enabled, actor, override.UpdatedAt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.overrides[name] = override
+	s.mu.Unlock()
+
+	flag := s.flag(name)
+	return &flag, nil
+}
+
+// Reset removes the runtime override of a flag, returning it to its
+// configured value, and records an audit entry
+func (s *FlagService) Reset(ctx context.Context, name, actor string) (*models.FeatureFlag, error) {
+	if _, ok := featureFlags[name]; !ok {
+		return nil, ErrUnknownFlag
+	}
+
+	if err := s.write(ctx, name, actor, "reset_feature_flag", nil, nil); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	delete(s.overrides, name)
+	s.mu.Unlock()
+
+	flag := s.flag(name)
+	return &flag, nil
+}
+
+// write replaces the override of a flag with whatever insert stores, if
+// anything, and records the change in the audit log in the same transaction
+func (s *FlagService) write(ctx context.Context, name, actor, action string, details interface{}, insert func(*sqlx.Tx) error) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM feature_flags WHERE name = ?`), name); err != nil {
+		return fmt.Errorf("failed to update feature flag: %w", err)
+	}
+	if insert != nil {
+		if err := insert(tx); err != nil {
+			return fmt.Errorf("failed to update feature flag: %w", err)
+		}
+	}
+
+	detailsJSON := []byte("{}")
+	if details != nil {
+		detailsJSON, _ = json.Marshal(details)
+	}
+	_, err = tx.ExecContext(ctx, tx.Rebind(`
+		INSERT INTO audit_log (created_at, actor, action, target, details)
+		VALUES (?, ?, ?, ?, ?)
+	`), time.Now(), actor, action, name, string(detailsJSON))
+	if err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+
+	return tx.Commit()
+}
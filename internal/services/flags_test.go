@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"licet/internal/config"
+)
+
+func TestFlagService(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	cfg := &config.Config{FeatureFlags: map[string]bool{FlagSnapshots: false}}
+	flags := NewFlagService(db, cfg)
+	if err := flags.Load(ctx); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if flags.Enabled(FlagSnapshots) || !flags.Enabled(FlagBudgetForecast) {
+		t.Fatal("Expected the configured value, else the built-in default")
+	}
+	if flags.Enabled("federation") {
+		t.Error("Expected unknown flags to be off")
+	}
+
+	flag, err := flags.Set(ctx, FlagSnapshots, true, "admin")
+	if err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if !flag.Enabled || flag.Default || !flag.Overridden || flag.UpdatedBy != "admin" {
+		t.Errorf("Unexpected flag %+v", flag)
+	}
+
+	// Another instance sharing the database sees the toggle after loading
+	other := NewFlagService(db, cfg)
+	if err := other.Load(ctx); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !other.Enabled(FlagSnapshots) {
+		t.Error("Expected the override to be loaded")
+	}
+
+	if _, err := flags.Set(ctx, FlagSnapshots, false, "admin"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if flags.Enabled(FlagSnapshots) {
+		t.Error("Expected the flag to be toggled off")
+	}
+
+	if _, err := flags.Reset(ctx, FlagBudgetForecast, "admin"); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if _, err := flags.Set(ctx, "federation", true, "admin"); !errors.Is(err, ErrUnknownFlag) {
+		t.Errorf("Expected ErrUnknownFlag, got %v", err)
+	}
+
+	var count int
+	db.Get(&count, `SELECT COUNT(*) FROM feature_flags`)
+	if count != 1 {
+		t.Errorf("Expected 1 stored override, got %d", count)
+	}
+	db.Get(&count, `SELECT COUNT(*) FROM audit_log WHERE action IN ('set_feature_flag', 'reset_feature_flag')`)
+	if count != 3 {
+		t.Errorf("Expected 3 audit entries, got %d", count)
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/models"
+)
+
+// notificationEventType marks sent notifications in alert_events, which the
+// hourly notification limit counts
+const notificationEventType = "notification"
+
+// floodSummaryMaxLines caps the alerts listed in a flood summary email
+const floodSummaryMaxLines = 100
+
+// notification is one email: a single alert or the new alerts of an incident
+type notification struct {
+	incidentID int64 // 0 for a single alert
+	alerts     []models.Alert
+}
+
+func (n notification) String() string {
+	if n.incidentID != 0 {
+		return fmt.Sprintf("incident %d", n.incidentID)
+	}
+	return fmt.Sprintf("alert %d", n.alerts[0].ID)
+}
+
+// notificationBudget returns how many notifications may still be sent this
+// hour, or -1 when notifications are not rate limited
+func (s *AlertService) notificationBudget(ctx context.Context) int {
+	limit := s.cfg.Alerts.MaxNotificationsPerHour
+	if limit <= 0 {
+		return -1
+	}
+
+	var sent int
+	query := `SELECT COUNT(*) FROM alert_events WHERE type = ? AND datetime > ?`
+	err := s.db.GetContext(ctx, &sent, s.db.Rebind(query), notificationEventType, s.clock.Now().Add(-time.Hour))
+	if err != nil {
+		log.Errorf("Failed to count sent notifications: %v", err)
+		return -1
+	}
+	if sent >= limit {
+		return 0
+	}
+	return limit - sent
+}
+
+// isFlood reports whether pending notifications should collapse into one
+// summary: there are more than the flood threshold, or more than may be sent
+func (s *AlertService) isFlood(pending, budget int) bool {
+	if threshold := s.cfg.Alerts.FloodThreshold; threshold > 0 && pending > threshold {
+		return true
+	}
+	return budget >= 0 && pending > budget
+}
+
+// recordNotification counts a sent notification towards the hourly limit
+func (s *AlertService) recordNotification() {
+	query := `INSERT INTO alert_events (datetime, type, hostname) VALUES (?, ?, ?)`
+	if _, err := s.db.Exec(s.db.Rebind(query), s.clock.Now(), notificationEventType, ""); err != nil {
+		log.Errorf("Failed to record notification: %v", err)
+	}
+}
+
+// markSent marks the alerts of a notification as sent
+func (s *AlertService) markSent(ctx context.Context, alerts []models.Alert) {
+	for _, alert := range alerts {
+		if err := s.MarkAlertSent(ctx, alert.ID); err != nil {
+			log.Errorf("Failed to mark alert %d as sent: %v", alert.ID, err)
+		}
+	}
+}
+
+// sendFloodSummary sends one email summarizing all pending notifications,
+// e.g. "42 servers down" during a site-wide network outage
+func (s *AlertService) sendFloodSummary(ctx context.Context, notifications []notification) error {
+	var alerts []models.Alert
+	for _, n := range notifications {
+		alerts = append(alerts, n.alerts...)
+	}
+
+	recipients := s.cfg.Email.To
+	for _, alert := range alerts {
+		if alert.Severity == "critical" {
+			recipients = append(recipients, s.cfg.Email.Alerts...)
+			break
+		}
+	}
+
+	subject, body := floodSummary(s.cfg.Branding.Name(), alerts)
+	if err := s.sendAlertEmail(ctx, alerts, subject, body, recipients, nil); err != nil {
+		return fmt.Errorf("failed to send flood summary: %w", err)
+	}
+	log.Warnf("Alert flood: sent one summary for %d notifications (%d alerts)", len(notifications), len(alerts))
+
+	s.recordNotification()
+	s.markSent(ctx, alerts)
+	return nil
+}
+
+// floodSummary describes a burst of alerts by type, counting distinct servers
+// for server-down alerts
+func floodSummary(product string, alerts []models.Alert) (string, string) {
+	counts := make(map[string]int)
+	downServers := make(map[string]bool)
+	for _, alert := range alerts {
+		if alert.AlertType == "down" {
+			downServers[alert.ServerHostname] = true
+			continue
+		}
+		counts[alert.AlertType]++
+	}
+
+	type part struct {
+		text  string
+		count int
+	}
+	var parts []part
+	if n := len(downServers); n > 0 {
+		noun := "servers"
+		if n == 1 {
+			noun = "server"
+		}
+		parts = append(parts, part{fmt.Sprintf("%d %s down", n, noun), n})
+	}
+	for alertType, n := range counts {
+		noun := "alerts"
+		if n == 1 {
+			noun = "alert"
+		}
+		parts = append(parts, part{fmt.Sprintf("%d %s %s", n, alertType, noun), n})
+	}
+	sort.SliceStable(parts, func(i, j int) bool {
+		if parts[i].count != parts[j].count {
+			return parts[i].count > parts[j].count
+		}
+		return parts[i].text < parts[j].text
+	})
+	texts := make([]string, len(parts))
+	for i, p := range parts {
+		texts[i] = p.text
+	}
+	summary := strings.Join(texts, ", ")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\nLicense Alert Flood\n\n%s\n\n", summary)
+	b.WriteString("Too many alerts were raised at once to notify about each, so they are summarized here.\n\n")
+	for i, alert := range alerts {
+		if i == floodSummaryMaxLines {
+			fmt.Fprintf(&b, "  ... and %d more, see the alerts page\n", len(alerts)-i)
+			break
+		}
+		fmt.Fprintf(&b, "  %s [%s] %s %s: %s\n", alert.CreatedAt.Format("15:04:05"),
+			alert.Severity, alert.ServerHostname, alert.AlertType, alert.Message)
+	}
+	fmt.Fprintf(&b, "\n--\n%s\n", product)
+
+	return "[" + product + "] Alert flood: " + summary, b.String()
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/clock"
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestFloodSummary(t *testing.T) {
+	var alerts []models.Alert
+	for i := 0; i < 60; i++ {
+		host := fmt.Sprintf("27000@lic%d", i)
+		alerts = append(alerts,
+			models.Alert{ServerHostname: host, AlertType: "down", Severity: "critical", Message: host + " is down"},
+			models.Alert{ServerHostname: host, AlertType: "down", Severity: "critical", Message: host + " still down"})
+	}
+	alerts = append(alerts, models.Alert{ServerHostname: "27000@x", FeatureName: "solver", AlertType: "utilization", Severity: "warning"})
+
+	subject, body := floodSummary("Licet", alerts)
+	if subject != "[Licet] Alert flood: 60 servers down, 1 utilization alert" {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+	if !strings.Contains(body, "27000@lic0 is down") {
+		t.Error("Expected the summary to list alerts")
+	}
+	if !strings.Contains(body, fmt.Sprintf("and %d more", len(alerts)-floodSummaryMaxLines)) {
+		t.Error("Expected the alert list to be capped")
+	}
+}
+
+func TestNotificationBudget(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	s := NewAlertService(db, &config.Config{Alerts: config.AlertConfig{MaxNotificationsPerHour: 3, FloodThreshold: 10}})
+
+	if budget := s.notificationBudget(ctx); budget != 3 {
+		t.Fatalf("Expected a budget of 3, got %d", budget)
+	}
+	if s.isFlood(3, 3) {
+		t.Error("Expected notifications within budget and threshold not to be a flood")
+	}
+	if !s.isFlood(4, 3) {
+		t.Error("Expected notifications beyond the budget to collapse")
+	}
+	if !s.isFlood(11, -1) {
+		t.Error("Expected notifications beyond the flood threshold to collapse")
+	}
+
+	for i := 0; i < 3; i++ {
+		s.recordNotification()
+	}
+	if budget := s.notificationBudget(ctx); budget != 0 {
+		t.Errorf("Expected the budget to be used up, got %d", budget)
+	}
+
+	// Sent notifications do not throttle alerts for any server
+	if s.CheckThrottle("", "down") {
+		t.Error("Expected notification events not to throttle alerts")
+	}
+
+	unlimited := NewAlertService(db, &config.Config{})
+	if budget := unlimited.notificationBudget(ctx); budget != -1 {
+		t.Errorf("Expected no limit without max_notifications_per_hour, got %d", budget)
+	}
+	if unlimited.isFlood(1000, -1) {
+		t.Error("Expected no flood protection when disabled")
+	}
+}
+
+func TestCheckThrottle_ResendInterval(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	s := NewAlertService(db, &config.Config{Alerts: config.AlertConfig{ResendIntervalMin: 60, MaxNotificationsPerHour: 1}})
+	c := clock.NewFixed(time.Date(2025, 3, 14, 9, 0, 0, 0, time.Local))
+	s.SetClock(c)
+
+	if s.CheckThrottle("27000@a", "down") {
+		t.Fatal("Expected the first alert not to be throttled")
+	}
+	c.Advance(59 * time.Minute)
+	if !s.CheckThrottle("27000@a", "down") {
+		t.Error("Expected the alert to be throttled within the resend interval")
+	}
+	c.Advance(2 * time.Minute)
+	if s.CheckThrottle("27000@a", "down") {
+		t.Error("Expected the alert to be resent after the resend interval")
+	}
+
+	s.recordNotification()
+	if budget := s.notificationBudget(ctx); budget != 0 {
+		t.Fatalf("Expected the budget to be used up, got %d", budget)
+	}
+	c.Advance(time.Hour + time.Minute)
+	if budget := s.notificationBudget(ctx); budget != 1 {
+		t.Errorf("Expected the budget to be restored an hour later, got %d", budget)
+	}
+}
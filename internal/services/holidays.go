@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+// holidayCalendar is a parsed site calendar
+type holidayCalendar struct {
+	servers map[string]bool   // nil for the default calendar
+	dates   map[string]string // "2006-01-02" -> name
+	yearly  map[string]string // "01-02" -> name
+}
+
+// HolidayCalendar knows the public holidays of each site. Analytics leave
+// holidays out of trends, anomaly detection and weekday averages so they do
+// not register as usage collapses. A nil HolidayCalendar has no holidays.
+type HolidayCalendar struct {
+	calendars []holidayCalendar
+}
+
+// NewHolidayCalendar parses the holiday config, or returns nil when no
+// calendars are configured
+func NewHolidayCalendar(cfg config.HolidayConfig) (*HolidayCalendar, error) {
+	if len(cfg.Calendars) == 0 {
+		return nil, nil
+	}
+
+	h := &HolidayCalendar{}
+	for _, c := range cfg.Calendars {
+		cal := holidayCalendar{
+			dates:  make(map[string]string),
+			yearly: make(map[string]string),
+		}
+		if len(c.Servers) > 0 {
+			cal.servers = make(map[string]bool, len(c.Servers))
+			for _, server := range c.Servers {
+				cal.servers[server] = true
+			}
+		}
+
+		for _, entry := range c.Dates {
+			date, name, _ := strings.Cut(strings.TrimSpace(entry), " ")
+			name = strings.TrimSpace(name)
+			if name == "" {
+				name = c.Name
+			}
+
+			if d, err := time.Parse("2006-01-02", date); err == nil {
+				cal.dates[d.Format("2006-01-02")] = name
+			} else if d, err := time.Parse("01-02", date); err == nil {
+				cal.yearly[d.Format("01-02")] = name
+			} else {
+				return nil, fmt.Errorf("invalid holiday %q in calendar %q: use YYYY-MM-DD or MM-DD", entry, c.Name)
+			}
+		}
+		h.calendars = append(h.calendars, cal)
+	}
+
+	return h, nil
+}
+
+// Holiday returns the name of the holiday on a date at a server's site.
+// Calendars listing the server replace the default calendars.
+func (h *HolidayCalendar) Holiday(server string, date time.Time) (string, bool) {
+	if h == nil {
+		return "", false
+	}
+
+	site := false
+	for _, cal := range h.calendars {
+		if cal.servers[server] {
+			site = true
+			break
+		}
+	}
+
+	day, yearly := date.Format("2006-01-02"), date.Format("01-02")
+	for _, cal := range h.calendars {
+		if site && !cal.servers[server] || !site && cal.servers != nil {
+			continue
+		}
+		if name, ok := cal.dates[day]; ok {
+			return name, true
+		}
+		if name, ok := cal.yearly[yearly]; ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// IsHoliday reports whether a date is a holiday at a server's site
+func (h *HolidayCalendar) IsHoliday(server string, date time.Time) bool {
+	_, ok := h.Holiday(server, date)
+	return ok
+}
+
+// withoutHolidays drops usage samples taken on holidays
+func (h *HolidayCalendar) withoutHolidays(server string, usage []models.FeatureUsage) []models.FeatureUsage {
+	if h == nil {
+		return usage
+	}
+	kept := make([]models.FeatureUsage, 0, len(usage))
+	for _, u := range usage {
+		if !h.IsHoliday(server, u.Date) {
+			kept = append(kept, u)
+		}
+	}
+	return kept
+}
+
+// withoutHolidayAggregates drops daily usage aggregates of holidays
+func (h *HolidayCalendar) withoutHolidayAggregates(server string, days []dailyUsage) []dailyUsage {
+	if h == nil {
+		return days
+	}
+	kept := make([]dailyUsage, 0, len(days))
+	for _, d := range days {
+		if !h.IsHoliday(server, d.Date) {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestHolidayCalendar(t *testing.T) {
+	cal, err := NewHolidayCalendar(config.HolidayConfig{Calendars: []config.HolidayCalendar{
+		{Name: "Default", Dates: []string{"01-01 New Year's Day", "12-25"}},
+		{Name: "Germany", Servers: []string{"27000@muc"}, Dates: []string{"10-03 Tag der Deutschen Einheit", "2027-03-26 Karfreitag"}},
+	}})
+	if err != nil {
+		t.Fatalf("NewHolidayCalendar failed: %v", err)
+	}
+
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	for _, tt := range []struct {
+		server, date, want string
+		holiday            bool
+	}{
+		{"27000@nyc", "2026-01-01", "New Year's Day", true},
+		{"27000@nyc", "2031-12-25", "Default", true},
+		{"27000@nyc", "2026-10-03", "", false},
+		{"27000@muc", "2026-10-03", "Tag der Deutschen Einheit", true},
+		{"27000@muc", "2027-03-26", "Karfreitag", true},
+		{"27000@muc", "2026-03-26", "", false},
+		{"27000@muc", "2026-12-25", "", false}, // Site calendars replace the default
+	} {
+		name, ok := cal.Holiday(tt.server, day(tt.date))
+		if ok != tt.holiday || name != tt.want {
+			t.Errorf("Holiday(%s, %s) = %q, %v; want %q, %v", tt.server, tt.date, name, ok, tt.want, tt.holiday)
+		}
+	}
+
+	if _, err := NewHolidayCalendar(config.HolidayConfig{Calendars: []config.HolidayCalendar{
+		{Name: "Bad", Dates: []string{"25.12."}},
+	}}); err == nil {
+		t.Error("Expected an error for an invalid date")
+	}
+
+	none, err := NewHolidayCalendar(config.HolidayConfig{})
+	if err != nil || none != nil || none.IsHoliday("27000@nyc", day("2026-12-25")) {
+		t.Errorf("Expected no calendar without config, got %v (%v)", none, err)
+	}
+}
+
+func TestHolidaysLeftOutOfAnalytics(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	if err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", TotalLicenses: 100},
+	}); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	// Steady weekday usage, light weekends, and a near-empty public holiday
+	holiday := time.Now().AddDate(0, 0, -6)
+	for day := 0; day < 28; day++ {
+		date := time.Now().AddDate(0, 0, -day)
+		users := 50
+		if wd := date.Weekday(); wd == time.Saturday || wd == time.Sunday {
+			users = 10
+		}
+		if day == 6 {
+			users = 1
+		}
+		_, err := db.Exec(`INSERT INTO feature_usage (server_hostname, feature_name, date, time, users_count) VALUES (?, ?, ?, ?, ?)`,
+			"27000@a", "solver", date.Format("2006-01-02"), "12:00:00", users)
+		if err != nil {
+			t.Fatalf("Failed to insert usage: %v", err)
+		}
+	}
+
+	cal, err := NewHolidayCalendar(config.HolidayConfig{Calendars: []config.HolidayCalendar{
+		{Name: "Founders Day", Dates: []string{holiday.Format("2006-01-02"), time.Now().AddDate(0, 0, 3).Format("2006-01-02")}},
+	}})
+	if err != nil {
+		t.Fatalf("NewHolidayCalendar failed: %v", err)
+	}
+	storage.SetHolidays(cal)
+
+	enhanced := NewEnhancedAnalyticsService(db, storage, "sqlite")
+	stats, err := enhanced.GetEnhancedStatistics(ctx, "27000@a", "solver", 30)
+	if err != nil {
+		t.Fatalf("GetEnhancedStatistics failed: %v", err)
+	}
+	if stats.WeekdayAvg != 50 || stats.WeekendAvg != 10 || stats.HolidayAvg != 1 {
+		t.Errorf("Expected weekday 50, weekend 10 and holiday 1, got %v, %v and %v",
+			stats.WeekdayAvg, stats.WeekendAvg, stats.HolidayAvg)
+	}
+
+	analytics := NewAnalyticsService(db, storage, "sqlite")
+	predictions, err := analytics.GetPredictiveAnalytics(ctx, "27000@a", "solver", 30)
+	if err != nil {
+		t.Fatalf("GetPredictiveAnalytics failed: %v", err)
+	}
+	for _, a := range predictions.Anomalies {
+		if a.Date == holiday.Format("2006-01-02") {
+			t.Errorf("Expected the holiday not to be reported as an anomaly, got %+v", a)
+		}
+	}
+	if predictions.Forecast[2].Holiday != "Founders Day" || predictions.Forecast[0].Holiday != "" {
+		t.Errorf("Expected the forecast to name the upcoming holiday, got %+v", predictions.Forecast[:3])
+	}
+
+	// The stored trend matches the live one, both without the holiday
+	live := trendFromUsage(mustUsage(t, storage, "27000@a", "solver"), cal)
+	if _, err := storage.UpdateFeatureTrends(ctx, "27000@a", DefaultTrendDays, time.Now()); err != nil {
+		t.Fatalf("UpdateFeatureTrends failed: %v", err)
+	}
+	stored, err := storage.GetFeatureTrend(ctx, "27000@a", "solver", DefaultTrendDays)
+	if err != nil || stored == nil {
+		t.Fatalf("GetFeatureTrend = %v, %v", stored, err)
+	}
+	if stored.Samples != 27 || live.Samples != 27 || math.Abs(stored.Slope-live.Slope) > 1e-9 {
+		t.Errorf("Expected 27 samples and equal slopes, got stored %+v and live %+v", stored, live)
+	}
+}
+
+func mustUsage(t *testing.T, storage *StorageService, server, feature string) []models.FeatureUsage {
+	t.Helper()
+	usage, err := storage.GetFeatureUsageHistory(context.Background(), server, feature, DefaultTrendDays)
+	if err != nil {
+		t.Fatalf("GetFeatureUsageHistory failed: %v", err)
+	}
+	return usage
+}
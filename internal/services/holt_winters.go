@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// holtWintersSeason is the season of daily usage, a week
+const holtWintersSeason = 7
+
+// predictionZ is the standard normal quantile of 95% prediction intervals
+const predictionZ = 1.96
+
+// holtWinters is additive Holt-Winters triple exponential smoothing fitted to
+// a series: a level, a trend per step and a seasonal offset for each step of
+// the season, each updated with its smoothing factor
+type holtWinters struct {
+	alpha, beta, gamma float64 // Smoothing factors of level, trend and season
+	level, trend       float64
+	season             []float64 // Seasonal offsets, the next step's first
+	sigma              float64   // Standard deviation of the one-step errors
+	fit                float64   // Share of the variance explained by one-step forecasts
+}
+
+// fitHoltWinters fits the smoothing factors minimizing the squared one-step
+// errors over a grid. The series needs two full seasons.
+func fitHoltWinters(y []float64, period int) (*holtWinters, error) {
+	if len(y) < 2*period {
+		return nil, fmt.Errorf("insufficient data for Holt-Winters forecasts (need at least %d days)", 2*period)
+	}
+
+	var best *holtWinters
+	bestSSE := math.Inf(1)
+	for alpha := 0.1; alpha < 0.95; alpha += 0.1 {
+		for beta := 0.0; beta < 0.55; beta += 0.1 {
+			for gamma := 0.1; gamma < 0.95; gamma += 0.1 {
+				hw, sse := runHoltWinters(y, period, alpha, beta, gamma)
+				if sse < bestSSE {
+					best, bestSSE = hw, sse
+				}
+			}
+		}
+	}
+
+	n := float64(len(y) - period)
+	best.sigma = math.Sqrt(bestSSE / n)
+	_, stdDev := calculateStats(y[period:])
+	if stdDev > 0 {
+		best.fit = math.Min(math.Max(1-bestSSE/(n*stdDev*stdDev), 0), 1)
+	}
+	return best, nil
+}
+
+// runHoltWinters smooths the series with the factors and returns the final
+// state and the sum of the squared one-step errors after the first season,
+// which initializes the state
+func runHoltWinters(y []float64, period int, alpha, beta, gamma float64) (*holtWinters, float64) {
+	var first, second float64
+	for i := 0; i < period; i++ {
+		first += y[i]
+		second += y[period+i]
+	}
+	first /= float64(period)
+	second /= float64(period)
+
+	hw := &holtWinters{alpha: alpha, beta: beta, gamma: gamma, level: first, trend: (second - first) / float64(period)}
+	season := make([]float64, period)
+	for i := 0; i < period; i++ {
+		season[i] = y[i] - first
+	}
+
+	var sse float64
+	for t := period; t < len(y); t++ {
+		s := season[t%period]
+		err := y[t] - (hw.level + hw.trend + s)
+		sse += err * err
+
+		level := alpha*(y[t]-s) + (1-alpha)*(hw.level+hw.trend)
+		hw.trend = beta*(level-hw.level) + (1-beta)*hw.trend
+		hw.level = level
+		season[t%period] = gamma*(y[t]-level) + (1-gamma)*s
+	}
+
+	// Rotate the offsets so the step after the series comes first
+	hw.season = make([]float64, period)
+	for i := range hw.season {
+		hw.season[i] = season[(len(y)+i)%period]
+	}
+	return hw, sse
+}
+
+// forecast returns the value h steps after the series and the half-width of
+// its 95% prediction interval
+func (hw *holtWinters) forecast(h int) (value, halfWidth float64) {
+	period := len(hw.season)
+	value = hw.level + float64(h)*hw.trend + hw.season[(h-1)%period]
+
+	// Variance of additive Holt-Winters forecast errors (Hyndman et al.)
+	variance := 1.0
+	for j := 1; j < h; j++ {
+		c := hw.alpha * (1 + float64(j)*hw.beta)
+		if j%period == 0 {
+			c += hw.gamma
+		}
+		variance += c * c
+	}
+	return value, predictionZ * hw.sigma * math.Sqrt(variance)
+}
+
+// dailyUsageSeries returns the average usage of each day since cutoff, oldest
+// first, and the last day. Days without usage and holidays take the usage of
+// the day a week before, or of the day before in the first week.
+func (s *StorageService) dailyUsageSeries(ctx context.Context, hostname, feature string, cutoff time.Time) ([]float64, time.Time, error) {
+	watermark, err := s.rollupWatermark(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	usage, args := s.usageAggregates(watermark, false, cutoff, hostname, feature)
+	var rows []struct {
+		Date     interface{} `db:"date"`
+		Samples  float64     `db:"samples"`
+		SumUsers float64     `db:"sum_users"`
+	}
+	query := `SELECT date, samples, sum_users FROM (` + usage + `) u ORDER BY date`
+	if err := s.db.SelectContext(ctx, &rows, s.db.Rebind(query), args...); err != nil {
+		return nil, time.Time{}, err
+	}
+	if len(rows) == 0 {
+		return nil, time.Time{}, nil
+	}
+
+	byDay := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		date := scannedDate(row.Date)
+		if row.Samples > 0 && !s.holidays.IsHoliday(hostname, date) {
+			byDay[date.Format("2006-01-02")] = row.SumUsers / row.Samples
+		}
+	}
+	first, last := calendarDay(scannedDate(rows[0].Date)), calendarDay(scannedDate(rows[len(rows)-1].Date))
+
+	var series []float64
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		v, ok := byDay[d.Format("2006-01-02")]
+		switch {
+		case ok:
+		case len(series) >= holtWintersSeason:
+			v = series[len(series)-holtWintersSeason]
+		case len(series) > 0:
+			v = series[len(series)-1]
+		}
+		series = append(series, v)
+	}
+	return series, last, nil
+}
+
+// calendarDay returns the date of t at midnight UTC, so days can be counted
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
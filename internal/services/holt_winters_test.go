@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"licet/internal/clock"
+	"licet/internal/models"
+)
+
+// weeklyUsage is busy on weekdays and quiet on weekends, growing slowly
+func weeklyUsage(day int) float64 {
+	base := 40 + 0.2*float64(day)
+	if day%7 >= 5 {
+		return base - 30
+	}
+	return base
+}
+
+func TestFitHoltWinters(t *testing.T) {
+	var series []float64
+	for day := 0; day < 35; day++ {
+		series = append(series, weeklyUsage(day))
+	}
+	hw, err := fitHoltWinters(series, holtWintersSeason)
+	if err != nil {
+		t.Fatalf("fitHoltWinters failed: %v", err)
+	}
+	if hw.fit < 0.9 {
+		t.Errorf("Expected a close fit of a seasonal series, got %.2f", hw.fit)
+	}
+	for h := 1; h <= 14; h++ {
+		want := weeklyUsage(len(series) - 1 + h)
+		got, halfWidth := hw.forecast(h)
+		if math.Abs(got-want) > 2 {
+			t.Errorf("Forecast %d steps ahead: expected about %.1f, got %.1f", h, want, got)
+		}
+		if prev := h - 1; prev > 0 {
+			if _, prevHalfWidth := hw.forecast(prev); halfWidth < prevHalfWidth {
+				t.Errorf("Expected the interval to widen, got %.2f after %.2f", halfWidth, prevHalfWidth)
+			}
+		}
+	}
+
+	if _, err := fitHoltWinters(series[:13], holtWintersSeason); err == nil {
+		t.Error("Expected an error with less than two seasons")
+	}
+}
+
+func TestPredictiveAnalyticsHoltWinters(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+	now := time.Date(2025, 3, 30, 12, 0, 0, 0, time.Local) // A Sunday
+	storage.SetClock(clock.NewFixed(now))
+	analytics := NewAnalyticsService(db, storage, "sqlite")
+	analytics.SetClock(clock.NewFixed(now))
+
+	if err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", TotalLicenses: 100},
+	}); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	// Day 0 is a Monday four weeks ago
+	start := now.AddDate(0, 0, -27)
+	for day := 0; day < 28; day++ {
+		_, err := db.Exec(`INSERT INTO feature_usage (server_hostname, feature_name, date, time, users_count) VALUES (?, ?, ?, ?, ?)`,
+			"27000@a", "solver", start.AddDate(0, 0, day).Format("2006-01-02"), "12:00:00", int(weeklyUsage(day)))
+		if err != nil {
+			t.Fatalf("Failed to insert usage: %v", err)
+		}
+	}
+
+	result, err := analytics.GetPredictiveAnalyticsWithModel(ctx, "27000@a", "solver", 30, ForecastHoltWinters)
+	if err != nil {
+		t.Fatalf("GetPredictiveAnalyticsWithModel failed: %v", err)
+	}
+	if result.Model != ForecastHoltWinters || len(result.Forecast) != 30 {
+		t.Fatalf("Expected 30 Holt-Winters forecast days, got %+v", result)
+	}
+	for _, p := range result.Forecast {
+		if p.LowerBound > p.PredictedUsage || p.UpperBound < p.PredictedUsage {
+			t.Errorf("Expected %s within [%.1f, %.1f], got %.1f", p.Date, p.LowerBound, p.UpperBound, p.PredictedUsage)
+		}
+	}
+	// Monday and Saturday after now
+	if monday, saturday := result.Forecast[0], result.Forecast[5]; monday.PredictedUsage-saturday.PredictedUsage < 20 {
+		t.Errorf("Expected the weekly cycle in the forecast, got Monday %.1f and Saturday %.1f",
+			monday.PredictedUsage, saturday.PredictedUsage)
+	}
+
+	linear, err := analytics.GetPredictiveAnalytics(ctx, "27000@a", "solver", 30)
+	if err != nil {
+		t.Fatalf("GetPredictiveAnalytics failed: %v", err)
+	}
+	if linear.Model != ForecastLinear || linear.Forecast[0].UpperBound <= linear.Forecast[0].LowerBound {
+		t.Errorf("Expected a linear forecast with an interval, got %+v", linear.Forecast[0])
+	}
+
+	if _, err := analytics.GetPredictiveAnalyticsWithModel(ctx, "27000@a", "solver", 30, "arima"); !errors.Is(err, ErrUnknownForecastModel) {
+		t.Errorf("Expected ErrUnknownForecastModel, got %v", err)
+	}
+}
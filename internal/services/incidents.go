@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	mail "github.com/wneessen/go-mail"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"licet/internal/models"
+)
+
+// ErrIncidentNotFound is returned when an incident does not exist
+var ErrIncidentNotFound = errors.New("incident not found")
+
+// severityRank orders alert severities for incident escalation
+var severityRank = map[string]int{"info": 0, "warning": 1, "critical": 2}
+
+// incidentWindow returns the alert correlation window, or 0 when disabled
+func (s *AlertService) incidentWindow() time.Duration {
+	return time.Duration(s.cfg.Alerts.IncidentWindowMin) * time.Minute
+}
+
+// correlate attaches an alert to the open incident for its server, or opens
+// a new incident. It returns nil when correlation is disabled.
+func (s *AlertService) correlate(ctx context.Context, tx *sqlx.Tx, alert *models.Alert, at time.Time) (*int64, error) {
+	window := s.incidentWindow()
+	if window <= 0 {
+		return nil, nil
+	}
+
+	var incident models.Incident
+	err := tx.GetContext(ctx, &incident, tx.Rebind(`
+		SELECT * FROM incidents
+		WHERE server_hostname = ? AND last_alert_at >= ?
+		ORDER BY last_alert_at DESC
+		LIMIT 1
+	`), alert.ServerHostname, at.Add(-window))
+
+	switch {
+	case err == nil:
+		severity := incident.Severity
+		if severityRank[alert.Severity] > severityRank[severity] {
+			severity = alert.Severity
+		}
+		_, err = tx.ExecContext(ctx, tx.Rebind(`
+			UPDATE incidents SET last_alert_at = ?, alert_count = alert_count + 1, severity = ?
+			WHERE id = ?
+		`), at, severity, incident.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update incident: %w", err)
+		}
+		return &incident.ID, nil
+
+	case errors.Is(err, sql.ErrNoRows):
+		_, err = tx.ExecContext(ctx, tx.Rebind(`
+			INSERT INTO incidents (server_hostname, root_cause, severity, alert_count, opened_at, last_alert_at)
+			VALUES (?, ?, ?, 1, ?, ?)
+		`), alert.ServerHostname, alert.AlertType, alert.Severity, at, at)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open incident: %w", err)
+		}
+		var id int64
+		err = tx.GetContext(ctx, &id, tx.Rebind(`
+			SELECT id FROM incidents WHERE server_hostname = ? AND opened_at = ?
+			ORDER BY id DESC LIMIT 1
+		`), alert.ServerHostname, at)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open incident: %w", err)
+		}
+		return &id, nil
+
+	default:
+		return nil, fmt.Errorf("failed to find incident: %w", err)
+	}
+}
+
+// withStatus marks incidents as open while they are inside the correlation window
+func (s *AlertService) withStatus(incidents []models.Incident) []models.Incident {
+	cutoff := s.clock.Now().Add(-s.incidentWindow())
+	for i := range incidents {
+		incidents[i].Status = "resolved"
+		if incidents[i].LastAlertAt.After(cutoff) {
+			incidents[i].Status = "open"
+		}
+	}
+	return incidents
+}
+
+// GetIncidents returns incidents with alerts in the last `days` days, newest first
+func (s *AlertService) GetIncidents(ctx context.Context, days int) ([]models.Incident, error) {
+	incidents := []models.Incident{}
+	query := `SELECT * FROM incidents WHERE last_alert_at >= ? ORDER BY last_alert_at DESC`
+	err := s.db.SelectContext(ctx, &incidents, s.db.Rebind(query), s.clock.Now().AddDate(0, 0, -days))
+	return s.withStatus(incidents), err
+}
+
+// GetIncident returns an incident with its alert timeline
+func (s *AlertService) GetIncident(ctx context.Context, id int64) (*models.Incident, error) {
+	var incident models.Incident
+	err := s.db.GetContext(ctx, &incident, s.db.Rebind(`SELECT * FROM incidents WHERE id = ?`), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIncidentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := []models.Alert{}
+	query := `SELECT * FROM alerts WHERE incident_id = ? ORDER BY created_at ASC, id ASC`
+	if err := s.db.SelectContext(ctx, &alerts, s.db.Rebind(query), id); err != nil {
+		return nil, err
+	}
+	incident.Alerts = s.withLinks(alerts)
+
+	return &s.withStatus([]models.Incident{incident})[0], nil
+}
+
+// sendIncident sends one notification for the new alerts of an incident.
+// The first notification starts a mail thread that later ones reply to.
+func (s *AlertService) sendIncident(ctx context.Context, incidentID int64, alerts []models.Alert) error {
+	incident, err := s.GetIncident(ctx, incidentID)
+	if err != nil {
+		return err
+	}
+
+	recipients := s.cfg.Email.To
+	if incident.Severity == "critical" {
+		recipients = append(recipients, s.cfg.Email.Alerts...)
+	}
+
+	subject := fmt.Sprintf("License Incident #%d: %s (%s)", incident.ID, incident.ServerHostname, incident.RootCause)
+
+	domain := s.messageDomain()
+	threadID := fmt.Sprintf("<licet-incident-%d@%s>", incident.ID, domain)
+	headers := map[mail.Header]string{mail.HeaderMessageID: threadID}
+	if incident.Notified {
+		subject = "Re: " + subject
+		headers = map[mail.Header]string{
+			mail.HeaderMessageID:  fmt.Sprintf("<licet-incident-%d-%d@%s>", incident.ID, time.Now().UnixNano(), domain),
+			mail.HeaderInReplyTo:  threadID,
+			mail.HeaderReferences: threadID,
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\nLicense Incident #%d (%s)\n\n", incident.ID, incident.Status)
+	fmt.Fprintf(&b, "Server: %s\nRoot cause: %s\nSeverity: %s\nOpened: %s\nAlerts: %d\n\n",
+		incident.ServerHostname, incident.RootCause, incident.Severity,
+		incident.OpenedAt.Format(time.RFC3339), incident.AlertCount)
+	fmt.Fprintf(&b, "New alerts:\n")
+	for _, alert := range alerts {
+		fmt.Fprintf(&b, "  %s [%s] %s: %s\n",
+			alert.CreatedAt.Format("15:04:05"), alert.Severity, alert.AlertType, alert.Message)
+	}
+
+	links, err := s.AlertLinks(&models.Alert{
+		ServerHostname: incident.ServerHostname,
+		AlertType:      incident.RootCause,
+		Severity:       incident.Severity,
+	})
+	if err != nil {
+		log.Warnf("Incident %d: %v", incident.ID, err)
+	}
+	b.WriteString(formatAlertLinks(links))
+	fmt.Fprintf(&b, "\n--\n%s\n", s.cfg.Branding.Name())
+
+	if err := s.sendAlertEmail(ctx, alerts, subject, b.String(), recipients, headers); err != nil {
+		return err
+	}
+
+	if !incident.Notified {
+		query := `UPDATE incidents SET notified = ? WHERE id = ?`
+		if _, err := s.db.ExecContext(ctx, s.db.Rebind(query), true, incident.ID); err != nil {
+			log.Errorf("Failed to mark incident %d as notified: %v", incident.ID, err)
+		}
+	}
+
+	log.Infof("Incident notification sent: #%d - %s (%d alerts)", incident.ID, incident.ServerHostname, len(alerts))
+	return nil
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestCreateAlert_CorrelatesIncidents(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	s := NewAlertService(db, &config.Config{Alerts: config.AlertConfig{IncidentWindowMin: 15}})
+
+	alerts := []*models.Alert{
+		{ServerHostname: "27000@a", AlertType: "down", Message: "Server down", Severity: "warning"},
+		{ServerHostname: "27000@a", FeatureName: "solver", AlertType: "unavailable", Message: "solver unavailable", Severity: "critical"},
+		{ServerHostname: "27000@a", FeatureName: "viewer", AlertType: "unavailable", Message: "viewer unavailable", Severity: "info"},
+		{ServerHostname: "27000@b", AlertType: "failover", Message: "Failover", Severity: "warning"},
+	}
+	for _, a := range alerts {
+		if err := s.CreateAlert(ctx, a); err != nil {
+			t.Fatalf("CreateAlert failed: %v", err)
+		}
+		if a.IncidentID == nil {
+			t.Fatal("Expected alert to be assigned to an incident")
+		}
+	}
+
+	if *alerts[0].IncidentID != *alerts[1].IncidentID || *alerts[0].IncidentID != *alerts[2].IncidentID {
+		t.Error("Expected alerts for the same server to share an incident")
+	}
+	if *alerts[0].IncidentID == *alerts[3].IncidentID {
+		t.Error("Expected alerts for another server to open a separate incident")
+	}
+
+	incident, err := s.GetIncident(ctx, *alerts[0].IncidentID)
+	if err != nil {
+		t.Fatalf("GetIncident failed: %v", err)
+	}
+	if incident.RootCause != "down" || incident.Severity != "critical" || incident.AlertCount != 3 || incident.Status != "open" {
+		t.Errorf("Unexpected incident: %+v", incident)
+	}
+	if len(incident.Alerts) != 3 || incident.Alerts[0].AlertType != "down" {
+		t.Errorf("Expected a 3 alert timeline starting with the root cause, got %+v", incident.Alerts)
+	}
+
+	// Once the window has passed, a new alert opens a new incident
+	db.Exec(`UPDATE incidents SET last_alert_at = ?`, time.Now().Add(-time.Hour))
+	next := &models.Alert{ServerHostname: "27000@a", AlertType: "down", Message: "Server down", Severity: "warning"}
+	if err := s.CreateAlert(ctx, next); err != nil {
+		t.Fatalf("CreateAlert failed: %v", err)
+	}
+	if *next.IncidentID == *alerts[0].IncidentID {
+		t.Error("Expected a new incident after the correlation window")
+	}
+
+	incidents, err := s.GetIncidents(ctx, 30)
+	if err != nil {
+		t.Fatalf("GetIncidents failed: %v", err)
+	}
+	if len(incidents) != 3 {
+		t.Fatalf("Expected 3 incidents, got %d", len(incidents))
+	}
+	if incidents[0].ID != *next.IncidentID || incidents[0].Status != "open" || incidents[2].Status != "resolved" {
+		t.Errorf("Unexpected incident order or status: %+v", incidents)
+	}
+
+	if _, err := s.GetIncident(ctx, 999); !errors.Is(err, ErrIncidentNotFound) {
+		t.Errorf("Expected ErrIncidentNotFound, got %v", err)
+	}
+}
+
+func TestCreateAlert_CorrelationDisabled(t *testing.T) {
+	db := newTestDB(t)
+	s := NewAlertService(db, &config.Config{})
+
+	alert := &models.Alert{ServerHostname: "27000@a", AlertType: "down", Message: "Server down", Severity: "warning"}
+	if err := s.CreateAlert(context.Background(), alert); err != nil {
+		t.Fatalf("CreateAlert failed: %v", err)
+	}
+	if alert.IncidentID != nil {
+		t.Errorf("Expected no incident when correlation is disabled, got %d", *alert.IncidentID)
+	}
+}
@@ -0,0 +1,527 @@
+//go:build integration
+
+package services
+
+// Integration tests running the migrations and the service queries against
+// real PostgreSQL, TimescaleDB and MySQL servers started in Docker, so SQL
+// that only SQLite accepts is caught before it reaches an installation.
+//
+//	go test -tags integration ./internal/services/
+//
+// Tests are skipped when Docker is not available.
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"licet/internal/config"
+	"licet/internal/database"
+	"licet/internal/models"
+)
+
+// integrationDatabase is a database server started for the integration tests
+type integrationDatabase struct {
+	dbType   string
+	db       *sqlx.DB
+	resource *dockertest.Resource
+}
+
+// integrationServer describes how to start a database server in Docker
+type integrationServer struct {
+	dbType     string
+	repository string
+	tag        string
+	port       string
+	env        []string
+}
+
+var integrationServers = []integrationServer{
+	{
+		dbType: "postgres", repository: "postgres", tag: "16-alpine", port: "5432/tcp",
+		env: []string{"POSTGRES_USER=licet", "POSTGRES_PASSWORD=licet", "POSTGRES_DB=licet"},
+	},
+	{
+		dbType: "timescaledb", repository: "timescale/timescaledb", tag: "latest-pg16", port: "5432/tcp",
+		env: []string{"POSTGRES_USER=licet", "POSTGRES_PASSWORD=licet", "POSTGRES_DB=licet"},
+	},
+	{
+		dbType: "mysql", repository: "mysql", tag: "8.0", port: "3306/tcp",
+		env: []string{"MYSQL_USER=licet", "MYSQL_PASSWORD=licet", "MYSQL_DATABASE=licet", "MYSQL_RANDOM_ROOT_PASSWORD=yes"},
+	},
+}
+
+var (
+	integrationDatabases []integrationDatabase
+	integrationSkip      string
+)
+
+func TestMain(m *testing.M) {
+	pool, err := dockertest.NewPool("")
+	if err == nil {
+		err = pool.Client.Ping()
+	}
+	if err != nil {
+		integrationSkip = fmt.Sprintf("Docker is not available: %v", err)
+		os.Exit(m.Run())
+	}
+	pool.MaxWait = 3 * time.Minute
+
+	code := 1
+	defer func() {
+		for _, d := range integrationDatabases {
+			d.db.Close()
+			pool.Purge(d.resource)
+		}
+		os.Exit(code)
+	}()
+
+	for _, server := range integrationServers {
+		d, err := startIntegrationDatabase(pool, server)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start %s: %v\n", server.dbType, err)
+			return
+		}
+		integrationDatabases = append(integrationDatabases, *d)
+	}
+
+	code = m.Run()
+}
+
+// startIntegrationDatabase starts a database server, waits until it accepts
+// connections and migrates it
+func startIntegrationDatabase(pool *dockertest.Pool, server integrationServer) (*integrationDatabase, error) {
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: server.repository,
+		Tag:        server.tag,
+		Env:        server.env,
+	}, func(hc *docker.HostConfig) {
+		hc.AutoRemove = true
+		hc.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start container: %w", err)
+	}
+	resource.Expire(600)
+
+	port, err := strconv.Atoi(resource.GetPort(server.port))
+	if err != nil {
+		pool.Purge(resource)
+		return nil, fmt.Errorf("failed to get port: %w", err)
+	}
+	cfg := config.DatabaseConfig{
+		Type:     server.dbType,
+		Host:     "localhost",
+		Port:     port,
+		Database: "licet",
+		Username: "licet",
+		Password: "licet",
+		SSLMode:  "disable",
+	}
+
+	var db *sqlx.DB
+	if err := pool.Retry(func() error {
+		var err error
+		db, err = database.New(cfg)
+		return err
+	}); err != nil {
+		pool.Purge(resource)
+		return nil, fmt.Errorf("database did not become ready: %w", err)
+	}
+
+	if err := database.RunMigrations(db, server.dbType); err != nil {
+		db.Close()
+		pool.Purge(resource)
+		return nil, err
+	}
+	return &integrationDatabase{dbType: server.dbType, db: db, resource: resource}, nil
+}
+
+// forEachDatabase runs a test against every database server
+func forEachDatabase(t *testing.T, test func(t *testing.T, db *sqlx.DB, dbType string)) {
+	t.Helper()
+	if integrationSkip != "" {
+		t.Skip(integrationSkip)
+	}
+	for _, d := range integrationDatabases {
+		t.Run(d.dbType, func(t *testing.T) {
+			test(t, d.db, d.dbType)
+		})
+	}
+}
+
+// insertUsageHistory stores one usage sample per hour over the last days
+func insertUsageHistory(t *testing.T, db *sqlx.DB, dbType, hostname, feature string, days int) {
+	t.Helper()
+	insert := database.NewDialect(dbType).InsertIgnoreUsage()
+	now := time.Now()
+	for d := days; d >= 0; d-- {
+		date := now.AddDate(0, 0, -d).Format("2006-01-02")
+		for hour := 8; hour < 18; hour++ {
+			if _, err := db.Exec(insert, hostname, feature, date, fmt.Sprintf("%02d:00:00", hour), days-d+hour%3, 0); err != nil {
+				t.Fatalf("Failed to insert usage: %v", err)
+			}
+		}
+	}
+}
+
+func TestIntegration_MigrationsAreIdempotent(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *sqlx.DB, dbType string) {
+		if err := database.RunMigrations(db, dbType); err != nil {
+			t.Fatalf("Running migrations again failed: %v", err)
+		}
+	})
+}
+
+func TestIntegration_Storage(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *sqlx.DB, dbType string) {
+		ctx := context.Background()
+		storage := NewStorageService(db, dbType)
+		host := "27000@storage"
+		expires := time.Now().AddDate(1, 0, 0).Truncate(time.Second)
+
+		features := []models.Feature{
+			{ServerHostname: host, Name: "feat1", Version: "1.0", VendorDaemon: "vd", TotalLicenses: 10, UsedLicenses: 4, ExpirationDate: expires},
+			{ServerHostname: host, Name: "feat2", Version: "2.0", VendorDaemon: "vd", TotalLicenses: 5, UsedLicenses: 5, ExpirationDate: expires},
+		}
+		if err := storage.StoreFeatures(ctx, features); err != nil {
+			t.Fatalf("StoreFeatures failed: %v", err)
+		}
+		first, err := storage.GetFeatures(ctx, host)
+		if err != nil {
+			t.Fatalf("GetFeatures failed: %v", err)
+		}
+		if len(first) != 2 {
+			t.Fatalf("Expected 2 features, got %d", len(first))
+		}
+
+		features[0].UsedLicenses = 7
+		if err := storage.StoreFeatures(ctx, features); err != nil {
+			t.Fatalf("StoreFeatures (update) failed: %v", err)
+		}
+		second, err := storage.GetFeatures(ctx, host)
+		if err != nil {
+			t.Fatalf("GetFeatures failed: %v", err)
+		}
+		if len(second) != 2 {
+			t.Fatalf("Expected the upsert to update in place, got %d features", len(second))
+		}
+		for _, f := range second {
+			if f.Name == "feat1" && f.UsedLicenses != 7 {
+				t.Errorf("Expected feat1 used licenses 7, got %d", f.UsedLicenses)
+			}
+		}
+
+		if err := storage.RecordUsage(ctx, features); err != nil {
+			t.Fatalf("RecordUsage failed: %v", err)
+		}
+		if err := storage.RecordUsage(ctx, features); err != nil {
+			t.Fatalf("RecordUsage (duplicate) failed: %v", err)
+		}
+		history, err := storage.GetFeatureUsageHistory(ctx, host, "feat1", 1)
+		if err != nil {
+			t.Fatalf("GetFeatureUsageHistory failed: %v", err)
+		}
+		if len(history) != 1 {
+			t.Errorf("Expected 1 usage sample, got %d", len(history))
+		}
+
+		now := time.Now()
+		users := []models.LicenseUser{{ServerHostname: host, FeatureName: "feat1", Username: "alice"}}
+		if err := storage.RecordUsers(ctx, users, now); err != nil {
+			t.Fatalf("RecordUsers failed: %v", err)
+		}
+		if err := storage.RecordUsers(ctx, users, now.Add(time.Minute)); err != nil {
+			t.Fatalf("RecordUsers (update) failed: %v", err)
+		}
+		seen, err := storage.GetFirstSeenUsers(ctx, now.Add(-time.Hour), now.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("GetFirstSeenUsers failed: %v", err)
+		}
+		if len(seen) != 1 {
+			t.Errorf("Expected 1 first-seen user, got %d", len(seen))
+		}
+
+		timestamps, err := storage.GetCollectionTimestamps(ctx, host)
+		if err != nil {
+			t.Fatalf("GetCollectionTimestamps failed: %v", err)
+		}
+		if timestamps[host].IsZero() {
+			t.Errorf("Expected a collection timestamp for %s", host)
+		}
+	})
+}
+
+func TestIntegration_Analytics(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *sqlx.DB, dbType string) {
+		ctx := context.Background()
+		storage := NewStorageService(db, dbType)
+		analytics := NewAnalyticsService(db, storage, dbType)
+		host := "27000@analytics"
+
+		if err := storage.StoreFeatures(ctx, []models.Feature{
+			{ServerHostname: host, Name: "solver", Version: "1.0", VendorDaemon: "vd", TotalLicenses: 40, UsedLicenses: 12, ExpirationDate: time.Now().AddDate(1, 0, 0)},
+		}); err != nil {
+			t.Fatalf("StoreFeatures failed: %v", err)
+		}
+		insertUsageHistory(t, db, dbType, host, "solver", 30)
+
+		utilization, err := analytics.GetCurrentUtilization(ctx, host)
+		if err != nil {
+			t.Fatalf("GetCurrentUtilization failed: %v", err)
+		}
+		if len(utilization) != 1 || utilization[0].UtilizationPct != 30 {
+			t.Errorf("Expected 30%% utilization of one feature, got %+v", utilization)
+		}
+
+		for _, days := range []int{1, 30} {
+			history, err := analytics.GetUtilizationHistory(ctx, host, "solver", days)
+			if err != nil {
+				t.Fatalf("GetUtilizationHistory(%d) failed: %v", days, err)
+			}
+			if len(history) == 0 {
+				t.Errorf("Expected utilization history over %d days", days)
+			}
+		}
+
+		stats, err := analytics.GetUtilizationStats(ctx, host, 30)
+		if err != nil {
+			t.Fatalf("GetUtilizationStats failed: %v", err)
+		}
+		if len(stats) != 1 || stats[0].TotalLicenses != 40 {
+			t.Errorf("Expected stats of one feature with 40 licenses, got %+v", stats)
+		}
+
+		heatmap, err := analytics.GetHeatmapData(ctx, host, 30)
+		if err != nil {
+			t.Fatalf("GetHeatmapData failed: %v", err)
+		}
+		if len(heatmap) != 1 || heatmap[0].HourlyData[9].PeakUsage == 0 {
+			t.Errorf("Expected hourly usage of one feature, got %+v", heatmap)
+		}
+
+		stored, err := storage.UpdateFeatureTrends(ctx, host, 30, time.Now())
+		if err != nil {
+			t.Fatalf("UpdateFeatureTrends failed: %v", err)
+		}
+		if stored != 1 {
+			t.Errorf("Expected 1 trend stored, got %d", stored)
+		}
+		trend, err := storage.GetFeatureTrend(ctx, host, "solver", 30)
+		if err != nil {
+			t.Fatalf("GetFeatureTrend failed: %v", err)
+		}
+		if trend == nil || trend.Slope <= 0 {
+			t.Errorf("Expected a rising trend, got %+v", trend)
+		}
+
+		if _, err := analytics.GetPredictiveAnalytics(ctx, host, "solver", 30); err != nil {
+			t.Errorf("GetPredictiveAnalytics failed: %v", err)
+		}
+	})
+}
+
+func TestIntegration_Rollups(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *sqlx.DB, dbType string) {
+		ctx := context.Background()
+		storage := NewStorageService(db, dbType)
+		analytics := NewAnalyticsService(db, storage, dbType)
+		host := "27000@rollups"
+
+		if err := storage.StoreFeatures(ctx, []models.Feature{
+			{ServerHostname: host, Name: "solver", Version: "1.0", VendorDaemon: "vd", TotalLicenses: 40, ExpirationDate: time.Now().AddDate(1, 0, 0)},
+		}); err != nil {
+			t.Fatalf("StoreFeatures failed: %v", err)
+		}
+		insertUsageHistory(t, db, dbType, host, "solver", 30)
+
+		stats, err := analytics.GetUtilizationStats(ctx, host, 30)
+		if err != nil {
+			t.Fatalf("GetUtilizationStats failed: %v", err)
+		}
+
+		if _, err := storage.RollupUsage(ctx, 2, time.Now()); err != nil {
+			t.Fatalf("RollupUsage failed: %v", err)
+		}
+		rolled, err := analytics.GetUtilizationStats(ctx, host, 30)
+		if err != nil {
+			t.Fatalf("GetUtilizationStats (rolled up) failed: %v", err)
+		}
+		if len(stats) != 1 || len(rolled) != 1 || stats[0].PeakUsage != rolled[0].PeakUsage {
+			t.Errorf("Expected the same stats from rollups, got %+v and %+v", stats, rolled)
+		}
+
+		if _, err := analytics.GetHeatmapData(ctx, host, 30); err != nil {
+			t.Errorf("GetHeatmapData (rolled up) failed: %v", err)
+		}
+		if _, err := analytics.GetUtilizationHistory(ctx, host, "solver", 30); err != nil {
+			t.Errorf("GetUtilizationHistory (rolled up) failed: %v", err)
+		}
+		if _, err := storage.UpdateFeatureTrends(ctx, host, 30, time.Now()); err != nil {
+			t.Errorf("UpdateFeatureTrends (rolled up) failed: %v", err)
+		}
+
+		days, trends, err := storage.Backfill(ctx, time.Now().AddDate(0, 0, -30), time.Now(), []string{host})
+		if err != nil {
+			t.Fatalf("Backfill failed: %v", err)
+		}
+		if days == 0 || trends != 1 {
+			t.Errorf("Expected rolled-up days and 1 trend backfilled, got %d and %d", days, trends)
+		}
+		backfilled, err := analytics.GetUtilizationStats(ctx, host, 30)
+		if err != nil {
+			t.Fatalf("GetUtilizationStats (backfilled) failed: %v", err)
+		}
+		if len(backfilled) != 1 || backfilled[0].PeakUsage != stats[0].PeakUsage {
+			t.Errorf("Expected the same stats after backfill, got %+v and %+v", stats, backfilled)
+		}
+	})
+}
+
+func TestIntegration_Events(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *sqlx.DB, dbType string) {
+		ctx := context.Background()
+		events := NewEventService(db, dbType)
+		now := time.Now().Truncate(time.Second)
+
+		denials := []models.LicenseEvent{
+			{ServerHostname: "27000@events", Date: now, Time: now, EventType: "DENIED", FeatureName: "solver", Username: "bob", Reason: "Licensed number of users already reached"},
+			{ServerHostname: "27000@events", Date: now, Time: now.Add(-time.Minute), EventType: "DENIED", FeatureName: "solver", Username: "carol", Reason: "Licensed number of users already reached"},
+		}
+		stored, err := events.RecordEvents(ctx, denials)
+		if err != nil {
+			t.Fatalf("RecordEvents failed: %v", err)
+		}
+		if stored != 2 {
+			t.Errorf("Expected 2 events stored, got %d", stored)
+		}
+		stored, err = events.RecordEvents(ctx, denials)
+		if err != nil {
+			t.Fatalf("RecordEvents (duplicate) failed: %v", err)
+		}
+		if stored != 0 {
+			t.Errorf("Expected duplicate events to be ignored, got %d stored", stored)
+		}
+
+		summary, err := events.GetDenials(ctx, "27000@events", "solver", 1)
+		if err != nil {
+			t.Fatalf("GetDenials failed: %v", err)
+		}
+		if summary.Total != 2 {
+			t.Errorf("Expected 2 denials, got %d", summary.Total)
+		}
+	})
+}
+
+func TestIntegration_Alerts(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *sqlx.DB, dbType string) {
+		ctx := context.Background()
+		alerts := NewAlertService(db, &config.Config{})
+
+		if err := alerts.CreateAlert(ctx, &models.Alert{
+			ServerHostname: "27000@alerts",
+			AlertType:      "server_down",
+			Message:        "Server is down",
+			Severity:       "critical",
+		}); err != nil {
+			t.Fatalf("CreateAlert failed: %v", err)
+		}
+
+		unsent, err := alerts.GetUnsentAlerts(ctx)
+		if err != nil {
+			t.Fatalf("GetUnsentAlerts failed: %v", err)
+		}
+		if len(unsent) != 1 {
+			t.Fatalf("Expected 1 unsent alert, got %d", len(unsent))
+		}
+		if err := alerts.MarkAlertSent(ctx, unsent[0].ID); err != nil {
+			t.Fatalf("MarkAlertSent failed: %v", err)
+		}
+		if unsent, err = alerts.GetUnsentAlerts(ctx); err != nil || len(unsent) != 0 {
+			t.Errorf("Expected no unsent alerts, got %d (%v)", len(unsent), err)
+		}
+
+		active, err := alerts.GetActiveAlerts(ctx)
+		if err != nil {
+			t.Fatalf("GetActiveAlerts failed: %v", err)
+		}
+		if len(active) != 1 {
+			t.Errorf("Expected 1 active alert, got %d", len(active))
+		}
+	})
+}
+
+func TestIntegration_Audit(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *sqlx.DB, dbType string) {
+		ctx := context.Background()
+		audit := NewAuditService(db)
+
+		if err := audit.Record(ctx, models.AuditEntry{
+			Actor:      "admin",
+			Action:     models.AuditActionRequest,
+			Method:     "POST",
+			Path:       "/api/v1/alerts/rules",
+			Status:     201,
+			DurationMS: 12,
+		}); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+
+		entries, total, err := audit.List(ctx, AuditFilter{Actor: "admin", Method: "POST", Since: time.Now().Add(-time.Hour)}, 10, 0)
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if total != 1 || len(entries) != 1 || entries[0].Path != "/api/v1/alerts/rules" {
+			t.Errorf("Expected the recorded entry, got %d of %d: %+v", len(entries), total, entries)
+		}
+	})
+}
+
+func TestIntegration_CleanupOldData(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *sqlx.DB, dbType string) {
+		ctx := context.Background()
+		dbStats := NewDBStatsService(db, config.DatabaseConfig{Type: dbType})
+
+		insertUsageHistory(t, db, dbType, "27000@cleanup", "old", 400)
+		result, err := dbStats.CleanupOldData(ctx, "feature_usage", 365)
+		if err != nil {
+			t.Fatalf("CleanupOldData failed: %v", err)
+		}
+		if result.RowsDeleted == 0 {
+			t.Error("Expected usage older than a year to be deleted")
+		}
+
+		for _, table := range []string{"license_events", "alerts", "alert_events", "webhook_deliveries", "audit_log"} {
+			if _, err := dbStats.CleanupOldData(ctx, table, 365); err != nil {
+				t.Errorf("CleanupOldData(%s) failed: %v", table, err)
+			}
+		}
+
+		if _, err := dbStats.GetDatabaseStats(ctx); err != nil {
+			t.Errorf("GetDatabaseStats failed: %v", err)
+		}
+
+		policy := map[string]int{}
+		for _, table := range config.RetentionTables {
+			policy[table] = 365
+		}
+		for _, dryRun := range []bool{true, false} {
+			run, err := dbStats.ApplyRetention(ctx, policy, dryRun, "test")
+			if err != nil {
+				t.Fatalf("ApplyRetention failed: %v", err)
+			}
+			if run.Error != "" {
+				t.Errorf("ApplyRetention (dry run %v) failed: %+v", dryRun, run.Results)
+			}
+		}
+		if runs, err := dbStats.GetRetentionRuns(ctx, 2); err != nil || len(runs) != 2 {
+			t.Errorf("Expected 2 retention runs, got %d (%v)", len(runs), err)
+		}
+	})
+}
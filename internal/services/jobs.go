@@ -0,0 +1,572 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"licet/internal/clock"
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+// Types of the jobs run by the job queue besides server refreshes
+const (
+	JobBackfill        = "backfill"
+	JobCapacityReports = "capacity_reports"
+	JobExport          = "export"
+	JobArchive         = "archive"
+)
+
+// jobPollInterval is how often idle workers look for jobs that became due,
+// such as retries; new jobs wake them at once
+const jobPollInterval = 5 * time.Second
+
+// Running jobs hold a lease their instance renews every jobHeartbeatInterval.
+// Jobs whose lease was not renewed for jobLease were left by an instance that
+// stopped and are queued again.
+const (
+	jobHeartbeatInterval = 30 * time.Second
+	jobLease             = 2 * time.Minute
+)
+
+var (
+	// ErrJobNotFound is returned for jobs that do not exist or were removed
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobFinished is returned when canceling a job that already finished
+	ErrJobFinished = errors.New("job already finished")
+)
+
+// JobHandler runs a job, returning its result. It should stop when ctx is
+// done, which happens when the job is canceled or the queue stops.
+type JobHandler func(ctx context.Context, job models.Job) (interface{}, error)
+
+// permanentError is a job error that retrying cannot fix
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// PermanentJobError marks a job error that retrying cannot fix, such as
+// invalid parameters, so the job fails without further attempts
+func PermanentJobError(err error) error {
+	return permanentError{err}
+}
+
+// JobFilter narrows a job listing; empty fields match every job
+type JobFilter struct {
+	Status string
+	Type   string
+}
+
+// JobQueue runs long work, such as backfills, exports and report generation,
+// on a fixed number of worker goroutines. Jobs are stored in the database so
+// they survive restarts and can be followed through the API; failed attempts
+// are retried with a growing delay. Replicas sharing the database share the
+// queue; each renews the leases of the jobs it runs.
+type JobQueue struct {
+	db     *sqlx.DB
+	cfg    config.JobsConfig
+	clock  clock.Clock
+	worker string // Identifies this instance in the jobs it claims
+
+	mu       sync.Mutex
+	handlers map[string]JobHandler
+	attempts map[string]int                // Job type -> attempts, when not the configured number
+	running  map[string]int                // Job type -> jobs running
+	cancels  map[string]context.CancelFunc // Running job ID -> cancels it
+	canceled map[string]bool               // Running job IDs canceled
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewJobQueue creates a job queue; Start runs its workers
+func NewJobQueue(db *sqlx.DB, cfg config.JobsConfig) *JobQueue {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.RetentionDays < 1 {
+		cfg.RetentionDays = 7
+	}
+	if cfg.OutputDir == "" {
+		cfg.OutputDir = filepath.Join(os.TempDir(), "licet-jobs")
+	}
+	return &JobQueue{
+		db:       db,
+		cfg:      cfg,
+		clock:    clock.System,
+		worker:   newWorkerID(),
+		handlers: make(map[string]JobHandler),
+		attempts: make(map[string]int),
+		running:  make(map[string]int),
+		cancels:  make(map[string]context.CancelFunc),
+		canceled: make(map[string]bool),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// SetClock sets the clock that job times and retry delays are based on
+func (q *JobQueue) SetClock(c clock.Clock) {
+	q.clock = c
+}
+
+// Register sets the handler running the jobs of a type. attempts overrides
+// the configured number of attempts of the type when positive.
+func (q *JobQueue) Register(jobType string, attempts int, handler JobHandler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = handler
+	if attempts > 0 {
+		q.attempts[jobType] = attempts
+	}
+}
+
+// Start requeues the running jobs whose lease expired, then starts the
+// workers, the renewal of leases and the hourly removal of old jobs. Jobs
+// that other replicas are running are left to them.
+func (q *JobQueue) Start() error {
+	if err := os.MkdirAll(q.cfg.OutputDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create job output directory: %w", err)
+	}
+	if err := q.requeueExpired(context.Background()); err != nil {
+		return fmt.Errorf("failed to requeue interrupted jobs: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q.cancel = cancel
+	for i := 0; i < q.cfg.Workers; i++ {
+		q.wg.Add(1)
+		go q.work(ctx)
+	}
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		ticker := time.NewTicker(jobHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := q.heartbeat(ctx); err != nil && ctx.Err() == nil {
+				log.Errorf("Failed to renew job leases: %v", err)
+			}
+			if err := q.requeueExpired(ctx); err != nil && ctx.Err() == nil {
+				log.Errorf("Failed to requeue interrupted jobs: %v", err)
+			}
+		}
+	}()
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			if err := q.prune(context.Background()); err != nil {
+				log.Errorf("Failed to remove old jobs: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop cancels the running jobs, waits for the workers to exit and queues
+// the jobs they were running again
+func (q *JobQueue) Stop() {
+	if q.cancel == nil {
+		return
+	}
+	q.cancel()
+	q.wg.Wait()
+
+	query := `UPDATE jobs SET status = ?, run_after = ?, worker = '' WHERE status = ? AND worker = ?`
+	if _, err := q.db.Exec(q.db.Rebind(query), models.JobQueued, q.clock.Now(), models.JobRunning, q.worker); err != nil {
+		log.Errorf("Failed to requeue stopped jobs: %v", err)
+	}
+}
+
+// heartbeat renews the leases of the jobs this instance is running
+func (q *JobQueue) heartbeat(ctx context.Context) error {
+	query := `UPDATE jobs SET heartbeat_at = ? WHERE status = ? AND worker = ?`
+	_, err := q.db.ExecContext(ctx, q.db.Rebind(query), q.clock.Now(), models.JobRunning, q.worker)
+	return err
+}
+
+// requeueExpired queues the running jobs whose lease expired again, as the
+// instance running them stopped without finishing them
+func (q *JobQueue) requeueExpired(ctx context.Context) error {
+	now := q.clock.Now()
+	query := `UPDATE jobs SET status = ?, run_after = ?, worker = '' WHERE status = ? AND (heartbeat_at IS NULL OR heartbeat_at < ?)`
+	res, err := q.db.ExecContext(ctx, q.db.Rebind(query), models.JobQueued, now, models.JobRunning, now.Add(-jobLease))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Infof("Requeued %d jobs interrupted by a stopped instance", n)
+	}
+	return nil
+}
+
+// Enqueue adds a job for the workers and returns it. A job of the same type,
+// server and parameters that is queued or running is returned instead of
+// adding another.
+func (q *JobQueue) Enqueue(ctx context.Context, jobType, server string, params interface{}) (models.Job, error) {
+	encoded := ""
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return models.Job{}, fmt.Errorf("failed to encode job parameters: %w", err)
+		}
+		encoded = string(data)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var existing jobRow
+	query := `SELECT * FROM jobs WHERE type = ? AND server = ? AND params = ? AND status IN (?, ?) ORDER BY created_at LIMIT 1`
+	err := q.db.GetContext(ctx, &existing, q.db.Rebind(query), jobType, server, encoded, models.JobQueued, models.JobRunning)
+	if err == nil {
+		return existing.job(), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return models.Job{}, fmt.Errorf("failed to look up jobs: %w", err)
+	}
+
+	attempts := q.cfg.MaxAttempts
+	if n, ok := q.attempts[jobType]; ok {
+		attempts = n
+	}
+	now := q.clock.Now()
+	row := jobRow{
+		ID:          newJobID(),
+		Type:        jobType,
+		Server:      server,
+		Params:      encoded,
+		Status:      models.JobQueued,
+		MaxAttempts: attempts,
+		RunAfter:    now,
+		CreatedAt:   now,
+	}
+	query = `INSERT INTO jobs (id, type, server, params, status, attempts, max_attempts, result, error, run_after, created_at)
+		VALUES (?, ?, ?, ?, ?, 0, ?, '', '', ?, ?)`
+	if _, err := q.db.ExecContext(ctx, q.db.Rebind(query), row.ID, row.Type, row.Server, row.Params, row.Status, row.MaxAttempts, row.RunAfter, row.CreatedAt); err != nil {
+		return models.Job{}, fmt.Errorf("failed to queue job: %w", err)
+	}
+	q.notify()
+	return row.job(), nil
+}
+
+// Get returns a job
+func (q *JobQueue) Get(ctx context.Context, id string) (models.Job, error) {
+	var row jobRow
+	err := q.db.GetContext(ctx, &row, q.db.Rebind(`SELECT * FROM jobs WHERE id = ?`), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Job{}, ErrJobNotFound
+	}
+	if err != nil {
+		return models.Job{}, err
+	}
+	return row.job(), nil
+}
+
+// List returns the most recent jobs matching filter, newest first
+func (q *JobQueue) List(ctx context.Context, filter JobFilter, limit int) ([]models.Job, error) {
+	query := `SELECT * FROM jobs WHERE 1 = 1`
+	args := []interface{}{}
+	if filter.Status != "" {
+		query += ` AND status = ?`
+		args = append(args, filter.Status)
+	}
+	if filter.Type != "" {
+		query += ` AND type = ?`
+		args = append(args, filter.Type)
+	}
+	query += ` ORDER BY created_at DESC LIMIT ?`
+	args = append(args, limit)
+
+	var rows []jobRow
+	if err := q.db.SelectContext(ctx, &rows, q.db.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	jobs := make([]models.Job, len(rows))
+	for i, row := range rows {
+		jobs[i] = row.job()
+	}
+	return jobs, nil
+}
+
+// Cancel cancels a queued job, or stops a running one of this instance
+func (q *JobQueue) Cancel(ctx context.Context, id string) (models.Job, error) {
+	q.mu.Lock()
+	if cancel, ok := q.cancels[id]; ok {
+		q.canceled[id] = true
+		cancel()
+		q.mu.Unlock()
+		return q.Get(ctx, id)
+	}
+	q.mu.Unlock()
+
+	query := `UPDATE jobs SET status = ?, finished_at = ? WHERE id = ? AND status = ?`
+	res, err := q.db.ExecContext(ctx, q.db.Rebind(query), models.JobCanceled, q.clock.Now(), id, models.JobQueued)
+	if err != nil {
+		return models.Job{}, fmt.Errorf("failed to cancel job: %w", err)
+	}
+	job, err := q.Get(ctx, id)
+	if err != nil {
+		return job, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return job, ErrJobFinished
+	}
+	return job, nil
+}
+
+// OutputPath returns the path of the file a job produces
+func (q *JobQueue) OutputPath(id string) string {
+	return filepath.Join(q.cfg.OutputDir, id)
+}
+
+// work runs jobs until ctx is done
+func (q *JobQueue) work(ctx context.Context) {
+	defer q.wg.Done()
+	for {
+		job, err := q.claim(ctx)
+		if err != nil {
+			log.Errorf("Failed to claim a job: %v", err)
+		}
+		if job != nil {
+			q.run(ctx, *job)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-time.After(jobPollInterval):
+		}
+	}
+}
+
+// notify wakes an idle worker
+func (q *JobQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// claim marks the oldest due job whose type is below its concurrency limit
+// as running and returns it, or nil when there is none
+func (q *JobQueue) claim(ctx context.Context) (*models.Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.clock.Now()
+	var rows []jobRow
+	query := `SELECT * FROM jobs WHERE status = ? AND run_after <= ? ORDER BY created_at LIMIT 50`
+	if err := q.db.SelectContext(ctx, &rows, q.db.Rebind(query), models.JobQueued, now); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if _, ok := q.handlers[row.Type]; !ok {
+			continue
+		}
+		if limit, ok := q.cfg.Concurrency[row.Type]; ok && q.running[row.Type] >= limit {
+			continue
+		}
+		query := `UPDATE jobs SET status = ?, attempts = attempts + 1, started_at = ?, worker = ?, heartbeat_at = ? WHERE id = ? AND status = ?`
+		res, err := q.db.ExecContext(ctx, q.db.Rebind(query), models.JobRunning, now, q.worker, now, row.ID, models.JobQueued)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue // Canceled meanwhile
+		}
+		q.running[row.Type]++
+		row.Status = models.JobRunning
+		row.Attempts++
+		row.StartedAt = &now
+		row.Worker = q.worker
+		row.HeartbeatAt = &now
+		job := row.job()
+		return &job, nil
+	}
+	return nil, nil
+}
+
+// run runs a claimed job and records its outcome
+func (q *JobQueue) run(ctx context.Context, job models.Job) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	q.mu.Lock()
+	handler := q.handlers[job.Type]
+	q.cancels[job.ID] = cancel
+	q.mu.Unlock()
+
+	log.Debugf("Running %s job %s (attempt %d of %d)", job.Type, job.ID, job.Attempts, job.MaxAttempts)
+	result, err := func() (result interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("job panicked: %v", p)
+			}
+		}()
+		return handler(jobCtx, job)
+	}()
+
+	q.mu.Lock()
+	canceled := q.canceled[job.ID]
+	delete(q.cancels, job.ID)
+	delete(q.canceled, job.ID)
+	q.running[job.Type]--
+	q.mu.Unlock()
+	q.notify()
+
+	if ctx.Err() != nil && !canceled {
+		return // Stopping; Stop queues the job again
+	}
+	if err := q.finish(job, result, err, canceled); err != nil {
+		log.Errorf("Failed to record the outcome of job %s: %v", job.ID, err)
+	}
+}
+
+// finish records the outcome of an attempt at a job, queueing it again when
+// it failed and has attempts left. Jobs another instance claimed after their
+// lease expired are left to it.
+func (q *JobQueue) finish(job models.Job, result interface{}, jobErr error, canceled bool) error {
+	now := q.clock.Now()
+	var permanent permanentError
+	switch {
+	case canceled:
+		query := `UPDATE jobs SET status = ?, error = ?, finished_at = ? WHERE id = ? AND worker = ?`
+		_, err := q.db.Exec(q.db.Rebind(query), models.JobCanceled, "canceled while running", now, job.ID, q.worker)
+		return err
+	case jobErr != nil && job.Attempts < job.MaxAttempts && !errors.As(jobErr, &permanent):
+		delay := time.Duration(q.cfg.RetryDelay) * time.Second << (job.Attempts - 1)
+		log.Warnf("%s job %s failed, retrying in %s: %v", job.Type, job.ID, delay, jobErr)
+		query := `UPDATE jobs SET status = ?, error = ?, run_after = ?, worker = '' WHERE id = ? AND worker = ?`
+		_, err := q.db.Exec(q.db.Rebind(query), models.JobQueued, jobErr.Error(), now.Add(delay), job.ID, q.worker)
+		return err
+	case jobErr != nil:
+		log.Errorf("%s job %s failed: %v", job.Type, job.ID, jobErr)
+		query := `UPDATE jobs SET status = ?, error = ?, finished_at = ? WHERE id = ? AND worker = ?`
+		_, err := q.db.Exec(q.db.Rebind(query), models.JobFailed, jobErr.Error(), now, job.ID, q.worker)
+		return err
+	}
+
+	encoded := ""
+	if result != nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to encode job result: %w", err)
+		}
+		encoded = string(data)
+	}
+	query := `UPDATE jobs SET status = ?, result = ?, error = '', finished_at = ? WHERE id = ? AND worker = ?`
+	_, err := q.db.Exec(q.db.Rebind(query), models.JobSucceeded, encoded, now, job.ID, q.worker)
+	return err
+}
+
+// prune removes the jobs finished longer ago than the retention, with the
+// files they produced
+func (q *JobQueue) prune(ctx context.Context) error {
+	cutoff := q.clock.Now().AddDate(0, 0, -q.cfg.RetentionDays)
+	var ids []string
+	query := `SELECT id FROM jobs WHERE finished_at IS NOT NULL AND finished_at < ?`
+	if err := q.db.SelectContext(ctx, &ids, q.db.Rebind(query), cutoff); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := os.Remove(q.OutputPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Warnf("Failed to remove the output of job %s: %v", id, err)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	query = `DELETE FROM jobs WHERE finished_at IS NOT NULL AND finished_at < ?`
+	_, err := q.db.ExecContext(ctx, q.db.Rebind(query), cutoff)
+	return err
+}
+
+// jobRow is a row of the jobs table
+type jobRow struct {
+	ID          string     `db:"id"`
+	Type        string     `db:"type"`
+	Server      string     `db:"server"`
+	Params      string     `db:"params"`
+	Status      string     `db:"status"`
+	Attempts    int        `db:"attempts"`
+	MaxAttempts int        `db:"max_attempts"`
+	Result      string     `db:"result"`
+	Error       string     `db:"error"`
+	RunAfter    time.Time  `db:"run_after"`
+	CreatedAt   time.Time  `db:"created_at"`
+	StartedAt   *time.Time `db:"started_at"`
+	FinishedAt  *time.Time `db:"finished_at"`
+	Worker      string     `db:"worker"`
+	HeartbeatAt *time.Time `db:"heartbeat_at"`
+}
+
+func (r jobRow) job() models.Job {
+	job := models.Job{
+		ID:          r.ID,
+		Type:        r.Type,
+		Server:      r.Server,
+		Status:      r.Status,
+		Attempts:    r.Attempts,
+		MaxAttempts: r.MaxAttempts,
+		Error:       r.Error,
+		RunAfter:    r.RunAfter,
+		CreatedAt:   r.CreatedAt,
+		StartedAt:   r.StartedAt,
+		FinishedAt:  r.FinishedAt,
+	}
+	if r.Params != "" {
+		job.Params = json.RawMessage(r.Params)
+	}
+	if r.Result != "" {
+		job.Result = json.RawMessage(r.Result)
+	}
+	return job
+}
+
+// newJobID returns a random job ID
+func newJobID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// newWorkerID returns an ID for this instance: its hostname, for telling
+// replicas apart in the jobs table, and a random suffix
+func newWorkerID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	host, _ := os.Hostname()
+	if len(host) > 64 {
+		host = host[:64]
+	}
+	return host + "-" + hex.EncodeToString(b)
+}
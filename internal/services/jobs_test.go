@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"licet/internal/clock"
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+// waitForJob waits until a job finished
+func waitForJob(t *testing.T, q *JobQueue, id string) models.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := q.Get(context.Background(), id)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if job.FinishedAt != nil {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Job %s did not finish", id)
+	return models.Job{}
+}
+
+func TestJobQueue(t *testing.T) {
+	ctx := context.Background()
+	q := NewJobQueue(newTestDB(t), config.JobsConfig{
+		Workers:     3,
+		MaxAttempts: 3,
+		Concurrency: map[string]int{"serial": 1},
+		OutputDir:   t.TempDir(),
+	})
+
+	var calls atomic.Int32
+	q.Register("flaky", 0, func(ctx context.Context, job models.Job) (interface{}, error) {
+		if calls.Add(1) < 3 {
+			return nil, errors.New("busy")
+		}
+		var params map[string]int
+		json.Unmarshal(job.Params, &params)
+		return map[string]int{"doubled": params["n"] * 2}, nil
+	})
+	q.Register("invalid", 0, func(ctx context.Context, job models.Job) (interface{}, error) {
+		return nil, PermanentJobError(errors.New("bad parameters"))
+	})
+	var running, overlapped atomic.Int32
+	q.Register("serial", 0, func(ctx context.Context, job models.Job) (interface{}, error) {
+		if running.Add(1) > 1 {
+			overlapped.Store(1)
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+		return nil, nil
+	})
+
+	flaky, err := q.Enqueue(ctx, "flaky", "", map[string]int{"n": 21})
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if again, _ := q.Enqueue(ctx, "flaky", "", map[string]int{"n": 21}); again.ID != flaky.ID {
+		t.Errorf("Expected the queued job for the same parameters, got %s", again.ID)
+	}
+	invalid, _ := q.Enqueue(ctx, "invalid", "", nil)
+	serial := []models.Job{}
+	for _, server := range []string{"a", "b", "c"} {
+		job, _ := q.Enqueue(ctx, "serial", server, nil)
+		serial = append(serial, job)
+	}
+	canceled, _ := q.Enqueue(ctx, "unregistered", "", nil)
+	if job, err := q.Cancel(ctx, canceled.ID); err != nil || job.Status != models.JobCanceled {
+		t.Errorf("Expected the queued job to be canceled, got %+v (%v)", job, err)
+	}
+	if _, err := q.Cancel(ctx, canceled.ID); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("Expected canceling a finished job to fail, got %v", err)
+	}
+
+	if err := q.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer q.Stop()
+
+	job := waitForJob(t, q, flaky.ID)
+	if job.Status != models.JobSucceeded || job.Attempts != 3 || string(job.Result) != `{"doubled":42}` {
+		t.Errorf("Expected success on the third attempt, got %+v", job)
+	}
+	job = waitForJob(t, q, invalid.ID)
+	if job.Status != models.JobFailed || job.Attempts != 1 || job.Error != "bad parameters" {
+		t.Errorf("Expected a permanent failure after one attempt, got %+v", job)
+	}
+	for _, s := range serial {
+		if job := waitForJob(t, q, s.ID); job.Status != models.JobSucceeded {
+			t.Errorf("Expected job %s to succeed, got %+v", s.ID, job)
+		}
+	}
+	if overlapped.Load() != 0 {
+		t.Error("Expected at most one serial job to run at a time")
+	}
+
+	jobs, err := q.List(ctx, JobFilter{Type: "serial"}, 10)
+	if err != nil || len(jobs) != 3 {
+		t.Errorf("Expected 3 serial jobs, got %d (%v)", len(jobs), err)
+	}
+
+	// Finished jobs and their output are removed after the retention
+	q.Stop()
+	os.WriteFile(q.OutputPath(flaky.ID), []byte("output"), 0o600)
+	q.SetClock(clock.NewFixed(time.Now().AddDate(0, 0, 8)))
+	if err := q.prune(ctx); err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	if _, err := q.Get(ctx, flaky.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected the old job to be removed, got %v", err)
+	}
+	if _, err := os.Stat(q.OutputPath(flaky.ID)); !os.IsNotExist(err) {
+		t.Errorf("Expected the output of the old job to be removed, got %v", err)
+	}
+}
+
+func TestJobQueue_RequeuesInterruptedJobs(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	q := NewJobQueue(db, config.JobsConfig{OutputDir: t.TempDir()})
+	job, err := q.Enqueue(ctx, "report", "", nil)
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	// As left by a process that stopped while running it
+	if _, err := db.Exec(`UPDATE jobs SET status = 'running', attempts = 1 WHERE id = ?`, job.ID); err != nil {
+		t.Fatalf("Failed to mark job running: %v", err)
+	}
+
+	q = NewJobQueue(db, config.JobsConfig{MaxAttempts: 2, OutputDir: t.TempDir()})
+	q.Register("report", 0, func(ctx context.Context, job models.Job) (interface{}, error) { return "done", nil })
+	if err := q.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer q.Stop()
+	if job := waitForJob(t, q, job.ID); job.Status != models.JobSucceeded || job.Attempts != 2 {
+		t.Errorf("Expected the interrupted job to run again, got %+v", job)
+	}
+}
+
+func TestJobQueue_LeavesJobsOfOtherInstances(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	clk := clock.NewFixed(time.Now())
+	q := NewJobQueue(db, config.JobsConfig{OutputDir: t.TempDir()})
+	q.SetClock(clk)
+	job, err := q.Enqueue(ctx, "report", "", nil)
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	// As claimed by a replica that renewed its lease a minute ago
+	query := `UPDATE jobs SET status = 'running', attempts = 1, worker = 'replica-b', heartbeat_at = ? WHERE id = ?`
+	if _, err := db.Exec(query, clk.Now().Add(-time.Minute), job.ID); err != nil {
+		t.Fatalf("Failed to mark job running: %v", err)
+	}
+
+	if err := q.requeueExpired(ctx); err != nil {
+		t.Fatalf("requeueExpired failed: %v", err)
+	}
+	if job, _ := q.Get(ctx, job.ID); job.Status != models.JobRunning {
+		t.Errorf("Expected the job of the other replica to keep running, got %+v", job)
+	}
+
+	// The replica stopped renewing its lease
+	clk.Advance(jobLease)
+	if err := q.requeueExpired(ctx); err != nil {
+		t.Fatalf("requeueExpired failed: %v", err)
+	}
+	if job, _ := q.Get(ctx, job.ID); job.Status != models.JobQueued {
+		t.Errorf("Expected the job to be queued again once its lease expired, got %+v", job)
+	}
+}
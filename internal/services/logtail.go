@@ -0,0 +1,142 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/parsers"
+)
+
+// logTailBatch is how many events are stored at once while reading a log
+const logTailBatch = 1000
+
+// logTailState is where the last read of a log file stopped
+type logTailState struct {
+	info   os.FileInfo
+	offset int64
+	parser parsers.LogEventParser // Keeps the date of logs that write it once
+}
+
+// LogTailer reads vendor daemon logs from local files, such as RLM ISV report
+// logs, and records their checkouts, check-ins and denials as license events.
+// Each read continues where the last one stopped; a file that was replaced
+// or truncated by log rotation is read from its start. Files are read from
+// the start when licet starts, and events stored before are skipped.
+type LogTailer struct {
+	events *EventService
+	files  []config.LogTailFile
+	now    func() time.Time
+
+	mu     sync.Mutex
+	states map[string]*logTailState
+}
+
+// NewLogTailer creates a tailer of the configured log files
+func NewLogTailer(cfg config.LogTailConfig, events *EventService) *LogTailer {
+	return &LogTailer{
+		events: events,
+		files:  cfg.Files,
+		now:    time.Now,
+		states: make(map[string]*logTailState),
+	}
+}
+
+// Poll reads the lines added to each log file since the last poll and
+// returns the number of events stored. A file that cannot be read does not
+// keep the others from being read.
+func (t *LogTailer) Poll(ctx context.Context) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stored := 0
+	var errs []error
+	for _, f := range t.files {
+		n, err := t.pollFile(ctx, f)
+		stored += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.Path, err))
+		}
+	}
+	return stored, errors.Join(errs...)
+}
+
+// pollFile reads the complete lines added to a log file. A line still being
+// written is read on the next poll.
+func (t *LogTailer) pollFile(ctx context.Context, f config.LogTailFile) (int, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	state := t.states[f.Path]
+	if state == nil || !os.SameFile(state.info, info) || info.Size() < state.offset {
+		parser, err := parsers.NewLogEventParser(f.Type, t.now())
+		if err != nil {
+			return 0, err
+		}
+		if state != nil {
+			log.Infof("Log %s was rotated, reading it from the start", f.Path)
+		}
+		state = &logTailState{parser: parser}
+		t.states[f.Path] = state
+	}
+	state.info = info
+	if _, err := file.Seek(state.offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	// The offset only moves past lines whose events are stored, so a failed
+	// batch is read again on the next poll
+	stored := 0
+	read := state.offset
+	var batch []models.LicenseEvent
+	flush := func() error {
+		if len(batch) > 0 {
+			n, err := t.events.RecordEvents(ctx, batch)
+			if err != nil {
+				return err
+			}
+			stored += n
+			batch = batch[:0]
+		}
+		state.offset = read
+		return nil
+	}
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			// An incomplete last line is left for the next poll
+			break
+		}
+		if err != nil {
+			return stored, err
+		}
+		read += int64(len(line))
+		if event, ok := state.parser.ParseLine(line); ok {
+			event.ServerHostname = f.Server
+			batch = append(batch, event)
+			if len(batch) >= logTailBatch {
+				if err := flush(); err != nil {
+					return stored, err
+				}
+			}
+		}
+	}
+	return stored, flush()
+}
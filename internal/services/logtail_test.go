@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestLogTailer_Poll(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "licsrv.rlog")
+
+	write := func(flag int, lines string) {
+		f, err := os.OpenFile(path, flag|os.O_WRONLY|os.O_CREATE, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(lines); err != nil {
+			t.Fatal(err)
+		}
+	}
+	count := func() int {
+		var n int
+		db.Get(&n, `SELECT COUNT(*) FROM license_events WHERE server_hostname = '5053@rlm'`)
+		return n
+	}
+
+	tailer := NewLogTailer(config.LogTailConfig{
+		Files: []config.LogTailFile{{Server: "5053@rlm", Type: "rlm_reportlog", Path: path}},
+	}, NewEventService(db, "sqlite"))
+
+	write(os.O_TRUNC, "START licsrv 11/15/2023 09:30\n"+
+		"OUT solver 1.0 jdoe ws01 \"\" 1 1 1 11/15 09:31:12.00\n"+
+		"DENY solver 1.0 bwong ws02 \"\" 1 -22 0 11/15 09:40\n"+
+		"IN 1 solver 1.0 jdoe ws01 \"\" 1 1 1 11/15 10:02:00")
+	n, err := tailer.Poll(ctx)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if n != 2 || count() != 2 {
+		t.Fatalf("Expected the 2 complete lines to be stored, got %d (%d rows)", n, count())
+	}
+
+	// The line being written is read once complete
+	write(os.O_APPEND, ".00\n")
+	if n, err := tailer.Poll(ctx); err != nil || n != 1 || count() != 3 {
+		t.Fatalf("Expected the completed check-in to be stored, got %d (%v)", n, err)
+	}
+	if n, err := tailer.Poll(ctx); err != nil || n != 0 {
+		t.Fatalf("Expected no new events, got %d (%v)", n, err)
+	}
+
+	var reason string
+	db.Get(&reason, `SELECT reason_code FROM license_events WHERE event_type = 'DENIED'`)
+	if reason != models.DenialNoSeats {
+		t.Errorf("Expected the RLM status to be classified, got %q", reason)
+	}
+
+	// A truncated log is read from its start
+	write(os.O_TRUNC, "START licsrv 11/16/2023 00:00\n"+
+		"OUT mesher 2.0 asmith ws03 \"\" 1 1 1 11/16 08:00:00.00\n")
+	if n, err := tailer.Poll(ctx); err != nil || n != 1 || count() != 4 {
+		t.Fatalf("Expected the rotated log to be read, got %d (%v)", n, err)
+	}
+
+	// A missing file is reported
+	os.Remove(path)
+	if _, err := tailer.Poll(ctx); err == nil {
+		t.Error("Expected an error for a missing log")
+	}
+}
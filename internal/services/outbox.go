@@ -0,0 +1,319 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	mail "github.com/wneessen/go-mail"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"licet/internal/clock"
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+// The outbox looks for due email every outboxPollInterval and sends at most
+// outboxBatchSize at a time
+const (
+	outboxPollInterval = 15 * time.Second
+	outboxBatchSize    = 50
+)
+
+var (
+	// ErrEmailNotFound is returned for outbox email that does not exist or
+	// was removed
+	ErrEmailNotFound = errors.New("email not found")
+	// ErrEmailNotDead is returned when requeueing email that has not failed
+	// for good
+	ErrEmailNotDead = errors.New("email is not a dead letter")
+)
+
+// EmailOutbox sends the email queued with email.queue enabled, retrying
+// failures with exponential backoff until the attempts run out, after which
+// the email is kept as a dead letter until requeued
+type EmailOutbox struct {
+	db    *sqlx.DB
+	cfg   *config.Config
+	clock clock.Clock
+	send  func(emailMessage) error
+
+	mu      sync.Mutex // Serializes sending batches
+	started bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewEmailOutbox creates an outbox sending with the SMTP server of the email
+// settings
+func NewEmailOutbox(db *sqlx.DB, cfg *config.Config) *EmailOutbox {
+	o := &EmailOutbox{
+		db:    db,
+		cfg:   cfg,
+		clock: clock.System,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	o.send = func(msg emailMessage) error {
+		m, err := buildMessage(o.cfg.Email, msg)
+		if err != nil {
+			return err
+		}
+		return deliver(o.cfg.Email, m)
+	}
+	return o
+}
+
+// SetClock sets the clock that retries are scheduled with
+func (o *EmailOutbox) SetClock(c clock.Clock) {
+	o.clock = c
+}
+
+// enqueueEmail adds an email to the outbox, to be sent by the next run of
+// an EmailOutbox
+func enqueueEmail(ctx context.Context, db *sqlx.DB, now time.Time, maxAttempts int, msg emailMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode email: %w", err)
+	}
+	query := `INSERT INTO email_outbox (subject, recipients, message, status, attempts, max_attempts, error, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, 0, ?, '', ?, ?)`
+	_, err = db.ExecContext(ctx, db.Rebind(query), msg.Subject, strings.Join(msg.Recipients, ","), string(data),
+		models.EmailQueued, max(maxAttempts, 1), now, now)
+	if err != nil {
+		return fmt.Errorf("failed to queue email: %w", err)
+	}
+	log.Debugf("Queued email %q to %d recipients", msg.Subject, len(msg.Recipients))
+	return nil
+}
+
+// Start sends due email periodically until Stop is called, and removes sent
+// email older than the retention once an hour
+func (o *EmailOutbox) Start() {
+	if !o.cfg.Email.Queue.Enabled {
+		return
+	}
+	log.Infof("Email outbox started (%d attempts per email)", o.cfg.Email.Queue.MaxAttempts)
+	o.started = true
+
+	go func() {
+		defer close(o.done)
+		ticker := time.NewTicker(outboxPollInterval)
+		defer ticker.Stop()
+		var pruned time.Time
+		for {
+			if _, err := o.Process(context.Background()); err != nil {
+				log.Errorf("Failed to send queued email: %v", err)
+			}
+			if now := o.clock.Now(); now.Sub(pruned) >= time.Hour {
+				if err := o.prune(context.Background()); err != nil {
+					log.Errorf("Failed to remove sent email: %v", err)
+				}
+				pruned = now
+			}
+			select {
+			case <-o.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops sending and waits for the batch being sent
+func (o *EmailOutbox) Stop() {
+	close(o.stop)
+	if o.started {
+		<-o.done
+	}
+}
+
+// Process sends the due email and returns how many were sent
+func (o *EmailOutbox) Process(ctx context.Context) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var rows []outboxRow
+	query := `SELECT * FROM email_outbox WHERE status = ? AND next_attempt_at <= ? ORDER BY id LIMIT ?`
+	if err := o.db.SelectContext(ctx, &rows, o.db.Rebind(query), models.EmailQueued, o.clock.Now(), outboxBatchSize); err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, row := range rows {
+		claimed, err := o.claim(ctx, row)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue // Sent by another instance meanwhile
+		}
+		row.Attempts++
+
+		var msg emailMessage
+		sendErr := json.Unmarshal([]byte(row.Message), &msg)
+		if sendErr != nil {
+			sendErr = fmt.Errorf("failed to decode email: %w", sendErr)
+			row.Attempts = row.MaxAttempts // Retrying cannot help
+		} else {
+			sendErr = o.send(msg)
+		}
+		if err := o.finish(ctx, row, msg.Headers[mail.HeaderMessageID], sendErr); err != nil {
+			return sent, err
+		}
+		if sendErr == nil {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// claim counts an attempt at an email and holds it back from other instances
+// for the first retry delay while it is sent, reporting whether the email was
+// still due
+func (o *EmailOutbox) claim(ctx context.Context, row outboxRow) (bool, error) {
+	lease := o.clock.Now().Add(o.retryDelay())
+	query := `UPDATE email_outbox SET attempts = attempts + 1, next_attempt_at = ? WHERE id = ? AND status = ? AND attempts = ?`
+	res, err := o.db.ExecContext(ctx, o.db.Rebind(query), lease, row.ID, models.EmailQueued, row.Attempts)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// finish records the outcome of an attempt at an email, scheduling a retry
+// when it failed and has attempts left. Once sent or given up on, the
+// receipts of the alerts the email notified of are updated.
+func (o *EmailOutbox) finish(ctx context.Context, row outboxRow, messageID string, sendErr error) error {
+	now := o.clock.Now()
+	switch {
+	case sendErr == nil:
+		log.Debugf("Sent queued email %d %q", row.ID, row.Subject)
+		query := `UPDATE email_outbox SET status = ?, error = '', sent_at = ? WHERE id = ?`
+		if _, err := o.db.ExecContext(ctx, o.db.Rebind(query), models.EmailSent, now, row.ID); err != nil {
+			return err
+		}
+		return updateEmailDeliveries(ctx, o.db, now, messageID, models.DeliverySent, "")
+	case row.Attempts < row.MaxAttempts:
+		delay := o.retryDelay() << (row.Attempts - 1)
+		log.Warnf("Email %d %q failed, retrying in %s: %v", row.ID, row.Subject, delay, sendErr)
+		query := `UPDATE email_outbox SET error = ?, next_attempt_at = ? WHERE id = ?`
+		_, err := o.db.ExecContext(ctx, o.db.Rebind(query), sendErr.Error(), now.Add(delay), row.ID)
+		return err
+	default:
+		log.Errorf("Email %d %q failed after %d attempts, kept as a dead letter: %v", row.ID, row.Subject, row.Attempts, sendErr)
+		query := `UPDATE email_outbox SET status = ?, error = ? WHERE id = ?`
+		if _, err := o.db.ExecContext(ctx, o.db.Rebind(query), models.EmailDead, sendErr.Error(), row.ID); err != nil {
+			return err
+		}
+		return updateEmailDeliveries(ctx, o.db, now, messageID, models.DeliveryFailed, sendErr.Error())
+	}
+}
+
+// retryDelay returns the delay before the first retry of an email
+func (o *EmailOutbox) retryDelay() time.Duration {
+	return time.Duration(max(o.cfg.Email.Queue.RetryDelay, 1)) * time.Second
+}
+
+// List returns the most recent email of the outbox, newest first, optionally
+// only that in a status
+func (o *EmailOutbox) List(ctx context.Context, status string, limit int) ([]models.OutboundEmail, error) {
+	query := `SELECT * FROM email_outbox WHERE 1 = 1`
+	args := []interface{}{}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	var rows []outboxRow
+	if err := o.db.SelectContext(ctx, &rows, o.db.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to list queued email: %w", err)
+	}
+	emails := make([]models.OutboundEmail, len(rows))
+	for i, row := range rows {
+		emails[i] = row.email()
+	}
+	return emails, nil
+}
+
+// Get returns an email of the outbox
+func (o *EmailOutbox) Get(ctx context.Context, id int64) (models.OutboundEmail, error) {
+	var row outboxRow
+	err := o.db.GetContext(ctx, &row, o.db.Rebind(`SELECT * FROM email_outbox WHERE id = ?`), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.OutboundEmail{}, ErrEmailNotFound
+	}
+	if err != nil {
+		return models.OutboundEmail{}, err
+	}
+	return row.email(), nil
+}
+
+// Requeue queues a dead letter again with all its attempts, to be sent by the
+// next run
+func (o *EmailOutbox) Requeue(ctx context.Context, id int64) (models.OutboundEmail, error) {
+	query := `UPDATE email_outbox SET status = ?, attempts = 0, error = '', next_attempt_at = ? WHERE id = ? AND status = ?`
+	res, err := o.db.ExecContext(ctx, o.db.Rebind(query), models.EmailQueued, o.clock.Now(), id, models.EmailDead)
+	if err != nil {
+		return models.OutboundEmail{}, err
+	}
+	email, err := o.Get(ctx, id)
+	if err != nil {
+		return email, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return email, ErrEmailNotDead
+	}
+	log.Infof("Requeued email %d %q", id, email.Subject)
+	return email, nil
+}
+
+// prune removes the email sent longer ago than the retention
+func (o *EmailOutbox) prune(ctx context.Context) error {
+	cutoff := o.clock.Now().AddDate(0, 0, -o.cfg.Email.Queue.RetentionDays)
+	query := `DELETE FROM email_outbox WHERE status = ? AND sent_at < ?`
+	_, err := o.db.ExecContext(ctx, o.db.Rebind(query), models.EmailSent, cutoff)
+	return err
+}
+
+// outboxRow is a row of the email_outbox table
+type outboxRow struct {
+	ID            int64      `db:"id"`
+	Subject       string     `db:"subject"`
+	Recipients    string     `db:"recipients"`
+	Message       string     `db:"message"`
+	Status        string     `db:"status"`
+	Attempts      int        `db:"attempts"`
+	MaxAttempts   int        `db:"max_attempts"`
+	Error         string     `db:"error"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+	SentAt        *time.Time `db:"sent_at"`
+}
+
+func (r outboxRow) email() models.OutboundEmail {
+	email := models.OutboundEmail{
+		ID:            r.ID,
+		Subject:       r.Subject,
+		Status:        r.Status,
+		Attempts:      r.Attempts,
+		MaxAttempts:   r.MaxAttempts,
+		Error:         r.Error,
+		NextAttemptAt: r.NextAttemptAt,
+		CreatedAt:     r.CreatedAt,
+		SentAt:        r.SentAt,
+	}
+	if r.Recipients != "" {
+		email.Recipients = strings.Split(r.Recipients, ",")
+	}
+	return email
+}
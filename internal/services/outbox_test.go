@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"licet/internal/clock"
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func newTestOutbox(t *testing.T) (*AlertService, *EmailOutbox, *clock.Fixed) {
+	t.Helper()
+	db := newTestDB(t)
+	cfg := &config.Config{Email: config.EmailConfig{
+		From:  "licet@example.com",
+		Queue: config.EmailQueueConfig{Enabled: true, MaxAttempts: 3, RetryDelay: 60, RetentionDays: 30},
+	}}
+	clk := clock.NewFixed(time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC))
+	alerts := NewAlertService(db, cfg)
+	alerts.SetClock(clk)
+	outbox := NewEmailOutbox(db, cfg)
+	outbox.SetClock(clk)
+	return alerts, outbox, clk
+}
+
+func TestEmailOutbox_RetriesUntilSent(t *testing.T) {
+	alerts, outbox, clk := newTestOutbox(t)
+	ctx := context.Background()
+
+	var sent []emailMessage
+	fail := true
+	outbox.send = func(msg emailMessage) error {
+		if fail {
+			return errors.New("connection refused")
+		}
+		sent = append(sent, msg)
+		return nil
+	}
+
+	if err := alerts.SendEmailAttachment("Report", "See attached", []string{"a@example.com", "b@example.com"}, "report.csv", []byte("a,b\n")); err != nil {
+		t.Fatalf("SendEmailAttachment failed: %v", err)
+	}
+	if n, err := outbox.Process(ctx); err != nil || n != 0 {
+		t.Fatalf("Process = %d, %v; want the attempt to fail", n, err)
+	}
+
+	// Not due again before the retry delay
+	fail = false
+	clk.Advance(30 * time.Second)
+	if n, _ := outbox.Process(ctx); n != 0 {
+		t.Fatal("Expected no retry before the retry delay")
+	}
+	clk.Advance(30 * time.Second)
+	if n, err := outbox.Process(ctx); err != nil || n != 1 {
+		t.Fatalf("Process = %d, %v; want the retry to be sent", n, err)
+	}
+	if len(sent) != 1 || sent[0].Filename != "report.csv" || string(sent[0].Attachment) != "a,b\n" || len(sent[0].Recipients) != 2 {
+		t.Errorf("Unexpected email sent: %+v", sent)
+	}
+
+	emails, err := outbox.List(ctx, models.EmailSent, 10)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(emails) != 1 || emails[0].Attempts != 2 || emails[0].SentAt == nil || emails[0].Recipients[1] != "b@example.com" {
+		t.Errorf("Unexpected sent email: %+v", emails)
+	}
+}
+
+func TestEmailOutbox_DeadLetterAndRequeue(t *testing.T) {
+	alerts, outbox, clk := newTestOutbox(t)
+	ctx := context.Background()
+
+	attempts := 0
+	outbox.send = func(emailMessage) error {
+		attempts++
+		return errors.New("550 mailbox unavailable")
+	}
+
+	if err := alerts.SendEmail("Alert", "Server down", []string{"ops@example.com"}); err != nil {
+		t.Fatalf("SendEmail failed: %v", err)
+	}
+	// Retries back off 60s, then 120s
+	for _, wait := range []time.Duration{0, time.Minute, 2 * time.Minute} {
+		clk.Advance(wait)
+		outbox.Process(ctx)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+
+	dead, err := outbox.List(ctx, models.EmailDead, 10)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(dead) != 1 || dead[0].Error != "550 mailbox unavailable" {
+		t.Fatalf("Expected a dead letter, got %+v", dead)
+	}
+	clk.Advance(time.Hour)
+	outbox.Process(ctx)
+	if attempts != 3 {
+		t.Error("Expected a dead letter not to be retried")
+	}
+
+	outbox.send = func(emailMessage) error { return nil }
+	email, err := outbox.Requeue(ctx, dead[0].ID)
+	if err != nil || email.Status != models.EmailQueued || email.Attempts != 0 {
+		t.Fatalf("Requeue = %+v, %v", email, err)
+	}
+	if n, _ := outbox.Process(ctx); n != 1 {
+		t.Error("Expected the requeued email to be sent")
+	}
+
+	if _, err := outbox.Requeue(ctx, dead[0].ID); !errors.Is(err, ErrEmailNotDead) {
+		t.Errorf("Requeueing a sent email: got %v, want ErrEmailNotDead", err)
+	}
+	if _, err := outbox.Requeue(ctx, 999); !errors.Is(err, ErrEmailNotFound) {
+		t.Errorf("Requeueing a missing email: got %v, want ErrEmailNotFound", err)
+	}
+}
+
+func TestEmailOutbox_RejectsInvalidRecipients(t *testing.T) {
+	alerts, outbox, _ := newTestOutbox(t)
+
+	if err := alerts.SendEmail("Alert", "Server down", []string{"not an address"}); err == nil {
+		t.Error("Expected an invalid recipient to be rejected before it is queued")
+	}
+	if emails, _ := outbox.List(context.Background(), "", 10); len(emails) != 0 {
+		t.Errorf("Expected nothing queued, got %+v", emails)
+	}
+}
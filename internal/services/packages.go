@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"licet/internal/models"
+)
+
+// StorePackages replaces the package components of a server with those of
+// its latest collection
+func (s *StorageService) StorePackages(ctx context.Context, hostname string, components []models.PackageComponent) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM feature_packages WHERE server_hostname = ?`), hostname); err != nil {
+		return fmt.Errorf("failed to clear packages of %s: %w", hostname, err)
+	}
+
+	// A component listed twice in a package keeps its first token count
+	seen := make(map[string]bool, len(components))
+	for _, c := range components {
+		key := c.PackageName + "|" + c.FeatureName
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		query := `
+			INSERT INTO feature_packages (server_hostname, package_name, feature_name, version, tokens)
+			VALUES (?, ?, ?, ?, ?)
+		`
+		if _, err := tx.ExecContext(ctx, tx.Rebind(query), hostname, c.PackageName, c.FeatureName, c.Version, c.Tokens); err != nil {
+			return fmt.Errorf("failed to store package %s: %w", c.PackageName, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetTokenPools returns the token usage of each package, optionally of one
+// server. A package the server reports as a feature is the token pool itself:
+// its seats are the tokens. Otherwise the used tokens are those the checkouts
+// of its components draw.
+func (s *StorageService) GetTokenPools(ctx context.Context, server string) ([]models.TokenPool, error) {
+	var components []models.PackageComponent
+	query := `
+		SELECT server_hostname, package_name, feature_name, version, tokens
+		FROM feature_packages
+	`
+	args := []interface{}{}
+	if server != "" {
+		query += ` WHERE server_hostname = ?`
+		args = append(args, server)
+	}
+	query += ` ORDER BY server_hostname, package_name, feature_name`
+	if err := s.db.SelectContext(ctx, &components, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	var seats []struct {
+		ServerHostname string `db:"server_hostname"`
+		Name           string `db:"name"`
+		Total          int    `db:"total"`
+		Used           int    `db:"used"`
+	}
+	query = `
+		SELECT server_hostname, name, SUM(total_licenses) AS total, SUM(used_licenses) AS used
+		FROM features
+		WHERE is_active = TRUE
+	`
+	args = []interface{}{}
+	if server != "" {
+		query += ` AND server_hostname = ?`
+		args = append(args, server)
+	}
+	query += ` GROUP BY server_hostname, name`
+	if err := s.db.SelectContext(ctx, &seats, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	type seatCount struct{ total, used int }
+	features := make(map[UsageKey]seatCount, len(seats))
+	for _, f := range seats {
+		features[UsageKey{f.ServerHostname, f.Name}] = seatCount{f.Total, f.Used}
+	}
+
+	pools := []models.TokenPool{}
+	for _, c := range components {
+		if n := len(pools); n == 0 || pools[n-1].ServerHostname != c.ServerHostname || pools[n-1].PackageName != c.PackageName {
+			pools = append(pools, models.TokenPool{ServerHostname: c.ServerHostname, PackageName: c.PackageName})
+		}
+		pool := &pools[len(pools)-1]
+		checkouts := features[UsageKey{c.ServerHostname, c.FeatureName}].used
+		pool.Components = append(pool.Components, models.TokenPoolComponent{
+			FeatureName: c.FeatureName,
+			Tokens:      c.Tokens,
+			Checkouts:   checkouts,
+			UsedTokens:  checkouts * c.Tokens,
+		})
+		pool.UsedTokens += checkouts * c.Tokens
+	}
+
+	for i := range pools {
+		pool := &pools[i]
+		if f, ok := features[UsageKey{pool.ServerHostname, pool.PackageName}]; ok {
+			pool.TotalTokens, pool.UsedTokens = f.total, f.used
+		}
+		if pool.TotalTokens > 0 {
+			pool.AvailableTokens = max(pool.TotalTokens-pool.UsedTokens, 0)
+			pool.UtilizationPct = roundHundredths(float64(pool.UsedTokens) * 100 / float64(pool.TotalTokens))
+		}
+	}
+	return pools, nil
+}
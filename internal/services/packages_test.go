@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"licet/internal/models"
+)
+
+func TestGetTokenPools(t *testing.T) {
+	db := newTestDB(t)
+	storage := NewStorageService(db, "sqlite")
+	ctx := context.Background()
+
+	err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "1055@a", Name: "anshpc_pack", TotalLicenses: 64, UsedLicenses: 12},
+		{ServerHostname: "1055@a", Name: "solver", TotalLicenses: 10, UsedLicenses: 3},
+		{ServerHostname: "1055@a", Name: "mesher", TotalLicenses: 10, UsedLicenses: 1},
+	})
+	if err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	err = storage.StorePackages(ctx, "1055@a", []models.PackageComponent{
+		{PackageName: "anshpc_pack", FeatureName: "solver", Tokens: 4},
+		{PackageName: "suite", FeatureName: "solver", Tokens: 2},
+		{PackageName: "suite", FeatureName: "mesher", Tokens: 1},
+		{PackageName: "suite", FeatureName: "mesher", Tokens: 3},
+	})
+	if err != nil {
+		t.Fatalf("StorePackages failed: %v", err)
+	}
+
+	pools, err := storage.GetTokenPools(ctx, "1055@a")
+	if err != nil {
+		t.Fatalf("GetTokenPools failed: %v", err)
+	}
+	if len(pools) != 2 {
+		t.Fatalf("Expected 2 token pools, got %+v", pools)
+	}
+
+	// The package feature is the pool itself
+	pack := pools[0]
+	if pack.PackageName != "anshpc_pack" || pack.TotalTokens != 64 || pack.UsedTokens != 12 || pack.AvailableTokens != 52 || pack.UtilizationPct != 18.75 {
+		t.Errorf("Expected 12 of 64 tokens used, got %+v", pack)
+	}
+
+	// Without one, the tokens are those its components draw
+	suite := pools[1]
+	if suite.PackageName != "suite" || suite.TotalTokens != 0 || suite.UsedTokens != 3*2+1*1 {
+		t.Errorf("Expected 7 tokens drawn by the components, got %+v", suite)
+	}
+	if len(suite.Components) != 2 || suite.Components[0].FeatureName != "mesher" || suite.Components[0].Tokens != 1 {
+		t.Errorf("Expected the first token count of a repeated component, got %+v", suite.Components)
+	}
+
+	// Collections replace the packages of a server
+	if err := storage.StorePackages(ctx, "1055@a", nil); err != nil {
+		t.Fatalf("StorePackages failed: %v", err)
+	}
+	if pools, err := storage.GetTokenPools(ctx, ""); err != nil || len(pools) != 0 {
+		t.Errorf("Expected no token pools, got %+v (%v)", pools, err)
+	}
+}
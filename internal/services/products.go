@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"sort"
+
+	"licet/internal/models"
+	"licet/internal/parsers"
+)
+
+// GetMLMProducts returns the usage of the features of MLM servers grouped by
+// the MathWorks product they license, under the family of its base product.
+// Families and their products are sorted by name.
+func (s *StorageService) GetMLMProducts(ctx context.Context, servers []string) ([]models.ProductFamily, error) {
+	families := []models.ProductFamily{}
+	if len(servers) == 0 {
+		return families, nil
+	}
+	mlm := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		mlm[server] = true
+	}
+
+	var rows []struct {
+		ServerHostname string `db:"server_hostname"`
+		Name           string `db:"name"`
+		LicenseModel   string `db:"license_model"`
+		Total          int    `db:"total"`
+		Used           int    `db:"used"`
+	}
+	query := `
+		SELECT server_hostname, name, license_model, SUM(total_licenses) AS total, SUM(used_licenses) AS used
+		FROM features
+		WHERE is_active = TRUE
+		GROUP BY server_hostname, name, license_model
+		ORDER BY server_hostname, name
+	`
+	if err := s.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, err
+	}
+
+	// A feature with uncounted and counted pools is uncapped
+	products := make(map[UsageKey]*models.ProductUsage)
+	var keys []UsageKey
+	for _, row := range rows {
+		if !mlm[row.ServerHostname] {
+			continue
+		}
+		key := UsageKey{row.ServerHostname, row.Name}
+		p, ok := products[key]
+		if !ok {
+			p = &models.ProductUsage{
+				ServerHostname: row.ServerHostname,
+				FeatureName:    row.Name,
+				ProductName:    parsers.LookupMLMProduct(row.Name).Name,
+			}
+			products[key] = p
+			keys = append(keys, key)
+		}
+		p.UsedLicenses += row.Used
+		if row.LicenseModel == models.LicenseModelUncounted {
+			p.Uncapped = true
+		} else {
+			p.TotalLicenses += row.Total
+		}
+	}
+
+	byFamily := make(map[string]int)
+	for _, key := range keys {
+		p := products[key]
+		if p.Uncapped {
+			p.TotalLicenses = 0
+		} else if p.TotalLicenses > 0 {
+			p.AvailableLicenses = max(p.TotalLicenses-p.UsedLicenses, 0)
+			p.UtilizationPct = roundHundredths(float64(p.UsedLicenses) * 100 / float64(p.TotalLicenses))
+		}
+
+		family := parsers.LookupMLMProduct(key.FeatureName).Family
+		i, ok := byFamily[family]
+		if !ok {
+			i = len(families)
+			byFamily[family] = i
+			families = append(families, models.ProductFamily{Family: family})
+		}
+		families[i].UsedLicenses += p.UsedLicenses
+		families[i].Products = append(families[i].Products, *p)
+	}
+
+	sort.Slice(families, func(i, j int) bool { return families[i].Family < families[j].Family })
+	for _, f := range families {
+		sort.SliceStable(f.Products, func(i, j int) bool { return f.Products[i].ProductName < f.Products[j].ProductName })
+	}
+	return families, nil
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/parsers"
+)
+
+func TestGetMLMProducts(t *testing.T) {
+	db := newTestDB(t)
+	storage := NewStorageService(db, "sqlite")
+	ctx := context.Background()
+
+	err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "27000@mlm", Name: "MATLAB", Version: "45", TotalLicenses: 50, UsedLicenses: 10, LicenseModel: models.LicenseModelFloating},
+		{ServerHostname: "27000@mlm", Name: "Signal_Toolbox", Version: "45", TotalLicenses: 10, UsedLicenses: 4, LicenseModel: models.LicenseModelFloating},
+		{ServerHostname: "27000@mlm", Name: "SIMULINK", Version: "45", TotalLicenses: 20, UsedLicenses: 5, LicenseModel: models.LicenseModelFloating},
+		{ServerHostname: "27000@mlm", Name: "MATLAB_Distrib_Comp_Engine", Version: "45", TotalLicenses: 9999, UsedLicenses: 3, LicenseModel: models.LicenseModelUncounted},
+		{ServerHostname: "27000@other", Name: "MATLAB", Version: "45", TotalLicenses: 5, UsedLicenses: 1, LicenseModel: models.LicenseModelFloating},
+	})
+	if err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	families, err := storage.GetMLMProducts(ctx, []string{"27000@mlm"})
+	if err != nil {
+		t.Fatalf("GetMLMProducts failed: %v", err)
+	}
+	if len(families) != 3 {
+		t.Fatalf("Expected 3 product families, got %+v", families)
+	}
+
+	matlab := families[0]
+	if matlab.Family != parsers.MLMFamilyMATLAB || matlab.UsedLicenses != 14 || len(matlab.Products) != 2 {
+		t.Fatalf("Expected MATLAB and a toolbox of the MLM server only, got %+v", matlab)
+	}
+	if p := matlab.Products[1]; p.ProductName != "Signal Processing Toolbox" || p.FeatureName != "Signal_Toolbox" || p.AvailableLicenses != 6 || p.UtilizationPct != 40 {
+		t.Errorf("Expected the toolbox under its product name, got %+v", p)
+	}
+
+	// Uncapped features count seats in use but no capacity
+	if engine := families[1].Products[0]; families[1].Family != parsers.MLMFamilyParallelServer || !engine.Uncapped || engine.TotalLicenses != 0 || engine.UsedLicenses != 3 || engine.UtilizationPct != 0 {
+		t.Errorf("Expected an uncapped parallel server, got %+v", families[1])
+	}
+	if families[2].Family != parsers.MLMFamilySimulink || families[2].Products[0].ProductName != "Simulink" {
+		t.Errorf("Expected the Simulink family, got %+v", families[2])
+	}
+
+	if families, err := storage.GetMLMProducts(ctx, nil); err != nil || len(families) != 0 {
+		t.Errorf("Expected no families without MLM servers, got %+v (%v)", families, err)
+	}
+}
+
+func TestDisplayNameService_MLMProducts(t *testing.T) {
+	names, err := NewDisplayNameService(newTestDB(t), config.DisplayConfig{
+		Rules: []config.DisplayNameRule{{Pattern: `^Compiler$`, DisplayName: "Compiler (site)"}},
+	})
+	if err != nil {
+		t.Fatalf("NewDisplayNameService failed: %v", err)
+	}
+	names.SetServerProfiles([]config.LicenseServer{
+		{Hostname: "27000@mlm", Type: "flexlm", Profile: parsers.ProfileMLM},
+		{Hostname: "27000@other", Type: "flexlm"},
+	})
+
+	if got := names.FeatureName("27000@mlm", "Statistics_Toolbox"); got != "Statistics and Machine Learning Toolbox" {
+		t.Errorf("mlm: got %q", got)
+	}
+	if got := names.FeatureName("27000@other", "Statistics_Toolbox"); got != "Statistics_Toolbox" {
+		t.Errorf("other server: got %q, want raw name", got)
+	}
+	if got := names.FeatureName("27000@mlm", "Compiler"); got != "Compiler (site)" {
+		t.Errorf("rule: got %q, want the configured rule", got)
+	}
+}
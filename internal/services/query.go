@@ -2,21 +2,34 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"licet/internal/clock"
 	"licet/internal/config"
 	"licet/internal/models"
 	"licet/internal/parsers"
 	"licet/internal/util"
 )
 
+// defaultQueryTimeout bounds a query when collection.query_timeout is unset,
+// and storing its results
+const defaultQueryTimeout = 30 * time.Second
+
+// ErrQueryTimeout is returned when a license server does not answer within
+// its query timeout
+var ErrQueryTimeout = errors.New("query timed out")
+
 // QueryService handles license server query operations
 type QueryService struct {
 	cfg           *config.Config
 	parserFactory *parsers.ParserFactory
 	storage       *StorageService
+	chaos         *ChaosService
 }
 
 // NewQueryService creates a new query service
@@ -29,6 +42,17 @@ func NewQueryService(cfg *config.Config, storage *StorageService) *QueryService
 	}
 }
 
+// SetClock sets the clock that query results and year-less checkout times
+// are based on
+func (s *QueryService) SetClock(c clock.Clock) {
+	s.parserFactory.SetClock(c)
+}
+
+// SetChaos makes queries answer with the faults injected into chaos
+func (s *QueryService) SetChaos(chaos *ChaosService) {
+	s.chaos = chaos
+}
+
 // GetAllServers returns all configured license servers
 func (s *QueryService) GetAllServers() ([]models.LicenseServer, error) {
 	var servers []models.LicenseServer
@@ -40,31 +64,147 @@ func (s *QueryService) GetAllServers() ([]models.LicenseServer, error) {
 			Type:        srv.Type,
 			CactiID:     srv.CactiID,
 			WebUI:       srv.WebUI,
+			Group:       srv.Group,
+
+			PollInterval: srv.PollInterval,
+			Schedule:     srv.Schedule,
+			Jitter:       srv.Jitter,
+			Timeout:      srv.Timeout,
 		})
 	}
 
 	return servers, nil
 }
 
+// queryMode returns the configured query mode of a server
+func (s *QueryService) queryMode(hostname string) string {
+	for _, srv := range s.cfg.Servers {
+		if srv.Hostname == hostname {
+			return srv.QueryMode
+		}
+	}
+	return ""
+}
+
+// queryProfile returns the vendor profile a server's output is parsed with
+func (s *QueryService) queryProfile(hostname string) string {
+	for _, srv := range s.cfg.Servers {
+		if srv.Hostname == hostname {
+			return srv.Profile
+		}
+	}
+	return ""
+}
+
+// queryCommand returns the script a server of type command is queried with
+func (s *QueryService) queryCommand(hostname string) (string, []string) {
+	for _, srv := range s.cfg.Servers {
+		if srv.Hostname == hostname {
+			return srv.Command, srv.CommandArgs
+		}
+	}
+	return "", nil
+}
+
+// querySource returns the REST API a server of type rest is queried through
+func (s *QueryService) querySource(hostname string) (parsers.RESTSource, error) {
+	for _, srv := range s.cfg.Servers {
+		if srv.Hostname != hostname {
+			continue
+		}
+		rest := srv.REST
+		token := rest.Token
+		if rest.TokenFile != "" {
+			data, err := os.ReadFile(rest.TokenFile)
+			if err != nil {
+				return parsers.RESTSource{}, fmt.Errorf("failed to read rest.token_file: %w", err)
+			}
+			token = strings.TrimSpace(string(data))
+		}
+		return parsers.RESTSource{
+			URL:           rest.URL,
+			UsersURL:      rest.UsersURL,
+			Token:         token,
+			AuthHeader:    rest.AuthHeader,
+			AuthScheme:    rest.AuthScheme,
+			FeaturesPath:  rest.FeaturesPath,
+			UsersPath:     rest.UsersPath,
+			FeatureFields: rest.FeatureFields,
+			UserFields:    rest.UserFields,
+		}, nil
+	}
+	return parsers.RESTSource{}, nil
+}
+
+// queryTimeout returns how long a query of a server may take
+func (s *QueryService) queryTimeout(hostname string) time.Duration {
+	for _, srv := range s.cfg.Servers {
+		if srv.Hostname == hostname && srv.Timeout > 0 {
+			return time.Duration(srv.Timeout) * time.Second
+		}
+	}
+	if s.cfg.Collection.QueryTimeout > 0 {
+		return time.Duration(s.cfg.Collection.QueryTimeout) * time.Second
+	}
+	return defaultQueryTimeout
+}
+
+// parser returns the parser querying a server, or answering with the fault
+// injected into it
+func (s *QueryService) parser(hostname, serverType string) (parsers.Parser, error) {
+	if fault, ok := s.chaos.Fault(hostname); ok {
+		log.Warnf("Chaos: answering the query of %s with fault %s", hostname, fault)
+		return s.parserFactory.GetFaultParser(serverType, fault)
+	}
+	if serverType == "rest" {
+		source, err := s.querySource(hostname)
+		if err != nil {
+			return nil, err
+		}
+		return s.parserFactory.GetRESTParser(source)
+	}
+	if serverType == "command" {
+		command, args := s.queryCommand(hostname)
+		return s.parserFactory.GetCommandParser(command, args)
+	}
+	return s.parserFactory.GetParserForProfile(serverType, s.queryMode(hostname), s.queryProfile(hostname))
+}
+
 // QueryServer queries a license server and optionally stores results
 func (s *QueryService) QueryServer(hostname, serverType string) (models.ServerQueryResult, error) {
-	parser, err := s.parserFactory.GetParser(serverType)
+	return s.QueryServerContext(context.Background(), hostname, serverType)
+}
+
+// QueryServerContext queries a license server within its query timeout and
+// optionally stores results. Cancelling ctx abandons the query.
+func (s *QueryService) QueryServerContext(ctx context.Context, hostname, serverType string) (models.ServerQueryResult, error) {
+	parser, err := s.parser(hostname, serverType)
 	if err != nil {
 		return models.ServerQueryResult{}, fmt.Errorf("failed to get parser for %s: %w", serverType, err)
 	}
 
 	log.Infof("Querying %s server: %s", serverType, hostname)
 
-	// Use context with timeout for both the query and subsequent storage
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	timeout := s.queryTimeout(hostname)
+	queryCtx, cancelQuery := context.WithTimeout(ctx, timeout)
+	defer cancelQuery()
 
-	result, err := parser.Query(ctx, hostname)
+	result, err := parser.Query(queryCtx, hostname)
+	// A killed vendor binary reports its signal rather than the deadline
+	if queryCtx.Err() != nil && ctx.Err() == nil {
+		err = fmt.Errorf("query of %s timed out after %s: %w", hostname, timeout, ErrQueryTimeout)
+	} else if ctx.Err() != nil {
+		err = ctx.Err()
+	}
 	if err != nil {
 		log.Debugf("Query error for %s: %v", hostname, err)
 		return result, err
 	}
 
+	// Storage gets its own deadline, whatever time the query took
+	ctx, cancel := context.WithTimeout(ctx, defaultQueryTimeout)
+	defer cancel()
+
 	log.Debugf("Query successful for %s: service=%s, features=%d, users=%d",
 		hostname, result.Status.Service, len(result.Features), len(result.Users))
 
@@ -83,6 +223,18 @@ func (s *QueryService) QueryServer(hostname, serverType string) (models.ServerQu
 		} else {
 			log.Debugf("Successfully recorded usage from %s", hostname)
 		}
+
+		if err := s.storage.RecordUsers(ctx, result.Users, time.Now()); err != nil {
+			log.Errorf("Failed to record users: %v", err)
+		}
+
+		if err := s.storage.RecordCheckouts(ctx, hostname, result.Users, time.Now()); err != nil {
+			log.Errorf("Failed to record checkouts: %v", err)
+		}
+
+		if err := s.storage.StorePackages(ctx, hostname, result.Packages); err != nil {
+			log.Errorf("Failed to store packages: %v", err)
+		}
 	}
 
 	return result, nil
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+// Username redaction modes
+const (
+	RedactModeHash = "hash"
+	RedactModeMask = "mask"
+)
+
+// Redactor hides individual usernames from roles that may only see
+// aggregates. Hashed names are stable, so per-user counts stay intact. All
+// methods are safe on a nil receiver, in which case nothing is redacted.
+type Redactor struct {
+	mode        string
+	key         []byte
+	exemptRoles map[string]bool
+}
+
+// NewRedactor creates a username redactor. It returns nil when redaction is disabled.
+func NewRedactor(cfg config.PrivacyConfig) *Redactor {
+	if !cfg.RedactUsernames {
+		return nil
+	}
+
+	r := &Redactor{
+		mode:        cfg.Mode,
+		key:         []byte(cfg.HashKey),
+		exemptRoles: make(map[string]bool, len(cfg.ExemptRoles)),
+	}
+	if r.mode != RedactModeMask {
+		r.mode = RedactModeHash
+		if cfg.HashKey == "" {
+			log.Warn("privacy.hash_key is not set; hashed usernames can be reversed by guessing")
+		}
+	}
+	for _, role := range cfg.ExemptRoles {
+		r.exemptRoles[role] = true
+	}
+	return r
+}
+
+// Applies reports whether usernames must be redacted for the given role
+func (r *Redactor) Applies(role string) bool {
+	return r != nil && !r.exemptRoles[role]
+}
+
+// Username returns the redacted form of a username
+func (r *Redactor) Username(name string) string {
+	if r == nil || name == "" {
+		return name
+	}
+	if r.mode == RedactModeMask {
+		return string([]rune(name)[:1]) + "***"
+	}
+	return Pseudonym(r.key, name)
+}
+
+// Pseudonym returns a stable keyed pseudonym for a username
+func Pseudonym(key []byte, name string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name))
+	return "user-" + hex.EncodeToString(mac.Sum(nil))[:10]
+}
+
+// Users returns a copy of users with redacted usernames
+func (r *Redactor) Users(users []models.LicenseUser) []models.LicenseUser {
+	if r == nil {
+		return users
+	}
+	redacted := make([]models.LicenseUser, len(users))
+	for i, u := range users {
+		u.Username = r.Username(u.Username)
+		redacted[i] = u
+	}
+	return redacted
+}
+
+// FeatureUsers returns a copy of feature users with redacted usernames
+func (r *Redactor) FeatureUsers(users []models.FeatureUser) []models.FeatureUser {
+	if r == nil {
+		return users
+	}
+	redacted := make([]models.FeatureUser, len(users))
+	for i, u := range users {
+		u.Username = r.Username(u.Username)
+		redacted[i] = u
+	}
+	return redacted
+}
+
+// Checkouts returns a copy of checkouts with redacted usernames
+func (r *Redactor) Checkouts(checkouts []models.Checkout) []models.Checkout {
+	if r == nil {
+		return checkouts
+	}
+	redacted := make([]models.Checkout, len(checkouts))
+	for i, c := range checkouts {
+		c.Username = r.Username(c.Username)
+		redacted[i] = c
+	}
+	return redacted
+}
+
+// Denials returns a copy of denials with redacted usernames
+func (r *Redactor) Denials(denials []models.Denial) []models.Denial {
+	if r == nil {
+		return denials
+	}
+	redacted := make([]models.Denial, len(denials))
+	for i, d := range denials {
+		d.Username = r.Username(d.Username)
+		redacted[i] = d
+	}
+	return redacted
+}
+
+// UserUsage returns a copy of user usage summaries with redacted usernames
+func (r *Redactor) UserUsage(usage []models.UserUsage) []models.UserUsage {
+	if r == nil {
+		return usage
+	}
+	redacted := make([]models.UserUsage, len(usage))
+	for i, u := range usage {
+		u.Username = r.Username(u.Username)
+		redacted[i] = u
+	}
+	return redacted
+}
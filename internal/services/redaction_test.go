@@ -0,0 +1,67 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestRedactor_Hash(t *testing.T) {
+	r := NewRedactor(config.PrivacyConfig{RedactUsernames: true, HashKey: "secret", ExemptRoles: []string{"admin"}})
+
+	if r.Applies("admin") {
+		t.Error("Expected admin to be exempt from redaction")
+	}
+	if !r.Applies("readonly") || !r.Applies("") {
+		t.Error("Expected readonly and anonymous roles to be redacted")
+	}
+
+	a := r.Username("jdoe")
+	if !strings.HasPrefix(a, "user-") || strings.Contains(a, "jdoe") {
+		t.Errorf("Expected hashed username, got %q", a)
+	}
+	if b := r.Username("jdoe"); a != b {
+		t.Errorf("Expected stable hash, got %q and %q", a, b)
+	}
+	if r.Username("asmith") == a {
+		t.Error("Expected different users to get different hashes")
+	}
+
+	other := NewRedactor(config.PrivacyConfig{RedactUsernames: true, HashKey: "other"})
+	if other.Username("jdoe") == a {
+		t.Error("Expected hash to depend on the key")
+	}
+}
+
+func TestRedactor_Mask(t *testing.T) {
+	r := NewRedactor(config.PrivacyConfig{RedactUsernames: true, Mode: RedactModeMask})
+	if got := r.Username("jdoe"); got != "j***" {
+		t.Errorf("Expected 'j***', got %q", got)
+	}
+	if got := r.Username("élise"); got != "é***" {
+		t.Errorf("Expected 'é***', got %q", got)
+	}
+}
+
+func TestRedactor_Users(t *testing.T) {
+	users := []models.LicenseUser{{Username: "jdoe", Host: "ws01"}}
+
+	var disabled *Redactor = NewRedactor(config.PrivacyConfig{})
+	if disabled != nil || disabled.Applies("readonly") {
+		t.Fatal("Expected disabled redaction to return a nil redactor that never applies")
+	}
+	if got := disabled.Users(users); got[0].Username != "jdoe" {
+		t.Errorf("Expected nil redactor to keep usernames, got %q", got[0].Username)
+	}
+
+	r := NewRedactor(config.PrivacyConfig{RedactUsernames: true, Mode: RedactModeMask})
+	redacted := r.Users(users)
+	if redacted[0].Username != "j***" || redacted[0].Host != "ws01" {
+		t.Errorf("Unexpected redacted user: %+v", redacted[0])
+	}
+	if users[0].Username != "jdoe" {
+		t.Error("Expected the original slice to be left unchanged")
+	}
+}
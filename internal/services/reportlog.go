@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"licet/internal/models"
+	"licet/internal/parsers"
+)
+
+// reportLogSession is a checkout from a report log with its check-in
+type reportLogSession struct {
+	feature, user, host string
+	out, in             time.Time
+}
+
+// reportLogPeak is the most licenses of a feature in use during an hour, and
+// when that was first reached
+type reportLogPeak struct {
+	feature string
+	at      time.Time
+	users   int
+}
+
+// reportLogSessions pairs the checkouts of a report log with their check-ins,
+// by handle, or by feature, user and host when the log has no handles. It
+// returns the sessions and the number of checkouts still held at the end of
+// the log. Check-ins of checkouts from before the log started are dropped.
+func reportLogSessions(records []parsers.ReportLogRecord) ([]reportLogSession, int) {
+	key := func(r parsers.ReportLogRecord) string {
+		if r.Handle != "" {
+			return r.Handle
+		}
+		return r.Feature + "|" + r.User + "|" + r.Host
+	}
+
+	var sessions []reportLogSession
+	open := make(map[string][]parsers.ReportLogRecord)
+	for _, r := range records {
+		switch r.Type {
+		case parsers.EventOut:
+			open[key(r)] = append(open[key(r)], r)
+		case parsers.EventIn:
+			held := open[key(r)]
+			if len(held) == 0 {
+				continue
+			}
+			out := held[0]
+			open[key(r)] = held[1:]
+			sessions = append(sessions, reportLogSession{
+				feature: out.Feature, user: out.User, host: out.Host, out: out.At, in: r.At,
+			})
+		}
+	}
+
+	held := 0
+	for _, checkouts := range open {
+		held += len(checkouts)
+	}
+	return sessions, held
+}
+
+// reportLogPeaks returns the peak usage of each feature in every hour with
+// checkouts or check-ins, by feature and time. Licenses checked out before the
+// log started only show up as check-ins, so usage starts from the fewest
+// licenses that keep it from dropping below zero.
+func reportLogPeaks(records []parsers.ReportLogRecord) []reportLogPeak {
+	byFeature := make(map[string][]parsers.ReportLogRecord)
+	var features []string
+	for _, r := range records {
+		if r.Type != parsers.EventOut && r.Type != parsers.EventIn {
+			continue
+		}
+		if _, ok := byFeature[r.Feature]; !ok {
+			features = append(features, r.Feature)
+		}
+		byFeature[r.Feature] = append(byFeature[r.Feature], r)
+	}
+	sort.Strings(features)
+
+	delta := func(r parsers.ReportLogRecord) int {
+		if r.Type == parsers.EventIn {
+			return -r.Count
+		}
+		return r.Count
+	}
+
+	var peaks []reportLogPeak
+	for _, feature := range features {
+		level, lowest := 0, 0
+		for _, r := range byFeature[feature] {
+			level += delta(r)
+			lowest = min(lowest, level)
+		}
+
+		level = -lowest
+		var peak *reportLogPeak
+		var hour time.Time
+		for _, r := range byFeature[feature] {
+			h := time.Date(r.At.Year(), r.At.Month(), r.At.Day(), r.At.Hour(), 0, 0, 0, r.At.Location())
+			if peak == nil || !h.Equal(hour) {
+				if peak != nil {
+					peaks = append(peaks, *peak)
+				}
+				hour = h
+				// Licenses still held at the start of the hour count toward its peak
+				peak = &reportLogPeak{feature: feature, at: h, users: level}
+			}
+			level += delta(r)
+			if level > peak.users {
+				peak.at, peak.users = r.At, level
+			}
+		}
+		if peak != nil {
+			peaks = append(peaks, *peak)
+		}
+	}
+	return peaks
+}
+
+// ImportReportLog stores the exact history of a server from its report log.
+// Checkouts that were checked in replace the polled checkouts within the span
+// of the log; checkouts still held are left to polling. The peak usage of
+// every hour is recorded as a usage sample, raising a polled sample of the
+// same minute, so high-water marks include checkouts shorter than the
+// collection interval, and rolled-up days are rolled up again. Importing the
+// same log again gives the same result.
+func (s *StorageService) ImportReportLog(ctx context.Context, hostname string, records []parsers.ReportLogRecord) (models.ReportLogImport, error) {
+	result := models.ReportLogImport{Records: len(records)}
+	if len(records) == 0 {
+		return result, nil
+	}
+	result.From, result.To = records[0].At, records[len(records)-1].At
+
+	sessions, open := reportLogSessions(records)
+	peaks := reportLogPeaks(records)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		DELETE FROM license_checkouts
+		WHERE server_hostname = ? AND checked_in_at IS NOT NULL AND checked_out_at >= ? AND checked_in_at <= ?
+	`
+	if _, err := tx.ExecContext(ctx, tx.Rebind(query), hostname, result.From, result.To); err != nil {
+		return result, fmt.Errorf("failed to clear polled checkouts: %w", err)
+	}
+
+	for _, session := range sessions {
+		query := `
+			INSERT INTO license_checkouts (server_hostname, feature_name, username, host, checked_out_at, last_seen, checked_in_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`
+		_, err := tx.ExecContext(ctx, tx.Rebind(query), hostname, session.feature, s.cipher.Encrypt(session.user),
+			s.cipher.Encrypt(session.host), session.out, session.in, session.in)
+		if err != nil {
+			return result, fmt.Errorf("failed to record checkout: %w", err)
+		}
+	}
+
+	stmt, err := tx.PreparexContext(ctx, s.dialect.InsertIgnoreUsage())
+	if err != nil {
+		return result, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, peak := range peaks {
+		date, timeStr := peak.at.Format("2006-01-02"), peak.at.Format("15:04:00")
+		query := `
+			UPDATE feature_usage SET users_count = ?
+			WHERE server_hostname = ? AND feature_name = ? AND date = ? AND time = ? AND users_count < ?
+		`
+		if _, err := tx.ExecContext(ctx, tx.Rebind(query), peak.users, hostname, peak.feature, date, timeStr, peak.users); err != nil {
+			return result, fmt.Errorf("failed to raise usage for %s: %w", peak.feature, err)
+		}
+		if _, err := stmt.ExecContext(ctx, hostname, peak.feature, date, timeStr, peak.users, 0); err != nil {
+			return result, fmt.Errorf("failed to record usage for %s: %w", peak.feature, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+	result.Sessions, result.Open, result.Samples = len(sessions), open, len(peaks)
+
+	if _, err := s.BackfillRollups(ctx, result.From, result.To, ""); err != nil {
+		return result, err
+	}
+	return result, nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/parsers"
+)
+
+func TestImportReportLog(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	// bob held a seat from before the log started; alice's second checkout
+	// lasts two minutes, between polls
+	log := `
+2024/06/01 09:10:00 OUT solver alice ws1 handle=1
+2024/06/01 09:30:00 OUT solver alice ws1 handle=2
+2024/06/01 09:32:00 IN  solver alice ws1 handle=2
+2024/06/01 09:40:00 IN  solver bob   ws2 handle=7
+2024/06/01 10:15:00 IN  solver alice ws1 handle=1
+2024/06/01 10:20:00 OUT solver carol ws3 handle=3
+2024/06/01 10:25:00 DENIED solver dave ws4 reason="Licensed number of users already reached."
+`
+	records, err := parsers.ParseReportLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("ParseReportLog failed: %v", err)
+	}
+
+	// A polled session within the log is replaced, one after it is kept
+	polled := `
+		INSERT INTO license_checkouts (server_hostname, feature_name, username, host, checked_out_at, last_seen, checked_in_at)
+		VALUES ('27000@a', 'solver', 'alice', 'ws1', ?, ?, ?)
+	`
+	at := func(clock string) time.Time {
+		t, _ := time.ParseInLocation("2006-01-02 15:04", "2024-06-01 "+clock, time.Local)
+		return t
+	}
+	db.MustExec(polled, at("09:15"), at("10:15"), at("10:15"))
+	db.MustExec(polled, at("11:00"), at("11:30"), at("11:30"))
+	db.MustExec(`INSERT INTO feature_usage (server_hostname, feature_name, date, time, users_count) VALUES ('27000@a', 'solver', '2024-06-01', '09:30:00', 1)`)
+
+	for i := 0; i < 2; i++ {
+		result, err := storage.ImportReportLog(ctx, "27000@a", records)
+		if err != nil {
+			t.Fatalf("import %d: ImportReportLog failed: %v", i, err)
+		}
+		if result.Records != 7 || result.Sessions != 2 || result.Open != 1 || result.Samples != 2 {
+			t.Fatalf("import %d: unexpected result %+v", i, result)
+		}
+	}
+
+	var sessions []struct {
+		Username     string    `db:"username"`
+		CheckedOutAt time.Time `db:"checked_out_at"`
+		CheckedInAt  time.Time `db:"checked_in_at"`
+	}
+	if err := db.Select(&sessions, `SELECT username, checked_out_at, checked_in_at FROM license_checkouts ORDER BY checked_out_at`); err != nil {
+		t.Fatalf("Failed to read checkouts: %v", err)
+	}
+	if len(sessions) != 3 {
+		t.Fatalf("Expected 2 exact sessions and the later polled one, got %+v", sessions)
+	}
+	if !sessions[0].CheckedOutAt.Equal(at("09:10")) || !sessions[0].CheckedInAt.Equal(at("10:15")) ||
+		!sessions[1].CheckedOutAt.Equal(at("09:30")) || !sessions[1].CheckedInAt.Equal(at("09:32")) {
+		t.Errorf("Unexpected sessions %+v", sessions)
+	}
+
+	// bob's seat counts from the start: 2 in use at 09:10, 3 at 09:30
+	var samples []struct {
+		Time  string `db:"time"`
+		Users int    `db:"users_count"`
+	}
+	if err := db.Select(&samples, `SELECT time, users_count FROM feature_usage ORDER BY time`); err != nil {
+		t.Fatalf("Failed to read usage: %v", err)
+	}
+	if len(samples) != 2 || samples[0].Users != 3 || !strings.Contains(samples[0].Time, "09:30") ||
+		samples[1].Users != 1 || !strings.Contains(samples[1].Time, "10:00") {
+		t.Errorf("Expected the polled sample raised to the 09:00 peak and a 10:00 peak, got %+v", samples)
+	}
+}
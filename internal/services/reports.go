@@ -0,0 +1,346 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/robfig/cron/v3"
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+	"licet/internal/models"
+	"licet/internal/util"
+)
+
+// ErrUnknownReport is returned for report names without a schedule
+var ErrUnknownReport = errors.New("unknown report")
+
+// Report formats
+const (
+	ReportFormatHTML = "html"
+	ReportFormatPDF  = "pdf"
+)
+
+// reportPeriods maps report periods to the days of data they cover and their
+// default cron schedule
+var reportPeriods = map[string]struct {
+	days int
+	cron string
+}{
+	"weekly":  {7, "0 6 * * 1"},
+	"monthly": {30, "0 6 1 * *"},
+}
+
+var reportNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ReportService renders scheduled capacity and utilization reports from the
+// enhanced analytics and emails them to their recipients
+type ReportService struct {
+	db        *sqlx.DB
+	cfg       *config.Config
+	enhanced  *EnhancedAnalyticsService
+	schedules []config.ReportSchedule
+}
+
+// Report is a rendered report
+type Report struct {
+	Name        string
+	Subject     string
+	Filename    string
+	ContentType string
+	Body        []byte
+	Text        string // Plain text summary, the email body of PDF reports
+}
+
+// reportData is what the report templates render
+type reportData struct {
+	Title       string
+	Period      string
+	Days        int
+	GeneratedAt time.Time
+	Capacity    *models.CapacityPlanningReport
+	Utilization []UtilizationWithTrend
+	Brand       config.BrandingConfig
+}
+
+// NewReportService validates the report schedules and fills in their defaults
+func NewReportService(db *sqlx.DB, cfg *config.Config, enhanced *EnhancedAnalyticsService) (*ReportService, error) {
+	s := &ReportService{db: db, cfg: cfg, enhanced: enhanced}
+	seen := make(map[string]bool)
+	for _, schedule := range cfg.Reports.Schedules {
+		if !reportNamePattern.MatchString(schedule.Name) {
+			return nil, fmt.Errorf("invalid report name %q: use letters, digits, - and _", schedule.Name)
+		}
+		if seen[schedule.Name] {
+			return nil, fmt.Errorf("duplicate report name %q", schedule.Name)
+		}
+		seen[schedule.Name] = true
+
+		period, ok := reportPeriods[schedule.Period]
+		if !ok {
+			return nil, fmt.Errorf("report %s: invalid period %q, expected weekly or monthly", schedule.Name, schedule.Period)
+		}
+		if schedule.Cron == "" {
+			schedule.Cron = period.cron
+		}
+		if _, err := cron.ParseStandard(schedule.Cron); err != nil {
+			return nil, fmt.Errorf("report %s: invalid cron schedule %q: %w", schedule.Name, schedule.Cron, err)
+		}
+		switch schedule.Format {
+		case "":
+			schedule.Format = ReportFormatHTML
+		case ReportFormatHTML, ReportFormatPDF:
+		default:
+			return nil, fmt.Errorf("report %s: invalid format %q, expected html or pdf", schedule.Name, schedule.Format)
+		}
+		s.schedules = append(s.schedules, schedule)
+	}
+	return s, nil
+}
+
+// Schedules returns the report schedules with their defaults filled in
+func (s *ReportService) Schedules() []config.ReportSchedule {
+	return s.schedules
+}
+
+// schedule returns the schedule of a report by name
+func (s *ReportService) schedule(name string) (config.ReportSchedule, error) {
+	for _, schedule := range s.schedules {
+		if schedule.Name == name {
+			return schedule, nil
+		}
+	}
+	return config.ReportSchedule{}, ErrUnknownReport
+}
+
+// Render generates the named report from current data
+func (s *ReportService) Render(ctx context.Context, name string) (*Report, error) {
+	schedule, err := s.schedule(name)
+	if err != nil {
+		return nil, err
+	}
+	days := reportPeriods[schedule.Period].days
+
+	capacity, err := s.enhanced.GenerateCapacityReport(ctx, days)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate capacity report: %w", err)
+	}
+	utilization, err := s.enhanced.GetCurrentUtilizationWithTrend(ctx, "", days)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get utilization: %w", err)
+	}
+
+	data := reportData{
+		Title:       fmt.Sprintf("License Capacity Report (%s)", schedule.Period),
+		Period:      schedule.Period,
+		Days:        days,
+		GeneratedAt: time.Now(),
+		Capacity:    capacity,
+		Utilization: utilization,
+		Brand:       s.cfg.Branding,
+	}
+
+	report := &Report{
+		Name:    name,
+		Subject: fmt.Sprintf("%s: %d features at capacity, %d underutilized", data.Title, capacity.FeaturesAtCapacity, capacity.FeaturesUnderutilized),
+		Text:    formatReportText(data),
+	}
+	stamp := data.GeneratedAt.Format("20060102")
+
+	switch schedule.Format {
+	case ReportFormatPDF:
+		var buf bytes.Buffer
+		if err := renderReportPDF(data).Write(&buf); err != nil {
+			return nil, fmt.Errorf("failed to render report: %w", err)
+		}
+		report.Body = buf.Bytes()
+		report.ContentType = "application/pdf"
+		report.Filename = fmt.Sprintf("%s_%s.pdf", name, stamp)
+	default:
+		var buf bytes.Buffer
+		if err := reportTemplate.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render report: %w", err)
+		}
+		report.Body = buf.Bytes()
+		report.ContentType = "text/html; charset=utf-8"
+		report.Filename = fmt.Sprintf("%s_%s.html", name, stamp)
+	}
+	return report, nil
+}
+
+// Send renders the named report and emails it to its recipients: HTML
+// reports as the email body, PDF reports as an attachment
+func (s *ReportService) Send(ctx context.Context, name string) error {
+	schedule, err := s.schedule(name)
+	if err != nil {
+		return err
+	}
+	if !s.cfg.Email.Enabled {
+		return fmt.Errorf("report %s: email is not enabled", name)
+	}
+	recipients := schedule.Recipients
+	if len(recipients) == 0 {
+		recipients = s.cfg.Email.To
+	}
+	if len(recipients) == 0 {
+		return fmt.Errorf("report %s: no recipients", name)
+	}
+
+	report, err := s.Render(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	mailer := NewAlertService(s.db, s.cfg)
+	if schedule.Format == ReportFormatPDF {
+		err = mailer.SendEmailAttachment(report.Subject, report.Text, recipients, report.Filename, report.Body)
+	} else {
+		err = mailer.SendHTMLEmail(report.Subject, string(report.Body), report.Text, recipients)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Report %s sent to %d recipients", name, len(recipients))
+	return nil
+}
+
+// formatReportText renders the summary of a report as plain text
+func formatReportText(data reportData) string {
+	var b strings.Builder
+	c := data.Capacity
+
+	fmt.Fprintf(&b, "%s\n\nLast %d days, generated %s\n\n", data.Title, data.Days, data.GeneratedAt.Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "Servers: %d\nFeatures: %d\nAt capacity: %d\nUnderutilized: %d\n",
+		c.TotalServers, c.TotalFeatures, c.FeaturesAtCapacity, c.FeaturesUnderutilized)
+
+	if len(c.Recommendations) > 0 {
+		b.WriteString("\nRecommendations:\n")
+		for _, r := range c.Recommendations {
+			fmt.Fprintf(&b, "  [%s] %s - %s\n", r.Priority, r.Title, r.Description)
+		}
+	}
+
+	fmt.Fprintf(&b, "\n--\n%s\n", data.Brand.Name())
+	return b.String()
+}
+
+// renderReportPDF lays out a report as a PDF document
+func renderReportPDF(data reportData) *util.PDFDocument {
+	doc := util.NewPDFDocument()
+	doc.SetHeader(data.Brand.Name())
+	c := data.Capacity
+
+	doc.Heading(data.Title)
+	doc.Text(fmt.Sprintf("Last %d days, generated %s", data.Days, data.GeneratedAt.Format("2006-01-02 15:04")))
+	doc.Table([]string{"Servers", "Features", "At capacity", "Underutilized"}, [][]string{{
+		fmt.Sprint(c.TotalServers), fmt.Sprint(c.TotalFeatures),
+		fmt.Sprint(c.FeaturesAtCapacity), fmt.Sprint(c.FeaturesUnderutilized),
+	}})
+
+	if len(c.Recommendations) > 0 {
+		doc.Heading("Recommendations")
+		for _, r := range c.Recommendations {
+			doc.Text(fmt.Sprintf("[%s] %s: %s", r.Priority, r.Title, r.Description))
+		}
+	}
+
+	insightTable := func(title string, insights []models.CapacityInsight) {
+		if len(insights) == 0 {
+			return
+		}
+		doc.Heading(title)
+		rows := make([][]string, len(insights))
+		for i, in := range insights {
+			rows[i] = []string{in.FeatureName, in.ServerHostname, fmt.Sprint(in.TotalLicenses),
+				fmt.Sprintf("%.1f", in.AvgUsage), fmt.Sprint(in.PeakUsage), fmt.Sprintf("%.1f%%", in.UtilizationPct), in.Recommendation}
+		}
+		doc.Table([]string{"Feature", "Server", "Total", "Avg", "Peak", "Util", "Recommendation"}, rows)
+	}
+	insightTable("High Utilization", c.HighUtilization)
+	insightTable("Low Utilization", c.LowUtilization)
+	insightTable("Trending Up", c.TrendingUp)
+	insightTable("Trending Down", c.TrendingDown)
+
+	if len(data.Utilization) > 0 {
+		doc.Heading("Utilization")
+		rows := make([][]string, len(data.Utilization))
+		for i, u := range data.Utilization {
+			rows[i] = []string{u.FeatureName, u.ServerHostname, fmt.Sprint(u.TotalLicenses),
+				fmt.Sprintf("%.1f", u.AvgUsage), fmt.Sprint(u.PeakUsage), fmt.Sprintf("%.1f%%", u.UtilizationPct), formatDaysToCapacity(u.DaysToCapacity)}
+		}
+		doc.Table([]string{"Feature", "Server", "Total", "Avg", "Peak", "Util", "Full in"}, rows)
+	}
+	return doc
+}
+
+// formatDaysToCapacity describes when a feature runs out of seats
+func formatDaysToCapacity(days int) string {
+	if days < 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"daysToCapacity": formatDaysToCapacity,
+	"insights": func(title string, insights []models.CapacityInsight) map[string]interface{} {
+		return map[string]interface{}{"Title": title, "Insights": insights}
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; font-size: 14px; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #f0f0f0; }
+td.num { text-align: right; }
+.brand { font-weight: bold; color: {{or .Brand.PrimaryColor "#555"}}; }
+</style>
+</head>
+<body>
+<p class="brand">{{.Brand.Name}}</p>
+<h1>{{.Title}}</h1>
+<p>Last {{.Days}} days, generated {{.GeneratedAt.Format "2006-01-02 15:04"}}</p>
+{{with .Capacity}}
+<table>
+<tr><th>Servers</th><th>Features</th><th>At capacity</th><th>Underutilized</th></tr>
+<tr><td class="num">{{.TotalServers}}</td><td class="num">{{.TotalFeatures}}</td><td class="num">{{.FeaturesAtCapacity}}</td><td class="num">{{.FeaturesUnderutilized}}</td></tr>
+</table>
+{{if .Recommendations}}
+<h2>Recommendations</h2>
+<ul>
+{{range .Recommendations}}<li><strong>[{{.Priority}}] {{.Title}}</strong>: {{.Description}}</li>
+{{end}}</ul>
+{{end}}
+{{template "insights" insights "High Utilization" .HighUtilization}}
+{{template "insights" insights "Low Utilization" .LowUtilization}}
+{{template "insights" insights "Trending Up" .TrendingUp}}
+{{template "insights" insights "Trending Down" .TrendingDown}}
+{{end}}
+{{if .Utilization}}
+<h2>Utilization</h2>
+<table>
+<tr><th>Feature</th><th>Server</th><th>Total</th><th>Avg</th><th>Peak</th><th>Util</th><th>Full in</th></tr>
+{{range .Utilization}}<tr><td>{{.FeatureName}}</td><td>{{.ServerHostname}}</td><td class="num">{{.TotalLicenses}}</td><td class="num">{{printf "%.1f" .AvgUsage}}</td><td class="num">{{.PeakUsage}}</td><td class="num">{{printf "%.1f%%" .UtilizationPct}}</td><td>{{daysToCapacity .DaysToCapacity}}</td></tr>
+{{end}}</table>
+{{end}}
+{{with .Brand.FooterText}}<p>{{.}}</p>{{end}}
+</body>
+</html>
+{{define "insights"}}{{if .Insights}}
+<h2>{{.Title}}</h2>
+<table>
+<tr><th>Feature</th><th>Server</th><th>Total</th><th>Avg</th><th>Peak</th><th>Util</th><th>Recommendation</th></tr>
+{{range .Insights}}<tr><td>{{.FeatureName}}</td><td>{{.ServerHostname}}</td><td class="num">{{.TotalLicenses}}</td><td class="num">{{printf "%.1f" .AvgUsage}}</td><td class="num">{{.PeakUsage}}</td><td class="num">{{printf "%.1f%%" .UtilizationPct}}</td><td>{{.Recommendation}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}`))
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestNewReportService_Validation(t *testing.T) {
+	tests := map[string][]config.ReportSchedule{
+		"name":      {{Name: "weekly report", Period: "weekly"}},
+		"duplicate": {{Name: "a", Period: "weekly"}, {Name: "a", Period: "monthly"}},
+		"period":    {{Name: "a", Period: "daily"}},
+		"cron":      {{Name: "a", Period: "weekly", Cron: "every monday"}},
+		"format":    {{Name: "a", Period: "weekly", Format: "docx"}},
+	}
+	for name, schedules := range tests {
+		cfg := &config.Config{Reports: config.ReportsConfig{Schedules: schedules}}
+		if _, err := NewReportService(nil, cfg, nil); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+
+	cfg := &config.Config{Reports: config.ReportsConfig{Schedules: []config.ReportSchedule{
+		{Name: "weekly", Period: "weekly"},
+		{Name: "monthly-pdf", Period: "monthly", Cron: "0 8 2 * *", Format: "pdf"},
+	}}}
+	s, err := NewReportService(nil, cfg, nil)
+	if err != nil {
+		t.Fatalf("NewReportService failed: %v", err)
+	}
+	schedules := s.Schedules()
+	if schedules[0].Cron != "0 6 * * 1" || schedules[0].Format != ReportFormatHTML {
+		t.Errorf("Expected weekly defaults, got %+v", schedules[0])
+	}
+	if schedules[1].Cron != "0 8 2 * *" || schedules[1].Format != ReportFormatPDF {
+		t.Errorf("Expected the configured schedule, got %+v", schedules[1])
+	}
+}
+
+func TestReportService_Render(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", TotalLicenses: 10},
+		{ServerHostname: "27000@a", Name: "viewer", TotalLicenses: 100},
+	})
+	if err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	for day := 0; day < 7; day++ {
+		date := time.Now().AddDate(0, 0, -day).Format("2006-01-02")
+		for feature, users := range map[string]int{"solver": 9, "viewer": 5} {
+			_, err := db.Exec(`INSERT INTO feature_usage (server_hostname, feature_name, date, time, users_count) VALUES (?, ?, ?, ?, ?)`,
+				"27000@a", feature, date, "12:00:00", users)
+			if err != nil {
+				t.Fatalf("Failed to insert usage: %v", err)
+			}
+		}
+	}
+
+	cfg := &config.Config{Reports: config.ReportsConfig{Schedules: []config.ReportSchedule{
+		{Name: "weekly", Period: "weekly"},
+		{Name: "weekly-pdf", Period: "weekly", Format: "pdf"},
+	}}}
+	s, err := NewReportService(db, cfg, NewEnhancedAnalyticsService(db, storage, "sqlite"))
+	if err != nil {
+		t.Fatalf("NewReportService failed: %v", err)
+	}
+
+	html, err := s.Render(ctx, "weekly")
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	body := string(html.Body)
+	if !strings.HasPrefix(html.ContentType, "text/html") || !strings.HasSuffix(html.Filename, ".html") {
+		t.Errorf("Unexpected HTML report %s %s", html.ContentType, html.Filename)
+	}
+	for _, want := range []string{"License Capacity Report (weekly)", "<h2>High Utilization</h2>", "<h2>Low Utilization</h2>", "<td>solver</td>", "90.0%"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected the HTML report to contain %q", want)
+		}
+	}
+	if !strings.Contains(html.Subject, "1 features at capacity, 1 underutilized") {
+		t.Errorf("Unexpected subject %q", html.Subject)
+	}
+
+	pdf, err := s.Render(ctx, "weekly-pdf")
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if pdf.ContentType != "application/pdf" || !bytes.HasPrefix(pdf.Body, []byte("%PDF-")) || !bytes.Contains(pdf.Body, []byte("(High Utilization) Tj")) {
+		t.Errorf("Unexpected PDF report %s %s", pdf.ContentType, pdf.Filename)
+	}
+	if !strings.Contains(pdf.Text, "At capacity: 1") {
+		t.Errorf("Unexpected text summary %q", pdf.Text)
+	}
+
+	if _, err := s.Render(ctx, "daily"); !errors.Is(err, ErrUnknownReport) {
+		t.Errorf("Expected ErrUnknownReport, got %v", err)
+	}
+	if err := s.Send(ctx, "weekly"); err == nil || !strings.Contains(err.Error(), "email is not enabled") {
+		t.Errorf("Expected sending without email to fail, got %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	"licet/internal/models"
+)
+
+// Kinds of resources with a stable id and UUID
+const (
+	ResourceServer  = "server"
+	ResourceFeature = "feature"
+)
+
+// ErrResourceNotFound is returned for ids and UUIDs no resource has
+var ErrResourceNotFound = errors.New("resource not found")
+
+// newUUID returns a random (version 4) UUID
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// RegisterResources gives a server and the named features of it a stable id
+// and UUID, unless they already have one
+func (s *StorageService) RegisterResources(ctx context.Context, hostname string, features []string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.registerResources(ctx, tx, hostname, features); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// registerResources registers a server and its features within a transaction
+func (s *StorageService) registerResources(ctx context.Context, tx *sqlx.Tx, hostname string, features []string) error {
+	var existing []struct {
+		Kind string `db:"kind"`
+		Name string `db:"name"`
+	}
+	query := `SELECT kind, name FROM resource_ids WHERE server_hostname = ?`
+	if err := tx.SelectContext(ctx, &existing, tx.Rebind(query), hostname); err != nil {
+		return fmt.Errorf("failed to get resource ids of %s: %w", hostname, err)
+	}
+	known := make(map[string]bool, len(existing))
+	for _, r := range existing {
+		known[r.Kind+"|"+r.Name] = true
+	}
+
+	now := s.clock.Now()
+	register := func(kind, name string) error {
+		if known[kind+"|"+name] {
+			return nil
+		}
+		known[kind+"|"+name] = true
+		query := `INSERT INTO resource_ids (kind, server_hostname, name, uuid, created_at) VALUES (?, ?, ?, ?, ?)`
+		if _, err := tx.ExecContext(ctx, tx.Rebind(query), kind, hostname, name, newUUID(), now); err != nil {
+			return fmt.Errorf("failed to register %s %s: %w", kind, name, err)
+		}
+		return nil
+	}
+
+	if err := register(ResourceServer, ""); err != nil {
+		return err
+	}
+	for _, name := range features {
+		if err := register(ResourceFeature, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterExistingResources registers the given servers and every feature
+// stored so far, so resources collected before ids existed have one too
+func (s *StorageService) RegisterExistingResources(ctx context.Context, hostnames []string) error {
+	var stored []struct {
+		ServerHostname string `db:"server_hostname"`
+		Name           string `db:"name"`
+	}
+	query := `SELECT DISTINCT server_hostname, name FROM features ORDER BY server_hostname, name`
+	if err := s.db.SelectContext(ctx, &stored, query); err != nil {
+		return fmt.Errorf("failed to get stored features: %w", err)
+	}
+
+	features := make(map[string][]string)
+	for _, hostname := range hostnames {
+		features[hostname] = nil
+	}
+	for _, f := range stored {
+		features[f.ServerHostname] = append(features[f.ServerHostname], f.Name)
+	}
+	for hostname, names := range features {
+		if err := s.RegisterResources(ctx, hostname, names); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetResource returns a resource by its UUID or its id
+func (s *StorageService) GetResource(ctx context.Context, id string) (models.Resource, error) {
+	var resource models.Resource
+	query := `SELECT id, kind, server_hostname, name, uuid, created_at FROM resource_ids WHERE uuid = ?`
+	args := []interface{}{id}
+	if n, err := strconv.ParseInt(id, 10, 64); err == nil {
+		query = `SELECT id, kind, server_hostname, name, uuid, created_at FROM resource_ids WHERE id = ?`
+		args = []interface{}{n}
+	}
+
+	err := s.db.GetContext(ctx, &resource, s.db.Rebind(query), args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return resource, ErrResourceNotFound
+	}
+	return resource, err
+}
+
+// resourceIDs returns the resources of a kind, of one server or of all when
+// hostname is empty, keyed by server and name
+func (s *StorageService) resourceIDs(ctx context.Context, kind, hostname string) (map[string]models.Resource, error) {
+	var resources []models.Resource
+	query := `SELECT id, kind, server_hostname, name, uuid, created_at FROM resource_ids WHERE kind = ?`
+	args := []interface{}{kind}
+	if hostname != "" {
+		query += ` AND server_hostname = ?`
+		args = append(args, hostname)
+	}
+	if err := s.db.SelectContext(ctx, &resources, s.db.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to get resource ids: %w", err)
+	}
+
+	byKey := make(map[string]models.Resource, len(resources))
+	for _, r := range resources {
+		byKey[r.ServerHostname+"|"+r.Name] = r
+	}
+	return byKey, nil
+}
+
+// SetServerIDs fills in the stable ids and UUIDs of servers
+func (s *StorageService) SetServerIDs(ctx context.Context, servers []models.LicenseServer) error {
+	ids, err := s.resourceIDs(ctx, ResourceServer, "")
+	if err != nil {
+		return err
+	}
+	for i := range servers {
+		if r, ok := ids[servers[i].Hostname+"|"]; ok {
+			servers[i].ResourceID, servers[i].UUID = r.ID, r.UUID
+		}
+	}
+	return nil
+}
+
+// SetFeatureIDs fills in the stable ids and UUIDs of the features of a
+// server. Versions of a feature share its id.
+func (s *StorageService) SetFeatureIDs(ctx context.Context, hostname string, features []models.Feature) error {
+	ids, err := s.resourceIDs(ctx, ResourceFeature, hostname)
+	if err != nil {
+		return err
+	}
+	for i := range features {
+		if r, ok := ids[features[i].ServerHostname+"|"+features[i].Name]; ok {
+			features[i].ResourceID, features[i].UUID = r.ID, r.UUID
+		}
+	}
+	return nil
+}
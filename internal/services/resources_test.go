@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"licet/internal/models"
+)
+
+func TestResourceIDs(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	store := func() []models.Feature {
+		t.Helper()
+		features := []models.Feature{
+			{ServerHostname: "27000@a", Name: "CAD/3D", Version: "1.0", TotalLicenses: 5},
+			{ServerHostname: "27000@a", Name: "CAD/3D", Version: "2.0", TotalLicenses: 5, ExpirationDate: time.Now().AddDate(1, 0, 0)},
+			{ServerHostname: "27000@a", Name: "viewer", TotalLicenses: 2},
+		}
+		if err := storage.StoreFeatures(ctx, features); err != nil {
+			t.Fatalf("StoreFeatures failed: %v", err)
+		}
+		if err := storage.SetFeatureIDs(ctx, "27000@a", features); err != nil {
+			t.Fatalf("SetFeatureIDs failed: %v", err)
+		}
+		return features
+	}
+
+	first := store()
+	if first[0].UUID == "" || first[0].UUID != first[1].UUID || first[0].ResourceID != first[1].ResourceID {
+		t.Fatalf("Expected both versions to share one id, got %+v", first[:2])
+	}
+	if first[2].UUID == first[0].UUID {
+		t.Error("Expected features to have their own UUIDs")
+	}
+	if !uuidV4(first[0].UUID) {
+		t.Errorf("Expected a version 4 UUID, got %s", first[0].UUID)
+	}
+
+	// Ids survive the rows being stored again or replaced
+	db.MustExec(`DELETE FROM features`)
+	if again := store(); again[0].UUID != first[0].UUID || again[0].ResourceID != first[0].ResourceID {
+		t.Errorf("Expected stable ids, got %s then %s", first[0].UUID, again[0].UUID)
+	}
+
+	for _, id := range []string{first[0].UUID, strconv.FormatInt(first[0].ResourceID, 10)} {
+		resource, err := storage.GetResource(ctx, id)
+		if err != nil {
+			t.Fatalf("GetResource(%s) failed: %v", id, err)
+		}
+		if resource.Kind != ResourceFeature || resource.ServerHostname != "27000@a" || resource.Name != "CAD/3D" {
+			t.Errorf("Unexpected resource for %s: %+v", id, resource)
+		}
+	}
+	if _, err := storage.GetResource(ctx, "00000000-0000-4000-8000-000000000000"); !errors.Is(err, ErrResourceNotFound) {
+		t.Errorf("Expected ErrResourceNotFound, got %v", err)
+	}
+
+	// Configured servers get ids before their first collection
+	if err := storage.RegisterExistingResources(ctx, []string{"27000@a", "5053@b"}); err != nil {
+		t.Fatalf("RegisterExistingResources failed: %v", err)
+	}
+	servers := []models.LicenseServer{{Hostname: "27000@a"}, {Hostname: "5053@b"}}
+	if err := storage.SetServerIDs(ctx, servers); err != nil {
+		t.Fatalf("SetServerIDs failed: %v", err)
+	}
+	if servers[0].UUID == "" || servers[1].UUID == "" || servers[0].UUID == servers[1].UUID {
+		t.Errorf("Expected distinct server UUIDs, got %+v", servers)
+	}
+	var count int
+	db.Get(&count, `SELECT COUNT(*) FROM resource_ids`)
+	if count != 4 {
+		t.Errorf("Expected 2 servers and 2 features, got %d resources", count)
+	}
+}
+
+// uuidV4 reports whether s is a formatted random UUID
+func uuidV4(s string) bool {
+	return len(s) == 36 && s[8] == '-' && s[13] == '-' && s[14] == '4' && s[18] == '-' && s[23] == '-' &&
+		(s[19] == '8' || s[19] == '9' || s[19] == 'a' || s[19] == 'b')
+}
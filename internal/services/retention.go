@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/models"
+)
+
+// retentionRunsLimit is the default number of retention runs listed
+const retentionRunsLimit = 50
+
+// ApplyRetention removes the rows of each table of the policy that are older
+// than its maximum age in days, or only counts them on a dry run, and records
+// the run in the retention history. A table that fails does not stop the
+// others; its error is recorded with the run.
+func (s *DBStatsService) ApplyRetention(ctx context.Context, policy map[string]int, dryRun bool, triggeredBy string) (*models.RetentionRun, error) {
+	s.retentionMu.Lock()
+	defer s.retentionMu.Unlock()
+
+	run := &models.RetentionRun{
+		TriggeredBy: triggeredBy,
+		DryRun:      dryRun,
+		Results:     make([]models.RetentionResult, 0, len(policy)),
+		StartedAt:   s.clock.Now(),
+	}
+
+	tables := make([]string, 0, len(policy))
+	for table := range policy {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	failed := 0
+	for _, table := range tables {
+		result := models.RetentionResult{TableName: table, MaxAgeDays: policy[table]}
+		if dryRun {
+			count, err := s.countOldData(ctx, table, result.MaxAgeDays)
+			if err != nil {
+				result.Error = err.Error()
+			}
+			result.RowsDeleted = count
+		} else {
+			cleanup, err := s.CleanupOldData(ctx, table, result.MaxAgeDays)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.RowsDeleted = cleanup.RowsDeleted
+			}
+		}
+		if result.Error != "" {
+			log.Errorf("Retention of %s failed: %s", table, result.Error)
+			failed++
+		}
+		run.RowsDeleted += result.RowsDeleted
+		run.Results = append(run.Results, result)
+	}
+	if failed > 0 {
+		run.Error = fmt.Sprintf("%d of %d tables failed", failed, len(tables))
+	}
+	run.CompletedAt = s.clock.Now()
+
+	results, err := json.Marshal(run.Results)
+	if err != nil {
+		return nil, err
+	}
+	run.ResultsJSON = string(results)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO retention_runs (triggered_by, dry_run, rows_deleted, results, error, started_at, completed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	if _, err := tx.ExecContext(ctx, tx.Rebind(query), run.TriggeredBy, run.DryRun, run.RowsDeleted,
+		run.ResultsJSON, run.Error, run.StartedAt, run.CompletedAt); err != nil {
+		return nil, fmt.Errorf("failed to record retention run: %w", err)
+	}
+	query = `SELECT id FROM retention_runs WHERE started_at = ? ORDER BY id DESC LIMIT 1`
+	if err := tx.GetContext(ctx, &run.ID, tx.Rebind(query), run.StartedAt); err != nil {
+		return nil, fmt.Errorf("failed to record retention run: %w", err)
+	}
+	return run, tx.Commit()
+}
+
+// GetRetentionRuns returns the latest retention runs, newest first. A limit
+// of 0 or less returns the default number of runs.
+func (s *DBStatsService) GetRetentionRuns(ctx context.Context, limit int) ([]models.RetentionRun, error) {
+	if limit <= 0 {
+		limit = retentionRunsLimit
+	}
+	runs := []models.RetentionRun{}
+	query := `SELECT * FROM retention_runs ORDER BY started_at DESC, id DESC LIMIT ?`
+	if err := s.db.SelectContext(ctx, &runs, s.db.Rebind(query), limit); err != nil {
+		return nil, fmt.Errorf("failed to get retention runs: %w", err)
+	}
+	for i := range runs {
+		if err := json.Unmarshal([]byte(runs[i].ResultsJSON), &runs[i].Results); err != nil {
+			return nil, fmt.Errorf("failed to decode retention run %d: %w", runs[i].ID, err)
+		}
+	}
+	return runs, nil
+}
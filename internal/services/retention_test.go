@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"licet/internal/clock"
+	"licet/internal/config"
+)
+
+func TestApplyRetention(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	now := time.Date(2025, 3, 14, 3, 30, 0, 0, time.Local)
+	s := NewDBStatsService(db, config.DatabaseConfig{Type: "sqlite"})
+	s.SetClock(clock.NewFixed(now))
+
+	for _, age := range []int{400, 200, 10} {
+		at := now.AddDate(0, 0, -age)
+		if _, err := db.Exec("INSERT INTO audit_log (created_at, actor, action) VALUES (?, 'admin', 'test')", at); err != nil {
+			t.Fatalf("Failed to insert audit entry: %v", err)
+		}
+		if _, err := db.Exec("INSERT INTO alert_events (datetime, type, hostname) VALUES (?, 'down', '27000@a')", at); err != nil {
+			t.Fatalf("Failed to insert alert event: %v", err)
+		}
+	}
+	count := func(table string) int {
+		t.Helper()
+		var n int
+		if err := db.Get(&n, "SELECT COUNT(*) FROM "+table); err != nil {
+			t.Fatalf("Failed to count %s: %v", table, err)
+		}
+		return n
+	}
+	policy := map[string]int{"audit_log": 365, "alert_events": 90}
+
+	dry, err := s.ApplyRetention(ctx, policy, true, "admin")
+	if err != nil {
+		t.Fatalf("ApplyRetention (dry run) failed: %v", err)
+	}
+	if dry.RowsDeleted != 3 || count("audit_log") != 3 || count("alert_events") != 3 {
+		t.Errorf("Expected a dry run to count 3 rows and keep them, got %d", dry.RowsDeleted)
+	}
+
+	run, err := s.ApplyRetention(ctx, policy, false, "schedule")
+	if err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if run.RowsDeleted != 3 || run.Error != "" {
+		t.Errorf("Expected 3 rows removed without errors, got %d (%s)", run.RowsDeleted, run.Error)
+	}
+	if count("audit_log") != 2 || count("alert_events") != 1 {
+		t.Errorf("Expected 2 audit entries and 1 alert event left, got %d and %d", count("audit_log"), count("alert_events"))
+	}
+	// Tables are applied in name order
+	if len(run.Results) != 2 || run.Results[0].TableName != "alert_events" || run.Results[0].RowsDeleted != 2 {
+		t.Errorf("Unexpected results %+v", run.Results)
+	}
+
+	// A failing table is recorded without stopping the others
+	failed, err := s.ApplyRetention(ctx, map[string]int{"servers": 1, "alert_events": 1}, false, "admin")
+	if err != nil {
+		t.Fatalf("ApplyRetention (failing table) failed: %v", err)
+	}
+	if failed.Error == "" || failed.Results[1].Error == "" || failed.Results[0].RowsDeleted != 1 {
+		t.Errorf("Expected the servers table to fail and alert events to be removed, got %+v", failed)
+	}
+
+	runs, err := s.GetRetentionRuns(ctx, 0)
+	if err != nil {
+		t.Fatalf("GetRetentionRuns failed: %v", err)
+	}
+	if len(runs) != 3 {
+		t.Fatalf("Expected 3 runs, got %d", len(runs))
+	}
+	if runs[0].ID != failed.ID || runs[2].ID != dry.ID || !runs[2].DryRun || runs[1].TriggeredBy != "schedule" {
+		t.Errorf("Expected runs newest first, got %+v", runs)
+	}
+	if len(runs[1].Results) != 2 || runs[1].Results[1].TableName != "audit_log" || runs[1].Results[1].RowsDeleted != 1 {
+		t.Errorf("Expected the results of a run to be stored, got %+v", runs[1].Results)
+	}
+
+	if runs, err := s.GetRetentionRuns(ctx, 1); err != nil || len(runs) != 1 {
+		t.Errorf("Expected 1 run with a limit, got %d (%v)", len(runs), err)
+	}
+}
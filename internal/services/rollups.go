@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Raw usage samples of whole days are rolled up per hour and per day once
+// they are older than rollup.after_days. Queries over a period read the
+// rolled-up days from the rollup tables and later days from the raw samples,
+// so they stay fast once raw samples pile up or are removed by retention.
+// Samples stored for a day after it was rolled up are not included until the
+// day is backfilled.
+
+// usageAggregateColumns are the aggregates of a rollup row, as computed from
+// raw samples
+const usageAggregateColumns = `COUNT(*) AS samples, SUM(users_count) AS sum_users,
+	SUM(users_count * users_count) AS sum_squares, MAX(users_count) AS peak_users,
+	MIN(users_count) AS min_users, MAX(queued_count) AS peak_queued`
+
+// rollupWatermark returns the last rolled-up day, or "" when nothing has been
+// rolled up
+func (s *StorageService) rollupWatermark(ctx context.Context) (string, error) {
+	var last interface{}
+	if err := s.db.GetContext(ctx, &last, "SELECT MAX(date) FROM feature_usage_daily"); err != nil {
+		return "", fmt.Errorf("failed to get rollup watermark: %w", err)
+	}
+	if last == nil {
+		return "", nil
+	}
+	return scannedDate(last).Format("2006-01-02"), nil
+}
+
+// scannedDate parses a date column, which scans as time.Time or text
+// depending on the driver and query
+func scannedDate(v interface{}) time.Time {
+	return eventTimestamp(v, nil)
+}
+
+// RollupUsage aggregates the raw usage of days more than afterDays before
+// now that are not rolled up yet. It returns the number of feature days
+// rolled up.
+func (s *StorageService) RollupUsage(ctx context.Context, afterDays int, now time.Time) (int, error) {
+	watermark, err := s.rollupWatermark(ctx)
+	if err != nil {
+		return 0, err
+	}
+	until := now.AddDate(0, 0, -afterDays).Format("2006-01-02")
+	if watermark != "" && watermark >= until {
+		return 0, nil
+	}
+
+	where := " WHERE date < ?"
+	args := []interface{}{until}
+	if watermark != "" {
+		where += " AND date > ?"
+		args = append(args, watermark)
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rolled, err := s.insertRollups(ctx, tx, where, args)
+	if err != nil {
+		return 0, err
+	}
+	return rolled, tx.Commit()
+}
+
+// BackfillRollups recomputes the rollups of the days from `from` to `to`
+// from their raw samples, which picks up samples stored after a day was
+// rolled up. Days after the watermark are left to RollupUsage, and feature
+// days whose raw samples were removed by retention keep their rollups. An
+// empty feature recomputes all features. It returns the number of feature
+// days recomputed.
+func (s *StorageService) BackfillRollups(ctx context.Context, from, to time.Time, feature string) (int, error) {
+	watermark, err := s.rollupWatermark(ctx)
+	if err != nil {
+		return 0, err
+	}
+	first, last := from.Format("2006-01-02"), to.Format("2006-01-02")
+	if last > watermark {
+		last = watermark
+	}
+	if watermark == "" || first > last {
+		return 0, nil
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	where := " WHERE date >= ? AND date <= ?"
+	args := []interface{}{first, last}
+	if feature != "" {
+		where += " AND feature_name = ?"
+		args = append(args, feature)
+	}
+
+	for _, table := range []string{"feature_usage_hourly", "feature_usage_daily"} {
+		remove := fmt.Sprintf(`
+			DELETE FROM %[1]s%[2]s AND EXISTS (
+				SELECT 1 FROM feature_usage u
+				WHERE u.server_hostname = %[1]s.server_hostname
+					AND u.feature_name = %[1]s.feature_name AND u.date = %[1]s.date
+			)
+		`, table, where)
+		if _, err := tx.ExecContext(ctx, tx.Rebind(remove), args...); err != nil {
+			return 0, fmt.Errorf("failed to clear rollups of %s: %w", table, err)
+		}
+	}
+
+	rolled, err := s.insertRollups(ctx, tx, where, args)
+	if err != nil {
+		return 0, err
+	}
+	return rolled, tx.Commit()
+}
+
+// Backfill recomputes the rollups of the days from `from` to `to` and then
+// the trends of the servers as of the storage clock. It returns the number of
+// feature days recomputed and of trends stored.
+func (s *StorageService) Backfill(ctx context.Context, from, to time.Time, servers []string) (int, int, error) {
+	days, err := s.BackfillRollups(ctx, from, to, "")
+	if err != nil {
+		return 0, 0, err
+	}
+	trends, err := s.updateTrends(ctx, servers)
+	return days, trends, err
+}
+
+// Recompute regenerates the aggregates derived from raw usage for the days
+// from `from` to `to`, one day at a time: the hourly and daily rollups of
+// rolled-up days, which also hold the daily peaks that monthly high-water
+// marks are taken from, and then the trend caches of the servers. An empty
+// feature recomputes all features. progress, when set, is called after each
+// day with the number of feature days recomputed. Running it again gives the
+// same result. It returns the number of feature days recomputed and of
+// trends stored.
+func (s *StorageService) Recompute(ctx context.Context, from, to time.Time, feature string, servers []string, progress func(day time.Time, featureDays int)) (int, int, error) {
+	days := 0
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		n, err := s.BackfillRollups(ctx, day, day, feature)
+		if err != nil {
+			return days, 0, fmt.Errorf("failed to recompute %s: %w", day.Format("2006-01-02"), err)
+		}
+		days += n
+		if progress != nil {
+			progress(day, n)
+		}
+	}
+	trends, err := s.updateTrends(ctx, servers)
+	return days, trends, err
+}
+
+// updateTrends recomputes the trends of the servers as of the storage clock.
+// It returns the number of trends stored.
+func (s *StorageService) updateTrends(ctx context.Context, servers []string) (int, error) {
+	now := s.clock.Now()
+	trends := 0
+	for _, server := range servers {
+		n, err := s.UpdateFeatureTrends(ctx, server, DefaultTrendDays, now)
+		if err != nil {
+			return trends, fmt.Errorf("failed to update trends of %s: %w", server, err)
+		}
+		trends += n
+	}
+	return trends, nil
+}
+
+// insertRollups rolls up the raw usage matching where into the hourly and
+// daily tables. It returns the number of feature days rolled up.
+func (s *StorageService) insertRollups(ctx context.Context, tx *sqlx.Tx, where string, args []interface{}) (int, error) {
+	hourly := fmt.Sprintf(`
+		INSERT INTO feature_usage_hourly (server_hostname, feature_name, date, hour,
+			samples, sum_users, sum_squares, peak_users, min_users, peak_queued)
+		SELECT server_hostname, feature_name, date, %s AS hour, %s
+		FROM feature_usage%s
+		GROUP BY server_hostname, feature_name, date, hour
+	`, s.dialect.HourExtract(), usageAggregateColumns, where)
+	if _, err := tx.ExecContext(ctx, tx.Rebind(hourly), args...); err != nil {
+		return 0, fmt.Errorf("failed to roll up hourly usage: %w", err)
+	}
+
+	daily := fmt.Sprintf(`
+		INSERT INTO feature_usage_daily (server_hostname, feature_name, date,
+			samples, sum_users, sum_squares, peak_users, min_users, peak_queued)
+		SELECT server_hostname, feature_name, date, %s
+		FROM feature_usage%s
+		GROUP BY server_hostname, feature_name, date
+	`, usageAggregateColumns, where)
+	result, err := tx.ExecContext(ctx, tx.Rebind(daily), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to roll up daily usage: %w", err)
+	}
+	rolled, _ := result.RowsAffected()
+	return int(rolled), nil
+}
+
+// usageAggregates returns a query of the usage per feature and day, or per
+// feature, day and hour, since cutoff, and its arguments. Days up to the
+// watermark come from the rollup tables. Its columns are server_hostname,
+// feature_name, date, hour (when hourly), samples, sum_users, sum_squares,
+// peak_users, min_users and peak_queued. Empty server or feature match all.
+func (s *StorageService) usageAggregates(watermark string, hourly bool, cutoff time.Time, server, feature string) (string, []interface{}) {
+	filter := " WHERE date >= ?"
+	filterArgs := []interface{}{cutoff.Format("2006-01-02")}
+	if server != "" {
+		filter += " AND server_hostname = ?"
+		filterArgs = append(filterArgs, server)
+	}
+	if feature != "" {
+		filter += " AND feature_name = ?"
+		filterArgs = append(filterArgs, feature)
+	}
+
+	rollupTable, rollupHour, rawHour, groupBy := "feature_usage_daily", "", "", ""
+	if hourly {
+		rollupTable, rollupHour, rawHour, groupBy = "feature_usage_hourly", "hour, ", s.dialect.HourExtract()+" AS hour, ", ", hour"
+	}
+
+	raw := fmt.Sprintf(`
+		SELECT server_hostname, feature_name, date, %s%s
+		FROM feature_usage%s`, rawHour, usageAggregateColumns, filter)
+	args := append([]interface{}{}, filterArgs...)
+	if watermark != "" {
+		raw += " AND date > ?"
+		args = append(args, watermark)
+	}
+	raw += " GROUP BY server_hostname, feature_name, date" + groupBy
+
+	if watermark == "" || watermark < cutoff.Format("2006-01-02") {
+		return raw, args
+	}
+
+	rollup := fmt.Sprintf(`
+		SELECT server_hostname, feature_name, date, %ssamples, sum_users, sum_squares, peak_users, min_users, peak_queued
+		FROM %s%s AND date <= ?`, rollupHour, rollupTable, filter)
+	rollupArgs := append(append([]interface{}{}, filterArgs...), watermark)
+	return rollup + " UNION ALL " + raw, append(rollupArgs, args...)
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"licet/internal/clock"
+	"licet/internal/database"
+	"licet/internal/models"
+)
+
+// insertHourlyUsage stores a usage sample per hour of the working day over
+// the last days
+func insertHourlyUsage(t *testing.T, db *sqlx.DB, hostname, feature string, days int, now time.Time) {
+	t.Helper()
+	insert := database.NewDialect("sqlite").InsertIgnoreUsage()
+	for d := 0; d <= days; d++ {
+		date := now.AddDate(0, 0, -d).Format("2006-01-02")
+		for hour := 8; hour < 18; hour++ {
+			for _, minute := range []int{0, 30} {
+				users := (d*3 + hour + minute/30) % 7
+				if _, err := db.Exec(insert, hostname, feature, date, fmt.Sprintf("%02d:%02d:00", hour, minute), users, 0); err != nil {
+					t.Fatalf("Failed to insert usage: %v", err)
+				}
+			}
+		}
+	}
+}
+
+func TestRollupUsage(t *testing.T) {
+	db := newTestDB(t)
+	storage := NewStorageService(db, "sqlite")
+	ctx := context.Background()
+	now := time.Now()
+
+	insertHourlyUsage(t, db, "27000@a", "solver", 10, now)
+	insertHourlyUsage(t, db, "27000@a", "mesher", 10, now)
+
+	rolled, err := storage.RollupUsage(ctx, 2, now)
+	if err != nil {
+		t.Fatalf("RollupUsage failed: %v", err)
+	}
+	// Days 3 to 10 ago of two features
+	if rolled != 16 {
+		t.Errorf("Expected 16 feature days rolled up, got %d", rolled)
+	}
+
+	var hourly int
+	if err := db.Get(&hourly, "SELECT COUNT(*) FROM feature_usage_hourly"); err != nil {
+		t.Fatalf("Failed to count hourly rollups: %v", err)
+	}
+	if hourly != 16*10 {
+		t.Errorf("Expected %d hourly rollups, got %d", 16*10, hourly)
+	}
+
+	watermark, err := storage.rollupWatermark(ctx)
+	if err != nil {
+		t.Fatalf("rollupWatermark failed: %v", err)
+	}
+	if want := now.AddDate(0, 0, -3).Format("2006-01-02"); watermark != want {
+		t.Errorf("Expected watermark %s, got %s", want, watermark)
+	}
+
+	rolled, err = storage.RollupUsage(ctx, 2, now)
+	if err != nil {
+		t.Fatalf("RollupUsage (again) failed: %v", err)
+	}
+	if rolled != 0 {
+		t.Errorf("Expected nothing left to roll up, got %d", rolled)
+	}
+
+	rolled, err = storage.RollupUsage(ctx, 1, now)
+	if err != nil {
+		t.Fatalf("RollupUsage (next day) failed: %v", err)
+	}
+	if rolled != 2 {
+		t.Errorf("Expected the next day of two features rolled up, got %d", rolled)
+	}
+}
+
+func TestRollupUsage_QueriesUnchanged(t *testing.T) {
+	db := newTestDB(t)
+	storage := NewStorageService(db, "sqlite")
+	analytics := NewAnalyticsService(db, storage, "sqlite")
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", Version: "1.0", TotalLicenses: 10, ExpirationDate: now.AddDate(1, 0, 0)},
+	}); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	insertHourlyUsage(t, db, "27000@a", "solver", 40, now)
+
+	type results struct {
+		stats, heatmap, history, dailyHistory interface{}
+		slope, avg                            float64
+	}
+	query := func() results {
+		t.Helper()
+		stats, err := analytics.GetUtilizationStats(ctx, "", 30)
+		if err != nil {
+			t.Fatalf("GetUtilizationStats failed: %v", err)
+		}
+		heatmap, err := analytics.GetHeatmapData(ctx, "", 30)
+		if err != nil {
+			t.Fatalf("GetHeatmapData failed: %v", err)
+		}
+		history, err := analytics.GetUtilizationHistory(ctx, "27000@a", "solver", 30)
+		if err != nil {
+			t.Fatalf("GetUtilizationHistory failed: %v", err)
+		}
+		dailyHistory, err := analytics.GetUtilizationHistory(ctx, "27000@a", "solver", 120)
+		if err != nil {
+			t.Fatalf("GetUtilizationHistory failed: %v", err)
+		}
+		if _, err := storage.UpdateFeatureTrends(ctx, "27000@a", 30, now); err != nil {
+			t.Fatalf("UpdateFeatureTrends failed: %v", err)
+		}
+		trend, err := storage.GetFeatureTrend(ctx, "27000@a", "solver", 30)
+		if err != nil || trend == nil {
+			t.Fatalf("GetFeatureTrend failed: %v", err)
+		}
+		return results{stats, heatmap, history, dailyHistory, trend.Slope, trend.AvgUsage}
+	}
+
+	before := query()
+	if n := len(before.history.([]models.UtilizationHistoryPoint)); n != 31*10 {
+		t.Errorf("Expected a point per hour of usage, got %d", n)
+	}
+	if n := len(before.dailyHistory.([]models.UtilizationHistoryPoint)); n != 41 {
+		t.Errorf("Expected a point per day of usage, got %d", n)
+	}
+
+	if _, err := storage.RollupUsage(ctx, 2, now); err != nil {
+		t.Fatalf("RollupUsage failed: %v", err)
+	}
+	// Raw samples of rolled-up days may be removed by retention
+	if _, err := db.Exec("DELETE FROM feature_usage WHERE date < ?", now.AddDate(0, 0, -2).Format("2006-01-02")); err != nil {
+		t.Fatalf("Failed to delete raw usage: %v", err)
+	}
+
+	after := query()
+	if !reflect.DeepEqual(before.stats, after.stats) {
+		t.Errorf("Stats changed with rollups:\nbefore %+v\nafter  %+v", before.stats, after.stats)
+	}
+	if !reflect.DeepEqual(before.heatmap, after.heatmap) {
+		t.Errorf("Heatmap changed with rollups:\nbefore %+v\nafter  %+v", before.heatmap, after.heatmap)
+	}
+	if !reflect.DeepEqual(before.history, after.history) {
+		t.Error("Hourly history changed with rollups")
+	}
+	if !reflect.DeepEqual(before.dailyHistory, after.dailyHistory) {
+		t.Error("Daily history changed with rollups")
+	}
+	if before.slope != after.slope || before.avg != after.avg {
+		t.Errorf("Trend changed with rollups: slope %v -> %v, avg %v -> %v", before.slope, after.slope, before.avg, after.avg)
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	db := newTestDB(t)
+	storage := NewStorageService(db, "sqlite")
+	ctx := context.Background()
+	c := clock.NewFixed(time.Date(2025, 3, 14, 12, 0, 0, 0, time.Local))
+	storage.SetClock(c)
+	now := c.Now()
+	day := func(ago int) string { return now.AddDate(0, 0, -ago).Format("2006-01-02") }
+
+	if err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", Version: "1.0", TotalLicenses: 10, ExpirationDate: now.AddDate(1, 0, 0)},
+	}); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	insertHourlyUsage(t, db, "27000@a", "solver", 10, now)
+	if _, err := storage.RollupUsage(ctx, 2, now); err != nil {
+		t.Fatalf("RollupUsage failed: %v", err)
+	}
+
+	// A sample stored after its day was rolled up
+	insert := database.NewDialect("sqlite").InsertIgnoreUsage()
+	if _, err := db.Exec(insert, "27000@a", "solver", day(5), "20:00:00", 50, 0); err != nil {
+		t.Fatalf("Failed to insert late usage: %v", err)
+	}
+	peak := func(date string) int {
+		t.Helper()
+		var p int
+		if err := db.Get(&p, "SELECT peak_users FROM feature_usage_daily WHERE feature_name = 'solver' AND date = ?", date); err != nil {
+			t.Fatalf("Failed to get rollup of %s: %v", date, err)
+		}
+		return p
+	}
+	if peak(day(5)) == 50 {
+		t.Fatal("Expected the late sample not to be rolled up yet")
+	}
+
+	days, trends, err := storage.Backfill(ctx, now.AddDate(0, 0, -5), now, []string{"27000@a"})
+	if err != nil {
+		t.Fatalf("Backfill failed: %v", err)
+	}
+	// Days 5 to 3 ago; later days are not rolled up yet
+	if days != 3 || trends != 1 {
+		t.Errorf("Expected 3 feature days and 1 trend, got %d and %d", days, trends)
+	}
+	if peak(day(5)) != 50 {
+		t.Error("Expected the backfill to include the late sample")
+	}
+	var hours int
+	if err := db.Get(&hours, "SELECT COUNT(*) FROM feature_usage_hourly WHERE date = ?", day(5)); err != nil {
+		t.Fatalf("Failed to count hourly rollups: %v", err)
+	}
+	if hours != 11 {
+		t.Errorf("Expected 11 hourly rollups including the late sample, got %d", hours)
+	}
+
+	// Rollups of days whose raw samples are gone are kept
+	if _, err := db.Exec("DELETE FROM feature_usage WHERE date = ?", day(4)); err != nil {
+		t.Fatalf("Failed to delete raw usage: %v", err)
+	}
+	days, _, err = storage.Backfill(ctx, now.AddDate(0, 0, -10), now, nil)
+	if err != nil {
+		t.Fatalf("Backfill failed: %v", err)
+	}
+	if days != 7 {
+		t.Errorf("Expected 7 feature days recomputed, got %d", days)
+	}
+	peak(day(4))
+
+	// Trends are computed as of the storage clock
+	if trend, err := storage.GetFeatureTrend(ctx, "27000@a", "solver", DefaultTrendDays); err != nil || trend == nil {
+		t.Fatalf("Expected the backfilled trend, got %v (%v)", trend, err)
+	}
+	c.Advance(trendMaxAge + time.Hour)
+	if trend, err := storage.GetFeatureTrend(ctx, "27000@a", "solver", DefaultTrendDays); err != nil || trend != nil {
+		t.Errorf("Expected the trend to be out of date a day later, got %v (%v)", trend, err)
+	}
+}
+
+func TestRecompute(t *testing.T) {
+	db := newTestDB(t)
+	storage := NewStorageService(db, "sqlite")
+	ctx := context.Background()
+	now := time.Date(2025, 3, 14, 12, 0, 0, 0, time.Local)
+	storage.SetClock(clock.NewFixed(now))
+
+	insertHourlyUsage(t, db, "27000@a", "solver", 10, now)
+	insertHourlyUsage(t, db, "27000@a", "mesher", 10, now)
+	if _, err := storage.RollupUsage(ctx, 2, now); err != nil {
+		t.Fatalf("RollupUsage failed: %v", err)
+	}
+	rollups := func() []map[string]interface{} {
+		t.Helper()
+		var rows []map[string]interface{}
+		r, err := db.Queryx("SELECT * FROM feature_usage_daily ORDER BY feature_name, date")
+		if err != nil {
+			t.Fatalf("Failed to read rollups: %v", err)
+		}
+		defer r.Close()
+		for r.Next() {
+			row := map[string]interface{}{}
+			if err := r.MapScan(row); err != nil {
+				t.Fatalf("Failed to scan rollup: %v", err)
+			}
+			rows = append(rows, row)
+		}
+		return rows
+	}
+	before := rollups()
+
+	var progress []int
+	days, trends, err := storage.Recompute(ctx, now.AddDate(0, 0, -12), now, "solver", []string{"27000@a"},
+		func(day time.Time, featureDays int) { progress = append(progress, featureDays) })
+	if err != nil {
+		t.Fatalf("Recompute failed: %v", err)
+	}
+	// Days 10 to 3 ago of one feature; the last days are not rolled up yet
+	if days != 8 {
+		t.Errorf("Expected 8 feature days recomputed, got %d", days)
+	}
+	if trends != 2 {
+		t.Errorf("Expected 2 trends, got %d", trends)
+	}
+	if want := []int{0, 0, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("Expected progress %v, got %v", want, progress)
+	}
+	if after := rollups(); !reflect.DeepEqual(before, after) {
+		t.Error("Expected recomputing to reproduce the rollups")
+	}
+
+	if _, _, err := storage.Recompute(ctx, now.AddDate(0, 0, -12), now, "", nil, nil); err != nil {
+		t.Fatalf("Recompute (again) failed: %v", err)
+	}
+	if after := rollups(); !reflect.DeepEqual(before, after) {
+		t.Error("Expected recomputing again to reproduce the rollups")
+	}
+}
+
+func TestQueuedHistory(t *testing.T) {
+	db := newTestDB(t)
+	storage := NewStorageService(db, "sqlite")
+	analytics := NewAnalyticsService(db, storage, "sqlite")
+	ctx := context.Background()
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	analytics.SetClock(clock.NewFixed(now))
+
+	for d := 10; d >= 0; d-- {
+		storage.SetClock(clock.NewFixed(now.AddDate(0, 0, -d)))
+		err := storage.RecordUsage(ctx, []models.Feature{
+			{ServerHostname: "27000@a", Name: "solver", UsedLicenses: 4, QueuedRequests: d % 3},
+			{ServerHostname: "27000@a", Name: "solver", UsedLicenses: 0, QueuedRequests: 1},
+		})
+		if err != nil {
+			t.Fatalf("RecordUsage failed: %v", err)
+		}
+	}
+
+	check := func(days int) {
+		t.Helper()
+		history, err := analytics.GetUtilizationHistory(ctx, "27000@a", "solver", days)
+		if err != nil {
+			t.Fatalf("GetUtilizationHistory failed: %v", err)
+		}
+		if len(history) != 11 {
+			t.Fatalf("Expected a point per day, got %d", len(history))
+		}
+		// Both pools' requests are queued for the feature
+		for i, point := range history {
+			if want := (10-i)%3 + 1; point.QueuedCount != want {
+				t.Errorf("Expected %d queued requests on %s, got %d", want, point.Timestamp, point.QueuedCount)
+			}
+		}
+	}
+
+	check(30)
+	if _, err := storage.RollupUsage(ctx, 2, now); err != nil {
+		t.Fatalf("RollupUsage failed: %v", err)
+	}
+	check(120)
+}
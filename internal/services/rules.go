@@ -0,0 +1,420 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/models"
+)
+
+var (
+	// ErrAlertRuleNotFound is returned for alert rules that do not exist
+	ErrAlertRuleNotFound = errors.New("alert rule not found")
+	// ErrInvalidAlertRule is returned for alert rules that cannot be evaluated
+	ErrInvalidAlertRule = errors.New("invalid alert rule")
+)
+
+// ruleMatch is a server or feature for which a rule's condition holds
+type ruleMatch struct {
+	server  string
+	feature string // Empty for server rules
+	message string
+}
+
+func (m ruleMatch) subject() string {
+	return m.server + "|" + m.feature
+}
+
+// GetAlertRules returns all alert rules
+func (s *AlertService) GetAlertRules(ctx context.Context) ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	query := `SELECT * FROM alert_rules ORDER BY id`
+	err := s.db.SelectContext(ctx, &rules, query)
+	return rules, err
+}
+
+// GetAlertRule returns one alert rule
+func (s *AlertService) GetAlertRule(ctx context.Context, id int64) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	query := `SELECT * FROM alert_rules WHERE id = ?`
+	err := s.db.GetContext(ctx, &rule, s.db.Rebind(query), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAlertRuleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// validateAlertRule checks a rule before it is stored and fills in the
+// default severity
+func validateAlertRule(rule *models.AlertRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidAlertRule)
+	}
+
+	switch rule.RuleType {
+	case models.AlertRuleUtilization:
+		if rule.Threshold <= 0 || rule.Threshold > 100 {
+			return fmt.Errorf("%w: utilization threshold must be a percentage between 0 and 100", ErrInvalidAlertRule)
+		}
+	case models.AlertRuleExpiration:
+		if rule.Threshold <= 0 {
+			return fmt.Errorf("%w: expiration threshold must be a positive number of days", ErrInvalidAlertRule)
+		}
+	case models.AlertRuleLongCheckout:
+		if rule.Threshold <= 0 {
+			return fmt.Errorf("%w: long_checkout threshold must be a positive number of hours", ErrInvalidAlertRule)
+		}
+	case models.AlertRuleDown:
+		if rule.FeaturePattern != "" {
+			return fmt.Errorf("%w: down rules apply to servers, not features", ErrInvalidAlertRule)
+		}
+	default:
+		return fmt.Errorf("%w: rule_type must be %s, %s, %s or %s", ErrInvalidAlertRule,
+			models.AlertRuleUtilization, models.AlertRuleDown, models.AlertRuleExpiration, models.AlertRuleLongCheckout)
+	}
+
+	if rule.DurationMin < 0 {
+		return fmt.Errorf("%w: duration_min must not be negative", ErrInvalidAlertRule)
+	}
+	if _, err := compileFeaturePattern(rule.FeaturePattern); err != nil {
+		return fmt.Errorf("%w: feature_pattern: %v", ErrInvalidAlertRule, err)
+	}
+
+	switch rule.Severity {
+	case "":
+		rule.Severity = "warning"
+	case "info", "warning", "critical":
+	default:
+		return fmt.Errorf("%w: severity must be info, warning or critical", ErrInvalidAlertRule)
+	}
+	return nil
+}
+
+// compileFeaturePattern anchors a rule's feature pattern to the whole feature
+// name. An empty pattern matches every feature.
+func compileFeaturePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// CreateAlertRule validates and stores a new alert rule, setting its ID
+func (s *AlertService) CreateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	if err := validateAlertRule(rule); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := `
+		INSERT INTO alert_rules (name, rule_type, server_hostname, feature_pattern, threshold, duration_min,
+			severity, enabled, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = tx.ExecContext(ctx, tx.Rebind(query), rule.Name, rule.RuleType, rule.ServerHostname,
+		rule.FeaturePattern, rule.Threshold, rule.DurationMin, rule.Severity, rule.Enabled,
+		rule.CreatedBy, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to store alert rule: %w", err)
+	}
+
+	query = `SELECT id FROM alert_rules WHERE name = ? AND created_at = ? ORDER BY id DESC LIMIT 1`
+	if err := tx.GetContext(ctx, &rule.ID, tx.Rebind(query), rule.Name, now); err != nil {
+		return fmt.Errorf("failed to store alert rule: %w", err)
+	}
+	rule.CreatedAt, rule.UpdatedAt = now, now
+
+	return tx.Commit()
+}
+
+// UpdateAlertRule validates and replaces an existing alert rule. Changing a
+// rule restarts the duration of its conditions.
+func (s *AlertService) UpdateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	if err := validateAlertRule(rule); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := `
+		UPDATE alert_rules SET name = ?, rule_type = ?, server_hostname = ?, feature_pattern = ?,
+			threshold = ?, duration_min = ?, severity = ?, enabled = ?, updated_at = ?
+		WHERE id = ?
+	`
+	result, err := tx.ExecContext(ctx, tx.Rebind(query), rule.Name, rule.RuleType, rule.ServerHostname,
+		rule.FeaturePattern, rule.Threshold, rule.DurationMin, rule.Severity, rule.Enabled, now, rule.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update alert rule: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrAlertRuleNotFound
+	}
+
+	query = `DELETE FROM alert_rule_state WHERE rule_id = ?`
+	if _, err := tx.ExecContext(ctx, tx.Rebind(query), rule.ID); err != nil {
+		return fmt.Errorf("failed to reset alert rule state: %w", err)
+	}
+	rule.UpdatedAt = now
+
+	return tx.Commit()
+}
+
+// DeleteAlertRule removes an alert rule and its state
+func (s *AlertService) DeleteAlertRule(ctx context.Context, id int64) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM alert_rules WHERE id = ?`), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrAlertRuleNotFound
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM alert_rule_state WHERE rule_id = ?`), id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ruleStates returns since when each rule's condition has held, by rule ID
+// and subject
+func (s *AlertService) ruleStates(ctx context.Context) (map[int64]map[string]time.Time, error) {
+	var rows []struct {
+		RuleID  int64     `db:"rule_id"`
+		Subject string    `db:"subject"`
+		Since   time.Time `db:"since"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, `SELECT rule_id, subject, since FROM alert_rule_state`); err != nil {
+		return nil, err
+	}
+
+	states := make(map[int64]map[string]time.Time)
+	for _, row := range rows {
+		if states[row.RuleID] == nil {
+			states[row.RuleID] = make(map[string]time.Time)
+		}
+		states[row.RuleID][row.Subject] = row.Since
+	}
+	return states, nil
+}
+
+// EvaluateRules raises an alert for each enabled rule whose condition has
+// held for a server or feature for the rule's duration. The features are the
+// current active features, the checkouts those still held and the polls the
+// last collection outcome of each server. Conditions that stop holding reset
+// their duration.
+func (s *AlertService) EvaluateRules(ctx context.Context, features []models.Feature, checkouts []models.Checkout, polls map[string]PollResult, now time.Time) error {
+	rules, err := s.GetAlertRules(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get alert rules: %w", err)
+	}
+	states, err := s.ruleStates(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get alert rule state: %w", err)
+	}
+
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		matches, err := ruleMatches(rule, features, checkouts, polls, now)
+		if err != nil {
+			log.Errorf("Failed to evaluate alert rule %d (%s): %v", rule.ID, rule.Name, err)
+			continue
+		}
+
+		held := states[rule.ID]
+		active := make(map[string]bool, len(matches))
+		for _, m := range matches {
+			active[m.subject()] = true
+
+			since, ok := held[m.subject()]
+			if !ok {
+				since = now
+				query := `INSERT INTO alert_rule_state (rule_id, subject, since) VALUES (?, ?, ?)`
+				if _, err := s.db.ExecContext(ctx, s.db.Rebind(query), rule.ID, m.subject(), now); err != nil {
+					log.Errorf("Failed to record alert rule state: %v", err)
+				}
+			}
+			if now.Sub(since) < time.Duration(rule.DurationMin)*time.Minute {
+				continue
+			}
+
+			if s.CheckThrottle(m.server, fmt.Sprintf("rule:%d:%s", rule.ID, m.feature)) {
+				continue
+			}
+			alert := &models.Alert{
+				ServerHostname: m.server,
+				FeatureName:    m.feature,
+				AlertType:      rule.RuleType,
+				Message:        fmt.Sprintf("%s: %s", rule.Name, m.message),
+				Severity:       rule.Severity,
+			}
+			if rule.DurationMin > 0 {
+				alert.Message += fmt.Sprintf(" for %d minutes", int(now.Sub(since).Minutes()))
+			}
+			if err := s.CreateAlert(ctx, alert); err != nil {
+				log.Errorf("Failed to create alert for rule %d: %v", rule.ID, err)
+			}
+		}
+
+		for subject := range held {
+			if active[subject] {
+				continue
+			}
+			query := `DELETE FROM alert_rule_state WHERE rule_id = ? AND subject = ?`
+			if _, err := s.db.ExecContext(ctx, s.db.Rebind(query), rule.ID, subject); err != nil {
+				log.Errorf("Failed to clear alert rule state: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// ruleMatches returns the servers or features for which a rule's condition
+// currently holds
+func ruleMatches(rule models.AlertRule, features []models.Feature, checkouts []models.Checkout, polls map[string]PollResult, now time.Time) ([]ruleMatch, error) {
+	if rule.RuleType == models.AlertRuleDown {
+		var matches []ruleMatch
+		for hostname, poll := range polls {
+			if poll.Up || (rule.ServerHostname != "" && hostname != rule.ServerHostname) {
+				continue
+			}
+			matches = append(matches, ruleMatch{server: hostname, message: fmt.Sprintf("License server %s is down", hostname)})
+		}
+		sort.Slice(matches, func(i, j int) bool { return matches[i].server < matches[j].server })
+		return matches, nil
+	}
+
+	pattern, err := compileFeaturePattern(rule.FeaturePattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if rule.RuleType == models.AlertRuleLongCheckout {
+		return longCheckoutMatches(rule, pattern, checkouts), nil
+	}
+
+	// Pools of the same feature on a server are combined, as for utilization
+	// thresholds
+	type pool struct {
+		server, name string
+		used, total  int
+		expires      time.Time
+	}
+	var order []string
+	pools := make(map[string]*pool)
+	for _, f := range features {
+		if rule.ServerHostname != "" && f.ServerHostname != rule.ServerHostname {
+			continue
+		}
+		if pattern != nil && !pattern.MatchString(f.Name) {
+			continue
+		}
+		key := f.ServerHostname + "|" + f.Name
+		p := pools[key]
+		if p == nil {
+			p = &pool{server: f.ServerHostname, name: f.Name}
+			pools[key] = p
+			order = append(order, key)
+		}
+		p.used += f.UsedLicenses
+		p.total += f.CountedLicenses()
+		if f.ExpirationDate.After(now) && (p.expires.IsZero() || f.ExpirationDate.Before(p.expires)) {
+			p.expires = f.ExpirationDate
+		}
+	}
+
+	var matches []ruleMatch
+	for _, key := range order {
+		p := pools[key]
+		switch rule.RuleType {
+		case models.AlertRuleUtilization:
+			if p.total == 0 {
+				continue
+			}
+			pct := float64(p.used) / float64(p.total) * 100
+			if pct < rule.Threshold {
+				continue
+			}
+			matches = append(matches, ruleMatch{server: p.server, feature: p.name,
+				message: fmt.Sprintf("Feature '%s' on %s is at %.0f%% utilization (%d of %d seats, threshold %.0f%%)",
+					p.name, p.server, pct, p.used, p.total, rule.Threshold)})
+
+		case models.AlertRuleExpiration:
+			if p.expires.IsZero() {
+				continue
+			}
+			days := p.expires.Sub(now).Hours() / 24
+			if days >= rule.Threshold {
+				continue
+			}
+			matches = append(matches, ruleMatch{server: p.server, feature: p.name,
+				message: fmt.Sprintf("License '%s' on %s expires in %d days (%s)",
+					p.name, p.server, int(days), p.expires.Format("2006-01-02"))})
+		}
+	}
+	return matches, nil
+}
+
+// longCheckoutMatches returns the features with checkouts held longer than a
+// rule's threshold in hours. Alerts are stored and listed to every role, so
+// they only count the checkouts; the holders are listed, redacted, by the
+// long checkouts endpoint.
+func longCheckoutMatches(rule models.AlertRule, pattern *regexp.Regexp, checkouts []models.Checkout) []ruleMatch {
+	var order []string
+	count := make(map[string]int)
+	longest := make(map[string]float64)
+	for _, c := range checkouts {
+		if c.CheckedInAt != nil || c.HeldHours < rule.Threshold {
+			continue
+		}
+		if rule.ServerHostname != "" && c.ServerHostname != rule.ServerHostname {
+			continue
+		}
+		if pattern != nil && !pattern.MatchString(c.FeatureName) {
+			continue
+		}
+		key := c.ServerHostname + "|" + c.FeatureName
+		if count[key] == 0 {
+			order = append(order, key)
+		}
+		count[key]++
+		longest[key] = max(longest[key], c.HeldHours)
+	}
+
+	matches := make([]ruleMatch, 0, len(order))
+	for _, key := range order {
+		server, feature, _ := strings.Cut(key, "|")
+		matches = append(matches, ruleMatch{server: server, feature: feature,
+			message: fmt.Sprintf("%d checkouts of '%s' on %s held longer than %.0f hours (longest %.0fh)",
+				count[key], feature, server, rule.Threshold, longest[key])})
+	}
+	return matches
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestAlertRules_CRUD(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	s := NewAlertService(db, &config.Config{})
+
+	invalid := []models.AlertRule{
+		{RuleType: models.AlertRuleUtilization, Threshold: 90},
+		{Name: "x", RuleType: "latency", Threshold: 90},
+		{Name: "x", RuleType: models.AlertRuleUtilization, Threshold: 150},
+		{Name: "x", RuleType: models.AlertRuleExpiration},
+		{Name: "x", RuleType: models.AlertRuleDown, FeaturePattern: "solver"},
+		{Name: "x", RuleType: models.AlertRuleLongCheckout},
+		{Name: "x", RuleType: models.AlertRuleUtilization, Threshold: 90, FeaturePattern: "("},
+		{Name: "x", RuleType: models.AlertRuleUtilization, Threshold: 90, Severity: "fatal"},
+	}
+	for _, rule := range invalid {
+		if err := s.CreateAlertRule(ctx, &rule); !errors.Is(err, ErrInvalidAlertRule) {
+			t.Errorf("Expected %+v to be rejected, got %v", rule, err)
+		}
+	}
+
+	rule := models.AlertRule{Name: "Solver busy", RuleType: models.AlertRuleUtilization, FeaturePattern: "solver.*", Threshold: 90, Enabled: true}
+	if err := s.CreateAlertRule(ctx, &rule); err != nil {
+		t.Fatalf("CreateAlertRule failed: %v", err)
+	}
+	if rule.ID == 0 || rule.Severity != "warning" {
+		t.Fatalf("Expected an ID and the default severity, got %+v", rule)
+	}
+
+	rule.Threshold = 95
+	if err := s.UpdateAlertRule(ctx, &rule); err != nil {
+		t.Fatalf("UpdateAlertRule failed: %v", err)
+	}
+	stored, err := s.GetAlertRule(ctx, rule.ID)
+	if err != nil {
+		t.Fatalf("GetAlertRule failed: %v", err)
+	}
+	if stored.Threshold != 95 || stored.FeaturePattern != "solver.*" {
+		t.Errorf("Expected the rule to be updated, got %+v", stored)
+	}
+
+	if err := s.DeleteAlertRule(ctx, rule.ID); err != nil {
+		t.Fatalf("DeleteAlertRule failed: %v", err)
+	}
+	if _, err := s.GetAlertRule(ctx, rule.ID); !errors.Is(err, ErrAlertRuleNotFound) {
+		t.Errorf("Expected ErrAlertRuleNotFound, got %v", err)
+	}
+	if err := s.UpdateAlertRule(ctx, &rule); !errors.Is(err, ErrAlertRuleNotFound) {
+		t.Errorf("Expected ErrAlertRuleNotFound, got %v", err)
+	}
+}
+
+func TestEvaluateRules_Duration(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	s := NewAlertService(db, &config.Config{Alerts: config.AlertConfig{ResendIntervalMin: 60}})
+
+	rules := []models.AlertRule{
+		{Name: "Solver busy", RuleType: models.AlertRuleUtilization, FeaturePattern: "solver", Threshold: 90, DurationMin: 15, Severity: "critical", Enabled: true},
+		{Name: "Server down", RuleType: models.AlertRuleDown, DurationMin: 10, Enabled: true},
+		{Name: "Renewal due", RuleType: models.AlertRuleExpiration, ServerHostname: "27000@a", Threshold: 30, Enabled: true},
+		{Name: "Disabled", RuleType: models.AlertRuleUtilization, Threshold: 1},
+	}
+	for i := range rules {
+		if err := s.CreateAlertRule(ctx, &rules[i]); err != nil {
+			t.Fatalf("CreateAlertRule failed: %v", err)
+		}
+	}
+
+	start := time.Now()
+	features := []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", TotalLicenses: 10, UsedLicenses: 9, ExpirationDate: start.AddDate(0, 0, 10)},
+		{ServerHostname: "27000@a", Name: "mesher", TotalLicenses: 10, UsedLicenses: 10, ExpirationDate: start.AddDate(0, 0, 90)},
+		{ServerHostname: "27000@b", Name: "solver", TotalLicenses: 10, UsedLicenses: 1, ExpirationDate: start.AddDate(0, 0, 5)},
+	}
+	polls := map[string]PollResult{
+		"27000@a": {Up: true},
+		"27000@b": {Up: false},
+	}
+
+	alertTypes := func() map[string]models.Alert {
+		t.Helper()
+		alerts, err := s.GetUnsentAlerts(ctx)
+		if err != nil {
+			t.Fatalf("GetUnsentAlerts failed: %v", err)
+		}
+		byType := make(map[string]models.Alert)
+		for _, a := range alerts {
+			byType[a.AlertType] = a
+		}
+		return byType
+	}
+
+	// Expiration rules without a duration fire at once; the others must hold
+	if err := s.EvaluateRules(ctx, features, nil, polls, start); err != nil {
+		t.Fatalf("EvaluateRules failed: %v", err)
+	}
+	got := alertTypes()
+	if len(got) != 1 || got["expiration"].FeatureName != "solver" || got["expiration"].ServerHostname != "27000@a" {
+		t.Fatalf("Expected only the expiration rule to fire, got %+v", got)
+	}
+
+	// 27000@b comes back up, so the down condition resets
+	polls["27000@b"] = PollResult{Up: true}
+	if err := s.EvaluateRules(ctx, features, nil, polls, start.Add(5*time.Minute)); err != nil {
+		t.Fatalf("EvaluateRules failed: %v", err)
+	}
+	polls["27000@b"] = PollResult{Up: false}
+	if err := s.EvaluateRules(ctx, features, nil, polls, start.Add(10*time.Minute)); err != nil {
+		t.Fatalf("EvaluateRules failed: %v", err)
+	}
+	if _, ok := alertTypes()["down"]; ok {
+		t.Fatal("Expected the down duration to restart after the server recovered")
+	}
+
+	if err := s.EvaluateRules(ctx, features, nil, polls, start.Add(20*time.Minute)); err != nil {
+		t.Fatalf("EvaluateRules failed: %v", err)
+	}
+	got = alertTypes()
+	if got["down"].ServerHostname != "27000@b" {
+		t.Errorf("Expected a down alert for 27000@b, got %+v", got)
+	}
+	utilization := got["utilization"]
+	if utilization.Severity != "critical" || !strings.HasPrefix(utilization.Message, "Solver busy:") || !strings.HasSuffix(utilization.Message, "for 20 minutes") {
+		t.Errorf("Unexpected utilization alert %+v", utilization)
+	}
+	if len(got) != 3 {
+		t.Errorf("Expected the disabled rule not to fire, got %+v", got)
+	}
+}
+
+func TestLongCheckoutMatches(t *testing.T) {
+	held := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	returned := held.Add(40 * time.Hour)
+	checkouts := []models.Checkout{
+		{ServerHostname: "27000@a", FeatureName: "solver", Username: "alice", Host: "ws1", HeldHours: 30},
+		{ServerHostname: "27000@a", FeatureName: "solver", Username: "bob", Host: "ws2", HeldHours: 2},
+		{ServerHostname: "27000@a", FeatureName: "solver", Username: "carol", Host: "ws3", HeldHours: 26},
+		{ServerHostname: "27000@a", FeatureName: "mesher", Username: "dave", Host: "ws4", HeldHours: 40, CheckedInAt: &returned},
+		{ServerHostname: "27000@b", FeatureName: "viewer", Username: "erin", Host: "ws5", HeldHours: 50},
+	}
+
+	rule := models.AlertRule{Name: "Idle seats", RuleType: models.AlertRuleLongCheckout, FeaturePattern: "solver|mesher", Threshold: 24}
+	matches, err := ruleMatches(rule, nil, checkouts, nil, held)
+	if err != nil {
+		t.Fatalf("ruleMatches failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].subject() != "27000@a|solver" {
+		t.Fatalf("Expected one match for solver, got %+v", matches)
+	}
+	want := "2 checkouts of 'solver' on 27000@a held longer than 24 hours (longest 30h)"
+	if matches[0].message != want {
+		t.Errorf("Expected message %q, got %q", want, matches[0].message)
+	}
+}
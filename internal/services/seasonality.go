@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"math"
+
+	"licet/internal/models"
+)
+
+// Seasonality detection thresholds
+const (
+	// seasonalCycleCV is the coefficient of variation of the average usage by
+	// hour, or by weekday, above which usage counts as cyclical
+	seasonalCycleCV = 0.2
+	// seasonalPeakShare is the share of the busiest hour's usage that the
+	// hours of a peak period reach
+	seasonalPeakShare = 0.8
+	// minDailyCycleDays is how many days of usage a daily cycle is detected from
+	minDailyCycleDays = 3
+)
+
+// weekdays and weekend are the days of week of the peak periods, 0=Sunday
+var (
+	weekdays = []int{1, 2, 3, 4, 5}
+	weekend  = []int{0, 6}
+	everyDay = []int{0, 1, 2, 3, 4, 5, 6}
+)
+
+// seasonalUsage sums the usage of a feature by day of week and hour
+type seasonalUsage struct {
+	server, feature string
+	users           [7][24]float64 // Sum of the sampled users
+	samples         [7][24]float64
+	days            map[string]bool
+}
+
+// profile returns the average usage by hour over the days of week, and
+// whether each hour was sampled
+func (u *seasonalUsage) profile(days []int) (avg [24]float64, sampled [24]bool) {
+	for hour := 0; hour < 24; hour++ {
+		var users, samples float64
+		for _, day := range days {
+			users += u.users[day][hour]
+			samples += u.samples[day][hour]
+		}
+		if samples > 0 {
+			avg[hour] = users / samples
+			sampled[hour] = true
+		}
+	}
+	return avg, sampled
+}
+
+// GetSeasonalPatterns decomposes the usage of the last days into the average
+// by hour of day and by day of week, detects daily and weekly cycles and
+// labels the peak periods. With a weekly cycle, weekdays and weekends have
+// peak periods of their own. Holidays are left out. Empty server or feature
+// match all.
+func (s *AnalyticsService) GetSeasonalPatterns(ctx context.Context, server, feature string, days int) ([]models.SeasonalPattern, error) {
+	cutoff := s.clock.Now().AddDate(0, 0, -days)
+	watermark, err := s.storage.rollupWatermark(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usage, args := s.storage.usageAggregates(watermark, true, cutoff, server, feature)
+	query := `
+		SELECT server_hostname, feature_name, date, hour, samples, sum_users
+		FROM (` + usage + `) u
+		ORDER BY server_hostname, feature_name
+	`
+	var rows []struct {
+		ServerHostname string      `db:"server_hostname"`
+		FeatureName    string      `db:"feature_name"`
+		Date           interface{} `db:"date"`
+		Hour           int         `db:"hour"`
+		Samples        float64     `db:"samples"`
+		SumUsers       float64     `db:"sum_users"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	var usages []*seasonalUsage
+	for _, row := range rows {
+		if len(usages) == 0 || usages[len(usages)-1].server != row.ServerHostname || usages[len(usages)-1].feature != row.FeatureName {
+			usages = append(usages, &seasonalUsage{server: row.ServerHostname, feature: row.FeatureName, days: make(map[string]bool)})
+		}
+		u := usages[len(usages)-1]
+		date := scannedDate(row.Date)
+		if row.Hour < 0 || row.Hour > 23 || s.storage.holidays.IsHoliday(row.ServerHostname, date) {
+			continue
+		}
+		day := int(date.Weekday())
+		u.users[day][row.Hour] += row.SumUsers
+		u.samples[day][row.Hour] += row.Samples
+		u.days[date.Format("2006-01-02")] = true
+	}
+
+	patterns := make([]models.SeasonalPattern, 0, len(usages))
+	for _, u := range usages {
+		patterns = append(patterns, seasonalPattern(u))
+	}
+	return patterns, nil
+}
+
+// seasonalPattern decomposes the usage of a feature
+func seasonalPattern(u *seasonalUsage) models.SeasonalPattern {
+	pattern := models.SeasonalPattern{
+		ServerHostname: u.server,
+		FeatureName:    u.feature,
+		HourlyPattern:  make([]float64, 24),
+		DailyPattern:   make([]float64, 7),
+		PeakPeriods:    []models.PeakPeriod{},
+	}
+
+	hourly, sampled := u.profile(everyDay)
+	var hourlyValues []float64
+	for hour, avg := range hourly {
+		pattern.HourlyPattern[hour] = roundHundredths(avg)
+		if sampled[hour] {
+			hourlyValues = append(hourlyValues, avg)
+		}
+	}
+
+	var dailyValues []float64
+	weekdaysSampled := 0
+	for day := 0; day < 7; day++ {
+		var users, samples float64
+		for hour := 0; hour < 24; hour++ {
+			users += u.users[day][hour]
+			samples += u.samples[day][hour]
+		}
+		if samples > 0 {
+			pattern.DailyPattern[day] = roundHundredths(users / samples)
+			dailyValues = append(dailyValues, users/samples)
+			weekdaysSampled++
+		}
+	}
+
+	pattern.HasDailyCycle = len(u.days) >= minDailyCycleDays && isCyclical(hourlyValues)
+	pattern.HasWeeklyCycle = weekdaysSampled == 7 && isCyclical(dailyValues)
+	if !pattern.HasDailyCycle {
+		return pattern
+	}
+
+	if !pattern.HasWeeklyCycle {
+		pattern.PeakPeriods = peakPeriods(hourly, sampled, everyDay, "")
+		return pattern
+	}
+	for _, part := range []struct {
+		days   []int
+		prefix string
+	}{{weekdays, "Weekday"}, {weekend, "Weekend"}} {
+		avg, sampled := u.profile(part.days)
+		var values []float64
+		for hour := range avg {
+			if sampled[hour] {
+				values = append(values, avg[hour])
+			}
+		}
+		// Usage flat across the day, such as on quiet weekends, has no peaks
+		if isCyclical(values) {
+			pattern.PeakPeriods = append(pattern.PeakPeriods, peakPeriods(avg, sampled, part.days, part.prefix)...)
+		}
+	}
+	return pattern
+}
+
+// isCyclical reports whether values vary enough around their mean to form a cycle
+func isCyclical(values []float64) bool {
+	if len(values) < 2 {
+		return false
+	}
+	mean, stdDev := calculateStats(values)
+	return mean > 0 && stdDev/mean >= seasonalCycleCV
+}
+
+// peakPeriods returns the runs of hours reaching seasonalPeakShare of the
+// busiest hour. A period spanning midnight ends at or before its start hour.
+func peakPeriods(avg [24]float64, sampled [24]bool, days []int, prefix string) []models.PeakPeriod {
+	var max float64
+	for _, v := range avg {
+		max = math.Max(max, v)
+	}
+	if max == 0 {
+		return nil
+	}
+	threshold := max * seasonalPeakShare
+
+	type run struct{ start, hours int }
+	var runs []run
+	for hour := 0; hour < 24; hour++ {
+		if !sampled[hour] || avg[hour] < threshold {
+			continue
+		}
+		if last := len(runs) - 1; last >= 0 && runs[last].start+runs[last].hours == hour {
+			runs[last].hours++
+		} else {
+			runs = append(runs, run{hour, 1})
+		}
+	}
+	// Join the runs before and after midnight
+	if last := len(runs) - 1; last > 0 && runs[0].start == 0 && runs[last].start+runs[last].hours == 24 {
+		runs[0] = run{runs[last].start, runs[last].hours + runs[0].hours}
+		runs = runs[:last]
+	}
+
+	periods := make([]models.PeakPeriod, 0, len(runs))
+	for _, r := range runs {
+		var sum float64
+		for i := 0; i < r.hours; i++ {
+			sum += avg[(r.start+i)%24]
+		}
+		periods = append(periods, models.PeakPeriod{
+			StartHour:  r.start,
+			EndHour:    (r.start + r.hours) % 24,
+			DaysOfWeek: days,
+			AvgUsage:   roundHundredths(sum / float64(r.hours)),
+			Label:      peakLabel(r.start, r.hours, prefix),
+		})
+	}
+	return periods
+}
+
+// peakLabel names a peak period by the time of day it starts at and its length
+func peakLabel(start, hours int, prefix string) string {
+	var label string
+	switch {
+	case start >= 7 && start <= 10 && hours >= 6:
+		label = "Business Hours"
+	case start < 6 || start >= 22:
+		label = "Overnight Peak"
+	case start < 12:
+		label = "Morning Peak"
+	case start < 17:
+		label = "Afternoon Peak"
+	default:
+		label = "Evening Peak"
+	}
+	if prefix != "" {
+		label = prefix + " " + label
+	}
+	return label
+}
+
+// roundHundredths rounds to two decimals
+func roundHundredths(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"licet/internal/clock"
+	"licet/internal/models"
+)
+
+func TestGetSeasonalPatterns(t *testing.T) {
+	db := newTestDB(t)
+	storage := NewStorageService(db, "sqlite")
+	analytics := NewAnalyticsService(db, storage, "sqlite")
+	now := time.Date(2025, 3, 29, 12, 0, 0, 0, time.Local)
+	analytics.SetClock(clock.NewFixed(now))
+
+	// Ten users during weekday business hours, one otherwise
+	for day := now.AddDate(0, 0, -27); !day.After(now); day = day.AddDate(0, 0, 1) {
+		for hour := 0; hour < 24; hour++ {
+			users := 1
+			if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday && hour >= 9 && hour < 17 {
+				users = 10
+			}
+			_, err := db.Exec(`INSERT INTO feature_usage (server_hostname, feature_name, date, time, users_count) VALUES (?, ?, ?, ?, ?)`,
+				"27000@a", "catia", day.Format("2006-01-02"), fmt.Sprintf("%02d:00:00", hour), users)
+			if err != nil {
+				t.Fatalf("Failed to insert usage: %v", err)
+			}
+		}
+	}
+
+	patterns, err := analytics.GetSeasonalPatterns(context.Background(), "27000@a", "", 28)
+	if err != nil {
+		t.Fatalf("GetSeasonalPatterns failed: %v", err)
+	}
+	if len(patterns) != 1 {
+		t.Fatalf("Expected 1 pattern, got %d", len(patterns))
+	}
+	p := patterns[0]
+	if !p.HasDailyCycle || !p.HasWeeklyCycle {
+		t.Errorf("Expected daily and weekly cycles, got %+v", p)
+	}
+	if p.HourlyPattern[12] <= p.HourlyPattern[3] {
+		t.Errorf("Expected more usage at noon than at night, got %v", p.HourlyPattern)
+	}
+	if p.DailyPattern[time.Wednesday] != 4 || p.DailyPattern[time.Sunday] != 1 {
+		t.Errorf("Unexpected daily pattern %v", p.DailyPattern)
+	}
+	want := models.PeakPeriod{StartHour: 9, EndHour: 17, AvgUsage: 10, Label: "Weekday Business Hours"}
+	if len(p.PeakPeriods) != 1 {
+		t.Fatalf("Expected the weekday peak only, got %+v", p.PeakPeriods)
+	}
+	got := p.PeakPeriods[0]
+	if got.StartHour != want.StartHour || got.EndHour != want.EndHour || got.AvgUsage != want.AvgUsage ||
+		got.Label != want.Label || len(got.DaysOfWeek) != 5 {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestPeakPeriods_Midnight(t *testing.T) {
+	var avg [24]float64
+	var sampled [24]bool
+	for hour := range avg {
+		sampled[hour] = true
+		avg[hour] = 1
+		if hour >= 22 || hour < 3 {
+			avg[hour] = 5
+		}
+	}
+
+	periods := peakPeriods(avg, sampled, everyDay, "")
+	if len(periods) != 1 {
+		t.Fatalf("Expected one period across midnight, got %+v", periods)
+	}
+	if p := periods[0]; p.StartHour != 22 || p.EndHour != 3 || p.AvgUsage != 5 || p.Label != "Overnight Peak" {
+		t.Errorf("Unexpected period %+v", p)
+	}
+}
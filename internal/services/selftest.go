@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+	"fmt"
+)
+
+// selftestHostname is the server the database check writes, which no license
+// server can be named since .invalid is reserved
+const selftestHostname = "selftest.licet.invalid"
+
+// CheckReadWrite writes a server, reads it back and rolls the write back, to
+// verify the database accepts both without leaving a trace
+func (s *StorageService) CheckReadWrite(ctx context.Context) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO servers (hostname, description, type, cacti_id, webui, group_name, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	if _, err := tx.ExecContext(ctx, tx.Rebind(query), selftestHostname, "Self-test", "flexlm", "", "", "", s.clock.Now()); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+	var description string
+	if err := tx.GetContext(ctx, &description, tx.Rebind(`SELECT description FROM servers WHERE hostname = ?`), selftestHostname); err != nil {
+		return fmt.Errorf("failed to read: %w", err)
+	}
+	if description != "Self-test" {
+		return fmt.Errorf("read %q after writing %q", description, "Self-test")
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"licet/internal/config"
+)
+
+// SyncServers records the configured servers in the servers table, so
+// analytics can filter and aggregate them by group
+func (s *StorageService) SyncServers(ctx context.Context, servers []config.LicenseServer) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := s.clock.Now()
+	for _, srv := range servers {
+		var count int
+		if err := tx.GetContext(ctx, &count, tx.Rebind(`SELECT COUNT(*) FROM servers WHERE hostname = ?`), srv.Hostname); err != nil {
+			return err
+		}
+		query := `UPDATE servers SET description = ?, type = ?, cacti_id = ?, webui = ?, group_name = ?, updated_at = ? WHERE hostname = ?`
+		if count == 0 {
+			query = `INSERT INTO servers (description, type, cacti_id, webui, group_name, updated_at, hostname) VALUES (?, ?, ?, ?, ?, ?, ?)`
+		}
+		_, err := tx.ExecContext(ctx, tx.Rebind(query),
+			srv.Description, srv.Type, srv.CactiID, srv.WebUI, srv.Group, now, srv.Hostname)
+		if err != nil {
+			return fmt.Errorf("failed to store server %s: %w", srv.Hostname, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetServerGroups returns the group of every server in one
+func (s *StorageService) GetServerGroups(ctx context.Context) (map[string]string, error) {
+	var rows []struct {
+		Hostname string `db:"hostname"`
+		Group    string `db:"group_name"`
+	}
+	query := `SELECT hostname, group_name FROM servers WHERE group_name <> ''`
+	if err := s.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, err
+	}
+	groups := make(map[string]string, len(rows))
+	for _, row := range rows {
+		groups[row.Hostname] = row.Group
+	}
+	return groups, nil
+}
+
+// FeatureFilter returns the features carrying a tag on the servers of a
+// group. Either may be empty to not filter by it; with neither the set is nil.
+func (s *StorageService) FeatureFilter(ctx context.Context, tag, group string) (*FeatureSet, error) {
+	if tag == "" && group == "" {
+		return nil, nil
+	}
+	set := &FeatureSet{}
+	if tag != "" {
+		tagged, err := s.TaggedFeatures(ctx, tag)
+		if err != nil {
+			return nil, err
+		}
+		*set = *tagged
+	}
+	if group != "" {
+		groups, err := s.GetServerGroups(ctx)
+		if err != nil {
+			return nil, err
+		}
+		set.servers = make(map[string]bool)
+		for hostname, g := range groups {
+			if g == group {
+				set.servers[hostname] = true
+			}
+		}
+	}
+	return set, nil
+}
+
+// FeatureFilter returns the features carrying a tag on the servers of a group
+func (s *AnalyticsService) FeatureFilter(ctx context.Context, tag, group string) (*FeatureSet, error) {
+	return s.storage.FeatureFilter(ctx, tag, group)
+}
+
+// FeatureFilter returns the features carrying a tag on the servers of a group
+func (s *EnhancedAnalyticsService) FeatureFilter(ctx context.Context, tag, group string) (*FeatureSet, error) {
+	return s.storage.FeatureFilter(ctx, tag, group)
+}
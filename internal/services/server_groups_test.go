@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestServerGroups(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	servers := []config.LicenseServer{
+		{Hostname: "27000@a", Type: "flexlm", Group: "emea"},
+		{Hostname: "27000@b", Type: "flexlm", Group: "emea"},
+		{Hostname: "27000@c", Type: "rlm", Group: "apac"},
+		{Hostname: "27000@d", Type: "rlm"},
+	}
+	if err := storage.SyncServers(ctx, servers); err != nil {
+		t.Fatalf("SyncServers failed: %v", err)
+	}
+	servers[1].Group = "amer"
+	if err := storage.SyncServers(ctx, servers); err != nil {
+		t.Fatalf("SyncServers failed on the second run: %v", err)
+	}
+
+	groups, err := storage.GetServerGroups(ctx)
+	if err != nil {
+		t.Fatalf("GetServerGroups failed: %v", err)
+	}
+	if len(groups) != 3 || groups["27000@a"] != "emea" || groups["27000@b"] != "amer" {
+		t.Fatalf("Unexpected server groups: %v", groups)
+	}
+
+	if set, err := storage.FeatureFilter(ctx, "", ""); err != nil || set != nil {
+		t.Errorf("Expected no filter without tag and group, got %+v, %v", set, err)
+	}
+	if err := storage.TagFeature(ctx, &models.FeatureTag{FeatureName: "catia", Tag: "CAD"}); err != nil {
+		t.Fatalf("TagFeature failed: %v", err)
+	}
+	set, err := storage.FeatureFilter(ctx, "CAD", "emea")
+	if err != nil {
+		t.Fatalf("FeatureFilter failed: %v", err)
+	}
+	for _, tt := range []struct {
+		server, feature string
+		want            bool
+	}{
+		{"27000@a", "catia", true},
+		{"27000@a", "matlab", false},
+		{"27000@b", "catia", false},
+		{"27000@d", "catia", false},
+	} {
+		if got := set.Contains(tt.server, tt.feature); got != tt.want {
+			t.Errorf("Contains(%s, %s) = %v, want %v", tt.server, tt.feature, got, tt.want)
+		}
+	}
+	if set, _ := storage.FeatureFilter(ctx, "", "apac"); !set.Contains("27000@c", "matlab") || set.Contains("27000@a", "matlab") {
+		t.Error("Expected the apac filter to contain every feature of 27000@c only")
+	}
+}
+
+func TestCapacityReportGroups(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	if err := storage.SyncServers(ctx, []config.LicenseServer{
+		{Hostname: "27000@a", Type: "flexlm", Group: "emea"},
+		{Hostname: "27000@b", Type: "flexlm", Group: "emea"},
+		{Hostname: "27000@c", Type: "flexlm"},
+	}); err != nil {
+		t.Fatalf("SyncServers failed: %v", err)
+	}
+	if err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "27000@a", Name: "catia", TotalLicenses: 10},
+		{ServerHostname: "27000@b", Name: "matlab", TotalLicenses: 10},
+		{ServerHostname: "27000@c", Name: "ansys", TotalLicenses: 10},
+	}); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	date := time.Now().Format("2006-01-02")
+	for _, u := range []struct {
+		server, feature string
+		users           int
+	}{
+		{"27000@a", "catia", 9},
+		{"27000@b", "matlab", 1},
+		{"27000@c", "ansys", 5},
+	} {
+		_, err := db.Exec(`INSERT INTO feature_usage (server_hostname, feature_name, date, time, users_count) VALUES (?, ?, ?, ?, ?)`,
+			u.server, u.feature, date, "12:00:00", u.users)
+		if err != nil {
+			t.Fatalf("Failed to insert usage: %v", err)
+		}
+	}
+
+	report, err := NewEnhancedAnalyticsService(db, storage, "sqlite").GetCapacityPlanningReport(ctx, 30)
+	if err != nil {
+		t.Fatalf("GetCapacityPlanningReport failed: %v", err)
+	}
+	want := models.GroupCapacity{
+		Group: "emea", Servers: 2, Features: 2, TotalLicenses: 20, AvgUsage: 10, PeakUsage: 10,
+		UtilizationPct: 50, FeaturesAtCapacity: 1, FeaturesUnderutilized: 1,
+	}
+	if len(report.Groups) != 1 || report.Groups[0] != want {
+		t.Fatalf("Expected the emea group only, got %+v", report.Groups)
+	}
+
+	set, err := storage.FeatureFilter(ctx, "", "emea")
+	if err != nil {
+		t.Fatalf("FeatureFilter failed: %v", err)
+	}
+	report, err = NewEnhancedAnalyticsService(db, storage, "sqlite").GetFilteredCapacityReport(ctx, 30, set)
+	if err != nil {
+		t.Fatalf("GetFilteredCapacityReport failed: %v", err)
+	}
+	if report.TotalServers != 2 || report.TotalFeatures != 2 {
+		t.Errorf("Expected the features of emea, got %+v", report)
+	}
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/models"
+)
+
+// ErrSilenceNotFound is returned when deleting a silence that is not stored
+var ErrSilenceNotFound = errors.New("silence not found")
+
+// alertmanagerSilence is a silence as returned by the Alertmanager v2 API
+type alertmanagerSilence struct {
+	ID       string `json:"id"`
+	Matchers []struct {
+		Name    string `json:"name"`
+		Value   string `json:"value"`
+		IsRegex bool   `json:"isRegex"`
+		IsEqual *bool  `json:"isEqual"` // Absent before Alertmanager 0.22 and means equal
+	} `json:"matchers"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	CreatedBy string    `json:"createdBy"`
+	Comment   string    `json:"comment"`
+	Status    struct {
+		State string `json:"state"`
+	} `json:"status"`
+}
+
+// ParseAlertmanagerSilences decodes one Alertmanager silence or a list of
+// them, dropping expired silences
+func ParseAlertmanagerSilences(r io.Reader) ([]models.AlertSilence, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid silence JSON: %w", err)
+	}
+
+	var received []alertmanagerSilence
+	if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(raw, &received); err != nil {
+			return nil, fmt.Errorf("invalid silence list: %w", err)
+		}
+	} else {
+		var one alertmanagerSilence
+		if err := json.Unmarshal(raw, &one); err != nil {
+			return nil, fmt.Errorf("invalid silence: %w", err)
+		}
+		received = append(received, one)
+	}
+
+	silences := make([]models.AlertSilence, 0, len(received))
+	for _, am := range received {
+		if am.ID == "" {
+			return nil, errors.New("silence without id")
+		}
+		if am.Status.State == "expired" {
+			continue
+		}
+		silence := models.AlertSilence{
+			ID:        am.ID,
+			StartsAt:  am.StartsAt,
+			EndsAt:    am.EndsAt,
+			CreatedBy: am.CreatedBy,
+			Comment:   am.Comment,
+		}
+		for _, m := range am.Matchers {
+			silence.Matchers = append(silence.Matchers, models.SilenceMatcher{
+				Name:    m.Name,
+				Value:   m.Value,
+				IsRegex: m.IsRegex,
+				IsEqual: m.IsEqual == nil || *m.IsEqual,
+			})
+		}
+		if len(silence.Matchers) == 0 {
+			return nil, fmt.Errorf("silence %s has no matchers", am.ID)
+		}
+		silences = append(silences, silence)
+	}
+	return silences, nil
+}
+
+// alertLabels returns the labels silence matchers are evaluated against
+func alertLabels(alert *models.Alert) map[string]string {
+	return map[string]string{
+		"job":       "licet",
+		"alertname": alert.AlertType,
+		"server":    alert.ServerHostname,
+		"feature":   alert.FeatureName,
+		"severity":  alert.Severity,
+	}
+}
+
+// silenceMatches reports whether every matcher of a silence matches the
+// labels. Labels that are not set match the empty string, as in Alertmanager.
+func silenceMatches(silence models.AlertSilence, labels map[string]string) bool {
+	for _, m := range silence.Matchers {
+		value := labels[m.Name]
+		matched := value == m.Value
+		if m.IsRegex {
+			re, err := regexp.Compile("^(?:" + m.Value + ")$")
+			if err != nil {
+				log.Warnf("Ignoring silence %s with invalid regex %q: %v", silence.ID, m.Value, err)
+				return false
+			}
+			matched = re.MatchString(value)
+		}
+		if matched != m.IsEqual {
+			return false
+		}
+	}
+	return true
+}
+
+// GetSilences returns the silences that have not ended, soonest ending first
+func (s *AlertService) GetSilences(ctx context.Context) ([]models.AlertSilence, error) {
+	return s.silences(ctx, `SELECT * FROM alert_silences WHERE ends_at > ? ORDER BY ends_at ASC`, s.clock.Now())
+}
+
+// activeSilence returns the ID of an active silence matching the alert
+func (s *AlertService) activeSilence(ctx context.Context, alert *models.Alert, now time.Time) (string, bool, error) {
+	silences, err := s.silences(ctx, `SELECT * FROM alert_silences WHERE starts_at <= ? AND ends_at > ? ORDER BY id`, now, now)
+	if err != nil {
+		return "", false, err
+	}
+	labels := alertLabels(alert)
+	for _, silence := range silences {
+		if silenceMatches(silence, labels) {
+			return silence.ID, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func (s *AlertService) silences(ctx context.Context, query string, args ...interface{}) ([]models.AlertSilence, error) {
+	var silences []models.AlertSilence
+	if err := s.db.SelectContext(ctx, &silences, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for i := range silences {
+		if err := json.Unmarshal([]byte(silences[i].MatchersJSON), &silences[i].Matchers); err != nil {
+			return nil, fmt.Errorf("invalid matchers of silence %s: %w", silences[i].ID, err)
+		}
+	}
+	return silences, nil
+}
+
+// StoreSilences adds or replaces silences. With replaceAll, silences not in
+// the list are removed, making the list authoritative.
+func (s *AlertService) StoreSilences(ctx context.Context, silences []models.AlertSilence, replaceAll bool) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if replaceAll {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM alert_silences`); err != nil {
+			return fmt.Errorf("failed to clear silences: %w", err)
+		}
+	}
+
+	remove := tx.Rebind(`DELETE FROM alert_silences WHERE id = ?`)
+	insert := tx.Rebind(`
+		INSERT INTO alert_silences (id, matchers, starts_at, ends_at, created_by, comment, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	now := s.clock.Now()
+	for _, silence := range silences {
+		matchers, err := json.Marshal(silence.Matchers)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, remove, silence.ID); err != nil {
+			return fmt.Errorf("failed to replace silence %s: %w", silence.ID, err)
+		}
+		// Stored in local time like every other timestamp, so SQLite's text
+		// comparison against the current time holds
+		_, err = tx.ExecContext(ctx, insert, silence.ID, string(matchers), silence.StartsAt.Local(), silence.EndsAt.Local(),
+			silence.CreatedBy, silence.Comment, now)
+		if err != nil {
+			return fmt.Errorf("failed to store silence %s: %w", silence.ID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteSilence removes a silence, e.g. one expired early in Alertmanager
+func (s *AlertService) DeleteSilence(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(`DELETE FROM alert_silences WHERE id = ?`), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrSilenceNotFound
+	}
+	return nil
+}
+
+// SyncSilences replaces the stored silences with the current silences of
+// the configured Alertmanager and returns how many are active or pending
+func (s *AlertService) SyncSilences(ctx context.Context) (int, error) {
+	base := strings.TrimRight(s.cfg.Alertmanager.URL, "/")
+	if base == "" {
+		return 0, errors.New("alertmanager.url is not configured")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/api/v2/silences", nil)
+	if err != nil {
+		return 0, err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch silences: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("alertmanager returned %s", resp.Status)
+	}
+
+	silences, err := ParseAlertmanagerSilences(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.StoreSilences(ctx, silences, true); err != nil {
+		return 0, err
+	}
+	return len(silences), nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func silenceJSON(id, state string, starts, ends time.Time, matchers string) string {
+	return fmt.Sprintf(`{"id": %q, "matchers": [%s], "startsAt": %q, "endsAt": %q,
+		"createdBy": "ops", "comment": "maintenance", "status": {"state": %q}}`,
+		id, matchers, starts.Format(time.RFC3339), ends.Format(time.RFC3339), state)
+}
+
+func TestParseAlertmanagerSilences(t *testing.T) {
+	now := time.Now()
+	body := "[" + silenceJSON("a", "active", now.Add(-time.Hour), now.Add(time.Hour),
+		`{"name": "server", "value": "27000@a", "isRegex": false}`) + "," +
+		silenceJSON("b", "expired", now.Add(-2*time.Hour), now.Add(-time.Hour),
+			`{"name": "alertname", "value": "down", "isRegex": false, "isEqual": true}`) + "]"
+
+	silences, err := ParseAlertmanagerSilences(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("ParseAlertmanagerSilences failed: %v", err)
+	}
+	if len(silences) != 1 || silences[0].ID != "a" {
+		t.Fatalf("Expected only the active silence, got %+v", silences)
+	}
+	if m := silences[0].Matchers[0]; !m.IsEqual || m.IsRegex {
+		t.Errorf("Expected a missing isEqual to mean equal, got %+v", m)
+	}
+
+	single := silenceJSON("c", "pending", now, now.Add(time.Hour), `{"name": "job", "value": "licet", "isRegex": false}`)
+	if silences, err := ParseAlertmanagerSilences(strings.NewReader(single)); err != nil || len(silences) != 1 {
+		t.Errorf("Expected a single silence object to be accepted, got %v, %v", silences, err)
+	}
+
+	noMatchers := silenceJSON("d", "active", now, now.Add(time.Hour), "")
+	if _, err := ParseAlertmanagerSilences(strings.NewReader(noMatchers)); err == nil {
+		t.Error("Expected a silence without matchers to be rejected")
+	}
+}
+
+func TestSilenceMatches(t *testing.T) {
+	labels := alertLabels(&models.Alert{ServerHostname: "27000@lic1", FeatureName: "solver", AlertType: "down", Severity: "critical"})
+
+	tests := []struct {
+		name     string
+		matchers []models.SilenceMatcher
+		want     bool
+	}{
+		{"equal", []models.SilenceMatcher{{Name: "server", Value: "27000@lic1", IsEqual: true}}, true},
+		{"all must match", []models.SilenceMatcher{
+			{Name: "server", Value: "27000@lic1", IsEqual: true},
+			{Name: "alertname", Value: "expiration", IsEqual: true},
+		}, false},
+		{"regex is anchored", []models.SilenceMatcher{{Name: "server", Value: "27000@lic", IsRegex: true, IsEqual: true}}, false},
+		{"regex", []models.SilenceMatcher{{Name: "server", Value: "27000@lic.*", IsRegex: true, IsEqual: true}}, true},
+		{"not equal", []models.SilenceMatcher{{Name: "severity", Value: "info", IsEqual: false}}, true},
+		{"missing label is empty", []models.SilenceMatcher{{Name: "team", Value: "", IsEqual: true}}, true},
+		{"job", []models.SilenceMatcher{{Name: "job", Value: "licet", IsEqual: true}}, true},
+	}
+	for _, tt := range tests {
+		if got := silenceMatches(models.AlertSilence{ID: "s", Matchers: tt.matchers}, labels); got != tt.want {
+			t.Errorf("%s: silenceMatches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCreateAlert_Silenced(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/silences" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "["+silenceJSON("maint", "active", now.Add(-time.Hour), now.Add(time.Hour),
+			`{"name": "server", "value": "27000@a", "isRegex": false, "isEqual": true}`)+"]")
+	}))
+	defer server.Close()
+
+	s := NewAlertService(db, &config.Config{Alertmanager: config.AlertmanagerConfig{URL: server.URL + "/"}})
+	if count, err := s.SyncSilences(ctx); err != nil || count != 1 {
+		t.Fatalf("SyncSilences = %d, %v", count, err)
+	}
+	bus := NewEventBus()
+	s.SetEventBus(bus)
+	published, cancel := bus.SubscribeBuffered(AlertTopic, 2)
+	defer cancel()
+
+	silenced := &models.Alert{ServerHostname: "27000@a", AlertType: "down", Message: "down", Severity: "warning"}
+	other := &models.Alert{ServerHostname: "27000@b", AlertType: "down", Message: "down", Severity: "warning"}
+	for _, a := range []*models.Alert{silenced, other} {
+		if err := s.CreateAlert(ctx, a); err != nil {
+			t.Fatalf("CreateAlert failed: %v", err)
+		}
+	}
+	if silenced.SilenceID == nil || *silenced.SilenceID != "maint" {
+		t.Errorf("Expected alert to be suppressed by the silence, got %v", silenced.SilenceID)
+	}
+	if other.SilenceID != nil {
+		t.Errorf("Expected alert for another server not to be silenced")
+	}
+	if len(published) != 1 || (<-published).(models.Alert).ServerHostname != "27000@b" {
+		t.Errorf("Expected only the unsilenced alert to be published")
+	}
+
+	unsent, err := s.GetUnsentAlerts(ctx)
+	if err != nil {
+		t.Fatalf("GetUnsentAlerts failed: %v", err)
+	}
+	if len(unsent) != 1 || unsent[0].ServerHostname != "27000@b" {
+		t.Errorf("Expected only the unsilenced alert to be pending, got %+v", unsent)
+	}
+
+	// A silence removed in Alertmanager stops suppressing alerts
+	if err := s.DeleteSilence(ctx, "maint"); err != nil {
+		t.Fatalf("DeleteSilence failed: %v", err)
+	}
+	if err := s.DeleteSilence(ctx, "maint"); err != ErrSilenceNotFound {
+		t.Errorf("Expected ErrSilenceNotFound, got %v", err)
+	}
+	later := &models.Alert{ServerHostname: "27000@a", AlertType: "down", Message: "down", Severity: "warning"}
+	if err := s.CreateAlert(ctx, later); err != nil {
+		t.Fatalf("CreateAlert failed: %v", err)
+	}
+	if later.SilenceID != nil {
+		t.Error("Expected alert after the silence was removed not to be silenced")
+	}
+}
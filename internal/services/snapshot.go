@@ -0,0 +1,374 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"licet/internal/models"
+)
+
+// Snapshot archive format
+const (
+	SnapshotFormat  = "licet-snapshot"
+	SnapshotVersion = 1
+
+	snapshotManifest = "manifest.json"
+)
+
+var (
+	// ErrInvalidSnapshot is returned for archives that are not Licet snapshots
+	ErrInvalidSnapshot = errors.New("invalid snapshot archive")
+	// ErrSnapshotConflict is returned when importing a server that already has
+	// history without asking to replace it
+	ErrSnapshotConflict = errors.New("server already has history")
+)
+
+// Kinds of snapshot columns that need converting between databases
+const (
+	snapshotPlain     = iota
+	snapshotDate      // DATE, exported as 2006-01-02
+	snapshotTime      // TIME, exported as 15:04:05
+	snapshotTimestamp // TIMESTAMP, exported as RFC 3339 with zone
+	snapshotEncrypted // Stored encrypted, exported in clear
+)
+
+type snapshotColumn struct {
+	name string
+	kind int
+}
+
+// snapshotTable is a table holding per-server history. Row ids are not
+// exported, and neither are references to rows that stay behind such as the
+// incident of an alert.
+type snapshotTable struct {
+	name       string
+	hostColumn string
+	columns    []snapshotColumn
+}
+
+var snapshotTables = []snapshotTable{
+	{"features", "server_hostname", []snapshotColumn{
+		{"server_hostname", snapshotPlain}, {"name", snapshotPlain}, {"version", snapshotPlain},
+		{"vendor_daemon", snapshotPlain}, {"total_licenses", snapshotPlain}, {"used_licenses", snapshotPlain},
+		{"reserved_licenses", snapshotPlain}, {"overdraft_licenses", snapshotPlain},
+		{"license_model", snapshotPlain}, {"parse_quality", snapshotPlain},
+		{"expiration_date", snapshotTimestamp}, {"last_updated", snapshotTimestamp}, {"is_active", snapshotPlain},
+	}},
+	{"feature_usage", "server_hostname", []snapshotColumn{
+		{"server_hostname", snapshotPlain}, {"feature_name", snapshotPlain},
+		{"date", snapshotDate}, {"time", snapshotTime}, {"users_count", snapshotPlain},
+	}},
+	{"license_events", "server_hostname", []snapshotColumn{
+		{"server_hostname", snapshotPlain}, {"event_date", snapshotDate}, {"event_time", snapshotTime},
+		{"event_type", snapshotPlain}, {"feature_name", snapshotPlain}, {"username", snapshotEncrypted},
+		{"reason", snapshotPlain}, {"reason_code", snapshotPlain},
+	}},
+	{"feature_users", "server_hostname", []snapshotColumn{
+		{"server_hostname", snapshotPlain}, {"feature_name", snapshotPlain}, {"username", snapshotEncrypted},
+		{"first_seen", snapshotTimestamp}, {"last_seen", snapshotTimestamp},
+	}},
+	{"license_checkouts", "server_hostname", []snapshotColumn{
+		{"server_hostname", snapshotPlain}, {"feature_name", snapshotPlain}, {"username", snapshotEncrypted},
+		{"host", snapshotEncrypted}, {"checked_out_at", snapshotTimestamp}, {"last_seen", snapshotTimestamp},
+		{"checked_in_at", snapshotTimestamp},
+	}},
+	{"alerts", "server_hostname", []snapshotColumn{
+		{"server_hostname", snapshotPlain}, {"feature_name", snapshotPlain}, {"alert_type", snapshotPlain},
+		{"message", snapshotPlain}, {"severity", snapshotPlain}, {"sent", snapshotPlain},
+		{"sent_at", snapshotTimestamp}, {"created_at", snapshotTimestamp},
+	}},
+	{"alert_events", "hostname", []snapshotColumn{
+		{"datetime", snapshotTimestamp}, {"type", snapshotPlain}, {"hostname", snapshotPlain},
+	}},
+	{"server_master_events", "server_hostname", []snapshotColumn{
+		{"server_hostname", snapshotPlain}, {"previous_master", snapshotPlain},
+		{"new_master", snapshotPlain}, {"detected_at", snapshotTimestamp},
+	}},
+	{"feature_display_names", "server_hostname", []snapshotColumn{
+		{"server_hostname", snapshotPlain}, {"feature_name", snapshotPlain},
+		{"display_name", snapshotPlain}, {"created_at", snapshotTimestamp},
+	}},
+}
+
+func (t snapshotTable) columnNames() []string {
+	names := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// ExportSnapshot writes the full history of a server as a zip archive with a
+// manifest and one JSON Lines file per table. Timestamps keep their zone and
+// usernames are written in clear, so the archive can be imported into an
+// instance with a different database or encryption key.
+func (s *StorageService) ExportSnapshot(ctx context.Context, hostname string, w io.Writer) (*models.SnapshotManifest, error) {
+	manifest := &models.SnapshotManifest{
+		Format:     SnapshotFormat,
+		Version:    SnapshotVersion,
+		Server:     hostname,
+		ExportedAt: time.Now(),
+		Rows:       make(map[string]int64, len(snapshotTables)),
+	}
+
+	archive := zip.NewWriter(w)
+	for _, table := range snapshotTables {
+		f, err := archive.Create(table.name + ".jsonl")
+		if err != nil {
+			return nil, err
+		}
+		n, err := s.exportTable(ctx, table, hostname, f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", table.name, err)
+		}
+		manifest.Rows[table.name] = n
+	}
+
+	f, err := archive.Create(snapshotManifest)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(f).Encode(manifest); err != nil {
+		return nil, err
+	}
+	return manifest, archive.Close()
+}
+
+// exportTable writes the rows of a server in one table, one JSON object per line
+func (s *StorageService) exportTable(ctx context.Context, table snapshotTable, hostname string, w io.Writer) (int64, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = ? ORDER BY id`,
+		strings.Join(table.columnNames(), ", "), table.name, table.hostColumn)
+	rows, err := s.db.QueryxContext(ctx, s.db.Rebind(query), hostname)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	enc := json.NewEncoder(w)
+	var n int64
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return n, err
+		}
+		record := make(map[string]interface{}, len(values))
+		for i, c := range table.columns {
+			v, err := s.exportValue(c, values[i])
+			if err != nil {
+				return n, fmt.Errorf("%s: %w", c.name, err)
+			}
+			record[c.name] = v
+		}
+		if err := enc.Encode(record); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+// exportValue converts a scanned value to its database independent form
+func (s *StorageService) exportValue(c snapshotColumn, v interface{}) (interface{}, error) {
+	if b, ok := v.([]byte); ok {
+		v = string(b)
+	}
+	switch value := v.(type) {
+	case time.Time:
+		switch c.kind {
+		case snapshotDate:
+			return value.Format("2006-01-02"), nil
+		case snapshotTime:
+			return value.Format("15:04:05"), nil
+		}
+		return value, nil
+	case string:
+		if c.kind == snapshotEncrypted {
+			return s.cipher.Decrypt(value)
+		}
+	}
+	return v, nil
+}
+
+// ImportSnapshot restores a server history archive exported by ExportSnapshot,
+// keeping the original timestamps. A server that already has history is
+// refused unless replace is set, in which case its history is removed first.
+// The import runs in one transaction and is recorded in the audit log.
+func (s *StorageService) ImportSnapshot(ctx context.Context, r io.ReaderAt, size int64, replace bool, actor string) (*models.SnapshotImport, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	manifest, err := readSnapshotManifest(files[snapshotManifest])
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.SnapshotImport{
+		Server:     manifest.Server,
+		ExportedAt: manifest.ExportedAt,
+		Replaced:   replace,
+		Rows:       make(map[string]int64, len(snapshotTables)),
+		ImportedAt: time.Now(),
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, table := range snapshotTables {
+		if replace {
+			query := fmt.Sprintf(`DELETE FROM %s WHERE %s = ?`, table.name, table.hostColumn)
+			if _, err := tx.ExecContext(ctx, tx.Rebind(query), manifest.Server); err != nil {
+				return nil, fmt.Errorf("failed to clear %s: %w", table.name, err)
+			}
+			continue
+		}
+		var count int
+		query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s = ?`, table.name, table.hostColumn)
+		if err := tx.GetContext(ctx, &count, tx.Rebind(query), manifest.Server); err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, fmt.Errorf("%w: %s has %d rows in %s", ErrSnapshotConflict, manifest.Server, count, table.name)
+		}
+	}
+
+	for _, table := range snapshotTables {
+		f := files[table.name+".jsonl"]
+		if f == nil {
+			// Archives of older versions may lack tables added since
+			continue
+		}
+		insert := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s)`, table.name,
+			strings.Join(table.columnNames(), ", "), strings.TrimSuffix(strings.Repeat("?, ", len(table.columns)), ", "))
+		stmt, err := tx.PreparexContext(ctx, tx.Rebind(insert))
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare %s: %w", table.name, err)
+		}
+		n, err := s.importTable(ctx, table, manifest.Server, f, func(args []interface{}) error {
+			_, err := stmt.ExecContext(ctx, args...)
+			return err
+		})
+		stmt.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to import %s: %w", table.name, err)
+		}
+		report.Rows[table.name] = n
+		report.TotalRows += n
+	}
+
+	details, _ := json.Marshal(report.Rows)
+	_, err = tx.ExecContext(ctx, tx.Rebind(`
+		INSERT INTO audit_log (created_at, actor, action, target, details)
+		VALUES (?, ?, ?, ?, ?)
+	`), report.ImportedAt, actor, "import_snapshot", manifest.Server, string(details))
+	if err != nil {
+		return nil, fmt.Errorf("failed to write audit entry: %w", err)
+	}
+
+	return report, tx.Commit()
+}
+
+// readSnapshotManifest reads and checks the manifest of an archive
+func readSnapshotManifest(f *zip.File) (*models.SnapshotManifest, error) {
+	if f == nil {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidSnapshot, snapshotManifest)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var manifest models.SnapshotManifest
+	if err := json.NewDecoder(rc).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	if manifest.Format != SnapshotFormat {
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidSnapshot, manifest.Format)
+	}
+	if manifest.Version > SnapshotVersion {
+		return nil, fmt.Errorf("%w: version %d is newer than supported version %d", ErrInvalidSnapshot, manifest.Version, SnapshotVersion)
+	}
+	if manifest.Server == "" {
+		return nil, fmt.Errorf("%w: manifest has no server", ErrInvalidSnapshot)
+	}
+	return &manifest, nil
+}
+
+// importTable decodes the rows of one table file and passes their column
+// values to insert. Rows of other servers are rejected.
+func (s *StorageService) importTable(ctx context.Context, table snapshotTable, hostname string, f *zip.File, insert func([]interface{}) error) (int64, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(rc))
+	dec.UseNumber()
+	var n int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		var record map[string]interface{}
+		if err := dec.Decode(&record); err == io.EOF {
+			return n, nil
+		} else if err != nil {
+			return n, fmt.Errorf("%w: row %d: %v", ErrInvalidSnapshot, n+1, err)
+		}
+		if record[table.hostColumn] != hostname {
+			return n, fmt.Errorf("%w: row %d belongs to %v", ErrInvalidSnapshot, n+1, record[table.hostColumn])
+		}
+
+		args := make([]interface{}, len(table.columns))
+		for i, c := range table.columns {
+			v, err := s.importValue(c, record[c.name])
+			if err != nil {
+				return n, fmt.Errorf("%w: row %d: %s: %v", ErrInvalidSnapshot, n+1, c.name, err)
+			}
+			args[i] = v
+		}
+		if err := insert(args); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// importValue converts an exported value back to the form this database stores
+func (s *StorageService) importValue(c snapshotColumn, v interface{}) (interface{}, error) {
+	switch value := v.(type) {
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i, nil
+		}
+		return value.Float64()
+	case string:
+		switch c.kind {
+		case snapshotTimestamp:
+			// Stored in local time like everything this instance records
+			if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+				return t.Local(), nil
+			}
+		case snapshotEncrypted:
+			return s.cipher.Encrypt(value), nil
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestSnapshotExportImport(t *testing.T) {
+	ctx := context.Background()
+
+	srcDB := newTestDB(t)
+	src := NewStorageService(srcDB, "sqlite")
+	srcCipher, _ := NewFieldCipher(config.EncryptionConfig{Enabled: true, Key: "site"})
+	src.SetCipher(srcCipher)
+
+	features := []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", Version: "1.0", TotalLicenses: 10, UsedLicenses: 2, ExpirationDate: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ServerHostname: "27000@b", Name: "mesher", Version: "2.0", TotalLicenses: 5, UsedLicenses: 1, ExpirationDate: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	if err := src.StoreFeatures(ctx, features[:1]); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	if err := src.StoreFeatures(ctx, features[1:]); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	if err := src.RecordUsage(ctx, features); err != nil {
+		t.Fatalf("RecordUsage failed: %v", err)
+	}
+	checkout := time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+	alice := models.LicenseUser{ServerHostname: "27000@a", FeatureName: "solver", Username: "alice", Host: "ws1", CheckedOutAt: checkout}
+	if err := src.RecordCheckouts(ctx, "27000@a", []models.LicenseUser{alice}, checkout.Add(time.Hour)); err != nil {
+		t.Fatalf("RecordCheckouts failed: %v", err)
+	}
+	_, err := srcDB.Exec(`INSERT INTO alerts (server_hostname, feature_name, alert_type, message, severity, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		"27000@a", "solver", "utilization", "solver is busy", "warning", checkout.Local())
+	if err != nil {
+		t.Fatalf("Failed to insert alert: %v", err)
+	}
+
+	var archive bytes.Buffer
+	manifest, err := src.ExportSnapshot(ctx, "27000@a", &archive)
+	if err != nil {
+		t.Fatalf("ExportSnapshot failed: %v", err)
+	}
+	if manifest.Rows["features"] != 1 || manifest.Rows["feature_usage"] != 1 || manifest.Rows["license_checkouts"] != 1 || manifest.Rows["alerts"] != 1 {
+		t.Fatalf("Unexpected exported rows %v", manifest.Rows)
+	}
+
+	// The target instance uses another encryption key
+	dstDB := newTestDB(t)
+	dst := NewStorageService(dstDB, "sqlite")
+	dstCipher, _ := NewFieldCipher(config.EncryptionConfig{Enabled: true, Key: "central"})
+	dst.SetCipher(dstCipher)
+
+	report, err := dst.ImportSnapshot(ctx, bytes.NewReader(archive.Bytes()), int64(archive.Len()), false, "admin")
+	if err != nil {
+		t.Fatalf("ImportSnapshot failed: %v", err)
+	}
+	if report.Server != "27000@a" || report.TotalRows != 4 {
+		t.Fatalf("Unexpected import report %+v", report)
+	}
+
+	usage, err := dst.GetUserUsage(ctx, "alice", 100000)
+	if err != nil {
+		t.Fatalf("GetUserUsage failed: %v", err)
+	}
+	if len(usage) != 1 || usage[0].TotalHours != 1 {
+		t.Fatalf("Expected alice's hour of checkout to survive the import, got %+v", usage)
+	}
+
+	var createdAt time.Time
+	dstDB.Get(&createdAt, `SELECT created_at FROM alerts WHERE server_hostname = ?`, "27000@a")
+	if !createdAt.Equal(checkout) {
+		t.Errorf("Expected alert timestamp %v, got %v", checkout, createdAt)
+	}
+	var date string
+	dstDB.Get(&date, `SELECT CAST(date AS TEXT) FROM feature_usage`)
+	if date != time.Now().Format("2006-01-02") {
+		t.Errorf("Expected usage date stored as a plain date, got %q", date)
+	}
+
+	// Importing again would duplicate the history
+	_, err = dst.ImportSnapshot(ctx, bytes.NewReader(archive.Bytes()), int64(archive.Len()), false, "admin")
+	if !errors.Is(err, ErrSnapshotConflict) {
+		t.Fatalf("Expected ErrSnapshotConflict, got %v", err)
+	}
+	if _, err := dst.ImportSnapshot(ctx, bytes.NewReader(archive.Bytes()), int64(archive.Len()), true, "admin"); err != nil {
+		t.Fatalf("ImportSnapshot with replace failed: %v", err)
+	}
+	var count int
+	dstDB.Get(&count, `SELECT COUNT(*) FROM license_checkouts`)
+	if count != 1 {
+		t.Errorf("Expected replace to leave 1 checkout, got %d", count)
+	}
+	dstDB.Get(&count, `SELECT COUNT(*) FROM audit_log WHERE action = 'import_snapshot'`)
+	if count != 2 {
+		t.Errorf("Expected 2 audit entries, got %d", count)
+	}
+}
+
+func TestImportSnapshot_Invalid(t *testing.T) {
+	storage := NewStorageService(newTestDB(t), "sqlite")
+	data := []byte("not a zip")
+	_, err := storage.ImportSnapshot(context.Background(), bytes.NewReader(data), int64(len(data)), false, "admin")
+	if !errors.Is(err, ErrInvalidSnapshot) {
+		t.Fatalf("Expected ErrInvalidSnapshot, got %v", err)
+	}
+}
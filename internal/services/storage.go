@@ -2,18 +2,26 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"licet/internal/clock"
 	"licet/internal/database"
 	"licet/internal/models"
 )
 
 // StorageService handles feature storage and retrieval operations
 type StorageService struct {
-	db      *sqlx.DB
-	dialect database.Dialect
+	db       *sqlx.DB
+	dialect  database.Dialect
+	cipher   *FieldCipher
+	holidays *HolidayCalendar
+	clock    clock.Clock
 }
 
 // NewStorageService creates a new storage service
@@ -21,9 +29,25 @@ func NewStorageService(db *sqlx.DB, dbType string) *StorageService {
 	return &StorageService{
 		db:      db,
 		dialect: database.NewDialect(dbType),
+		clock:   clock.System,
 	}
 }
 
+// SetCipher enables encryption of stored usernames
+func (s *StorageService) SetCipher(c *FieldCipher) {
+	s.cipher = c
+}
+
+// SetClock sets the clock that stored usage and history periods are based on
+func (s *StorageService) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// SetHolidays sets the holiday calendar that analytics leave out of trends
+func (s *StorageService) SetHolidays(h *HolidayCalendar) {
+	s.holidays = h
+}
+
 // StoreFeatures stores features to the database using optimized batch operations.
 // It first marks all existing features for the server as inactive, then upserts
 // the new features as active. This ensures that replaced/removed licenses are
@@ -53,8 +77,17 @@ func (s *StorageService) StoreFeatures(ctx context.Context, features []models.Fe
 	}
 	defer stmt.Close()
 
-	now := time.Now()
+	now := s.clock.Now()
 	for _, feature := range features {
+		licenseModel := feature.LicenseModel
+		if licenseModel == "" {
+			licenseModel = models.LicenseModelFloating
+		}
+		parseQuality := feature.ParseQuality
+		if parseQuality == "" {
+			parseQuality = models.ParseQualityOK
+		}
+
 		_, err := stmt.ExecContext(ctx,
 			feature.ServerHostname,
 			feature.Name,
@@ -62,6 +95,12 @@ func (s *StorageService) StoreFeatures(ctx context.Context, features []models.Fe
 			feature.VendorDaemon,
 			feature.TotalLicenses,
 			feature.UsedLicenses,
+			feature.ReservedLicenses,
+			feature.BorrowedLicenses,
+			feature.OverdraftLicenses,
+			feature.QueuedRequests,
+			licenseModel,
+			parseQuality,
 			feature.ExpirationDate,
 			now,
 		)
@@ -70,6 +109,14 @@ func (s *StorageService) StoreFeatures(ctx context.Context, features []models.Fe
 		}
 	}
 
+	names := make([]string, 0, len(features))
+	for _, feature := range features {
+		names = append(names, feature.Name)
+	}
+	if err := s.registerResources(ctx, tx, hostname, names); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
@@ -85,7 +132,7 @@ func (s *StorageService) RecordUsage(ctx context.Context, features []models.Feat
 	}
 	defer tx.Rollback()
 
-	now := time.Now()
+	now := s.clock.Now()
 	date := now.Format("2006-01-02")
 	timeStr := now.Format("15:04:00")
 
@@ -95,6 +142,12 @@ func (s *StorageService) RecordUsage(ctx context.Context, features []models.Feat
 	}
 	defer stmt.Close()
 
+	// Requests queue per feature name, whichever pool a sample is kept for
+	queued := make(map[UsageKey]int)
+	for _, feature := range features {
+		queued[UsageKey{feature.ServerHostname, feature.Name}] += feature.QueuedRequests
+	}
+
 	for _, feature := range features {
 		_, err := stmt.ExecContext(ctx,
 			feature.ServerHostname,
@@ -102,6 +155,7 @@ func (s *StorageService) RecordUsage(ctx context.Context, features []models.Feat
 			date,
 			timeStr,
 			feature.UsedLicenses,
+			queued[UsageKey{feature.ServerHostname, feature.Name}],
 		)
 		if err != nil {
 			return fmt.Errorf("failed to record usage for %s: %w", feature.Name, err)
@@ -111,11 +165,108 @@ func (s *StorageService) RecordUsage(ctx context.Context, features []models.Feat
 	return tx.Commit()
 }
 
+// RecordUsers records that the given users were seen using their features.
+// The first sighting of a user on a feature is kept as first_seen.
+func (s *StorageService) RecordUsers(ctx context.Context, users []models.LicenseUser, at time.Time) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PreparexContext(ctx, s.dialect.UpsertFeatureUser())
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, user := range users {
+		if user.Username == "" {
+			continue
+		}
+		_, err := stmt.ExecContext(ctx, user.ServerHostname, user.FeatureName, s.cipher.Encrypt(user.Username), at, at)
+		if err != nil {
+			return fmt.Errorf("failed to record user %s: %w", user.Username, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetFirstSeenUsers returns users first seen on a feature within [since, until)
+func (s *StorageService) GetFirstSeenUsers(ctx context.Context, since, until time.Time) ([]models.FeatureUser, error) {
+	users := []models.FeatureUser{}
+	query := `
+		SELECT * FROM feature_users
+		WHERE first_seen >= ? AND first_seen < ?
+		ORDER BY server_hostname, feature_name, username
+	`
+	if err := s.db.SelectContext(ctx, &users, s.db.Rebind(query), since, until); err != nil {
+		return nil, err
+	}
+	return s.decryptUsers(users), nil
+}
+
+// GetLastSeenUsers returns users last seen on a feature within [since, until)
+func (s *StorageService) GetLastSeenUsers(ctx context.Context, since, until time.Time) ([]models.FeatureUser, error) {
+	users := []models.FeatureUser{}
+	query := `
+		SELECT * FROM feature_users
+		WHERE last_seen >= ? AND last_seen < ?
+		ORDER BY server_hostname, feature_name, username
+	`
+	if err := s.db.SelectContext(ctx, &users, s.db.Rebind(query), since, until); err != nil {
+		return nil, err
+	}
+	return s.decryptUsers(users), nil
+}
+
+// decryptUsers replaces stored usernames with their plaintext. Values that
+// cannot be decrypted are left as stored.
+func (s *StorageService) decryptUsers(users []models.FeatureUser) []models.FeatureUser {
+	for i := range users {
+		name, err := s.cipher.Decrypt(users[i].Username)
+		if err != nil {
+			log.Warnf("Failed to decrypt username: %v", err)
+			continue
+		}
+		users[i].Username = name
+	}
+	return users
+}
+
 // GetFeatures retrieves all active features for a server
 func (s *StorageService) GetFeatures(ctx context.Context, hostname string) ([]models.Feature, error) {
 	var features []models.Feature
-	query := `SELECT * FROM features WHERE server_hostname = ? AND is_active = 1 ORDER BY name`
-	err := s.db.SelectContext(ctx, &features, query, hostname)
+	query := `SELECT * FROM features WHERE server_hostname = ? AND is_active = TRUE ORDER BY name`
+	err := s.db.SelectContext(ctx, &features, s.db.Rebind(query), hostname)
+	return features, err
+}
+
+// CountFeatures returns the number of active features of a server, or of
+// every server when hostname is empty. Versions count once.
+func (s *StorageService) CountFeatures(ctx context.Context, hostname string) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM (SELECT DISTINCT server_hostname, name FROM features WHERE is_active = TRUE`
+	args := []interface{}{}
+	if hostname != "" {
+		query += ` AND server_hostname = ?`
+		args = append(args, hostname)
+	}
+	query += `) f`
+	err := s.db.GetContext(ctx, &count, s.db.Rebind(query), args...)
+	return count, err
+}
+
+// GetActiveFeatures retrieves the active features of every server
+func (s *StorageService) GetActiveFeatures(ctx context.Context) ([]models.Feature, error) {
+	var features []models.Feature
+	query := `SELECT * FROM features WHERE is_active = TRUE ORDER BY server_hostname, name, version`
+	err := s.db.SelectContext(ctx, &features, s.db.Rebind(query))
 	return features, err
 }
 
@@ -123,7 +274,7 @@ func (s *StorageService) GetFeatures(ctx context.Context, hostname string) ([]mo
 func (s *StorageService) GetAllFeatures(ctx context.Context, hostname string) ([]models.Feature, error) {
 	var features []models.Feature
 	query := `SELECT * FROM features WHERE server_hostname = ? ORDER BY name`
-	err := s.db.SelectContext(ctx, &features, query, hostname)
+	err := s.db.SelectContext(ctx, &features, s.db.Rebind(query), hostname)
 	return features, err
 }
 
@@ -132,14 +283,15 @@ func (s *StorageService) GetFeaturesWithExpiration(ctx context.Context, hostname
 	var features []models.Feature
 	query := `
 		SELECT id, server_hostname, name, version, vendor_daemon,
-		       total_licenses, used_licenses, expiration_date, last_updated, is_active
+		       total_licenses, used_licenses, reserved_licenses, overdraft_licenses,
+		       license_model, parse_quality, expiration_date, last_updated, is_active
 		FROM features
 		WHERE server_hostname = ?
 		  AND expiration_date IS NOT NULL
-		  AND is_active = 1
+		  AND is_active = TRUE
 		ORDER BY expiration_date ASC, name ASC
 	`
-	err := s.db.SelectContext(ctx, &features, query, hostname)
+	err := s.db.SelectContext(ctx, &features, s.db.Rebind(query), hostname)
 	return features, err
 }
 
@@ -148,7 +300,8 @@ func (s *StorageService) GetAllFeaturesWithExpiration(ctx context.Context, hostn
 	var features []models.Feature
 	query := `
 		SELECT f.id, f.server_hostname, f.name, f.version, f.vendor_daemon,
-		       f.total_licenses, f.used_licenses, f.expiration_date, f.last_updated, f.is_active
+		       f.total_licenses, f.used_licenses, f.reserved_licenses, f.overdraft_licenses,
+		       f.license_model, f.parse_quality, f.expiration_date, f.last_updated, f.is_active
 		FROM features f
 		INNER JOIN (
 			SELECT server_hostname, name, version, expiration_date, MAX(id) as max_id
@@ -159,28 +312,43 @@ func (s *StorageService) GetAllFeaturesWithExpiration(ctx context.Context, hostn
 		) latest ON f.id = latest.max_id
 		ORDER BY f.is_active DESC, f.expiration_date ASC, f.name ASC
 	`
-	err := s.db.SelectContext(ctx, &features, query, hostname)
+	err := s.db.SelectContext(ctx, &features, s.db.Rebind(query), hostname)
 	return features, err
 }
 
 // GetExpiringFeatures returns active features expiring within the specified number of days
 func (s *StorageService) GetExpiringFeatures(ctx context.Context, days int) ([]models.Feature, error) {
 	var features []models.Feature
-	cutoff := time.Now().AddDate(0, 0, days)
+	cutoff := s.clock.Now().AddDate(0, 0, days)
 
 	query := `
 		SELECT * FROM features
-		WHERE expiration_date <= ? AND expiration_date > ? AND is_active = 1
+		WHERE expiration_date <= ? AND expiration_date > ? AND is_active = TRUE
 		ORDER BY expiration_date ASC
 	`
-	err := s.db.SelectContext(ctx, &features, query, cutoff, time.Now())
+	err := s.db.SelectContext(ctx, &features, s.db.Rebind(query), cutoff, s.clock.Now())
+	return features, err
+}
+
+// GetDegradedFeatures returns active features whose data was only parsed by
+// falling back to defaults, optionally limited to a server
+func (s *StorageService) GetDegradedFeatures(ctx context.Context, hostname string) ([]models.Feature, error) {
+	var features []models.Feature
+	query := `SELECT * FROM features WHERE parse_quality <> ? AND is_active = TRUE`
+	args := []interface{}{models.ParseQualityOK}
+	if hostname != "" {
+		query += ` AND server_hostname = ?`
+		args = append(args, hostname)
+	}
+	query += ` ORDER BY server_hostname, name`
+	err := s.db.SelectContext(ctx, &features, s.db.Rebind(query), args...)
 	return features, err
 }
 
 // GetFeatureUsageHistory returns historical usage data for a specific feature
 func (s *StorageService) GetFeatureUsageHistory(ctx context.Context, hostname, featureName string, days int) ([]models.FeatureUsage, error) {
 	var usage []models.FeatureUsage
-	cutoff := time.Now().AddDate(0, 0, -days)
+	cutoff := s.clock.Now().AddDate(0, 0, -days)
 
 	query := `
 		SELECT * FROM feature_usage
@@ -190,3 +358,254 @@ func (s *StorageService) GetFeatureUsageHistory(ctx context.Context, hostname, f
 	err := s.db.SelectContext(ctx, &usage, query, hostname, featureName, cutoff)
 	return usage, err
 }
+
+// UsageKey identifies a feature in batched usage history
+type UsageKey struct {
+	ServerHostname string
+	FeatureName    string
+}
+
+// GetUsageHistoryBatch retrieves the usage history of every feature in one
+// query, optionally limited to a server. Each feature's history is ordered
+// newest first, like GetFeatureUsageHistory.
+func (s *StorageService) GetUsageHistoryBatch(ctx context.Context, hostname string, days int) (map[UsageKey][]models.FeatureUsage, error) {
+	var usage []models.FeatureUsage
+	cutoff := s.clock.Now().AddDate(0, 0, -days)
+
+	query := `SELECT * FROM feature_usage WHERE date >= ?`
+	args := []interface{}{cutoff}
+	if hostname != "" {
+		query += ` AND server_hostname = ?`
+		args = append(args, hostname)
+	}
+	query += ` ORDER BY server_hostname, feature_name, date DESC, time DESC`
+
+	if err := s.db.SelectContext(ctx, &usage, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	history := make(map[UsageKey][]models.FeatureUsage)
+	for _, u := range usage {
+		key := UsageKey{u.ServerHostname, u.FeatureName}
+		history[key] = append(history[key], u)
+	}
+	return history, nil
+}
+
+// GetCollectionTimestamps returns the latest collection time for each server,
+// or only for the given server when hostname is non-empty
+func (s *StorageService) GetCollectionTimestamps(ctx context.Context, hostname string) (map[string]time.Time, error) {
+	var rows []struct {
+		ServerHostname string    `db:"server_hostname"`
+		LastUpdated    time.Time `db:"last_updated"`
+	}
+
+	// Grouping on the bare column (rather than selecting MAX()) keeps the column
+	// type information so drivers return a time value instead of a string.
+	query := `
+		SELECT f.server_hostname, f.last_updated
+		FROM features f
+		WHERE f.last_updated = (
+			SELECT MAX(last_updated) FROM features WHERE server_hostname = f.server_hostname
+		)
+	`
+	args := []interface{}{}
+	if hostname != "" {
+		query += ` AND f.server_hostname = ?`
+		args = append(args, hostname)
+	}
+	query += ` GROUP BY f.server_hostname, f.last_updated`
+
+	if err := s.db.SelectContext(ctx, &rows, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	timestamps := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		timestamps[row.ServerHostname] = row.LastUpdated
+	}
+	return timestamps, nil
+}
+
+// Feature metadata shown alongside collected data, whose changes are recorded
+// in metadata_revisions
+const (
+	metadataDisplayNames = "display_names"
+	metadataFeatureTags  = "feature_tags"
+	metadataContracts    = "license_contracts"
+)
+
+// GetDataVersions returns the collection timestamps of GetCollectionTimestamps
+// together with when the display names, tags and contracts last changed, keyed
+// "metadata:<name>", so responses showing them are versioned by both
+func (s *StorageService) GetDataVersions(ctx context.Context, hostname string) (map[string]time.Time, error) {
+	versions, err := s.GetCollectionTimestamps(ctx, hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Name      string    `db:"name"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, `SELECT name, updated_at FROM metadata_revisions`); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		versions["metadata:"+row.Name] = row.UpdatedAt
+	}
+	return versions, nil
+}
+
+// touchMetadata records that the named feature metadata changed at the given
+// time, which changes the data versions of the responses showing it
+func touchMetadata(ctx context.Context, db sqlx.ExtContext, name string, at time.Time) error {
+	query := db.Rebind(`UPDATE metadata_revisions SET updated_at = ? WHERE name = ?`)
+	if _, err := db.ExecContext(ctx, query, at, name); err != nil {
+		return fmt.Errorf("failed to record the %s revision: %w", name, err)
+	}
+	return nil
+}
+
+// MergeDuplicateFeatures merges feature rows that differ only by an empty
+// version into the matching versioned row for the same server, name and
+// expiration date, so they are not counted twice. It returns the number of
+// rows removed.
+func (s *StorageService) MergeDuplicateFeatures(ctx context.Context) (int, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var features []models.Feature
+	if err := tx.SelectContext(ctx, &features, `SELECT * FROM features ORDER BY id`); err != nil {
+		return 0, err
+	}
+
+	merged := 0
+	for _, group := range duplicateFeatures(features) {
+		keeper := group.keeper
+
+		// The most recently collected row wins for counts and active state
+		for _, dup := range group.duplicates {
+			if keeper.VendorDaemon == "" {
+				keeper.VendorDaemon = dup.VendorDaemon
+			}
+			if dup.LastUpdated.After(keeper.LastUpdated) {
+				keeper.TotalLicenses = dup.TotalLicenses
+				keeper.UsedLicenses = dup.UsedLicenses
+				keeper.LastUpdated = dup.LastUpdated
+				keeper.IsActive = dup.IsActive
+			}
+
+			if _, err := tx.ExecContext(ctx, s.db.Rebind(`DELETE FROM features WHERE id = ?`), dup.ID); err != nil {
+				return 0, fmt.Errorf("failed to remove duplicate feature %d: %w", dup.ID, err)
+			}
+			merged++
+		}
+
+		_, err := tx.ExecContext(ctx, s.db.Rebind(`
+			UPDATE features
+			SET vendor_daemon = ?, total_licenses = ?, used_licenses = ?, last_updated = ?, is_active = ?
+			WHERE id = ?
+		`), keeper.VendorDaemon, keeper.TotalLicenses, keeper.UsedLicenses, keeper.LastUpdated, keeper.IsActive, keeper.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to merge into feature %d: %w", keeper.ID, err)
+		}
+	}
+
+	return merged, tx.Commit()
+}
+
+// featureDuplicates is a versioned feature row and the rows for the same
+// server, name and expiration date that lack a version
+type featureDuplicates struct {
+	keeper     *models.Feature // Most recently collected versioned row
+	duplicates []models.Feature
+}
+
+// duplicateFeatures finds the unversioned rows MergeDuplicateFeatures merges
+// into their versioned counterparts, ordered by the keeper's ID
+func duplicateFeatures(features []models.Feature) []featureDuplicates {
+	groups := make(map[string][]models.Feature)
+	for _, f := range features {
+		key := f.ServerHostname + "|" + f.Name + "|" + f.ExpirationDate.UTC().Format(time.RFC3339)
+		groups[key] = append(groups[key], f)
+	}
+
+	var found []featureDuplicates
+	for _, group := range groups {
+		var keeper *models.Feature
+		var unversioned []models.Feature
+		for i := range group {
+			if group[i].Version == "" {
+				unversioned = append(unversioned, group[i])
+			} else if keeper == nil || group[i].LastUpdated.After(keeper.LastUpdated) {
+				keeper = &group[i]
+			}
+		}
+		if keeper != nil && len(unversioned) > 0 {
+			found = append(found, featureDuplicates{keeper: keeper, duplicates: unversioned})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].keeper.ID < found[j].keeper.ID })
+	return found
+}
+
+// RecordMaster stores the current MASTER host of a license server. It returns
+// the change when the master differs from the last one seen, or nil when it is
+// unchanged or the server has not been seen before.
+func (s *StorageService) RecordMaster(ctx context.Context, hostname, master string, at time.Time) (*models.MasterChange, error) {
+	var last string
+	err := s.db.GetContext(ctx, &last, s.db.Rebind(`
+		SELECT new_master FROM server_master_events
+		WHERE server_hostname = ?
+		ORDER BY detected_at DESC, id DESC
+		LIMIT 1
+	`), hostname)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get last master for %s: %w", hostname, err)
+	}
+	if last == master {
+		return nil, nil
+	}
+
+	change := &models.MasterChange{
+		ServerHostname: hostname,
+		PreviousMaster: last,
+		NewMaster:      master,
+		DetectedAt:     at,
+	}
+	_, err = s.db.ExecContext(ctx, s.db.Rebind(`
+		INSERT INTO server_master_events (server_hostname, previous_master, new_master, detected_at)
+		VALUES (?, ?, ?, ?)
+	`), change.ServerHostname, change.PreviousMaster, change.NewMaster, change.DetectedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record master for %s: %w", hostname, err)
+	}
+
+	if last == "" {
+		return nil, nil
+	}
+	return change, nil
+}
+
+// GetMasterChanges returns master failovers within the given number of days,
+// newest first. An empty hostname returns failovers for all servers.
+func (s *StorageService) GetMasterChanges(ctx context.Context, hostname string, days int) ([]models.MasterChange, error) {
+	changes := []models.MasterChange{}
+	query := `
+		SELECT * FROM server_master_events
+		WHERE previous_master <> '' AND detected_at >= ?
+	`
+	args := []interface{}{s.clock.Now().AddDate(0, 0, -days)}
+	if hostname != "" {
+		query += " AND server_hostname = ?"
+		args = append(args, hostname)
+	}
+	query += " ORDER BY detected_at DESC, id DESC"
+
+	err := s.db.SelectContext(ctx, &changes, s.db.Rebind(query), args...)
+	return changes, err
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"licet/internal/config"
+	"licet/internal/database"
+	"licet/internal/models"
+)
+
+// newTestDB creates a migrated SQLite database in a temporary directory
+func newTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	db, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "licet_test.db"))
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.RunMigrations(db, "sqlite"); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	return db
+}
+
+func TestGetCollectionTimestamps(t *testing.T) {
+	db := newTestDB(t)
+	storage := NewStorageService(db, "sqlite")
+	ctx := context.Background()
+
+	features := []models.Feature{
+		{ServerHostname: "27000@a", Name: "feat1", Version: "1.0", TotalLicenses: 10, ExpirationDate: time.Now().AddDate(1, 0, 0)},
+		{ServerHostname: "27000@a", Name: "feat2", Version: "1.0", TotalLicenses: 5, ExpirationDate: time.Now().AddDate(1, 0, 0)},
+	}
+	if err := storage.StoreFeatures(ctx, features); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	if err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "5053@b", Name: "feat3", Version: "2.0", TotalLicenses: 1, ExpirationDate: time.Now().AddDate(1, 0, 0)},
+	}); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	all, err := storage.GetCollectionTimestamps(ctx, "")
+	if err != nil {
+		t.Fatalf("GetCollectionTimestamps failed: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("Expected 2 servers, got %d", len(all))
+	}
+	if all["27000@a"].IsZero() || all["5053@b"].IsZero() {
+		t.Errorf("Expected non-zero timestamps, got %v", all)
+	}
+
+	first := all["27000@a"]
+	time.Sleep(10 * time.Millisecond)
+	if err := storage.StoreFeatures(ctx, features); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	single, err := storage.GetCollectionTimestamps(ctx, "27000@a")
+	if err != nil {
+		t.Fatalf("GetCollectionTimestamps failed: %v", err)
+	}
+	if len(single) != 1 {
+		t.Fatalf("Expected 1 server, got %d", len(single))
+	}
+	if !single["27000@a"].After(first) {
+		t.Errorf("Expected timestamp to advance after new collection: %v -> %v", first, single["27000@a"])
+	}
+}
+
+func TestGetDataVersions_MetadataEdits(t *testing.T) {
+	db := newTestDB(t)
+	storage := NewStorageService(db, "sqlite")
+	names, err := NewDisplayNameService(db, config.DisplayConfig{})
+	if err != nil {
+		t.Fatalf("NewDisplayNameService failed: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", Version: "1.0", TotalLicenses: 10, ExpirationDate: time.Now().AddDate(1, 0, 0)},
+	}); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	versions := func() map[string]time.Time {
+		t.Helper()
+		v, err := storage.GetDataVersions(ctx, "27000@a")
+		if err != nil {
+			t.Fatalf("GetDataVersions failed: %v", err)
+		}
+		return v
+	}
+
+	before := versions()
+	if len(before) != 4 || before["27000@a"].IsZero() || before["metadata:display_names"].IsZero() {
+		t.Fatalf("Expected the collection and three metadata versions, got %v", before)
+	}
+
+	edits := []struct {
+		key  string
+		edit func() error
+	}{
+		{"metadata:display_names", func() error { return names.SetOverride(ctx, "", "solver", "Solver") }},
+		{"metadata:display_names", func() error { return names.DeleteOverride(ctx, "", "solver") }},
+		{"metadata:feature_tags", func() error {
+			return storage.TagFeature(ctx, &models.FeatureTag{FeatureName: "solver", Tag: "cfd"})
+		}},
+		{"metadata:license_contracts", func() error {
+			return storage.CreateContract(ctx, &models.LicenseContract{FeatureName: "solver", CostPerSeat: 1200})
+		}},
+	}
+	for _, e := range edits {
+		time.Sleep(10 * time.Millisecond)
+		if err := e.edit(); err != nil {
+			t.Fatalf("Editing %s failed: %v", e.key, err)
+		}
+		after := versions()
+		if !after[e.key].After(before[e.key]) {
+			t.Errorf("Expected %s to advance: %v -> %v", e.key, before[e.key], after[e.key])
+		}
+		if !after["27000@a"].Equal(before["27000@a"]) {
+			t.Errorf("Expected the collection time to stay, got %v", after["27000@a"])
+		}
+		before = after
+	}
+}
+
+func TestMergeDuplicateFeatures(t *testing.T) {
+	db := newTestDB(t)
+	storage := NewStorageService(db, "sqlite")
+	ctx := context.Background()
+
+	exp := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	older := time.Now().Add(-time.Hour)
+	newer := time.Now()
+
+	insert := `INSERT INTO features (server_hostname, name, version, vendor_daemon, total_licenses, used_licenses, expiration_date, last_updated, is_active)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1)`
+	db.MustExec(insert, "27000@a", "F1", "2.0", "", 10, 3, exp, older)
+	db.MustExec(insert, "27000@a", "F1", "", "vendord", 10, 7, exp, newer)
+	// Different expiration date is a distinct license, not a duplicate
+	db.MustExec(insert, "27000@a", "F1", "", "vendord", 5, 1, exp.AddDate(1, 0, 0), newer)
+
+	merged, err := storage.MergeDuplicateFeatures(ctx)
+	if err != nil {
+		t.Fatalf("MergeDuplicateFeatures failed: %v", err)
+	}
+	if merged != 1 {
+		t.Errorf("Expected 1 merged row, got %d", merged)
+	}
+
+	features, err := storage.GetFeatures(ctx, "27000@a")
+	if err != nil {
+		t.Fatalf("GetFeatures failed: %v", err)
+	}
+	if len(features) != 2 {
+		t.Fatalf("Expected 2 features after merge, got %d", len(features))
+	}
+	for _, f := range features {
+		if f.ExpirationDate.Equal(exp) {
+			if f.Version != "2.0" || f.VendorDaemon != "vendord" || f.UsedLicenses != 7 {
+				t.Errorf("Unexpected merged feature: %+v", f)
+			}
+		}
+	}
+
+	// Running again is a no-op
+	if merged, _ := storage.MergeDuplicateFeatures(ctx); merged != 0 {
+		t.Errorf("Expected no further merges, got %d", merged)
+	}
+}
+
+func TestStoreFeatures_ReservedSeatsExcludedFromAvailable(t *testing.T) {
+	db := newTestDB(t)
+	storage := NewStorageService(db, "sqlite")
+	analytics := NewAnalyticsService(db, storage, "sqlite")
+	ctx := context.Background()
+
+	err := storage.StoreFeatures(ctx, []models.Feature{{
+		ServerHostname:    "27000@a",
+		Name:              "solver",
+		Version:           "1.0",
+		TotalLicenses:     10,
+		UsedLicenses:      4,
+		ReservedLicenses:  3,
+		OverdraftLicenses: 2,
+		ExpirationDate:    time.Now().AddDate(1, 0, 0),
+	}})
+	if err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	utilization, err := analytics.GetCurrentUtilization(ctx, "27000@a")
+	if err != nil {
+		t.Fatalf("GetCurrentUtilization failed: %v", err)
+	}
+	if len(utilization) != 1 {
+		t.Fatalf("Expected 1 utilization row, got %d", len(utilization))
+	}
+
+	u := utilization[0]
+	if u.ReservedLicenses != 3 || u.OverdraftLicenses != 2 {
+		t.Errorf("Expected 3 reserved and 2 overdraft, got %d and %d", u.ReservedLicenses, u.OverdraftLicenses)
+	}
+	if u.AvailableLicenses != 3 {
+		t.Errorf("Expected 3 available licenses, got %d", u.AvailableLicenses)
+	}
+	if u.UtilizationPct != 40 {
+		t.Errorf("Expected 40%% utilization, got %.1f", u.UtilizationPct)
+	}
+}
+
+func TestGetDegradedFeatures(t *testing.T) {
+	db := newTestDB(t)
+	storage := NewStorageService(db, "sqlite")
+	ctx := context.Background()
+
+	features := []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", TotalLicenses: 5, ExpirationDate: time.Now().AddDate(0, 1, 0)},
+		{ServerHostname: "27000@a", Name: "mesher", TotalLicenses: 5, ExpirationDate: time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC),
+			ParseQuality: models.ParseQualityDefaultedExpiration},
+	}
+	if err := storage.StoreFeatures(ctx, features); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	degraded, err := storage.GetDegradedFeatures(ctx, "")
+	if err != nil {
+		t.Fatalf("GetDegradedFeatures failed: %v", err)
+	}
+	if len(degraded) != 1 || degraded[0].Name != "mesher" || degraded[0].ParseQuality != models.ParseQualityDefaultedExpiration {
+		t.Fatalf("Expected only mesher to be degraded, got %+v", degraded)
+	}
+
+	// Once the date parses again the feature is no longer degraded
+	features[1].ParseQuality = models.ParseQualityOK
+	if err := storage.StoreFeatures(ctx, features); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	if degraded, _ := storage.GetDegradedFeatures(ctx, "27000@a"); len(degraded) != 0 {
+		t.Errorf("Expected no degraded features, got %+v", degraded)
+	}
+}
+
+func TestGetCurrentUtilization_ExcludesUncounted(t *testing.T) {
+	db := newTestDB(t)
+	storage := NewStorageService(db, "sqlite")
+	analytics := NewAnalyticsService(db, storage, "sqlite")
+	ctx := context.Background()
+
+	exp := time.Now().AddDate(1, 0, 0)
+	err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", Version: "1.0", TotalLicenses: 10, UsedLicenses: 5, ExpirationDate: exp},
+		{ServerHostname: "27000@a", Name: "viewer", TotalLicenses: 9999, LicenseModel: models.LicenseModelUncounted},
+	})
+	if err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	features, err := storage.GetFeatures(ctx, "27000@a")
+	if err != nil {
+		t.Fatalf("GetFeatures failed: %v", err)
+	}
+	for _, f := range features {
+		if f.Name == "solver" && f.LicenseModel != models.LicenseModelFloating {
+			t.Errorf("Expected features without a model to be stored as floating, got '%s'", f.LicenseModel)
+		}
+	}
+
+	utilization, err := analytics.GetCurrentUtilization(ctx, "")
+	if err != nil {
+		t.Fatalf("GetCurrentUtilization failed: %v", err)
+	}
+	if len(utilization) != 1 || utilization[0].FeatureName != "solver" {
+		t.Errorf("Expected only the counted feature in utilization, got %+v", utilization)
+	}
+}
+
+func TestRecordMaster(t *testing.T) {
+	db := newTestDB(t)
+	storage := NewStorageService(db, "sqlite")
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour)
+
+	// First observation is a baseline, not a failover
+	change, err := storage.RecordMaster(ctx, "27000@a,27000@b,27000@c", "a", start)
+	if err != nil || change != nil {
+		t.Fatalf("Expected no change for first observation, got %+v, %v", change, err)
+	}
+
+	change, err = storage.RecordMaster(ctx, "27000@a,27000@b,27000@c", "a", start.Add(time.Minute))
+	if err != nil || change != nil {
+		t.Fatalf("Expected no change for same master, got %+v, %v", change, err)
+	}
+
+	change, err = storage.RecordMaster(ctx, "27000@a,27000@b,27000@c", "b", start.Add(2*time.Minute))
+	if err != nil {
+		t.Fatalf("RecordMaster failed: %v", err)
+	}
+	if change == nil || change.PreviousMaster != "a" || change.NewMaster != "b" {
+		t.Fatalf("Expected failover from a to b, got %+v", change)
+	}
+
+	failovers, err := storage.GetMasterChanges(ctx, "27000@a,27000@b,27000@c", 1)
+	if err != nil {
+		t.Fatalf("GetMasterChanges failed: %v", err)
+	}
+	if len(failovers) != 1 || failovers[0].NewMaster != "b" {
+		t.Errorf("Expected one failover to b, got %+v", failovers)
+	}
+
+	if failovers, _ := storage.GetMasterChanges(ctx, "27000@other", 1); len(failovers) != 0 {
+		t.Errorf("Expected no failovers for another server, got %d", len(failovers))
+	}
+}
+
+func TestStoreFeatures_UpdatesInPlace(t *testing.T) {
+	db := newTestDB(t)
+	storage := NewStorageService(db, "sqlite")
+	ctx := context.Background()
+
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	solver := models.Feature{ServerHostname: "27000@a", Name: "solver", Version: "1.0", TotalLicenses: 10, UsedLicenses: 4, ExpirationDate: expires}
+	mesher := models.Feature{ServerHostname: "27000@a", Name: "mesher", Version: "1.0", TotalLicenses: 5, UsedLicenses: 1, ExpirationDate: expires}
+	if err := storage.StoreFeatures(ctx, []models.Feature{solver, mesher}); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	var firstID int64
+	if err := db.Get(&firstID, `SELECT id FROM features WHERE name = 'solver'`); err != nil {
+		t.Fatalf("Failed to read feature: %v", err)
+	}
+
+	solver.UsedLicenses = 7
+	if err := storage.StoreFeatures(ctx, []models.Feature{solver}); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	features, err := storage.GetFeatures(ctx, "27000@a")
+	if err != nil {
+		t.Fatalf("GetFeatures failed: %v", err)
+	}
+	if len(features) != 1 || features[0].ID != firstID || features[0].UsedLicenses != 7 {
+		t.Errorf("Expected solver to be updated in place with id %d, got %+v", firstID, features)
+	}
+	var rows int
+	db.Get(&rows, `SELECT COUNT(*) FROM features`)
+	if rows != 2 {
+		t.Errorf("Expected mesher to be kept as inactive, got %d rows", rows)
+	}
+}
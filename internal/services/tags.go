@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"licet/internal/models"
+)
+
+var (
+	// ErrFeatureTagNotFound is returned for feature tags that do not exist
+	ErrFeatureTagNotFound = errors.New("feature tag not found")
+	// ErrInvalidFeatureTag is returned for feature tags that cannot be stored
+	ErrInvalidFeatureTag = errors.New("invalid feature tag")
+)
+
+// tagPattern matches the tags that can be assigned, e.g. "CAD" or "team-a"
+var tagPattern = regexp.MustCompile(`^[\pL\pN][\pL\pN _.-]{0,63}$`)
+
+// GetFeatureTags returns the tag assignments, of one tag or of all with an
+// empty tag
+func (s *StorageService) GetFeatureTags(ctx context.Context, tag string) ([]models.FeatureTag, error) {
+	tags := []models.FeatureTag{}
+	query := `SELECT * FROM feature_tags`
+	args := []interface{}{}
+	if tag != "" {
+		query += ` WHERE tag = ?`
+		args = append(args, tag)
+	}
+	query += ` ORDER BY tag, feature_name, server_hostname`
+	err := s.db.SelectContext(ctx, &tags, s.db.Rebind(query), args...)
+	return tags, err
+}
+
+// GetTagSummaries returns every tag with the number of features carrying it
+func (s *StorageService) GetTagSummaries(ctx context.Context) ([]models.TagSummary, error) {
+	summaries := []models.TagSummary{}
+	query := `SELECT tag, COUNT(*) AS features FROM feature_tags GROUP BY tag ORDER BY tag`
+	err := s.db.SelectContext(ctx, &summaries, query)
+	return summaries, err
+}
+
+// TagFeature assigns a tag to a feature, setting the ID of the assignment.
+// Assigning a tag the feature already carries returns that assignment.
+func (s *StorageService) TagFeature(ctx context.Context, t *models.FeatureTag) error {
+	t.ServerHostname = strings.TrimSpace(t.ServerHostname)
+	t.FeatureName = strings.TrimSpace(t.FeatureName)
+	t.Tag = strings.TrimSpace(t.Tag)
+	if t.FeatureName == "" {
+		return fmt.Errorf("%w: feature_name is required", ErrInvalidFeatureTag)
+	}
+	if !tagPattern.MatchString(t.Tag) {
+		return fmt.Errorf("%w: tag must be 1-64 letters, digits, spaces, dots, dashes or underscores", ErrInvalidFeatureTag)
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT * FROM feature_tags WHERE server_hostname = ? AND feature_name = ? AND tag = ?`
+	err = tx.GetContext(ctx, t, tx.Rebind(query), t.ServerHostname, t.FeatureName, t.Tag)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	t.CreatedAt = s.clock.Now()
+	query = `INSERT INTO feature_tags (server_hostname, feature_name, tag, created_by, created_at) VALUES (?, ?, ?, ?, ?)`
+	if _, err := tx.ExecContext(ctx, tx.Rebind(query), t.ServerHostname, t.FeatureName, t.Tag, t.CreatedBy, t.CreatedAt); err != nil {
+		return fmt.Errorf("failed to store feature tag: %w", err)
+	}
+	query = `SELECT id FROM feature_tags WHERE server_hostname = ? AND feature_name = ? AND tag = ?`
+	if err := tx.GetContext(ctx, &t.ID, tx.Rebind(query), t.ServerHostname, t.FeatureName, t.Tag); err != nil {
+		return fmt.Errorf("failed to store feature tag: %w", err)
+	}
+	if err := touchMetadata(ctx, tx, metadataFeatureTags, t.CreatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UntagFeature removes a tag assignment
+func (s *StorageService) UntagFeature(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(`DELETE FROM feature_tags WHERE id = ?`), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrFeatureTagNotFound
+	}
+	return touchMetadata(ctx, s.db, metadataFeatureTags, s.clock.Now())
+}
+
+// FeatureSet is the set of features carrying a tag, on the servers of a
+// group. A nil set contains every feature.
+type FeatureSet struct {
+	servers    map[string]bool // Servers of the group, nil for every server
+	everywhere map[string]bool // Features tagged on every server, nil for every feature
+	onServer   map[string]bool // "server|feature" of features tagged on one server
+}
+
+// Contains reports whether the feature on the server is in the set
+func (s *FeatureSet) Contains(server, feature string) bool {
+	if s == nil {
+		return true
+	}
+	if s.servers != nil && !s.servers[server] {
+		return false
+	}
+	return s.everywhere == nil || s.everywhere[feature] || s.onServer[server+"|"+feature]
+}
+
+// TaggedFeatures returns the features carrying a tag. A tag no feature
+// carries gives an empty set.
+func (s *StorageService) TaggedFeatures(ctx context.Context, tag string) (*FeatureSet, error) {
+	tags, err := s.GetFeatureTags(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+	set := &FeatureSet{everywhere: make(map[string]bool), onServer: make(map[string]bool)}
+	for _, t := range tags {
+		if t.ServerHostname == "" {
+			set.everywhere[t.FeatureName] = true
+		} else {
+			set.onServer[t.ServerHostname+"|"+t.FeatureName] = true
+		}
+	}
+	return set, nil
+}
+
+// GetFilteredUtilizationHistory returns the combined usage of the features in
+// the set over time, optionally of one server or one feature: the users and
+// queued requests of all of them at each point of their histories
+func (s *AnalyticsService) GetFilteredUtilizationHistory(ctx context.Context, server, feature string, set *FeatureSet, days int) ([]models.UtilizationHistoryPoint, error) {
+	var features []struct {
+		ServerHostname string `db:"server_hostname"`
+		Name           string `db:"name"`
+	}
+	query := `SELECT DISTINCT server_hostname, name FROM features WHERE 1=1`
+	args := []interface{}{}
+	if server != "" {
+		query += ` AND server_hostname = ?`
+		args = append(args, server)
+	}
+	if feature != "" {
+		query += ` AND name = ?`
+		args = append(args, feature)
+	}
+	if err := s.db.SelectContext(ctx, &features, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]int)
+	queued := make(map[string]int)
+	for _, f := range features {
+		if !set.Contains(f.ServerHostname, f.Name) {
+			continue
+		}
+		history, err := s.GetUtilizationHistory(ctx, f.ServerHostname, f.Name, days)
+		if err != nil {
+			return nil, err
+		}
+		for _, point := range history {
+			users[point.Timestamp] += point.UsersCount
+			queued[point.Timestamp] += point.QueuedCount
+		}
+	}
+
+	combined := make([]models.UtilizationHistoryPoint, 0, len(users))
+	for timestamp, count := range users {
+		combined = append(combined, models.UtilizationHistoryPoint{
+			Timestamp:   timestamp,
+			UsersCount:  count,
+			QueuedCount: queued[timestamp],
+		})
+	}
+	sort.Slice(combined, func(i, j int) bool { return combined[i].Timestamp < combined[j].Timestamp })
+	return combined, nil
+}
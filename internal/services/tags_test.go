@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"licet/internal/models"
+)
+
+func TestFeatureTags(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	cad := models.FeatureTag{FeatureName: "solidworks", Tag: "CAD", CreatedBy: "admin"}
+	if err := storage.TagFeature(ctx, &cad); err != nil {
+		t.Fatalf("TagFeature failed: %v", err)
+	}
+	again := models.FeatureTag{FeatureName: " solidworks ", Tag: "CAD"}
+	if err := storage.TagFeature(ctx, &again); err != nil || again.ID != cad.ID || again.CreatedBy != "admin" {
+		t.Fatalf("Expected tagging again to return the assignment, got %+v, %v", again, err)
+	}
+	for _, tag := range []models.FeatureTag{
+		{ServerHostname: "27000@b", FeatureName: "catia", Tag: "CAD"},
+		{FeatureName: "solidworks", Tag: "team-a"},
+	} {
+		if err := storage.TagFeature(ctx, &tag); err != nil {
+			t.Fatalf("TagFeature failed: %v", err)
+		}
+	}
+	for _, invalid := range []models.FeatureTag{
+		{Tag: "CAD"},
+		{FeatureName: "catia"},
+		{FeatureName: "catia", Tag: "-cad"},
+		{FeatureName: "catia", Tag: "cad,cae"},
+	} {
+		if err := storage.TagFeature(ctx, &invalid); !errors.Is(err, ErrInvalidFeatureTag) {
+			t.Errorf("Expected ErrInvalidFeatureTag for %+v, got %v", invalid, err)
+		}
+	}
+
+	summaries, err := storage.GetTagSummaries(ctx)
+	if err != nil || len(summaries) != 2 || summaries[0] != (models.TagSummary{Tag: "CAD", Features: 2}) {
+		t.Fatalf("Unexpected tag summaries: %+v, %v", summaries, err)
+	}
+
+	set, err := storage.TaggedFeatures(ctx, "CAD")
+	if err != nil {
+		t.Fatalf("TaggedFeatures failed: %v", err)
+	}
+	for _, tt := range []struct {
+		server, feature string
+		want            bool
+	}{
+		{"27000@a", "solidworks", true},
+		{"27000@b", "catia", true},
+		{"27000@a", "catia", false},
+		{"27000@a", "matlab", false},
+	} {
+		if got := set.Contains(tt.server, tt.feature); got != tt.want {
+			t.Errorf("Contains(%s, %s) = %v, want %v", tt.server, tt.feature, got, tt.want)
+		}
+	}
+	var all *FeatureSet
+	if !all.Contains("27000@a", "matlab") {
+		t.Error("Expected a nil set to contain every feature")
+	}
+
+	if err := storage.UntagFeature(ctx, cad.ID); err != nil {
+		t.Fatalf("UntagFeature failed: %v", err)
+	}
+	if err := storage.UntagFeature(ctx, cad.ID); !errors.Is(err, ErrFeatureTagNotFound) {
+		t.Errorf("Expected ErrFeatureTagNotFound, got %v", err)
+	}
+	if tags, _ := storage.GetFeatureTags(ctx, "CAD"); len(tags) != 1 || tags[0].FeatureName != "catia" {
+		t.Errorf("Expected catia to be left tagged CAD, got %+v", tags)
+	}
+}
+
+func TestTaggedUtilization(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	if err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "27000@a", Name: "solidworks", TotalLicenses: 10},
+		{ServerHostname: "27000@a", Name: "matlab", TotalLicenses: 10},
+		{ServerHostname: "27000@b", Name: "catia", TotalLicenses: 10},
+	}); err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	date := time.Now().Format("2006-01-02")
+	for _, u := range []struct {
+		server, feature string
+		users           int
+	}{
+		{"27000@a", "solidworks", 9},
+		{"27000@a", "matlab", 1},
+		{"27000@b", "catia", 3},
+	} {
+		_, err := db.Exec(`INSERT INTO feature_usage (server_hostname, feature_name, date, time, users_count) VALUES (?, ?, ?, ?, ?)`,
+			u.server, u.feature, date, "12:00:00", u.users)
+		if err != nil {
+			t.Fatalf("Failed to insert usage: %v", err)
+		}
+	}
+	for _, tag := range []models.FeatureTag{
+		{FeatureName: "solidworks", Tag: "CAD"},
+		{ServerHostname: "27000@b", FeatureName: "catia", Tag: "CAD"},
+	} {
+		if err := storage.TagFeature(ctx, &tag); err != nil {
+			t.Fatalf("TagFeature failed: %v", err)
+		}
+	}
+
+	set, err := storage.FeatureFilter(ctx, "CAD", "")
+	if err != nil {
+		t.Fatalf("FeatureFilter failed: %v", err)
+	}
+	analytics := NewAnalyticsService(db, storage, "sqlite")
+	history, err := analytics.GetFilteredUtilizationHistory(ctx, "", "", set, 1)
+	if err != nil {
+		t.Fatalf("GetFilteredUtilizationHistory failed: %v", err)
+	}
+	if len(history) != 1 || history[0].UsersCount != 12 {
+		t.Fatalf("Expected the combined usage of the CAD features, got %+v", history)
+	}
+	if history, _ := analytics.GetFilteredUtilizationHistory(ctx, "27000@a", "", set, 1); len(history) != 1 || history[0].UsersCount != 9 {
+		t.Errorf("Expected the CAD usage on 27000@a, got %+v", history)
+	}
+	if history, _ := analytics.GetFilteredUtilizationHistory(ctx, "", "catia", set, 1); len(history) != 1 || history[0].UsersCount != 3 {
+		t.Errorf("Expected the usage of catia, got %+v", history)
+	}
+
+	report, err := NewEnhancedAnalyticsService(db, storage, "sqlite").GetFilteredCapacityReport(ctx, 30, set)
+	if err != nil {
+		t.Fatalf("GetFilteredCapacityReport failed: %v", err)
+	}
+	if report.TotalFeatures != 2 || report.TotalServers != 2 || report.FeaturesAtCapacity != 1 || report.HighUtilization[0].FeatureName != "solidworks" {
+		t.Fatalf("Unexpected tagged capacity report: %+v", report)
+	}
+}
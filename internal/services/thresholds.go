@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/models"
+)
+
+var (
+	// ErrAlertThresholdNotFound is returned when deleting a threshold override that does not exist
+	ErrAlertThresholdNotFound = errors.New("alert threshold not found")
+	// ErrInvalidAlertThreshold is returned for threshold overrides that cannot be applied
+	ErrInvalidAlertThreshold = errors.New("invalid alert threshold")
+)
+
+// GetAlertThresholds returns all utilization threshold overrides
+func (s *AlertService) GetAlertThresholds(ctx context.Context) ([]models.AlertThreshold, error) {
+	var thresholds []models.AlertThreshold
+	query := `SELECT * FROM alert_thresholds ORDER BY server_hostname, feature_name`
+	err := s.db.SelectContext(ctx, &thresholds, query)
+	return thresholds, err
+}
+
+// SetAlertThreshold stores a utilization threshold override, replacing any
+// existing override for the same server and feature
+func (s *AlertService) SetAlertThreshold(ctx context.Context, t models.AlertThreshold) error {
+	if t.ServerHostname == "" && t.FeatureName == "" {
+		return fmt.Errorf("%w: server_hostname or feature_name is required; use alerts.utilization_* for global thresholds", ErrInvalidAlertThreshold)
+	}
+	if t.WarningPct <= 0 || t.CriticalPct <= 0 {
+		return fmt.Errorf("%w: thresholds must be positive percentages", ErrInvalidAlertThreshold)
+	}
+	if t.WarningPct > t.CriticalPct {
+		return fmt.Errorf("%w: warning_pct must not exceed critical_pct", ErrInvalidAlertThreshold)
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM alert_thresholds WHERE server_hostname = ? AND feature_name = ?`
+	if _, err := tx.ExecContext(ctx, tx.Rebind(query), t.ServerHostname, t.FeatureName); err != nil {
+		return fmt.Errorf("failed to replace alert threshold: %w", err)
+	}
+
+	query = `
+		INSERT INTO alert_thresholds (server_hostname, feature_name, warning_pct, critical_pct, updated_by)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	_, err = tx.ExecContext(ctx, tx.Rebind(query), t.ServerHostname, t.FeatureName, t.WarningPct, t.CriticalPct, t.UpdatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to store alert threshold: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// DeleteAlertThreshold removes a utilization threshold override
+func (s *AlertService) DeleteAlertThreshold(ctx context.Context, server, feature string) error {
+	query := `DELETE FROM alert_thresholds WHERE server_hostname = ? AND feature_name = ?`
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(query), server, feature)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrAlertThresholdNotFound
+	}
+	return nil
+}
+
+// utilizationThresholds returns the warning and critical utilization
+// percentages of a feature. The most specific override wins: server and
+// feature, then feature on any server, then every feature of the server,
+// then the global config.
+func (s *AlertService) utilizationThresholds(overrides []models.AlertThreshold, server, feature string) (float64, float64) {
+	best := -1
+	var warning, critical float64
+	for _, t := range overrides {
+		rank := -1
+		switch {
+		case t.ServerHostname == server && t.FeatureName == feature:
+			rank = 2
+		case t.ServerHostname == "" && t.FeatureName == feature:
+			rank = 1
+		case t.ServerHostname == server && t.FeatureName == "":
+			rank = 0
+		}
+		if rank > best {
+			best, warning, critical = rank, t.WarningPct, t.CriticalPct
+		}
+	}
+	if best < 0 {
+		return s.cfg.Alerts.UtilizationWarn, s.cfg.Alerts.UtilizationCrit
+	}
+	return warning, critical
+}
+
+// CheckUtilization raises an alert for each feature of a server whose
+// utilization reached its warning or critical threshold. Pools of the same
+// feature (e.g. with different expiration dates) are added up.
+func (s *AlertService) CheckUtilization(ctx context.Context, hostname string, features []models.Feature) error {
+	overrides, err := s.GetAlertThresholds(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get alert thresholds: %w", err)
+	}
+
+	type seats struct{ used, total int }
+	byName := make(map[string]*seats)
+	for _, f := range features {
+		if f.CountedLicenses() == 0 {
+			continue
+		}
+		if byName[f.Name] == nil {
+			byName[f.Name] = &seats{}
+		}
+		byName[f.Name].used += f.UsedLicenses
+		byName[f.Name].total += f.CountedLicenses()
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		u := byName[name]
+		pct := float64(u.used) / float64(u.total) * 100
+		warning, critical := s.utilizationThresholds(overrides, hostname, name)
+
+		severity, threshold := "", 0.0
+		switch {
+		case critical > 0 && pct >= critical:
+			severity, threshold = "critical", critical
+		case warning > 0 && pct >= warning:
+			severity, threshold = "warning", warning
+		default:
+			continue
+		}
+
+		if s.CheckThrottle(hostname, "utilization:"+name) {
+			continue
+		}
+		alert := &models.Alert{
+			ServerHostname: hostname,
+			FeatureName:    name,
+			AlertType:      "utilization",
+			Message: fmt.Sprintf("Feature '%s' on %s is at %.0f%% utilization (%d of %d seats, %s threshold %.0f%%)",
+				name, hostname, pct, u.used, u.total, severity, threshold),
+			Severity: severity,
+		}
+		if err := s.CreateAlert(ctx, alert); err != nil {
+			log.Errorf("Failed to create utilization alert: %v", err)
+		}
+	}
+	return nil
+}
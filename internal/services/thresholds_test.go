@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestUtilizationThresholds_Precedence(t *testing.T) {
+	s := NewAlertService(nil, &config.Config{Alerts: config.AlertConfig{UtilizationWarn: 80, UtilizationCrit: 95}})
+	overrides := []models.AlertThreshold{
+		{ServerHostname: "27000@a", FeatureName: "", WarningPct: 60, CriticalPct: 70},
+		{ServerHostname: "", FeatureName: "solver", WarningPct: 50, CriticalPct: 100},
+		{ServerHostname: "27000@a", FeatureName: "solver", WarningPct: 90, CriticalPct: 99},
+	}
+
+	tests := []struct {
+		server, feature string
+		warning         float64
+	}{
+		{"27000@a", "solver", 90}, // Server and feature
+		{"27000@b", "solver", 50}, // Feature on any server
+		{"27000@a", "mesher", 60}, // Every feature of the server
+		{"27000@b", "mesher", 80}, // Global
+	}
+	for _, tt := range tests {
+		if warning, _ := s.utilizationThresholds(overrides, tt.server, tt.feature); warning != tt.warning {
+			t.Errorf("%s on %s: warning = %v, want %v", tt.feature, tt.server, warning, tt.warning)
+		}
+	}
+}
+
+func TestSetAlertThreshold_Validation(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	s := NewAlertService(db, &config.Config{})
+
+	invalid := []models.AlertThreshold{
+		{WarningPct: 50, CriticalPct: 60},
+		{FeatureName: "solver", WarningPct: 0, CriticalPct: 60},
+		{FeatureName: "solver", WarningPct: 90, CriticalPct: 60},
+	}
+	for _, th := range invalid {
+		if err := s.SetAlertThreshold(ctx, th); !errors.Is(err, ErrInvalidAlertThreshold) {
+			t.Errorf("Expected %+v to be rejected, got %v", th, err)
+		}
+	}
+
+	th := models.AlertThreshold{FeatureName: "solver", WarningPct: 50, CriticalPct: 60}
+	if err := s.SetAlertThreshold(ctx, th); err != nil {
+		t.Fatalf("SetAlertThreshold failed: %v", err)
+	}
+	th.WarningPct = 55
+	if err := s.SetAlertThreshold(ctx, th); err != nil {
+		t.Fatalf("SetAlertThreshold failed: %v", err)
+	}
+	thresholds, err := s.GetAlertThresholds(ctx)
+	if err != nil {
+		t.Fatalf("GetAlertThresholds failed: %v", err)
+	}
+	if len(thresholds) != 1 || thresholds[0].WarningPct != 55 {
+		t.Errorf("Expected the override to be replaced, got %+v", thresholds)
+	}
+
+	if err := s.DeleteAlertThreshold(ctx, "", "solver"); err != nil {
+		t.Fatalf("DeleteAlertThreshold failed: %v", err)
+	}
+	if err := s.DeleteAlertThreshold(ctx, "", "solver"); !errors.Is(err, ErrAlertThresholdNotFound) {
+		t.Errorf("Expected ErrAlertThresholdNotFound, got %v", err)
+	}
+}
+
+func TestCheckUtilization(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	s := NewAlertService(db, &config.Config{Alerts: config.AlertConfig{
+		UtilizationWarn: 80, UtilizationCrit: 95, ResendIntervalMin: 60,
+	}})
+
+	// A 2-seat feature at 50% is fine, but this site wants to hear about it
+	if err := s.SetAlertThreshold(ctx, models.AlertThreshold{FeatureName: "viewer", WarningPct: 50, CriticalPct: 100}); err != nil {
+		t.Fatalf("SetAlertThreshold failed: %v", err)
+	}
+
+	features := []models.Feature{
+		{Name: "solver", TotalLicenses: 300, UsedLicenses: 290},
+		{Name: "solver", TotalLicenses: 200, UsedLicenses: 190}, // Second pool, 96% overall
+		{Name: "mesher", TotalLicenses: 10, UsedLicenses: 7},
+		{Name: "viewer", TotalLicenses: 2, UsedLicenses: 1},
+		{Name: "reader", TotalLicenses: 999, UsedLicenses: 999, LicenseModel: models.LicenseModelUncounted},
+	}
+	if err := s.CheckUtilization(ctx, "27000@a", features); err != nil {
+		t.Fatalf("CheckUtilization failed: %v", err)
+	}
+
+	alerts, err := s.GetUnsentAlerts(ctx)
+	if err != nil {
+		t.Fatalf("GetUnsentAlerts failed: %v", err)
+	}
+	severities := make(map[string]string)
+	for _, a := range alerts {
+		severities[a.FeatureName] = a.Severity
+	}
+	if len(alerts) != 2 || severities["solver"] != "critical" || severities["viewer"] != "warning" {
+		t.Fatalf("Expected critical solver and warning viewer alerts, got %+v", alerts)
+	}
+	if !strings.Contains(alerts[0].Message, "utilization") {
+		t.Errorf("Unexpected alert message %q", alerts[0].Message)
+	}
+
+	// Repeated checks are throttled per feature
+	if err := s.CheckUtilization(ctx, "27000@a", features); err != nil {
+		t.Fatalf("CheckUtilization failed: %v", err)
+	}
+	if alerts, _ := s.GetUnsentAlerts(ctx); len(alerts) != 2 {
+		t.Errorf("Expected throttled alerts not to repeat, got %d", len(alerts))
+	}
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+)
+
+// Spans are exported in batches, at most every tracingFlushInterval and at
+// most maxPendingSpans at a time; spans beyond that are dropped
+const (
+	tracingFlushInterval = 5 * time.Second
+	maxPendingSpans      = 2048
+)
+
+// Span is a timed operation of a trace, such as the collection of a server
+// within a collection cycle
+type Span struct {
+	tracer     *Tracer
+	traceID    string
+	spanID     string
+	parentID   string
+	name       string
+	start      time.Time
+	end        time.Time
+	attributes map[string]string
+	err        string
+}
+
+// Tracer records the spans of collections and exports them to an
+// OpenTelemetry collector over OTLP/HTTP. A nil tracer records nothing.
+type Tracer struct {
+	cfg    config.TracingConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	pending []*Span
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewTracer creates a tracer exporting to cfg.Endpoint
+func NewTracer(cfg config.TracingConfig) *Tracer {
+	return &Tracer{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// StartSpan starts a span of the trace of parent, or of a new trace when
+// parent is nil. attrs alternate attribute names and values.
+func (t *Tracer) StartSpan(parent *Span, name string, attrs ...string) *Span {
+	if t == nil {
+		return nil
+	}
+	span := &Span{tracer: t, spanID: randomHex(8), name: name, start: time.Now(), attributes: make(map[string]string)}
+	if parent != nil {
+		span.traceID, span.parentID = parent.traceID, parent.spanID
+	} else {
+		span.traceID = randomHex(16)
+	}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		span.attributes[attrs[i]] = attrs[i+1]
+	}
+	return span
+}
+
+// SetAttribute sets an attribute of the span
+func (s *Span) SetAttribute(key, value string) {
+	if s != nil {
+		s.attributes[key] = value
+	}
+}
+
+// Finish ends the span, failed if err is not nil, and queues it for export
+func (s *Span) Finish(err error) {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	if err != nil {
+		s.err = err.Error()
+	}
+
+	t := s.tracer
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.pending) >= maxPendingSpans {
+		log.Debugf("Tracing: dropped span %s, the export queue is full", s.name)
+		return
+	}
+	t.pending = append(t.pending, s)
+}
+
+// TraceID returns the ID of the trace of the span, or "" for a nil span
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.traceID
+}
+
+// Start exports the finished spans periodically until Stop is called
+func (t *Tracer) Start() {
+	log.Infof("Tracing collections to %s", t.cfg.Endpoint)
+	go func() {
+		defer close(t.done)
+		ticker := time.NewTicker(tracingFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.stop:
+				return
+			case <-ticker.C:
+				if err := t.Flush(context.Background()); err != nil {
+					log.Warnf("Tracing: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic export and exports the spans left
+func (t *Tracer) Stop() {
+	close(t.stop)
+	<-t.done
+	if err := t.Flush(context.Background()); err != nil {
+		log.Warnf("Tracing: %v", err)
+	}
+}
+
+// Flush exports the finished spans
+func (t *Tracer) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	spans := t.pending
+	t.pending = nil
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(t.otlpRequest(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(t.cfg.Endpoint, "/")+"/v1/traces", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.cfg.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export %d spans: %w", len(spans), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to export %d spans: collector returned %s", len(spans), resp.Status)
+	}
+	return nil
+}
+
+// otlpRequest builds the OTLP/JSON export request of spans
+func (t *Tracer) otlpRequest(spans []*Span) map[string]interface{} {
+	otlpSpans := make([]map[string]interface{}, 0, len(spans))
+	for _, s := range spans {
+		span := map[string]interface{}{
+			"traceId":           s.traceID,
+			"spanId":            s.spanID,
+			"name":              s.name,
+			"kind":              1, // SPAN_KIND_INTERNAL
+			"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        otlpAttributes(s.attributes),
+		}
+		if s.parentID != "" {
+			span["parentSpanId"] = s.parentID
+		}
+		if s.err != "" {
+			span["status"] = map[string]interface{}{"code": 2, "message": s.err} // STATUS_CODE_ERROR
+		}
+		otlpSpans = append(otlpSpans, span)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []map[string]interface{}{{
+			"resource": map[string]interface{}{
+				"attributes": otlpAttributes(map[string]string{"service.name": t.cfg.ServiceName}),
+			},
+			"scopeSpans": []map[string]interface{}{{
+				"scope": map[string]interface{}{"name": "licet"},
+				"spans": otlpSpans,
+			}},
+		}},
+	}
+}
+
+// otlpAttributes converts attributes to OTLP key-values
+func otlpAttributes(attrs map[string]string) []map[string]interface{} {
+	kvs := make([]map[string]interface{}, 0, len(attrs))
+	for key, value := range attrs {
+		kvs = append(kvs, map[string]interface{}{"key": key, "value": map[string]interface{}{"stringValue": value}})
+	}
+	return kvs
+}
+
+// randomHex returns n random bytes in hex, as trace and span IDs are written
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
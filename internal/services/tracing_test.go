@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+// otlpSpan is the part of an exported OTLP/JSON span the tests check
+type otlpSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Attributes   []struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue string `json:"stringValue"`
+		} `json:"value"`
+	} `json:"attributes"`
+	Status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+// newTestCollector starts a collector of OTLP/JSON exports, returning the
+// spans it received
+func newTestCollector(t *testing.T) (*httptest.Server, *[]otlpSpan) {
+	t.Helper()
+	var spans []otlpSpan
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unexpected export", http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []otlpSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &spans
+}
+
+func TestTracer_Export(t *testing.T) {
+	srv, spans := newTestCollector(t)
+	tracer := NewTracer(config.TracingConfig{Endpoint: srv.URL + "/", ServiceName: "licet", Headers: map[string]string{"authorization": "Bearer token"}})
+
+	cycle := tracer.StartSpan(nil, "collection")
+	span := tracer.StartSpan(cycle, "collect 27000@lic1", "server", "27000@lic1")
+	span.Finish(errors.New("connection refused"))
+	cycle.Finish(nil)
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if len(*spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(*spans))
+	}
+	server, root := (*spans)[0], (*spans)[1]
+	if len(root.TraceID) != 32 || len(root.SpanID) != 16 || root.ParentSpanID != "" || root.Status.Code != 0 {
+		t.Errorf("Unexpected cycle span %+v", root)
+	}
+	if server.TraceID != root.TraceID || server.ParentSpanID != root.SpanID {
+		t.Errorf("Expected the server span in the trace of the cycle, got %+v", server)
+	}
+	if server.Status.Code != 2 || server.Status.Message != "connection refused" {
+		t.Errorf("Expected the server span to have failed, got %+v", server.Status)
+	}
+	if len(server.Attributes) != 1 || server.Attributes[0].Key != "server" || server.Attributes[0].Value.StringValue != "27000@lic1" {
+		t.Errorf("Unexpected attributes %+v", server.Attributes)
+	}
+
+	// Nothing is left to export
+	if err := tracer.Flush(context.Background()); err != nil || len(*spans) != 2 {
+		t.Errorf("Expected nothing to export, got %v and %d spans", err, len(*spans))
+	}
+}
+
+func TestTracer_Nil(t *testing.T) {
+	var tracer *Tracer
+	span := tracer.StartSpan(nil, "collection")
+	span.SetAttribute("servers", "1")
+	span.Finish(nil)
+	if span.TraceID() != "" {
+		t.Errorf("Expected no trace without a tracer, got %q", span.TraceID())
+	}
+}
+
+func TestCollectServer_Traced(t *testing.T) {
+	srv, spans := newTestCollector(t)
+	cfg := &config.Config{Servers: []config.LicenseServer{{Hostname: "27000@slow", Type: "flexlm", Timeout: 1}}}
+	collector := newHangingCollector(t, cfg)
+	tracer := NewTracer(config.TracingConfig{Endpoint: srv.URL, Headers: map[string]string{"Authorization": "Bearer token"}})
+	collector.SetTracer(tracer)
+
+	collector.CollectServer(models.LicenseServer{Hostname: "27000@slow", Type: "flexlm"})
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	poll := collector.PollResults()["27000@slow"]
+	if len(*spans) != 1 || (*spans)[0].TraceID != poll.TraceID || (*spans)[0].Name != "collect 27000@slow" {
+		t.Fatalf("Expected the span of the poll with trace %s, got %+v", poll.TraceID, *spans)
+	}
+	if (*spans)[0].Status.Code != 2 {
+		t.Errorf("Expected the timed out poll to be traced as failed, got %+v", (*spans)[0].Status)
+	}
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"licet/internal/models"
+)
+
+// DefaultTrendDays is the period for which trends are precomputed after each collection
+const DefaultTrendDays = 30
+
+// trendMaxAge is how old a precomputed trend may be before it is recomputed on demand
+const trendMaxAge = 24 * time.Hour
+
+// minTrendSamples is the number of samples needed for a meaningful trend
+const minTrendSamples = 7
+
+// dailyUsage aggregates the usage samples of a feature on one day. Trends
+// regress over the date only, so these sums give the same result as
+// regressing over every sample.
+type dailyUsage struct {
+	FeatureName string    `db:"feature_name"`
+	Date        time.Time `db:"date"`
+	Samples     float64   `db:"samples"`
+	SumUsers    float64   `db:"sum_users"`
+	SumSquares  float64   `db:"sum_squares"`
+	PeakUsers   int       `db:"peak_users"`
+}
+
+// dailyFromUsage aggregates a feature's usage history by day, oldest first
+func dailyFromUsage(usage []models.FeatureUsage) []dailyUsage {
+	byDate := make(map[time.Time]*dailyUsage)
+	for _, u := range usage {
+		d, ok := byDate[u.Date]
+		if !ok {
+			d = &dailyUsage{FeatureName: u.FeatureName, Date: u.Date}
+			byDate[u.Date] = d
+		}
+		y := float64(u.UsersCount)
+		d.Samples++
+		d.SumUsers += y
+		d.SumSquares += y * y
+		if u.UsersCount > d.PeakUsers {
+			d.PeakUsers = u.UsersCount
+		}
+	}
+
+	days := make([]dailyUsage, 0, len(byDate))
+	for _, d := range byDate {
+		days = append(days, *d)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date.Before(days[j].Date) })
+	return days
+}
+
+// trendFromDaily computes regression and summary statistics from daily
+// aggregates ordered oldest first. Identity and CurrentUsage are left to the caller.
+func trendFromDaily(days []dailyUsage) models.FeatureTrend {
+	var t models.FeatureTrend
+	if len(days) == 0 {
+		return t
+	}
+
+	start := days[0].Date
+	var n, sumX, sumY, sumXY, sumX2, sumY2 float64
+	for _, d := range days {
+		x := d.Date.Sub(start).Hours() / 24
+		n += d.Samples
+		sumX += d.Samples * x
+		sumY += d.SumUsers
+		sumXY += x * d.SumUsers
+		sumX2 += d.Samples * x * x
+		sumY2 += d.SumSquares
+		if d.PeakUsers > t.PeakUsage {
+			t.PeakUsage = d.PeakUsers
+		}
+		t.LastDay = x
+	}
+	t.Samples = int(n)
+
+	denom := n*sumX2 - sumX*sumX
+	if denom == 0 {
+		t.Intercept = sumY / n
+	} else {
+		t.Slope = (n*sumXY - sumX*sumY) / denom
+		t.Intercept = (sumY - t.Slope*sumX) / n
+	}
+
+	t.AvgUsage = sumY / n
+	t.StdDev = math.Sqrt(math.Max(sumY2/n-t.AvgUsage*t.AvgUsage, 0))
+
+	a, b := t.Intercept, t.Slope
+	ssTot := sumY2 - n*t.AvgUsage*t.AvgUsage
+	ssRes := sumY2 - 2*a*sumY - 2*b*sumXY + n*a*a + 2*a*b*sumX + b*b*sumX2
+	if ssTot > 1e-9 {
+		t.RSquared = math.Min(math.Max(1-ssRes/ssTot, 0), 1)
+	}
+
+	return t
+}
+
+// trendFromUsage computes a feature trend from its usage history (newest
+// first), leaving out holidays
+func trendFromUsage(usage []models.FeatureUsage, holidays *HolidayCalendar) models.FeatureTrend {
+	var days []dailyUsage
+	if len(usage) > 0 {
+		days = holidays.withoutHolidayAggregates(usage[0].ServerHostname, dailyFromUsage(usage))
+	}
+	t := trendFromDaily(days)
+	if len(usage) > 0 {
+		t.ServerHostname = usage[0].ServerHostname
+		t.FeatureName = usage[0].FeatureName
+		t.CurrentUsage = usage[0].UsersCount
+	}
+	return t
+}
+
+// UpdateFeatureTrends recomputes the trends of a server's features over the
+// last `days` days and stores them. It returns the number of trends stored.
+func (s *StorageService) UpdateFeatureTrends(ctx context.Context, hostname string, days int, at time.Time) (int, error) {
+	watermark, err := s.rollupWatermark(ctx)
+	if err != nil {
+		return 0, err
+	}
+	usage, args := s.usageAggregates(watermark, false, at.AddDate(0, 0, -days), hostname, "")
+	var rows []struct {
+		dailyUsage
+		Date interface{} `db:"date"`
+	}
+	query := `
+		SELECT feature_name, date, samples, sum_users, sum_squares, peak_users
+		FROM (` + usage + `) u
+		ORDER BY feature_name, date
+	`
+	if err := s.db.SelectContext(ctx, &rows, s.db.Rebind(query), args...); err != nil {
+		return 0, fmt.Errorf("failed to aggregate usage: %w", err)
+	}
+	daily := make([]dailyUsage, len(rows))
+	for i, row := range rows {
+		daily[i] = row.dailyUsage
+		daily[i].Date = scannedDate(row.Date)
+	}
+
+	var current []struct {
+		Name string `db:"name"`
+		Used int    `db:"used_licenses"`
+	}
+	query = `SELECT name, used_licenses FROM features WHERE server_hostname = ? AND is_active = TRUE`
+	if err := s.db.SelectContext(ctx, &current, s.db.Rebind(query), hostname); err != nil {
+		return 0, fmt.Errorf("failed to get current usage: %w", err)
+	}
+	used := make(map[string]int, len(current))
+	for _, c := range current {
+		used[c.Name] = c.Used
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query = `DELETE FROM feature_trends WHERE server_hostname = ? AND period_days = ?`
+	if _, err := tx.ExecContext(ctx, tx.Rebind(query), hostname, days); err != nil {
+		return 0, fmt.Errorf("failed to clear trends: %w", err)
+	}
+
+	insert := tx.Rebind(`
+		INSERT INTO feature_trends (server_hostname, feature_name, period_days, samples, slope, intercept,
+			r_squared, last_day, current_usage, avg_usage, std_dev, peak_usage, computed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+
+	stored := 0
+	for start := 0; start < len(daily); {
+		end := start
+		for end < len(daily) && daily[end].FeatureName == daily[start].FeatureName {
+			end++
+		}
+
+		feature := daily[start].FeatureName
+		t := trendFromDaily(s.holidays.withoutHolidayAggregates(hostname, daily[start:end]))
+		_, err := tx.ExecContext(ctx, insert, hostname, feature, days, t.Samples, t.Slope, t.Intercept,
+			t.RSquared, t.LastDay, used[feature], t.AvgUsage, t.StdDev, t.PeakUsage, at)
+		if err != nil {
+			return 0, fmt.Errorf("failed to store trend for %s: %w", feature, err)
+		}
+		stored++
+		start = end
+	}
+
+	return stored, tx.Commit()
+}
+
+// GetFeatureTrend returns the precomputed trend of a feature, or nil when
+// none is stored for the period or it is out of date
+func (s *StorageService) GetFeatureTrend(ctx context.Context, hostname, feature string, days int) (*models.FeatureTrend, error) {
+	var t models.FeatureTrend
+	query := `
+		SELECT * FROM feature_trends
+		WHERE server_hostname = ? AND feature_name = ? AND period_days = ? AND computed_at >= ?
+	`
+	err := s.db.GetContext(ctx, &t, s.db.Rebind(query), hostname, feature, days, s.clock.Now().Add(-trendMaxAge))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetFeatureTrends returns the up-to-date precomputed trends for the period,
+// optionally limited to a server
+func (s *StorageService) GetFeatureTrends(ctx context.Context, hostname string, days int) (map[UsageKey]models.FeatureTrend, error) {
+	var trends []models.FeatureTrend
+	query := `SELECT * FROM feature_trends WHERE period_days = ? AND computed_at >= ?`
+	args := []interface{}{days, s.clock.Now().Add(-trendMaxAge)}
+	if hostname != "" {
+		query += ` AND server_hostname = ?`
+		args = append(args, hostname)
+	}
+	if err := s.db.SelectContext(ctx, &trends, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	result := make(map[UsageKey]models.FeatureTrend, len(trends))
+	for _, t := range trends {
+		result[UsageKey{t.ServerHostname, t.FeatureName}] = t
+	}
+	return result, nil
+}
+
+// featureTrend returns the precomputed trend of a feature when available,
+// otherwise it computes the trend from the usage history
+func (s *StorageService) featureTrend(ctx context.Context, hostname, feature string, days int) (models.FeatureTrend, error) {
+	stored, err := s.GetFeatureTrend(ctx, hostname, feature, days)
+	if err != nil {
+		return models.FeatureTrend{}, err
+	}
+	if stored != nil {
+		return *stored, nil
+	}
+
+	usage, err := s.GetFeatureUsageHistory(ctx, hostname, feature, days)
+	if err != nil {
+		return models.FeatureTrend{}, err
+	}
+	t := trendFromUsage(usage, s.holidays)
+	t.PeriodDays = days
+	return t, nil
+}
+
+// getUsageOutliers returns usage samples outside [low, high], oldest first
+func (s *StorageService) getUsageOutliers(ctx context.Context, hostname, feature string, days int, low, high float64) ([]models.FeatureUsage, error) {
+	var usage []models.FeatureUsage
+	query := `
+		SELECT * FROM feature_usage
+		WHERE server_hostname = ? AND feature_name = ? AND date >= ?
+			AND (users_count < ? OR users_count > ?)
+		ORDER BY date ASC, time ASC
+	`
+	cutoff := s.clock.Now().AddDate(0, 0, -days)
+	err := s.db.SelectContext(ctx, &usage, s.db.Rebind(query), hostname, feature, cutoff, low, high)
+	return usage, err
+}
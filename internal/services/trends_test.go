@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"licet/internal/models"
+)
+
+// usageSeries returns the regression inputs of a usage history (newest first)
+func usageSeries(usage []models.FeatureUsage) (x, y []float64) {
+	start := usage[len(usage)-1].Date
+	for i := len(usage) - 1; i >= 0; i-- {
+		x = append(x, usage[i].Date.Sub(start).Hours()/24)
+		y = append(y, float64(usage[i].UsersCount))
+	}
+	return x, y
+}
+
+func TestUpdateFeatureTrends(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", TotalLicenses: 50, UsedLicenses: 31},
+		{ServerHostname: "27000@a", Name: "viewer", TotalLicenses: 10},
+	})
+	if err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+
+	// Several samples a day so the daily aggregation is exercised
+	for day := 0; day < 12; day++ {
+		date := time.Now().AddDate(0, 0, -day).Format("2006-01-02")
+		for i, hour := range []string{"09:00:00", "13:00:00", "17:00:00"} {
+			for _, u := range []struct {
+				feature string
+				users   int
+			}{
+				{"solver", 30 - day*2 + i*3},
+				{"viewer", (day * 7 % 5) + i},
+			} {
+				_, err := db.Exec(`INSERT INTO feature_usage (server_hostname, feature_name, date, time, users_count) VALUES (?, ?, ?, ?, ?)`,
+					"27000@a", u.feature, date, hour, u.users)
+				if err != nil {
+					t.Fatalf("Failed to insert usage: %v", err)
+				}
+			}
+		}
+	}
+
+	n, err := storage.UpdateFeatureTrends(ctx, "27000@a", DefaultTrendDays, time.Now())
+	if err != nil {
+		t.Fatalf("UpdateFeatureTrends failed: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("Expected 2 trends, got %d", n)
+	}
+
+	// Stored trends must match the regression over every sample
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+	for _, feature := range []string{"solver", "viewer"} {
+		trend, err := storage.GetFeatureTrend(ctx, "27000@a", feature, DefaultTrendDays)
+		if err != nil || trend == nil {
+			t.Fatalf("GetFeatureTrend(%s) = %v, %v", feature, trend, err)
+		}
+
+		usage, err := storage.GetFeatureUsageHistory(ctx, "27000@a", feature, DefaultTrendDays)
+		if err != nil {
+			t.Fatalf("GetFeatureUsageHistory failed: %v", err)
+		}
+		x, y := usageSeries(usage)
+		slope, intercept := linearRegression(x, y)
+		mean, stdDev := calculateStats(y)
+		rSquared := calculateRSquared(x, y, slope, intercept)
+
+		if trend.Samples != len(usage) || !near(trend.LastDay, x[len(x)-1]) {
+			t.Errorf("%s: expected %d samples over %v days, got %d over %v",
+				feature, len(usage), x[len(x)-1], trend.Samples, trend.LastDay)
+		}
+		if !near(trend.Slope, slope) || !near(trend.Intercept, intercept) || !near(trend.RSquared, rSquared) {
+			t.Errorf("%s: expected regression %v/%v/%v, got %v/%v/%v",
+				feature, slope, intercept, rSquared, trend.Slope, trend.Intercept, trend.RSquared)
+		}
+		if !near(trend.AvgUsage, mean) || !near(trend.StdDev, stdDev) {
+			t.Errorf("%s: expected mean %v and std dev %v, got %v and %v",
+				feature, mean, stdDev, trend.AvgUsage, trend.StdDev)
+		}
+	}
+
+	solver, _ := storage.GetFeatureTrend(ctx, "27000@a", "solver", DefaultTrendDays)
+	if solver.CurrentUsage != 31 || solver.PeakUsage != 36 {
+		t.Errorf("Expected current usage 31 and peak 36, got %d and %d", solver.CurrentUsage, solver.PeakUsage)
+	}
+
+	// Recomputing replaces the stored trends rather than adding to them
+	if _, err := storage.UpdateFeatureTrends(ctx, "27000@a", DefaultTrendDays, time.Now()); err != nil {
+		t.Fatalf("UpdateFeatureTrends failed: %v", err)
+	}
+	trends, err := storage.GetFeatureTrends(ctx, "", DefaultTrendDays)
+	if err != nil || len(trends) != 2 {
+		t.Errorf("Expected 2 stored trends, got %d (%v)", len(trends), err)
+	}
+
+	// Out-of-date trends are ignored
+	if _, err := storage.UpdateFeatureTrends(ctx, "27000@a", DefaultTrendDays, time.Now().Add(-2*trendMaxAge)); err != nil {
+		t.Fatalf("UpdateFeatureTrends failed: %v", err)
+	}
+	if trend, err := storage.GetFeatureTrend(ctx, "27000@a", "solver", DefaultTrendDays); err != nil || trend != nil {
+		t.Errorf("Expected no current trend, got %+v (%v)", trend, err)
+	}
+}
+
+func TestTrendLookupsMatchLiveComputation(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	storage := NewStorageService(db, "sqlite")
+
+	err := storage.StoreFeatures(ctx, []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", TotalLicenses: 100, UsedLicenses: 40},
+	})
+	if err != nil {
+		t.Fatalf("StoreFeatures failed: %v", err)
+	}
+	for day := 0; day < 14; day++ {
+		users := 40 - day*2
+		if day == 5 {
+			users = 90 // anomaly
+		}
+		date := time.Now().AddDate(0, 0, -day).Format("2006-01-02")
+		_, err := db.Exec(`INSERT INTO feature_usage (server_hostname, feature_name, date, time, users_count) VALUES (?, ?, ?, ?, ?)`,
+			"27000@a", "solver", date, "12:00:00", users)
+		if err != nil {
+			t.Fatalf("Failed to insert usage: %v", err)
+		}
+	}
+
+	analytics := NewAnalyticsService(db, storage, "sqlite")
+	enhanced := NewEnhancedAnalyticsService(db, storage, "sqlite")
+
+	livePrediction, err := analytics.GetPredictiveAnalytics(ctx, "27000@a", "solver", DefaultTrendDays)
+	if err != nil {
+		t.Fatalf("GetPredictiveAnalytics failed: %v", err)
+	}
+	liveTrend, err := enhanced.GetTrendAnalysis(ctx, "27000@a", "solver", DefaultTrendDays)
+	if err != nil {
+		t.Fatalf("GetTrendAnalysis failed: %v", err)
+	}
+	if len(livePrediction.Anomalies) != 1 || livePrediction.Anomalies[0].Usage != 90 {
+		t.Errorf("Expected one anomaly of 90 users, got %+v", livePrediction.Anomalies)
+	}
+
+	if _, err := storage.UpdateFeatureTrends(ctx, "27000@a", DefaultTrendDays, time.Now()); err != nil {
+		t.Fatalf("UpdateFeatureTrends failed: %v", err)
+	}
+
+	storedPrediction, err := analytics.GetPredictiveAnalytics(ctx, "27000@a", "solver", DefaultTrendDays)
+	if err != nil {
+		t.Fatalf("GetPredictiveAnalytics failed: %v", err)
+	}
+	storedTrend, err := enhanced.GetTrendAnalysis(ctx, "27000@a", "solver", DefaultTrendDays)
+	if err != nil {
+		t.Fatalf("GetTrendAnalysis failed: %v", err)
+	}
+
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+	if !near(storedPrediction.TrendSlope, livePrediction.TrendSlope) ||
+		!near(storedPrediction.ConfidenceLevel, livePrediction.ConfidenceLevel) ||
+		storedPrediction.DaysToCapacity != livePrediction.DaysToCapacity ||
+		len(storedPrediction.Anomalies) != len(livePrediction.Anomalies) {
+		t.Errorf("Stored prediction %+v differs from live %+v", storedPrediction, livePrediction)
+	}
+	if !near(storedTrend.ProjectedUsage30Days, liveTrend.ProjectedUsage30Days) ||
+		storedTrend.Direction != liveTrend.Direction ||
+		storedTrend.DaysToCapacity != liveTrend.DaysToCapacity {
+		t.Errorf("Stored trend %+v differs from live %+v", storedTrend, liveTrend)
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+// UserDigestService reports users who started or stopped using features
+type UserDigestService struct {
+	db       *sqlx.DB
+	cfg      *config.Config
+	storage  *StorageService
+	features []*regexp.Regexp
+}
+
+// NewUserDigestService creates a user digest service. Feature patterns from
+// the config restrict the digest to matching features.
+func NewUserDigestService(db *sqlx.DB, cfg *config.Config, storage *StorageService) (*UserDigestService, error) {
+	s := &UserDigestService{db: db, cfg: cfg, storage: storage}
+	for _, pattern := range cfg.UserDigest.Features {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user digest feature pattern %q: %w", pattern, err)
+		}
+		s.features = append(s.features, re)
+	}
+	return s, nil
+}
+
+// GetDigest returns the users first seen during [since, until), and the users
+// whose last use crossed the inactivity threshold during that period
+func (s *UserDigestService) GetDigest(ctx context.Context, since, until time.Time) (*models.UserDigest, error) {
+	inactiveDays := s.cfg.UserDigest.InactiveDays
+	if inactiveDays <= 0 {
+		inactiveDays = 30
+	}
+
+	newUsers, err := s.storage.GetFirstSeenUsers(ctx, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get new users: %w", err)
+	}
+
+	inactive := -time.Duration(inactiveDays) * 24 * time.Hour
+	removedUsers, err := s.storage.GetLastSeenUsers(ctx, since.Add(inactive), until.Add(inactive))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get removed users: %w", err)
+	}
+
+	return &models.UserDigest{
+		PeriodStart:  since,
+		PeriodEnd:    until,
+		InactiveDays: inactiveDays,
+		NewUsers:     s.filter(newUsers),
+		RemovedUsers: s.filter(removedUsers),
+	}, nil
+}
+
+// filter keeps users of features matching the configured patterns
+func (s *UserDigestService) filter(users []models.FeatureUser) []models.FeatureUser {
+	if len(s.features) == 0 {
+		return users
+	}
+	filtered := []models.FeatureUser{}
+	for _, user := range users {
+		for _, re := range s.features {
+			if re.MatchString(user.FeatureName) {
+				filtered = append(filtered, user)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// SendDailyDigest builds the digest for the last 24 hours and emails it when
+// digest emails are enabled
+func (s *UserDigestService) SendDailyDigest(ctx context.Context) error {
+	until := time.Now()
+	digest, err := s.GetDigest(ctx, until.Add(-24*time.Hour), until)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("User digest: %d new and %d removed users", len(digest.NewUsers), len(digest.RemovedUsers))
+
+	if !s.cfg.UserDigest.Email || !s.cfg.Email.Enabled {
+		return nil
+	}
+	if len(digest.NewUsers) == 0 && len(digest.RemovedUsers) == 0 {
+		log.Debug("User digest is empty, not sending email")
+		return nil
+	}
+
+	subject := fmt.Sprintf("License User Digest: %d new, %d removed", len(digest.NewUsers), len(digest.RemovedUsers))
+	return NewAlertService(s.db, s.cfg).SendEmail(subject, FormatUserDigest(digest, s.cfg.Branding.Name()), s.cfg.Email.To)
+}
+
+// FormatUserDigest renders a digest as plain text, signed by product
+func FormatUserDigest(digest *models.UserDigest, product string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "License User Digest\n\nPeriod: %s to %s\n\n",
+		digest.PeriodStart.Format(time.RFC3339), digest.PeriodEnd.Format(time.RFC3339))
+
+	fmt.Fprintf(&b, "First-time users (%d):\n", len(digest.NewUsers))
+	for _, u := range digest.NewUsers {
+		fmt.Fprintf(&b, "  %s  %s on %s (first seen %s)\n",
+			u.Username, u.FeatureName, u.ServerHostname, u.FirstSeen.Format("2006-01-02 15:04"))
+	}
+
+	fmt.Fprintf(&b, "\nUsers inactive for %d days (%d):\n", digest.InactiveDays, len(digest.RemovedUsers))
+	for _, u := range digest.RemovedUsers {
+		fmt.Fprintf(&b, "  %s  %s on %s (last seen %s)\n",
+			u.Username, u.FeatureName, u.ServerHostname, u.LastSeen.Format("2006-01-02 15:04"))
+	}
+
+	fmt.Fprintf(&b, "\n--\n%s\n", product)
+	return b.String()
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+func TestUserDigest(t *testing.T) {
+	db := newTestDB(t)
+	storage := NewStorageService(db, "sqlite")
+	ctx := context.Background()
+	now := time.Now()
+
+	record := func(at time.Time, users ...models.LicenseUser) {
+		t.Helper()
+		if err := storage.RecordUsers(ctx, users, at); err != nil {
+			t.Fatalf("RecordUsers failed: %v", err)
+		}
+	}
+	user := func(feature, name string) models.LicenseUser {
+		return models.LicenseUser{ServerHostname: "27000@a", FeatureName: feature, Username: name}
+	}
+
+	// jdoe has used the solver for a long time; asmith last used it 30 days ago
+	record(now.AddDate(0, 0, -60), user("solver", "jdoe"), user("solver", "asmith"))
+	record(now.Add(-30*24*time.Hour-time.Hour), user("solver", "asmith"))
+	record(now.Add(-2*time.Hour), user("solver", "jdoe"), user("solver", "bnew"), user("viewer", "cview"))
+
+	cfg := &config.Config{UserDigest: config.UserDigestConfig{InactiveDays: 30, Features: []string{"^solver$"}}}
+	digest, err := NewUserDigestService(db, cfg, storage)
+	if err != nil {
+		t.Fatalf("NewUserDigestService failed: %v", err)
+	}
+
+	result, err := digest.GetDigest(ctx, now.Add(-24*time.Hour), now)
+	if err != nil {
+		t.Fatalf("GetDigest failed: %v", err)
+	}
+
+	if len(result.NewUsers) != 1 || result.NewUsers[0].Username != "bnew" {
+		t.Errorf("Expected bnew as the only new solver user, got %+v", result.NewUsers)
+	}
+	if len(result.RemovedUsers) != 1 || result.RemovedUsers[0].Username != "asmith" {
+		t.Fatalf("Expected asmith as the only removed user, got %+v", result.RemovedUsers)
+	}
+	if !result.RemovedUsers[0].FirstSeen.Before(result.RemovedUsers[0].LastSeen) {
+		t.Errorf("Expected first_seen to be kept on later sightings, got %+v", result.RemovedUsers[0])
+	}
+
+	text := FormatUserDigest(result, "Licet")
+	if !strings.Contains(text, "bnew") || !strings.Contains(text, "asmith") {
+		t.Errorf("Expected digest text to list users, got:\n%s", text)
+	}
+}
+
+func TestNewUserDigestService_InvalidPattern(t *testing.T) {
+	cfg := &config.Config{UserDigest: config.UserDigestConfig{Features: []string{"("}}}
+	if _, err := NewUserDigestService(nil, cfg, nil); err == nil {
+		t.Error("Expected error for invalid feature pattern")
+	}
+}
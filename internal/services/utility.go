@@ -22,6 +22,12 @@ type UtilityChecker struct {
 	binaries map[string]string
 }
 
+// serverTypeUtilities names the utility that queries each server type
+var serverTypeUtilities = map[string]string{
+	"flexlm": "FlexLM (lmutil)",
+	"rlm":    "RLM (rlmutil)",
+}
+
 // NewUtilityChecker creates a new utility checker
 func NewUtilityChecker() *UtilityChecker {
 	binPaths := util.GetDefaultBinaryPaths()
@@ -50,6 +56,16 @@ func (uc *UtilityChecker) CheckAll() []UtilityStatus {
 	return statuses
 }
 
+// CheckServerType checks the utility that queries a server type. It returns
+// false for types that are queried without one.
+func (uc *UtilityChecker) CheckServerType(serverType string) (UtilityStatus, bool) {
+	name, ok := serverTypeUtilities[serverType]
+	if !ok {
+		return UtilityStatus{}, false
+	}
+	return uc.checkUtility(name, uc.binaries[name]), true
+}
+
 // checkUtility checks if a single utility is available and executable
 func (uc *UtilityChecker) checkUtility(name, path string) UtilityStatus {
 	status := UtilityStatus{
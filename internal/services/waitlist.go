@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+var (
+	// ErrWaitlistNotFound is returned when a waitlist subscription does not exist
+	ErrWaitlistNotFound = errors.New("waitlist subscription not found")
+	// ErrInvalidWaitlist is returned for subscriptions that cannot be stored
+	ErrInvalidWaitlist = errors.New("invalid waitlist subscription")
+	// ErrWaitlistFull is returned when a user holds waitlist.max_per_user pending subscriptions
+	ErrWaitlistFull = errors.New("too many pending waitlist subscriptions")
+)
+
+// Subscribe adds a user to the waitlist of a feature, on one server or any.
+// Subscribing again while waiting returns the pending subscription.
+func (s *AlertService) Subscribe(ctx context.Context, sub models.WaitlistSubscription) (*models.WaitlistSubscription, error) {
+	sub.Username = strings.TrimSpace(sub.Username)
+	sub.ServerHostname = strings.TrimSpace(sub.ServerHostname)
+	sub.FeatureName = strings.TrimSpace(sub.FeatureName)
+	sub.Email = strings.TrimSpace(sub.Email)
+	if sub.Username == "" {
+		return nil, fmt.Errorf("%w: username is required", ErrInvalidWaitlist)
+	}
+	if sub.FeatureName == "" {
+		return nil, fmt.Errorf("%w: feature_name is required", ErrInvalidWaitlist)
+	}
+	if sub.Email == "" {
+		sub.Email = waitlistEmail(s.cfg, sub.Username)
+	}
+	if !strings.Contains(sub.Email, "@") {
+		return nil, fmt.Errorf("%w: email is required", ErrInvalidWaitlist)
+	}
+
+	storedUser := s.cipher.Encrypt(sub.Username)
+	var pending []models.WaitlistSubscription
+	query := `SELECT * FROM waitlist_subscriptions WHERE username = ? AND notified_at IS NULL`
+	if err := s.db.SelectContext(ctx, &pending, s.db.Rebind(query), storedUser); err != nil {
+		return nil, fmt.Errorf("failed to get waitlist subscriptions: %w", err)
+	}
+	s.decryptWaitlist(pending)
+	for _, p := range pending {
+		if p.ServerHostname == sub.ServerHostname && p.FeatureName == sub.FeatureName {
+			return &p, nil
+		}
+	}
+	if len(pending) >= s.cfg.Waitlist.MaxPerUser {
+		return nil, ErrWaitlistFull
+	}
+
+	sub.CreatedAt = s.clock.Now()
+	sub.NotifiedAt = nil
+	query = `
+		INSERT INTO waitlist_subscriptions (username, email, server_hostname, feature_name, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	if _, err := s.db.ExecContext(ctx, s.db.Rebind(query), storedUser, s.cipher.Encrypt(sub.Email), sub.ServerHostname, sub.FeatureName, sub.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to store waitlist subscription: %w", err)
+	}
+	err := s.db.GetContext(ctx, &sub.ID, s.db.Rebind(`
+		SELECT id FROM waitlist_subscriptions WHERE username = ? AND server_hostname = ? AND feature_name = ? AND notified_at IS NULL
+		ORDER BY id DESC LIMIT 1
+	`), storedUser, sub.ServerHostname, sub.FeatureName)
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// waitlistEmail returns the address notifying a user: the username when it
+// is an email address, else the username at waitlist.email_domain
+func waitlistEmail(cfg *config.Config, username string) string {
+	if strings.Contains(username, "@") || cfg.Waitlist.EmailDomain == "" {
+		return username
+	}
+	return username + "@" + strings.TrimPrefix(cfg.Waitlist.EmailDomain, "@")
+}
+
+// GetWaitlist returns the subscriptions of a user, or of everyone when
+// username is empty, newest first
+func (s *AlertService) GetWaitlist(ctx context.Context, username string) ([]models.WaitlistSubscription, error) {
+	subs := []models.WaitlistSubscription{}
+	query := `SELECT * FROM waitlist_subscriptions ORDER BY id DESC`
+	var args []interface{}
+	if username != "" {
+		query = `SELECT * FROM waitlist_subscriptions WHERE username = ? ORDER BY id DESC`
+		args = append(args, s.cipher.Encrypt(username))
+	}
+	if err := s.db.SelectContext(ctx, &subs, s.db.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to get waitlist subscriptions: %w", err)
+	}
+	s.decryptWaitlist(subs)
+	return subs, nil
+}
+
+// decryptWaitlist decrypts the usernames and email addresses of stored
+// subscriptions in place
+func (s *AlertService) decryptWaitlist(subs []models.WaitlistSubscription) {
+	for i := range subs {
+		if name, err := s.cipher.Decrypt(subs[i].Username); err == nil {
+			subs[i].Username = name
+		}
+		if email, err := s.cipher.Decrypt(subs[i].Email); err == nil {
+			subs[i].Email = email
+		}
+	}
+}
+
+// Unsubscribe removes a subscription of a user, or of anyone when username
+// is empty
+func (s *AlertService) Unsubscribe(ctx context.Context, id int64, username string) error {
+	var owner string
+	err := s.db.GetContext(ctx, &owner, s.db.Rebind(`SELECT username FROM waitlist_subscriptions WHERE id = ?`), id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && username != "" && owner != s.cipher.Encrypt(username)) {
+		return ErrWaitlistNotFound
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, s.db.Rebind(`DELETE FROM waitlist_subscriptions WHERE id = ?`), id)
+	return err
+}
+
+// CheckWaitlist notifies the users waiting for features of a server that
+// have free seats after a collection. Each user gets one email for all their
+// features; users notified within waitlist.cooldown_min keep waiting.
+func (s *AlertService) CheckWaitlist(ctx context.Context, hostname string, features []models.Feature) error {
+	free := make(map[string]int)
+	for _, f := range features {
+		if f.CountedLicenses() > 0 {
+			free[f.Name] += f.AvailableLicenses()
+		}
+	}
+
+	var pending []models.WaitlistSubscription
+	query := `
+		SELECT * FROM waitlist_subscriptions
+		WHERE notified_at IS NULL AND (server_hostname = ? OR server_hostname = '')
+		ORDER BY id
+	`
+	if err := s.db.SelectContext(ctx, &pending, s.db.Rebind(query), hostname); err != nil {
+		return fmt.Errorf("failed to get waitlist subscriptions: %w", err)
+	}
+	s.decryptWaitlist(pending)
+
+	byUser := make(map[string][]models.WaitlistSubscription)
+	for _, sub := range pending {
+		if free[sub.FeatureName] > 0 {
+			byUser[sub.Username] = append(byUser[sub.Username], sub)
+		}
+	}
+	users := make([]string, 0, len(byUser))
+	for username := range byUser {
+		users = append(users, username)
+	}
+	sort.Strings(users)
+
+	now := s.clock.Now()
+	cooldown := now.Add(-time.Duration(s.cfg.Waitlist.CooldownMin) * time.Minute)
+	for _, username := range users {
+		var recent int
+		query := `SELECT COUNT(*) FROM waitlist_subscriptions WHERE username = ? AND notified_at > ?`
+		if err := s.db.GetContext(ctx, &recent, s.db.Rebind(query), s.cipher.Encrypt(username), cooldown); err != nil {
+			return fmt.Errorf("failed to check the waitlist cooldown: %w", err)
+		}
+		subs := byUser[username]
+		ids := make([]int64, len(subs))
+		for i, sub := range subs {
+			ids[i] = sub.ID
+		}
+		// Subscriptions are logged by ID, keeping usernames out of the log
+		if recent > 0 {
+			log.Debugf("Waitlist: subscriptions %v wait out the cooldown", ids)
+			continue
+		}
+
+		if err := s.notifyWaitlist(hostname, subs, free); err != nil {
+			log.Errorf("Failed to notify waitlist subscriptions %v of free seats: %v", ids, err)
+			continue
+		}
+		for _, sub := range subs {
+			query := `UPDATE waitlist_subscriptions SET notified_at = ? WHERE id = ?`
+			if _, err := s.db.ExecContext(ctx, s.db.Rebind(query), now, sub.ID); err != nil {
+				return fmt.Errorf("failed to mark waitlist subscription %d notified: %w", sub.ID, err)
+			}
+		}
+		log.Infof("Waitlist: notified subscriptions %v of free seats on %s", ids, hostname)
+	}
+	return nil
+}
+
+// notifyWaitlist emails a user the features they wait for that have free
+// seats on a server
+func (s *AlertService) notifyWaitlist(hostname string, subs []models.WaitlistSubscription, free map[string]int) error {
+	names := make([]string, len(subs))
+	var body strings.Builder
+	body.WriteString("Seats you are waiting for are free:\n\n")
+	for i, sub := range subs {
+		names[i] = sub.FeatureName
+		fmt.Fprintf(&body, "  %s on %s: %d free\n", sub.FeatureName, hostname, free[sub.FeatureName])
+	}
+	body.WriteString("\nSeats are not held for you; check one out soon. You will not be notified ")
+	body.WriteString("again for these features unless you join the waitlist again.\n")
+	if base := strings.TrimRight(s.cfg.Alerts.BaseURL, "/"); base != "" {
+		fmt.Fprintf(&body, "\n%s/details/%s\n", base, url.PathEscape(hostname))
+	}
+	fmt.Fprintf(&body, "\n--\n%s\n", s.cfg.Branding.Name())
+
+	subject := fmt.Sprintf("[%s] Seat available: %s", s.cfg.Branding.Name(), strings.Join(names, ", "))
+	return s.SendEmail(subject, body.String(), []string{subs[0].Email})
+}
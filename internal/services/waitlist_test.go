@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/models"
+)
+
+func TestWaitlist(t *testing.T) {
+	alerts, outbox, clk := newTestOutbox(t)
+	ctx := context.Background()
+	alerts.cfg.Email.Enabled = true
+	alerts.cfg.Waitlist.Enabled = true
+	alerts.cfg.Waitlist.CooldownMin = 60
+	alerts.cfg.Waitlist.MaxPerUser = 2
+	alerts.cfg.Waitlist.EmailDomain = "example.com"
+
+	var sent []emailMessage
+	outbox.send = func(msg emailMessage) error {
+		sent = append(sent, msg)
+		return nil
+	}
+	process := func() {
+		t.Helper()
+		if _, err := outbox.Process(ctx); err != nil {
+			t.Fatalf("Process failed: %v", err)
+		}
+	}
+
+	solver, err := alerts.Subscribe(ctx, models.WaitlistSubscription{Username: "alice", FeatureName: "solver"})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if solver.ID == 0 || solver.Email != "alice@example.com" {
+		t.Fatalf("Unexpected subscription: %+v", solver)
+	}
+	again, err := alerts.Subscribe(ctx, models.WaitlistSubscription{Username: "alice", FeatureName: "solver"})
+	if err != nil || again.ID != solver.ID {
+		t.Fatalf("Expected subscribing again to return the pending subscription, got %+v, %v", again, err)
+	}
+	if _, err := alerts.Subscribe(ctx, models.WaitlistSubscription{Username: "alice", ServerHostname: "27000@a", FeatureName: "mesher"}); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if _, err := alerts.Subscribe(ctx, models.WaitlistSubscription{Username: "alice", FeatureName: "viewer"}); !errors.Is(err, ErrWaitlistFull) {
+		t.Errorf("Expected ErrWaitlistFull, got %v", err)
+	}
+	if _, err := alerts.Subscribe(ctx, models.WaitlistSubscription{Username: "bob", FeatureName: "solver", Email: "bob@corp.example"}); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if _, err := alerts.Subscribe(ctx, models.WaitlistSubscription{Username: "carol"}); !errors.Is(err, ErrInvalidWaitlist) {
+		t.Errorf("Expected ErrInvalidWaitlist without a feature, got %v", err)
+	}
+
+	// All seats taken: nobody is notified
+	features := []models.Feature{
+		{ServerHostname: "27000@a", Name: "solver", TotalLicenses: 2, UsedLicenses: 2},
+		{ServerHostname: "27000@a", Name: "mesher", TotalLicenses: 1, UsedLicenses: 1},
+	}
+	if err := alerts.CheckWaitlist(ctx, "27000@a", features); err != nil {
+		t.Fatalf("CheckWaitlist failed: %v", err)
+	}
+	process()
+	if len(sent) != 0 {
+		t.Fatalf("Expected no notification, got %+v", sent)
+	}
+
+	// A solver seat frees up: both users waiting for it are notified once
+	features[0].UsedLicenses = 1
+	if err := alerts.CheckWaitlist(ctx, "27000@a", features); err != nil {
+		t.Fatalf("CheckWaitlist failed: %v", err)
+	}
+	process()
+	if len(sent) != 2 || sent[0].Recipients[0] != "alice@example.com" || sent[1].Recipients[0] != "bob@corp.example" {
+		t.Fatalf("Unexpected notifications: %+v", sent)
+	}
+	if !strings.Contains(sent[0].Subject, "Seat available: solver") || !strings.Contains(sent[0].Text, "solver on 27000@a: 1 free") {
+		t.Errorf("Unexpected notification: %s\n%s", sent[0].Subject, sent[0].Text)
+	}
+	if err := alerts.CheckWaitlist(ctx, "27000@a", features); err != nil {
+		t.Fatalf("CheckWaitlist failed: %v", err)
+	}
+	process()
+	if len(sent) != 2 {
+		t.Fatalf("Expected subscriptions to be notified once, got %d emails", len(sent))
+	}
+
+	// A mesher seat frees up within alice's cooldown, then after it
+	features[1].UsedLicenses = 0
+	clk.Advance(30 * time.Minute)
+	if err := alerts.CheckWaitlist(ctx, "27000@a", features); err != nil {
+		t.Fatalf("CheckWaitlist failed: %v", err)
+	}
+	process()
+	if len(sent) != 2 {
+		t.Fatalf("Expected no notification within the cooldown, got %+v", sent[2:])
+	}
+	clk.Advance(31 * time.Minute)
+	if err := alerts.CheckWaitlist(ctx, "27000@a", features); err != nil {
+		t.Fatalf("CheckWaitlist failed: %v", err)
+	}
+	process()
+	if len(sent) != 3 || !strings.Contains(sent[2].Subject, "mesher") {
+		t.Fatalf("Expected the mesher notification after the cooldown, got %+v", sent)
+	}
+
+	subs, err := alerts.GetWaitlist(ctx, "alice")
+	if err != nil || len(subs) != 2 || subs[0].NotifiedAt == nil || subs[1].NotifiedAt == nil {
+		t.Fatalf("Unexpected subscriptions of alice: %+v, %v", subs, err)
+	}
+	if err := alerts.Unsubscribe(ctx, subs[0].ID, "bob"); !errors.Is(err, ErrWaitlistNotFound) {
+		t.Errorf("Expected other users' subscriptions to be hidden, got %v", err)
+	}
+	if err := alerts.Unsubscribe(ctx, subs[0].ID, "alice"); err != nil {
+		t.Fatalf("Unsubscribe failed: %v", err)
+	}
+	if all, _ := alerts.GetWaitlist(ctx, ""); len(all) != 2 {
+		t.Errorf("Expected 2 subscriptions left, got %+v", all)
+	}
+}
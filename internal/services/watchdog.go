@@ -0,0 +1,178 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+)
+
+// WatchdogEvent is the webhook payload sent by the collection watchdog
+type WatchdogEvent struct {
+	Event       string    `json:"event"` // collection_stalled, collection_recovered
+	Message     string    `json:"message"`
+	LastSuccess time.Time `json:"last_success"`
+	Threshold   string    `json:"threshold"`
+	Time        time.Time `json:"time"`
+}
+
+// Watchdog notifies when license collection stops succeeding. It runs on its
+// own ticker, independent of the scheduler, and notifies directly via SMTP
+// and/or webhook rather than through the alert queue.
+type Watchdog struct {
+	cfg       *config.Config
+	collector *CollectorService
+	alerts    *AlertService
+	client    *http.Client
+	started   time.Time
+
+	mu      sync.Mutex
+	stalled bool
+	stop    chan struct{}
+}
+
+// NewWatchdog creates a collection watchdog
+func NewWatchdog(cfg *config.Config, collector *CollectorService, alerts *AlertService) *Watchdog {
+	return &Watchdog{
+		cfg:       cfg,
+		collector: collector,
+		alerts:    alerts,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		started:   time.Now(),
+		stop:      make(chan struct{}),
+	}
+}
+
+// threshold returns how long collection may go without success
+func (w *Watchdog) threshold() time.Duration {
+	interval := w.cfg.RRD.CollectionInterval
+	if interval <= 0 {
+		interval = 5
+	}
+	missed := w.cfg.Watchdog.MissedIntervals
+	if missed <= 0 {
+		missed = 3
+	}
+	return time.Duration(interval*missed) * time.Minute
+}
+
+// Start checks collection health every minute until Stop is called
+func (w *Watchdog) Start() {
+	if !w.cfg.Watchdog.Enabled {
+		return
+	}
+	log.Infof("Collection watchdog started (threshold %s)", w.threshold())
+
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case now := <-ticker.C:
+				w.Check(now)
+			}
+		}
+	}()
+}
+
+// Stop stops the watchdog
+func (w *Watchdog) Stop() {
+	close(w.stop)
+}
+
+// Check notifies when collection has stalled or recovered since the last
+// check and reports whether collection is currently stalled
+func (w *Watchdog) Check(now time.Time) bool {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("Collection watchdog check panicked: %v", r)
+		}
+	}()
+
+	last := w.collector.LastSuccess()
+	since := last
+	if since.IsZero() {
+		since = w.started
+	}
+	stalled := now.Sub(since) > w.threshold()
+
+	w.mu.Lock()
+	changed := stalled != w.stalled
+	w.stalled = stalled
+	w.mu.Unlock()
+
+	if !changed {
+		return stalled
+	}
+
+	event := WatchdogEvent{
+		Event:       "collection_recovered",
+		LastSuccess: last,
+		Threshold:   w.threshold().String(),
+		Time:        now,
+	}
+	if stalled {
+		event.Event = "collection_stalled"
+		if last.IsZero() {
+			event.Message = fmt.Sprintf("No license collection has succeeded since %s started at %s",
+				w.cfg.Branding.Name(), w.started.Format(time.RFC3339))
+		} else {
+			event.Message = fmt.Sprintf("No license collection has succeeded since %s (threshold %s)",
+				last.Format(time.RFC3339), event.Threshold)
+		}
+		log.Error(event.Message)
+	} else {
+		event.Message = fmt.Sprintf("License collection recovered at %s", last.Format(time.RFC3339))
+		log.Info(event.Message)
+	}
+
+	w.notify(event)
+	return stalled
+}
+
+// notify sends a watchdog event via the configured direct channels
+func (w *Watchdog) notify(event WatchdogEvent) {
+	if w.cfg.Watchdog.Email && w.cfg.Email.Enabled {
+		product := w.cfg.Branding.Name()
+		subject := "[critical] " + product + " collection stalled"
+		if event.Event == "collection_recovered" {
+			subject = "[info] " + product + " collection recovered"
+		}
+		recipients := append(append([]string{}, w.cfg.Email.To...), w.cfg.Email.Alerts...)
+		body := fmt.Sprintf("\n%s\n\n--\n%s watchdog\n", event.Message, product)
+		if err := w.alerts.sendEmailDirect(subject, body, recipients); err != nil {
+			log.Errorf("Watchdog email failed: %v", err)
+		}
+	}
+
+	if w.cfg.Watchdog.WebhookURL != "" {
+		if err := w.postWebhook(event); err != nil {
+			log.Errorf("Watchdog webhook failed: %v", err)
+		}
+	}
+}
+
+func (w *Watchdog) postWebhook(event WatchdogEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	resp, err := w.client.Post(w.cfg.Watchdog.WebhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
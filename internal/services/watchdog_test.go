@@ -0,0 +1,48 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+)
+
+func TestWatchdog_Check(t *testing.T) {
+	var events []WatchdogEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e WatchdogEvent
+		json.NewDecoder(r.Body).Decode(&e)
+		events = append(events, e)
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{
+		RRD:      config.RRDConfig{CollectionInterval: 5},
+		Watchdog: config.WatchdogConfig{Enabled: true, MissedIntervals: 3, WebhookURL: srv.URL},
+	}
+	collector := NewCollectorService(nil, cfg, nil, nil)
+	w := NewWatchdog(cfg, collector, nil)
+	start := w.started
+
+	if w.Check(start.Add(10 * time.Minute)) {
+		t.Error("Expected no stall within the threshold")
+	}
+	if !w.Check(start.Add(20 * time.Minute)) {
+		t.Error("Expected a stall after 3 missed intervals")
+	}
+	w.Check(start.Add(25 * time.Minute))
+	if len(events) != 1 || events[0].Event != "collection_stalled" {
+		t.Fatalf("Expected a single stalled notification, got %+v", events)
+	}
+
+	collector.lastSuccess.Store(start.Add(26 * time.Minute).UnixNano())
+	if w.Check(start.Add(27 * time.Minute)) {
+		t.Error("Expected recovery after a successful collection")
+	}
+	if len(events) != 2 || events[1].Event != "collection_recovered" {
+		t.Errorf("Expected a recovered notification, got %+v", events)
+	}
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"licet/internal/config"
+	"licet/internal/models"
+)
+
+// Webhook event types
+const (
+	WebhookEventAlert        = "alert"
+	WebhookEventServerStatus = "server_status"
+)
+
+// WebhookSignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the
+// request body, keyed with the endpoint's secret
+const WebhookSignatureHeader = "X-Licet-Signature"
+
+// WebhookEvent is the JSON payload POSTed to webhook endpoints
+type WebhookEvent struct {
+	Event string      `json:"event"`
+	Time  time.Time   `json:"time"`
+	Data  interface{} `json:"data"`
+}
+
+// ServerStatusChange is the data of a server_status webhook event
+type ServerStatusChange struct {
+	Server         string `json:"server"`
+	Type           string `json:"type"`
+	Status         string `json:"status"` // up, down
+	PreviousStatus string `json:"previous_status"`
+}
+
+// WebhookService POSTs events to the configured webhook endpoints and logs
+// every delivery attempt
+type WebhookService struct {
+	db     *sqlx.DB
+	cfg    *config.Config
+	client *http.Client
+	sleep  func(time.Duration) // Waits between retries; replaced in tests
+}
+
+// NewWebhookService creates a webhook notifier
+func NewWebhookService(db *sqlx.DB, cfg *config.Config) *WebhookService {
+	timeout := cfg.Webhooks.TimeoutSec
+	if timeout <= 0 {
+		timeout = 10
+	}
+	return &WebhookService{
+		db:     db,
+		cfg:    cfg,
+		client: &http.Client{Timeout: time.Duration(timeout) * time.Second},
+		sleep:  time.Sleep,
+	}
+}
+
+// Enabled reports whether any webhook endpoint is configured
+func (s *WebhookService) Enabled() bool {
+	return len(s.cfg.Webhooks.Endpoints) > 0
+}
+
+// Notify delivers an event to every endpoint subscribed to it. Deliveries to
+// different endpoints run concurrently; Notify returns when all of them have
+// succeeded or used up their attempts. The outcome for each endpoint is
+// recorded on the receipts of alert events.
+func (s *WebhookService) Notify(ctx context.Context, event string, data interface{}) {
+	payload, err := json.Marshal(WebhookEvent{Event: event, Time: time.Now().UTC(), Data: data})
+	if err != nil {
+		log.Errorf("Failed to encode webhook event %s: %v", event, err)
+		return
+	}
+
+	done := make(chan struct{})
+	pending := 0
+	for _, endpoint := range s.cfg.Webhooks.Endpoints {
+		if !subscribed(endpoint, event) {
+			continue
+		}
+		pending++
+		go func(endpoint config.WebhookEndpoint) {
+			defer func() { done <- struct{}{} }()
+			requestID, err := s.deliver(ctx, endpoint, event, payload)
+			if alert, ok := data.(models.Alert); ok && alert.ID != 0 {
+				status, errMsg := models.DeliverySent, ""
+				if err != nil {
+					status, errMsg = models.DeliveryFailed, err.Error()
+				}
+				recordDeliveries(ctx, s.db, time.Now(), []int64{alert.ID}, models.DeliveryChannelWebhook, []string{endpoint.Name}, status, requestID, errMsg)
+			}
+		}(endpoint)
+	}
+	for ; pending > 0; pending-- {
+		<-done
+	}
+}
+
+// subscribed reports whether an endpoint receives an event type
+func subscribed(endpoint config.WebhookEndpoint, event string) bool {
+	if len(endpoint.Events) == 0 {
+		return true
+	}
+	for _, e := range endpoint.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver POSTs a payload to one endpoint, retrying failures with exponential
+// backoff, and returns the request ID the endpoint accepted it with, if any,
+// or the error of the last attempt
+func (s *WebhookService) deliver(ctx context.Context, endpoint config.WebhookEndpoint, event string, payload []byte) (string, error) {
+	attempts := s.cfg.Webhooks.MaxAttempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+	backoff := time.Duration(s.cfg.Webhooks.BackoffSec) * time.Second
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			s.sleep(backoff)
+			backoff *= 2
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		start := time.Now()
+		var status int
+		var requestID string
+		status, requestID, err = s.post(ctx, endpoint, event, payload)
+		delivery := models.WebhookDelivery{
+			Webhook:    endpoint.Name,
+			URL:        endpoint.URL,
+			Event:      event,
+			Payload:    string(payload),
+			Attempt:    attempt,
+			StatusCode: status,
+			Success:    err == nil,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		s.logDelivery(delivery)
+
+		if err == nil {
+			return requestID, nil
+		}
+		log.Warnf("Webhook %s delivery of %s failed (attempt %d of %d): %v", endpoint.Name, event, attempt, attempts, err)
+	}
+	log.Errorf("Webhook %s gave up delivering %s after %d attempts", endpoint.Name, event, attempts)
+	return "", err
+}
+
+// post sends one signed request and returns the response status with the
+// request ID of the response, if the endpoint returns one
+func (s *WebhookService) post(ctx context.Context, endpoint config.WebhookEndpoint, event string, payload []byte) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Licet-Webhook")
+	req.Header.Set("X-Licet-Event", event)
+	if endpoint.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(endpoint.Secret, payload))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, "", fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return resp.StatusCode, resp.Header.Get("X-Request-Id"), nil
+}
+
+// SignWebhookPayload returns the signature header value of a payload, so
+// receivers can verify it with the shared secret
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// logDelivery records a delivery attempt
+func (s *WebhookService) logDelivery(d models.WebhookDelivery) {
+	query := `
+		INSERT INTO webhook_deliveries (webhook, url, event, payload, attempt, status_code, success, error, duration_ms, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := s.db.Exec(s.db.Rebind(query), d.Webhook, d.URL, d.Event, d.Payload, d.Attempt,
+		d.StatusCode, d.Success, d.Error, d.DurationMs, time.Now())
+	if err != nil {
+		log.Errorf("Failed to log webhook delivery: %v", err)
+	}
+}
+
+// GetDeliveries returns the most recent delivery attempts, optionally only
+// those of one webhook or only failed ones
+func (s *WebhookService) GetDeliveries(ctx context.Context, webhook string, failedOnly bool, limit int) ([]models.WebhookDelivery, error) {
+	query := `SELECT * FROM webhook_deliveries WHERE 1 = 1`
+	var args []interface{}
+	if webhook != "" {
+		query += ` AND webhook = ?`
+		args = append(args, webhook)
+	}
+	if failedOnly {
+		query += ` AND success = ?`
+		args = append(args, false)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	var deliveries []models.WebhookDelivery
+	err := s.db.SelectContext(ctx, &deliveries, s.db.Rebind(query), args...)
+	return deliveries, err
+}
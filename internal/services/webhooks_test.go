@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	// echo -n '{"event":"alert"}' | openssl dgst -sha256 -hmac secret
+	got := SignWebhookPayload("secret", []byte(`{"event":"alert"}`))
+	want := "sha256=583f65e70ec3427bb98a71fcd66260a0b5cc3309d73aa7bbcedfed385477ab96"
+	if got != want {
+		t.Errorf("SignWebhookPayload = %q, want %q", got, want)
+	}
+}
+
+func TestWebhookService_NotifyRetries(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	var lastSignature, lastEvent string
+	var lastBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		lastSignature = r.Header.Get(WebhookSignatureHeader)
+		lastEvent = r.Header.Get("X-Licet-Event")
+		lastBody, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{Webhooks: config.WebhookConfig{
+		MaxAttempts: 5,
+		BackoffSec:  1,
+		Endpoints: []config.WebhookEndpoint{
+			{Name: "ops", URL: server.URL, Secret: "s3cret"},
+			{Name: "alerts-only", URL: server.URL + "/never", Events: []string{WebhookEventAlert}},
+		},
+	}}
+	s := NewWebhookService(db, cfg)
+	var waits []time.Duration
+	s.sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	s.Notify(ctx, WebhookEventServerStatus, ServerStatusChange{Server: "27000@a", Status: "down", PreviousStatus: "up"})
+
+	if calls.Load() != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", calls.Load())
+	}
+	if len(waits) != 2 || waits[0] != time.Second || waits[1] != 2*time.Second {
+		t.Errorf("Expected exponential backoff of 1s and 2s, got %v", waits)
+	}
+	if lastEvent != WebhookEventServerStatus {
+		t.Errorf("Unexpected event header %q", lastEvent)
+	}
+	if lastSignature != SignWebhookPayload("s3cret", lastBody) {
+		t.Errorf("Signature %q does not match the body", lastSignature)
+	}
+	var payload struct {
+		Event string             `json:"event"`
+		Data  ServerStatusChange `json:"data"`
+	}
+	if err := json.Unmarshal(lastBody, &payload); err != nil || payload.Data.Server != "27000@a" {
+		t.Errorf("Unexpected payload %s (%v)", lastBody, err)
+	}
+
+	deliveries, err := s.GetDeliveries(ctx, "", false, 10)
+	if err != nil {
+		t.Fatalf("GetDeliveries failed: %v", err)
+	}
+	if len(deliveries) != 3 || !deliveries[0].Success || deliveries[0].Attempt != 3 {
+		t.Fatalf("Expected 3 logged attempts ending in success, got %+v", deliveries)
+	}
+	failed, err := s.GetDeliveries(ctx, "ops", true, 10)
+	if err != nil {
+		t.Fatalf("GetDeliveries failed: %v", err)
+	}
+	if len(failed) != 2 || failed[0].StatusCode != http.StatusServiceUnavailable || failed[0].Error == "" {
+		t.Errorf("Expected 2 failed attempts with status and error, got %+v", failed)
+	}
+}
@@ -0,0 +1,379 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression is a parsed arithmetic expression over license features, e.g.
+// used(solver) + 2*used("cfd-pro", "27000@b"). Features are read through
+// functions taking a feature name and an optional server:
+//
+//	used(f)   seats in use
+//	total(f)  seats issued
+//	free(f)   seats available
+//
+// min(a, b, ...) and max(a, b, ...) combine expressions. Numbers, + - * /
+// and parentheses have their usual meaning.
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+// FeatureLookup returns a feature's value for a function (used, total or
+// free), summed over all servers if server is empty
+type FeatureLookup func(function, feature, server string) float64
+
+// featureFunctions are the functions reading a feature
+var featureFunctions = map[string]bool{"used": true, "total": true, "free": true}
+
+// ParseExpression parses an expression
+func ParseExpression(source string) (*Expression, error) {
+	p := &exprParser{source: source}
+	p.next()
+	root, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns the expression as it was parsed
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Features returns the features the expression reads, in order of first use
+func (e *Expression) Features() []string {
+	var features []string
+	seen := make(map[string]bool)
+	e.root.walk(func(n exprNode) {
+		if call, ok := n.(*featureCall); ok && !seen[call.feature] {
+			seen[call.feature] = true
+			features = append(features, call.feature)
+		}
+	})
+	return features
+}
+
+// Eval evaluates the expression, reading features through lookup
+func (e *Expression) Eval(lookup FeatureLookup) (float64, error) {
+	v, err := e.root.eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("expression %q is not a finite number", e.source)
+	}
+	return v, nil
+}
+
+// Expression syntax tree
+
+type exprNode interface {
+	eval(lookup FeatureLookup) (float64, error)
+	walk(visit func(exprNode))
+}
+
+type numberNode float64
+
+func (n numberNode) eval(FeatureLookup) (float64, error) { return float64(n), nil }
+func (n numberNode) walk(visit func(exprNode))           { visit(n) }
+
+type negateNode struct{ operand exprNode }
+
+func (n *negateNode) eval(lookup FeatureLookup) (float64, error) {
+	v, err := n.operand.eval(lookup)
+	return -v, err
+}
+
+func (n *negateNode) walk(visit func(exprNode)) {
+	visit(n)
+	n.operand.walk(visit)
+}
+
+type binaryNode struct {
+	op          byte
+	left, right exprNode
+}
+
+func (n *binaryNode) eval(lookup FeatureLookup) (float64, error) {
+	l, err := n.left.eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	r, err := n.right.eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	default:
+		if r == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	}
+}
+
+func (n *binaryNode) walk(visit func(exprNode)) {
+	visit(n)
+	n.left.walk(visit)
+	n.right.walk(visit)
+}
+
+type featureCall struct {
+	function, feature, server string
+}
+
+func (n *featureCall) eval(lookup FeatureLookup) (float64, error) {
+	return lookup(n.function, n.feature, n.server), nil
+}
+
+func (n *featureCall) walk(visit func(exprNode)) { visit(n) }
+
+type aggregateCall struct {
+	function string // min or max
+	args     []exprNode
+}
+
+func (n *aggregateCall) eval(lookup FeatureLookup) (float64, error) {
+	var result float64
+	for i, arg := range n.args {
+		v, err := arg.eval(lookup)
+		if err != nil {
+			return 0, err
+		}
+		if i == 0 || (n.function == "min" && v < result) || (n.function == "max" && v > result) {
+			result = v
+		}
+	}
+	return result, nil
+}
+
+func (n *aggregateCall) walk(visit func(exprNode)) {
+	visit(n)
+	for _, arg := range n.args {
+		arg.walk(visit)
+	}
+}
+
+// Tokenizer and recursive descent parser
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokString
+	tokOp // One of + - * / ( ) ,
+	tokInvalid
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+type exprParser struct {
+	source string
+	pos    int
+	tok    token
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("at position %d: %s", p.tok.pos+1, fmt.Sprintf(format, args...))
+}
+
+// next reads the next token
+func (p *exprParser) next() {
+	for p.pos < len(p.source) && unicode.IsSpace(rune(p.source[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.source) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+
+	c := p.source[p.pos]
+	switch {
+	case strings.IndexByte("+-*/(),", c) >= 0:
+		p.pos++
+		p.tok = token{kind: tokOp, text: string(c), pos: start}
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.source) && (p.source[p.pos] >= '0' && p.source[p.pos] <= '9' || p.source[p.pos] == '.') {
+			p.pos++
+		}
+		p.tok = token{kind: tokNumber, text: p.source[start:p.pos], pos: start}
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for p.pos < len(p.source) && (p.source[p.pos] == '_' || unicode.IsLetter(rune(p.source[p.pos])) || unicode.IsDigit(rune(p.source[p.pos]))) {
+			p.pos++
+		}
+		p.tok = token{kind: tokIdent, text: p.source[start:p.pos], pos: start}
+	case c == '"' || c == '\'':
+		end := strings.IndexByte(p.source[p.pos+1:], c)
+		if end < 0 {
+			p.pos = len(p.source)
+			p.tok = token{kind: tokInvalid, text: "unterminated string", pos: start}
+			return
+		}
+		p.tok = token{kind: tokString, text: p.source[p.pos+1 : p.pos+1+end], pos: start}
+		p.pos += end + 2
+	default:
+		p.pos++
+		p.tok = token{kind: tokInvalid, text: string(c), pos: start}
+	}
+}
+
+func (p *exprParser) isOp(op string) bool {
+	return p.tok.kind == tokOp && p.tok.text == op
+}
+
+func (p *exprParser) expect(op string) error {
+	if !p.isOp(op) {
+		return p.errorf("expected %q, found %s", op, p.tok)
+	}
+	p.next()
+	return nil
+}
+
+// parseSum parses terms joined by + and -
+func (p *exprParser) parseSum() (exprNode, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+") || p.isOp("-") {
+		op := p.tok.text[0]
+		p.next()
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseProduct parses factors joined by * and /
+func (p *exprParser) parseProduct() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*") || p.isOp("/") {
+		op := p.tok.text[0]
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.isOp("-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negateNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	switch p.tok.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(p.tok.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %s", p.tok)
+		}
+		p.next()
+		return numberNode(v), nil
+	case tokIdent:
+		return p.parseCall()
+	case tokOp:
+		if p.isOp("(") {
+			p.next()
+			node, err := p.parseSum()
+			if err != nil {
+				return nil, err
+			}
+			return node, p.expect(")")
+		}
+	}
+	return nil, p.errorf("unexpected %s", p.tok)
+}
+
+// parseCall parses a function call, the current token being its name
+func (p *exprParser) parseCall() (exprNode, error) {
+	name := strings.ToLower(p.tok.text)
+	p.next()
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	if featureFunctions[name] {
+		call := &featureCall{function: name}
+		if p.tok.kind != tokIdent && p.tok.kind != tokString {
+			return nil, p.errorf("%s expects a feature name, found %s", name, p.tok)
+		}
+		call.feature = p.tok.text
+		p.next()
+		if p.isOp(",") {
+			p.next()
+			if p.tok.kind != tokString {
+				return nil, p.errorf("%s expects a quoted server, found %s", name, p.tok)
+			}
+			call.server = p.tok.text
+			p.next()
+		}
+		return call, p.expect(")")
+	}
+
+	if name != "min" && name != "max" {
+		return nil, fmt.Errorf("unknown function %q, expected used, total, free, min or max", name)
+	}
+	call := &aggregateCall{function: name}
+	for {
+		arg, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+	return call, p.expect(")")
+}
@@ -0,0 +1,87 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpression_Eval(t *testing.T) {
+	values := map[string]float64{
+		"used:a": 3, "total:a": 10, "free:a": 7,
+		"used:b": 2, "total:b": 4, "free:b": 2,
+		"used:b@27000@x": 1,
+	}
+	lookup := func(function, feature, server string) float64 {
+		key := function + ":" + feature
+		if server != "" {
+			key += "@" + server
+		}
+		return values[key]
+	}
+
+	tests := []struct {
+		expr string
+		want float64
+	}{
+		{"used(a)+2*used(b)", 7},
+		{"(used(a) + used(b)) / 2", 2.5},
+		{"-used(a) + 10", 7},
+		{"total(a) - used(a)", 7},
+		{"free('a')", 7},
+		{`used(b, "27000@x")`, 1},
+		{"max(used(a), 2*used(b), 1)", 4},
+		{"min(free(a), free(b))", 2},
+		{"1.5 * 4", 6},
+		{"used(unknown)", 0},
+		{"USED(a)", 3},
+	}
+	for _, tt := range tests {
+		expr, err := ParseExpression(tt.expr)
+		if err != nil {
+			t.Errorf("ParseExpression(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		got, err := expr.Eval(lookup)
+		if err != nil {
+			t.Errorf("Eval(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Eval(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	expr, _ := ParseExpression("used(a) / (used(b) - 2)")
+	if _, err := expr.Eval(lookup); err == nil {
+		t.Error("Expected an error dividing by zero")
+	}
+}
+
+func TestParseExpression_Errors(t *testing.T) {
+	for _, source := range []string{
+		"",
+		"used(a",
+		"used(a) +",
+		"used()",
+		"used(a, b)",
+		"avg(used(a))",
+		"used(a) used(b)",
+		"used('a)",
+		"2 % 3",
+		"1..2",
+	} {
+		if _, err := ParseExpression(source); err == nil {
+			t.Errorf("ParseExpression(%q): expected an error", source)
+		}
+	}
+}
+
+func TestExpression_Features(t *testing.T) {
+	expr, err := ParseExpression(`used(a) + 2*total(b) - max(free(a), used("c-d"))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := expr.Features(), []string{"a", "b", "c-d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Features() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Calendar is a minimal iCalendar (RFC 5545) writer for feeds of all-day
+// events that calendar clients can subscribe to
+type Calendar struct {
+	name   string
+	events []CalendarEvent
+}
+
+// CalendarEvent is an all-day event. Reminders are days before the event at
+// which clients show an alarm.
+type CalendarEvent struct {
+	UID         string
+	Date        time.Time
+	Summary     string
+	Description string
+	Categories  []string
+	Reminders   []int
+}
+
+// NewCalendar creates an empty calendar shown by clients as name
+func NewCalendar(name string) *Calendar {
+	return &Calendar{name: name}
+}
+
+// AddEvent adds an event to the calendar
+func (c *Calendar) AddEvent(event CalendarEvent) {
+	c.events = append(c.events, event)
+}
+
+// Write writes the calendar as an iCalendar file
+func (c *Calendar) Write(w io.Writer) error {
+	var b bytes.Buffer
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	line := func(name, value string) {
+		writeICalLine(&b, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//Licet//License Expirations//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	line("X-WR-CALNAME", escapeICalText(c.name))
+	for _, e := range c.events {
+		line("BEGIN", "VEVENT")
+		line("UID", escapeICalText(e.UID))
+		line("DTSTAMP", stamp)
+		line("DTSTART;VALUE=DATE", e.Date.Format("20060102"))
+		line("DTEND;VALUE=DATE", e.Date.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY", escapeICalText(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escapeICalText(e.Description))
+		}
+		if len(e.Categories) > 0 {
+			categories := make([]string, len(e.Categories))
+			for i, category := range e.Categories {
+				categories[i] = escapeICalText(category)
+			}
+			line("CATEGORIES", strings.Join(categories, ","))
+		}
+		line("TRANSP", "TRANSPARENT")
+		for _, days := range e.Reminders {
+			line("BEGIN", "VALARM")
+			line("ACTION", "DISPLAY")
+			line("TRIGGER", fmt.Sprintf("-P%dD", days))
+			line("DESCRIPTION", escapeICalText(e.Summary))
+			line("END", "VALARM")
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// escapeICalText escapes a TEXT value
+func escapeICalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeICalLine writes a content line, folded after 75 octets without
+// splitting UTF-8 characters
+func writeICalLine(b *bytes.Buffer, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74 // Continuation lines start with a space
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,57 @@
+package util
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestCalendar_Write(t *testing.T) {
+	cal := NewCalendar("License expirations")
+	cal.AddEvent(CalendarEvent{
+		UID:         "solver-27000@lic1@licet",
+		Date:        time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC),
+		Summary:     "solver expires; renew 10 seats, now",
+		Description: "Server: 27000@lic1\nVersions: 2.0",
+		Categories:  []string{"License expiration", "vendor"},
+		Reminders:   []int{30, 7},
+	})
+	cal.AddEvent(CalendarEvent{UID: "long", Date: time.Date(2027, 2, 1, 0, 0, 0, 0, time.UTC), Summary: strings.Repeat("é", 60)})
+
+	var buf bytes.Buffer
+	if err := cal.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"X-WR-CALNAME:License expirations\r\n",
+		"DTSTART;VALUE=DATE:20270131\r\nDTEND;VALUE=DATE:20270201\r\n",
+		`SUMMARY:solver expires\; renew 10 seats\, now` + "\r\n",
+		`DESCRIPTION:Server: 27000@lic1\nVersions: 2.0` + "\r\n",
+		"CATEGORIES:License expiration,vendor\r\n",
+		"TRIGGER:-P30D\r\n",
+		"TRIGGER:-P7D\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the calendar to contain %q, got:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "BEGIN:VALARM"); n != 2 {
+		t.Errorf("Expected 2 alarms, got %d", n)
+	}
+
+	// Long lines are folded at 75 octets without splitting characters
+	for _, line := range strings.Split(out, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Line longer than 75 octets: %q", line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("Folding split a character: %q", line)
+		}
+	}
+}
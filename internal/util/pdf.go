@@ -0,0 +1,309 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Page geometry of a PDFDocument in points: A4 with 50pt margins
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 50.0
+	pdfTextWidth  = pdfPageWidth - 2*pdfMargin
+)
+
+// Fonts of a PDFDocument, the standard Type 1 fonts every reader provides
+const (
+	pdfFontRegular = "F1" // Helvetica
+	pdfFontBold    = "F2" // Helvetica-Bold
+	pdfFontMono    = "F3" // Courier
+)
+
+// PDFDocument is a minimal PDF writer for reports. Content flows top to bottom
+// across A4 pages: headings, wrapped paragraphs and fixed-width tables. Text
+// outside Latin-1 is replaced, as only the standard fonts are used.
+type PDFDocument struct {
+	pages  []*bytes.Buffer
+	y      float64
+	header string
+}
+
+// NewPDFDocument creates a document with one empty page
+func NewPDFDocument() *PDFDocument {
+	doc := &PDFDocument{}
+	doc.PageBreak()
+	return doc
+}
+
+// PageBreak starts a new page
+func (d *PDFDocument) PageBreak() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pdfPageHeight - pdfMargin
+}
+
+// SetHeader sets a line printed in the top margin of every page, e.g. the
+// product name
+func (d *PDFDocument) SetHeader(text string) {
+	d.header = text
+}
+
+// Pages returns the number of pages
+func (d *PDFDocument) Pages() int {
+	return len(d.pages)
+}
+
+// Heading adds a bold heading, kept on the page of the content following it
+func (d *PDFDocument) Heading(text string) {
+	d.space(40)
+	d.y -= 6
+	d.line(pdfFontBold, 14, text)
+	d.y -= 4
+}
+
+// Text adds a paragraph, wrapped at the text width
+func (d *PDFDocument) Text(text string) {
+	const size = 10.0
+	charWidth := size * 0.5 // Helvetica averages about half an em per character
+	width := int(pdfTextWidth / charWidth)
+	for _, paragraph := range strings.Split(text, "\n") {
+		for _, line := range wrapText(paragraph, width) {
+			d.line(pdfFontRegular, size, line)
+		}
+	}
+	d.y -= 4
+}
+
+// Table adds a table in a fixed-width font. Columns are as wide as their
+// widest cell; cells are shortened when the table exceeds the text width.
+// The header row is repeated on each page the table continues on.
+func (d *PDFDocument) Table(header []string, rows [][]string) {
+	const size = 8.0
+	charWidth := size * 0.6 // Courier is 0.6em wide
+	maxChars := int(pdfTextWidth / charWidth)
+
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = len([]rune(h))
+	}
+	for _, row := range rows {
+		for i := 0; i < len(row) && i < len(widths); i++ {
+			widths[i] = max(widths[i], len([]rune(row[i])))
+		}
+	}
+	// Shrink the widest column until the table fits, two spaces apart
+	for {
+		total := 2 * (len(widths) - 1)
+		widest := 0
+		for i, w := range widths {
+			total += w
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if total <= maxChars || widths[widest] <= 4 {
+			break
+		}
+		widths[widest]--
+	}
+
+	format := func(cells []string) string {
+		var b strings.Builder
+		for i, w := range widths {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			if runes := []rune(cell); len(runes) > w {
+				cell = string(runes[:w-1]) + "~"
+			}
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			b.WriteString(cell)
+			b.WriteString(strings.Repeat(" ", w-len([]rune(cell))))
+		}
+		return strings.TrimRight(b.String(), " ")
+	}
+
+	writeHeader := func() {
+		d.line(pdfFontMono, size, format(header))
+		d.rule()
+	}
+
+	d.space(3 * size * 1.4)
+	writeHeader()
+	for _, row := range rows {
+		if d.y-size*1.4 < pdfMargin {
+			d.PageBreak()
+			writeHeader()
+		}
+		d.line(pdfFontMono, size, format(row))
+	}
+	d.y -= 8
+}
+
+// Chart adds a line chart of values across the text width, captioned and
+// labelled with the first and last of labels on the x axis. A positive limit,
+// such as the seats of a feature, is drawn as a dashed line.
+func (d *PDFDocument) Chart(caption string, labels []string, values []float64, limit float64) {
+	const height = 110.0
+	d.space(height + 40)
+	d.line(pdfFontBold, 9, caption)
+
+	top := d.y - 6
+	bottom := top - height
+	left := pdfMargin + 30 // Room for the y axis labels
+	right := pdfPageWidth - pdfMargin
+	page := d.pages[len(d.pages)-1]
+
+	scale := limit
+	for _, v := range values {
+		scale = max(scale, v)
+	}
+	if scale <= 0 {
+		scale = 1
+	}
+	scale *= 1.1
+	y := func(v float64) float64 { return bottom + v/scale*height }
+
+	fmt.Fprintf(page, "0.5 w %.2f %.2f m %.2f %.2f l %.2f %.2f l S\n", left, top, left, bottom, right, bottom)
+	d.text(page, pdfFontRegular, 7, pdfMargin, bottom-2, "0")
+	d.text(page, pdfFontRegular, 7, pdfMargin, top-6, strconv.FormatFloat(scale, 'f', 0, 64))
+	if limit > 0 {
+		fmt.Fprintf(page, "[3 2] 0 d 0.8 0 0 RG %.2f %.2f m %.2f %.2f l S [] 0 d 0 G\n", left, y(limit), right, y(limit))
+		d.text(page, pdfFontRegular, 7, pdfMargin, y(limit)-2, strconv.FormatFloat(limit, 'f', -1, 64))
+	}
+
+	if len(values) > 0 {
+		step := (right - left) / float64(max(len(values)-1, 1))
+		fmt.Fprintf(page, "1 w 0.2 0.4 0.8 RG %.2f %.2f m", left, y(values[0]))
+		if len(values) == 1 {
+			fmt.Fprintf(page, " %.2f %.2f l", right, y(values[0]))
+		}
+		for i, v := range values[1:] {
+			fmt.Fprintf(page, " %.2f %.2f l", left+float64(i+1)*step, y(v))
+		}
+		page.WriteString(" S 0 G\n")
+	}
+
+	if len(labels) > 0 {
+		d.text(page, pdfFontRegular, 7, left, bottom-10, labels[0])
+		last := labels[len(labels)-1]
+		d.text(page, pdfFontRegular, 7, right-float64(len([]rune(last)))*7*0.5, bottom-10, last)
+	}
+	d.y = bottom - 20
+}
+
+// text writes text at a position of a page
+func (d *PDFDocument) text(page *bytes.Buffer, font string, size, x, y float64, text string) {
+	fmt.Fprintf(page, "BT /%s %.0f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfEscape(text))
+}
+
+// space starts a new page unless the given height still fits on this one
+func (d *PDFDocument) space(height float64) {
+	if d.y-height < pdfMargin {
+		d.PageBreak()
+	}
+}
+
+// line writes one line of text and moves below it
+func (d *PDFDocument) line(font string, size float64, text string) {
+	d.space(size * 1.4)
+	d.y -= size * 1.4
+	page := d.pages[len(d.pages)-1]
+	fmt.Fprintf(page, "BT /%s %.0f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, pdfMargin, d.y+size*0.3, pdfEscape(text))
+}
+
+// rule draws a thin horizontal line below the previous line
+func (d *PDFDocument) rule() {
+	page := d.pages[len(d.pages)-1]
+	fmt.Fprintf(page, "0.5 w %.2f %.2f m %.2f %.2f l S\n", pdfMargin, d.y, pdfPageWidth-pdfMargin, d.y)
+	d.y -= 2
+}
+
+// Write writes the document as PDF
+func (d *PDFDocument) Write(w io.Writer) error {
+	var buf bytes.Buffer
+	var offsets []int
+
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects 1-5 are the catalog, page tree and fonts; each page follows as
+	// a page object and its content stream
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	for i, page := range d.pages {
+		content := page.String()
+		if d.header != "" {
+			content = fmt.Sprintf("BT /%s 8 Tf %.2f %.2f Td (%s) Tj ET\n", pdfFontRegular, pdfMargin, pdfPageHeight-pdfMargin/2, pdfEscape(d.header)) + content
+		}
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R /F3 5 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 7+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// pdfEscape encodes text as the content of a PDF string in Latin-1
+func pdfEscape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r == '\t':
+			b.WriteByte(' ')
+		case r < 0x20 || r > 0xff:
+			b.WriteByte('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+	return b.String()
+}
+
+// wrapText breaks text into lines of at most width characters at spaces
+func wrapText(text string, width int) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	line := words[0]
+	for _, word := range words[1:] {
+		if len([]rune(line))+1+len([]rune(word)) > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line += " " + word
+	}
+	return append(lines, line)
+}
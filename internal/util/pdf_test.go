@@ -0,0 +1,100 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPDFDocument_Write(t *testing.T) {
+	doc := NewPDFDocument()
+	doc.Heading("Capacity (weekly)")
+	doc.Text("Features above 80% utilization \\ café ✓")
+	rows := make([][]string, 120)
+	for i := range rows {
+		rows[i] = []string{fmt.Sprintf("feature_%d", i), strings.Repeat("x", 200), "42"}
+	}
+	doc.Table([]string{"Feature", "Server", "Peak"}, rows)
+
+	if doc.Pages() < 2 {
+		t.Fatalf("Expected the table to continue on a second page, got %d pages", doc.Pages())
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+
+	if !strings.HasPrefix(out, "%PDF-1.4\n") || !strings.HasSuffix(out, "%%EOF\n") {
+		t.Error("Expected a PDF header and trailer")
+	}
+	if !strings.Contains(out, `(Capacity \(weekly\)) Tj`) {
+		t.Error("Expected parentheses in text to be escaped")
+	}
+	if !strings.Contains(out, "\\\\ caf\xe9 ?") {
+		t.Error("Expected Latin-1 text with unsupported characters replaced")
+	}
+	if strings.Contains(out, strings.Repeat("x", 101)) {
+		t.Error("Expected long cells to be shortened to the page width")
+	}
+	if got := strings.Count(out, "(Feature  "); got != doc.Pages() {
+		t.Errorf("Expected the table header on each of %d pages, got %d", doc.Pages(), got)
+	}
+
+	// Every xref entry points at its object
+	xref := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(out, -1)
+	if len(xref) != 5+2*doc.Pages() {
+		t.Fatalf("Expected %d objects, got %d", 5+2*doc.Pages(), len(xref))
+	}
+	for i, entry := range xref {
+		offset, _ := strconv.Atoi(entry[1])
+		if want := fmt.Sprintf("%d 0 obj", i+1); !strings.HasPrefix(out[offset:], want) {
+			t.Errorf("xref entry %d does not point at %q", i+1, want)
+		}
+	}
+}
+
+func TestPDFDocument_Chart(t *testing.T) {
+	doc := NewPDFDocument()
+	doc.Chart("solver on 27000@lic1", []string{"2026-03-01", "2026-03-03"}, []float64{4, 8, 6}, 10)
+	doc.Chart("viewer", nil, nil, 0)
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"(solver on 27000@lic1) Tj",
+		"(2026-03-01) Tj",
+		"(2026-03-03) Tj",
+		"(11) Tj", // The y axis reaches 10% above the limit
+		"(10) Tj",
+		"[3 2] 0 d",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the chart to contain %q", want)
+		}
+	}
+	// Three points: a move and two lines
+	if !regexp.MustCompile(`0\.2 0\.4 0\.8 RG [\d.]+ [\d.]+ m [\d.]+ [\d.]+ l [\d.]+ [\d.]+ l S`).MatchString(out) {
+		t.Error("Expected a line through the three values")
+	}
+	if strings.Count(out, "[3 2] 0 d") != 1 {
+		t.Error("Expected no limit line without a limit")
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	lines := wrapText("one two three four", 9)
+	if strings.Join(lines, "|") != "one two|three|four" {
+		t.Errorf("Unexpected wrapping %q", lines)
+	}
+	if lines := wrapText("", 9); len(lines) != 1 || lines[0] != "" {
+		t.Errorf("Expected an empty line, got %q", lines)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/robfig/cron/v3"
 )
 
 // Pre-compiled regex patterns for validation
@@ -168,6 +170,23 @@ func ValidatePort(port int) error {
 	return nil
 }
 
+// ValidateRange validates that an integer is between min and max, inclusive
+func ValidateRange(value, min, max int, fieldName string) error {
+	if value < min || value > max {
+		return fmt.Errorf("%s must be between %d and %d", fieldName, min, max)
+	}
+	return nil
+}
+
+// ValidateHost validates a hostname or IPv4 address without a port, e.g. of
+// an SMTP server
+func ValidateHost(host string) error {
+	if !isValidHost(host) {
+		return fmt.Errorf("invalid host: %q", host)
+	}
+	return nil
+}
+
 // ValidatePositiveInt validates that an integer is positive
 func ValidatePositiveInt(value int, fieldName string) error {
 	if value < 0 {
@@ -175,3 +194,24 @@ func ValidatePositiveInt(value int, fieldName string) error {
 	}
 	return nil
 }
+
+// ValidatePollSchedule validates the polling schedule of a server: an interval
+// in minutes or a cron expression, not both, and the jitter in seconds
+func ValidatePollSchedule(interval int, schedule string, jitter int) error {
+	if interval < 0 {
+		return fmt.Errorf("poll_interval must be a positive number of minutes")
+	}
+	if jitter < 0 {
+		return fmt.Errorf("jitter must be a positive number of seconds")
+	}
+	if schedule == "" {
+		return nil
+	}
+	if interval > 0 {
+		return fmt.Errorf("set either poll_interval or schedule, not both")
+	}
+	if _, err := cron.ParseStandard(schedule); err != nil {
+		return fmt.Errorf("invalid schedule %q: %w", schedule, err)
+	}
+	return nil
+}
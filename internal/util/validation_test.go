@@ -210,3 +210,84 @@ func TestSupportedServerTypes(t *testing.T) {
 		t.Error("invalid should not be supported")
 	}
 }
+
+func TestValidatePollSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval int
+		schedule string
+		jitter   int
+		valid    bool
+	}{
+		{"global interval", 0, "", 0, true},
+		{"own interval with jitter", 15, "", 30, true},
+		{"cron schedule", 0, "*/30 8-18 * * 1-5", 0, true},
+		{"negative interval", -5, "", 0, false},
+		{"negative jitter", 5, "", -1, false},
+		{"interval and schedule", 5, "0 * * * *", 0, false},
+		{"invalid schedule", 0, "every hour", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePollSchedule(tt.interval, tt.schedule, tt.jitter)
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid, got error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestValidateRange(t *testing.T) {
+	tests := []struct {
+		name  string
+		value int
+		valid bool
+	}{
+		{"Minimum", 1, true},
+		{"Maximum", 365, true},
+		{"Below", 0, false},
+		{"Above", 366, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRange(tt.value, 1, 365, "test_field")
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid, got error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestValidateHost(t *testing.T) {
+	tests := []struct {
+		name  string
+		host  string
+		valid bool
+	}{
+		{"Hostname", "smtp.example.com", true},
+		{"IP address", "10.0.0.25", true},
+		{"Empty", "", false},
+		{"With port", "smtp.example.com:587", false},
+		{"With scheme", "smtp://smtp.example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateHost(tt.host)
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid, got error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package util
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestXLSXColumn(t *testing.T) {
+	tests := map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"}
+	for index, want := range tests {
+		if got := XLSXColumn(index); got != want {
+			t.Errorf("XLSXColumn(%d) = %q, want %q", index, got, want)
+		}
+	}
+}
+
+func TestWorkbook_Write(t *testing.T) {
+	wb := NewWorkbook()
+	sheet := wb.AddSheet("Forecast: 2027/28 <seats> and a very long name", "Feature", "Seats", "Date")
+	sheet.AddRow("solver & mesher", 12, time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC))
+	sheet.AddRow("viewer", 1.5, time.Time{})
+	sheet.AddRow("mesher", int64(3), time.Date(2027, 1, 31, 18, 0, 0, 0, time.UTC))
+
+	var buf bytes.Buffer
+	if err := wb.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Workbook is not a zip archive: %v", err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", f.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Missing part %s", name)
+		}
+	}
+
+	if !strings.Contains(files["xl/workbook.xml"], `name="Forecast_ 2027_28 &lt;seats&gt; and a"`) {
+		t.Errorf("Expected a sanitized, shortened sheet name, got %s", files["xl/workbook.xml"])
+	}
+
+	sheetXML := files["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`state="frozen"`,
+		`<c r="A1" s="2" t="inlineStr"><is><t xml:space="preserve">Feature</t></is></c>`,
+		`<t xml:space="preserve">solver &amp; mesher</t>`,
+		`<c r="B2"><v>12</v></c>`,
+		`<c r="C2" s="1"><v>46418</v></c>`,
+		`<c r="B3"><v>1.5</v></c>`,
+		`<c r="C4" s="3"><v>46418.75</v></c>`,
+	} {
+		if !strings.Contains(sheetXML, want) {
+			t.Errorf("Expected sheet to contain %s", want)
+		}
+	}
+	if strings.Contains(sheetXML, `r="C3"`) {
+		t.Error("Expected the zero time to leave the cell empty")
+	}
+}
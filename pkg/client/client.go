@@ -0,0 +1,193 @@
+// Package client is a Go client for the Licet v2 REST API.
+//
+//	c := client.New("http://licet.example.com:8080", client.WithAPIKey(key))
+//	utilization, err := c.CurrentUtilization(ctx, "")
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pageSize is the page size used when fetching complete lists
+const pageSize = 500
+
+// Client talks to a Licet server
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithAPIKey authenticates requests with an API key
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithHTTPClient replaces the default HTTP client
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// New creates a client for the Licet server at baseURL
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is returned when the server responds with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("licet: %d %s", e.StatusCode, e.Message)
+}
+
+type envelope[T any] struct {
+	Data  T    `json:"data"`
+	Meta  Meta `json:"meta"`
+	Error *struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// get performs a GET against the v2 API and decodes the envelope
+func get[T any](ctx context.Context, c *Client, path string, params url.Values) (T, Meta, error) {
+	var env envelope[T]
+
+	u := c.baseURL + "/api/v2" + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return env.Data, env.Meta, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return env.Data, env.Meta, err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil && resp.StatusCode < 300 {
+		return env.Data, env.Meta, fmt.Errorf("licet: decode response: %w", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		if env.Error != nil {
+			apiErr.Message = env.Error.Message
+		}
+		return env.Data, env.Meta, apiErr
+	}
+
+	return env.Data, env.Meta, nil
+}
+
+// list fetches every page of a v2 list endpoint
+func list[T any](ctx context.Context, c *Client, path string, params url.Values) ([]T, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("limit", strconv.Itoa(pageSize))
+
+	var all []T
+	for page := 1; ; page++ {
+		params.Set("page", strconv.Itoa(page))
+		items, meta, err := get[[]T](ctx, c, path, params)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if page >= meta.TotalPages {
+			return all, nil
+		}
+	}
+}
+
+// filter builds query parameters from key/value pairs, skipping empty values
+func filter(kv ...string) url.Values {
+	params := url.Values{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		if kv[i+1] != "" {
+			params.Set(kv[i], kv[i+1])
+		}
+	}
+	return params
+}
+
+// Health returns the server health status
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	h, _, err := get[Health](ctx, c, "/health", nil)
+	return &h, err
+}
+
+// Servers returns all configured license servers
+func (c *Client) Servers(ctx context.Context) ([]Server, error) {
+	return list[Server](ctx, c, "/servers", nil)
+}
+
+// ServerStatus queries the live status of a license server
+func (c *Client) ServerStatus(ctx context.Context, hostname, serverType string) (*ServerStatus, error) {
+	s, _, err := get[ServerStatus](ctx, c, "/servers/"+url.PathEscape(hostname)+"/status", filter("type", serverType))
+	return &s, err
+}
+
+// Features returns the active features of a license server
+func (c *Client) Features(ctx context.Context, hostname string) ([]Feature, error) {
+	return list[Feature](ctx, c, "/servers/"+url.PathEscape(hostname)+"/features", nil)
+}
+
+// CurrentUtilization returns current utilization, optionally for a single server
+func (c *Client) CurrentUtilization(ctx context.Context, server string) ([]Utilization, error) {
+	return list[Utilization](ctx, c, "/utilization/current", filter("server", server))
+}
+
+// UtilizationHistory returns the usage time series of a feature.
+// Period is one of 7d, 30d, 90d or 1y.
+func (c *Client) UtilizationHistory(ctx context.Context, server, feature, period string) ([]UtilizationPoint, error) {
+	return list[UtilizationPoint](ctx, c, "/utilization/history", filter("server", server, "feature", feature, "period", period))
+}
+
+// UtilizationStats returns aggregated usage statistics over the given number of days
+func (c *Client) UtilizationStats(ctx context.Context, server string, days int) ([]UtilizationStats, error) {
+	return list[UtilizationStats](ctx, c, "/utilization/stats", filter("server", server, "days", strconv.Itoa(days)))
+}
+
+// Alerts returns pending alerts
+func (c *Client) Alerts(ctx context.Context) ([]Alert, error) {
+	return list[Alert](ctx, c, "/alerts", nil)
+}
+
+// Incidents returns correlated alert incidents from the last days
+func (c *Client) Incidents(ctx context.Context, days int) ([]Incident, error) {
+	return list[Incident](ctx, c, "/incidents", filter("days", strconv.Itoa(days)))
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestClient_ListPaginates(t *testing.T) {
+	servers := []Server{{Hostname: "27000@a"}, {Hostname: "27000@b"}, {Hostname: "5053@c"}}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/servers" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("X-API-Key") != "secret" {
+			t.Errorf("expected API key header")
+		}
+
+		// Serve one item per page regardless of the requested limit
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		total := len(servers)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": servers[page-1 : page],
+			"meta": map[string]interface{}{"total": total, "page": page, "total_pages": total},
+		})
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL+"/", WithAPIKey("secret"))
+	got, err := c.Servers(context.Background())
+	if err != nil {
+		t.Fatalf("Servers failed: %v", err)
+	}
+	if len(got) != 3 || got[2].Hostname != "5053@c" {
+		t.Errorf("unexpected servers: %+v", got)
+	}
+}
+
+func TestClient_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]interface{}{"status": 400, "message": "server and feature parameters required"},
+		})
+	}))
+	defer srv.Close()
+
+	_, err := New(srv.URL).UtilizationHistory(context.Background(), "", "", "")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+	if apiErr.StatusCode != 400 || apiErr.Message != "server and feature parameters required" {
+		t.Errorf("unexpected error: %+v", apiErr)
+	}
+}
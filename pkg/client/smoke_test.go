@@ -0,0 +1,53 @@
+//go:build smoke
+
+package client
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+// Smoke tests run against a live Licet instance:
+//
+//	LICET_URL=http://localhost:8080 LICET_API_KEY=... go test -tags smoke ./pkg/client/
+func smokeClient(t *testing.T) *Client {
+	t.Helper()
+	baseURL := os.Getenv("LICET_URL")
+	if baseURL == "" {
+		t.Skip("LICET_URL not set")
+	}
+	return New(baseURL, WithAPIKey(os.Getenv("LICET_API_KEY")))
+}
+
+func TestSmoke_Health(t *testing.T) {
+	h, err := smokeClient(t).Health(context.Background())
+	if err != nil {
+		t.Fatalf("Health failed: %v", err)
+	}
+	if h.Status != "ok" {
+		t.Errorf("Expected status ok, got %q", h.Status)
+	}
+}
+
+func TestSmoke_ServersAndUtilization(t *testing.T) {
+	c := smokeClient(t)
+	ctx := context.Background()
+
+	servers, err := c.Servers(ctx)
+	if err != nil {
+		t.Fatalf("Servers failed: %v", err)
+	}
+	for _, s := range servers {
+		if _, err := c.Features(ctx, s.Hostname); err != nil {
+			t.Errorf("Features(%s) failed: %v", s.Hostname, err)
+		}
+	}
+
+	if _, err := c.CurrentUtilization(ctx, ""); err != nil {
+		t.Errorf("CurrentUtilization failed: %v", err)
+	}
+	if _, err := c.Alerts(ctx); err != nil {
+		t.Errorf("Alerts failed: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package client
+
+import "time"
+
+// Server is a configured license server
+type Server struct {
+	ID          int64  `json:"id"`
+	Hostname    string `json:"hostname"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	CactiID     string `json:"cacti_id,omitempty"`
+	WebUI       string `json:"webui,omitempty"`
+}
+
+// ServerStatus is the live status of a license server
+type ServerStatus struct {
+	Hostname    string    `json:"hostname"`
+	Service     string    `json:"service"`
+	Master      string    `json:"master"`
+	Version     string    `json:"version"`
+	Message     string    `json:"message,omitempty"`
+	Warnings    []string  `json:"warnings,omitempty"`
+	LastChecked time.Time `json:"last_checked"`
+}
+
+// Feature is a license feature served by a license server
+type Feature struct {
+	ID                int64     `json:"id"`
+	ServerHostname    string    `json:"server_hostname"`
+	Name              string    `json:"name"`
+	Version           string    `json:"version"`
+	VendorDaemon      string    `json:"vendor_daemon"`
+	TotalLicenses     int       `json:"total_licenses"`
+	UsedLicenses      int       `json:"used_licenses"`
+	ReservedLicenses  int       `json:"reserved_licenses"`
+	BorrowedLicenses  int       `json:"borrowed_licenses"`
+	OverdraftLicenses int       `json:"overdraft_licenses"`
+	QueuedRequests    int       `json:"queued_requests"` // Checkout requests waiting for a free seat
+	ParseQuality      string    `json:"parse_quality"`   // "ok", or "defaulted_expiration" when the expiration date could not be parsed
+	ExpirationDate    time.Time `json:"expiration_date"`
+	LastUpdated       time.Time `json:"last_updated"`
+	IsActive          bool      `json:"is_active"`
+}
+
+// Utilization is the current utilization of a feature
+type Utilization struct {
+	ServerHostname    string  `json:"server_hostname"`
+	FeatureName       string  `json:"feature_name"`
+	Version           string  `json:"version"`
+	TotalLicenses     int     `json:"total_licenses"`
+	UsedLicenses      int     `json:"used_licenses"`
+	ReservedLicenses  int     `json:"reserved_licenses"`
+	BorrowedLicenses  int     `json:"borrowed_licenses"`
+	OverdraftLicenses int     `json:"overdraft_licenses"`
+	AvailableLicenses int     `json:"available_licenses"`
+	UtilizationPct    float64 `json:"utilization_pct"`
+	VendorDaemon      string  `json:"vendor_daemon"`
+	LicenseModel      string  `json:"license_model"`
+}
+
+// UtilizationPoint is a single point in a utilization time series
+type UtilizationPoint struct {
+	Timestamp   string `json:"timestamp"`
+	UsersCount  int    `json:"users_count"`
+	QueuedCount int    `json:"queued_count"`
+}
+
+// UtilizationStats are aggregated usage statistics for a feature
+type UtilizationStats struct {
+	ServerHostname string  `json:"server_hostname"`
+	FeatureName    string  `json:"feature_name"`
+	AvgUsage       float64 `json:"avg_usage"`
+	PeakUsage      int     `json:"peak_usage"`
+	MinUsage       int     `json:"min_usage"`
+	TotalLicenses  int     `json:"total_licenses"`
+}
+
+// Alert is a generated alert
+type Alert struct {
+	ID             int64       `json:"id"`
+	ServerHostname string      `json:"server_hostname"`
+	FeatureName    string      `json:"feature_name"`
+	AlertType      string      `json:"alert_type"`
+	Message        string      `json:"message"`
+	Severity       string      `json:"severity"`
+	Sent           bool        `json:"sent"`
+	SentAt         *time.Time  `json:"sent_at,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+	IncidentID     *int64      `json:"incident_id,omitempty"`
+	Links          *AlertLinks `json:"links,omitempty"`
+}
+
+// Incident groups related alerts for the same server
+type Incident struct {
+	ID             int64     `json:"id"`
+	ServerHostname string    `json:"server_hostname"`
+	RootCause      string    `json:"root_cause"`
+	Severity       string    `json:"severity"`
+	Status         string    `json:"status"` // open, resolved
+	AlertCount     int       `json:"alert_count"`
+	OpenedAt       time.Time `json:"opened_at"`
+	LastAlertAt    time.Time `json:"last_alert_at"`
+	Notified       bool      `json:"notified"`
+	Alerts         []Alert   `json:"alerts,omitempty"`
+}
+
+// AlertLinks are the deep links attached to an alert
+type AlertLinks struct {
+	Details string `json:"details,omitempty"`
+	Trend   string `json:"trend,omitempty"`
+	Runbook string `json:"runbook,omitempty"`
+}
+
+// Health is the server health check response
+type Health struct {
+	Status  string `json:"status"`
+	Version string `json:"version"`
+}
+
+// Meta is the metadata block of a v2 API response
+type Meta struct {
+	Total       *int              `json:"total,omitempty"`
+	Page        int               `json:"page,omitempty"`
+	Limit       int               `json:"limit,omitempty"`
+	TotalPages  int               `json:"total_pages,omitempty"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Filters     map[string]string `json:"filters"`
+}
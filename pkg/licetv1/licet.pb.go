@@ -0,0 +1,1217 @@
+// The licet.v1 gRPC service serves the servers, utilization and forecasts of
+// the REST API and streams collection and alert events, for integrations that
+// already use gRPC. Requests authenticate with an API key in the x-api-key
+// or authorization ("Bearer <key>") metadata when authentication is enabled.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: licet/v1/licet.proto
+
+package licetv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type EventType int32
+
+const (
+	EventType_EVENT_TYPE_UNSPECIFIED   EventType = 0
+	EventType_EVENT_TYPE_SERVER_STATUS EventType = 1
+	EventType_EVENT_TYPE_ALERT         EventType = 2
+)
+
+// Enum value maps for EventType.
+var (
+	EventType_name = map[int32]string{
+		0: "EVENT_TYPE_UNSPECIFIED",
+		1: "EVENT_TYPE_SERVER_STATUS",
+		2: "EVENT_TYPE_ALERT",
+	}
+	EventType_value = map[string]int32{
+		"EVENT_TYPE_UNSPECIFIED":   0,
+		"EVENT_TYPE_SERVER_STATUS": 1,
+		"EVENT_TYPE_ALERT":         2,
+	}
+)
+
+func (x EventType) Enum() *EventType {
+	p := new(EventType)
+	*p = x
+	return p
+}
+
+func (x EventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_licet_v1_licet_proto_enumTypes[0].Descriptor()
+}
+
+func (EventType) Type() protoreflect.EnumType {
+	return &file_licet_v1_licet_proto_enumTypes[0]
+}
+
+func (x EventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EventType.Descriptor instead.
+func (EventType) EnumDescriptor() ([]byte, []int) {
+	return file_licet_v1_licet_proto_rawDescGZIP(), []int{0}
+}
+
+type ListServersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListServersRequest) Reset() {
+	*x = ListServersRequest{}
+	mi := &file_licet_v1_licet_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListServersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListServersRequest) ProtoMessage() {}
+
+func (x *ListServersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_licet_v1_licet_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListServersRequest.ProtoReflect.Descriptor instead.
+func (*ListServersRequest) Descriptor() ([]byte, []int) {
+	return file_licet_v1_licet_proto_rawDescGZIP(), []int{0}
+}
+
+type ListServersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Servers       []*Server              `protobuf:"bytes,1,rep,name=servers,proto3" json:"servers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListServersResponse) Reset() {
+	*x = ListServersResponse{}
+	mi := &file_licet_v1_licet_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListServersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListServersResponse) ProtoMessage() {}
+
+func (x *ListServersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_licet_v1_licet_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListServersResponse.ProtoReflect.Descriptor instead.
+func (*ListServersResponse) Descriptor() ([]byte, []int) {
+	return file_licet_v1_licet_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListServersResponse) GetServers() []*Server {
+	if x != nil {
+		return x.Servers
+	}
+	return nil
+}
+
+type Server struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hostname      string                 `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"` // flexlm, rlm, ...
+	ResourceId    int64                  `protobuf:"varint,4,opt,name=resource_id,json=resourceId,proto3" json:"resource_id,omitempty"`
+	Uuid          string                 `protobuf:"bytes,5,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	PollInterval  int32                  `protobuf:"varint,6,opt,name=poll_interval,json=pollInterval,proto3" json:"poll_interval,omitempty"` // Minutes between polls, if not the global interval
+	Schedule      string                 `protobuf:"bytes,7,opt,name=schedule,proto3" json:"schedule,omitempty"`                              // Cron expression of the polls
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Server) Reset() {
+	*x = Server{}
+	mi := &file_licet_v1_licet_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Server) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Server) ProtoMessage() {}
+
+func (x *Server) ProtoReflect() protoreflect.Message {
+	mi := &file_licet_v1_licet_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Server.ProtoReflect.Descriptor instead.
+func (*Server) Descriptor() ([]byte, []int) {
+	return file_licet_v1_licet_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Server) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *Server) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Server) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Server) GetResourceId() int64 {
+	if x != nil {
+		return x.ResourceId
+	}
+	return 0
+}
+
+func (x *Server) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *Server) GetPollInterval() int32 {
+	if x != nil {
+		return x.PollInterval
+	}
+	return 0
+}
+
+func (x *Server) GetSchedule() string {
+	if x != nil {
+		return x.Schedule
+	}
+	return ""
+}
+
+type GetUtilizationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Server        string                 `protobuf:"bytes,1,opt,name=server,proto3" json:"server,omitempty"` // Empty for all servers
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUtilizationRequest) Reset() {
+	*x = GetUtilizationRequest{}
+	mi := &file_licet_v1_licet_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUtilizationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUtilizationRequest) ProtoMessage() {}
+
+func (x *GetUtilizationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_licet_v1_licet_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUtilizationRequest.ProtoReflect.Descriptor instead.
+func (*GetUtilizationRequest) Descriptor() ([]byte, []int) {
+	return file_licet_v1_licet_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetUtilizationRequest) GetServer() string {
+	if x != nil {
+		return x.Server
+	}
+	return ""
+}
+
+type GetUtilizationResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Features      []*FeatureUtilization  `protobuf:"bytes,1,rep,name=features,proto3" json:"features,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUtilizationResponse) Reset() {
+	*x = GetUtilizationResponse{}
+	mi := &file_licet_v1_licet_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUtilizationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUtilizationResponse) ProtoMessage() {}
+
+func (x *GetUtilizationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_licet_v1_licet_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUtilizationResponse.ProtoReflect.Descriptor instead.
+func (*GetUtilizationResponse) Descriptor() ([]byte, []int) {
+	return file_licet_v1_licet_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetUtilizationResponse) GetFeatures() []*FeatureUtilization {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+type FeatureUtilization struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Server            string                 `protobuf:"bytes,1,opt,name=server,proto3" json:"server,omitempty"`
+	Feature           string                 `protobuf:"bytes,2,opt,name=feature,proto3" json:"feature,omitempty"`
+	Version           string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	TotalLicenses     int32                  `protobuf:"varint,4,opt,name=total_licenses,json=totalLicenses,proto3" json:"total_licenses,omitempty"`
+	UsedLicenses      int32                  `protobuf:"varint,5,opt,name=used_licenses,json=usedLicenses,proto3" json:"used_licenses,omitempty"`
+	ReservedLicenses  int32                  `protobuf:"varint,6,opt,name=reserved_licenses,json=reservedLicenses,proto3" json:"reserved_licenses,omitempty"`
+	OverdraftLicenses int32                  `protobuf:"varint,7,opt,name=overdraft_licenses,json=overdraftLicenses,proto3" json:"overdraft_licenses,omitempty"`
+	AvailableLicenses int32                  `protobuf:"varint,8,opt,name=available_licenses,json=availableLicenses,proto3" json:"available_licenses,omitempty"`
+	UtilizationPct    float64                `protobuf:"fixed64,9,opt,name=utilization_pct,json=utilizationPct,proto3" json:"utilization_pct,omitempty"`
+	LicenseModel      string                 `protobuf:"bytes,10,opt,name=license_model,json=licenseModel,proto3" json:"license_model,omitempty"`
+	VendorDaemon      string                 `protobuf:"bytes,11,opt,name=vendor_daemon,json=vendorDaemon,proto3" json:"vendor_daemon,omitempty"`
+	DisplayName       string                 `protobuf:"bytes,12,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *FeatureUtilization) Reset() {
+	*x = FeatureUtilization{}
+	mi := &file_licet_v1_licet_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FeatureUtilization) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FeatureUtilization) ProtoMessage() {}
+
+func (x *FeatureUtilization) ProtoReflect() protoreflect.Message {
+	mi := &file_licet_v1_licet_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FeatureUtilization.ProtoReflect.Descriptor instead.
+func (*FeatureUtilization) Descriptor() ([]byte, []int) {
+	return file_licet_v1_licet_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *FeatureUtilization) GetServer() string {
+	if x != nil {
+		return x.Server
+	}
+	return ""
+}
+
+func (x *FeatureUtilization) GetFeature() string {
+	if x != nil {
+		return x.Feature
+	}
+	return ""
+}
+
+func (x *FeatureUtilization) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *FeatureUtilization) GetTotalLicenses() int32 {
+	if x != nil {
+		return x.TotalLicenses
+	}
+	return 0
+}
+
+func (x *FeatureUtilization) GetUsedLicenses() int32 {
+	if x != nil {
+		return x.UsedLicenses
+	}
+	return 0
+}
+
+func (x *FeatureUtilization) GetReservedLicenses() int32 {
+	if x != nil {
+		return x.ReservedLicenses
+	}
+	return 0
+}
+
+func (x *FeatureUtilization) GetOverdraftLicenses() int32 {
+	if x != nil {
+		return x.OverdraftLicenses
+	}
+	return 0
+}
+
+func (x *FeatureUtilization) GetAvailableLicenses() int32 {
+	if x != nil {
+		return x.AvailableLicenses
+	}
+	return 0
+}
+
+func (x *FeatureUtilization) GetUtilizationPct() float64 {
+	if x != nil {
+		return x.UtilizationPct
+	}
+	return 0
+}
+
+func (x *FeatureUtilization) GetLicenseModel() string {
+	if x != nil {
+		return x.LicenseModel
+	}
+	return ""
+}
+
+func (x *FeatureUtilization) GetVendorDaemon() string {
+	if x != nil {
+		return x.VendorDaemon
+	}
+	return ""
+}
+
+func (x *FeatureUtilization) GetDisplayName() string {
+	if x != nil {
+		return x.DisplayName
+	}
+	return ""
+}
+
+type StreamEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Servers       []string               `protobuf:"bytes,1,rep,name=servers,proto3" json:"servers,omitempty"`                             // Only events of these servers; empty for all
+	Types         []EventType            `protobuf:"varint,2,rep,packed,name=types,proto3,enum=licet.v1.EventType" json:"types,omitempty"` // Only events of these types; empty for all
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEventsRequest) Reset() {
+	*x = StreamEventsRequest{}
+	mi := &file_licet_v1_licet_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEventsRequest) ProtoMessage() {}
+
+func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_licet_v1_licet_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEventsRequest.ProtoReflect.Descriptor instead.
+func (*StreamEventsRequest) Descriptor() ([]byte, []int) {
+	return file_licet_v1_licet_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *StreamEventsRequest) GetServers() []string {
+	if x != nil {
+		return x.Servers
+	}
+	return nil
+}
+
+func (x *StreamEventsRequest) GetTypes() []EventType {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+type Event struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Type  EventType              `protobuf:"varint,1,opt,name=type,proto3,enum=licet.v1.EventType" json:"type,omitempty"`
+	Time  *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*Event_ServerStatus
+	//	*Event_Alert
+	Payload       isEvent_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_licet_v1_licet_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_licet_v1_licet_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_licet_v1_licet_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Event) GetType() EventType {
+	if x != nil {
+		return x.Type
+	}
+	return EventType_EVENT_TYPE_UNSPECIFIED
+}
+
+func (x *Event) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Event) GetPayload() isEvent_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *Event) GetServerStatus() *ServerStatus {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_ServerStatus); ok {
+			return x.ServerStatus
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetAlert() *Alert {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_Alert); ok {
+			return x.Alert
+		}
+	}
+	return nil
+}
+
+type isEvent_Payload interface {
+	isEvent_Payload()
+}
+
+type Event_ServerStatus struct {
+	ServerStatus *ServerStatus `protobuf:"bytes,3,opt,name=server_status,json=serverStatus,proto3,oneof"`
+}
+
+type Event_Alert struct {
+	Alert *Alert `protobuf:"bytes,4,opt,name=alert,proto3,oneof"`
+}
+
+func (*Event_ServerStatus) isEvent_Payload() {}
+
+func (*Event_Alert) isEvent_Payload() {}
+
+type ServerStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Server        string                 `protobuf:"bytes,1,opt,name=server,proto3" json:"server,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"` // up, down, warning
+	Master        string                 `protobuf:"bytes,4,opt,name=master,proto3" json:"master,omitempty"`
+	Version       string                 `protobuf:"bytes,5,opt,name=version,proto3" json:"version,omitempty"`
+	Message       string                 `protobuf:"bytes,6,opt,name=message,proto3" json:"message,omitempty"`
+	Features      int32                  `protobuf:"varint,7,opt,name=features,proto3" json:"features,omitempty"`
+	Users         int32                  `protobuf:"varint,8,opt,name=users,proto3" json:"users,omitempty"`
+	CollectedAt   *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=collected_at,json=collectedAt,proto3" json:"collected_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServerStatus) Reset() {
+	*x = ServerStatus{}
+	mi := &file_licet_v1_licet_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServerStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServerStatus) ProtoMessage() {}
+
+func (x *ServerStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_licet_v1_licet_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServerStatus.ProtoReflect.Descriptor instead.
+func (*ServerStatus) Descriptor() ([]byte, []int) {
+	return file_licet_v1_licet_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ServerStatus) GetServer() string {
+	if x != nil {
+		return x.Server
+	}
+	return ""
+}
+
+func (x *ServerStatus) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ServerStatus) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ServerStatus) GetMaster() string {
+	if x != nil {
+		return x.Master
+	}
+	return ""
+}
+
+func (x *ServerStatus) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *ServerStatus) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ServerStatus) GetFeatures() int32 {
+	if x != nil {
+		return x.Features
+	}
+	return 0
+}
+
+func (x *ServerStatus) GetUsers() int32 {
+	if x != nil {
+		return x.Users
+	}
+	return 0
+}
+
+func (x *ServerStatus) GetCollectedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CollectedAt
+	}
+	return nil
+}
+
+type Alert struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Server        string                 `protobuf:"bytes,2,opt,name=server,proto3" json:"server,omitempty"`
+	Feature       string                 `protobuf:"bytes,3,opt,name=feature,proto3" json:"feature,omitempty"`
+	AlertType     string                 `protobuf:"bytes,4,opt,name=alert_type,json=alertType,proto3" json:"alert_type,omitempty"` // expiration, down, denial, failover, ...
+	Message       string                 `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	Severity      string                 `protobuf:"bytes,6,opt,name=severity,proto3" json:"severity,omitempty"` // info, warning, critical
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Alert) Reset() {
+	*x = Alert{}
+	mi := &file_licet_v1_licet_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Alert) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Alert) ProtoMessage() {}
+
+func (x *Alert) ProtoReflect() protoreflect.Message {
+	mi := &file_licet_v1_licet_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Alert.ProtoReflect.Descriptor instead.
+func (*Alert) Descriptor() ([]byte, []int) {
+	return file_licet_v1_licet_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Alert) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Alert) GetServer() string {
+	if x != nil {
+		return x.Server
+	}
+	return ""
+}
+
+func (x *Alert) GetFeature() string {
+	if x != nil {
+		return x.Feature
+	}
+	return ""
+}
+
+func (x *Alert) GetAlertType() string {
+	if x != nil {
+		return x.AlertType
+	}
+	return ""
+}
+
+func (x *Alert) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Alert) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *Alert) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type GetForecastRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Server        string                 `protobuf:"bytes,1,opt,name=server,proto3" json:"server,omitempty"`
+	Feature       string                 `protobuf:"bytes,2,opt,name=feature,proto3" json:"feature,omitempty"`
+	Days          int32                  `protobuf:"varint,3,opt,name=days,proto3" json:"days,omitempty"` // Days of history the trend is fitted to, default 30
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetForecastRequest) Reset() {
+	*x = GetForecastRequest{}
+	mi := &file_licet_v1_licet_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetForecastRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetForecastRequest) ProtoMessage() {}
+
+func (x *GetForecastRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_licet_v1_licet_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetForecastRequest.ProtoReflect.Descriptor instead.
+func (*GetForecastRequest) Descriptor() ([]byte, []int) {
+	return file_licet_v1_licet_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetForecastRequest) GetServer() string {
+	if x != nil {
+		return x.Server
+	}
+	return ""
+}
+
+func (x *GetForecastRequest) GetFeature() string {
+	if x != nil {
+		return x.Feature
+	}
+	return ""
+}
+
+func (x *GetForecastRequest) GetDays() int32 {
+	if x != nil {
+		return x.Days
+	}
+	return 0
+}
+
+type GetForecastResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Server          string                 `protobuf:"bytes,1,opt,name=server,proto3" json:"server,omitempty"`
+	Feature         string                 `protobuf:"bytes,2,opt,name=feature,proto3" json:"feature,omitempty"`
+	TotalLicenses   int32                  `protobuf:"varint,3,opt,name=total_licenses,json=totalLicenses,proto3" json:"total_licenses,omitempty"`
+	CurrentUsage    float64                `protobuf:"fixed64,4,opt,name=current_usage,json=currentUsage,proto3" json:"current_usage,omitempty"`
+	TrendSlope      float64                `protobuf:"fixed64,5,opt,name=trend_slope,json=trendSlope,proto3" json:"trend_slope,omitempty"`                // Licenses per day
+	DaysToCapacity  int32                  `protobuf:"varint,6,opt,name=days_to_capacity,json=daysToCapacity,proto3" json:"days_to_capacity,omitempty"`   // -1 when usage does not reach capacity
+	ConfidenceLevel float64                `protobuf:"fixed64,7,opt,name=confidence_level,json=confidenceLevel,proto3" json:"confidence_level,omitempty"` // 0 to 1
+	Points          []*ForecastPoint       `protobuf:"bytes,8,rep,name=points,proto3" json:"points,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *GetForecastResponse) Reset() {
+	*x = GetForecastResponse{}
+	mi := &file_licet_v1_licet_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetForecastResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetForecastResponse) ProtoMessage() {}
+
+func (x *GetForecastResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_licet_v1_licet_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetForecastResponse.ProtoReflect.Descriptor instead.
+func (*GetForecastResponse) Descriptor() ([]byte, []int) {
+	return file_licet_v1_licet_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetForecastResponse) GetServer() string {
+	if x != nil {
+		return x.Server
+	}
+	return ""
+}
+
+func (x *GetForecastResponse) GetFeature() string {
+	if x != nil {
+		return x.Feature
+	}
+	return ""
+}
+
+func (x *GetForecastResponse) GetTotalLicenses() int32 {
+	if x != nil {
+		return x.TotalLicenses
+	}
+	return 0
+}
+
+func (x *GetForecastResponse) GetCurrentUsage() float64 {
+	if x != nil {
+		return x.CurrentUsage
+	}
+	return 0
+}
+
+func (x *GetForecastResponse) GetTrendSlope() float64 {
+	if x != nil {
+		return x.TrendSlope
+	}
+	return 0
+}
+
+func (x *GetForecastResponse) GetDaysToCapacity() int32 {
+	if x != nil {
+		return x.DaysToCapacity
+	}
+	return 0
+}
+
+func (x *GetForecastResponse) GetConfidenceLevel() float64 {
+	if x != nil {
+		return x.ConfidenceLevel
+	}
+	return 0
+}
+
+func (x *GetForecastResponse) GetPoints() []*ForecastPoint {
+	if x != nil {
+		return x.Points
+	}
+	return nil
+}
+
+type ForecastPoint struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Date           string                 `protobuf:"bytes,1,opt,name=date,proto3" json:"date,omitempty"` // YYYY-MM-DD
+	PredictedUsage float64                `protobuf:"fixed64,2,opt,name=predicted_usage,json=predictedUsage,proto3" json:"predicted_usage,omitempty"`
+	Holiday        string                 `protobuf:"bytes,3,opt,name=holiday,proto3" json:"holiday,omitempty"` // Holiday at the server's site on this date, if any
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ForecastPoint) Reset() {
+	*x = ForecastPoint{}
+	mi := &file_licet_v1_licet_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ForecastPoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForecastPoint) ProtoMessage() {}
+
+func (x *ForecastPoint) ProtoReflect() protoreflect.Message {
+	mi := &file_licet_v1_licet_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForecastPoint.ProtoReflect.Descriptor instead.
+func (*ForecastPoint) Descriptor() ([]byte, []int) {
+	return file_licet_v1_licet_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ForecastPoint) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *ForecastPoint) GetPredictedUsage() float64 {
+	if x != nil {
+		return x.PredictedUsage
+	}
+	return 0
+}
+
+func (x *ForecastPoint) GetHoliday() string {
+	if x != nil {
+		return x.Holiday
+	}
+	return ""
+}
+
+var File_licet_v1_licet_proto protoreflect.FileDescriptor
+
+const file_licet_v1_licet_proto_rawDesc = "" +
+	"\n" +
+	"\x14licet/v1/licet.proto\x12\blicet.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x14\n" +
+	"\x12ListServersRequest\"A\n" +
+	"\x13ListServersResponse\x12*\n" +
+	"\aservers\x18\x01 \x03(\v2\x10.licet.v1.ServerR\aservers\"\xd0\x01\n" +
+	"\x06Server\x12\x1a\n" +
+	"\bhostname\x18\x01 \x01(\tR\bhostname\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x1f\n" +
+	"\vresource_id\x18\x04 \x01(\x03R\n" +
+	"resourceId\x12\x12\n" +
+	"\x04uuid\x18\x05 \x01(\tR\x04uuid\x12#\n" +
+	"\rpoll_interval\x18\x06 \x01(\x05R\fpollInterval\x12\x1a\n" +
+	"\bschedule\x18\a \x01(\tR\bschedule\"/\n" +
+	"\x15GetUtilizationRequest\x12\x16\n" +
+	"\x06server\x18\x01 \x01(\tR\x06server\"R\n" +
+	"\x16GetUtilizationResponse\x128\n" +
+	"\bfeatures\x18\x01 \x03(\v2\x1c.licet.v1.FeatureUtilizationR\bfeatures\"\xcd\x03\n" +
+	"\x12FeatureUtilization\x12\x16\n" +
+	"\x06server\x18\x01 \x01(\tR\x06server\x12\x18\n" +
+	"\afeature\x18\x02 \x01(\tR\afeature\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\x12%\n" +
+	"\x0etotal_licenses\x18\x04 \x01(\x05R\rtotalLicenses\x12#\n" +
+	"\rused_licenses\x18\x05 \x01(\x05R\fusedLicenses\x12+\n" +
+	"\x11reserved_licenses\x18\x06 \x01(\x05R\x10reservedLicenses\x12-\n" +
+	"\x12overdraft_licenses\x18\a \x01(\x05R\x11overdraftLicenses\x12-\n" +
+	"\x12available_licenses\x18\b \x01(\x05R\x11availableLicenses\x12'\n" +
+	"\x0futilization_pct\x18\t \x01(\x01R\x0eutilizationPct\x12#\n" +
+	"\rlicense_model\x18\n" +
+	" \x01(\tR\flicenseModel\x12#\n" +
+	"\rvendor_daemon\x18\v \x01(\tR\fvendorDaemon\x12!\n" +
+	"\fdisplay_name\x18\f \x01(\tR\vdisplayName\"Z\n" +
+	"\x13StreamEventsRequest\x12\x18\n" +
+	"\aservers\x18\x01 \x03(\tR\aservers\x12)\n" +
+	"\x05types\x18\x02 \x03(\x0e2\x13.licet.v1.EventTypeR\x05types\"\xd3\x01\n" +
+	"\x05Event\x12'\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x13.licet.v1.EventTypeR\x04type\x12.\n" +
+	"\x04time\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12=\n" +
+	"\rserver_status\x18\x03 \x01(\v2\x16.licet.v1.ServerStatusH\x00R\fserverStatus\x12'\n" +
+	"\x05alert\x18\x04 \x01(\v2\x0f.licet.v1.AlertH\x00R\x05alertB\t\n" +
+	"\apayload\"\x8f\x02\n" +
+	"\fServerStatus\x12\x16\n" +
+	"\x06server\x18\x01 \x01(\tR\x06server\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x16\n" +
+	"\x06master\x18\x04 \x01(\tR\x06master\x12\x18\n" +
+	"\aversion\x18\x05 \x01(\tR\aversion\x12\x18\n" +
+	"\amessage\x18\x06 \x01(\tR\amessage\x12\x1a\n" +
+	"\bfeatures\x18\a \x01(\x05R\bfeatures\x12\x14\n" +
+	"\x05users\x18\b \x01(\x05R\x05users\x12=\n" +
+	"\fcollected_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\vcollectedAt\"\xd9\x01\n" +
+	"\x05Alert\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x16\n" +
+	"\x06server\x18\x02 \x01(\tR\x06server\x12\x18\n" +
+	"\afeature\x18\x03 \x01(\tR\afeature\x12\x1d\n" +
+	"\n" +
+	"alert_type\x18\x04 \x01(\tR\talertType\x12\x18\n" +
+	"\amessage\x18\x05 \x01(\tR\amessage\x12\x1a\n" +
+	"\bseverity\x18\x06 \x01(\tR\bseverity\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"Z\n" +
+	"\x12GetForecastRequest\x12\x16\n" +
+	"\x06server\x18\x01 \x01(\tR\x06server\x12\x18\n" +
+	"\afeature\x18\x02 \x01(\tR\afeature\x12\x12\n" +
+	"\x04days\x18\x03 \x01(\x05R\x04days\"\xba\x02\n" +
+	"\x13GetForecastResponse\x12\x16\n" +
+	"\x06server\x18\x01 \x01(\tR\x06server\x12\x18\n" +
+	"\afeature\x18\x02 \x01(\tR\afeature\x12%\n" +
+	"\x0etotal_licenses\x18\x03 \x01(\x05R\rtotalLicenses\x12#\n" +
+	"\rcurrent_usage\x18\x04 \x01(\x01R\fcurrentUsage\x12\x1f\n" +
+	"\vtrend_slope\x18\x05 \x01(\x01R\n" +
+	"trendSlope\x12(\n" +
+	"\x10days_to_capacity\x18\x06 \x01(\x05R\x0edaysToCapacity\x12)\n" +
+	"\x10confidence_level\x18\a \x01(\x01R\x0fconfidenceLevel\x12/\n" +
+	"\x06points\x18\b \x03(\v2\x17.licet.v1.ForecastPointR\x06points\"f\n" +
+	"\rForecastPoint\x12\x12\n" +
+	"\x04date\x18\x01 \x01(\tR\x04date\x12'\n" +
+	"\x0fpredicted_usage\x18\x02 \x01(\x01R\x0epredictedUsage\x12\x18\n" +
+	"\aholiday\x18\x03 \x01(\tR\aholiday*[\n" +
+	"\tEventType\x12\x1a\n" +
+	"\x16EVENT_TYPE_UNSPECIFIED\x10\x00\x12\x1c\n" +
+	"\x18EVENT_TYPE_SERVER_STATUS\x10\x01\x12\x14\n" +
+	"\x10EVENT_TYPE_ALERT\x10\x022\xbd\x02\n" +
+	"\fLicetService\x12J\n" +
+	"\vListServers\x12\x1c.licet.v1.ListServersRequest\x1a\x1d.licet.v1.ListServersResponse\x12S\n" +
+	"\x0eGetUtilization\x12\x1f.licet.v1.GetUtilizationRequest\x1a .licet.v1.GetUtilizationResponse\x12@\n" +
+	"\fStreamEvents\x12\x1d.licet.v1.StreamEventsRequest\x1a\x0f.licet.v1.Event0\x01\x12J\n" +
+	"\vGetForecast\x12\x1c.licet.v1.GetForecastRequest\x1a\x1d.licet.v1.GetForecastResponseB\x1bZ\x19licet/pkg/licetv1;licetv1b\x06proto3"
+
+var (
+	file_licet_v1_licet_proto_rawDescOnce sync.Once
+	file_licet_v1_licet_proto_rawDescData []byte
+)
+
+func file_licet_v1_licet_proto_rawDescGZIP() []byte {
+	file_licet_v1_licet_proto_rawDescOnce.Do(func() {
+		file_licet_v1_licet_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_licet_v1_licet_proto_rawDesc), len(file_licet_v1_licet_proto_rawDesc)))
+	})
+	return file_licet_v1_licet_proto_rawDescData
+}
+
+var file_licet_v1_licet_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_licet_v1_licet_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_licet_v1_licet_proto_goTypes = []any{
+	(EventType)(0),                 // 0: licet.v1.EventType
+	(*ListServersRequest)(nil),     // 1: licet.v1.ListServersRequest
+	(*ListServersResponse)(nil),    // 2: licet.v1.ListServersResponse
+	(*Server)(nil),                 // 3: licet.v1.Server
+	(*GetUtilizationRequest)(nil),  // 4: licet.v1.GetUtilizationRequest
+	(*GetUtilizationResponse)(nil), // 5: licet.v1.GetUtilizationResponse
+	(*FeatureUtilization)(nil),     // 6: licet.v1.FeatureUtilization
+	(*StreamEventsRequest)(nil),    // 7: licet.v1.StreamEventsRequest
+	(*Event)(nil),                  // 8: licet.v1.Event
+	(*ServerStatus)(nil),           // 9: licet.v1.ServerStatus
+	(*Alert)(nil),                  // 10: licet.v1.Alert
+	(*GetForecastRequest)(nil),     // 11: licet.v1.GetForecastRequest
+	(*GetForecastResponse)(nil),    // 12: licet.v1.GetForecastResponse
+	(*ForecastPoint)(nil),          // 13: licet.v1.ForecastPoint
+	(*timestamppb.Timestamp)(nil),  // 14: google.protobuf.Timestamp
+}
+var file_licet_v1_licet_proto_depIdxs = []int32{
+	3,  // 0: licet.v1.ListServersResponse.servers:type_name -> licet.v1.Server
+	6,  // 1: licet.v1.GetUtilizationResponse.features:type_name -> licet.v1.FeatureUtilization
+	0,  // 2: licet.v1.StreamEventsRequest.types:type_name -> licet.v1.EventType
+	0,  // 3: licet.v1.Event.type:type_name -> licet.v1.EventType
+	14, // 4: licet.v1.Event.time:type_name -> google.protobuf.Timestamp
+	9,  // 5: licet.v1.Event.server_status:type_name -> licet.v1.ServerStatus
+	10, // 6: licet.v1.Event.alert:type_name -> licet.v1.Alert
+	14, // 7: licet.v1.ServerStatus.collected_at:type_name -> google.protobuf.Timestamp
+	14, // 8: licet.v1.Alert.created_at:type_name -> google.protobuf.Timestamp
+	13, // 9: licet.v1.GetForecastResponse.points:type_name -> licet.v1.ForecastPoint
+	1,  // 10: licet.v1.LicetService.ListServers:input_type -> licet.v1.ListServersRequest
+	4,  // 11: licet.v1.LicetService.GetUtilization:input_type -> licet.v1.GetUtilizationRequest
+	7,  // 12: licet.v1.LicetService.StreamEvents:input_type -> licet.v1.StreamEventsRequest
+	11, // 13: licet.v1.LicetService.GetForecast:input_type -> licet.v1.GetForecastRequest
+	2,  // 14: licet.v1.LicetService.ListServers:output_type -> licet.v1.ListServersResponse
+	5,  // 15: licet.v1.LicetService.GetUtilization:output_type -> licet.v1.GetUtilizationResponse
+	8,  // 16: licet.v1.LicetService.StreamEvents:output_type -> licet.v1.Event
+	12, // 17: licet.v1.LicetService.GetForecast:output_type -> licet.v1.GetForecastResponse
+	14, // [14:18] is the sub-list for method output_type
+	10, // [10:14] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_licet_v1_licet_proto_init() }
+func file_licet_v1_licet_proto_init() {
+	if File_licet_v1_licet_proto != nil {
+		return
+	}
+	file_licet_v1_licet_proto_msgTypes[7].OneofWrappers = []any{
+		(*Event_ServerStatus)(nil),
+		(*Event_Alert)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_licet_v1_licet_proto_rawDesc), len(file_licet_v1_licet_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_licet_v1_licet_proto_goTypes,
+		DependencyIndexes: file_licet_v1_licet_proto_depIdxs,
+		EnumInfos:         file_licet_v1_licet_proto_enumTypes,
+		MessageInfos:      file_licet_v1_licet_proto_msgTypes,
+	}.Build()
+	File_licet_v1_licet_proto = out.File
+	file_licet_v1_licet_proto_goTypes = nil
+	file_licet_v1_licet_proto_depIdxs = nil
+}
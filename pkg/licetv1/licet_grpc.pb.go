@@ -0,0 +1,256 @@
+// The licet.v1 gRPC service serves the servers, utilization and forecasts of
+// the REST API and streams collection and alert events, for integrations that
+// already use gRPC. Requests authenticate with an API key in the x-api-key
+// or authorization ("Bearer <key>") metadata when authentication is enabled.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: licet/v1/licet.proto
+
+package licetv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	LicetService_ListServers_FullMethodName    = "/licet.v1.LicetService/ListServers"
+	LicetService_GetUtilization_FullMethodName = "/licet.v1.LicetService/GetUtilization"
+	LicetService_StreamEvents_FullMethodName   = "/licet.v1.LicetService/StreamEvents"
+	LicetService_GetForecast_FullMethodName    = "/licet.v1.LicetService/GetForecast"
+)
+
+// LicetServiceClient is the client API for LicetService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LicetServiceClient interface {
+	// ListServers lists the configured license servers
+	ListServers(ctx context.Context, in *ListServersRequest, opts ...grpc.CallOption) (*ListServersResponse, error)
+	// GetUtilization returns the current utilization of the features of all
+	// servers, or of one
+	GetUtilization(ctx context.Context, in *GetUtilizationRequest, opts ...grpc.CallOption) (*GetUtilizationResponse, error)
+	// StreamEvents streams the status of servers as they are collected and
+	// alerts as they are raised, until the client cancels
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+	// GetForecast projects the usage of a feature from its trend
+	GetForecast(ctx context.Context, in *GetForecastRequest, opts ...grpc.CallOption) (*GetForecastResponse, error)
+}
+
+type licetServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLicetServiceClient(cc grpc.ClientConnInterface) LicetServiceClient {
+	return &licetServiceClient{cc}
+}
+
+func (c *licetServiceClient) ListServers(ctx context.Context, in *ListServersRequest, opts ...grpc.CallOption) (*ListServersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListServersResponse)
+	err := c.cc.Invoke(ctx, LicetService_ListServers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *licetServiceClient) GetUtilization(ctx context.Context, in *GetUtilizationRequest, opts ...grpc.CallOption) (*GetUtilizationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUtilizationResponse)
+	err := c.cc.Invoke(ctx, LicetService_GetUtilization_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *licetServiceClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &LicetService_ServiceDesc.Streams[0], LicetService_StreamEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LicetService_StreamEventsClient = grpc.ServerStreamingClient[Event]
+
+func (c *licetServiceClient) GetForecast(ctx context.Context, in *GetForecastRequest, opts ...grpc.CallOption) (*GetForecastResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetForecastResponse)
+	err := c.cc.Invoke(ctx, LicetService_GetForecast_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LicetServiceServer is the server API for LicetService service.
+// All implementations must embed UnimplementedLicetServiceServer
+// for forward compatibility.
+type LicetServiceServer interface {
+	// ListServers lists the configured license servers
+	ListServers(context.Context, *ListServersRequest) (*ListServersResponse, error)
+	// GetUtilization returns the current utilization of the features of all
+	// servers, or of one
+	GetUtilization(context.Context, *GetUtilizationRequest) (*GetUtilizationResponse, error)
+	// StreamEvents streams the status of servers as they are collected and
+	// alerts as they are raised, until the client cancels
+	StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error
+	// GetForecast projects the usage of a feature from its trend
+	GetForecast(context.Context, *GetForecastRequest) (*GetForecastResponse, error)
+	mustEmbedUnimplementedLicetServiceServer()
+}
+
+// UnimplementedLicetServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedLicetServiceServer struct{}
+
+func (UnimplementedLicetServiceServer) ListServers(context.Context, *ListServersRequest) (*ListServersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListServers not implemented")
+}
+func (UnimplementedLicetServiceServer) GetUtilization(context.Context, *GetUtilizationRequest) (*GetUtilizationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUtilization not implemented")
+}
+func (UnimplementedLicetServiceServer) StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedLicetServiceServer) GetForecast(context.Context, *GetForecastRequest) (*GetForecastResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetForecast not implemented")
+}
+func (UnimplementedLicetServiceServer) mustEmbedUnimplementedLicetServiceServer() {}
+func (UnimplementedLicetServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeLicetServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LicetServiceServer will
+// result in compilation errors.
+type UnsafeLicetServiceServer interface {
+	mustEmbedUnimplementedLicetServiceServer()
+}
+
+func RegisterLicetServiceServer(s grpc.ServiceRegistrar, srv LicetServiceServer) {
+	// If the following call pancis, it indicates UnimplementedLicetServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&LicetService_ServiceDesc, srv)
+}
+
+func _LicetService_ListServers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListServersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LicetServiceServer).ListServers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LicetService_ListServers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LicetServiceServer).ListServers(ctx, req.(*ListServersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LicetService_GetUtilization_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUtilizationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LicetServiceServer).GetUtilization(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LicetService_GetUtilization_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LicetServiceServer).GetUtilization(ctx, req.(*GetUtilizationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LicetService_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LicetServiceServer).StreamEvents(m, &grpc.GenericServerStream[StreamEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LicetService_StreamEventsServer = grpc.ServerStreamingServer[Event]
+
+func _LicetService_GetForecast_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetForecastRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LicetServiceServer).GetForecast(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LicetService_GetForecast_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LicetServiceServer).GetForecast(ctx, req.(*GetForecastRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// LicetService_ServiceDesc is the grpc.ServiceDesc for LicetService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LicetService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "licet.v1.LicetService",
+	HandlerType: (*LicetServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListServers",
+			Handler:    _LicetService_ListServers_Handler,
+		},
+		{
+			MethodName: "GetUtilization",
+			Handler:    _LicetService_GetUtilization_Handler,
+		},
+		{
+			MethodName: "GetForecast",
+			Handler:    _LicetService_GetForecast_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _LicetService_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "licet/v1/licet.proto",
+}
@@ -4,7 +4,12 @@ import (
 	"embed"
 	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -33,8 +38,80 @@ func timeSince(t time.Time) string {
 	}
 }
 
-// LoadTemplates loads all HTML templates from the embedded filesystem
-func LoadTemplates() *template.Template {
+// Templates holds the parsed HTML templates. The *.html files of an optional
+// override directory replace the embedded templates of the same name or add
+// new ones.
+type Templates struct {
+	dir   string
+	watch bool
+
+	mu        sync.RWMutex
+	tmpl      *template.Template
+	signature string // Names, sizes and modification times of the override files
+}
+
+// LoadTemplates loads all HTML templates from the embedded filesystem and dir,
+// if set. With watch (dev mode), templates are reloaded when files in dir
+// change.
+func LoadTemplates(dir string, watch bool) *Templates {
+	t := &Templates{dir: dir, watch: watch && dir != ""}
+	if err := t.reload(); err != nil {
+		log.Fatalf("Failed to load templates: %v", err)
+	}
+	return t
+}
+
+// CheckTemplates parses the embedded templates and those of dir, if set, and
+// renders every page with a title only, returning the first error
+func CheckTemplates(dir string) error {
+	t := &Templates{dir: dir}
+	if err := t.reload(); err != nil {
+		return err
+	}
+	for _, tmpl := range t.tmpl.Templates() {
+		if filepath.Ext(tmpl.Name()) != ".html" {
+			continue
+		}
+		if err := tmpl.Execute(io.Discard, map[string]interface{}{"Title": "Self-test"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExecuteTemplate renders the named template, reloading the templates first
+// if they are watched and changed
+func (t *Templates) ExecuteTemplate(w io.Writer, name string, data interface{}) error {
+	if t.watch {
+		if signature, err := t.overrideSignature(); err != nil {
+			log.WithError(err).Warn("Failed to check template directory")
+		} else if signature != t.currentSignature() {
+			if err := t.reload(); err != nil {
+				// Keep serving the previous templates until the files change again
+				log.WithError(err).Error("Failed to reload templates")
+				t.mu.Lock()
+				t.signature = signature
+				t.mu.Unlock()
+			} else {
+				log.Info("Reloaded templates")
+			}
+		}
+	}
+
+	t.mu.RLock()
+	tmpl := t.tmpl
+	t.mu.RUnlock()
+	return tmpl.ExecuteTemplate(w, name, data)
+}
+
+func (t *Templates) currentSignature() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.signature
+}
+
+// reload parses the embedded templates and the override files
+func (t *Templates) reload() error {
 	// Create a FuncMap with custom template functions
 	funcMap := template.FuncMap{
 		"timeSince": timeSince,
@@ -46,11 +123,54 @@ func LoadTemplates() *template.Template {
 	// Parse templates with custom functions
 	tmpl, err := template.New("").Funcs(funcMap).ParseFS(templateFS, "templates/*.html")
 	if err != nil {
-		log.Fatalf("Failed to load embedded templates: %v", err)
+		return fmt.Errorf("embedded templates: %w", err)
+	}
+
+	var signature string
+	if t.dir != "" {
+		// Taken before parsing, so files changed meanwhile are parsed again
+		if signature, err = t.overrideSignature(); err != nil {
+			return err
+		}
+		files, err := filepath.Glob(filepath.Join(t.dir, "*.html"))
+		if err != nil {
+			return err
+		}
+		if len(files) > 0 {
+			if tmpl, err = tmpl.ParseFiles(files...); err != nil {
+				return fmt.Errorf("templates in %s: %w", t.dir, err)
+			}
+			log.WithField("dir", t.dir).Infof("Overriding %d template(s)", len(files))
+		}
 	}
 
-	log.Infof("Successfully loaded %d embedded template(s)", len(tmpl.Templates()))
-	return tmpl
+	t.mu.Lock()
+	t.tmpl = tmpl
+	t.signature = signature
+	t.mu.Unlock()
+
+	log.Debugf("Loaded %d template(s)", len(tmpl.Templates()))
+	return nil
+}
+
+// overrideSignature summarizes the *.html files of the override directory
+func (t *Templates) overrideSignature() (string, error) {
+	entries, err := os.ReadDir(t.dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read template directory: %w", err)
+	}
+	var b strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".html" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
 }
 
 // GetStaticFS returns the embedded static filesystem
@@ -0,0 +1,103 @@
+package web
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"licet/internal/config"
+)
+
+func render(t *testing.T, tmpl *Templates, name string) string {
+	t.Helper()
+	var b strings.Builder
+	if err := tmpl.ExecuteTemplate(&b, name, map[string]interface{}{"Title": "Test"}); err != nil {
+		t.Fatalf("ExecuteTemplate(%s) failed: %v", name, err)
+	}
+	return b.String()
+}
+
+func TestLoadTemplates_Overrides(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "alerts.html"), []byte(`custom {{.Title}}`), 0644)
+	os.WriteFile(filepath.Join(dir, "extra.html"), []byte(`extra`), 0644)
+
+	tmpl := LoadTemplates(dir, false)
+	if got := render(t, tmpl, "alerts.html"); got != "custom Test" {
+		t.Errorf("Expected the override, got %q", got)
+	}
+	if got := render(t, tmpl, "extra.html"); got != "extra" {
+		t.Errorf("Expected the added template, got %q", got)
+	}
+	if tmpl.tmpl.Lookup("index.html") == nil {
+		t.Error("Expected the embedded templates to remain")
+	}
+}
+
+func TestLoadTemplates_Watch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "alerts.html")
+	os.WriteFile(path, []byte(`v1`), 0644)
+
+	tmpl := LoadTemplates(dir, true)
+	if got := render(t, tmpl, "alerts.html"); got != "v1" {
+		t.Fatalf("Expected v1, got %q", got)
+	}
+
+	later := time.Now().Add(time.Minute)
+	os.WriteFile(path, []byte(`v2`), 0644)
+	os.Chtimes(path, later, later)
+	if got := render(t, tmpl, "alerts.html"); got != "v2" {
+		t.Errorf("Expected the changed template, got %q", got)
+	}
+
+	// A broken template keeps the previous version
+	os.WriteFile(path, []byte(`{{.Title`), 0644)
+	os.Chtimes(path, later.Add(time.Minute), later.Add(time.Minute))
+	if got := render(t, tmpl, "alerts.html"); got != "v2" {
+		t.Errorf("Expected the previous template, got %q", got)
+	}
+}
+
+func TestTemplates_Branding(t *testing.T) {
+	tmpl := LoadTemplates("", false)
+	data := map[string]interface{}{
+		"Version": "1.2.3",
+		"Brand": config.BrandingConfig{
+			ProductName: "Acme Licenses",
+			LogoFile:    "/etc/licet/logo.png",
+			FooterText:  "Hosted by Acme IT",
+			NavbarColor: "#123456",
+		},
+	}
+
+	var b strings.Builder
+	for _, name := range []string{"brand-style", "brand", "footer"} {
+		if err := tmpl.ExecuteTemplate(&b, name, data); err != nil {
+			t.Fatalf("ExecuteTemplate(%s) failed: %v", name, err)
+		}
+	}
+	out := b.String()
+	for _, want := range []string{"background-color: #123456", `src="/branding/logo"`, "Acme Licenses v1.2.3", "Hosted by Acme IT"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "github.com") || strings.Contains(out, ".btn-primary") {
+		t.Errorf("Unexpected default footer or unset color in:\n%s", out)
+	}
+}
+
+func TestCheckTemplates(t *testing.T) {
+	if err := CheckTemplates(""); err != nil {
+		t.Fatalf("CheckTemplates failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "alerts.html"), []byte(`{{template "missing"}}`), 0644)
+	if err := CheckTemplates(dir); err == nil {
+		t.Error("Expected an error for a template that fails to render")
+	}
+}